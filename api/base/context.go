@@ -0,0 +1,109 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"reflect"
+
+	"golang.org/x/net/context"
+)
+
+// NewContextFacadeCaller returns a FacadeCaller that wraps the given
+// one, abandoning any call still in flight when the context is done.
+// Calls made after the context is done fail immediately with the
+// context's error, without reaching the API server.
+//
+// The response of an abandoned call is left untouched. If the call
+// later succeeds, any watchers it started on the API server are
+// stopped, as nothing else can stop them.
+func NewContextFacadeCaller(ctx context.Context, facade FacadeCaller) FacadeCaller {
+	return &contextFacadeCaller{
+		FacadeCaller: facade,
+		ctx:          ctx,
+	}
+}
+
+type contextFacadeCaller struct {
+	FacadeCaller
+	ctx context.Context
+}
+
+// FacadeCall is part of the FacadeCaller interface.
+func (c *contextFacadeCaller) FacadeCall(request string, args, response interface{}) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	// The call decodes into a value of its own, so that an abandoned
+	// call cannot write to the response after we have returned.
+	private := response
+	responseValue := reflect.ValueOf(response)
+	if responseValue.Kind() == reflect.Ptr && !responseValue.IsNil() {
+		private = reflect.New(responseValue.Elem().Type()).Interface()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.FacadeCaller.FacadeCall(request, args, private)
+	}()
+	select {
+	case err := <-done:
+		if private != response {
+			responseValue.Elem().Set(reflect.ValueOf(private).Elem())
+		}
+		return err
+	case <-c.ctx.Done():
+		go func() {
+			if err := <-done; err == nil {
+				stopWatchers(c.RawAPICaller(), reflect.ValueOf(private))
+			}
+		}()
+		return c.ctx.Err()
+	}
+}
+
+// watcherFacades maps the names of the watcher id fields in API
+// results to the facades of the watchers they identify.
+var watcherFacades = map[string]string{
+	"NotifyWatcherId":               "NotifyWatcher",
+	"StringsWatcherId":              "StringsWatcher",
+	"EntitiesWatcherId":             "EntityWatcher",
+	"RelationUnitsWatcherId":        "RelationUnitsWatcher",
+	"RelationStatusWatcherId":       "RelationStatusWatcher",
+	"OfferStatusWatcherId":          "OfferStatusWatcher",
+	"ApplicationRelationsWatcherId": "ApplicationRelationsWatcher",
+	"AllWatcherId":                  "AllWatcher",
+}
+
+// stopWatchers stops the watchers identified in v, the result of an
+// abandoned call, including those in the elements of any slices it
+// holds.
+func stopWatchers(caller APICaller, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			stopWatchers(caller, v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			stopWatchers(caller, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			facade, ok := watcherFacades[t.Field(i).Name]
+			if !ok || field.Kind() != reflect.String {
+				stopWatchers(caller, field)
+				continue
+			}
+			id := field.String()
+			if id == "" {
+				continue
+			}
+			err := caller.APICall(facade, caller.BestFacadeVersion(facade), id, "Stop", nil, nil)
+			if err != nil {
+				logger.Errorf("cannot stop %s %q started by abandoned call: %v", facade, id, err)
+			}
+		}
+	}
+}
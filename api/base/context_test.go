@@ -0,0 +1,130 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	"golang.org/x/net/context"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type contextSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&contextSuite{})
+
+func (s *contextSuite) TestCall(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(request, gc.Equals, "Method")
+		*(result.(*string)) = "result"
+		return errors.New("boom")
+	})
+	facade := base.NewContextFacadeCaller(context.Background(), base.NewFacadeCaller(apiCaller, "Facade"))
+	var result string
+	err := facade.FacadeCall("Method", nil, &result)
+	c.Check(err, gc.ErrorMatches, "boom")
+	c.Check(result, gc.Equals, "result")
+}
+
+func (s *contextSuite) TestAlreadyCancelled(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	facade := base.NewContextFacadeCaller(ctx, base.NewFacadeCaller(apiCaller, "Facade"))
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.Equals, context.Canceled)
+}
+
+func (s *contextSuite) TestCancelledMidCall(c *gc.C) {
+	called := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		close(called)
+		<-release
+		*(result.(*string)) = "result"
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	facade := base.NewContextFacadeCaller(ctx, base.NewFacadeCaller(apiCaller, "Facade"))
+	var result string
+	errc := make(chan error, 1)
+	go func() {
+		errc <- facade.FacadeCall("Method", nil, &result)
+	}()
+	select {
+	case <-called:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for call")
+	}
+	cancel()
+	select {
+	case err := <-errc:
+		c.Check(err, gc.Equals, context.Canceled)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("call not abandoned after cancellation")
+	}
+	c.Check(result, gc.Equals, "")
+}
+
+func (s *contextSuite) TestAbandonedWatcherStopped(c *gc.C) {
+	called := make(chan struct{})
+	release := make(chan struct{})
+	stopped := make(chan string, 1)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch request {
+		case "WatchThings":
+			close(called)
+			<-release
+			*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+				Results: []params.StringsWatchResult{{StringsWatcherId: "7"}},
+			}
+		case "Stop":
+			c.Check(objType, gc.Equals, "StringsWatcher")
+			stopped <- id
+		default:
+			c.Errorf("unexpected request %q", request)
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	facade := base.NewContextFacadeCaller(ctx, base.NewFacadeCaller(apiCaller, "Facade"))
+	var results params.StringsWatchResults
+	errc := make(chan error, 1)
+	go func() {
+		errc <- facade.FacadeCall("WatchThings", nil, &results)
+	}()
+	select {
+	case <-called:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for call")
+	}
+	cancel()
+	select {
+	case err := <-errc:
+		c.Check(err, gc.Equals, context.Canceled)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("call not abandoned after cancellation")
+	}
+	close(release)
+	select {
+	case id := <-stopped:
+		c.Check(id, gc.Equals, "7")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("watcher not stopped")
+	}
+	c.Check(results.Results, gc.HasLen, 0)
+}
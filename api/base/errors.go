@@ -0,0 +1,57 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// TranslateError converts an error returned by the API server into the
+// juju/errors type corresponding to its code, preserving its message,
+// so that callers can use errors.IsNotFound and friends. Errors without
+// a code are returned unchanged, as are those whose codes callers are
+// expected to check with params.IsCodeTryAgain and similar, or that
+// carry further information. Any other code is returned as a plain
+// error with the code included in the text.
+func TranslateError(err error) error {
+	apiErr, ok := errors.Cause(err).(*params.Error)
+	if !ok || apiErr == nil {
+		return err
+	}
+	msg := apiErr.Message
+	switch apiErr.Code {
+	case "":
+		return err
+	case params.CodeNotFound, params.CodeModelNotFound:
+		return errors.NewNotFound(nil, msg)
+	case params.CodeUserNotFound:
+		return errors.NewUserNotFound(nil, msg)
+	case params.CodeUnauthorized:
+		return errors.NewUnauthorized(nil, msg)
+	case params.CodeAlreadyExists:
+		return errors.NewAlreadyExists(nil, msg)
+	case params.CodeNotSupported:
+		return errors.NewNotSupported(nil, msg)
+	case params.CodeNotImplemented:
+		return errors.NewNotImplemented(nil, msg)
+	case params.CodeNotAssigned:
+		return errors.NewNotAssigned(nil, msg)
+	case params.CodeNotProvisioned:
+		return errors.NewNotProvisioned(nil, msg)
+	case params.CodeBadRequest:
+		return errors.NewBadRequest(nil, msg)
+	case params.CodeMethodNotAllowed:
+		return errors.NewMethodNotAllowed(nil, msg)
+	case params.CodeTryAgain,
+		params.CodeRetry,
+		params.CodeStopped,
+		params.CodeDischargeRequired,
+		params.CodeRedirect,
+		params.CodeSuspended:
+		return err
+	}
+	return errors.Errorf("%s (%s)", msg, apiErr.Code)
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+type errorsSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&errorsSuite{})
+
+// isParamsError returns a checker function that reports whether
+// an error is a *params.Error with the given code.
+func isParamsError(code string) func(error) bool {
+	return func(err error) bool {
+		_, ok := err.(*params.Error)
+		return ok && params.ErrCode(err) == code
+	}
+}
+
+func isPlainError(err error) bool {
+	return params.ErrCode(err) == "" &&
+		!errors.IsNotFound(err) &&
+		!errors.IsUnauthorized(err) &&
+		!errors.IsAlreadyExists(err) &&
+		!errors.IsNotSupported(err)
+}
+
+func (s *errorsSuite) TestTranslateError(c *gc.C) {
+	for i, test := range []struct {
+		code    string
+		check   func(error) bool
+		message string
+	}{
+		{params.CodeNotFound, errors.IsNotFound, "boom"},
+		{params.CodeUserNotFound, errors.IsUserNotFound, "boom"},
+		{params.CodeModelNotFound, errors.IsNotFound, "boom"},
+		{params.CodeUnauthorized, errors.IsUnauthorized, "boom"},
+		{params.CodeLoginExpired, isPlainError, `boom \(login expired\)`},
+		{params.CodeNoCreds, isPlainError, `boom \(no credentials provided\)`},
+		{params.CodeCannotEnterScope, isPlainError, `boom \(cannot enter scope\)`},
+		{params.CodeCannotEnterScopeYet, isPlainError, `boom \(cannot enter scope yet\)`},
+		{params.CodeExcessiveContention, isPlainError, `boom \(excessive contention\)`},
+		{params.CodeUnitHasSubordinates, isPlainError, `boom \(unit has subordinates\)`},
+		{params.CodeNotAssigned, errors.IsNotAssigned, "boom"},
+		{params.CodeStopped, isParamsError(params.CodeStopped), "boom"},
+		{params.CodeDead, isPlainError, `boom \(dead\)`},
+		{params.CodeHasAssignedUnits, isPlainError, `boom \(machine has assigned units\)`},
+		{params.CodeHasHostedModels, isPlainError, `boom \(controller has hosted models\)`},
+		{params.CodeMachineHasAttachedStorage, isPlainError, `boom \(machine has attached storage\)`},
+		{params.CodeNotProvisioned, errors.IsNotProvisioned, "boom"},
+		{params.CodeNoAddressSet, isPlainError, `boom \(no address set\)`},
+		{params.CodeTryAgain, isParamsError(params.CodeTryAgain), "boom"},
+		{params.CodeNotImplemented, errors.IsNotImplemented, "boom"},
+		{params.CodeAlreadyExists, errors.IsAlreadyExists, "boom"},
+		{params.CodeUpgradeInProgress, isPlainError, `boom \(upgrade in progress\)`},
+		{params.CodeActionNotAvailable, isPlainError, `boom \(action no longer available\)`},
+		{params.CodeOperationBlocked, isPlainError, `boom \(operation is blocked\)`},
+		{params.CodeLeadershipClaimDenied, isPlainError, `boom \(leadership claim denied\)`},
+		{params.CodeLeaseClaimDenied, isPlainError, `boom \(lease claim denied\)`},
+		{params.CodeNotSupported, errors.IsNotSupported, "boom"},
+		{params.CodeBadRequest, errors.IsBadRequest, "boom"},
+		{params.CodeMethodNotAllowed, errors.IsMethodNotAllowed, "boom"},
+		{params.CodeForbidden, isPlainError, `boom \(forbidden\)`},
+		{params.CodeDischargeRequired, isParamsError(params.CodeDischargeRequired), "boom"},
+		{params.CodeRedirect, isParamsError(params.CodeRedirect), "boom"},
+		{params.CodeRetry, isParamsError(params.CodeRetry), "boom"},
+		{params.CodeSuspended, isParamsError(params.CodeSuspended), "boom"},
+		{"something new", isPlainError, `boom \(something new\)`},
+	} {
+		c.Logf("test %d: %s", i, test.code)
+		err := base.TranslateError(&params.Error{Code: test.code, Message: "boom"})
+		c.Check(err, gc.ErrorMatches, test.message)
+		c.Check(err, jc.Satisfies, test.check)
+	}
+}
+
+func (s *errorsSuite) TestTranslateErrorTraced(c *gc.C) {
+	err := base.TranslateError(errors.Trace(&params.Error{Code: params.CodeNotFound, Message: "boom"}))
+	c.Check(err, gc.ErrorMatches, "boom")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *errorsSuite) TestTranslateErrorPassesThrough(c *gc.C) {
+	c.Check(base.TranslateError(nil), jc.ErrorIsNil)
+	plain := errors.New("boom")
+	c.Check(base.TranslateError(plain), gc.Equals, plain)
+	noCode := &params.Error{Message: "boom"}
+	c.Check(base.TranslateError(noCode), gc.Equals, noCode)
+}
@@ -0,0 +1,225 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/clock"
+	"gopkg.in/tomb.v1"
+)
+
+// ErrConnectionBroken is returned by calls made through a caller
+// monitored by a ConnectionMonitor once the monitor has declared the
+// connection broken.
+var ErrConnectionBroken = errors.New("api connection broken")
+
+// Ping makes a request to the API server through caller which succeeds
+// if the connection is working.
+func Ping(caller APICaller) error {
+	return caller.APICall("Pinger", caller.BestFacadeVersion("Pinger"), "", "Ping", nil, nil)
+}
+
+// MonitorConfig holds the configuration for a ConnectionMonitor.
+type MonitorConfig struct {
+	// Caller is the connection to monitor.
+	Caller APICaller
+
+	// Clock is used to schedule pings and time them out.
+	Clock clock.Clock
+
+	// PingInterval is the time to wait between pings.
+	PingInterval time.Duration
+
+	// PingTimeout is the time to wait for a ping to complete before
+	// counting it as missed.
+	PingTimeout time.Duration
+
+	// MaxMissed is the number of pings in a row that must fail or
+	// time out before the connection is declared broken.
+	MaxMissed int
+}
+
+// Validate returns an error if the config cannot be used to create a
+// ConnectionMonitor.
+func (config MonitorConfig) Validate() error {
+	if config.Caller == nil {
+		return errors.NotValidf("nil Caller")
+	}
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.PingInterval <= 0 {
+		return errors.NotValidf("non-positive PingInterval")
+	}
+	if config.PingTimeout <= 0 {
+		return errors.NotValidf("non-positive PingTimeout")
+	}
+	if config.MaxMissed < 1 {
+		return errors.NotValidf("MaxMissed %d", config.MaxMissed)
+	}
+	return nil
+}
+
+// ConnectionMonitor pings an API connection periodically, and declares
+// it broken when too many pings in a row fail or time out. This
+// detects a half-dead connection long before the calls made on it
+// would time out at the TCP level.
+//
+// ConnectionMonitor is a worker; once the connection is declared
+// broken, it stops with ErrConnectionBroken.
+type ConnectionMonitor struct {
+	tomb   tomb.Tomb
+	config MonitorConfig
+	dead   chan struct{}
+}
+
+// NewConnectionMonitor starts and returns a ConnectionMonitor.
+func NewConnectionMonitor(config MonitorConfig) (*ConnectionMonitor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	m := &ConnectionMonitor{
+		config: config,
+		dead:   make(chan struct{}),
+	}
+	go func() {
+		defer m.tomb.Done()
+		m.tomb.Kill(m.loop())
+	}()
+	return m, nil
+}
+
+// Dead returns a channel that is closed when the connection is declared
+// broken. It is not closed if the monitor is merely stopped.
+func (m *ConnectionMonitor) Dead() <-chan struct{} {
+	return m.dead
+}
+
+// Kill is part of the worker.Worker interface.
+func (m *ConnectionMonitor) Kill() {
+	m.tomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (m *ConnectionMonitor) Wait() error {
+	return m.tomb.Wait()
+}
+
+func (m *ConnectionMonitor) loop() error {
+	missed := 0
+	for {
+		select {
+		case <-m.tomb.Dying():
+			return tomb.ErrDying
+		case <-m.config.Clock.After(m.config.PingInterval):
+		}
+		err := m.ping()
+		if err == tomb.ErrDying {
+			return err
+		}
+		if err == nil {
+			missed = 0
+			continue
+		}
+		missed++
+		logger.Debugf("health ping %d of %d missed: %v", missed, m.config.MaxMissed, err)
+		if missed >= m.config.MaxMissed {
+			logger.Errorf("api connection broken after %d missed pings", missed)
+			close(m.dead)
+			return ErrConnectionBroken
+		}
+	}
+}
+
+// ping pings the connection, returning an error if the ping fails or
+// times out, or tomb.ErrDying if the monitor is stopped first.
+func (m *ConnectionMonitor) ping() error {
+	// result is buffered so that the goroutine is not leaked when
+	// the ping times out.
+	result := make(chan error, 1)
+	go func() {
+		result <- Ping(m.config.Caller)
+	}()
+	select {
+	case <-m.tomb.Dying():
+		return tomb.ErrDying
+	case err := <-result:
+		return err
+	case <-m.config.Clock.After(m.config.PingTimeout):
+		return errors.Errorf("timed out after %v", m.config.PingTimeout)
+	}
+}
+
+// NewMonitoredAPICaller returns an APICaller that wraps the given one,
+// failing calls with ErrConnectionBroken as soon as the monitor
+// declares the connection broken, including calls that are already
+// waiting for a response. An abandoned call may still complete in the
+// background, so its response value must not be used once
+// ErrConnectionBroken has been returned.
+func NewMonitoredAPICaller(caller APICaller, monitor *ConnectionMonitor) APICaller {
+	return &monitoredAPICaller{
+		APICaller: caller,
+		monitor:   monitor,
+	}
+}
+
+type monitoredAPICaller struct {
+	APICaller
+	monitor *ConnectionMonitor
+}
+
+// APICall is part of the APICaller interface.
+func (c *monitoredAPICaller) APICall(objType string, version int, id, request string, args, response interface{}) error {
+	return c.monitor.call(func() error {
+		return c.APICaller.APICall(objType, version, id, request, args, response)
+	})
+}
+
+// NewMonitoredFacadeCaller returns a FacadeCaller that wraps the given
+// one, failing calls as described for NewMonitoredAPICaller.
+func NewMonitoredFacadeCaller(facade FacadeCaller, monitor *ConnectionMonitor) FacadeCaller {
+	return &monitoredFacadeCaller{
+		FacadeCaller: facade,
+		monitor:      monitor,
+	}
+}
+
+type monitoredFacadeCaller struct {
+	FacadeCaller
+	monitor *ConnectionMonitor
+}
+
+// FacadeCall is part of the FacadeCaller interface.
+func (c *monitoredFacadeCaller) FacadeCall(request string, args, response interface{}) error {
+	return c.monitor.call(func() error {
+		return c.FacadeCaller.FacadeCall(request, args, response)
+	})
+}
+
+// RawAPICaller is part of the FacadeCaller interface.
+func (c *monitoredFacadeCaller) RawAPICaller() APICaller {
+	return NewMonitoredAPICaller(c.FacadeCaller.RawAPICaller(), c.monitor)
+}
+
+// call runs f, returning ErrConnectionBroken without waiting for it to
+// complete if the connection is, or becomes, broken.
+func (m *ConnectionMonitor) call(f func() error) error {
+	select {
+	case <-m.dead:
+		return ErrConnectionBroken
+	default:
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-m.dead:
+		return ErrConnectionBroken
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	coretesting "github.com/juju/juju/testing"
+)
+
+const (
+	testPingInterval = 30 * time.Second
+	testPingTimeout  = 5 * time.Second
+)
+
+type monitorSuite struct {
+	jujutesting.IsolationSuite
+	clock *jujutesting.Clock
+
+	mu      sync.Mutex
+	pings   int
+	pingErr []error
+	release chan struct{}
+}
+
+var _ = gc.Suite(&monitorSuite{})
+
+func (s *monitorSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = jujutesting.NewClock(time.Time{})
+	s.pings = 0
+	s.pingErr = nil
+	s.release = make(chan struct{})
+	s.AddCleanup(func(*gc.C) { close(s.release) })
+}
+
+// caller returns an APICaller whose pings return the errors in
+// s.pingErr in turn, then succeed. Any other call blocks until the
+// test finishes, like a call on a half-dead connection.
+func (s *monitorSuite) caller(c *gc.C) base.APICaller {
+	return apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		if objType != "Pinger" {
+			<-s.release
+			return errors.New("released")
+		}
+		c.Check(request, gc.Equals, "Ping")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.pings++
+		if len(s.pingErr) == 0 {
+			return nil
+		}
+		err := s.pingErr[0]
+		s.pingErr = s.pingErr[1:]
+		return err
+	})
+}
+
+func (s *monitorSuite) config(c *gc.C) base.MonitorConfig {
+	return base.MonitorConfig{
+		Caller:       s.caller(c),
+		Clock:        s.clock,
+		PingInterval: testPingInterval,
+		PingTimeout:  testPingTimeout,
+		MaxMissed:    2,
+	}
+}
+
+func (s *monitorSuite) newMonitor(c *gc.C, errs ...error) *base.ConnectionMonitor {
+	s.pingErr = errs
+	m, err := base.NewConnectionMonitor(s.config(c))
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddCleanup(func(*gc.C) { m.Kill() })
+	return m
+}
+
+func (s *monitorSuite) waitForAlarm(c *gc.C) {
+	select {
+	case <-s.clock.Alarms():
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for clock")
+	}
+}
+
+// ping lets the monitor make its next ping, and waits for the ping to
+// start waiting for its timeout.
+func (s *monitorSuite) ping(c *gc.C) {
+	s.waitForAlarm(c)
+	s.clock.Advance(testPingInterval)
+	s.waitForAlarm(c)
+}
+
+func (s *monitorSuite) assertDead(c *gc.C, m *base.ConnectionMonitor) {
+	select {
+	case <-m.Dead():
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for monitor to declare connection broken")
+	}
+	c.Assert(m.Wait(), gc.Equals, base.ErrConnectionBroken)
+}
+
+func (s *monitorSuite) assertNotDead(c *gc.C, m *base.ConnectionMonitor) {
+	select {
+	case <-m.Dead():
+		c.Fatal("connection declared broken unexpectedly")
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func (s *monitorSuite) TestValidate(c *gc.C) {
+	for i, test := range []struct {
+		modify func(*base.MonitorConfig)
+		err    string
+	}{{
+		modify: func(config *base.MonitorConfig) { config.Caller = nil },
+		err:    "nil Caller not valid",
+	}, {
+		modify: func(config *base.MonitorConfig) { config.Clock = nil },
+		err:    "nil Clock not valid",
+	}, {
+		modify: func(config *base.MonitorConfig) { config.PingInterval = 0 },
+		err:    "non-positive PingInterval not valid",
+	}, {
+		modify: func(config *base.MonitorConfig) { config.PingTimeout = -time.Second },
+		err:    "non-positive PingTimeout not valid",
+	}, {
+		modify: func(config *base.MonitorConfig) { config.MaxMissed = 0 },
+		err:    "MaxMissed 0 not valid",
+	}} {
+		c.Logf("test %d", i)
+		config := s.config(c)
+		test.modify(&config)
+		_, err := base.NewConnectionMonitor(config)
+		c.Check(err, gc.ErrorMatches, test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+}
+
+func (s *monitorSuite) TestPing(c *gc.C) {
+	var calls []string
+	caller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		calls = append(calls, objType+"."+request)
+		return nil
+	})
+	c.Assert(base.Ping(caller), jc.ErrorIsNil)
+	c.Assert(calls, jc.DeepEquals, []string{"Pinger.Ping"})
+}
+
+func (s *monitorSuite) TestHealthyConnection(c *gc.C) {
+	m := s.newMonitor(c)
+	for i := 0; i < 5; i++ {
+		s.ping(c)
+	}
+	s.assertNotDead(c, m)
+	m.Kill()
+	c.Assert(m.Wait(), jc.ErrorIsNil)
+	s.assertNotDead(c, m)
+}
+
+func (s *monitorSuite) TestBrokenAfterMissedPings(c *gc.C) {
+	m := s.newMonitor(c, errors.New("boom"), errors.New("boom"))
+	s.ping(c)
+	s.assertNotDead(c, m)
+	s.ping(c)
+	s.assertDead(c, m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Assert(s.pings, gc.Equals, 2)
+}
+
+func (s *monitorSuite) TestSuccessResetsMissedPings(c *gc.C) {
+	m := s.newMonitor(c, errors.New("boom"), nil, errors.New("boom"), errors.New("boom"))
+	s.ping(c)
+	s.ping(c)
+	s.ping(c)
+	s.assertNotDead(c, m)
+	s.ping(c)
+	s.assertDead(c, m)
+}
+
+func (s *monitorSuite) TestPingTimeout(c *gc.C) {
+	config := s.config(c)
+	config.MaxMissed = 1
+	config.Caller = apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		<-s.release
+		return nil
+	})
+	m, err := base.NewConnectionMonitor(config)
+	c.Assert(err, jc.ErrorIsNil)
+	defer m.Kill()
+
+	s.ping(c)
+	s.assertNotDead(c, m)
+	s.clock.Advance(testPingTimeout)
+	s.assertDead(c, m)
+}
+
+func (s *monitorSuite) TestMonitoredAPICaller(c *gc.C) {
+	m := s.newMonitor(c, errors.New("boom"), errors.New("boom"))
+	caller := base.NewMonitoredAPICaller(s.caller(c), m)
+
+	// A call in progress fails as soon as the connection is
+	// declared broken.
+	result := make(chan error, 1)
+	go func() {
+		result <- caller.APICall("Facade", 1, "", "Method", nil, nil)
+	}()
+	s.ping(c)
+	s.ping(c)
+	select {
+	case err := <-result:
+		c.Assert(err, gc.Equals, base.ErrConnectionBroken)
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for call to fail")
+	}
+
+	// Later calls fail immediately.
+	err := caller.APICall("Facade", 1, "", "Method", nil, nil)
+	c.Assert(err, gc.Equals, base.ErrConnectionBroken)
+	err = base.NewMonitoredFacadeCaller(base.NewFacadeCaller(s.caller(c), "Facade"), m).FacadeCall("Method", nil, nil)
+	c.Assert(err, gc.Equals, base.ErrConnectionBroken)
+}
+
+func (s *monitorSuite) TestMonitoredAPICallerPassesResults(c *gc.C) {
+	m := s.newMonitor(c)
+	caller := base.NewMonitoredAPICaller(apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*string)) = objType + "." + request
+		return errors.New("failed")
+	}), m)
+	var result string
+	err := caller.APICall("Facade", 1, "", "Method", nil, &result)
+	c.Assert(err, gc.ErrorMatches, "failed")
+	c.Assert(result, gc.Equals, "Facade.Method")
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"time"
+
+	"github.com/juju/loggo"
+	"github.com/juju/utils/clock"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// CallInfo identifies a facade call reported to a CallObserver.
+type CallInfo struct {
+	Facade  string
+	Version int
+	Request string
+}
+
+// CallObserver is notified of the progress of facade calls, so that
+// clients can record metrics or trace slow or failing calls. It is
+// not given the arguments or responses of the calls it observes.
+// Its methods are called synchronously, so they should not block.
+type CallObserver interface {
+	// CallStarted is called before a call is made.
+	CallStarted(call CallInfo)
+
+	// CallSucceeded is called when a call completes without error.
+	CallSucceeded(call CallInfo, duration time.Duration)
+
+	// CallFailed is called when a call fails. The code holds the
+	// params error code of the error, if any.
+	CallFailed(call CallInfo, duration time.Duration, err error, code string)
+}
+
+// NewObservingFacadeCaller returns a FacadeCaller that wraps the given
+// one, reporting each call to the supplied observer and using clock to
+// time them. If the observer is nil, the facade is returned unchanged.
+func NewObservingFacadeCaller(facade FacadeCaller, observer CallObserver, clock clock.Clock) FacadeCaller {
+	if observer == nil {
+		return facade
+	}
+	return &observingFacadeCaller{
+		FacadeCaller: facade,
+		observer:     observer,
+		clock:        clock,
+	}
+}
+
+type observingFacadeCaller struct {
+	FacadeCaller
+	observer CallObserver
+	clock    clock.Clock
+}
+
+// FacadeCall is part of the FacadeCaller interface.
+func (c *observingFacadeCaller) FacadeCall(request string, args, response interface{}) error {
+	call := CallInfo{
+		Facade:  c.Name(),
+		Version: c.BestAPIVersion(),
+		Request: request,
+	}
+	c.observer.CallStarted(call)
+	start := c.clock.Now()
+	err := c.FacadeCaller.FacadeCall(request, args, response)
+	duration := c.clock.Now().Sub(start)
+	if err != nil {
+		c.observer.CallFailed(call, duration, err, params.ErrCode(err))
+	} else {
+		c.observer.CallSucceeded(call, duration)
+	}
+	return err
+}
+
+// NewLoggingCallObserver returns a CallObserver that logs each call to
+// the given logger: starts at TRACE level, completions at DEBUG level,
+// and failures at DEBUG level with the error and its code.
+func NewLoggingCallObserver(logger loggo.Logger) CallObserver {
+	return loggingCallObserver{logger}
+}
+
+type loggingCallObserver struct {
+	logger loggo.Logger
+}
+
+// CallStarted is part of the CallObserver interface.
+func (o loggingCallObserver) CallStarted(call CallInfo) {
+	o.logger.Tracef("%s(%d).%s started", call.Facade, call.Version, call.Request)
+}
+
+// CallSucceeded is part of the CallObserver interface.
+func (o loggingCallObserver) CallSucceeded(call CallInfo, duration time.Duration) {
+	o.logger.Debugf("%s(%d).%s succeeded in %v", call.Facade, call.Version, call.Request, duration)
+}
+
+// CallFailed is part of the CallObserver interface.
+func (o loggingCallObserver) CallFailed(call CallInfo, duration time.Duration, err error, code string) {
+	if code == "" {
+		code = "none"
+	}
+	o.logger.Debugf("%s(%d).%s failed in %v (code %s): %v", call.Facade, call.Version, call.Request, duration, code, err)
+}
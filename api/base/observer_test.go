@@ -0,0 +1,119 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/apiserver/params"
+)
+
+type observerSuite struct {
+	jujutesting.IsolationSuite
+	clock    *jujutesting.Clock
+	observer *recordingObserver
+}
+
+var _ = gc.Suite(&observerSuite{})
+
+func (s *observerSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = jujutesting.NewClock(time.Time{})
+	s.observer = &recordingObserver{}
+}
+
+func (s *observerSuite) facade(err error) base.FacadeCaller {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		s.observer.events = append(s.observer.events, "call")
+		s.clock.Advance(2 * time.Second)
+		return err
+	})
+	return base.NewObservingFacadeCaller(
+		base.NewFacadeCaller(apiCaller, "Facade"), s.observer, s.clock,
+	)
+}
+
+func (s *observerSuite) TestSuccess(c *gc.C) {
+	err := s.facade(nil).FacadeCall("Method", nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.observer.events, jc.DeepEquals, []string{
+		"started Facade(0).Method",
+		"call",
+		"succeeded Facade(0).Method in 2s",
+	})
+}
+
+func (s *observerSuite) TestFailure(c *gc.C) {
+	callErr := &params.Error{Code: params.CodeNotFound, Message: "not here"}
+	err := s.facade(callErr).FacadeCall("Method", nil, nil)
+	c.Assert(err, gc.Equals, callErr)
+	c.Check(s.observer.events, jc.DeepEquals, []string{
+		"started Facade(0).Method",
+		"call",
+		`failed Facade(0).Method in 2s with "not found": not here`,
+	})
+}
+
+func (s *observerSuite) TestFailureWithoutCode(c *gc.C) {
+	err := s.facade(errors.New("boom")).FacadeCall("Method", nil, nil)
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Check(s.observer.events, jc.DeepEquals, []string{
+		"started Facade(0).Method",
+		"call",
+		`failed Facade(0).Method in 2s with "": boom`,
+	})
+}
+
+func (s *observerSuite) TestNilObserver(c *gc.C) {
+	facade := base.NewFacadeCaller(apitesting.APICallerFunc(nil), "Facade")
+	c.Check(base.NewObservingFacadeCaller(facade, nil, s.clock), gc.Equals, facade)
+}
+
+func (s *observerSuite) TestLoggingObserver(c *gc.C) {
+	var tw loggo.TestWriter
+	c.Assert(loggo.RegisterWriter("observer-tester", &tw), gc.IsNil)
+	defer loggo.RemoveWriter("observer-tester")
+	logger := loggo.GetLogger("juju.api.base.observer-tester")
+	logger.SetLogLevel(loggo.TRACE)
+
+	observer := base.NewLoggingCallObserver(logger)
+	call := base.CallInfo{Facade: "Facade", Version: 2, Request: "Method"}
+	observer.CallStarted(call)
+	observer.CallSucceeded(call, time.Second)
+	observer.CallFailed(call, time.Second, errors.New("boom"), params.CodeTryAgain)
+	observer.CallFailed(call, time.Second, errors.New("boom"), "")
+	c.Check(tw.Log(), jc.LogMatches, []jc.SimpleMessage{
+		{loggo.TRACE, `Facade\(2\)\.Method started`},
+		{loggo.DEBUG, `Facade\(2\)\.Method succeeded in 1s`},
+		{loggo.DEBUG, `Facade\(2\)\.Method failed in 1s \(code try again\): boom`},
+		{loggo.DEBUG, `Facade\(2\)\.Method failed in 1s \(code none\): boom`},
+	})
+}
+
+// recordingObserver is a CallObserver that records the events it is
+// notified of.
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) CallStarted(call base.CallInfo) {
+	o.events = append(o.events, fmt.Sprintf("started %s(%d).%s", call.Facade, call.Version, call.Request))
+}
+
+func (o *recordingObserver) CallSucceeded(call base.CallInfo, duration time.Duration) {
+	o.events = append(o.events, fmt.Sprintf("succeeded %s(%d).%s in %v", call.Facade, call.Version, call.Request, duration))
+}
+
+func (o *recordingObserver) CallFailed(call base.CallInfo, duration time.Duration, err error, code string) {
+	o.events = append(o.events, fmt.Sprintf("failed %s(%d).%s in %v with %q: %v", call.Facade, call.Version, call.Request, duration, code, err))
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"reflect"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// The helpers below operate on the Results slice of a bulk call's
+// response, whose elements are structs with an Error field of type
+// *params.Error, such as params.ErrorResults.Results or
+// params.StringsWatchResults.Results. They panic if given anything
+// else, as that is a programming error.
+
+var paramsErrorType = reflect.TypeOf((*params.Error)(nil))
+
+// CheckResultCount returns an error if the given slice of results
+// does not hold the expected number of entries.
+func CheckResultCount(results interface{}, expected int) error {
+	if n := resultsValue(results).Len(); n != expected {
+		return errors.Errorf("expected %d result(s), got %d", expected, n)
+	}
+	return nil
+}
+
+// OneResult checks that the given slice of results holds exactly one
+// entry and returns its error, translated with TranslateError, if it
+// has one. Otherwise, if out is not nil, the entry is copied into the
+// value out points to.
+func OneResult(results interface{}, out interface{}) error {
+	v := resultsValue(results)
+	if n := v.Len(); n != 1 {
+		return errors.Errorf("expected 1 result, got %d", n)
+	}
+	result := v.Index(0)
+	if err := resultError(result); err != nil {
+		return err
+	}
+	if out != nil {
+		reflect.ValueOf(out).Elem().Set(result)
+	}
+	return nil
+}
+
+// CollectErrors returns the errors held in the given slice of results,
+// translated with TranslateError. The returned slice has an entry for
+// each result, which is nil for results without an error.
+func CollectErrors(results interface{}) []error {
+	v := resultsValue(results)
+	errs := make([]error, v.Len())
+	for i := range errs {
+		errs[i] = resultError(v.Index(i))
+	}
+	return errs
+}
+
+// FirstError returns the first non-nil error in errs, or nil if
+// there is none.
+func FirstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultsValue returns the reflected value of the given slice of
+// results.
+func resultsValue(results interface{}) reflect.Value {
+	v := reflect.ValueOf(results)
+	if v.Kind() != reflect.Slice {
+		panic(errors.Errorf("expected slice of results, got %T", results))
+	}
+	return v
+}
+
+// resultError returns the translated error held in the Error field
+// of the given result, if any.
+func resultError(result reflect.Value) error {
+	var field reflect.Value
+	if result.Kind() == reflect.Struct {
+		field = result.FieldByName("Error")
+	}
+	if !field.IsValid() || field.Type() != paramsErrorType {
+		panic(errors.Errorf("result type %s has no *params.Error Error field", result.Type()))
+	}
+	if field.IsNil() {
+		return nil
+	}
+	return TranslateError(field.Interface().(*params.Error))
+}
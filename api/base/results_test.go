@@ -0,0 +1,81 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+type resultsSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&resultsSuite{})
+
+func (s *resultsSuite) TestCheckResultCount(c *gc.C) {
+	results := []params.ErrorResult{{}, {}}
+	c.Check(base.CheckResultCount(results, 2), jc.ErrorIsNil)
+	c.Check(base.CheckResultCount(results, 1), gc.ErrorMatches, `expected 1 result\(s\), got 2`)
+	c.Check(base.CheckResultCount(results, 3), gc.ErrorMatches, `expected 3 result\(s\), got 2`)
+	c.Check(base.CheckResultCount([]params.ErrorResult(nil), 0), jc.ErrorIsNil)
+}
+
+func (s *resultsSuite) TestOneResult(c *gc.C) {
+	var out params.StringResult
+	err := base.OneResult([]params.StringResult{{Result: "foo"}}, &out)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(out, jc.DeepEquals, params.StringResult{Result: "foo"})
+}
+
+func (s *resultsSuite) TestOneResultNilOut(c *gc.C) {
+	err := base.OneResult([]params.ErrorResult{{}}, nil)
+	c.Check(err, jc.ErrorIsNil)
+}
+
+func (s *resultsSuite) TestOneResultError(c *gc.C) {
+	out := params.StringResult{Result: "untouched"}
+	err := base.OneResult([]params.StringResult{{
+		Result: "foo",
+		Error:  &params.Error{Code: params.CodeNotFound, Message: "foo not found"},
+	}}, &out)
+	c.Check(err, gc.ErrorMatches, "foo not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+	c.Check(out.Result, gc.Equals, "untouched")
+}
+
+func (s *resultsSuite) TestOneResultCountMismatch(c *gc.C) {
+	err := base.OneResult([]params.ErrorResult{}, nil)
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 0")
+	err = base.OneResult([]params.ErrorResult{{}, {}}, nil)
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 2")
+}
+
+func (s *resultsSuite) TestCollectErrors(c *gc.C) {
+	errs := base.CollectErrors([]params.ErrorResult{
+		{},
+		{Error: &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"}},
+		{Error: &params.Error{Message: "boom"}},
+	})
+	c.Assert(errs, gc.HasLen, 3)
+	c.Check(errs[0], jc.ErrorIsNil)
+	c.Check(errs[1], jc.Satisfies, errors.IsUnauthorized)
+	c.Check(errs[2], gc.ErrorMatches, "boom")
+	c.Check(base.FirstError(errs), gc.Equals, errs[1])
+}
+
+func (s *resultsSuite) TestFirstErrorNone(c *gc.C) {
+	c.Check(base.FirstError(nil), jc.ErrorIsNil)
+	c.Check(base.FirstError([]error{nil, nil}), jc.ErrorIsNil)
+}
+
+func (s *resultsSuite) TestInvalidResults(c *gc.C) {
+	c.Check(func() { base.CheckResultCount(params.ErrorResults{}, 1) }, gc.PanicMatches, "expected slice of results, got params.ErrorResults")
+	c.Check(func() { base.CollectErrors([]string{"foo"}) }, gc.PanicMatches, "result type string has no \\*params.Error Error field")
+}
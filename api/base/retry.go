@@ -0,0 +1,136 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/utils/clock"
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+)
+
+var logger = loggo.GetLogger("juju.api.base")
+
+// RetryConfig holds the configuration for a FacadeCaller that retries
+// calls failing with transient errors.
+type RetryConfig struct {
+	// Clock is used to wait between attempts.
+	Clock clock.Clock
+
+	// Attempts is the maximum number of times a call will be made.
+	Attempts int
+
+	// Delay is the time to wait after the first failed attempt. The
+	// delay is doubled after each subsequent failed attempt.
+	Delay time.Duration
+
+	// MaxDelay caps the time to wait between attempts.
+	MaxDelay time.Duration
+
+	// Stop, if non-nil, aborts any pending retry when closed; the
+	// most recent error is then returned.
+	Stop <-chan struct{}
+
+	// NonIdempotent holds the names of facade methods that must never
+	// be retried, because repeating them may not be safe.
+	NonIdempotent []string
+}
+
+// Validate returns an error if the config cannot be used to create
+// a retrying FacadeCaller.
+func (config RetryConfig) Validate() error {
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.Attempts < 1 {
+		return errors.NotValidf("Attempts %d", config.Attempts)
+	}
+	if config.Delay <= 0 {
+		return errors.NotValidf("non-positive Delay")
+	}
+	if config.MaxDelay < config.Delay {
+		return errors.NotValidf("MaxDelay less than Delay")
+	}
+	return nil
+}
+
+// NewRetryingFacadeCaller returns a FacadeCaller that wraps the given
+// one, retrying calls which fail with transport-level errors or with
+// params.CodeTryAgain, backing off exponentially between attempts.
+//
+// Calls failing with rpc.ErrShutdown are not retried: the connection
+// has been closed, and no call made on it can succeed again. It is up
+// to the caller to reconnect, and to make the call again on a facade
+// using the new connection.
+func NewRetryingFacadeCaller(facade FacadeCaller, config RetryConfig) (FacadeCaller, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &retryingFacadeCaller{
+		FacadeCaller:  facade,
+		config:        config,
+		nonIdempotent: set.NewStrings(config.NonIdempotent...),
+	}, nil
+}
+
+type retryingFacadeCaller struct {
+	FacadeCaller
+	config        RetryConfig
+	nonIdempotent set.Strings
+}
+
+// FacadeCall is part of the FacadeCaller interface.
+func (c *retryingFacadeCaller) FacadeCall(request string, args, response interface{}) error {
+	if c.nonIdempotent.Contains(request) {
+		return c.FacadeCaller.FacadeCall(request, args, response)
+	}
+	delay := c.config.Delay
+	for attempt := 1; ; attempt++ {
+		err := c.FacadeCaller.FacadeCall(request, args, response)
+		if err == nil || !isTransientError(err) {
+			return err
+		}
+		if attempt >= c.config.Attempts {
+			return errors.Annotatef(err, "%s failed after %d attempts", request, attempt)
+		}
+		logger.Debugf("%s attempt %d failed, retrying in %v: %v", request, attempt, delay, err)
+		select {
+		case <-c.config.Stop:
+			return errors.Annotatef(err, "%s stopped after %d attempts", request, attempt)
+		case <-c.config.Clock.After(delay):
+		}
+		delay *= 2
+		if delay > c.config.MaxDelay {
+			delay = c.config.MaxDelay
+		}
+	}
+}
+
+// isTransientError reports whether the error returned by a facade
+// call is likely to go away if the call is repeated.
+func isTransientError(err error) bool {
+	cause := errors.Cause(err)
+	if params.IsCodeTryAgain(cause) {
+		return true
+	}
+	switch cause {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return true
+	case rpc.ErrShutdown:
+		// The connection has gone for good; see
+		// NewRetryingFacadeCaller.
+		return false
+	}
+	if netErr, ok := cause.(net.Error); ok {
+		return netErr.Temporary() || netErr.Timeout()
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package base_test
+
+import (
+	"io"
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+)
+
+type retrySuite struct {
+	jujutesting.IsolationSuite
+	clock *recordingClock
+}
+
+var _ = gc.Suite(&retrySuite{})
+
+func (s *retrySuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = &recordingClock{Clock: jujutesting.NewClock(time.Time{})}
+}
+
+func (s *retrySuite) config() base.RetryConfig {
+	return base.RetryConfig{
+		Clock:    s.clock,
+		Attempts: 5,
+		Delay:    time.Second,
+		MaxDelay: 3 * time.Second,
+	}
+}
+
+func (s *retrySuite) facade(c *gc.C, config base.RetryConfig, errs ...error) (base.FacadeCaller, *int) {
+	var calls int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		calls++
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	})
+	facade, err := base.NewRetryingFacadeCaller(base.NewFacadeCaller(apiCaller, "Facade"), config)
+	c.Assert(err, jc.ErrorIsNil)
+	return facade, &calls
+}
+
+func (s *retrySuite) TestValidate(c *gc.C) {
+	for i, test := range []struct {
+		modify func(*base.RetryConfig)
+		err    string
+	}{{
+		modify: func(config *base.RetryConfig) { config.Clock = nil },
+		err:    "nil Clock not valid",
+	}, {
+		modify: func(config *base.RetryConfig) { config.Attempts = 0 },
+		err:    "Attempts 0 not valid",
+	}, {
+		modify: func(config *base.RetryConfig) { config.Delay = 0 },
+		err:    "non-positive Delay not valid",
+	}, {
+		modify: func(config *base.RetryConfig) { config.MaxDelay = time.Millisecond },
+		err:    "MaxDelay less than Delay not valid",
+	}} {
+		c.Logf("test %d", i)
+		config := s.config()
+		test.modify(&config)
+		_, err := base.NewRetryingFacadeCaller(nil, config)
+		c.Check(err, gc.ErrorMatches, test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+}
+
+func (s *retrySuite) TestSuccessFirstTime(c *gc.C) {
+	facade, calls := s.facade(c, s.config())
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(*calls, gc.Equals, 1)
+	c.Check(s.clock.delays, gc.HasLen, 0)
+}
+
+func (s *retrySuite) TestRetriesTransientErrors(c *gc.C) {
+	facade, calls := s.facade(c, s.config(),
+		io.EOF,
+		&params.Error{Code: params.CodeTryAgain, Message: "try again"},
+		io.ErrUnexpectedEOF,
+	)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(*calls, gc.Equals, 4)
+	c.Check(s.clock.delays, jc.DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 3 * time.Second,
+	})
+}
+
+func (s *retrySuite) TestDoesNotRetryPermanentErrors(c *gc.C) {
+	facade, calls := s.facade(c, s.config(),
+		&params.Error{Code: params.CodeNotFound, Message: "not found"},
+	)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.ErrorMatches, "not found")
+	c.Check(*calls, gc.Equals, 1)
+}
+
+func (s *retrySuite) TestDoesNotRetryConnectionShutdown(c *gc.C) {
+	facade, calls := s.facade(c, s.config(), rpc.ErrShutdown)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.Equals, rpc.ErrShutdown)
+	c.Check(*calls, gc.Equals, 1)
+	c.Check(s.clock.delays, gc.HasLen, 0)
+}
+
+func (s *retrySuite) TestDoesNotRetryNonIdempotent(c *gc.C) {
+	config := s.config()
+	config.NonIdempotent = []string{"Method"}
+	facade, calls := s.facade(c, config, io.EOF)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.Equals, io.EOF)
+	c.Check(*calls, gc.Equals, 1)
+}
+
+func (s *retrySuite) TestGivesUp(c *gc.C) {
+	config := s.config()
+	config.Attempts = 3
+	facade, calls := s.facade(c, config, io.EOF, io.EOF, io.EOF, io.EOF)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.ErrorMatches, "Method failed after 3 attempts: EOF")
+	c.Check(errors.Cause(err), gc.Equals, io.EOF)
+	c.Check(*calls, gc.Equals, 3)
+}
+
+func (s *retrySuite) TestStop(c *gc.C) {
+	stop := make(chan struct{})
+	close(stop)
+	config := s.config()
+	config.Clock = jujutesting.NewClock(time.Time{})
+	config.Stop = stop
+	facade, calls := s.facade(c, config, io.EOF, io.EOF)
+	err := facade.FacadeCall("Method", nil, nil)
+	c.Check(err, gc.ErrorMatches, "Method stopped after 1 attempts: EOF")
+	c.Check(*calls, gc.Equals, 1)
+}
+
+// recordingClock is a testing clock that records the durations it
+// is asked to wait for, and advances immediately past them.
+type recordingClock struct {
+	*jujutesting.Clock
+	delays []time.Duration
+}
+
+func (c *recordingClock) After(d time.Duration) <-chan time.Time {
+	c.delays = append(c.delays, d)
+	ch := c.Clock.After(d)
+	c.Advance(d)
+	return ch
+}
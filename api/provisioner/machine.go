@@ -254,6 +254,42 @@ func (m *Machine) SetInstanceInfo(
 	return result.OneError()
 }
 
+// SetProvisionerMetadata records details about how the machine's
+// instance was configured. Entries whose keys start with any of
+// secretPrefixes are discarded before the metadata is stored.
+func (m *Machine) SetProvisionerMetadata(metadata map[string]string, secretPrefixes ...string) error {
+	var result params.ErrorResults
+	args := params.ProvisionerMetadataArgs{
+		Args: []params.ProvisionerMetadataArg{{
+			Tag:            m.tag.String(),
+			Metadata:       metadata,
+			SecretPrefixes: secretPrefixes,
+		}},
+	}
+	err := m.st.facade.FacadeCall("SetProvisionerMetadata", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
+// SetAppliedInstanceTags records the tags that were set on the
+// machine's instance.
+func (m *Machine) SetAppliedInstanceTags(tags map[string]string) error {
+	var result params.ErrorResults
+	args := params.InstanceTagsArgs{
+		Args: []params.InstanceTagsArg{{
+			Tag:  m.tag.String(),
+			Tags: tags,
+		}},
+	}
+	err := m.st.facade.FacadeCall("SetAppliedInstanceTags", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
 // InstanceId returns the provider specific instance id for the
 // machine or an CodeNotProvisioned error, if not set.
 func (m *Machine) InstanceId() (instance.Id, error) {
@@ -0,0 +1,288 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/clock"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/watcher"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/catacomb"
+)
+
+// CacheConfig holds the configuration for a CachedState.
+type CacheConfig struct {
+	// Clock is used to expire cached entries.
+	Clock clock.Clock
+
+	// TTL is the maximum time for which a remote application's
+	// details are cached.
+	TTL time.Duration
+}
+
+// Validate returns an error if the config cannot be used to create a
+// CachedState.
+func (config CacheConfig) Validate() error {
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.TTL <= 0 {
+		return errors.NotValidf("non-positive TTL")
+	}
+	return nil
+}
+
+// CacheStats holds the number of cache hits and misses recorded by a
+// CachedState.
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// CachedState is a State that caches the details of remote
+// applications returned by RemoteApplications. An entry is discarded
+// when its TTL expires, when Refresh or Invalidate is called for it,
+// or when the watcher returned by WatchRemoteApplications reports a
+// change to the application. Errors are never cached, and calls made
+// with the context-aware variants of the State methods bypass the
+// cache.
+type CachedState struct {
+	*State
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	stats   CacheStats
+	// generation is incremented whenever entries are invalidated,
+	// so that results fetched before an invalidation are not
+	// cached after it.
+	generation uint64
+}
+
+type cacheEntry struct {
+	application params.RemoteApplication
+	expires     time.Time
+}
+
+// NewCachedState returns a CachedState that wraps the given State.
+func NewCachedState(st *State, config CacheConfig) (*CachedState, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &CachedState{
+		State:   st,
+		config:  config,
+		entries: make(map[string]cacheEntry),
+	}, nil
+}
+
+// RemoteApplications is part of the State API. Applications that are
+// not cached are fetched with a single call.
+func (c *CachedState) RemoteApplications(applications []string) ([]params.RemoteApplicationResult, error) {
+	results := make([]params.RemoteApplicationResult, len(applications))
+	var missing []string
+	var missingIndexes []int
+
+	c.mu.Lock()
+	now := c.config.Clock.Now()
+	for i, name := range applications {
+		entry, ok := c.entries[name]
+		if ok && now.Before(entry.expires) {
+			c.stats.Hits++
+			results[i].Result = copyRemoteApplication(entry.application)
+			continue
+		}
+		c.stats.Misses++
+		missing = append(missing, name)
+		missingIndexes = append(missingIndexes, i)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return results, nil
+	}
+	fetched, err := c.State.RemoteApplications(missing)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.store(missing, fetched, generation)
+	for i, result := range fetched {
+		results[missingIndexes[i]] = result
+	}
+	return results, nil
+}
+
+// Refresh discards any cached details of the remote application with
+// the given name and fetches them again.
+func (c *CachedState) Refresh(application string) error {
+	c.Invalidate(application)
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	fetched, err := c.State.RemoteApplications([]string{application})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.store([]string{application}, fetched, generation)
+	if err := fetched[0].Error; err != nil {
+		return base.TranslateError(err)
+	}
+	return nil
+}
+
+// Invalidate discards any cached details of the remote applications
+// with the given names.
+func (c *CachedState) Invalidate(applications ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, name := range applications {
+		delete(c.entries, name)
+	}
+}
+
+// Stats returns the number of cache hits and misses recorded so far.
+func (c *CachedState) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// store caches the successful results fetched for the given
+// applications, unless entries have been invalidated since the
+// given generation.
+func (c *CachedState) store(applications []string, fetched []params.RemoteApplicationResult, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	expires := c.config.Clock.Now().Add(c.config.TTL)
+	for i, result := range fetched {
+		if result.Error != nil || result.Result == nil {
+			continue
+		}
+		c.entries[applications[i]] = cacheEntry{
+			application: *copyRemoteApplication(*result.Result),
+			expires:     expires,
+		}
+	}
+}
+
+// copyRemoteApplication returns a copy of the given application that
+// shares no memory with it, so that cached entries cannot be changed
+// by callers.
+func copyRemoteApplication(application params.RemoteApplication) *params.RemoteApplication {
+	if application.Endpoints != nil {
+		endpoints := make([]params.RemoteEndpoint, len(application.Endpoints))
+		copy(endpoints, application.Endpoints)
+		application.Endpoints = endpoints
+	}
+	return &application
+}
+
+// WatchRemoteApplications is part of the State API. The cached details
+// of each application reported by the returned watcher are discarded
+// before the change is delivered.
+func (c *CachedState) WatchRemoteApplications() (watcher.StringsWatcher, error) {
+	source, err := c.State.WatchRemoteApplications()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newInvalidatingWatcher(source, "remote applications", func(applications []string) error {
+		c.Invalidate(applications...)
+		return nil
+	})
+}
+
+// newInvalidatingWatcher returns a StringsWatcher that forwards the
+// changes reported by source, the named watcher, after passing each
+// set of changes to invalidate. If invalidate fails, the watcher
+// stops with its error.
+func newInvalidatingWatcher(source watcher.StringsWatcher, name string, invalidate func([]string) error) (watcher.StringsWatcher, error) {
+	w := &invalidatingWatcher{
+		source:     source,
+		name:       name,
+		invalidate: invalidate,
+		out:        make(chan []string),
+	}
+	err := catacomb.Invoke(catacomb.Plan{
+		Site: &w.catacomb,
+		Work: w.loop,
+		Init: []worker.Worker{source},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+// invalidatingWatcher is a StringsWatcher that forwards the changes
+// reported by another, invalidating the corresponding cache entries
+// first.
+type invalidatingWatcher struct {
+	catacomb   catacomb.Catacomb
+	source     watcher.StringsWatcher
+	name       string
+	invalidate func([]string) error
+	out        chan []string
+}
+
+func (w *invalidatingWatcher) loop() error {
+	for {
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case changes, ok := <-w.source.Changes():
+			if !ok {
+				return errors.Errorf("%s watcher closed", w.name)
+			}
+			if err := w.invalidate(changes); err != nil {
+				return errors.Trace(err)
+			}
+			select {
+			case <-w.catacomb.Dying():
+				return w.catacomb.ErrDying()
+			case w.out <- changes:
+			}
+		}
+	}
+}
+
+// Changes is part of the watcher.StringsWatcher interface.
+func (w *invalidatingWatcher) Changes() watcher.StringsChannel {
+	return w.out
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *invalidatingWatcher) Kill() {
+	w.catacomb.Kill(nil)
+}
+
+// KillWithReason is part of the apiwatcher.ReasonKiller interface.
+func (w *invalidatingWatcher) KillWithReason(reason error) {
+	w.catacomb.Kill(reason)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *invalidatingWatcher) Wait() error {
+	return w.catacomb.Wait()
+}
+
+// Stop kills the watcher and waits for it to finish, returning the
+// error it finished with.
+//
+// Deprecated: use Kill and Wait, or worker.Stop.
+func (w *invalidatingWatcher) Stop() error {
+	w.Kill()
+	return w.Wait()
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type cachedStateSuite struct {
+	coretesting.BaseSuite
+
+	clock *jujutesting.Clock
+
+	mu      sync.Mutex
+	fetched [][]string
+	// next is sent to the StringsWatcher's Next calls.
+	next chan []string
+}
+
+var _ = gc.Suite(&cachedStateSuite{})
+
+func (s *cachedStateSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.clock = jujutesting.NewClock(time.Time{})
+	s.fetched = nil
+	s.next = make(chan []string)
+}
+
+func (s *cachedStateSuite) apiCaller(c *gc.C) apitesting.APICallerFunc {
+	stopped := make(chan struct{})
+	return apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch request {
+		case "RemoteApplications":
+			var requested []string
+			var results params.RemoteApplicationResults
+			for _, entity := range arg.(params.Entities).Entities {
+				tag, err := names.ParseApplicationTag(entity.Tag)
+				c.Assert(err, jc.ErrorIsNil)
+				name := tag.Id()
+				requested = append(requested, name)
+				if name == "unknown" {
+					results.Results = append(results.Results, params.RemoteApplicationResult{
+						Error: &params.Error{Code: params.CodeNotFound, Message: "unknown not found"},
+					})
+					continue
+				}
+				results.Results = append(results.Results, params.RemoteApplicationResult{
+					Result: &params.RemoteApplication{
+						Name:      name,
+						Life:      params.Alive,
+						Endpoints: []params.RemoteEndpoint{{Name: "db"}},
+					},
+				})
+			}
+			s.mu.Lock()
+			s.fetched = append(s.fetched, requested)
+			s.mu.Unlock()
+			*(result.(*params.RemoteApplicationResults)) = results
+		case "WatchRemoteApplications":
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: "66",
+				Changes:          []string{"mysql"},
+			}
+		case "Next":
+			select {
+			case changes := <-s.next:
+				out := (*result.(*interface{})).(*params.StringsWatchResult)
+				out.Changes = changes
+				return nil
+			case <-stopped:
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			}
+		case "Stop":
+			close(stopped)
+		default:
+			c.Errorf("unexpected request %q", request)
+		}
+		return nil
+	})
+}
+
+func (s *cachedStateSuite) newCachedState(c *gc.C) *remoterelations.CachedState {
+	st, err := remoterelations.NewCachedState(remoterelations.NewState(s.apiCaller(c)), remoterelations.CacheConfig{
+		Clock: s.clock,
+		TTL:   time.Minute,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return st
+}
+
+func (s *cachedStateSuite) fetchedNames() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetched
+}
+
+func (s *cachedStateSuite) TestInvalidConfig(c *gc.C) {
+	st := remoterelations.NewState(apitesting.APICallerFunc(nil))
+	_, err := remoterelations.NewCachedState(st, remoterelations.CacheConfig{TTL: time.Minute})
+	c.Check(err, gc.ErrorMatches, "nil Clock not valid")
+	_, err = remoterelations.NewCachedState(st, remoterelations.CacheConfig{Clock: s.clock})
+	c.Check(err, gc.ErrorMatches, "non-positive TTL not valid")
+}
+
+func (s *cachedStateSuite) TestCachesResults(c *gc.C) {
+	st := s.newCachedState(c)
+	results, err := st.RemoteApplications([]string{"mysql", "unknown"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 2)
+	c.Check(results[0].Result.Name, gc.Equals, "mysql")
+	c.Check(results[1].Error, gc.ErrorMatches, "unknown not found")
+
+	// Only the successful result is cached.
+	results, err = st.RemoteApplications([]string{"postgresql", "mysql", "unknown"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 3)
+	c.Check(results[0].Result.Name, gc.Equals, "postgresql")
+	c.Check(results[1].Result.Name, gc.Equals, "mysql")
+	c.Check(results[2].Error, gc.ErrorMatches, "unknown not found")
+	c.Check(s.fetchedNames(), jc.DeepEquals, [][]string{
+		{"mysql", "unknown"},
+		{"postgresql", "unknown"},
+	})
+	c.Check(st.Stats(), jc.DeepEquals, remoterelations.CacheStats{Hits: 1, Misses: 4})
+}
+
+func (s *cachedStateSuite) TestCachedEntriesNotShared(c *gc.C) {
+	st := s.newCachedState(c)
+	results, err := st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	results[0].Result.Name = "changed"
+	results[0].Result.Endpoints[0].Name = "changed"
+
+	results, err = st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(results[0].Result.Name, gc.Equals, "mysql")
+	c.Check(results[0].Result.Endpoints[0].Name, gc.Equals, "db")
+}
+
+func (s *cachedStateSuite) TestTTL(c *gc.C) {
+	st := s.newCachedState(c)
+	_, err := st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	s.clock.Advance(59 * time.Second)
+	_, err = st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.fetchedNames(), gc.HasLen, 1)
+
+	s.clock.Advance(time.Second)
+	_, err = st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.fetchedNames(), gc.HasLen, 2)
+}
+
+func (s *cachedStateSuite) TestRefresh(c *gc.C) {
+	st := s.newCachedState(c)
+	_, err := st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = st.Refresh("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.fetchedNames(), jc.DeepEquals, [][]string{{"mysql"}, {"mysql"}})
+	c.Check(st.Stats(), jc.DeepEquals, remoterelations.CacheStats{Hits: 1, Misses: 1})
+}
+
+func (s *cachedStateSuite) TestRefreshError(c *gc.C) {
+	st := s.newCachedState(c)
+	err := st.Refresh("unknown")
+	c.Check(err, gc.ErrorMatches, "unknown not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *cachedStateSuite) TestInvalidate(c *gc.C) {
+	st := s.newCachedState(c)
+	_, err := st.RemoteApplications([]string{"mysql", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+	st.Invalidate("mysql")
+	_, err = st.RemoteApplications([]string{"mysql", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.fetchedNames(), jc.DeepEquals, [][]string{{"mysql", "postgresql"}, {"mysql"}})
+}
+
+func (s *cachedStateSuite) TestWatcherInvalidates(c *gc.C) {
+	st := s.newCachedState(c)
+	_, err := st.RemoteApplications([]string{"mysql", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	w, err := st.WatchRemoteApplications()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+	assertChange := func(expect ...string) {
+		select {
+		case changes := <-w.Changes():
+			c.Check(changes, jc.DeepEquals, expect)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for change")
+		}
+	}
+	assertChange("mysql")
+	_, err = st.RemoteApplications([]string{"mysql", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.next <- []string{"postgresql"}
+	assertChange("postgresql")
+	_, err = st.RemoteApplications([]string{"mysql", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Check(s.fetchedNames(), jc.DeepEquals, [][]string{
+		{"mysql", "postgresql"},
+		{"mysql"},
+		{"postgresql"},
+	})
+}
+
+func (s *cachedStateSuite) TestConcurrentReaders(c *gc.C) {
+	st := s.newCachedState(c)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				results, err := st.RemoteApplications([]string{"mysql"})
+				c.Check(err, jc.ErrorIsNil)
+				c.Check(results[0].Result.Endpoints, jc.DeepEquals, []params.RemoteEndpoint{{Name: "db"}})
+				st.Invalidate("mysql")
+			}
+		}()
+	}
+	wg.Wait()
+	stats := st.Stats()
+	c.Check(stats.Hits+stats.Misses, gc.Equals, 100)
+}
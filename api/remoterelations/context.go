@@ -0,0 +1,176 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"golang.org/x/net/context"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/status"
+	"github.com/juju/juju/watcher"
+)
+
+// withContext returns a copy of the State whose facade calls are
+// abandoned when the given context is done.
+func (st *State) withContext(ctx context.Context) *State {
+	withContext := *st
+	withContext.facade = base.NewContextFacadeCaller(ctx, st.facade)
+	return &withContext
+}
+
+// WatchRemoteApplicationsCtx is WatchRemoteApplications, abandoning
+// the call if the context is done before it completes.
+func (st *State) WatchRemoteApplicationsCtx(ctx context.Context) (watcher.StringsWatcher, error) {
+	return st.withContext(ctx).WatchRemoteApplications()
+}
+
+// WatchRemoteRelationsCtx is WatchRemoteRelations, abandoning the
+// call if the context is done before it completes.
+func (st *State) WatchRemoteRelationsCtx(ctx context.Context) (watcher.StringsWatcher, error) {
+	return st.withContext(ctx).WatchRemoteRelations()
+}
+
+// WatchRemoteApplicationRelationsCtx is
+// WatchRemoteApplicationRelations, abandoning the call if the context
+// is done before it completes.
+func (st *State) WatchRemoteApplicationRelationsCtx(ctx context.Context, application string) (watcher.StringsWatcher, error) {
+	return st.withContext(ctx).WatchRemoteApplicationRelations(application)
+}
+
+// WatchRelationsForRemoteApplicationsCtx is
+// WatchRelationsForRemoteApplications, abandoning the call if the
+// context is done before it completes.
+func (st *State) WatchRelationsForRemoteApplicationsCtx(ctx context.Context, applications []string) ([]watcher.StringsWatcher, []error, error) {
+	return st.withContext(ctx).WatchRelationsForRemoteApplications(applications)
+}
+
+// WatchApplicationRelationsCtx is WatchApplicationRelations, abandoning
+// the call if the context is done before it completes.
+func (st *State) WatchApplicationRelationsCtx(ctx context.Context, application string) (watcher.ApplicationRelationsWatcher, error) {
+	return st.withContext(ctx).WatchApplicationRelations(application)
+}
+
+// WatchLocalRelationUnitsCtx is WatchLocalRelationUnits, abandoning
+// the call if the context is done before it completes.
+func (st *State) WatchLocalRelationUnitsCtx(ctx context.Context, relationKey string) (watcher.RelationUnitsWatcher, error) {
+	return st.withContext(ctx).WatchLocalRelationUnits(relationKey)
+}
+
+// WatchRelationSuspendedStatusCtx is WatchRelationSuspendedStatus,
+// abandoning the call if the context is done before it completes.
+func (st *State) WatchRelationSuspendedStatusCtx(ctx context.Context, relationKey string) (watcher.RelationStatusWatcher, error) {
+	return st.withContext(ctx).WatchRelationSuspendedStatus(relationKey)
+}
+
+// WatchOfferStatusCtx is WatchOfferStatus, abandoning the call if the
+// context is done before it completes.
+func (st *State) WatchOfferStatusCtx(ctx context.Context, offerUUID string) (watcher.OfferStatusWatcher, error) {
+	return st.withContext(ctx).WatchOfferStatus(offerUUID)
+}
+
+// RelationUnitSettingsCtx is RelationUnitSettings, abandoning the
+// call if the context is done before it completes.
+func (st *State) RelationUnitSettingsCtx(ctx context.Context, relationUnits []params.RelationUnit) ([]params.SettingsResult, error) {
+	return st.withContext(ctx).RelationUnitSettings(relationUnits)
+}
+
+// ConsumeRemoteRelationChangeCtx is ConsumeRemoteRelationChange,
+// abandoning the call if the context is done before it completes.
+func (st *State) ConsumeRemoteRelationChangeCtx(ctx context.Context, change params.RemoteRelationChangeEvent) error {
+	return st.withContext(ctx).ConsumeRemoteRelationChange(change)
+}
+
+// ExportEntitiesCtx is ExportEntities, abandoning the call if the
+// context is done before it completes.
+func (st *State) ExportEntitiesCtx(ctx context.Context, tags []names.Tag) ([]params.TokenResult, error) {
+	return st.withContext(ctx).ExportEntities(tags)
+}
+
+// GetTokenCtx is GetToken, abandoning the call if the context is
+// done before it completes.
+func (st *State) GetTokenCtx(ctx context.Context, tag names.Tag) (string, error) {
+	return st.withContext(ctx).GetToken(tag)
+}
+
+// GetTokensCtx is GetTokens, abandoning the call if the context is
+// done before it completes.
+func (st *State) GetTokensCtx(ctx context.Context, tags []names.Tag) ([]params.StringResult, error) {
+	return st.withContext(ctx).GetTokens(tags)
+}
+
+// RelationKeyCtx is RelationKey, abandoning the call if the context is
+// done before it completes.
+func (st *State) RelationKeyCtx(ctx context.Context, token string) (string, error) {
+	return st.withContext(ctx).RelationKey(token)
+}
+
+// ImportRemoteEntityCtx is ImportRemoteEntity, abandoning the call
+// if the context is done before it completes.
+func (st *State) ImportRemoteEntityCtx(ctx context.Context, tag names.Tag, token string) error {
+	return st.withContext(ctx).ImportRemoteEntity(tag, token)
+}
+
+// SaveMacaroonCtx is SaveMacaroon, abandoning the call if the context
+// is done before it completes.
+func (st *State) SaveMacaroonCtx(ctx context.Context, entity names.Tag, mac *macaroon.Macaroon) error {
+	return st.withContext(ctx).SaveMacaroon(entity, mac)
+}
+
+// SetRemoteApplicationsStatusCtx is SetRemoteApplicationsStatus,
+// abandoning the call if the context is done before it completes.
+func (st *State) SetRemoteApplicationsStatusCtx(ctx context.Context, statuses map[string]status.StatusInfo) error {
+	return st.withContext(ctx).SetRemoteApplicationsStatus(statuses)
+}
+
+// SetRelationsSuspendedCtx is SetRelationsSuspended, abandoning the
+// call if the context is done before it completes.
+func (st *State) SetRelationsSuspendedCtx(ctx context.Context, relationKeys []string, suspended bool, reason string) error {
+	return st.withContext(ctx).SetRelationsSuspended(relationKeys, suspended, reason)
+}
+
+// RemoteApplicationsCtx is RemoteApplications, abandoning the call if
+// the context is done before it completes.
+func (st *State) RemoteApplicationsCtx(ctx context.Context, applications []string) ([]params.RemoteApplicationResult, error) {
+	return st.withContext(ctx).RemoteApplications(applications)
+}
+
+// RelationsCtx is Relations, abandoning the call if the context is
+// done before it completes.
+func (st *State) RelationsCtx(ctx context.Context, keys []string) ([]params.RemoteRelationResult, error) {
+	return st.withContext(ctx).Relations(keys)
+}
+
+// OfferConnectionsCtx is OfferConnections, abandoning the call if the
+// context is done before it completes.
+func (st *State) OfferConnectionsCtx(ctx context.Context, offerUUIDs []string) ([]OfferConnectionsResult, error) {
+	return st.withContext(ctx).OfferConnections(offerUUIDs)
+}
+
+// ControllerAPIInfoForModelCtx is ControllerAPIInfoForModel,
+// abandoning the call if the context is done before it completes.
+func (st *State) ControllerAPIInfoForModelCtx(ctx context.Context, modelUUID string) (*ControllerInfo, error) {
+	return st.withContext(ctx).ControllerAPIInfoForModel(modelUUID)
+}
+
+// UpdateControllerForModelCtx is UpdateControllerForModel, abandoning
+// the call if the context is done before it completes.
+func (st *State) UpdateControllerForModelCtx(ctx context.Context, controllerInfo ControllerInfo, modelUUID string) error {
+	return st.withContext(ctx).UpdateControllerForModel(controllerInfo, modelUUID)
+}
+
+// WatchEgressAddressesForRelationCtx is
+// WatchEgressAddressesForRelation, abandoning the call if the context
+// is done before it completes.
+func (st *State) WatchEgressAddressesForRelationCtx(ctx context.Context, relationKey string) (watcher.StringsWatcher, error) {
+	return st.withContext(ctx).WatchEgressAddressesForRelation(relationKey)
+}
+
+// PublishIngressNetworkChangeCtx is PublishIngressNetworkChange,
+// abandoning the call if the context is done before it completes.
+func (st *State) PublishIngressNetworkChangeCtx(ctx context.Context, change params.IngressNetworksChangeEvent, allowOpen bool) error {
+	return st.withContext(ctx).PublishIngressNetworkChange(change, allowOpen)
+}
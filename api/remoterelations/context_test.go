@@ -0,0 +1,83 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"golang.org/x/net/context"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+var _ = gc.Suite(&contextSuite{})
+
+type contextSuite struct {
+	coretesting.BaseSuite
+}
+
+func (s *contextSuite) TestCallWithContext(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(request, gc.Equals, "RemoteApplications")
+		*(result.(*params.RemoteApplicationResults)) = params.RemoteApplicationResults{
+			Results: []params.RemoteApplicationResult{{
+				Result: &params.RemoteApplication{Name: "mysql"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	results, err := st.RemoteApplicationsCtx(context.Background(), []string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Check(results[0].Result.Name, gc.Equals, "mysql")
+}
+
+func (s *contextSuite) TestWatchWithCancelledContext(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteApplicationsCtx(ctx)
+	c.Check(errors.Cause(err), gc.Equals, context.Canceled)
+}
+
+func (s *contextSuite) TestCancelMidCall(c *gc.C) {
+	called := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		close(called)
+		<-release
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	st := remoterelations.NewState(apiCaller)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := st.WatchRemoteApplicationsCtx(ctx)
+		errc <- err
+	}()
+	select {
+	case <-called:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for call")
+	}
+	cancel()
+	select {
+	case err := <-errc:
+		c.Check(errors.Cause(err), gc.Equals, context.Canceled)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("call not abandoned after cancellation")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cert"
+)
+
+// ControllerInfo holds the details needed to connect to the API
+// servers of an external controller.
+type ControllerInfo struct {
+	// ControllerTag identifies the controller.
+	ControllerTag names.ControllerTag
+
+	// Alias holds a human friendly name for the controller.
+	Alias string
+
+	// Addrs holds the addresses of the controller's API servers.
+	Addrs []string
+
+	// CACert holds the CA certificate, in PEM format, used to
+	// validate the API servers' certificates.
+	CACert string
+}
+
+// Validate returns an error if the controller info is missing details
+// needed to connect to the controller.
+func (info ControllerInfo) Validate() error {
+	if info.ControllerTag.Id() == "" {
+		return errors.NotValidf("empty controller tag")
+	}
+	if len(info.Addrs) == 0 {
+		return errors.NotValidf("empty controller addresses")
+	}
+	if _, err := cert.ParseCert(info.CACert); err != nil {
+		return errors.NewNotValid(err, "controller CA certificate not valid")
+	}
+	return nil
+}
+
+// ControllerAPIInfoForModel returns the details needed to connect to
+// the controller hosting the model with the given UUID. If the model
+// is not known, an error satisfying errors.IsNotFound is returned.
+func (st *State) ControllerAPIInfoForModel(modelUUID string) (*ControllerInfo, error) {
+	if !names.IsValidModel(modelUUID) {
+		return nil, errors.NotValidf("model UUID %q", modelUUID)
+	}
+	modelTag := names.NewModelTag(modelUUID)
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: modelTag.String()}},
+	}
+	var results params.ControllerAPIInfoResults
+	err := st.facade.FacadeCall("ControllerAPIInfoForModels", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.ControllerAPIInfoResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(result.Addresses) == 0 {
+		return nil, errors.Errorf("no API addresses for controller of model %q", modelUUID)
+	}
+	controllerTag, err := names.ParseControllerTag(result.ControllerTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &ControllerInfo{
+		ControllerTag: controllerTag,
+		Addrs:         result.Addresses,
+		CACert:        result.CACert,
+	}, nil
+}
+
+// UpdateControllerForModel records that the model with the given UUID
+// is hosted by the controller described by controllerInfo.
+func (st *State) UpdateControllerForModel(controllerInfo ControllerInfo, modelUUID string) error {
+	if !names.IsValidModel(modelUUID) {
+		return errors.NotValidf("model UUID %q", modelUUID)
+	}
+	if err := controllerInfo.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	args := params.UpdateControllersForModelsParams{
+		Changes: []params.UpdateControllerForModel{{
+			ModelTag: names.NewModelTag(modelUUID).String(),
+			Info: params.ExternalControllerInfo{
+				ControllerTag: controllerInfo.ControllerTag.String(),
+				Alias:         controllerInfo.Alias,
+				Addrs:         controllerInfo.Addrs,
+				CACert:        controllerInfo.CACert,
+			},
+		}},
+	}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("UpdateControllersForModels", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	return errors.Trace(base.OneResult(results.Results, nil))
+}
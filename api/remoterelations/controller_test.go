@@ -0,0 +1,175 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+func (s *remoteRelationsSuite) TestControllerAPIInfoForModel(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "ControllerAPIInfoForModels")
+		c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+			{Tag: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.ControllerAPIInfoResults{})
+		*(result.(*params.ControllerAPIInfoResults)) = params.ControllerAPIInfoResults{
+			Results: []params.ControllerAPIInfoResult{{
+				ControllerTag: coretesting.ControllerTag.String(),
+				Addresses:     []string{"1.2.3.4:17070"},
+				CACert:        coretesting.CACert,
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	info, err := st.ControllerAPIInfoForModel(coretesting.ModelTag.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+	c.Check(info, jc.DeepEquals, &remoterelations.ControllerInfo{
+		ControllerTag: coretesting.ControllerTag,
+		Addrs:         []string{"1.2.3.4:17070"},
+		CACert:        coretesting.CACert,
+	})
+}
+
+func (s *remoteRelationsSuite) TestControllerAPIInfoForModelNotFound(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ControllerAPIInfoResults)) = params.ControllerAPIInfoResults{
+			Results: []params.ControllerAPIInfoResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "model not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ControllerAPIInfoForModel(coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, "model not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestControllerAPIInfoForModelNoAddresses(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ControllerAPIInfoResults)) = params.ControllerAPIInfoResults{
+			Results: []params.ControllerAPIInfoResult{{
+				ControllerTag: coretesting.ControllerTag.String(),
+				CACert:        coretesting.CACert,
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ControllerAPIInfoForModel(coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, `no API addresses for controller of model ".*"`)
+}
+
+func (s *remoteRelationsSuite) TestControllerAPIInfoForModelInvalidUUID(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ControllerAPIInfoForModel("foo")
+	c.Check(err, gc.ErrorMatches, `model UUID "foo" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestControllerAPIInfoForModelResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ControllerAPIInfoResults)) = params.ControllerAPIInfoResults{
+			Results: []params.ControllerAPIInfoResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ControllerAPIInfoForModel(coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 2")
+}
+
+func (s *remoteRelationsSuite) controllerInfo() remoterelations.ControllerInfo {
+	return remoterelations.ControllerInfo{
+		ControllerTag: coretesting.ControllerTag,
+		Alias:         "offerer",
+		Addrs:         []string{"1.2.3.4:17070", "5.6.7.8:17070"},
+		CACert:        coretesting.CACert,
+	}
+}
+
+func (s *remoteRelationsSuite) TestUpdateControllerForModel(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "UpdateControllersForModels")
+		c.Check(arg, jc.DeepEquals, params.UpdateControllersForModelsParams{
+			Changes: []params.UpdateControllerForModel{{
+				ModelTag: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+				Info: params.ExternalControllerInfo{
+					ControllerTag: "controller-deadbeef-1bad-500d-9000-4b1d0d06f00d",
+					Alias:         "offerer",
+					Addrs:         []string{"1.2.3.4:17070", "5.6.7.8:17070"},
+					CACert:        coretesting.CACert,
+				},
+			}},
+		})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.UpdateControllerForModel(s.controllerInfo(), coretesting.ModelTag.Id())
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestUpdateControllerForModelResultError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.UpdateControllerForModel(s.controllerInfo(), coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, "permission denied")
+	c.Check(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *remoteRelationsSuite) TestUpdateControllerForModelInvalid(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+
+	err := st.UpdateControllerForModel(s.controllerInfo(), "foo")
+	c.Check(err, gc.ErrorMatches, `model UUID "foo" not valid`)
+
+	info := s.controllerInfo()
+	info.Addrs = nil
+	err = st.UpdateControllerForModel(info, coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, "empty controller addresses not valid")
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+
+	info = s.controllerInfo()
+	info.CACert = "not a certificate"
+	err = st.UpdateControllerForModel(info, coretesting.ModelTag.Id())
+	c.Check(err, gc.ErrorMatches, "controller CA certificate not valid: no certificates found")
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
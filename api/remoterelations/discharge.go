@@ -0,0 +1,94 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// DischargeRequiredError is returned by State when the controller
+// will not authorise a call until a third party caveat of the
+// macaroon used for a cross-model relation is discharged, typically
+// because the previous discharge has expired.
+type DischargeRequiredError struct {
+	// Message holds the reason the controller gave for requiring
+	// a discharge.
+	Message string
+
+	// Macaroon holds the macaroon that must be discharged.
+	Macaroon *macaroon.Macaroon
+}
+
+// Error implements error.
+func (e *DischargeRequiredError) Error() string {
+	return e.Message
+}
+
+// IsDischargeRequired reports whether err is a *DischargeRequiredError.
+func IsDischargeRequired(err error) bool {
+	_, ok := errors.Cause(err).(*DischargeRequiredError)
+	return ok
+}
+
+// translateDischargeRequired returns a *DischargeRequiredError if err
+// is a params.Error with params.CodeDischargeRequired that carries a
+// macaroon, and err otherwise.
+func translateDischargeRequired(err error) error {
+	apiErr, ok := errors.Cause(err).(*params.Error)
+	if !ok || apiErr == nil || apiErr.Code != params.CodeDischargeRequired {
+		return err
+	}
+	if apiErr.Info == nil || apiErr.Info.Macaroon == nil {
+		return err
+	}
+	return &DischargeRequiredError{
+		Message:  apiErr.Message,
+		Macaroon: apiErr.Info.Macaroon,
+	}
+}
+
+// DischargeHandler is called by a State returned by
+// WithDischargeHandler when a call fails because a discharge is
+// required. It must acquire a discharge for the macaroon in err, and
+// return the entity the macaroon authorises access to, along with the
+// macaroon to save for it.
+type DischargeHandler func(err *DischargeRequiredError) (names.Tag, *macaroon.Macaroon, error)
+
+// WithDischargeHandler returns a copy of the State which handles
+// DischargeRequiredErrors returned by ConsumeRemoteRelationChange and
+// RelationUnitSettings by calling handler, saving the macaroon it
+// returns with SaveMacaroon, and making the call again. Each call is
+// retried at most once; if the retried call also requires a
+// discharge, its DischargeRequiredError is returned.
+func (st *State) WithDischargeHandler(handler DischargeHandler) *State {
+	handled := *st
+	handled.dischargeHandler = handler
+	return &handled
+}
+
+// withDischarge runs call, retrying it once after acquiring and
+// saving a discharge if it fails with a DischargeRequiredError and
+// the State has a DischargeHandler.
+func (st *State) withDischarge(call func() error) error {
+	err := call()
+	if st.dischargeHandler == nil {
+		return err
+	}
+	dischargeErr, ok := errors.Cause(err).(*DischargeRequiredError)
+	if !ok {
+		return err
+	}
+	entity, mac, err := st.dischargeHandler(dischargeErr)
+	if err != nil {
+		return errors.Annotate(err, "cannot acquire discharge")
+	}
+	if err := st.SaveMacaroon(entity, mac); err != nil {
+		return errors.Annotate(err, "cannot save discharged macaroon")
+	}
+	return call()
+}
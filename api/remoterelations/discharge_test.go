@@ -0,0 +1,192 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/macaroon.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type dischargeSuite struct {
+	coretesting.BaseSuite
+
+	expired    *macaroon.Macaroon
+	discharged *macaroon.Macaroon
+	relation   names.RelationTag
+}
+
+var _ = gc.Suite(&dischargeSuite{})
+
+func (s *dischargeSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	var err error
+	s.expired, err = macaroon.New([]byte("secret"), "expired", "location")
+	c.Assert(err, jc.ErrorIsNil)
+	s.discharged, err = macaroon.New([]byte("secret"), "discharged", "location")
+	c.Assert(err, jc.ErrorIsNil)
+	s.relation = names.NewRelationTag("wordpress:db mysql:db")
+}
+
+func (s *dischargeSuite) dischargeRequired() *params.Error {
+	return &params.Error{
+		Code:    params.CodeDischargeRequired,
+		Message: "macaroon expired",
+		Info:    &params.ErrorInfo{Macaroon: s.expired},
+	}
+}
+
+// dischargeCaller is a fake API caller whose calls to the method
+// under test fail with a discharge required error the given number of
+// times, and then succeed.
+type dischargeCaller struct {
+	c        *gc.C
+	suite    *dischargeSuite
+	failures int
+	calls    []string
+	saved    []params.EntityMacaroonArg
+}
+
+func (f *dischargeCaller) call(objType string, version int, id, request string, arg, result interface{}) error {
+	f.calls = append(f.calls, request)
+	switch request {
+	case "SaveMacaroons":
+		f.saved = append(f.saved, arg.(params.EntityMacaroonArgs).Args...)
+		*(result.(*params.ErrorResults)) = params.ErrorResults{Results: []params.ErrorResult{{}}}
+		return nil
+	case "ConsumeRemoteRelationChange":
+		var err *params.Error
+		if f.failures > 0 {
+			f.failures--
+			err = f.suite.dischargeRequired()
+		}
+		*(result.(*params.ErrorResults)) = params.ErrorResults{Results: []params.ErrorResult{{Error: err}}}
+		return nil
+	case "RelationUnitSettings":
+		if f.failures > 0 {
+			f.failures--
+			return f.suite.dischargeRequired()
+		}
+		*(result.(*params.SettingsResults)) = params.SettingsResults{
+			Results: []params.SettingsResult{{Settings: params.Settings{"foo": "bar"}}},
+		}
+		return nil
+	}
+	f.c.Fatalf("unexpected request %q", request)
+	return nil
+}
+
+func (s *dischargeSuite) newState(c *gc.C, failures int) (*remoterelations.State, *dischargeCaller) {
+	caller := &dischargeCaller{c: c, suite: s, failures: failures}
+	return remoterelations.NewState(apitesting.APICallerFunc(caller.call)), caller
+}
+
+// handler returns a DischargeHandler that records the errors it is
+// called with, and discharges for s.relation.
+func (s *dischargeSuite) handler(handled *[]*remoterelations.DischargeRequiredError) remoterelations.DischargeHandler {
+	return func(err *remoterelations.DischargeRequiredError) (names.Tag, *macaroon.Macaroon, error) {
+		*handled = append(*handled, err)
+		return s.relation, s.discharged, nil
+	}
+}
+
+var dischargeChange = params.RemoteRelationChangeEvent{
+	RelationToken:    "rel-token",
+	ApplicationToken: "app-token",
+}
+
+func (s *dischargeSuite) TestDischargeRequiredError(c *gc.C) {
+	st, _ := s.newState(c, 1)
+	err := st.ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, gc.ErrorMatches, "macaroon expired")
+	c.Assert(err, jc.Satisfies, remoterelations.IsDischargeRequired)
+	dischargeErr, ok := errors.Cause(err).(*remoterelations.DischargeRequiredError)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(dischargeErr.Macaroon, gc.Equals, s.expired)
+}
+
+func (s *dischargeSuite) TestDischargeRequiredWithoutMacaroon(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return &params.Error{Code: params.CodeDischargeRequired, Message: "no macaroon"}
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, gc.ErrorMatches, "no macaroon")
+	c.Assert(err, gc.Not(jc.Satisfies), remoterelations.IsDischargeRequired)
+}
+
+func (s *dischargeSuite) TestConsumeRemoteRelationChangeRetried(c *gc.C) {
+	st, caller := s.newState(c, 1)
+	var handled []*remoterelations.DischargeRequiredError
+	err := st.WithDischargeHandler(s.handler(&handled)).ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(handled, gc.HasLen, 1)
+	c.Assert(handled[0].Macaroon, gc.Equals, s.expired)
+	c.Assert(caller.calls, jc.DeepEquals, []string{
+		"ConsumeRemoteRelationChange", "SaveMacaroons", "ConsumeRemoteRelationChange",
+	})
+	c.Assert(caller.saved, jc.DeepEquals, []params.EntityMacaroonArg{{
+		Tag: s.relation.String(), Macaroon: s.discharged,
+	}})
+}
+
+func (s *dischargeSuite) TestRelationUnitSettingsRetried(c *gc.C) {
+	st, caller := s.newState(c, 1)
+	var handled []*remoterelations.DischargeRequiredError
+	results, err := st.WithDischargeHandler(s.handler(&handled)).RelationUnitSettings([]params.RelationUnit{{
+		Relation: s.relation.String(),
+		Unit:     "unit-wordpress-0",
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Settings, jc.DeepEquals, params.Settings{"foo": "bar"})
+	c.Assert(handled, gc.HasLen, 1)
+	c.Assert(caller.calls, jc.DeepEquals, []string{
+		"RelationUnitSettings", "SaveMacaroons", "RelationUnitSettings",
+	})
+}
+
+func (s *dischargeSuite) TestRetriedOnlyOnce(c *gc.C) {
+	st, caller := s.newState(c, 2)
+	var handled []*remoterelations.DischargeRequiredError
+	err := st.WithDischargeHandler(s.handler(&handled)).ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, jc.Satisfies, remoterelations.IsDischargeRequired)
+	c.Assert(handled, gc.HasLen, 1)
+	c.Assert(caller.calls, jc.DeepEquals, []string{
+		"ConsumeRemoteRelationChange", "SaveMacaroons", "ConsumeRemoteRelationChange",
+	})
+}
+
+func (s *dischargeSuite) TestHandlerError(c *gc.C) {
+	st, caller := s.newState(c, 1)
+	handler := func(*remoterelations.DischargeRequiredError) (names.Tag, *macaroon.Macaroon, error) {
+		return nil, nil, errors.New("third party unavailable")
+	}
+	err := st.WithDischargeHandler(handler).ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, gc.ErrorMatches, "cannot acquire discharge: third party unavailable")
+	c.Assert(caller.calls, jc.DeepEquals, []string{"ConsumeRemoteRelationChange"})
+}
+
+func (s *dischargeSuite) TestOtherErrorsNotRetried(c *gc.C) {
+	var calls int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		calls++
+		return &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"}
+	})
+	handler := func(*remoterelations.DischargeRequiredError) (names.Tag, *macaroon.Macaroon, error) {
+		c.Fatalf("unexpected discharge")
+		return nil, nil, nil
+	}
+	st := remoterelations.NewState(apiCaller).WithDischargeHandler(handler)
+	err := st.ConsumeRemoteRelationChange(dischargeChange)
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+	c.Assert(calls, gc.Equals, 1)
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// IsTerminal reports whether an error returned by State indicates a
+// condition that will not go away by retrying, so that the remote
+// relations worker should stop processing the affected relation or
+// application rather than restart. These are:
+//   - the relation, application, offer or user no longer exists
+//     (NotFound, UserNotFound);
+//   - permission to use the offer has been revoked (Unauthorized);
+//   - the relation has been suspended (params.CodeSuspended);
+//   - the request itself is not valid or not supported by the
+//     controller (NotValid, NotSupported).
+//
+// All other errors, including connection failures and those with
+// params.CodeTryAgain, are considered retryable.
+func IsTerminal(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch {
+	case errors.IsNotFound(err),
+		errors.IsUserNotFound(err),
+		errors.IsUnauthorized(err),
+		errors.IsNotValid(err),
+		errors.IsNotSupported(err):
+		return true
+	}
+	// Errors from watchers are not translated, so check the
+	// codes of the corresponding params errors too.
+	switch params.ErrCode(err) {
+	case params.CodeNotFound,
+		params.CodeUserNotFound,
+		params.CodeModelNotFound,
+		params.CodeUnauthorized,
+		params.CodeNotSupported,
+		params.CodeSuspended:
+		return true
+	}
+	return false
+}
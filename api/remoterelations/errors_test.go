@@ -0,0 +1,95 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"io"
+	"net"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+)
+
+func (s *remoteRelationsSuite) TestIsTerminalCodes(c *gc.C) {
+	// Every params error code must be listed here, so that
+	// the classification of new codes is a deliberate choice.
+	for i, test := range []struct {
+		code     string
+		terminal bool
+	}{
+		{params.CodeNotFound, true},
+		{params.CodeUserNotFound, true},
+		{params.CodeModelNotFound, true},
+		{params.CodeUnauthorized, true},
+		{params.CodeLoginExpired, false},
+		{params.CodeNoCreds, false},
+		{params.CodeCannotEnterScope, false},
+		{params.CodeCannotEnterScopeYet, false},
+		{params.CodeExcessiveContention, false},
+		{params.CodeUnitHasSubordinates, false},
+		{params.CodeNotAssigned, false},
+		{params.CodeStopped, false},
+		{params.CodeDead, false},
+		{params.CodeHasAssignedUnits, false},
+		{params.CodeHasHostedModels, false},
+		{params.CodeMachineHasAttachedStorage, false},
+		{params.CodeNotProvisioned, false},
+		{params.CodeNoAddressSet, false},
+		{params.CodeTryAgain, false},
+		{params.CodeNotImplemented, false},
+		{params.CodeAlreadyExists, false},
+		{params.CodeUpgradeInProgress, false},
+		{params.CodeActionNotAvailable, false},
+		{params.CodeOperationBlocked, false},
+		{params.CodeLeadershipClaimDenied, false},
+		{params.CodeLeaseClaimDenied, false},
+		{params.CodeNotSupported, true},
+		{params.CodeBadRequest, false},
+		{params.CodeMethodNotAllowed, false},
+		{params.CodeForbidden, false},
+		{params.CodeDischargeRequired, false},
+		{params.CodeRedirect, false},
+		{params.CodeRetry, false},
+		{params.CodeSuspended, true},
+		{"something new", false},
+	} {
+		c.Logf("test %d: %s", i, test.code)
+		apiErr := &params.Error{Code: test.code, Message: "boom"}
+		c.Check(remoterelations.IsTerminal(apiErr), gc.Equals, test.terminal)
+		translated := errors.Annotate(base.TranslateError(apiErr), "context")
+		c.Check(remoterelations.IsTerminal(translated), gc.Equals, test.terminal)
+	}
+}
+
+func (s *remoteRelationsSuite) TestIsTerminalOtherErrors(c *gc.C) {
+	c.Check(remoterelations.IsTerminal(nil), jc.IsFalse)
+	c.Check(remoterelations.IsTerminal(io.EOF), jc.IsFalse)
+	c.Check(remoterelations.IsTerminal(rpc.ErrShutdown), jc.IsFalse)
+	c.Check(remoterelations.IsTerminal(&net.OpError{Op: "dial", Err: errors.New("refused")}), jc.IsFalse)
+	c.Check(remoterelations.IsTerminal(errors.New("boom")), jc.IsFalse)
+	c.Check(remoterelations.IsTerminal(errors.NotValidf("relation key")), jc.IsTrue)
+}
+
+func (s *remoteRelationsSuite) TestIsTerminalFromState(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringResults)) = params.StringResults{
+			Results: []params.StringResult{{
+				Error: &params.Error{Code: params.CodeUnauthorized, Message: "offer permission revoked"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.GetToken(names.NewApplicationTag("mysql"))
+	c.Check(err, gc.ErrorMatches, "offer permission revoked")
+	c.Check(remoterelations.IsTerminal(err), jc.IsTrue)
+}
@@ -0,0 +1,20 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/juju/api/base"
+)
+
+// Monitored returns a copy of the State whose facade calls and
+// watchers fail with base.ErrConnectionBroken as soon as the given
+// monitor declares the API connection broken, rather than when the
+// connection eventually times out. Watchers returned by the copy die
+// with base.ErrConnectionBroken.
+func (st *State) Monitored(monitor *base.ConnectionMonitor) *State {
+	monitored := *st
+	monitored.facade = base.NewMonitoredFacadeCaller(st.facade, monitor)
+	monitored.watcherCaller = base.NewMonitoredAPICaller(st.watcherCaller, monitor)
+	return &monitored
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+func (s *remoteRelationsSuite) TestMonitoredWatcherFailsFast(c *gc.C) {
+	hung := make(chan struct{})
+	defer close(hung)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "Pinger":
+			return errors.New("no response")
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchRemoteRelations")
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: "1",
+				Changes:          []string{"wordpress:db mysql:db"},
+			}
+		case "StringsWatcher":
+			// The connection is half-dead: calls never complete.
+			<-hung
+			return errors.New("connection reset")
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	clock := jujutesting.NewClock(time.Time{})
+	monitor, err := base.NewConnectionMonitor(base.MonitorConfig{
+		Caller:       apiCaller,
+		Clock:        clock,
+		PingInterval: time.Minute,
+		PingTimeout:  time.Second,
+		MaxMissed:    1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer monitor.Kill()
+
+	st := remoterelations.NewState(apiCaller).Monitored(monitor)
+	w, err := st.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	defer w.Kill()
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for initial event")
+	}
+
+	select {
+	case <-clock.Alarms():
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for monitor")
+	}
+	clock.Advance(time.Minute)
+
+	dead := make(chan error, 1)
+	go func() {
+		dead <- w.Wait()
+	}()
+	select {
+	case err := <-dead:
+		c.Assert(errors.Cause(err), gc.Equals, base.ErrConnectionBroken)
+	case <-time.After(coretesting.LongWait):
+		c.Fatal("timed out waiting for watcher to fail")
+	}
+
+	_, err = st.WatchRemoteRelations()
+	c.Assert(errors.Cause(err), gc.Equals, base.ErrConnectionBroken)
+}
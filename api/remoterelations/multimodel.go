@@ -0,0 +1,206 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	apiwatcher "github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/watcher"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/catacomb"
+)
+
+// NewStateForModel creates a new client-side RemoteRelations facade
+// whose calls apply to the model with the given UUID, rather than to
+// the model of the connection. This allows a controller-side worker to
+// manage the cross-model relations of many models over one connection.
+// The model UUID is sent as the facade id of each call.
+func NewStateForModel(caller base.APICaller, modelUUID string) (*State, error) {
+	if !names.IsValidModel(modelUUID) {
+		return nil, errors.NotValidf("model UUID %q", modelUUID)
+	}
+	return newStateForModel(caller, &modelCaller{
+		APICaller: caller,
+		modelTag:  names.NewModelTag(modelUUID),
+	}), nil
+}
+
+func newStateForModel(caller base.APICaller, scoped *modelCaller) *State {
+	return &State{
+		facade:        base.NewFacadeCaller(scoped, remoteRelationsFacade),
+		watcherCaller: apiwatcher.NewMultiplexingCaller(caller),
+	}
+}
+
+// modelCaller is a base.APICaller that scopes the calls made through
+// it to a single model.
+type modelCaller struct {
+	base.APICaller
+	modelTag names.ModelTag
+
+	// evicted, if not nil, is closed when the model has gone away.
+	evicted chan struct{}
+}
+
+// APICall is part of the base.APICaller interface. It fails with an
+// error satisfying errors.IsNotFound once the model has been evicted.
+func (c *modelCaller) APICall(objType string, version int, id, request string, args, response interface{}) error {
+	if c.evicted != nil {
+		select {
+		case <-c.evicted:
+			return errors.NotFoundf("model %q", c.modelTag.Id())
+		default:
+		}
+	}
+	if id != "" {
+		return errors.Errorf("cannot make %s.%s call for model %q with id %q", objType, request, c.modelTag.Id(), id)
+	}
+	return c.APICaller.APICall(objType, version, c.modelTag.Id(), request, args, response)
+}
+
+// ModelTag is part of the base.APICaller interface.
+func (c *modelCaller) ModelTag() (names.ModelTag, bool) {
+	return c.modelTag, true
+}
+
+// MultiModelConfig holds the configuration for a MultiModelClient.
+type MultiModelConfig struct {
+	// Caller is the connection over which calls are made for all
+	// models.
+	Caller base.APICaller
+
+	// RemovedModels notifies of the UUIDs of models that have gone
+	// away. The MultiModelClient takes responsibility for stopping
+	// it.
+	RemovedModels watcher.StringsWatcher
+}
+
+// Validate returns an error if the config cannot be used to create a
+// MultiModelClient.
+func (config MultiModelConfig) Validate() error {
+	if config.Caller == nil {
+		return errors.NotValidf("nil Caller")
+	}
+	if config.RemovedModels == nil {
+		return errors.NotValidf("nil RemovedModels")
+	}
+	return nil
+}
+
+// MultiModelClient provides a State for each of the models managed by
+// a controller-side worker, sharing a single connection. The State for
+// a model is created when first asked for and reused after that, until
+// the RemovedModels watcher reports that the model has gone away.
+// From then on, calls for the model, including those made with a State
+// already handed out, fail with an error satisfying errors.IsNotFound.
+type MultiModelClient struct {
+	catacomb catacomb.Catacomb
+	config   MultiModelConfig
+
+	mu      sync.Mutex
+	models  map[string]*modelState
+	removed set.Strings
+}
+
+// modelState holds the State for a model, and the caller through
+// which it makes its calls.
+type modelState struct {
+	caller *modelCaller
+	state  *State
+}
+
+// NewMultiModelClient returns a MultiModelClient with the given
+// configuration. It must be stopped with Kill and Wait when it is no
+// longer needed.
+func NewMultiModelClient(config MultiModelConfig) (*MultiModelClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &MultiModelClient{
+		config:  config,
+		models:  make(map[string]*modelState),
+		removed: set.NewStrings(),
+	}
+	err := catacomb.Invoke(catacomb.Plan{
+		Site: &c.catacomb,
+		Work: c.loop,
+		Init: []worker.Worker{config.RemovedModels},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c, nil
+}
+
+// ForModel returns the State for the model with the given UUID. If the
+// model has gone away, an error satisfying errors.IsNotFound is
+// returned.
+func (c *MultiModelClient) ForModel(modelUUID string) (*State, error) {
+	if !names.IsValidModel(modelUUID) {
+		return nil, errors.NotValidf("model UUID %q", modelUUID)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.removed.Contains(modelUUID) {
+		return nil, errors.NotFoundf("model %q", modelUUID)
+	}
+	if model, ok := c.models[modelUUID]; ok {
+		return model.state, nil
+	}
+	scoped := &modelCaller{
+		APICaller: c.config.Caller,
+		modelTag:  names.NewModelTag(modelUUID),
+		evicted:   make(chan struct{}),
+	}
+	model := &modelState{
+		caller: scoped,
+		state:  newStateForModel(c.config.Caller, scoped),
+	}
+	c.models[modelUUID] = model
+	return model.state, nil
+}
+
+// evict discards the State for each of the models with the given
+// UUIDs, and causes all further calls for them to fail.
+func (c *MultiModelClient) evict(modelUUIDs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, modelUUID := range modelUUIDs {
+		c.removed.Add(modelUUID)
+		if model, ok := c.models[modelUUID]; ok {
+			close(model.caller.evicted)
+			delete(c.models, modelUUID)
+		}
+	}
+}
+
+func (c *MultiModelClient) loop() error {
+	for {
+		select {
+		case <-c.catacomb.Dying():
+			return c.catacomb.ErrDying()
+		case modelUUIDs, ok := <-c.config.RemovedModels.Changes():
+			if !ok {
+				return errors.New("removed models watcher closed")
+			}
+			c.evict(modelUUIDs)
+		}
+	}
+}
+
+// Kill is part of the worker.Worker interface.
+func (c *MultiModelClient) Kill() {
+	c.catacomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (c *MultiModelClient) Wait() error {
+	return c.catacomb.Wait()
+}
@@ -0,0 +1,194 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/tomb.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/watcher"
+)
+
+const (
+	modelUUID0 = "deadbeef-0bad-400d-8000-4b1d0d06f00d"
+	modelUUID1 = "deadbeef-0bad-400d-8000-4b1d0d06f11d"
+)
+
+type multiModelSuite struct {
+	coretesting.BaseSuite
+
+	// calls holds the facade id of each call made, which is the
+	// UUID of the model the call applies to.
+	calls   []string
+	removed *removedModelsWatcher
+}
+
+var _ = gc.Suite(&multiModelSuite{})
+
+func (s *multiModelSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.calls = nil
+	s.removed = newRemovedModelsWatcher()
+}
+
+func (s *multiModelSuite) apiCaller(c *gc.C) apitesting.APICallerFunc {
+	return apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "RemoteApplications")
+		s.calls = append(s.calls, id)
+		*(result.(*params.RemoteApplicationResults)) = params.RemoteApplicationResults{
+			Results: []params.RemoteApplicationResult{{
+				Result: &params.RemoteApplication{Name: "mysql"},
+			}},
+		}
+		return nil
+	})
+}
+
+func (s *multiModelSuite) newClient(c *gc.C) *remoterelations.MultiModelClient {
+	client, err := remoterelations.NewMultiModelClient(remoterelations.MultiModelConfig{
+		Caller:        s.apiCaller(c),
+		RemovedModels: s.removed,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddCleanup(func(c *gc.C) {
+		client.Kill()
+		c.Check(client.Wait(), jc.ErrorIsNil)
+	})
+	return client
+}
+
+// remove reports the removal of the given models, and waits for the
+// report to be handled.
+func (s *multiModelSuite) remove(modelUUIDs ...string) {
+	s.removed.changes <- modelUUIDs
+	// The client handles one change at a time, so the first has
+	// been handled once it accepts another.
+	s.removed.changes <- nil
+}
+
+func (s *multiModelSuite) TestNewStateForModel(c *gc.C) {
+	st, err := remoterelations.NewStateForModel(s.apiCaller(c), modelUUID0)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = st.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.calls, jc.DeepEquals, []string{modelUUID0})
+}
+
+func (s *multiModelSuite) TestNewStateForModelInvalidUUID(c *gc.C) {
+	_, err := remoterelations.NewStateForModel(s.apiCaller(c), "not-a-uuid")
+	c.Assert(err, gc.ErrorMatches, `model UUID "not-a-uuid" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *multiModelSuite) TestInvalidConfig(c *gc.C) {
+	_, err := remoterelations.NewMultiModelClient(remoterelations.MultiModelConfig{
+		RemovedModels: s.removed,
+	})
+	c.Assert(err, gc.ErrorMatches, "nil Caller not valid")
+	_, err = remoterelations.NewMultiModelClient(remoterelations.MultiModelConfig{
+		Caller: s.apiCaller(c),
+	})
+	c.Assert(err, gc.ErrorMatches, "nil RemovedModels not valid")
+}
+
+func (s *multiModelSuite) TestRequestsScopedToModel(c *gc.C) {
+	client := s.newClient(c)
+	for _, modelUUID := range []string{modelUUID0, modelUUID1, modelUUID0} {
+		st, err := client.ForModel(modelUUID)
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = st.RemoteApplications([]string{"mysql"})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	c.Assert(s.calls, jc.DeepEquals, []string{modelUUID0, modelUUID1, modelUUID0})
+}
+
+func (s *multiModelSuite) TestStatesCached(c *gc.C) {
+	client := s.newClient(c)
+	st0, err := client.ForModel(modelUUID0)
+	c.Assert(err, jc.ErrorIsNil)
+	st1, err := client.ForModel(modelUUID1)
+	c.Assert(err, jc.ErrorIsNil)
+	again, err := client.ForModel(modelUUID0)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(again, gc.Equals, st0)
+	c.Assert(st1, gc.Not(gc.Equals), st0)
+}
+
+func (s *multiModelSuite) TestEviction(c *gc.C) {
+	client := s.newClient(c)
+	st0, err := client.ForModel(modelUUID0)
+	c.Assert(err, jc.ErrorIsNil)
+	st1, err := client.ForModel(modelUUID1)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.remove(modelUUID0)
+
+	_, err = client.ForModel(modelUUID0)
+	c.Assert(err, gc.ErrorMatches, `model "`+modelUUID0+`" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = st0.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	// Other models are unaffected.
+	_, err = st1.RemoteApplications([]string{"mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.calls, jc.DeepEquals, []string{modelUUID1})
+}
+
+func (s *multiModelSuite) TestEvictionBeforeUse(c *gc.C) {
+	client := s.newClient(c)
+	s.remove(modelUUID0)
+	_, err := client.ForModel(modelUUID0)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *multiModelSuite) TestWatcherClosed(c *gc.C) {
+	client, err := remoterelations.NewMultiModelClient(remoterelations.MultiModelConfig{
+		Caller:        s.apiCaller(c),
+		RemovedModels: s.removed,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	close(s.removed.changes)
+	c.Assert(client.Wait(), gc.ErrorMatches, "removed models watcher closed")
+}
+
+func (s *multiModelSuite) TestKillStopsWatcher(c *gc.C) {
+	client := s.newClient(c)
+	client.Kill()
+	c.Assert(client.Wait(), jc.ErrorIsNil)
+	c.Assert(s.removed.Wait(), jc.ErrorIsNil)
+}
+
+type removedModelsWatcher struct {
+	tomb    tomb.Tomb
+	changes chan []string
+}
+
+func newRemovedModelsWatcher() *removedModelsWatcher {
+	w := &removedModelsWatcher{changes: make(chan []string)}
+	go func() {
+		defer w.tomb.Done()
+		<-w.tomb.Dying()
+	}()
+	return w
+}
+
+func (w *removedModelsWatcher) Changes() watcher.StringsChannel {
+	return w.changes
+}
+
+func (w *removedModelsWatcher) Kill() {
+	w.tomb.Kill(nil)
+}
+
+func (w *removedModelsWatcher) Wait() error {
+	return w.tomb.Wait()
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/status"
+)
+
+// OfferConnection describes a relation made to an application offer
+// from a consuming model.
+type OfferConnection struct {
+	// Username is the name of the user who made the relation.
+	Username string
+
+	// SourceModelUUID is the UUID of the consuming model.
+	SourceModelUUID string
+
+	// RelationKey is the key of the relation.
+	RelationKey string
+
+	// Status is the status of the relation.
+	Status status.StatusInfo
+}
+
+// OfferConnectionsResult holds the connections to an application
+// offer, or the error that prevented them being retrieved.
+type OfferConnectionsResult struct {
+	Connections []OfferConnection
+	Error       error
+}
+
+// OfferConnections returns the connections made to the application
+// offers with the given UUIDs, for use by the offering model. The
+// results are returned in the same order as the UUIDs; an error for an
+// individual offer is reported in its result, and identifies the
+// offer. If no UUIDs are given, no call is made. Controllers that do
+// not support listing offer connections cause an error satisfying
+// errors.IsNotSupported to be returned without making the call.
+func (st *State) OfferConnections(offerUUIDs []string) ([]OfferConnectionsResult, error) {
+	if len(offerUUIDs) == 0 {
+		return nil, nil
+	}
+	if version := st.FacadeVersion(); version < offerConnectionsMinVersion {
+		return nil, errors.NotSupportedf(
+			"listing offer connections (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, offerConnectionsMinVersion, version,
+		)
+	}
+	args := params.OfferArgs{Args: make([]params.OfferArg, len(offerUUIDs))}
+	for i, uuid := range offerUUIDs {
+		if !utils.IsValidUUIDString(uuid) {
+			return nil, errors.NotValidf("offer UUID %q", uuid)
+		}
+		args.Args[i].OfferUUID = uuid
+	}
+	var results params.OfferConnectionsResults
+	err := st.facade.FacadeCall("OfferConnections", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(offerUUIDs)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	connections := make([]OfferConnectionsResult, len(offerUUIDs))
+	for i, result := range results.Results {
+		if result.Error != nil {
+			connections[i].Error = errors.Annotatef(base.TranslateError(result.Error), "offer %q", offerUUIDs[i])
+			continue
+		}
+		offerConnections, err := offerConnectionsFromParams(result.Connections)
+		if err != nil {
+			connections[i].Error = errors.Annotatef(err, "offer %q", offerUUIDs[i])
+			continue
+		}
+		connections[i].Connections = offerConnections
+	}
+	return connections, nil
+}
+
+func offerConnectionsFromParams(in []params.OfferConnection) ([]OfferConnection, error) {
+	out := make([]OfferConnection, len(in))
+	for i, conn := range in {
+		modelTag, err := names.ParseModelTag(conn.SourceModelTag)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !names.IsValidRelation(conn.RelationKey) {
+			return nil, errors.NotValidf("relation key %q", conn.RelationKey)
+		}
+		out[i] = OfferConnection{
+			Username:        conn.Username,
+			SourceModelUUID: modelTag.Id(),
+			RelationKey:     conn.RelationKey,
+			Status: status.StatusInfo{
+				Status:  conn.Status.Status,
+				Message: conn.Status.Info,
+				Data:    conn.Status.Data,
+				Since:   conn.Status.Since,
+			},
+		}
+	}
+	return out, nil
+}
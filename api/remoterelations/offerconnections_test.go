@@ -0,0 +1,161 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/status"
+	coretesting "github.com/juju/juju/testing"
+)
+
+var _ = gc.Suite(&offerConnectionsSuite{})
+
+type offerConnectionsSuite struct {
+	coretesting.BaseSuite
+}
+
+const (
+	offerUUID1 = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	offerUUID2 = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+)
+
+func (s *offerConnectionsSuite) TestOfferConnections(c *gc.C) {
+	since := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "OfferConnections")
+		c.Check(arg, jc.DeepEquals, params.OfferArgs{Args: []params.OfferArg{
+			{OfferUUID: offerUUID1},
+			{OfferUUID: offerUUID2},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.OfferConnectionsResults{})
+		*(result.(*params.OfferConnectionsResults)) = params.OfferConnectionsResults{
+			Results: []params.OfferConnectionsResult{{
+				Connections: []params.OfferConnection{{
+					Username:       "fred",
+					SourceModelTag: coretesting.ModelTag.String(),
+					RelationKey:    "wordpress:db mysql:db",
+					Status: params.EntityStatus{
+						Status: status.Active,
+						Info:   "ready",
+						Since:  &since,
+					},
+				}},
+			}, {
+				Error: &params.Error{Code: params.CodeNotFound, Message: "offer not found"},
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	results, err := st.OfferConnections([]string{offerUUID1, offerUUID2})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+	c.Assert(results, gc.HasLen, 2)
+	c.Check(results[0], jc.DeepEquals, remoterelations.OfferConnectionsResult{
+		Connections: []remoterelations.OfferConnection{{
+			Username:        "fred",
+			SourceModelUUID: coretesting.ModelTag.Id(),
+			RelationKey:     "wordpress:db mysql:db",
+			Status: status.StatusInfo{
+				Status:  status.Active,
+				Message: "ready",
+				Since:   &since,
+			},
+		}},
+	})
+	c.Check(results[1].Connections, gc.IsNil)
+	c.Check(results[1].Error, gc.ErrorMatches, `offer "`+offerUUID2+`": offer not found`)
+	c.Check(results[1].Error, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsInvalidConnection(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.OfferConnectionsResults)) = params.OfferConnectionsResults{
+			Results: []params.OfferConnectionsResult{{
+				Connections: []params.OfferConnection{{
+					Username:       "fred",
+					SourceModelTag: "machine-0",
+					RelationKey:    "wordpress:db mysql:db",
+				}},
+			}, {
+				Connections: []params.OfferConnection{{
+					Username:       "fred",
+					SourceModelTag: coretesting.ModelTag.String(),
+					RelationKey:    "bad",
+				}},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	results, err := st.OfferConnections([]string{offerUUID1, offerUUID2})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 2)
+	c.Check(results[0].Error, gc.ErrorMatches, `offer "`+offerUUID1+`": "machine-0" is not a valid model tag`)
+	c.Check(results[1].Error, gc.ErrorMatches, `offer "`+offerUUID2+`": relation key "bad" not valid`)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsEmpty(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	results, err := st.OfferConnections(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 0)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsInvalidUUID(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	_, err := st.OfferConnections([]string{offerUUID1, "not-a-uuid"})
+	c.Check(err, gc.ErrorMatches, `offer UUID "not-a-uuid" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsNotSupported(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 3})
+	_, err := st.OfferConnections([]string{offerUUID1})
+	c.Check(err, gc.ErrorMatches, `listing offer connections \(requires RemoteRelations facade version 4, controller has version 3\) not supported`)
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.OfferConnectionsResults)) = params.OfferConnectionsResults{}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	_, err := st.OfferConnections([]string{offerUUID1})
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 0`)
+}
+
+func (s *offerConnectionsSuite) TestOfferConnectionsCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"}
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	_, err := st.OfferConnections([]string{offerUUID1})
+	c.Check(err, gc.ErrorMatches, "permission denied")
+	c.Check(err, jc.Satisfies, errors.IsUnauthorized)
+}
@@ -0,0 +1,180 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// SettingsHash returns a hash of the content of the given relation
+// settings. Equal settings have equal hashes, whatever the order in
+// which they were set, and nil settings hash the same as empty ones.
+func SettingsHash(settings map[string]interface{}) (string, error) {
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	// Maps are marshalled with their keys sorted, so the encoding
+	// depends only on the content.
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", errors.Annotate(err, "cannot hash settings")
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PublishStats holds the number of publishes suppressed by a
+// PublishFilter.
+type PublishStats struct {
+	// SuppressedUnits is the number of changed units dropped from
+	// events because their settings had already been published.
+	SuppressedUnits int
+
+	// SuppressedEvents is the number of events not published at
+	// all, because nothing in them had changed.
+	SuppressedEvents int
+}
+
+// PublishFilter drops units whose settings are unchanged from the
+// relation change events published to a remote model, so that
+// rewriting a unit's settings without changing them does not cause
+// them to be published again. It remembers the hash of the settings
+// last published for each unit of each relation.
+//
+// Reset must be called for a relation when it is suspended or resumed,
+// so that the relation's settings are published in full afterwards.
+type PublishFilter struct {
+	mu        sync.Mutex
+	relations map[string]*publishedRelation
+	stats     PublishStats
+	// generation is incremented whenever a relation is reset, so
+	// that events published concurrently with a reset are not
+	// recorded after it.
+	generation uint64
+}
+
+// publishedRelation holds what was last published for a relation.
+type publishedRelation struct {
+	life  params.Life
+	units map[int]string
+}
+
+// NewPublishFilter returns a PublishFilter that has not yet seen any
+// relations.
+func NewPublishFilter() *PublishFilter {
+	return &PublishFilter{
+		relations: make(map[string]*publishedRelation),
+	}
+}
+
+// Publish calls publish with the given event, less those changed units
+// whose settings are the same as those last published for the unit in
+// the relation. If nothing in the event has changed, publish is not
+// called and nil is returned. The first event for a relation, and any
+// event that changes its life or has departed units, is always
+// published.
+//
+// publish is typically State.ConsumeRemoteRelationChange. The
+// published settings are only remembered if it succeeds.
+func (f *PublishFilter) Publish(
+	event params.RemoteRelationChangeEvent,
+	publish func(params.RemoteRelationChangeEvent) error,
+) error {
+	filtered, hashes, generation, ok := f.filter(event)
+	if !ok {
+		return nil
+	}
+	if err := publish(filtered); err != nil {
+		return errors.Trace(err)
+	}
+	f.record(filtered, hashes, generation)
+	return nil
+}
+
+// filter returns the event with unchanged units removed, the hashes of
+// the remaining units' settings, the current generation, and whether
+// the event should be published.
+func (f *PublishFilter) filter(event params.RemoteRelationChangeEvent) (
+	params.RemoteRelationChangeEvent, map[int]string, uint64, bool,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	published := f.relations[event.RelationToken]
+	hashes := make(map[int]string)
+	filtered := event
+	filtered.ChangedUnits = nil
+	for _, unit := range event.ChangedUnits {
+		hash, err := SettingsHash(unit.Settings)
+		if err != nil {
+			// Settings that cannot be hashed are always
+			// published, and the remote model reports any
+			// problem with them.
+			filtered.ChangedUnits = append(filtered.ChangedUnits, unit)
+			continue
+		}
+		if published != nil && published.units[unit.UnitId] == hash {
+			f.stats.SuppressedUnits++
+			continue
+		}
+		hashes[unit.UnitId] = hash
+		filtered.ChangedUnits = append(filtered.ChangedUnits, unit)
+	}
+	changed := published == nil ||
+		published.life != event.Life ||
+		len(filtered.ChangedUnits) > 0 ||
+		len(filtered.DepartedUnits) > 0
+	if !changed {
+		f.stats.SuppressedEvents++
+	}
+	return filtered, hashes, f.generation, changed
+}
+
+// record remembers that the given event was published, unless a
+// relation has been reset since the given generation.
+func (f *PublishFilter) record(event params.RemoteRelationChangeEvent, hashes map[int]string, generation uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if generation != f.generation {
+		return
+	}
+	if event.Life == params.Dead {
+		delete(f.relations, event.RelationToken)
+		return
+	}
+	published := f.relations[event.RelationToken]
+	if published == nil {
+		published = &publishedRelation{units: make(map[int]string)}
+		f.relations[event.RelationToken] = published
+	}
+	published.life = event.Life
+	for unitId, hash := range hashes {
+		published.units[unitId] = hash
+	}
+	for _, unitId := range event.DepartedUnits {
+		delete(published.units, unitId)
+	}
+}
+
+// Reset forgets what has been published for the relation with the
+// given token, so that its next event is published in full.
+func (f *PublishFilter) Reset(relationToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.relations, relationToken)
+	f.generation++
+}
+
+// Stats returns the number of publishes suppressed so far.
+func (f *PublishFilter) Stats() PublishStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type publishFilterSuite struct {
+	coretesting.BaseSuite
+
+	filter    *remoterelations.PublishFilter
+	published []params.RemoteRelationChangeEvent
+	err       error
+}
+
+var _ = gc.Suite(&publishFilterSuite{})
+
+func (s *publishFilterSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.filter = remoterelations.NewPublishFilter()
+	s.published = nil
+	s.err = nil
+}
+
+func (s *publishFilterSuite) publish(event params.RemoteRelationChangeEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+// assertPublishes publishes the event through the filter, and checks
+// that the expected event, if any, was published as a result.
+func (s *publishFilterSuite) assertPublishes(c *gc.C, event params.RemoteRelationChangeEvent, expected *params.RemoteRelationChangeEvent) {
+	s.published = nil
+	err := s.filter.Publish(event, s.publish)
+	c.Assert(err, jc.ErrorIsNil)
+	if expected == nil {
+		c.Assert(s.published, gc.HasLen, 0)
+		return
+	}
+	c.Assert(s.published, jc.DeepEquals, []params.RemoteRelationChangeEvent{*expected})
+}
+
+func changeEvent(relationToken string, life params.Life, units ...params.RemoteRelationUnitChange) params.RemoteRelationChangeEvent {
+	return params.RemoteRelationChangeEvent{
+		RelationToken:    relationToken,
+		ApplicationToken: "app-token",
+		Life:             life,
+		ChangedUnits:     units,
+	}
+}
+
+func unitChange(id int, settings map[string]interface{}) params.RemoteRelationUnitChange {
+	return params.RemoteRelationUnitChange{UnitId: id, Settings: settings}
+}
+
+func (s *publishFilterSuite) TestSettingsHash(c *gc.C) {
+	hash1, err := remoterelations.SettingsHash(map[string]interface{}{"a": "1", "b": "2"})
+	c.Assert(err, jc.ErrorIsNil)
+	hash2, err := remoterelations.SettingsHash(map[string]interface{}{"b": "2", "a": "1"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(hash1, gc.Equals, hash2)
+
+	hash3, err := remoterelations.SettingsHash(map[string]interface{}{"a": "1", "b": "3"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(hash3, gc.Not(gc.Equals), hash1)
+
+	nilHash, err := remoterelations.SettingsHash(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	emptyHash, err := remoterelations.SettingsHash(map[string]interface{}{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(nilHash, gc.Equals, emptyHash)
+}
+
+func (s *publishFilterSuite) TestFirstEventPublished(c *gc.C) {
+	event := changeEvent("rel-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	s.assertPublishes(c, event, &event)
+
+	// An empty first event for another relation is still published.
+	empty := changeEvent("other-token", params.Alive)
+	s.assertPublishes(c, empty, &empty)
+}
+
+func (s *publishFilterSuite) TestUnchangedUnitsDropped(c *gc.C) {
+	unit0 := unitChange(0, map[string]interface{}{"foo": "bar"})
+	unit1 := unitChange(1, map[string]interface{}{"baz": "qux"})
+	event := changeEvent("rel-token", params.Alive, unit0, unit1)
+	s.assertPublishes(c, event, &event)
+
+	unit1Changed := unitChange(1, map[string]interface{}{"baz": "quux"})
+	expected := changeEvent("rel-token", params.Alive, unit1Changed)
+	s.assertPublishes(c, changeEvent("rel-token", params.Alive, unit0, unit1Changed), &expected)
+
+	c.Assert(s.filter.Stats(), jc.DeepEquals, remoterelations.PublishStats{
+		SuppressedUnits: 1,
+	})
+}
+
+func (s *publishFilterSuite) TestEmptyEventNotPublished(c *gc.C) {
+	event := changeEvent("rel-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	s.assertPublishes(c, event, &event)
+	s.assertPublishes(c, event, nil)
+	s.assertPublishes(c, event, nil)
+
+	c.Assert(s.filter.Stats(), jc.DeepEquals, remoterelations.PublishStats{
+		SuppressedUnits:  2,
+		SuppressedEvents: 2,
+	})
+}
+
+func (s *publishFilterSuite) TestLifeChangePublished(c *gc.C) {
+	unit0 := unitChange(0, map[string]interface{}{"foo": "bar"})
+	event := changeEvent("rel-token", params.Alive, unit0)
+	s.assertPublishes(c, event, &event)
+
+	expected := changeEvent("rel-token", params.Dying)
+	s.assertPublishes(c, changeEvent("rel-token", params.Dying, unit0), &expected)
+}
+
+func (s *publishFilterSuite) TestDepartedUnitsPublished(c *gc.C) {
+	unit0 := unitChange(0, map[string]interface{}{"foo": "bar"})
+	event := changeEvent("rel-token", params.Alive, unit0)
+	s.assertPublishes(c, event, &event)
+
+	departed := changeEvent("rel-token", params.Alive)
+	departed.DepartedUnits = []int{0}
+	s.assertPublishes(c, departed, &departed)
+
+	// A unit that rejoins with the same settings is published again.
+	s.assertPublishes(c, event, &event)
+}
+
+func (s *publishFilterSuite) TestFailedPublishNotRemembered(c *gc.C) {
+	event := changeEvent("rel-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	s.err = errors.New("boom")
+	err := s.filter.Publish(event, s.publish)
+	c.Assert(err, gc.ErrorMatches, "boom")
+
+	s.err = nil
+	s.assertPublishes(c, event, &event)
+}
+
+func (s *publishFilterSuite) TestReset(c *gc.C) {
+	event := changeEvent("rel-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	other := changeEvent("other-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	s.assertPublishes(c, event, &event)
+	s.assertPublishes(c, other, &other)
+
+	// After the relation is suspended and resumed, its settings
+	// are published in full; other relations are unaffected.
+	s.filter.Reset("rel-token")
+	s.assertPublishes(c, event, &event)
+	s.assertPublishes(c, other, nil)
+}
+
+func (s *publishFilterSuite) TestResetDuringPublish(c *gc.C) {
+	event := changeEvent("rel-token", params.Alive, unitChange(0, map[string]interface{}{"foo": "bar"}))
+	err := s.filter.Publish(event, func(event params.RemoteRelationChangeEvent) error {
+		s.filter.Reset("rel-token")
+		return nil
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The reset happened after the event was filtered, so the
+	// event is published again.
+	s.assertPublishes(c, event, &event)
+}
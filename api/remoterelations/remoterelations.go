@@ -0,0 +1,800 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package remoterelations provides the client side API for the
+// RemoteRelations facade, used by the remote relations worker to
+// manage cross-model relations.
+package remoterelations
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"github.com/juju/utils/clock"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/juju/juju/api/base"
+	apiwatcher "github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/status"
+	"github.com/juju/juju/watcher"
+	"github.com/juju/juju/worker"
+)
+
+const remoteRelationsFacade = "RemoteRelations"
+
+// setStatusMinVersion is the first version of the RemoteRelations
+// facade that supports setting the status of remote applications.
+const setStatusMinVersion = 2
+
+// applicationRelationsMinVersion is the first version of the
+// RemoteRelations facade that supports WatchApplicationRelations.
+const applicationRelationsMinVersion = 3
+
+// offerConnectionsMinVersion is the first version of the
+// RemoteRelations facade that supports OfferConnections.
+const offerConnectionsMinVersion = 4
+
+// relationKeysMinVersion is the first version of the RemoteRelations
+// facade that supports RelationKeys.
+const relationKeysMinVersion = 5
+
+// ingressNetworksMinVersion is the first version of the
+// RemoteRelations facade that supports publishing ingress networks and
+// watching egress addresses.
+const ingressNetworksMinVersion = 6
+
+// State provides access to a remoterelations's view of the state.
+type State struct {
+	facade base.FacadeCaller
+
+	// watcherCaller is used by the watchers returned from State,
+	// sharing a single long-poll between them if the API server
+	// supports it.
+	watcherCaller base.APICaller
+
+	// resume, if set, causes the watchers returned from State to
+	// be re-established when they fail.
+	resume *apiwatcher.ResumeConfig
+
+	// dischargeHandler, if set, is used to acquire discharges
+	// when calls fail because one is required.
+	dischargeHandler DischargeHandler
+}
+
+// NewState creates a new client-side RemoteRelations facade.
+func NewState(caller base.APICaller) *State {
+	facadeCaller := base.NewFacadeCaller(caller, remoteRelationsFacade)
+	return &State{
+		facade:        facadeCaller,
+		watcherCaller: apiwatcher.NewMultiplexingCaller(caller),
+	}
+}
+
+// watchMethods holds the facade methods that create server-side
+// watchers. These are never retried, as a call which fails after the
+// server has processed it would leak a watcher.
+var watchMethods = []string{
+	"WatchRemoteApplications",
+	"WatchRemoteRelations",
+	"WatchRemoteApplicationRelations",
+	"WatchLocalRelationUnits",
+	"WatchRelationSuspendedStatus",
+	"WatchOfferStatus",
+	"WatchApplicationRelations",
+	"WatchEgressAddressesForRelations",
+}
+
+// NewStateWithRetry creates a new client-side RemoteRelations facade
+// which retries calls that fail with transient errors, according to
+// the supplied configuration.
+func NewStateWithRetry(caller base.APICaller, config base.RetryConfig) (*State, error) {
+	config.NonIdempotent = append(config.NonIdempotent, watchMethods...)
+	facadeCaller, err := base.NewRetryingFacadeCaller(
+		base.NewFacadeCaller(caller, remoteRelationsFacade), config,
+	)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &State{
+		facade:        facadeCaller,
+		watcherCaller: apiwatcher.NewMultiplexingCaller(caller),
+	}, nil
+}
+
+// Observed returns a copy of the State that reports each of its
+// facade calls to the given observer, timing them with clock. Calls
+// made by the watchers it returns are not reported.
+func (st *State) Observed(observer base.CallObserver, clock clock.Clock) *State {
+	observed := *st
+	observed.facade = base.NewObservingFacadeCaller(st.facade, observer, clock)
+	return &observed
+}
+
+// FacadeVersion returns the version of the RemoteRelations facade
+// negotiated with the controller.
+func (st *State) FacadeVersion() int {
+	return st.facade.BestAPIVersion()
+}
+
+// WatchRemoteApplications returns a strings watcher that notifies of
+// the addition, removal, and lifecycle changes of remote applications
+// in the model.
+func (st *State) WatchRemoteApplications() (watcher.StringsWatcher, error) {
+	if st.resume != nil {
+		return apiwatcher.NewResumableStringsWatcher(st.unresumable().WatchRemoteApplications, *st.resume)
+	}
+	var result params.StringsWatchResult
+	err := st.facade.FacadeCall("WatchRemoteApplications", nil, &result)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if result.Error != nil {
+		return nil, base.TranslateError(result.Error)
+	}
+	w := apiwatcher.NewStringsWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchRemoteRelations returns a strings watcher that notifies of the
+// addition, removal, and lifecycle changes of remote relations in the
+// model.
+func (st *State) WatchRemoteRelations() (watcher.StringsWatcher, error) {
+	if st.resume != nil {
+		return apiwatcher.NewResumableStringsWatcher(st.unresumable().WatchRemoteRelations, *st.resume)
+	}
+	var result params.StringsWatchResult
+	err := st.facade.FacadeCall("WatchRemoteRelations", nil, &result)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if result.Error != nil {
+		return nil, base.TranslateError(result.Error)
+	}
+	w := apiwatcher.NewStringsWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchRemoteApplicationRelations returns a watcher that notifies of
+// changes to the lifecycles of the relations involving the remote
+// application with the given name. Changes are reported as relation
+// keys; unit membership and settings changes are not reported, and
+// should be watched per relation with WatchLocalRelationUnits.
+func (st *State) WatchRemoteApplicationRelations(application string) (watcher.StringsWatcher, error) {
+	watchers, errs, err := st.WatchRelationsForRemoteApplications([]string{application})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	return watchers[0], nil
+}
+
+// WatchRelationsForRemoteApplications returns a watcher for each of the
+// remote applications with the given names, as described for
+// WatchRemoteApplicationRelations, registering them all with a single
+// API call. The watchers and errors are returned in the same order as
+// the names; the watcher is nil wherever the error is not. Invalid
+// names are rejected without affecting the others.
+func (st *State) WatchRelationsForRemoteApplications(applications []string) ([]watcher.StringsWatcher, []error, error) {
+	watchers := make([]watcher.StringsWatcher, len(applications))
+	errs := make([]error, len(applications))
+	var args params.Entities
+	var indexes []int
+	for i, name := range applications {
+		if !names.IsValidApplication(name) {
+			errs[i] = errors.NotValidf("application name %q", name)
+			continue
+		}
+		args.Entities = append(args.Entities, params.Entity{
+			Tag: names.NewApplicationTag(name).String(),
+		})
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return watchers, errs, nil
+	}
+	var results params.StringsWatchResults
+	err := st.facade.FacadeCall("WatchRemoteApplicationRelations", args, &results)
+	if err != nil {
+		return nil, nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(indexes)); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	resultErrs := base.CollectErrors(results.Results)
+	for j, i := range indexes {
+		if resultErrs[j] != nil {
+			errs[i] = resultErrs[j]
+			continue
+		}
+		w := apiwatcher.NewStringsWatcher(st.watcherCaller, results.Results[j])
+		if st.resume != nil {
+			w, errs[i] = st.resumeApplicationRelations(applications[i], w)
+		}
+		watchers[i] = w
+	}
+	return watchers, errs, nil
+}
+
+// resumeApplicationRelations returns a resumable watcher that starts
+// with the given watcher of the relations of the remote application
+// with the given name.
+func (st *State) resumeApplicationRelations(application string, first watcher.StringsWatcher) (watcher.StringsWatcher, error) {
+	unresumable := st.unresumable()
+	watch := func() (watcher.StringsWatcher, error) {
+		if w := first; w != nil {
+			first = nil
+			return w, nil
+		}
+		return unresumable.WatchRemoteApplicationRelations(application)
+	}
+	w, err := apiwatcher.NewResumableStringsWatcher(watch, *st.resume)
+	if err != nil {
+		if first != nil {
+			worker.Stop(first)
+		}
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+// WatchApplicationRelations returns a watcher that notifies of changes
+// to the relations involving the remote application with the given
+// name, reporting for each changed relation its key, life and
+// suspended status, and the units that have changed their settings in,
+// or departed from, the relation. Unlike WatchRemoteApplicationRelations,
+// the watcher delivers watcher.ApplicationRelationsChange values rather
+// than bare relation keys, so a separate WatchLocalRelationUnits is not
+// needed per relation. Controllers that do not support the call cause
+// an error satisfying errors.IsNotSupported to be returned without
+// making the call.
+func (st *State) WatchApplicationRelations(application string) (watcher.ApplicationRelationsWatcher, error) {
+	if version := st.FacadeVersion(); version < applicationRelationsMinVersion {
+		return nil, errors.NotSupportedf(
+			"watching application relations (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, applicationRelationsMinVersion, version,
+		)
+	}
+	if !names.IsValidApplication(application) {
+		return nil, errors.NotValidf("application name %q", application)
+	}
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewApplicationTag(application).String()}},
+	}
+	var results params.ApplicationRelationsWatchResults
+	err := st.facade.FacadeCall("WatchApplicationRelations", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.ApplicationRelationsWatchResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := apiwatcher.NewApplicationRelationsWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchLocalRelationUnits returns a watcher that notifies of changes to the
+// local units in the relation with the given key. Units leaving the relation
+// scope are reported in the Departed field of the change.
+func (st *State) WatchLocalRelationUnits(relationKey string) (watcher.RelationUnitsWatcher, error) {
+	if !names.IsValidRelation(relationKey) {
+		return nil, errors.NotValidf("relation key %q", relationKey)
+	}
+	if st.resume != nil {
+		watch := func() (watcher.RelationUnitsWatcher, error) {
+			return st.unresumable().WatchLocalRelationUnits(relationKey)
+		}
+		return apiwatcher.NewResumableRelationUnitsWatcher(watch, *st.resume)
+	}
+	relationTag := names.NewRelationTag(relationKey)
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: relationTag.String()}},
+	}
+	var results params.RelationUnitsWatchResults
+	err := st.facade.FacadeCall("WatchLocalRelationUnits", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.RelationUnitsWatchResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := apiwatcher.NewRelationUnitsWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchRelationSuspendedStatus returns a watcher that notifies of
+// changes to the life and suspended status of the relation with the
+// given key.
+func (st *State) WatchRelationSuspendedStatus(relationKey string) (watcher.RelationStatusWatcher, error) {
+	if !names.IsValidRelation(relationKey) {
+		return nil, errors.NotValidf("relation key %q", relationKey)
+	}
+	relationTag := names.NewRelationTag(relationKey)
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: relationTag.String()}},
+	}
+	var results params.RelationStatusWatchResults
+	err := st.facade.FacadeCall("WatchRelationSuspendedStatus", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.RelationLifeSuspendedStatusWatchResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := apiwatcher.NewRelationStatusWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchOfferStatus returns a watcher that notifies of changes to the
+// status of the application offer with the given UUID.
+func (st *State) WatchOfferStatus(offerUUID string) (watcher.OfferStatusWatcher, error) {
+	if !utils.IsValidUUIDString(offerUUID) {
+		return nil, errors.NotValidf("offer UUID %q", offerUUID)
+	}
+	args := params.OfferArgs{
+		Args: []params.OfferArg{{OfferUUID: offerUUID}},
+	}
+	var results params.OfferStatusWatchResults
+	err := st.facade.FacadeCall("WatchOfferStatus", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.OfferStatusWatchResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := apiwatcher.NewOfferStatusWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// WatchEgressAddressesForRelation returns a watcher that notifies of
+// changes to the CIDRs from which traffic for the relation with the
+// given key originates in this model. Each change holds the full
+// current set of CIDRs, rather than the CIDRs added or removed.
+// Controllers that do not support the watcher cause an error
+// satisfying errors.IsNotSupported to be returned without making the
+// call.
+func (st *State) WatchEgressAddressesForRelation(relationKey string) (watcher.StringsWatcher, error) {
+	if !names.IsValidRelation(relationKey) {
+		return nil, errors.NotValidf("relation key %q", relationKey)
+	}
+	if version := st.FacadeVersion(); version < ingressNetworksMinVersion {
+		return nil, errors.NotSupportedf(
+			"watching egress addresses (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, ingressNetworksMinVersion, version,
+		)
+	}
+	if st.resume != nil {
+		watch := func() (watcher.StringsWatcher, error) {
+			return st.unresumable().WatchEgressAddressesForRelation(relationKey)
+		}
+		return apiwatcher.NewResumableStringsWatcher(watch, *st.resume)
+	}
+	relationTag := names.NewRelationTag(relationKey)
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: relationTag.String()}},
+	}
+	var results params.StringsWatchResults
+	err := st.facade.FacadeCall("WatchEgressAddressesForRelations", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	var result params.StringsWatchResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := apiwatcher.NewStringsWatcher(st.watcherCaller, result)
+	return w, nil
+}
+
+// PublishIngressNetworkChange tells the offering model the networks
+// from which the consuming model's traffic for a relation originates.
+// Each network must be a valid CIDR; one that admits every address,
+// such as 0.0.0.0/0, is rejected unless allowOpen is true, so that a
+// relation is not opened to the world by mistake. An invalid change
+// causes an error satisfying errors.IsNotValid to be returned without
+// making the call; so does a controller that does not support
+// publishing ingress networks, with an error satisfying
+// errors.IsNotSupported.
+func (st *State) PublishIngressNetworkChange(change params.IngressNetworksChangeEvent, allowOpen bool) error {
+	if err := validateIngressNetworks(change, allowOpen); err != nil {
+		return errors.Trace(err)
+	}
+	if version := st.FacadeVersion(); version < ingressNetworksMinVersion {
+		return errors.NotSupportedf(
+			"publishing ingress networks (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, ingressNetworksMinVersion, version,
+		)
+	}
+	args := params.IngressNetworksChanges{
+		Changes: []params.IngressNetworksChangeEvent{change},
+	}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("PublishIngressNetworkChanges", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	return errors.Trace(base.OneResult(results.Results, nil))
+}
+
+// validateIngressNetworks returns an error satisfying errors.IsNotValid
+// if change cannot be published.
+func validateIngressNetworks(change params.IngressNetworksChangeEvent, allowOpen bool) error {
+	if change.RelationToken == "" {
+		return errors.NotValidf("empty relation token")
+	}
+	if change.ApplicationToken == "" {
+		return errors.NotValidf("empty application token")
+	}
+	for _, cidr := range change.Networks {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return errors.NotValidf("ingress network %q", cidr)
+		}
+		if ones, _ := ipNet.Mask.Size(); ones == 0 && !allowOpen {
+			return errors.NewNotValid(nil, fmt.Sprintf("ingress network %q admits all addresses", cidr))
+		}
+	}
+	return nil
+}
+
+// RelationUnitSettings returns the relation settings for each of the
+// specified relation units, fetched with a single API call. The results
+// are returned in the same order as the relation units; an error for an
+// individual unit is reported in its result. The Hash of each result
+// holds the SettingsHash of its settings, if the controller did not
+// supply one. If the controller requires a macaroon to be discharged
+// for any of the units, a *DischargeRequiredError is returned.
+func (st *State) RelationUnitSettings(relationUnits []params.RelationUnit) ([]params.SettingsResult, error) {
+	for _, ru := range relationUnits {
+		if _, err := names.ParseRelationTag(ru.Relation); err != nil {
+			return nil, errors.NotValidf("relation tag %q", ru.Relation)
+		}
+		if _, err := names.ParseUnitTag(ru.Unit); err != nil {
+			return nil, errors.NotValidf("unit tag %q", ru.Unit)
+		}
+	}
+	args := params.RelationUnits{RelationUnits: relationUnits}
+	var results params.SettingsResults
+	err := st.withDischarge(func() error {
+		results = params.SettingsResults{}
+		err := st.facade.FacadeCall("RelationUnitSettings", args, &results)
+		if err != nil {
+			return translateDischargeRequired(base.TranslateError(err))
+		}
+		if err := base.CheckResultCount(results.Results, len(relationUnits)); err != nil {
+			return err
+		}
+		// A discharge required for any unit is required for
+		// the whole call.
+		for _, result := range results.Results {
+			if result.Error == nil {
+				continue
+			}
+			if err := translateDischargeRequired(result.Error); IsDischargeRequired(err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, result := range results.Results {
+		if result.Error != nil || result.Hash != "" {
+			continue
+		}
+		settings := make(map[string]interface{}, len(result.Settings))
+		for k, v := range result.Settings {
+			settings[k] = v
+		}
+		hash, err := SettingsHash(settings)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		results.Results[i].Hash = hash
+	}
+	return results.Results, nil
+}
+
+// ConsumeRemoteRelationChange publishes the specified relation change
+// event to the model on the other side of the relation. If the relation
+// no longer exists there, an error satisfying errors.IsNotFound is
+// returned; if the controller requires a macaroon to be discharged, a
+// *DischargeRequiredError is returned.
+func (st *State) ConsumeRemoteRelationChange(change params.RemoteRelationChangeEvent) error {
+	if change.RelationToken == "" {
+		return errors.NotValidf("change with empty relation token")
+	}
+	if change.ApplicationToken == "" {
+		return errors.NotValidf("change with empty application token")
+	}
+	args := params.RemoteRelationsChanges{
+		Changes: []params.RemoteRelationChangeEvent{change},
+	}
+	err := st.withDischarge(func() error {
+		var results params.ErrorResults
+		err := st.facade.FacadeCall("ConsumeRemoteRelationChange", args, &results)
+		if err != nil {
+			return translateDischargeRequired(base.TranslateError(err))
+		}
+		return translateDischargeRequired(base.OneResult(results.Results, nil))
+	})
+	return errors.Trace(err)
+}
+
+// ExportEntities allocates unique, remote entity IDs for the given
+// entities in the local model. The results are returned in the same
+// order as the tags.
+func (st *State) ExportEntities(tags []names.Tag) ([]params.TokenResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(tags))}
+	for i, tag := range tags {
+		if tag == nil {
+			return nil, errors.NotValidf("nil tag")
+		}
+		args.Entities[i].Tag = tag.String()
+	}
+	var results params.TokenResults
+	err := st.facade.FacadeCall("ExportEntities", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(tags)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results, nil
+}
+
+// GetToken returns the token associated with the entity with the
+// given tag.
+func (st *State) GetToken(tag names.Tag) (string, error) {
+	if tag == nil {
+		return "", errors.NotValidf("nil tag")
+	}
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: tag.String()}},
+	}
+	var results params.StringResults
+	err := st.facade.FacadeCall("GetTokens", args, &results)
+	if err != nil {
+		return "", errors.Trace(base.TranslateError(err))
+	}
+	var result params.StringResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return "", errors.Trace(err)
+	}
+	return result.Result, nil
+}
+
+// GetTokens returns the tokens associated with the entities with the
+// given tags. The results are returned in the same order as the tags;
+// an error for an individual entity, such as one satisfying
+// errors.IsNotFound for an entity that has no token, is reported in
+// its result.
+func (st *State) GetTokens(tags []names.Tag) ([]params.StringResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(tags))}
+	for i, tag := range tags {
+		if tag == nil {
+			return nil, errors.NotValidf("nil tag")
+		}
+		args.Entities[i].Tag = tag.String()
+	}
+	var results params.StringResults
+	err := st.facade.FacadeCall("GetTokens", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(tags)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results, nil
+}
+
+// RelationKey returns the key of the local relation identified by the
+// given token. It returns an error satisfying errors.IsNotFound if no
+// relation has the token. Controllers that do not support resolving
+// tokens cause an error satisfying errors.IsNotSupported to be returned
+// without making the call.
+func (st *State) RelationKey(token string) (string, error) {
+	if token == "" {
+		return "", errors.NotValidf("empty token")
+	}
+	if version := st.FacadeVersion(); version < relationKeysMinVersion {
+		return "", errors.NotSupportedf(
+			"resolving relation tokens (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, relationKeysMinVersion, version,
+		)
+	}
+	args := params.RemoteTokens{Tokens: []string{token}}
+	var results params.RelationKeyResults
+	err := st.facade.FacadeCall("RelationKeys", args, &results)
+	if err != nil {
+		return "", errors.Trace(base.TranslateError(err))
+	}
+	var result params.RelationKeyResult
+	if err := base.OneResult(results.Results, &result); err != nil {
+		return "", errors.Trace(err)
+	}
+	return result.Key, nil
+}
+
+// ImportRemoteEntity adds an entity to the remote entities collection
+// with the specified opaque token. If the entity has already been
+// imported, an error satisfying errors.IsAlreadyExists is returned.
+func (st *State) ImportRemoteEntity(tag names.Tag, token string) error {
+	if tag == nil {
+		return errors.NotValidf("nil tag")
+	}
+	if token == "" {
+		return errors.NotValidf("empty token for %q", tag.String())
+	}
+	args := params.RemoteEntityArgs{Args: []params.RemoteEntityArg{
+		{Tag: tag.String(), Token: token},
+	}}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("ImportRemoteEntities", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	return errors.Trace(base.OneResult(results.Results, nil))
+}
+
+// SaveMacaroon saves the macaroon used to authorise access to the
+// specified cross-model relation entity.
+func (st *State) SaveMacaroon(entity names.Tag, mac *macaroon.Macaroon) error {
+	if entity == nil {
+		return errors.NotValidf("nil tag")
+	}
+	if mac == nil {
+		return errors.NotValidf("nil macaroon for %q", entity.String())
+	}
+	args := params.EntityMacaroonArgs{Args: []params.EntityMacaroonArg{
+		{Tag: entity.String(), Macaroon: mac},
+	}}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("SaveMacaroons", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	return errors.Trace(base.OneResult(results.Results, nil))
+}
+
+// SetRemoteApplicationsStatus sets the status of the specified remote
+// applications, keyed on application name. Controllers that do not
+// support setting remote application status cause an error satisfying
+// errors.IsNotSupported to be returned without making the call.
+func (st *State) SetRemoteApplicationsStatus(statuses map[string]status.StatusInfo) error {
+	if version := st.FacadeVersion(); version < setStatusMinVersion {
+		return errors.NotSupportedf(
+			"setting remote application status (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, setStatusMinVersion, version,
+		)
+	}
+	applications := make([]string, 0, len(statuses))
+	for name := range statuses {
+		if !names.IsValidApplication(name) {
+			return errors.NotValidf("application name %q", name)
+		}
+		applications = append(applications, name)
+	}
+	sort.Strings(applications)
+	args := params.SetStatus{Entities: make([]params.EntityStatusArgs, len(applications))}
+	for i, name := range applications {
+		info := statuses[name]
+		args.Entities[i] = params.EntityStatusArgs{
+			Tag:    names.NewApplicationTag(name).String(),
+			Status: info.Status.String(),
+			Info:   info.Message,
+			Data:   info.Data,
+		}
+	}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("SetRemoteApplicationsStatus", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(applications)); err != nil {
+		return errors.Trace(err)
+	}
+	return results.Combine()
+}
+
+// SetRelationsSuspended sets the suspended status of the relations
+// with the given keys, recording the reason for the change. Settings
+// stop flowing over a suspended relation until it is resumed.
+// Suspending an already suspended relation, or resuming one that is
+// not suspended, succeeds without effect. Errors for individual
+// relations identify the relation key.
+func (st *State) SetRelationsSuspended(relationKeys []string, suspended bool, reason string) error {
+	args := params.RelationSuspendedArgs{Args: make([]params.RelationSuspendedArg, len(relationKeys))}
+	for i, key := range relationKeys {
+		if !names.IsValidRelation(key) {
+			return errors.NotValidf("relation key %q", key)
+		}
+		args.Args[i] = params.RelationSuspendedArg{
+			Tag:       names.NewRelationTag(key).String(),
+			Message:   reason,
+			Suspended: suspended,
+		}
+	}
+	var results params.ErrorResults
+	err := st.facade.FacadeCall("SetRelationsSuspended", args, &results)
+	if err != nil {
+		return errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(relationKeys)); err != nil {
+		return errors.Trace(err)
+	}
+	var errs []error
+	for i, err := range base.CollectErrors(results.Results) {
+		if err != nil {
+			errs = append(errs, errors.Annotatef(err, "relation %q", relationKeys[i]))
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
+
+// RemoteApplications returns the current state of the remote
+// applications with the specified names. The results are returned
+// in the same order as the names; an error for an individual
+// application is reported in its result.
+func (st *State) RemoteApplications(applications []string) ([]params.RemoteApplicationResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(applications))}
+	for i, name := range applications {
+		if !names.IsValidApplication(name) {
+			return nil, errors.NotValidf("application name %q", name)
+		}
+		args.Entities[i].Tag = names.NewApplicationTag(name).String()
+	}
+	var results params.RemoteApplicationResults
+	err := st.facade.FacadeCall("RemoteApplications", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(applications)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results, nil
+}
+
+// Relations returns the current state of the relations with the
+// specified keys. The results are returned in the same order as the
+// keys; an error for an individual relation is reported in its result.
+func (st *State) Relations(keys []string) ([]params.RemoteRelationResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(keys))}
+	for i, key := range keys {
+		if !names.IsValidRelation(key) {
+			return nil, errors.NotValidf("relation key %q", key)
+		}
+		args.Entities[i].Tag = names.NewRelationTag(key).String()
+	}
+	var results params.RemoteRelationResults
+	err := st.facade.FacadeCall("Relations", args, &results)
+	if err != nil {
+		return nil, errors.Trace(base.TranslateError(err))
+	}
+	if err := base.CheckResultCount(results.Results, len(keys)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results, nil
+}
@@ -0,0 +1,1583 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/juju/juju/api/base"
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/life"
+	"github.com/juju/juju/status"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/watcher"
+)
+
+var _ = gc.Suite(&remoteRelationsSuite{})
+
+type remoteRelationsSuite struct {
+	coretesting.BaseSuite
+}
+
+// versionedCaller is an APICallerFunc that reports the given
+// facade version as the best one available.
+type versionedCaller struct {
+	apitesting.APICallerFunc
+	version int
+}
+
+func (v versionedCaller) BestFacadeVersion(facade string) int {
+	return v.version
+}
+
+func (s *remoteRelationsSuite) TestNewState(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	c.Assert(st, gc.NotNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteApplications(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "WatchRemoteApplications")
+		c.Check(arg, gc.IsNil)
+		c.Assert(result, gc.FitsTypeOf, &params.StringsWatchResult{})
+		*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+			Error: &params.Error{Message: "FAIL"},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteApplications()
+	c.Check(err, gc.ErrorMatches, "FAIL")
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestRemoteApplications(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(version, gc.Equals, 0)
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "RemoteApplications")
+		c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+			{Tag: "application-mysql"}, {Tag: "application-db2"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.RemoteApplicationResults{})
+		*(result.(*params.RemoteApplicationResults)) = params.RemoteApplicationResults{
+			Results: []params.RemoteApplicationResult{{
+				Result: &params.RemoteApplication{Name: "mysql", Life: params.Alive},
+			}, {
+				Error: &params.Error{Code: params.CodeNotFound, Message: "db2 not found"},
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	results, err := st.RemoteApplications([]string{"mysql", "db2"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(results, jc.DeepEquals, []params.RemoteApplicationResult{{
+		Result: &params.RemoteApplication{Name: "mysql", Life: params.Alive},
+	}, {
+		Error: &params.Error{Code: params.CodeNotFound, Message: "db2 not found"},
+	}})
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestRemoteApplicationsInvalidName(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RemoteApplications([]string{"mysql", "!@#"})
+	c.Check(err, gc.ErrorMatches, `application name "!@#" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestRemoteApplicationsCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return errors.New("boom")
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RemoteApplications([]string{"mysql"})
+	c.Check(err, gc.ErrorMatches, "boom")
+}
+
+func (s *remoteRelationsSuite) TestRemoteApplicationsResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.RemoteApplicationResults)) = params.RemoteApplicationResults{
+			Results: []params.RemoteApplicationResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RemoteApplications([]string{"mysql"})
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 2`)
+}
+
+func (s *remoteRelationsSuite) TestRelations(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "Relations")
+		c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+			{Tag: "relation-wordpress.db#mysql.db"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.RemoteRelationResults{})
+		*(result.(*params.RemoteRelationResults)) = params.RemoteRelationResults{
+			Results: []params.RemoteRelationResult{{
+				Error: &params.Error{Message: "FAIL"},
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	results, err := st.Relations([]string{"wordpress:db mysql:db"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Check(results[0].Error, gc.ErrorMatches, "FAIL")
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestRelationsInvalidKey(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.Relations([]string{"not a key"})
+	c.Check(err, gc.ErrorMatches, `relation key "not a key" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestRelationsResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.RemoteRelationResults)) = params.RemoteRelationResults{}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.Relations([]string{"wordpress:db mysql:db"})
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 0`)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteRelations(c *gc.C) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(id, gc.Equals, "")
+			c.Check(request, gc.Equals, "WatchRemoteRelations")
+			c.Check(arg, gc.IsNil)
+			c.Assert(result, gc.FitsTypeOf, &params.StringsWatchResult{})
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: "66",
+				Changes:          []string{"wordpress:db mysql:db"},
+			}
+		case "StringsWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	w, err := st.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteRelationsError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+			Error: &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteRelations()
+	c.Check(err, gc.ErrorMatches, "permission denied")
+	c.Check(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteRelationsCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return errors.New("boom")
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteRelations()
+	c.Check(err, gc.ErrorMatches, "boom")
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteApplicationRelations(c *gc.C) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(id, gc.Equals, "")
+			c.Check(request, gc.Equals, "WatchRemoteApplicationRelations")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "application-mysql"},
+			}})
+			c.Assert(result, gc.FitsTypeOf, &params.StringsWatchResults{})
+			*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+				Results: []params.StringsWatchResult{{
+					StringsWatcherId: "66",
+					Changes:          []string{"wordpress:db mysql:db"},
+				}},
+			}
+		case "StringsWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	w, err := st.WatchRemoteApplicationRelations("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteApplicationRelationsInvalidName(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteApplicationRelations("mysql/0")
+	c.Check(err, gc.ErrorMatches, `application name "mysql/0" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteApplicationRelationsError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+			Results: []params.StringsWatchResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "application not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteApplicationRelations("mysql")
+	c.Check(err, gc.ErrorMatches, "application not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestWatchRemoteApplicationRelationsTooManyResults(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+			Results: []params.StringsWatchResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRemoteApplicationRelations("mysql")
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 2`)
+}
+
+func (s *remoteRelationsSuite) TestWatchApplicationRelations(c *gc.C) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(version, gc.Equals, 3)
+			c.Check(request, gc.Equals, "WatchApplicationRelations")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "application-mysql"},
+			}})
+			c.Assert(result, gc.FitsTypeOf, &params.ApplicationRelationsWatchResults{})
+			*(result.(*params.ApplicationRelationsWatchResults)) = params.ApplicationRelationsWatchResults{
+				Results: []params.ApplicationRelationsWatchResult{{
+					ApplicationRelationsWatcherId: "66",
+					Changes: []params.ApplicationRelationChange{{
+						RelationTag:   "relation-wordpress.db#mysql.db",
+						Life:          params.Alive,
+						ChangedUnits:  []params.RelationUnitSettingsVersion{{UnitTag: "unit-mysql-0", Version: 2}},
+						DepartedUnits: []string{"unit-mysql-1"},
+					}},
+				}},
+			}
+		case "ApplicationRelationsWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 3})
+	w, err := st.WatchApplicationRelations("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []watcher.ApplicationRelationsChange{{
+			Key:      "wordpress:db mysql:db",
+			Life:     life.Alive,
+			Changed:  map[string]watcher.UnitSettings{"mysql/0": {Version: 2}},
+			Departed: []string{"mysql/1"},
+		}})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchApplicationRelationsOldFacade(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 2})
+	_, err := st.WatchApplicationRelations("mysql")
+	c.Check(err, gc.ErrorMatches, `watching application relations \(requires RemoteRelations facade version 3, controller has version 2\) not supported`)
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *remoteRelationsSuite) TestWatchApplicationRelationsInvalidName(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 3})
+	_, err := st.WatchApplicationRelations("mysql/0")
+	c.Check(err, gc.ErrorMatches, `application name "mysql/0" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestWatchApplicationRelationsError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ApplicationRelationsWatchResults)) = params.ApplicationRelationsWatchResults{
+			Results: []params.ApplicationRelationsWatchResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "application not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 3})
+	_, err := st.WatchApplicationRelations("mysql")
+	c.Check(err, gc.ErrorMatches, "application not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationsForRemoteApplications(c *gc.C) {
+	var callCount int
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchRemoteApplicationRelations")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "application-mysql"},
+				{Tag: "application-postgresql"},
+			}})
+			*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+				Results: []params.StringsWatchResult{{
+					StringsWatcherId: "66",
+					Changes:          []string{"wordpress:db mysql:db"},
+				}, {
+					Error: &params.Error{Code: params.CodeNotFound, Message: "postgresql not found"},
+				}},
+			}
+			callCount++
+		case "StringsWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	watchers, errs, err := st.WatchRelationsForRemoteApplications([]string{"mysql", "mysql/0", "postgresql"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+	c.Assert(watchers, gc.HasLen, 3)
+	c.Assert(errs, gc.HasLen, 3)
+
+	c.Check(errs[0], jc.ErrorIsNil)
+	c.Check(errs[1], gc.ErrorMatches, `application name "mysql/0" not valid`)
+	c.Check(watchers[1], gc.IsNil)
+	c.Check(errs[2], gc.ErrorMatches, "postgresql not found")
+	c.Check(errs[2], jc.Satisfies, errors.IsNotFound)
+	c.Check(watchers[2], gc.IsNil)
+
+	w := watchers[0]
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationsForRemoteApplicationsAllInvalid(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	watchers, errs, err := st.WatchRelationsForRemoteApplications([]string{"mysql/0"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(watchers, jc.DeepEquals, []watcher.StringsWatcher{nil})
+	c.Check(errs[0], jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationsForRemoteApplicationsCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return errors.New("boom")
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, _, err := st.WatchRelationsForRemoteApplications([]string{"mysql"})
+	c.Check(err, gc.ErrorMatches, "boom")
+}
+
+func (s *remoteRelationsSuite) TestWatchLocalRelationUnits(c *gc.C) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchLocalRelationUnits")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "relation-wordpress.db#mysql.db"},
+			}})
+			c.Assert(result, gc.FitsTypeOf, &params.RelationUnitsWatchResults{})
+			*(result.(*params.RelationUnitsWatchResults)) = params.RelationUnitsWatchResults{
+				Results: []params.RelationUnitsWatchResult{{
+					RelationUnitsWatcherId: "42",
+					Changes: params.RelationUnitsChange{
+						Changed:  map[string]params.UnitSettings{"mysql/0": {Version: 3}},
+						Departed: []string{"mysql/1"},
+					},
+				}},
+			}
+		case "RelationUnitsWatcher":
+			c.Check(id, gc.Equals, "42")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	w, err := st.WatchLocalRelationUnits("wordpress:db mysql:db")
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case change := <-w.Changes():
+		c.Check(change, jc.DeepEquals, watcher.RelationUnitsChange{
+			Changed:  map[string]watcher.UnitSettings{"mysql/0": {Version: 3}},
+			Departed: []string{"mysql/1"},
+		})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchLocalRelationUnitsInvalidKey(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchLocalRelationUnits("wordpress")
+	c.Check(err, gc.ErrorMatches, `relation key "wordpress" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestWatchLocalRelationUnitsError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.RelationUnitsWatchResults)) = params.RelationUnitsWatchResults{
+			Results: []params.RelationUnitsWatchResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchLocalRelationUnits("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestWatchLocalRelationUnitsTooManyResults(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.RelationUnitsWatchResults)) = params.RelationUnitsWatchResults{
+			Results: []params.RelationUnitsWatchResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchLocalRelationUnits("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 2")
+}
+
+func (s *remoteRelationsSuite) TestRelationUnitSettings(c *gc.C) {
+	var callCount int
+	relationUnits := []params.RelationUnit{{
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "unit-mysql-0",
+	}, {
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "unit-mysql-1",
+	}, {
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "unit-mysql-2",
+	}}
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "RelationUnitSettings")
+		c.Check(arg, jc.DeepEquals, params.RelationUnits{RelationUnits: relationUnits})
+		c.Assert(result, gc.FitsTypeOf, &params.SettingsResults{})
+		*(result.(*params.SettingsResults)) = params.SettingsResults{
+			Results: []params.SettingsResult{{
+				Settings: params.Settings{"foo": "bar"},
+			}, {
+				Error: &params.Error{Code: params.CodeNotFound, Message: "unit not found"},
+			}, {
+				Settings: params.Settings{"baz": "qux"},
+				Hash:     "controller-hash",
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	results, err := st.RelationUnitSettings(relationUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	fooHash, err := remoterelations.SettingsHash(map[string]interface{}{"foo": "bar"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(results, jc.DeepEquals, []params.SettingsResult{{
+		Settings: params.Settings{"foo": "bar"},
+		Hash:     fooHash,
+	}, {
+		Error: &params.Error{Code: params.CodeNotFound, Message: "unit not found"},
+	}, {
+		Settings: params.Settings{"baz": "qux"},
+		Hash:     "controller-hash",
+	}})
+	// All units are fetched with a single API call.
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestRelationUnitSettingsInvalidTags(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RelationUnitSettings([]params.RelationUnit{{
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "mysql/0",
+	}})
+	c.Check(err, gc.ErrorMatches, `unit tag "mysql/0" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+	_, err = st.RelationUnitSettings([]params.RelationUnit{{
+		Relation: "wordpress:db mysql:db",
+		Unit:     "unit-mysql-0",
+	}})
+	c.Check(err, gc.ErrorMatches, `relation tag "wordpress:db mysql:db" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestRelationUnitSettingsCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return &params.Error{Code: params.CodeNotFound, Message: "relation not found"}
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RelationUnitSettings([]params.RelationUnit{{
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "unit-mysql-0",
+	}})
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestRelationUnitSettingsResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.RelationUnitSettings([]params.RelationUnit{{
+		Relation: "relation-wordpress.db#mysql.db",
+		Unit:     "unit-mysql-0",
+	}})
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 0`)
+}
+
+func (s *remoteRelationsSuite) TestConsumeRemoteRelationChange(c *gc.C) {
+	var callCount int
+	change := params.RemoteRelationChangeEvent{
+		RelationToken:    "rel-token",
+		ApplicationToken: "app-token",
+		Life:             params.Alive,
+		ChangedUnits: []params.RemoteRelationUnitChange{{
+			UnitId:   1,
+			Settings: map[string]interface{}{"foo": "bar"},
+		}},
+		DepartedUnits: []int{2},
+	}
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "ConsumeRemoteRelationChange")
+		c.Check(arg, jc.DeepEquals, params.RemoteRelationsChanges{
+			Changes: []params.RemoteRelationChangeEvent{change},
+		})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(change)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestConsumeRemoteRelationChangeNotFound(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(params.RemoteRelationChangeEvent{
+		RelationToken:    "rel-token",
+		ApplicationToken: "app-token",
+	})
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestConsumeRemoteRelationChangeResultError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeTryAgain, Message: "try again later"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(params.RemoteRelationChangeEvent{
+		RelationToken:    "rel-token",
+		ApplicationToken: "app-token",
+	})
+	c.Check(err, gc.ErrorMatches, "try again later")
+	c.Check(params.IsCodeTryAgain(err), jc.IsTrue)
+}
+
+func (s *remoteRelationsSuite) TestConsumeRemoteRelationChangeCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return errors.New("connection is shut down")
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(params.RemoteRelationChangeEvent{
+		RelationToken:    "rel-token",
+		ApplicationToken: "app-token",
+	})
+	c.Check(err, gc.ErrorMatches, "connection is shut down")
+}
+
+func (s *remoteRelationsSuite) TestConsumeRemoteRelationChangeMissingTokens(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ConsumeRemoteRelationChange(params.RemoteRelationChangeEvent{
+		ApplicationToken: "app-token",
+	})
+	c.Check(err, gc.ErrorMatches, "change with empty relation token not valid")
+	err = st.ConsumeRemoteRelationChange(params.RemoteRelationChangeEvent{
+		RelationToken: "rel-token",
+	})
+	c.Check(err, gc.ErrorMatches, "change with empty application token not valid")
+}
+
+func (s *remoteRelationsSuite) TestExportEntities(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "ExportEntities")
+		c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+			{Tag: "application-mysql"}, {Tag: "relation-wordpress.db#mysql.db"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.TokenResults{})
+		*(result.(*params.TokenResults)) = params.TokenResults{
+			Results: []params.TokenResult{{
+				Token: "token-mysql",
+			}, {
+				Error: &params.Error{Code: params.CodeAlreadyExists, Message: "already exported"},
+			}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	results, err := st.ExportEntities([]names.Tag{
+		names.NewApplicationTag("mysql"),
+		names.NewRelationTag("wordpress:db mysql:db"),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(results, jc.DeepEquals, []params.TokenResult{{
+		Token: "token-mysql",
+	}, {
+		Error: &params.Error{Code: params.CodeAlreadyExists, Message: "already exported"},
+	}})
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestExportEntitiesResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.TokenResults)) = params.TokenResults{
+			Results: []params.TokenResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ExportEntities([]names.Tag{names.NewApplicationTag("mysql")})
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 2`)
+}
+
+func (s *remoteRelationsSuite) TestExportEntitiesNilTag(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.ExportEntities([]names.Tag{nil})
+	c.Check(err, gc.ErrorMatches, "nil tag not valid")
+}
+
+func (s *remoteRelationsSuite) TestGetToken(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "GetTokens")
+		c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+			{Tag: "application-mysql"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.StringResults{})
+		*(result.(*params.StringResults)) = params.StringResults{
+			Results: []params.StringResult{{Result: "token-mysql"}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	token, err := st.GetToken(names.NewApplicationTag("mysql"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(token, gc.Equals, "token-mysql")
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestGetTokenNotFound(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringResults)) = params.StringResults{
+			Results: []params.StringResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "token not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.GetToken(names.NewApplicationTag("mysql"))
+	c.Check(err, gc.ErrorMatches, "token not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestGetTokenResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.GetToken(names.NewApplicationTag("mysql"))
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 0")
+}
+
+func (s *remoteRelationsSuite) TestImportRemoteEntity(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "ImportRemoteEntities")
+		c.Check(arg, jc.DeepEquals, params.RemoteEntityArgs{Args: []params.RemoteEntityArg{
+			{Tag: "application-mysql", Token: "token-mysql"},
+		}})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ImportRemoteEntity(names.NewApplicationTag("mysql"), "token-mysql")
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestImportRemoteEntityAlreadyExists(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeAlreadyExists, Message: "token already exists"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ImportRemoteEntity(names.NewApplicationTag("mysql"), "token-mysql")
+	c.Check(err, gc.ErrorMatches, "token already exists")
+	c.Check(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *remoteRelationsSuite) TestImportRemoteEntityResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ImportRemoteEntity(names.NewApplicationTag("mysql"), "token-mysql")
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 2")
+}
+
+func (s *remoteRelationsSuite) TestImportRemoteEntityEmptyToken(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.ImportRemoteEntity(names.NewApplicationTag("mysql"), "")
+	c.Check(err, gc.ErrorMatches, `empty token for "application-mysql" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestSaveMacaroon(c *gc.C) {
+	mac, err := macaroon.New([]byte("secret"), "id", "location")
+	c.Assert(err, jc.ErrorIsNil)
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "SaveMacaroons")
+		c.Assert(arg, gc.FitsTypeOf, params.EntityMacaroonArgs{})
+
+		// Check that the macaroon survives the trip over the wire.
+		data, err := json.Marshal(arg)
+		c.Assert(err, jc.ErrorIsNil)
+		var args params.EntityMacaroonArgs
+		err = json.Unmarshal(data, &args)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(args.Args, gc.HasLen, 1)
+		c.Check(args.Args[0].Tag, gc.Equals, "relation-wordpress.db#mysql.db")
+		c.Check(args.Args[0].Macaroon.Id(), gc.Equals, mac.Id())
+		c.Check(args.Args[0].Macaroon.Signature(), jc.DeepEquals, mac.Signature())
+
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err = st.SaveMacaroon(names.NewRelationTag("wordpress:db mysql:db"), mac)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestSaveMacaroonResultError(c *gc.C) {
+	mac, err := macaroon.New([]byte("secret"), "id", "location")
+	c.Assert(err, jc.ErrorIsNil)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err = st.SaveMacaroon(names.NewRelationTag("wordpress:db mysql:db"), mac)
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestSaveMacaroonNil(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SaveMacaroon(names.NewRelationTag("wordpress:db mysql:db"), nil)
+	c.Check(err, gc.ErrorMatches, `nil macaroon for "relation-wordpress.db#mysql.db" not valid`)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *remoteRelationsSuite) TestFacadeVersion(c *gc.C) {
+	st := remoterelations.NewState(versionedCaller{version: 3})
+	c.Check(st.FacadeVersion(), gc.Equals, 3)
+}
+
+func (s *remoteRelationsSuite) TestSetRemoteApplicationsStatus(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(version, gc.Equals, 2)
+		c.Check(request, gc.Equals, "SetRemoteApplicationsStatus")
+		c.Check(arg, jc.DeepEquals, params.SetStatus{Entities: []params.EntityStatusArgs{{
+			Tag:    "application-db2",
+			Status: "error",
+			Info:   "offer removed",
+		}, {
+			Tag:    "application-mysql",
+			Status: "active",
+			Info:   "ready",
+		}}})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}, {}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 2})
+	err := st.SetRemoteApplicationsStatus(map[string]status.StatusInfo{
+		"mysql": {Status: status.Active, Message: "ready"},
+		"db2":   {Status: status.Error, Message: "offer removed"},
+	})
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestSetRemoteApplicationsStatusResultErrors(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Message: "db2 not found"},
+			}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 2})
+	err := st.SetRemoteApplicationsStatus(map[string]status.StatusInfo{
+		"mysql": {Status: status.Active},
+		"db2":   {Status: status.Error},
+	})
+	c.Check(err, gc.ErrorMatches, "db2 not found")
+}
+
+func (s *remoteRelationsSuite) TestSetRemoteApplicationsStatusOldFacade(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 1})
+	err := st.SetRemoteApplicationsStatus(map[string]status.StatusInfo{
+		"mysql": {Status: status.Active},
+	})
+	c.Check(err, gc.ErrorMatches, `setting remote application status \(requires RemoteRelations facade version 2, controller has version 1\) not supported`)
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *remoteRelationsSuite) TestNewStateWithRetry(c *gc.C) {
+	var calls []string
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		calls = append(calls, request)
+		return io.EOF
+	})
+	st, err := remoterelations.NewStateWithRetry(apiCaller, base.RetryConfig{
+		Clock:    jujutesting.NewClock(time.Time{}),
+		Attempts: 2,
+		Delay:    time.Nanosecond,
+		MaxDelay: time.Nanosecond,
+		Stop:     closedChannel(),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Watchers are never retried.
+	_, err = st.WatchRemoteRelations()
+	c.Check(errors.Cause(err), gc.Equals, io.EOF)
+	c.Check(calls, jc.DeepEquals, []string{"WatchRemoteRelations"})
+
+	// Other calls are retried until stopped.
+	calls = nil
+	_, err = st.GetToken(names.NewApplicationTag("mysql"))
+	c.Check(err, gc.ErrorMatches, "GetTokens stopped after 1 attempts: EOF")
+	c.Check(calls, jc.DeepEquals, []string{"GetTokens"})
+}
+
+func (s *remoteRelationsSuite) TestNewStateWithRetryInvalidConfig(c *gc.C) {
+	_, err := remoterelations.NewStateWithRetry(apitesting.APICallerFunc(nil), base.RetryConfig{})
+	c.Check(err, gc.ErrorMatches, "nil Clock not valid")
+}
+
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationSuspendedStatus(c *gc.C) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchRelationSuspendedStatus")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "relation-wordpress.db#mysql.db"},
+			}})
+			c.Assert(result, gc.FitsTypeOf, &params.RelationStatusWatchResults{})
+			*(result.(*params.RelationStatusWatchResults)) = params.RelationStatusWatchResults{
+				Results: []params.RelationLifeSuspendedStatusWatchResult{{
+					RelationStatusWatcherId: "66",
+					Changes: []params.RelationLifeSuspendedStatusChange{{
+						Key:       "wordpress:db mysql:db",
+						Life:      params.Alive,
+						Suspended: true,
+					}},
+				}},
+			}
+		case "RelationStatusWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	w, err := st.WatchRelationSuspendedStatus("wordpress:db mysql:db")
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []watcher.RelationStatusChange{{
+			Key:       "wordpress:db mysql:db",
+			Life:      life.Alive,
+			Suspended: true,
+		}})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationSuspendedStatusInvalidKey(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRelationSuspendedStatus("mysql")
+	c.Check(err, gc.ErrorMatches, `relation key "mysql" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationSuspendedStatusResultError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.RelationStatusWatchResults)) = params.RelationStatusWatchResults{
+			Results: []params.RelationLifeSuspendedStatusWatchResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRelationSuspendedStatus("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestWatchRelationSuspendedStatusResultCount(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchRelationSuspendedStatus("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 0")
+}
+
+func (s *remoteRelationsSuite) TestWatchOfferStatus(c *gc.C) {
+	offerUUID := utils.MustNewUUID().String()
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchOfferStatus")
+			c.Check(arg, jc.DeepEquals, params.OfferArgs{
+				Args: []params.OfferArg{{OfferUUID: offerUUID}},
+			})
+			c.Assert(result, gc.FitsTypeOf, &params.OfferStatusWatchResults{})
+			*(result.(*params.OfferStatusWatchResults)) = params.OfferStatusWatchResults{
+				Results: []params.OfferStatusWatchResult{{
+					OfferStatusWatcherId: "66",
+					Changes: []params.OfferStatusChange{{
+						OfferName: "hosted-mysql",
+						Status:    "active",
+					}},
+				}},
+			}
+		case "OfferStatusWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	w, err := st.WatchOfferStatus(offerUUID)
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []watcher.OfferStatusChange{{
+			Name:   "hosted-mysql",
+			Status: status.Active,
+		}})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchOfferStatusInvalidUUID(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchOfferStatus("hosted-mysql")
+	c.Check(err, gc.ErrorMatches, `offer UUID "hosted-mysql" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestWatchOfferStatusResultCount(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.OfferStatusWatchResults)) = params.OfferStatusWatchResults{
+			Results: []params.OfferStatusWatchResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	_, err := st.WatchOfferStatus(utils.MustNewUUID().String())
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 2")
+}
+
+func (s *remoteRelationsSuite) TestSetRelationsSuspended(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "SetRelationsSuspended")
+		c.Check(arg, jc.DeepEquals, params.RelationSuspendedArgs{Args: []params.RelationSuspendedArg{{
+			Tag:       "relation-wordpress.db#mysql.db",
+			Message:   "payment overdue",
+			Suspended: true,
+		}}})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SetRelationsSuspended([]string{"wordpress:db mysql:db"}, true, "payment overdue")
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestSetRelationsSuspendedResultErrors(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{
+				{Error: &params.Error{Message: "not found", Code: params.CodeNotFound}},
+				{},
+			},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SetRelationsSuspended([]string{"wordpress:db mysql:db", "django:db pgsql:db"}, false, "")
+	c.Check(err, gc.ErrorMatches, `relation "wordpress:db mysql:db": not found`)
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestSetRelationsSuspendedMultipleErrors(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{
+				{Error: &params.Error{Message: "not found", Code: params.CodeNotFound}},
+				{Error: &params.Error{Message: "permission denied", Code: params.CodeUnauthorized}},
+			},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SetRelationsSuspended([]string{"wordpress:db mysql:db", "django:db pgsql:db"}, true, "")
+	c.Check(err, gc.ErrorMatches, `relation "wordpress:db mysql:db": not found
+relation "django:db pgsql:db": permission denied`)
+}
+
+func (s *remoteRelationsSuite) TestSetRelationsSuspendedInvalidKey(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SetRelationsSuspended([]string{"mysql"}, true, "")
+	c.Check(err, gc.ErrorMatches, `relation key "mysql" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestSetRelationsSuspendedResultCountMismatch(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}, {}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(apiCaller)
+	err := st.SetRelationsSuspended([]string{"wordpress:db mysql:db"}, true, "")
+	c.Check(err, gc.ErrorMatches, `expected 1 result\(s\), got 2`)
+}
+
+func (s *remoteRelationsSuite) TestObserved(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return &params.Error{Code: params.CodeNotFound, Message: "not found"}
+	})
+	observer := &recordingObserver{}
+	st := remoterelations.NewState(apiCaller).Observed(observer, jujutesting.NewClock(time.Time{}))
+	_, err := st.GetToken(names.NewApplicationTag("mysql"))
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+	c.Check(observer.started, jc.DeepEquals, []string{"RemoteRelations.GetTokens"})
+	c.Check(observer.failed, jc.DeepEquals, []string{"not found"})
+}
+
+// recordingObserver is a base.CallObserver that records the calls
+// started and the codes of those that failed.
+type recordingObserver struct {
+	started []string
+	failed  []string
+}
+
+func (o *recordingObserver) CallStarted(call base.CallInfo) {
+	o.started = append(o.started, call.Facade+"."+call.Request)
+}
+
+func (o *recordingObserver) CallSucceeded(call base.CallInfo, duration time.Duration) {}
+
+func (o *recordingObserver) CallFailed(call base.CallInfo, duration time.Duration, err error, code string) {
+	o.failed = append(o.failed, code)
+}
+
+func (s *remoteRelationsSuite) TestWatchEgressAddressesForRelation(c *gc.C) {
+	stopped := make(chan struct{})
+	next := make(chan []string)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchEgressAddressesForRelations")
+			c.Check(arg, jc.DeepEquals, params.Entities{Entities: []params.Entity{
+				{Tag: "relation-wordpress.db#mysql.db"},
+			}})
+			c.Assert(result, gc.FitsTypeOf, &params.StringsWatchResults{})
+			*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+				Results: []params.StringsWatchResult{{
+					StringsWatcherId: "66",
+					Changes:          []string{"10.0.0.0/24"},
+				}},
+			}
+		case "StringsWatcher":
+			c.Check(id, gc.Equals, "66")
+			switch request {
+			case "Next":
+				select {
+				case changes := <-next:
+					out := (*result.(*interface{})).(*params.StringsWatchResult)
+					out.Changes = changes
+					return nil
+				case <-stopped:
+					return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+				}
+			case "Stop":
+				close(stopped)
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	w, err := st.WatchEgressAddressesForRelation("wordpress:db mysql:db")
+	c.Assert(err, jc.ErrorIsNil)
+	assertChange := func(expect ...string) {
+		select {
+		case changes := <-w.Changes():
+			c.Check(changes, jc.DeepEquals, expect)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for change")
+		}
+	}
+	assertChange("10.0.0.0/24")
+
+	// Each change holds the full set of CIDRs.
+	select {
+	case next <- []string{"10.0.0.0/24", "192.168.1.0/24"}:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out sending change")
+	}
+	assertChange("10.0.0.0/24", "192.168.1.0/24")
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestWatchEgressAddressesForRelationInvalidKey(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	_, err := st.WatchEgressAddressesForRelation("mysql")
+	c.Check(err, gc.ErrorMatches, `relation key "mysql" not valid`)
+}
+
+func (s *remoteRelationsSuite) TestWatchEgressAddressesForRelationNotSupported(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 5})
+	_, err := st.WatchEgressAddressesForRelation("wordpress:db mysql:db")
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *remoteRelationsSuite) TestWatchEgressAddressesForRelationResultError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.StringsWatchResults)) = params.StringsWatchResults{
+			Results: []params.StringsWatchResult{{
+				Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	_, err := st.WatchEgressAddressesForRelation("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestWatchEgressAddressesForRelationResultCount(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	_, err := st.WatchEgressAddressesForRelation("wordpress:db mysql:db")
+	c.Check(err, gc.ErrorMatches, "expected 1 result, got 0")
+}
+
+func ingressChange(networks ...string) params.IngressNetworksChangeEvent {
+	return params.IngressNetworksChangeEvent{
+		RelationToken:    "token-relation",
+		ApplicationToken: "token-application",
+		Networks:         networks,
+		IngressRequired:  true,
+	}
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChange(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "PublishIngressNetworkChanges")
+		c.Check(arg, jc.DeepEquals, params.IngressNetworksChanges{
+			Changes: []params.IngressNetworksChangeEvent{ingressChange("10.0.0.0/24", "2001:db8::/32")},
+		})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	err := st.PublishIngressNetworkChange(ingressChange("10.0.0.0/24", "2001:db8::/32"), false)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChangeAllowOpen(c *gc.C) {
+	var callCount int
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	err := st.PublishIngressNetworkChange(ingressChange("0.0.0.0/0", "::/0"), true)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChangeInvalid(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	noRelationToken := ingressChange()
+	noRelationToken.RelationToken = ""
+	noApplicationToken := ingressChange()
+	noApplicationToken.ApplicationToken = ""
+	for i, test := range []struct {
+		change params.IngressNetworksChangeEvent
+		err    string
+	}{{
+		change: noRelationToken,
+		err:    "empty relation token not valid",
+	}, {
+		change: noApplicationToken,
+		err:    "empty application token not valid",
+	}, {
+		change: ingressChange("10.0.0.0/24", "10.0.0.1"),
+		err:    `ingress network "10.0.0.1" not valid`,
+	}, {
+		change: ingressChange("10.0.0.0/33"),
+		err:    `ingress network "10.0.0.0/33" not valid`,
+	}, {
+		change: ingressChange("0.0.0.0/0"),
+		err:    `ingress network "0.0.0.0/0" admits all addresses`,
+	}, {
+		change: ingressChange("::/0"),
+		err:    `ingress network "::/0" admits all addresses`,
+	}} {
+		c.Logf("test %d: %v", i, test.change)
+		err := st.PublishIngressNetworkChange(test.change, false)
+		c.Check(err, gc.ErrorMatches, test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChangeNotSupported(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected API call")
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 5})
+	err := st.PublishIngressNetworkChange(ingressChange("10.0.0.0/24"), false)
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChangeResultError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Code: params.CodeUnauthorized, Message: "permission denied"},
+			}},
+		}
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	err := st.PublishIngressNetworkChange(ingressChange("10.0.0.0/24"), false)
+	c.Check(err, gc.ErrorMatches, "permission denied")
+	c.Check(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *remoteRelationsSuite) TestPublishIngressNetworkChangeCallError(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return &params.Error{Code: params.CodeNotFound, Message: "relation not found"}
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 6})
+	err := st.PublishIngressNetworkChange(ingressChange("10.0.0.0/24"), false)
+	c.Check(err, gc.ErrorMatches, "relation not found")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+
+	apiwatcher "github.com/juju/juju/api/watcher"
+)
+
+// Resumable returns a copy of the State whose WatchRemoteApplications,
+// WatchRemoteRelations and WatchLocalRelationUnits watchers re-establish
+// themselves, by making the original watch call again, when they fail.
+// The fresh initial event of a re-established watcher is delivered on
+// the same Changes channel.
+func (st *State) Resumable(config apiwatcher.ResumeConfig) (*State, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	resumable := *st
+	resumable.resume = &config
+	return &resumable, nil
+}
+
+// unresumable returns a copy of the State whose watchers are not
+// re-established when they fail.
+func (st *State) unresumable() *State {
+	unresumable := *st
+	unresumable.resume = nil
+	return &unresumable
+}
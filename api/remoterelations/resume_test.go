@@ -0,0 +1,86 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/clock"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	apiwatcher "github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+func (s *remoteRelationsSuite) TestResumableWatchRemoteRelations(c *gc.C) {
+	var mu sync.Mutex
+	var watchCalls int
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			c.Check(request, gc.Equals, "WatchRemoteRelations")
+			mu.Lock()
+			watchCalls++
+			watcherId := fmt.Sprint(watchCalls)
+			mu.Unlock()
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: watcherId,
+				Changes:          []string{"wordpress:db mysql:db"},
+			}
+		case "StringsWatcher":
+			switch request {
+			case "Next":
+				if id == "1" {
+					return &params.Error{Message: "connection is shut down"}
+				}
+				<-stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				if id != "1" {
+					close(stopped)
+				}
+			}
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	})
+	st, err := remoterelations.NewState(apiCaller).Resumable(apiwatcher.ResumeConfig{
+		Clock:    clock.WallClock,
+		Attempts: 1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	w, err := st.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	// The initial event is delivered again by the re-established
+	// watcher after the first one fails.
+	for i := 0; i < 2; i++ {
+		select {
+		case changes := <-w.Changes():
+			c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c.Check(watchCalls, gc.Equals, 2)
+}
+
+func (s *remoteRelationsSuite) TestResumableInvalidConfig(c *gc.C) {
+	_, err := remoterelations.NewState(apitesting.APICallerFunc(nil)).Resumable(apiwatcher.ResumeConfig{})
+	c.Check(err, gc.ErrorMatches, "nil Clock not valid")
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// fullRelationUnitSettingsMinVersion is the first version of the
+// RemoteRelations facade that supports FullRelationUnitSettings.
+const fullRelationUnitSettingsMinVersion = 5
+
+// RelationUnitSnapshot holds the settings of a local unit in scope of
+// a relation, as returned by FullRelationUnitSettings.
+type RelationUnitSnapshot struct {
+	// Unit is the name of the unit.
+	Unit string
+
+	// Settings holds the unit's settings in the relation.
+	Settings map[string]interface{}
+
+	// Version is the version of the settings.
+	Version int64
+}
+
+// FullRelationUnitSettings returns the settings of every local unit
+// in scope of the relation with the given key, and their versions, so
+// that they can be published in full when a cross-model relation is
+// first established. The controller may return a large relation in
+// several pages, which are fetched in turn; as the pages are not read
+// atomically, a unit reported on more than one page is reported once,
+// with its latest settings. Controllers that do not support the call
+// cause an error satisfying errors.IsNotSupported to be returned
+// without making it.
+func (st *State) FullRelationUnitSettings(relationKey string) ([]RelationUnitSnapshot, error) {
+	if !names.IsValidRelation(relationKey) {
+		return nil, errors.NotValidf("relation key %q", relationKey)
+	}
+	if version := st.FacadeVersion(); version < fullRelationUnitSettingsMinVersion {
+		return nil, errors.NotSupportedf(
+			"getting full relation unit settings (requires %s facade version %d, controller has version %d)",
+			remoteRelationsFacade, fullRelationUnitSettingsMinVersion, version,
+		)
+	}
+	relationTag := names.NewRelationTag(relationKey).String()
+	var snapshots []RelationUnitSnapshot
+	indices := make(map[string]int)
+	seenTokens := set.NewStrings()
+	var pageToken string
+	for {
+		args := params.FullRelationUnitSettingsArgs{
+			Args: []params.FullRelationUnitSettingsArg{{
+				RelationTag: relationTag,
+				PageToken:   pageToken,
+			}},
+		}
+		var results params.FullRelationUnitSettingsResults
+		err := st.facade.FacadeCall("FullRelationUnitSettings", args, &results)
+		if err != nil {
+			return nil, errors.Trace(base.TranslateError(err))
+		}
+		var result params.FullRelationUnitSettingsResult
+		if err := base.OneResult(results.Results, &result); err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, unit := range result.Units {
+			snapshot, err := relationUnitSnapshotFromParams(unit)
+			if err != nil {
+				return nil, errors.Annotatef(err, "relation %q", relationKey)
+			}
+			if i, ok := indices[snapshot.Unit]; ok {
+				if snapshot.Version >= snapshots[i].Version {
+					snapshots[i] = snapshot
+				}
+				continue
+			}
+			indices[snapshot.Unit] = len(snapshots)
+			snapshots = append(snapshots, snapshot)
+		}
+		if result.NextPageToken == "" {
+			return snapshots, nil
+		}
+		if seenTokens.Contains(result.NextPageToken) {
+			return nil, errors.Errorf("relation %q: page token %q repeated", relationKey, result.NextPageToken)
+		}
+		seenTokens.Add(result.NextPageToken)
+		pageToken = result.NextPageToken
+	}
+}
+
+func relationUnitSnapshotFromParams(in params.RelationUnitFullSettings) (RelationUnitSnapshot, error) {
+	unitTag, err := names.ParseUnitTag(in.UnitTag)
+	if err != nil {
+		return RelationUnitSnapshot{}, errors.Trace(err)
+	}
+	settings := make(map[string]interface{}, len(in.Settings))
+	for k, v := range in.Settings {
+		settings[k] = v
+	}
+	return RelationUnitSnapshot{
+		Unit:     unitTag.Id(),
+		Settings: settings,
+		Version:  in.Version,
+	}, nil
+}
+
+// InitialRelationChange returns the change event that publishes the
+// given snapshot of the local units in a relation, as returned by
+// FullRelationUnitSettings, to the other side of the relation. It is
+// passed to ConsumeRemoteRelationChange when the relation is first
+// established, before any changes are published.
+func InitialRelationChange(relationToken, applicationToken string, life params.Life, units []RelationUnitSnapshot) (params.RemoteRelationChangeEvent, error) {
+	change := params.RemoteRelationChangeEvent{
+		RelationToken:    relationToken,
+		ApplicationToken: applicationToken,
+		Life:             life,
+		ChangedUnits:     make([]params.RemoteRelationUnitChange, len(units)),
+	}
+	for i, unit := range units {
+		unitId, err := unitNumber(unit.Unit)
+		if err != nil {
+			return params.RemoteRelationChangeEvent{}, errors.Trace(err)
+		}
+		change.ChangedUnits[i] = params.RemoteRelationUnitChange{
+			UnitId:   unitId,
+			Settings: unit.Settings,
+		}
+	}
+	return change, nil
+}
+
+// unitNumber returns the number of the unit with the given name.
+func unitNumber(unitName string) (int, error) {
+	if !names.IsValidUnit(unitName) {
+		return 0, errors.NotValidf("unit name %q", unitName)
+	}
+	return strconv.Atoi(unitName[strings.LastIndex(unitName, "/")+1:])
+}
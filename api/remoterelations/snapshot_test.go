@@ -0,0 +1,191 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+var _ = gc.Suite(&snapshotSuite{})
+
+type snapshotSuite struct {
+	coretesting.BaseSuite
+}
+
+const snapshotRelationKey = "wordpress:db mysql:server"
+
+// pagedCaller returns an API caller that serves the given pages of
+// FullRelationUnitSettings results in turn, checking that each call
+// asks for the page following the last.
+func pagedCaller(c *gc.C, pages []params.FullRelationUnitSettingsResult, calls *int) versionedCaller {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RemoteRelations")
+		c.Check(request, gc.Equals, "FullRelationUnitSettings")
+		var pageToken string
+		if *calls > 0 {
+			pageToken = pages[*calls-1].NextPageToken
+		}
+		c.Check(arg, jc.DeepEquals, params.FullRelationUnitSettingsArgs{
+			Args: []params.FullRelationUnitSettingsArg{{
+				RelationTag: "relation-wordpress.db#mysql.server",
+				PageToken:   pageToken,
+			}},
+		})
+		c.Assert(*calls, jc.LessThan, len(pages))
+		c.Assert(result, gc.FitsTypeOf, &params.FullRelationUnitSettingsResults{})
+		*(result.(*params.FullRelationUnitSettingsResults)) = params.FullRelationUnitSettingsResults{
+			Results: []params.FullRelationUnitSettingsResult{pages[*calls]},
+		}
+		*calls++
+		return nil
+	})
+	return versionedCaller{apiCaller, 5}
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettings(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, []params.FullRelationUnitSettingsResult{{
+		Units: []params.RelationUnitFullSettings{{
+			UnitTag:  "unit-mysql-0",
+			Settings: params.Settings{"host": "10.0.0.1"},
+			Version:  3,
+		}},
+	}}, &calls))
+	snapshots, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(calls, gc.Equals, 1)
+	c.Assert(snapshots, jc.DeepEquals, []remoterelations.RelationUnitSnapshot{{
+		Unit:     "mysql/0",
+		Settings: map[string]interface{}{"host": "10.0.0.1"},
+		Version:  3,
+	}})
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsPages(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, []params.FullRelationUnitSettingsResult{{
+		Units: []params.RelationUnitFullSettings{{
+			UnitTag:  "unit-mysql-0",
+			Settings: params.Settings{"host": "10.0.0.1"},
+			Version:  3,
+		}, {
+			UnitTag:  "unit-mysql-1",
+			Settings: params.Settings{"host": "10.0.0.2"},
+			Version:  1,
+		}},
+		NextPageToken: "page-2",
+	}, {
+		Units: []params.RelationUnitFullSettings{{
+			// Changed since the first page was read.
+			UnitTag:  "unit-mysql-1",
+			Settings: params.Settings{"host": "10.0.0.3"},
+			Version:  2,
+		}},
+		NextPageToken: "page-3",
+	}, {
+		Units: []params.RelationUnitFullSettings{{
+			UnitTag: "unit-mysql-2",
+			Version: 1,
+		}},
+	}}, &calls))
+	snapshots, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(calls, gc.Equals, 3)
+	c.Assert(snapshots, jc.DeepEquals, []remoterelations.RelationUnitSnapshot{{
+		Unit:     "mysql/0",
+		Settings: map[string]interface{}{"host": "10.0.0.1"},
+		Version:  3,
+	}, {
+		Unit:     "mysql/1",
+		Settings: map[string]interface{}{"host": "10.0.0.3"},
+		Version:  2,
+	}, {
+		Unit:     "mysql/2",
+		Settings: map[string]interface{}{},
+		Version:  1,
+	}})
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsEmptyRelation(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, []params.FullRelationUnitSettingsResult{{}}, &calls))
+	snapshots, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(calls, gc.Equals, 1)
+	c.Assert(snapshots, gc.HasLen, 0)
+
+	change, err := remoterelations.InitialRelationChange("rel-token", "app-token", params.Alive, snapshots)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(change.ChangedUnits, gc.HasLen, 0)
+	c.Assert(change.DepartedUnits, gc.HasLen, 0)
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsRepeatedPageToken(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, []params.FullRelationUnitSettingsResult{{
+		NextPageToken: "page-2",
+	}, {
+		NextPageToken: "page-2",
+	}}, &calls))
+	_, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, gc.ErrorMatches, `relation "wordpress:db mysql:server": page token "page-2" repeated`)
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsError(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, []params.FullRelationUnitSettingsResult{{
+		Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+	}}, &calls))
+	_, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, gc.ErrorMatches, "relation not found")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsInvalidKey(c *gc.C) {
+	var calls int
+	st := remoterelations.NewState(pagedCaller(c, nil, &calls))
+	_, err := st.FullRelationUnitSettings("mysql")
+	c.Assert(err, gc.ErrorMatches, `relation key "mysql" not valid`)
+	c.Assert(calls, gc.Equals, 0)
+}
+
+func (s *snapshotSuite) TestFullRelationUnitSettingsNotSupported(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Fatalf("unexpected call to %s", request)
+		return nil
+	})
+	st := remoterelations.NewState(versionedCaller{apiCaller, 4})
+	_, err := st.FullRelationUnitSettings(snapshotRelationKey)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *snapshotSuite) TestInitialRelationChange(c *gc.C) {
+	change, err := remoterelations.InitialRelationChange("rel-token", "app-token", params.Alive, []remoterelations.RelationUnitSnapshot{{
+		Unit:     "mysql/0",
+		Settings: map[string]interface{}{"host": "10.0.0.1"},
+		Version:  3,
+	}, {
+		Unit:    "mysql/12",
+		Version: 1,
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(change, jc.DeepEquals, params.RemoteRelationChangeEvent{
+		RelationToken:    "rel-token",
+		ApplicationToken: "app-token",
+		Life:             params.Alive,
+		ChangedUnits: []params.RemoteRelationUnitChange{{
+			UnitId:   0,
+			Settings: map[string]interface{}{"host": "10.0.0.1"},
+		}, {
+			UnitId: 12,
+		}},
+	})
+}
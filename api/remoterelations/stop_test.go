@@ -0,0 +1,184 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/clock"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	apiwatcher "github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/watcher"
+)
+
+var _ = gc.Suite(&stopSuite{})
+
+type stopSuite struct {
+	coretesting.BaseSuite
+}
+
+// stopCounter serves a WatchRemoteRelations call and the Next and Stop
+// calls of the resulting watcher, counting the Stop calls.
+type stopCounter struct {
+	c *gc.C
+
+	// nextErr, if set, is returned by Next instead of waiting for
+	// the watcher to be stopped.
+	nextErr error
+
+	mu      sync.Mutex
+	stops   int
+	stopped chan struct{}
+}
+
+func newStopCounter(c *gc.C, nextErr error) *stopCounter {
+	return &stopCounter{c: c, nextErr: nextErr, stopped: make(chan struct{})}
+}
+
+func (s *stopCounter) apiCaller() apitesting.APICallerFunc {
+	return func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "RemoteRelations":
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: "66",
+				Changes:          []string{"wordpress:db mysql:db"},
+			}
+		case "StringsWatcher":
+			switch request {
+			case "Next":
+				if s.nextErr != nil {
+					return s.nextErr
+				}
+				<-s.stopped
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			case "Stop":
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				s.stops++
+				if s.stops == 1 {
+					close(s.stopped)
+				}
+				return s.nextErr
+			}
+		default:
+			s.c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	}
+}
+
+// checkStoppedOnce checks that exactly one Stop call is made.
+func (s *stopCounter) checkStoppedOnce(c *gc.C) {
+	select {
+	case <-s.stopped:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for Stop call")
+	}
+	// Give any further Stop call a chance to arrive.
+	time.Sleep(coretesting.ShortWait)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Check(s.stops, gc.Equals, 1)
+}
+
+func (s *stopSuite) watch(c *gc.C, st *remoterelations.State) watcher.StringsWatcher {
+	w, err := st.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case changes := <-w.Changes():
+		c.Check(changes, jc.DeepEquals, []string{"wordpress:db mysql:db"})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for initial event")
+	}
+	return w
+}
+
+func (s *stopSuite) TestKill(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	w := s.watch(c, remoterelations.NewState(counter.apiCaller()))
+	w.Kill()
+	c.Check(w.Wait(), jc.ErrorIsNil)
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestKillWithReason(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	w := s.watch(c, remoterelations.NewState(counter.apiCaller()))
+	c.Assert(w, gc.Implements, new(apiwatcher.ReasonKiller))
+	apiwatcher.KillWithReason(w, errors.New("relation removed"))
+	c.Check(w.Wait(), gc.ErrorMatches, "relation removed")
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestKillWithReasonAfterKill(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	w := s.watch(c, remoterelations.NewState(counter.apiCaller()))
+	w.Kill()
+	apiwatcher.KillWithReason(w, errors.New("relation removed"))
+	c.Check(w.Wait(), jc.ErrorIsNil)
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestConnectionError(c *gc.C) {
+	counter := newStopCounter(c, errors.New("connection is shut down"))
+	w, err := remoterelations.NewState(counter.apiCaller()).WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(w.Wait(), gc.ErrorMatches, "connection is shut down")
+	counter.checkStoppedOnce(c)
+
+	// Killing the dead watcher, with or without a reason, does not
+	// stop it again or change its error.
+	apiwatcher.KillWithReason(w, errors.New("relation removed"))
+	w.Kill()
+	c.Check(w.Wait(), gc.ErrorMatches, "connection is shut down")
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestDeprecatedStop(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	w := s.watch(c, remoterelations.NewState(counter.apiCaller()))
+	stopper, ok := w.(interface {
+		Stop() error
+	})
+	c.Assert(ok, jc.IsTrue)
+	c.Check(stopper.Stop(), jc.ErrorIsNil)
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestResumableKillWithReason(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	st, err := remoterelations.NewState(counter.apiCaller()).Resumable(apiwatcher.ResumeConfig{
+		Clock:    clock.WallClock,
+		Attempts: 1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	w := s.watch(c, st)
+	c.Assert(w, gc.Implements, new(apiwatcher.ReasonKiller))
+	apiwatcher.KillWithReason(w, errors.New("relation removed"))
+	c.Check(w.Wait(), gc.ErrorMatches, "relation removed")
+	counter.checkStoppedOnce(c)
+}
+
+func (s *stopSuite) TestCachedKillWithReason(c *gc.C) {
+	counter := newStopCounter(c, nil)
+	st, err := remoterelations.NewCachedState(remoterelations.NewState(counter.apiCaller()), remoterelations.CacheConfig{
+		Clock: clock.WallClock,
+		TTL:   time.Minute,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	w, err := st.WatchRemoteApplications()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(w, gc.Implements, new(apiwatcher.ReasonKiller))
+	apiwatcher.KillWithReason(w, errors.New("relation removed"))
+	c.Check(w.Wait(), gc.ErrorMatches, "relation removed")
+	counter.checkStoppedOnce(c)
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v2"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/watcher"
+)
+
+// TokenResolver resolves the tokens by which the offering model refers
+// to relations back to the keys of the local relations, without a
+// facade call for each event. Tokens are cached for the relations
+// reported by the watcher returned by WatchRemoteRelations, which
+// fetches them in bulk; a token that is not cached is resolved with a
+// single call, and cached if found. It is safe for concurrent use.
+type TokenResolver struct {
+	st *State
+
+	mu sync.Mutex
+	// keys maps tokens to relation keys, and tokens the reverse,
+	// so that the entry for a relation can be found from either.
+	keys   map[string]string
+	tokens map[string]string
+	// generation is incremented whenever entries are invalidated,
+	// so that tokens fetched before an invalidation are not cached
+	// after it.
+	generation uint64
+}
+
+// NewTokenResolver returns a TokenResolver that makes its facade calls
+// with st.
+func NewTokenResolver(st *State) *TokenResolver {
+	return &TokenResolver{
+		st:     st,
+		keys:   make(map[string]string),
+		tokens: make(map[string]string),
+	}
+}
+
+// Resolve returns the key of the local relation identified by token.
+// It returns an error satisfying errors.IsNotFound if no relation has
+// the token; such results are not cached.
+func (r *TokenResolver) Resolve(token string) (string, error) {
+	r.mu.Lock()
+	key, ok := r.keys[token]
+	generation := r.generation
+	r.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := r.st.RelationKey(token)
+	if errors.IsNotFound(err) {
+		return "", errors.NotFoundf("relation with token %q", token)
+	} else if err != nil {
+		return "", errors.Annotatef(err, "cannot resolve relation token %q", token)
+	}
+	r.store(map[string]string{key: token}, generation)
+	return key, nil
+}
+
+// Refresh discards any cached tokens of the relations with the given
+// keys, and fetches them again with a single call. Relations that no
+// longer have tokens, such as those that have been removed, are left
+// uncached.
+func (r *TokenResolver) Refresh(keys ...string) error {
+	tags := make([]names.Tag, len(keys))
+	for i, key := range keys {
+		if !names.IsValidRelation(key) {
+			return errors.NotValidf("relation key %q", key)
+		}
+		tags[i] = names.NewRelationTag(key)
+	}
+	r.Invalidate(keys...)
+	if len(keys) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	results, err := r.st.GetTokens(tags)
+	if err != nil {
+		return errors.Annotate(err, "cannot get relation tokens")
+	}
+	tokens := make(map[string]string)
+	for i, result := range results {
+		if result.Error == nil {
+			tokens[keys[i]] = result.Result
+			continue
+		}
+		if err := base.TranslateError(result.Error); !errors.IsNotFound(err) {
+			return errors.Annotatef(err, "cannot get token of relation %q", keys[i])
+		}
+	}
+	r.store(tokens, generation)
+	return nil
+}
+
+// Invalidate discards any cached tokens of the relations with the
+// given keys.
+func (r *TokenResolver) Invalidate(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	for _, key := range keys {
+		if token, ok := r.tokens[key]; ok {
+			delete(r.keys, token)
+			delete(r.tokens, key)
+		}
+	}
+}
+
+// store caches the given tokens, keyed by relation key, unless entries
+// have been invalidated since the given generation.
+func (r *TokenResolver) store(tokens map[string]string, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation != generation {
+		return
+	}
+	for key, token := range tokens {
+		if old, ok := r.tokens[key]; ok {
+			delete(r.keys, old)
+		}
+		r.keys[token] = key
+		r.tokens[key] = token
+	}
+}
+
+// WatchRemoteRelations returns the watcher returned by the State
+// method of the same name, except that the tokens of the relations
+// reported in each change are refreshed before the change is
+// delivered, so that the tokens of removed relations are discarded.
+// The watcher stops with an error if the tokens cannot be fetched.
+func (r *TokenResolver) WatchRemoteRelations() (watcher.StringsWatcher, error) {
+	source, err := r.st.WatchRemoteRelations()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newInvalidatingWatcher(source, "remote relations", func(keys []string) error {
+		return r.Refresh(keys...)
+	})
+}
@@ -0,0 +1,270 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/remoterelations"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/watcher"
+)
+
+const (
+	wordpressKey = "wordpress:db mysql:db"
+	mediawikiKey = "mediawiki:db mysql:db"
+)
+
+type tokenResolverSuite struct {
+	coretesting.BaseSuite
+
+	mu sync.Mutex
+	// tokens holds the token of each relation, by key.
+	tokens map[string]string
+	// calls records the GetTokens and RelationKeys calls made, with
+	// the keys or tokens they were made for.
+	calls [][]string
+	// next is sent to the StringsWatcher's Next calls.
+	next chan []string
+}
+
+var _ = gc.Suite(&tokenResolverSuite{})
+
+func (s *tokenResolverSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.tokens = map[string]string{
+		wordpressKey: "token-wordpress",
+		mediawikiKey: "token-mediawiki",
+	}
+	s.calls = nil
+	s.next = make(chan []string)
+}
+
+func (s *tokenResolverSuite) apiCaller(c *gc.C) apitesting.APICallerFunc {
+	stopped := make(chan struct{})
+	return apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch request {
+		case "GetTokens":
+			call := []string{request}
+			var results params.StringResults
+			for _, entity := range arg.(params.Entities).Entities {
+				tag, err := names.ParseRelationTag(entity.Tag)
+				c.Assert(err, jc.ErrorIsNil)
+				call = append(call, tag.Id())
+				token, ok := s.tokens[tag.Id()]
+				if !ok {
+					results.Results = append(results.Results, params.StringResult{
+						Error: &params.Error{Code: params.CodeNotFound, Message: "token not found"},
+					})
+					continue
+				}
+				results.Results = append(results.Results, params.StringResult{Result: token})
+			}
+			s.calls = append(s.calls, call)
+			*(result.(*params.StringResults)) = results
+		case "RelationKeys":
+			call := []string{request}
+			var results params.RelationKeyResults
+			for _, token := range arg.(params.RemoteTokens).Tokens {
+				call = append(call, token)
+				result := params.RelationKeyResult{
+					Error: &params.Error{Code: params.CodeNotFound, Message: "relation not found"},
+				}
+				for key, t := range s.tokens {
+					if t == token {
+						result = params.RelationKeyResult{Key: key}
+					}
+				}
+				results.Results = append(results.Results, result)
+			}
+			s.calls = append(s.calls, call)
+			*(result.(*params.RelationKeyResults)) = results
+		case "WatchRemoteRelations":
+			*(result.(*params.StringsWatchResult)) = params.StringsWatchResult{
+				StringsWatcherId: "66",
+				Changes:          []string{wordpressKey},
+			}
+		case "Next":
+			s.mu.Unlock()
+			defer s.mu.Lock()
+			select {
+			case changes := <-s.next:
+				out := (*result.(*interface{})).(*params.StringsWatchResult)
+				out.Changes = changes
+				return nil
+			case <-stopped:
+				return &params.Error{Code: params.CodeStopped, Message: "stopped"}
+			}
+		case "Stop":
+			close(stopped)
+		default:
+			c.Errorf("unexpected request %q", request)
+		}
+		return nil
+	})
+}
+
+func (s *tokenResolverSuite) newResolver(c *gc.C) *remoterelations.TokenResolver {
+	return remoterelations.NewTokenResolver(remoterelations.NewState(versionedCaller{s.apiCaller(c), 5}))
+}
+
+// takeCalls returns the calls made since it was last called.
+func (s *tokenResolverSuite) takeCalls() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func (s *tokenResolverSuite) setToken(key, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token == "" {
+		delete(s.tokens, key)
+	} else {
+		s.tokens[key] = token
+	}
+}
+
+func (s *tokenResolverSuite) assertChange(c *gc.C, w watcher.StringsWatcher, expect ...string) {
+	select {
+	case changes, ok := <-w.Changes():
+		c.Assert(ok, jc.IsTrue)
+		c.Assert(changes, jc.DeepEquals, expect)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for change")
+	}
+}
+
+func (s *tokenResolverSuite) TestResolveFromWatcher(c *gc.C) {
+	r := s.newResolver(c)
+	w, err := r.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+	s.assertChange(c, w, wordpressKey)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{{"GetTokens", wordpressKey}})
+
+	key, err := r.Resolve("token-wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(key, gc.Equals, wordpressKey)
+	c.Check(s.takeCalls(), gc.HasLen, 0)
+}
+
+func (s *tokenResolverSuite) TestResolveMissFallsBack(c *gc.C) {
+	r := s.newResolver(c)
+	key, err := r.Resolve("token-mediawiki")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(key, gc.Equals, mediawikiKey)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{{"RelationKeys", "token-mediawiki"}})
+
+	// The resolved token is cached.
+	key, err = r.Resolve("token-mediawiki")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(key, gc.Equals, mediawikiKey)
+	c.Check(s.takeCalls(), gc.HasLen, 0)
+}
+
+func (s *tokenResolverSuite) TestResolveUnknownToken(c *gc.C) {
+	r := s.newResolver(c)
+	_, err := r.Resolve("token-unknown")
+	c.Check(err, gc.ErrorMatches, `relation with token "token-unknown" not found`)
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+
+	// Unknown tokens are not cached.
+	_, err = r.Resolve("token-unknown")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{
+		{"RelationKeys", "token-unknown"},
+		{"RelationKeys", "token-unknown"},
+	})
+}
+
+func (s *tokenResolverSuite) TestResolveNotSupported(c *gc.C) {
+	r := remoterelations.NewTokenResolver(remoterelations.NewState(versionedCaller{s.apiCaller(c), 4}))
+	_, err := r.Resolve("token-wordpress")
+	c.Check(err, jc.Satisfies, errors.IsNotSupported)
+	c.Check(s.takeCalls(), gc.HasLen, 0)
+}
+
+func (s *tokenResolverSuite) TestWatcherInvalidatesRemovedRelations(c *gc.C) {
+	r := s.newResolver(c)
+	w, err := r.WatchRemoteRelations()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+	s.assertChange(c, w, wordpressKey)
+	_, err = r.Resolve("token-mediawiki")
+	c.Assert(err, jc.ErrorIsNil)
+	s.takeCalls()
+
+	// The wordpress relation is removed, and the mediawiki relation
+	// changes: the former's token is discarded, the latter's fetched
+	// again.
+	s.setToken(wordpressKey, "")
+	select {
+	case s.next <- []string{wordpressKey, mediawikiKey}:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out sending change")
+	}
+	s.assertChange(c, w, wordpressKey, mediawikiKey)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{{"GetTokens", wordpressKey, mediawikiKey}})
+
+	_, err = r.Resolve("token-wordpress")
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+	key, err := r.Resolve("token-mediawiki")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(key, gc.Equals, mediawikiKey)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{{"RelationKeys", "token-wordpress"}})
+}
+
+func (s *tokenResolverSuite) TestInvalidate(c *gc.C) {
+	r := s.newResolver(c)
+	_, err := r.Resolve("token-wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	r.Invalidate(wordpressKey)
+	_, err = r.Resolve("token-wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.takeCalls(), jc.DeepEquals, [][]string{
+		{"RelationKeys", "token-wordpress"},
+		{"RelationKeys", "token-wordpress"},
+	})
+}
+
+func (s *tokenResolverSuite) TestConcurrentResolve(c *gc.C) {
+	r := s.newResolver(c)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			key, err := r.Resolve("token-wordpress")
+			c.Check(err, jc.ErrorIsNil)
+			c.Check(key, gc.Equals, wordpressKey)
+		}()
+		go func() {
+			defer wg.Done()
+			c.Check(r.Refresh(wordpressKey, mediawikiKey), jc.ErrorIsNil)
+		}()
+	}
+	wg.Wait()
+	key, err := r.Resolve("token-mediawiki")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(key, gc.Equals, mediawikiKey)
+}
@@ -0,0 +1,186 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"encoding/json"
+	"time"
+
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/life"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type applicationRelationsWatcherSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&applicationRelationsWatcherSuite{})
+
+// nextCaller returns an APICaller for an ApplicationRelationsWatcher
+// whose Next calls decode the JSON documents sent on next, as the
+// client would decode them from the wire.
+func nextCaller(c *gc.C, next <-chan string) (apitesting.APICallerFunc, func()) {
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "ApplicationRelationsWatcher")
+		c.Check(id, gc.Equals, "66")
+		switch request {
+		case "Next":
+			select {
+			case doc := <-next:
+				// The watcher passes a pointer to the interface
+				// holding its result value.
+				out := (*result.(*interface{})).(*params.ApplicationRelationsWatchResult)
+				return json.Unmarshal([]byte(doc), out)
+			case <-stopped:
+				return &params.Error{Code: params.CodeStopped}
+			}
+		case "Stop":
+			close(stopped)
+		}
+		return nil
+	})
+	return apiCaller, func() {
+		select {
+		case <-stopped:
+		default:
+			close(stopped)
+		}
+	}
+}
+
+func assertApplicationRelationsChange(c *gc.C, w corewatcher.ApplicationRelationsWatcher, expect []corewatcher.ApplicationRelationsChange) {
+	select {
+	case changes, ok := <-w.Changes():
+		c.Assert(ok, jc.IsTrue)
+		c.Check(changes, jc.DeepEquals, expect)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for change")
+	}
+}
+
+func waitForWatcherError(c *gc.C, w corewatcher.ApplicationRelationsWatcher) error {
+	dead := make(chan error, 1)
+	go func() {
+		dead <- w.Wait()
+	}()
+	select {
+	case err := <-dead:
+		return err
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for watcher to die")
+	}
+	return nil
+}
+
+func (s *applicationRelationsWatcherSuite) TestChanges(c *gc.C) {
+	next := make(chan string)
+	apiCaller, _ := nextCaller(c, next)
+	w := watcher.NewApplicationRelationsWatcher(apiCaller, params.ApplicationRelationsWatchResult{
+		ApplicationRelationsWatcherId: "66",
+		Changes: []params.ApplicationRelationChange{{
+			RelationTag: "relation-wordpress.db#mysql.db",
+			Life:        params.Alive,
+			ChangedUnits: []params.RelationUnitSettingsVersion{
+				{UnitTag: "unit-mysql-0", Version: 1},
+				{UnitTag: "unit-mysql-1", Version: 3},
+			},
+		}},
+	})
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	assertApplicationRelationsChange(c, w, []corewatcher.ApplicationRelationsChange{{
+		Key:  "wordpress:db mysql:db",
+		Life: life.Alive,
+		Changed: map[string]corewatcher.UnitSettings{
+			"mysql/0": {Version: 1},
+			"mysql/1": {Version: 3},
+		},
+	}})
+
+	// Fields added by newer servers are ignored.
+	next <- `{"changes": [{
+		"relation-tag": "relation-wordpress.db#mysql.db",
+		"life": "dying",
+		"suspended": true,
+		"suspended-reason": "maintenance",
+		"changed-units": [{"unit-tag": "unit-mysql-0", "version": 2, "hash": "abc"}],
+		"departed-units": ["unit-mysql-1"]
+	}], "cursor": 42}`
+	assertApplicationRelationsChange(c, w, []corewatcher.ApplicationRelationsChange{{
+		Key:       "wordpress:db mysql:db",
+		Life:      life.Dying,
+		Suspended: true,
+		Changed: map[string]corewatcher.UnitSettings{
+			"mysql/0": {Version: 2},
+		},
+		Departed: []string{"mysql/1"},
+	}})
+}
+
+func (s *applicationRelationsWatcherSuite) TestInvalidInitialChanges(c *gc.C) {
+	for i, test := range []struct {
+		change params.ApplicationRelationChange
+		err    string
+	}{{
+		change: params.ApplicationRelationChange{Life: params.Alive},
+		err:    "change 0 with no relation tag not valid",
+	}, {
+		change: params.ApplicationRelationChange{RelationTag: "application-mysql", Life: params.Alive},
+		err:    `"application-mysql" is not a valid relation tag`,
+	}, {
+		change: params.ApplicationRelationChange{RelationTag: "relation-wordpress.db#mysql.db"},
+		err:    `relation "wordpress:db mysql:db": life value "" not valid`,
+	}, {
+		change: params.ApplicationRelationChange{
+			RelationTag:  "relation-wordpress.db#mysql.db",
+			Life:         params.Alive,
+			ChangedUnits: []params.RelationUnitSettingsVersion{{Version: 1}},
+		},
+		err: `relation "wordpress:db mysql:db": "" is not a valid tag`,
+	}, {
+		change: params.ApplicationRelationChange{
+			RelationTag:   "relation-wordpress.db#mysql.db",
+			Life:          params.Alive,
+			DepartedUnits: []string{"mysql/0"},
+		},
+		err: `relation "wordpress:db mysql:db": "mysql/0" is not a valid tag`,
+	}} {
+		c.Logf("test %d", i)
+		apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Errorf("unexpected API call %s.%s", objType, request)
+			return nil
+		})
+		w := watcher.NewApplicationRelationsWatcher(apiCaller, params.ApplicationRelationsWatchResult{
+			ApplicationRelationsWatcherId: "66",
+			Changes:                       []params.ApplicationRelationChange{test.change},
+		})
+		c.Check(waitForWatcherError(c, w), gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *applicationRelationsWatcherSuite) TestInvalidLaterChange(c *gc.C) {
+	next := make(chan string)
+	apiCaller, cleanup := nextCaller(c, next)
+	defer cleanup()
+	w := watcher.NewApplicationRelationsWatcher(apiCaller, params.ApplicationRelationsWatchResult{
+		ApplicationRelationsWatcherId: "66",
+	})
+	defer w.Kill()
+	assertApplicationRelationsChange(c, w, []corewatcher.ApplicationRelationsChange{})
+
+	next <- `{"changes": [{"relation-tag": "relation-wordpress.db#mysql.db"}]}`
+	c.Check(waitForWatcherError(c, w), gc.ErrorMatches, `relation "wordpress:db mysql:db": life value "" not valid`)
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import (
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/watcher"
+)
+
+// coalescingLoop delivers the given initial change and those read from
+// the API, merging each change read while the previous one has yet to
+// be delivered into it.
+func (w *relationUnitsWatcher) coalescingLoop(changes watcher.RelationUnitsChange) error {
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return nil
+		case out <- changes:
+			out = nil
+		case data, ok := <-w.in:
+			if !ok {
+				// The tomb is already killed with the correct error
+				// at this point, so just return.
+				return nil
+			}
+			next := copyRelationUnitsChanged(data.(*params.RelationUnitsWatchResult).Changes)
+			if out == nil {
+				changes = next
+			} else {
+				changes = mergeRelationUnitsChanges(changes, next)
+			}
+			out = w.out
+		}
+	}
+}
+
+// mergeRelationUnitsChanges returns a change equivalent to delivering
+// pending followed by next. Units that depart and then rejoin are
+// reported only as changed, with their latest settings version; units
+// that change and then depart are reported only as departed.
+func mergeRelationUnitsChanges(pending, next watcher.RelationUnitsChange) watcher.RelationUnitsChange {
+	merged := watcher.RelationUnitsChange{
+		Changed: make(map[string]watcher.UnitSettings),
+	}
+	for unit, settings := range pending.Changed {
+		merged.Changed[unit] = settings
+	}
+	departed := make(map[string]bool)
+	for _, unit := range pending.Departed {
+		departed[unit] = true
+	}
+	for unit, settings := range next.Changed {
+		merged.Changed[unit] = settings
+		delete(departed, unit)
+	}
+	for _, unit := range next.Departed {
+		delete(merged.Changed, unit)
+		departed[unit] = true
+	}
+	// Preserve the order in which units departed.
+	for _, units := range [][]string{pending.Departed, next.Departed} {
+		for _, unit := range units {
+			if departed[unit] {
+				merged.Departed = append(merged.Departed, unit)
+				delete(departed, unit)
+			}
+		}
+	}
+	if len(merged.Changed) == 0 {
+		merged.Changed = nil
+	}
+	return merged
+}
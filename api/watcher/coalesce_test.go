@@ -0,0 +1,150 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type coalescingWatcherSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&coalescingWatcherSuite{})
+
+func (s *coalescingWatcherSuite) TestMerge(c *gc.C) {
+	pending := corewatcher.RelationUnitsChange{
+		Changed: map[string]corewatcher.UnitSettings{
+			"mysql/0": {Version: 1},
+			"mysql/1": {Version: 1},
+		},
+		Departed: []string{"mysql/2", "mysql/3"},
+	}
+	next := corewatcher.RelationUnitsChange{
+		Changed: map[string]corewatcher.UnitSettings{
+			"mysql/0": {Version: 2},
+			"mysql/2": {Version: 5},
+		},
+		Departed: []string{"mysql/1", "mysql/4"},
+	}
+	merged := watcher.MergeRelationUnitsChanges(pending, next)
+	c.Check(merged, jc.DeepEquals, corewatcher.RelationUnitsChange{
+		Changed: map[string]corewatcher.UnitSettings{
+			"mysql/0": {Version: 2},
+			"mysql/2": {Version: 5},
+		},
+		Departed: []string{"mysql/3", "mysql/1", "mysql/4"},
+	})
+	// The inputs are not modified.
+	c.Check(pending.Changed, gc.HasLen, 2)
+	c.Check(pending.Departed, jc.DeepEquals, []string{"mysql/2", "mysql/3"})
+}
+
+func (s *coalescingWatcherSuite) TestMergeOnlyDeparted(c *gc.C) {
+	merged := watcher.MergeRelationUnitsChanges(
+		corewatcher.RelationUnitsChange{
+			Changed: map[string]corewatcher.UnitSettings{"mysql/0": {Version: 1}},
+		},
+		corewatcher.RelationUnitsChange{
+			Departed: []string{"mysql/0", "mysql/0"},
+		},
+	)
+	c.Check(merged, jc.DeepEquals, corewatcher.RelationUnitsChange{
+		Departed: []string{"mysql/0"},
+	})
+}
+
+// TestStress pushes thousands of changes through a coalescing watcher
+// whose consumer is slow, and checks that the consumer converges on
+// the final state having received far fewer events.
+func (s *coalescingWatcherSuite) TestStress(c *gc.C) {
+	const numEvents = 5000
+	const numUnits = 50
+
+	// changeFor returns the i'th change served by the API.
+	changeFor := func(i int) params.RelationUnitsChange {
+		unit := fmt.Sprintf("mysql/%d", i%numUnits)
+		if i%7 == 0 {
+			return params.RelationUnitsChange{Departed: []string{unit}}
+		}
+		return params.RelationUnitsChange{
+			Changed: map[string]params.UnitSettings{unit: {Version: int64(i)}},
+		}
+	}
+	// expected holds the settings versions of the units in scope
+	// once all changes have been applied.
+	expected := make(map[string]int64)
+	for i := 1; i <= numEvents; i++ {
+		change := changeFor(i)
+		for unit, settings := range change.Changed {
+			expected[unit] = settings.Version
+		}
+		for _, unit := range change.Departed {
+			delete(expected, unit)
+		}
+	}
+
+	var served int
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RelationUnitsWatcher")
+		switch request {
+		case "Next":
+			if served == numEvents {
+				<-stopped
+				return &params.Error{Code: params.CodeStopped}
+			}
+			served++
+			out := (*result.(*interface{})).(*params.RelationUnitsWatchResult)
+			out.Changes = changeFor(served)
+		case "Stop":
+			close(stopped)
+		}
+		return nil
+	})
+	w := watcher.NewCoalescingRelationUnitsWatcher(apiCaller, params.RelationUnitsWatchResult{
+		RelationUnitsWatcherId: "66",
+	})
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	view := make(map[string]int64)
+	var received int
+	timeout := time.After(coretesting.LongWait)
+	for {
+		select {
+		case change := <-w.Changes():
+			received++
+			for unit, settings := range change.Changed {
+				view[unit] = settings.Version
+			}
+			for _, unit := range change.Departed {
+				delete(view, unit)
+			}
+		case <-timeout:
+			c.Fatalf("timed out after %d events", received)
+		}
+		if reflect.DeepEqual(view, expected) {
+			break
+		}
+		// Simulate a consumer making a facade call per event.
+		time.Sleep(time.Millisecond)
+	}
+	c.Logf("received %d events for %d changes", received, numEvents)
+	c.Check(received < numEvents, jc.IsTrue)
+}
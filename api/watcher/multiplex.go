@@ -0,0 +1,166 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// multiplexerFacade is the name of the facade that serves Next calls
+// for many watchers at once.
+const multiplexerFacade = "WatcherMultiplexer"
+
+// multiplexMaxWait bounds the time a NextBatch call waits for changes,
+// so that watchers started while a call is in flight are included in
+// the next batch without undue delay.
+const multiplexMaxWait = 10 * time.Second
+
+// NewMultiplexingCaller returns an APICaller that wraps the given one,
+// sharing a single NextBatch long-poll between the Next calls of all
+// watchers constructed with it. If the API server does not support the
+// WatcherMultiplexer facade, the caller is returned unchanged.
+//
+// Each watcher still receives only its own results and errors, and
+// stopping one watcher does not affect the others.
+func NewMultiplexingCaller(caller base.APICaller) base.APICaller {
+	version := caller.BestFacadeVersion(multiplexerFacade)
+	if version == 0 {
+		return caller
+	}
+	return &multiplexingCaller{
+		APICaller: caller,
+		version:   version,
+		pending:   make(map[params.WatcherId]*pendingNext),
+	}
+}
+
+type multiplexingCaller struct {
+	base.APICaller
+	version int
+
+	mu      sync.Mutex
+	pending map[params.WatcherId]*pendingNext
+	running bool
+}
+
+// pendingNext holds a Next call waiting for a batch result.
+type pendingNext struct {
+	response interface{}
+	done     chan error
+}
+
+// APICall is part of the base.APICaller interface. Next calls on
+// watchers are batched; all other calls are passed through.
+func (m *multiplexingCaller) APICall(facade string, version int, id, request string, args, response interface{}) error {
+	if id == "" || (request != "Next" && request != "Stop") {
+		return m.APICaller.APICall(facade, version, id, request, args, response)
+	}
+	key := params.WatcherId{Facade: facade, Version: version, Id: id}
+	if request == "Stop" {
+		err := m.APICaller.APICall(facade, version, id, request, args, response)
+		// Don't leave the watcher's Next waiting for a batch
+		// that may not return for some time.
+		m.finish(key, &params.Error{Code: params.CodeStopped, Message: "watcher was stopped"})
+		return err
+	}
+	return m.next(key, response)
+}
+
+// next registers a Next call for the watcher with the given key,
+// starting the batch loop if necessary, and waits for its result.
+func (m *multiplexingCaller) next(key params.WatcherId, response interface{}) error {
+	p := &pendingNext{
+		response: response,
+		done:     make(chan error, 1),
+	}
+	m.mu.Lock()
+	if _, ok := m.pending[key]; ok {
+		m.mu.Unlock()
+		return errors.Errorf("concurrent Next calls on %s watcher %q", key.Facade, key.Id)
+	}
+	m.pending[key] = p
+	if !m.running {
+		m.running = true
+		go m.loop()
+	}
+	m.mu.Unlock()
+	return <-p.done
+}
+
+// take removes and returns the pending Next call for the watcher with
+// the given key, if any.
+func (m *multiplexingCaller) take(key params.WatcherId) (*pendingNext, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[key]
+	delete(m.pending, key)
+	return p, ok
+}
+
+// finish completes the pending Next call for the watcher with the
+// given key, if any, with the given error.
+func (m *multiplexingCaller) finish(key params.WatcherId, err error) {
+	if p, ok := m.take(key); ok {
+		p.done <- err
+	}
+}
+
+// loop makes NextBatch calls for the pending Next calls until there
+// are none left.
+func (m *multiplexingCaller) loop() {
+	for {
+		m.mu.Lock()
+		if len(m.pending) == 0 {
+			m.running = false
+			m.mu.Unlock()
+			return
+		}
+		args := params.NextBatchArgs{MaxWait: multiplexMaxWait}
+		for key := range m.pending {
+			args.Watchers = append(args.Watchers, key)
+		}
+		m.mu.Unlock()
+
+		var results params.NextBatchResults
+		err := m.APICaller.APICall(multiplexerFacade, m.version, "", "NextBatch", args, &results)
+		if err != nil {
+			// The call as a whole failed, so every watcher in
+			// the batch is affected.
+			for _, key := range args.Watchers {
+				m.finish(key, err)
+			}
+			continue
+		}
+		for _, result := range results.Results {
+			m.deliver(result)
+		}
+	}
+}
+
+// deliver completes the pending Next call for the watcher identified
+// in the given result.
+func (m *multiplexingCaller) deliver(result params.NextBatchResult) {
+	p, ok := m.take(result.Watcher)
+	if !ok {
+		logger.Debugf("discarding result for unknown watcher %v", result.Watcher)
+		return
+	}
+	var err error
+	if result.Error != nil {
+		err = result.Error
+	} else if len(result.Result) > 0 && p.response != nil {
+		err = json.Unmarshal(result.Result, p.response)
+		if err != nil {
+			err = errors.Annotatef(err, "decoding %s result", result.Watcher.Facade)
+		}
+	}
+	p.done <- err
+}
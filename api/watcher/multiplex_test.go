@@ -0,0 +1,160 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type multiplexSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&multiplexSuite{})
+
+// multiplexCaller is an APICaller that reports support for the
+// WatcherMultiplexer facade.
+type multiplexCaller struct {
+	apitesting.APICallerFunc
+}
+
+func (multiplexCaller) BestFacadeVersion(facade string) int {
+	if facade == "WatcherMultiplexer" {
+		return 1
+	}
+	return 0
+}
+
+func (s *multiplexSuite) TestNotSupported(c *gc.C) {
+	caller := &struct{ apitesting.APICallerFunc }{}
+	c.Check(watcher.NewMultiplexingCaller(caller), gc.Equals, caller)
+}
+
+func (s *multiplexSuite) TestSharedNextLoop(c *gc.C) {
+	batches := make(chan params.NextBatchArgs)
+	replies := make(chan params.NextBatchResults)
+	done := make(chan struct{})
+	defer close(done)
+	caller := multiplexCaller{func(objType string, version int, id, request string, arg, result interface{}) error {
+		switch objType {
+		case "WatcherMultiplexer":
+			c.Check(request, gc.Equals, "NextBatch")
+			c.Check(version, gc.Equals, 1)
+			select {
+			case batches <- arg.(params.NextBatchArgs):
+			case <-done:
+				return nil
+			}
+			select {
+			case r := <-replies:
+				*(result.(*params.NextBatchResults)) = r
+			case <-done:
+			}
+		case "StringsWatcher":
+			// Next calls must only be made through NextBatch.
+			c.Check(request, gc.Equals, "Stop")
+		default:
+			c.Errorf("unexpected facade %q", objType)
+		}
+		return nil
+	}}
+	mux := watcher.NewMultiplexingCaller(caller)
+	w1 := watcher.NewStringsWatcher(mux, params.StringsWatchResult{
+		StringsWatcherId: "1", Changes: []string{"one"},
+	})
+	defer w1.Kill()
+	w2 := watcher.NewStringsWatcher(mux, params.StringsWatchResult{
+		StringsWatcherId: "2", Changes: []string{"two"},
+	})
+	defer w2.Kill()
+	assertChange(c, w1, "one")
+	assertChange(c, w2, "two")
+
+	// Both watchers' Next calls end up in a single batch.
+	for {
+		batch := nextBatch(c, batches)
+		if len(batch) == 2 {
+			c.Check(batch, jc.DeepEquals, []string{"1", "2"})
+			break
+		}
+		replies <- params.NextBatchResults{}
+	}
+
+	// A result for one watcher is delivered only to that watcher.
+	replies <- params.NextBatchResults{
+		Results: []params.NextBatchResult{{
+			Watcher: params.WatcherId{Facade: "StringsWatcher", Id: "1"},
+			Result:  mustMarshal(c, params.StringsWatchResult{Changes: []string{"a"}}),
+		}},
+	}
+	assertChange(c, w1, "a")
+	assertNoChange(c, w2)
+
+	// An error for one watcher kills only that watcher.
+	nextBatch(c, batches)
+	replies <- params.NextBatchResults{
+		Results: []params.NextBatchResult{{
+			Watcher: params.WatcherId{Facade: "StringsWatcher", Id: "2"},
+			Error:   &params.Error{Message: "boom"},
+		}},
+	}
+	c.Check(w2.Wait(), gc.ErrorMatches, "boom")
+	assertNoChange(c, w1)
+
+	// Stopping a watcher completes its pending Next call.
+	w1.Kill()
+	c.Check(w1.Wait(), jc.ErrorIsNil)
+}
+
+func nextBatch(c *gc.C, batches <-chan params.NextBatchArgs) []string {
+	select {
+	case batch := <-batches:
+		var ids []string
+		for _, w := range batch.Watchers {
+			c.Check(w.Facade, gc.Equals, "StringsWatcher")
+			ids = append(ids, w.Id)
+		}
+		sort.Strings(ids)
+		return ids
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for NextBatch call")
+	}
+	panic("unreachable")
+}
+
+func assertChange(c *gc.C, w corewatcher.StringsWatcher, expect ...string) {
+	select {
+	case changes, ok := <-w.Changes():
+		c.Assert(ok, jc.IsTrue)
+		c.Check(changes, jc.DeepEquals, expect)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for change")
+	}
+}
+
+func assertNoChange(c *gc.C, w corewatcher.StringsWatcher) {
+	select {
+	case changes := <-w.Changes():
+		c.Fatalf("unexpected change %v", changes)
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func mustMarshal(c *gc.C, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	c.Assert(err, jc.ErrorIsNil)
+	return data
+}
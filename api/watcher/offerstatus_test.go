@@ -0,0 +1,98 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"time"
+
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/status"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type offerStatusWatcherSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&offerStatusWatcherSuite{})
+
+func (s *offerStatusWatcherSuite) TestChangesCoalesced(c *gc.C) {
+	next := make(chan params.OfferStatusWatchResult)
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "OfferStatusWatcher")
+		c.Check(id, gc.Equals, "66")
+		switch request {
+		case "Next":
+			select {
+			case r := <-next:
+				// The watcher passes a pointer to the interface
+				// holding its result value.
+				out := (*result.(*interface{})).(*params.OfferStatusWatchResult)
+				*out = r
+				return nil
+			case <-stopped:
+				return &params.Error{Code: params.CodeStopped}
+			}
+		case "Stop":
+			close(stopped)
+		}
+		return nil
+	})
+	w := watcher.NewOfferStatusWatcher(apiCaller, params.OfferStatusWatchResult{
+		OfferStatusWatcherId: "66",
+		Changes: []params.OfferStatusChange{{
+			OfferName: "hosted-mysql",
+			Status:    "active",
+			Info:      "ready",
+		}},
+	})
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	assertChange := func(expect []corewatcher.OfferStatusChange) {
+		select {
+		case changes, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+			c.Check(changes, jc.DeepEquals, expect)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for change")
+		}
+	}
+	assertChange([]corewatcher.OfferStatusChange{{
+		Name:    "hosted-mysql",
+		Status:  status.Active,
+		Message: "ready",
+	}})
+
+	// A change that leaves the status as it was is not reported.
+	next <- params.OfferStatusWatchResult{
+		Changes: []params.OfferStatusChange{{
+			OfferName: "hosted-mysql",
+			Status:    "active",
+			Info:      "ready",
+		}},
+	}
+	next <- params.OfferStatusWatchResult{
+		Changes: []params.OfferStatusChange{{
+			OfferName: "hosted-mysql",
+			Status:    "error",
+			Info:      "hook failed",
+		}},
+	}
+	assertChange([]corewatcher.OfferStatusChange{{
+		Name:    "hosted-mysql",
+		Status:  status.Error,
+		Message: "hook failed",
+	}})
+}
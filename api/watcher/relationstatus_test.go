@@ -0,0 +1,106 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"time"
+
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	apitesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/life"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type relationStatusWatcherSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&relationStatusWatcherSuite{})
+
+func (s *relationStatusWatcherSuite) TestChanges(c *gc.C) {
+	next := make(chan params.RelationLifeSuspendedStatusWatchResult)
+	stopped := make(chan struct{})
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "RelationStatusWatcher")
+		c.Check(id, gc.Equals, "66")
+		switch request {
+		case "Next":
+			select {
+			case r := <-next:
+				// The watcher passes a pointer to the interface
+				// holding its result value.
+				out := (*result.(*interface{})).(*params.RelationLifeSuspendedStatusWatchResult)
+				*out = r
+				return nil
+			case <-stopped:
+				return &params.Error{Code: params.CodeStopped}
+			}
+		case "Stop":
+			close(stopped)
+		}
+		return nil
+	})
+	w := watcher.NewRelationStatusWatcher(apiCaller, params.RelationLifeSuspendedStatusWatchResult{
+		RelationStatusWatcherId: "66",
+		Changes: []params.RelationLifeSuspendedStatusChange{{
+			Key:  "wordpress:db mysql:db",
+			Life: params.Alive,
+		}},
+	})
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	assertChange := func(expect []corewatcher.RelationStatusChange) {
+		select {
+		case changes, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+			c.Check(changes, jc.DeepEquals, expect)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for change")
+		}
+	}
+	assertChange([]corewatcher.RelationStatusChange{{
+		Key:  "wordpress:db mysql:db",
+		Life: life.Alive,
+	}})
+
+	next <- params.RelationLifeSuspendedStatusWatchResult{
+		Changes: []params.RelationLifeSuspendedStatusChange{{
+			Key:             "wordpress:db mysql:db",
+			Life:            params.Alive,
+			Suspended:       true,
+			SuspendedReason: "maintenance",
+		}},
+	}
+	assertChange([]corewatcher.RelationStatusChange{{
+		Key:             "wordpress:db mysql:db",
+		Life:            life.Alive,
+		Suspended:       true,
+		SuspendedReason: "maintenance",
+	}})
+}
+
+func (s *relationStatusWatcherSuite) TestInvalidLife(c *gc.C) {
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Errorf("unexpected API call %s.%s", objType, request)
+		return nil
+	})
+	w := watcher.NewRelationStatusWatcher(apiCaller, params.RelationLifeSuspendedStatusWatchResult{
+		RelationStatusWatcherId: "66",
+		Changes: []params.RelationLifeSuspendedStatusChange{{
+			Key:  "wordpress:db mysql:db",
+			Life: "bewildered",
+		}},
+	})
+	err := w.Wait()
+	c.Check(err, gc.ErrorMatches, `relation "wordpress:db mysql:db": life value "bewildered" not valid`)
+}
@@ -0,0 +1,252 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/clock"
+	"gopkg.in/tomb.v1"
+
+	"github.com/juju/juju/watcher"
+	"github.com/juju/juju/worker"
+)
+
+// ResumeConfig holds the configuration for a resumable watcher.
+type ResumeConfig struct {
+	// Clock is used to wait between attempts to re-establish a
+	// watcher.
+	Clock clock.Clock
+
+	// Attempts is the number of times the watcher will be
+	// re-established in a row before the resumable watcher dies.
+	Attempts int
+
+	// Delay is the time to wait before each attempt.
+	Delay time.Duration
+}
+
+// Validate returns an error if the config cannot be used to create a
+// resumable watcher.
+func (config ResumeConfig) Validate() error {
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.Attempts < 1 {
+		return errors.NotValidf("Attempts %d", config.Attempts)
+	}
+	if config.Delay < 0 {
+		return errors.NotValidf("negative Delay")
+	}
+	return nil
+}
+
+// resumer holds the logic common to resumable watchers.
+type resumer struct {
+	tomb   tomb.Tomb
+	config ResumeConfig
+}
+
+// Kill is part of the worker.Worker interface.
+func (r *resumer) Kill() {
+	r.tomb.Kill(nil)
+}
+
+// KillWithReason is part of the ReasonKiller interface.
+func (r *resumer) KillWithReason(reason error) {
+	r.tomb.Kill(reason)
+}
+
+// Wait is part of the worker.Worker interface.
+func (r *resumer) Wait() error {
+	return r.tomb.Wait()
+}
+
+// Stop kills the watcher and waits for it to finish, returning the
+// error it finished with.
+//
+// Deprecated: use Kill and Wait, or worker.Stop.
+func (r *resumer) Stop() error {
+	r.Kill()
+	return r.Wait()
+}
+
+// watchDeath returns a channel on which the error the given watcher
+// dies with will be sent.
+func watchDeath(w worker.Worker) <-chan error {
+	dead := make(chan error, 1)
+	go func() {
+		err := w.Wait()
+		if err == nil {
+			err = errors.New("watcher stopped unexpectedly")
+		}
+		dead <- err
+	}()
+	return dead
+}
+
+// reestablish calls start until it succeeds, waiting before each
+// attempt, and returns an error if it fails the configured number of
+// times. It returns tomb.ErrDying if the resumable watcher is killed
+// while waiting.
+func (r *resumer) reestablish(cause error, start func() error) error {
+	lastErr := cause
+	for attempt := 1; attempt <= r.config.Attempts; attempt++ {
+		logger.Debugf("re-establishing watcher (attempt %d) after error: %v", attempt, lastErr)
+		select {
+		case <-r.tomb.Dying():
+			return tomb.ErrDying
+		case <-r.config.Clock.After(r.config.Delay):
+		}
+		if lastErr = start(); lastErr == nil {
+			return nil
+		}
+	}
+	return errors.Annotatef(lastErr, "re-establishing watcher failed after %d attempts", r.config.Attempts)
+}
+
+// NewResumableStringsWatcher returns a StringsWatcher that uses watch
+// to start a watcher, and calls it again to re-establish the watcher
+// if it fails, for example because the API connection was dropped.
+// The initial event of each re-established watcher is delivered on the
+// same Changes channel, so consumers see every change at least once.
+func NewResumableStringsWatcher(watch func() (watcher.StringsWatcher, error), config ResumeConfig) (watcher.StringsWatcher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	current, err := watch()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := &resumableStringsWatcher{
+		resumer: resumer{config: config},
+		watch:   watch,
+		out:     make(chan []string),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop(current))
+	}()
+	return w, nil
+}
+
+type resumableStringsWatcher struct {
+	resumer
+	watch func() (watcher.StringsWatcher, error)
+	out   chan []string
+}
+
+func (w *resumableStringsWatcher) loop(current watcher.StringsWatcher) error {
+	for {
+		err := w.forward(current)
+		if err == tomb.ErrDying {
+			return err
+		}
+		err = w.reestablish(err, func() (err error) {
+			current, err = w.watch()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// forward delivers the changes from the given watcher until it dies,
+// returning its error, or the resumable watcher is killed.
+func (w *resumableStringsWatcher) forward(current watcher.StringsWatcher) error {
+	defer worker.Stop(current)
+	dead := watchDeath(current)
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case err := <-dead:
+			return err
+		case changes := <-current.Changes():
+			select {
+			case <-w.tomb.Dying():
+				return tomb.ErrDying
+			case w.out <- changes:
+			}
+		}
+	}
+}
+
+// Changes is part of the watcher.StringsWatcher interface.
+func (w *resumableStringsWatcher) Changes() watcher.StringsChannel {
+	return w.out
+}
+
+// NewResumableRelationUnitsWatcher returns a RelationUnitsWatcher that
+// uses watch to start a watcher, and calls it again to re-establish the
+// watcher if it fails, as described for NewResumableStringsWatcher.
+func NewResumableRelationUnitsWatcher(watch func() (watcher.RelationUnitsWatcher, error), config ResumeConfig) (watcher.RelationUnitsWatcher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	current, err := watch()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := &resumableRelationUnitsWatcher{
+		resumer: resumer{config: config},
+		watch:   watch,
+		out:     make(chan watcher.RelationUnitsChange),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop(current))
+	}()
+	return w, nil
+}
+
+type resumableRelationUnitsWatcher struct {
+	resumer
+	watch func() (watcher.RelationUnitsWatcher, error)
+	out   chan watcher.RelationUnitsChange
+}
+
+func (w *resumableRelationUnitsWatcher) loop(current watcher.RelationUnitsWatcher) error {
+	for {
+		err := w.forward(current)
+		if err == tomb.ErrDying {
+			return err
+		}
+		err = w.reestablish(err, func() (err error) {
+			current, err = w.watch()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// forward delivers the changes from the given watcher until it dies,
+// returning its error, or the resumable watcher is killed.
+func (w *resumableRelationUnitsWatcher) forward(current watcher.RelationUnitsWatcher) error {
+	defer worker.Stop(current)
+	dead := watchDeath(current)
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case err := <-dead:
+			return err
+		case change := <-current.Changes():
+			select {
+			case <-w.tomb.Dying():
+				return tomb.ErrDying
+			case w.out <- change:
+			}
+		}
+	}
+}
+
+// Changes is part of the watcher.RelationUnitsWatcher interface.
+func (w *resumableRelationUnitsWatcher) Changes() watcher.RelationUnitsChannel {
+	return w.out
+}
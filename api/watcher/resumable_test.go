@@ -0,0 +1,228 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/tomb.v1"
+
+	"github.com/juju/juju/api/watcher"
+	coretesting "github.com/juju/juju/testing"
+	corewatcher "github.com/juju/juju/watcher"
+)
+
+type resumableWatcherSuite struct {
+	jujutesting.IsolationSuite
+}
+
+var _ = gc.Suite(&resumableWatcherSuite{})
+
+func (s *resumableWatcherSuite) config() watcher.ResumeConfig {
+	return watcher.ResumeConfig{
+		Clock:    immediateClock{jujutesting.NewClock(time.Time{})},
+		Attempts: 3,
+		Delay:    time.Second,
+	}
+}
+
+func (s *resumableWatcherSuite) TestValidate(c *gc.C) {
+	for i, test := range []struct {
+		mutate func(*watcher.ResumeConfig)
+		err    string
+	}{{
+		mutate: func(config *watcher.ResumeConfig) { config.Clock = nil },
+		err:    "nil Clock not valid",
+	}, {
+		mutate: func(config *watcher.ResumeConfig) { config.Attempts = 0 },
+		err:    "Attempts 0 not valid",
+	}, {
+		mutate: func(config *watcher.ResumeConfig) { config.Delay = -time.Second },
+		err:    "negative Delay not valid",
+	}} {
+		c.Logf("test %d", i)
+		config := s.config()
+		test.mutate(&config)
+		err := config.Validate()
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *resumableWatcherSuite) TestInitialWatchError(c *gc.C) {
+	w, err := watcher.NewResumableStringsWatcher(func() (corewatcher.StringsWatcher, error) {
+		return nil, errors.New("boom")
+	}, s.config())
+	c.Check(err, gc.ErrorMatches, "boom")
+	c.Check(w, gc.IsNil)
+}
+
+func (s *resumableWatcherSuite) TestReestablishesStringsWatcher(c *gc.C) {
+	var watchers []*fakeStringsWatcher
+	watch := func() (corewatcher.StringsWatcher, error) {
+		w := newFakeStringsWatcher([]string{"initial"})
+		watchers = append(watchers, w)
+		return w, nil
+	}
+	w, err := watcher.NewResumableStringsWatcher(watch, s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	assertStringsChange(c, w, "initial")
+	c.Assert(watchers, gc.HasLen, 1)
+	watchers[0].changes <- []string{"first"}
+	assertStringsChange(c, w, "first")
+
+	// Kill the underlying watcher, as happens when the API
+	// connection is dropped; the fresh watcher's initial event
+	// is delivered on the same channel.
+	watchers[0].tomb.Kill(errors.New("connection is shut down"))
+	assertStringsChange(c, w, "initial")
+	c.Assert(watchers, gc.HasLen, 2)
+	c.Check(watchers[0].stopped(), jc.IsTrue)
+	watchers[1].changes <- []string{"second"}
+	assertStringsChange(c, w, "second")
+}
+
+func (s *resumableWatcherSuite) TestGivesUpAfterAttempts(c *gc.C) {
+	var calls int
+	first := newFakeStringsWatcher([]string{"initial"})
+	watch := func() (corewatcher.StringsWatcher, error) {
+		calls++
+		if calls == 1 {
+			return first, nil
+		}
+		return nil, errors.Errorf("attempt %d failed", calls-1)
+	}
+	w, err := watcher.NewResumableStringsWatcher(watch, s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	assertStringsChange(c, w, "initial")
+
+	first.tomb.Kill(errors.New("connection is shut down"))
+	err = w.Wait()
+	c.Check(err, gc.ErrorMatches, "re-establishing watcher failed after 3 attempts: attempt 3 failed")
+	c.Check(calls, gc.Equals, 4)
+}
+
+func (s *resumableWatcherSuite) TestReestablishesRelationUnitsWatcher(c *gc.C) {
+	var watchers []*fakeRelationUnitsWatcher
+	initial := corewatcher.RelationUnitsChange{
+		Changed: map[string]corewatcher.UnitSettings{"mysql/0": {Version: 1}},
+	}
+	watch := func() (corewatcher.RelationUnitsWatcher, error) {
+		w := newFakeRelationUnitsWatcher(initial)
+		watchers = append(watchers, w)
+		return w, nil
+	}
+	w, err := watcher.NewResumableRelationUnitsWatcher(watch, s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		w.Kill()
+		c.Check(w.Wait(), jc.ErrorIsNil)
+	}()
+
+	assertRelationUnitsChange(c, w, initial)
+	watchers[0].tomb.Kill(errors.New("connection is shut down"))
+	assertRelationUnitsChange(c, w, initial)
+	c.Check(watchers, gc.HasLen, 2)
+}
+
+func assertStringsChange(c *gc.C, w corewatcher.StringsWatcher, expect ...string) {
+	select {
+	case changes, ok := <-w.Changes():
+		c.Assert(ok, jc.IsTrue)
+		c.Check(changes, jc.DeepEquals, expect)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for change")
+	}
+}
+
+func assertRelationUnitsChange(c *gc.C, w corewatcher.RelationUnitsWatcher, expect corewatcher.RelationUnitsChange) {
+	select {
+	case change, ok := <-w.Changes():
+		c.Assert(ok, jc.IsTrue)
+		c.Check(change, jc.DeepEquals, expect)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for change")
+	}
+}
+
+// immediateClock is a testing clock whose After channels fire
+// immediately.
+type immediateClock struct {
+	*jujutesting.Clock
+}
+
+func (c immediateClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Now().Add(d)
+	return ch
+}
+
+type fakeWatcher struct {
+	tomb tomb.Tomb
+}
+
+func (w *fakeWatcher) run() {
+	go func() {
+		defer w.tomb.Done()
+		<-w.tomb.Dying()
+	}()
+}
+
+func (w *fakeWatcher) Kill() {
+	w.tomb.Kill(nil)
+}
+
+func (w *fakeWatcher) Wait() error {
+	return w.tomb.Wait()
+}
+
+func (w *fakeWatcher) stopped() bool {
+	select {
+	case <-w.tomb.Dead():
+		return true
+	case <-time.After(coretesting.LongWait):
+		return false
+	}
+}
+
+type fakeStringsWatcher struct {
+	fakeWatcher
+	changes chan []string
+}
+
+func newFakeStringsWatcher(initial []string) *fakeStringsWatcher {
+	w := &fakeStringsWatcher{changes: make(chan []string, 1)}
+	w.changes <- initial
+	w.run()
+	return w
+}
+
+func (w *fakeStringsWatcher) Changes() corewatcher.StringsChannel {
+	return w.changes
+}
+
+type fakeRelationUnitsWatcher struct {
+	fakeWatcher
+	changes chan corewatcher.RelationUnitsChange
+}
+
+func newFakeRelationUnitsWatcher(initial corewatcher.RelationUnitsChange) *fakeRelationUnitsWatcher {
+	w := &fakeRelationUnitsWatcher{changes: make(chan corewatcher.RelationUnitsChange, 1)}
+	w.changes <- initial
+	w.run()
+	return w
+}
+
+func (w *fakeRelationUnitsWatcher) Changes() corewatcher.RelationUnitsChannel {
+	return w.changes
+}
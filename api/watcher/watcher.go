@@ -8,16 +8,43 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
+	"gopkg.in/juju/names.v2"
 	"gopkg.in/tomb.v1"
 
 	"github.com/juju/juju/api/base"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/life"
 	"github.com/juju/juju/core/migration"
+	"github.com/juju/juju/status"
 	"github.com/juju/juju/watcher"
+	"github.com/juju/juju/worker"
 )
 
 var logger = loggo.GetLogger("juju.api.watcher")
 
+// ReasonKiller is implemented by the watchers in this package. As well
+// as being killed plainly, they can be killed with a reason, which
+// becomes the result of Wait. However they are killed, the watcher is
+// stopped on the server too.
+type ReasonKiller interface {
+	worker.Worker
+
+	// KillWithReason kills the watcher, recording reason as the
+	// error to be returned by Wait, unless the watcher has already
+	// failed. A nil reason is the same as calling Kill.
+	KillWithReason(reason error)
+}
+
+// KillWithReason kills w with the given reason if it is a
+// ReasonKiller, and plainly otherwise.
+func KillWithReason(w worker.Worker, reason error) {
+	if rk, ok := w.(ReasonKiller); ok {
+		rk.KillWithReason(reason)
+		return
+	}
+	w.Kill()
+}
+
 // commonWatcher implements common watcher logic in one place to
 // reduce code duplication, but it's not in fact a complete watcher;
 // it's intended for embedding.
@@ -126,11 +153,25 @@ func (w *commonWatcher) Kill() {
 	w.tomb.Kill(nil)
 }
 
+// KillWithReason is part of the ReasonKiller interface.
+func (w *commonWatcher) KillWithReason(reason error) {
+	w.tomb.Kill(reason)
+}
+
 // Wait is part of the worker.Worker interface.
 func (w *commonWatcher) Wait() error {
 	return w.tomb.Wait()
 }
 
+// Stop kills the watcher and waits for it to finish, returning the
+// error it finished with.
+//
+// Deprecated: use Kill and Wait, or worker.Stop.
+func (w *commonWatcher) Stop() error {
+	w.Kill()
+	return w.Wait()
+}
+
 // notifyWatcher will send events when something changes.
 // It does not send content for those changes.
 type notifyWatcher struct {
@@ -245,13 +286,28 @@ type relationUnitsWatcher struct {
 	caller                 base.APICaller
 	relationUnitsWatcherId string
 	out                    chan watcher.RelationUnitsChange
+	coalesce               bool
 }
 
 func NewRelationUnitsWatcher(caller base.APICaller, result params.RelationUnitsWatchResult) watcher.RelationUnitsWatcher {
+	return newRelationUnitsWatcher(caller, result, false)
+}
+
+// NewCoalescingRelationUnitsWatcher returns a RelationUnitsWatcher like
+// NewRelationUnitsWatcher, except that changes arriving while the
+// consumer has yet to read from the Changes channel are merged into a
+// single pending change, so that a slow consumer always receives the
+// most recent consolidated view instead of a backlog of events.
+func NewCoalescingRelationUnitsWatcher(caller base.APICaller, result params.RelationUnitsWatchResult) watcher.RelationUnitsWatcher {
+	return newRelationUnitsWatcher(caller, result, true)
+}
+
+func newRelationUnitsWatcher(caller base.APICaller, result params.RelationUnitsWatchResult, coalesce bool) watcher.RelationUnitsWatcher {
 	w := &relationUnitsWatcher{
 		caller:                 caller,
 		relationUnitsWatcherId: result.RelationUnitsWatcherId,
-		out: make(chan watcher.RelationUnitsChange),
+		out:                    make(chan watcher.RelationUnitsChange),
+		coalesce:               coalesce,
 	}
 	go func() {
 		defer w.tomb.Done()
@@ -282,6 +338,9 @@ func (w *relationUnitsWatcher) loop(initialChanges params.RelationUnitsChange) e
 	w.commonWatcher.init()
 	go w.commonLoop()
 
+	if w.coalesce {
+		return w.coalescingLoop(changes)
+	}
 	for {
 		select {
 		// Send the initial event or subsequent change.
@@ -441,6 +500,283 @@ func (w *entitiesWatcher) Changes() watcher.StringsChannel {
 	return w.out
 }
 
+// relationStatusWatcher will sends notifications of changes to the
+// life and suspended status of relations.
+type relationStatusWatcher struct {
+	commonWatcher
+	caller                  base.APICaller
+	relationStatusWatcherId string
+	out                     chan []watcher.RelationStatusChange
+}
+
+// NewRelationStatusWatcher returns a watcher notifying of changes to
+// the life and suspended status of relations, given the result of an
+// API call that started a RelationStatusWatcher.
+func NewRelationStatusWatcher(caller base.APICaller, result params.RelationLifeSuspendedStatusWatchResult) watcher.RelationStatusWatcher {
+	w := &relationStatusWatcher{
+		caller:                  caller,
+		relationStatusWatcherId: result.RelationStatusWatcherId,
+		out:                     make(chan []watcher.RelationStatusChange),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop(result.Changes))
+	}()
+	return w
+}
+
+func copyRelationStatusChanges(src []params.RelationLifeSuspendedStatusChange) ([]watcher.RelationStatusChange, error) {
+	dst := make([]watcher.RelationStatusChange, len(src))
+	for i, change := range src {
+		relationLife := life.Value(change.Life)
+		if err := relationLife.Validate(); err != nil {
+			return nil, errors.Annotatef(err, "relation %q", change.Key)
+		}
+		dst[i] = watcher.RelationStatusChange{
+			Key:             change.Key,
+			Life:            relationLife,
+			Suspended:       change.Suspended,
+			SuspendedReason: change.SuspendedReason,
+		}
+	}
+	return dst, nil
+}
+
+func (w *relationStatusWatcher) loop(initialChanges []params.RelationLifeSuspendedStatusChange) error {
+	changes, err := copyRelationStatusChanges(initialChanges)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.newResult = func() interface{} { return new(params.RelationLifeSuspendedStatusWatchResult) }
+	w.call = makeWatcherAPICaller(w.caller, "RelationStatusWatcher", w.relationStatusWatcherId)
+	w.commonWatcher.init()
+	go w.commonLoop()
+
+	for {
+		select {
+		// Send the initial event or subsequent change.
+		case w.out <- changes:
+		case <-w.tomb.Dying():
+			return nil
+		}
+		// Read the next change.
+		data, ok := <-w.in
+		if !ok {
+			// The tomb is already killed with the correct error
+			// at this point, so just return.
+			return nil
+		}
+		changes, err = copyRelationStatusChanges(data.(*params.RelationLifeSuspendedStatusWatchResult).Changes)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// Changes returns a channel that will receive the changes to
+// the life and suspended status of the watched relations.
+func (w *relationStatusWatcher) Changes() watcher.RelationStatusChannel {
+	return w.out
+}
+
+// applicationRelationsWatcher will send notifications of changes to
+// the relations of an application.
+type applicationRelationsWatcher struct {
+	commonWatcher
+	caller                        base.APICaller
+	applicationRelationsWatcherId string
+	out                           chan []watcher.ApplicationRelationsChange
+}
+
+// NewApplicationRelationsWatcher returns a watcher notifying of changes
+// to the relations of an application, given the result of an API call
+// that started an ApplicationRelationsWatcher. The relation and unit
+// tags sent by the server are translated to plain keys and names; the
+// watcher fails if a change lacks its relation tag or life, or holds
+// an invalid tag. Fields it does not know about are ignored.
+func NewApplicationRelationsWatcher(caller base.APICaller, result params.ApplicationRelationsWatchResult) watcher.ApplicationRelationsWatcher {
+	w := &applicationRelationsWatcher{
+		caller:                        caller,
+		applicationRelationsWatcherId: result.ApplicationRelationsWatcherId,
+		out:                           make(chan []watcher.ApplicationRelationsChange),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop(result.Changes))
+	}()
+	return w
+}
+
+func copyApplicationRelationsChanges(src []params.ApplicationRelationChange) ([]watcher.ApplicationRelationsChange, error) {
+	dst := make([]watcher.ApplicationRelationsChange, len(src))
+	for i, change := range src {
+		if change.RelationTag == "" {
+			return nil, errors.NotValidf("change %d with no relation tag", i)
+		}
+		relationTag, err := names.ParseRelationTag(change.RelationTag)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		key := relationTag.Id()
+		relationLife := life.Value(change.Life)
+		if err := relationLife.Validate(); err != nil {
+			return nil, errors.Annotatef(err, "relation %q", key)
+		}
+		var changed map[string]watcher.UnitSettings
+		if len(change.ChangedUnits) > 0 {
+			changed = make(map[string]watcher.UnitSettings)
+		}
+		for _, unit := range change.ChangedUnits {
+			unitTag, err := names.ParseUnitTag(unit.UnitTag)
+			if err != nil {
+				return nil, errors.Annotatef(err, "relation %q", key)
+			}
+			changed[unitTag.Id()] = watcher.UnitSettings{Version: unit.Version}
+		}
+		var departed []string
+		for _, tag := range change.DepartedUnits {
+			unitTag, err := names.ParseUnitTag(tag)
+			if err != nil {
+				return nil, errors.Annotatef(err, "relation %q", key)
+			}
+			departed = append(departed, unitTag.Id())
+		}
+		dst[i] = watcher.ApplicationRelationsChange{
+			Key:       key,
+			Life:      relationLife,
+			Suspended: change.Suspended,
+			Changed:   changed,
+			Departed:  departed,
+		}
+	}
+	return dst, nil
+}
+
+func (w *applicationRelationsWatcher) loop(initialChanges []params.ApplicationRelationChange) error {
+	changes, err := copyApplicationRelationsChanges(initialChanges)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.newResult = func() interface{} { return new(params.ApplicationRelationsWatchResult) }
+	w.call = makeWatcherAPICaller(w.caller, "ApplicationRelationsWatcher", w.applicationRelationsWatcherId)
+	w.commonWatcher.init()
+	go w.commonLoop()
+
+	for {
+		select {
+		// Send the initial event or subsequent change.
+		case w.out <- changes:
+		case <-w.tomb.Dying():
+			return nil
+		}
+		// Read the next change.
+		data, ok := <-w.in
+		if !ok {
+			// The tomb is already killed with the correct error
+			// at this point, so just return.
+			return nil
+		}
+		changes, err = copyApplicationRelationsChanges(data.(*params.ApplicationRelationsWatchResult).Changes)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// Changes returns a channel that will receive the changes to the
+// relations of the watched application.
+func (w *applicationRelationsWatcher) Changes() watcher.ApplicationRelationsChannel {
+	return w.out
+}
+
+// offerStatusWatcher will send notifications of changes to the
+// status of an application offer.
+type offerStatusWatcher struct {
+	commonWatcher
+	caller               base.APICaller
+	offerStatusWatcherId string
+	out                  chan []watcher.OfferStatusChange
+
+	// last holds the most recent status reported for each offer,
+	// so that changes which leave it untouched can be dropped.
+	last map[string]watcher.OfferStatusChange
+}
+
+// NewOfferStatusWatcher returns a watcher notifying of changes to the
+// status of an application offer, given the result of an API call that
+// started an OfferStatusWatcher. Changes that leave an offer's status
+// and message as they were are not reported.
+func NewOfferStatusWatcher(caller base.APICaller, result params.OfferStatusWatchResult) watcher.OfferStatusWatcher {
+	w := &offerStatusWatcher{
+		caller:               caller,
+		offerStatusWatcherId: result.OfferStatusWatcherId,
+		out:                  make(chan []watcher.OfferStatusChange),
+		last:                 make(map[string]watcher.OfferStatusChange),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop(result.Changes))
+	}()
+	return w
+}
+
+// filterChanges converts the supplied changes, dropping any that do
+// not alter the status last reported for the offer.
+func (w *offerStatusWatcher) filterChanges(src []params.OfferStatusChange) []watcher.OfferStatusChange {
+	var dst []watcher.OfferStatusChange
+	for _, change := range src {
+		next := watcher.OfferStatusChange{
+			Name:    change.OfferName,
+			Status:  status.Status(change.Status),
+			Message: change.Info,
+			Since:   change.Since,
+		}
+		if prev, ok := w.last[next.Name]; ok {
+			if prev.Status == next.Status && prev.Message == next.Message {
+				continue
+			}
+		}
+		w.last[next.Name] = next
+		dst = append(dst, next)
+	}
+	return dst
+}
+
+func (w *offerStatusWatcher) loop(initialChanges []params.OfferStatusChange) error {
+	// The initial event is always sent, even if it is empty.
+	changes := w.filterChanges(initialChanges)
+	w.newResult = func() interface{} { return new(params.OfferStatusWatchResult) }
+	w.call = makeWatcherAPICaller(w.caller, "OfferStatusWatcher", w.offerStatusWatcherId)
+	w.commonWatcher.init()
+	go w.commonLoop()
+
+	for {
+		select {
+		// Send the initial event or subsequent change.
+		case w.out <- changes:
+		case <-w.tomb.Dying():
+			return nil
+		}
+		// Read changes until one alters an offer's status.
+		changes = nil
+		for len(changes) == 0 {
+			data, ok := <-w.in
+			if !ok {
+				// The tomb is already killed with the correct error
+				// at this point, so just return.
+				return nil
+			}
+			changes = w.filterChanges(data.(*params.OfferStatusWatchResult).Changes)
+		}
+	}
+}
+
+// Changes returns a channel that will receive the changes to
+// the status of the watched offer.
+func (w *offerStatusWatcher) Changes() watcher.OfferStatusChannel {
+	return w.out
+}
+
 // NewMigrationStatusWatcher takes the NotifyWatcherId returns by the
 // MigrationSlave.Watch API and returns a watcher which will report
 // status changes for any migration of the model associated with the
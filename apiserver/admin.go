@@ -43,7 +43,7 @@ var MaintenanceNoLoginError = errors.New("login failed - maintenance in progress
 var errAlreadyLoggedIn = errors.New("already logged in")
 
 // login is the internal version of the Login API call.
-func (a *admin) login(req params.LoginRequest, loginVersion int) (params.LoginResult, error) {
+func (a *admin) login(req params.LoginRequest, loginVersion int) (result params.LoginResult, err error) {
 	var fail params.LoginResult
 
 	a.mu.Lock()
@@ -52,6 +52,9 @@ func (a *admin) login(req params.LoginRequest, loginVersion int) (params.LoginRe
 		// This can only happen if Login is called concurrently.
 		return fail, errAlreadyLoggedIn
 	}
+	defer func() {
+		a.recordAgentLogin(req, result, err)
+	}()
 
 	// apiRoot is the API root exposed to the client after authentication.
 	var apiRoot rpc.Root = newAPIRoot(a.root.state, a.root.resources, a.root)
@@ -279,6 +282,37 @@ func (a *admin) checkCreds(req params.LoginRequest, lookForModelUser bool) (stat
 	return doCheckCreds(a.root.state, req, lookForModelUser, a.authenticator())
 }
 
+// recordAgentLogin records a machine agent's attempt to log in, for
+// auditing, given the outcome of login. The login succeeded only if it
+// returned no error and no discharge is required. Logins refused by
+// the rate limiter are not recorded, so that a burst of them does not
+// load the database too. Failing to record the login does not affect
+// it.
+func (a *admin) recordAgentLogin(req params.LoginRequest, result params.LoginResult, err error) {
+	if errors.Cause(err) == common.ErrTryAgain {
+		return
+	}
+	success := err == nil && result.DischargeRequired == nil
+	var machine *state.Machine
+	if success {
+		machine, _ = a.root.entity.(*state.Machine)
+	}
+	if machine == nil {
+		tag, err := names.ParseMachineTag(req.AuthTag)
+		if err != nil {
+			return
+		}
+		machine, err = a.root.state.Machine(tag.Id())
+		if err != nil {
+			// Unknown machines have no history to record.
+			return
+		}
+	}
+	if err := machine.RecordAgentLogin(a.root.remoteAddr, success); err != nil {
+		logger.Warningf("cannot record login of %v: %v", machine.Tag(), err)
+	}
+}
+
 func (a *admin) checkControllerMachineCreds(req params.LoginRequest) (state.Entity, error) {
 	return checkControllerMachineCreds(a.srv.state, req, a.authenticator())
 }
@@ -673,6 +673,32 @@ func (s *loginSuite) TestMachineLoginOtherModel(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 }
 
+func (s *loginSuite) TestMachineLoginRecordsHistory(c *gc.C) {
+	info, srv := s.newMachineAndServer(c)
+	defer assertStop(c, srv)
+	password := info.Password
+
+	info.Password = "wrong password"
+	_, err := api.Open(info, fastDialOpts)
+	c.Assert(err, gc.ErrorMatches, "invalid entity name or password.*")
+
+	info.Password = password
+	st, err := api.Open(info, fastDialOpts)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	machine, err := s.State.Machine(info.Tag.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	history, err := machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Check(history[0].Success, jc.IsFalse)
+	c.Check(history[1].Success, jc.IsTrue)
+	for _, login := range history {
+		c.Check(login.Address, gc.Matches, `.+:\d+`)
+	}
+}
+
 func (s *loginSuite) TestMachineLoginOtherModelNotProvisioned(c *gc.C) {
 	info, srv := newServer(c, s.State)
 	defer assertStop(c, srv)
@@ -698,6 +724,37 @@ func (s *loginSuite) TestMachineLoginOtherModelNotProvisioned(c *gc.C) {
 	err := st.Login(machine.Tag(), password, "nonce", nil)
 	c.Assert(err, gc.ErrorMatches, `machine 0 not provisioned \(not provisioned\)`)
 	c.Assert(err, jc.Satisfies, params.IsCodeNotProvisioned)
+
+	history, err := machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 1)
+	c.Check(history[0].Success, jc.IsFalse)
+}
+
+func (s *loginSuite) TestMachineLoginDuringMaintenanceRecordsHistory(c *gc.C) {
+	machine, password := s.Factory.MakeMachineReturningPassword(
+		c, &factory.MachineParams{Nonce: "fake_nonce"})
+	cfg := defaultServerConfig(c)
+	cfg.Validator = func(req params.LoginRequest) error {
+		// Like jujud's validator, refuse only users during an
+		// upgrade.
+		if kind, _ := names.TagKind(req.AuthTag); kind == names.UserTagKind {
+			return params.UpgradeInProgressError
+		}
+		return nil
+	}
+	info, srv := newServerWithConfig(c, s.State, cfg)
+	defer assertStop(c, srv)
+	info.ModelTag = s.State.ModelTag()
+
+	st := s.openAPIWithoutLogin(c, info)
+	err := st.Login(machine.Tag(), password+"wrong", "fake_nonce", nil)
+	c.Assert(err, gc.ErrorMatches, "login failed - maintenance in progress")
+
+	history, err := machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 1)
+	c.Check(history[0].Success, jc.IsFalse)
 }
 
 func (s *loginSuite) TestOtherEnvironmentFromController(c *gc.C) {
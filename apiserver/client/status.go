@@ -553,6 +553,7 @@ func makeMachineStatus(machine *state.Machine) (status params.MachineStatus) {
 	} else {
 		status.Hardware = hc.String()
 	}
+	status.ProvisionerMetadata = machine.ProvisionerMetadata()
 	status.Containers = make(map[string]params.MachineStatus)
 	return
 }
@@ -267,6 +267,86 @@ func NetworkConfigsToStateArgs(networkConfig []params.NetworkConfig) (
 	return devicesArgs, devicesAddrs
 }
 
+// NetworkConfigsToObservedNetworkConfig converts the network config
+// observed by a machine agent, which holds an entry for each address of
+// each interface, into the per-interface form recorded by
+// state.Machine.SetObservedNetworkConfig. Each interface's gateway
+// address becomes its default route. Addresses that cannot be parsed
+// are skipped.
+func NetworkConfigsToObservedNetworkConfig(networkConfig []params.NetworkConfig) []state.NetworkConfig {
+	var result []state.NetworkConfig
+	indexByName := make(map[string]int)
+	for _, netConfig := range networkConfig {
+		i, ok := indexByName[netConfig.InterfaceName]
+		if !ok {
+			var mtu uint
+			if netConfig.MTU >= 0 {
+				mtu = uint(netConfig.MTU)
+			}
+			i = len(result)
+			indexByName[netConfig.InterfaceName] = i
+			result = append(result, state.NetworkConfig{
+				InterfaceName:       netConfig.InterfaceName,
+				ParentInterfaceName: netConfig.ParentInterfaceName,
+				InterfaceType:       netConfig.InterfaceType,
+				MACAddress:          netConfig.MACAddress,
+				MTU:                 mtu,
+				Disabled:            netConfig.Disabled,
+			})
+		}
+		observed := &result[i]
+
+		if netConfig.Address != "" {
+			ipAddr := net.ParseIP(netConfig.Address)
+			if ipAddr == nil {
+				logger.Warningf("ignoring unexpected Address format %q", netConfig.Address)
+				continue
+			}
+			address := ipAddr.String()
+			if _, ipNet, err := net.ParseCIDR(netConfig.CIDR); err == nil {
+				ipNet.IP = ipAddr
+				address = ipNet.String()
+			}
+			observed.Addresses = appendUnique(observed.Addresses, address)
+		}
+		for _, server := range netConfig.DNSServers {
+			observed.DNSServers = appendUnique(observed.DNSServers, server)
+		}
+		if gateway := net.ParseIP(netConfig.GatewayAddress); gateway != nil {
+			destination := "0.0.0.0/0"
+			if gateway.To4() == nil {
+				destination = "::/0"
+			}
+			route := state.NetworkRoute{
+				DestinationCIDR: destination,
+				GatewayIP:       gateway.String(),
+			}
+			if !containsRoute(observed.Routes, route) {
+				observed.Routes = append(observed.Routes, route)
+			}
+		}
+	}
+	return result
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func containsRoute(routes []state.NetworkRoute, route state.NetworkRoute) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
 // NetworkingEnvironFromModelConfig constructs and returns
 // environs.NetworkingEnviron using the given configGetter. Returns an error
 // satisfying errors.IsNotSupported() if the model config does not support
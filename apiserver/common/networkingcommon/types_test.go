@@ -894,6 +894,53 @@ func (s *TypesSuite) TestNetworkConfigsToStateArgs(c *gc.C) {
 	c.Check(devicesAddrs, jc.DeepEquals, expectedLinkLayerDeviceAdressesWithFinalNetworkConfig)
 }
 
+func (s *TypesSuite) TestNetworkConfigsToObservedNetworkConfig(c *gc.C) {
+	observed := networkingcommon.NetworkConfigsToObservedNetworkConfig([]params.NetworkConfig{{
+		InterfaceName:  "eth0",
+		InterfaceType:  "ethernet",
+		MACAddress:     "aa:bb:cc:dd:ee:f0",
+		MTU:            1500,
+		CIDR:           "10.20.19.0/24",
+		Address:        "10.20.19.100",
+		DNSServers:     []string{"10.20.19.2"},
+		GatewayAddress: "10.20.19.1",
+	}, {
+		InterfaceName:  "eth0",
+		InterfaceType:  "ethernet",
+		MACAddress:     "aa:bb:cc:dd:ee:f0",
+		MTU:            1500,
+		CIDR:           "10.20.19.0/24",
+		Address:        "10.20.19.101",
+		DNSServers:     []string{"10.20.19.2", "10.20.19.3"},
+		GatewayAddress: "10.20.19.1",
+	}, {
+		InterfaceName: "eth1",
+		InterfaceType: "ethernet",
+		Address:       "fe80::1",
+		Disabled:      true,
+	}, {
+		InterfaceName: "eth1",
+		Address:       "bogus",
+	}})
+	c.Assert(observed, jc.DeepEquals, []state.NetworkConfig{{
+		InterfaceName: "eth0",
+		InterfaceType: "ethernet",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		Addresses:     []string{"10.20.19.100/24", "10.20.19.101/24"},
+		DNSServers:    []string{"10.20.19.2", "10.20.19.3"},
+		Routes: []state.NetworkRoute{{
+			DestinationCIDR: "0.0.0.0/0",
+			GatewayIP:       "10.20.19.1",
+		}},
+	}, {
+		InterfaceName: "eth1",
+		InterfaceType: "ethernet",
+		Disabled:      true,
+		Addresses:     []string{"fe80::1"},
+	}})
+}
+
 func (s *TypesSuite) TestMergeProviderAndObservedNetworkConfigsBothNil(c *gc.C) {
 	result := networkingcommon.MergeProviderAndObservedNetworkConfigs(nil, nil)
 	c.Check(result, gc.IsNil)
@@ -190,3 +190,67 @@ func (w *MultiNotifyWatcher) Err() error {
 func (w *MultiNotifyWatcher) Changes() <-chan struct{} {
 	return w.changes
 }
+
+// StringsNotifyWatcher implements state.NotifyWatcher, notifying
+// whenever a StringsWatcher reports changes.
+type StringsNotifyWatcher struct {
+	tomb    tomb.Tomb
+	source  state.StringsWatcher
+	changes chan struct{}
+}
+
+// NewStringsNotifyWatcher returns a NotifyWatcher that sends an event
+// for each event sent by the given StringsWatcher, discarding their
+// contents. The watcher takes ownership of w, and stops it when it is
+// itself stopped.
+func NewStringsNotifyWatcher(w state.StringsWatcher) *StringsNotifyWatcher {
+	sw := &StringsNotifyWatcher{
+		source:  w,
+		changes: make(chan struct{}),
+	}
+	go func() {
+		defer sw.tomb.Done()
+		defer close(sw.changes)
+		defer watcher.Stop(w, &sw.tomb)
+		sw.tomb.Kill(sw.loop())
+	}()
+	return sw
+}
+
+func (w *StringsNotifyWatcher) loop() error {
+	var out chan<- struct{}
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case _, ok := <-w.source.Changes():
+			if !ok {
+				return watcher.EnsureErr(w.source)
+			}
+			out = w.changes
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
+func (w *StringsNotifyWatcher) Kill() {
+	w.tomb.Kill(nil)
+}
+
+func (w *StringsNotifyWatcher) Wait() error {
+	return w.tomb.Wait()
+}
+
+func (w *StringsNotifyWatcher) Stop() error {
+	w.Kill()
+	return w.Wait()
+}
+
+func (w *StringsNotifyWatcher) Err() error {
+	return w.tomb.Err()
+}
+
+func (w *StringsNotifyWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
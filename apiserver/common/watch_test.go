@@ -15,6 +15,8 @@ import (
 	apiservertesting "github.com/juju/juju/apiserver/testing"
 	"github.com/juju/juju/state"
 	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/workertest"
 )
 
 type agentEntityWatcherSuite struct{}
@@ -129,3 +131,60 @@ func (*multiNotifyWatcherSuite) TestMultiNotifyWatcherStop(c *gc.C) {
 type nopSyncStarter struct{}
 
 func (nopSyncStarter) StartSync() {}
+
+type stringsNotifyWatcherSuite struct{}
+
+var _ = gc.Suite(&stringsNotifyWatcherSuite{})
+
+type fakeMachinesWatcher struct {
+	worker.Worker
+	C chan []string
+}
+
+func newFakeMachinesWatcher() *fakeMachinesWatcher {
+	return &fakeMachinesWatcher{
+		Worker: workertest.NewErrorWorker(nil),
+		C:      make(chan []string, 1),
+	}
+}
+
+func (w *fakeMachinesWatcher) Changes() <-chan []string {
+	return w.C
+}
+
+func (w *fakeMachinesWatcher) Stop() error {
+	return worker.Stop(w)
+}
+
+func (w *fakeMachinesWatcher) Err() error {
+	return nil
+}
+
+func (*stringsNotifyWatcherSuite) TestStringsNotifyWatcher(c *gc.C) {
+	source := newFakeMachinesWatcher()
+	w := common.NewStringsNotifyWatcher(source)
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, nopSyncStarter{}, w)
+	wc.AssertNoChange()
+
+	source.C <- []string{"0", "1"}
+	wc.AssertOneChange()
+	source.C <- []string{"1"}
+	wc.AssertOneChange()
+}
+
+func (*stringsNotifyWatcherSuite) TestStringsNotifyWatcherStopsSource(c *gc.C) {
+	source := newFakeMachinesWatcher()
+	w := common.NewStringsNotifyWatcher(source)
+	statetesting.AssertStop(c, w)
+	workertest.CheckKilled(c, source)
+	statetesting.NewNotifyWatcherC(c, nopSyncStarter{}, w).AssertClosed()
+}
+
+func (*stringsNotifyWatcherSuite) TestStringsNotifyWatcherSourceClosed(c *gc.C) {
+	source := newFakeMachinesWatcher()
+	w := common.NewStringsNotifyWatcher(source)
+	close(source.C)
+	err := w.Wait()
+	c.Assert(err, gc.ErrorMatches, "expected an error from .*, got nil")
+}
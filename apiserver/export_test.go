@@ -99,7 +99,7 @@ func TestingAPIHandler(c *gc.C, srvSt, st *state.State) (*apiHandler, *common.Re
 		state:    srvSt,
 		tag:      names.NewMachineTag("0"),
 	}
-	h, err := newAPIHandler(srv, st, nil, st.ModelUUID(), "testing.invalid:1234")
+	h, err := newAPIHandler(srv, st, nil, st.ModelUUID(), "testing.invalid:1234", "127.0.0.1:1234")
 	c.Assert(err, jc.ErrorIsNil)
 	return h, h.getResources()
 }
@@ -153,6 +153,10 @@ func (api *MachinerAPI) SetObservedNetworkConfig(args params.SetMachineNetworkCo
 		logger.Infof("not updating machine network config: no observed network config found")
 		return nil
 	}
+	observedNetworkConfig := networkingcommon.NetworkConfigsToObservedNetworkConfig(observedConfig)
+	if err := m.SetObservedNetworkConfig(observedNetworkConfig); err != nil {
+		return errors.Trace(err)
+	}
 
 	providerConfig, err := api.getOneMachineProviderNetworkConfig(m)
 	if errors.IsNotProvisioned(err) {
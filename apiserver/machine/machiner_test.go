@@ -323,6 +323,30 @@ func (s *machinerSuite) TestSetObservedNetworkConfig(c *gc.C) {
 	}
 }
 
+func (s *machinerSuite) TestSetObservedNetworkConfigRecordsObservedConfig(c *gc.C) {
+	args := params.SetMachineNetworkConfig{
+		Tag: s.machine1.Tag().String(),
+		Config: []params.NetworkConfig{{
+			InterfaceName: "eth0",
+			InterfaceType: "ethernet",
+			MACAddress:    "aa:bb:cc:dd:ee:f0",
+			CIDR:          "10.0.0.0/24",
+			Address:       "10.0.0.2",
+		}},
+	}
+	err := s.machiner.SetObservedNetworkConfig(args)
+	c.Assert(err, jc.ErrorIsNil)
+
+	observed, err := s.machine1.ObservedNetworkConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, jc.DeepEquals, []state.NetworkConfig{{
+		InterfaceName: "eth0",
+		InterfaceType: "ethernet",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		Addresses:     []string{"10.0.0.2/24"},
+	}})
+}
+
 func (s *machinerSuite) TestSetObservedNetworkConfigPermissions(c *gc.C) {
 	args := params.SetMachineNetworkConfig{
 		Tag:    "machine-0",
@@ -90,6 +90,7 @@ const (
 	CodeDischargeRequired         = "macaroon discharge required"
 	CodeRedirect                  = "redirection required"
 	CodeRetry                     = "retry"
+	CodeSuspended                 = "suspended"
 )
 
 // ErrCode returns the error code associated with
@@ -235,3 +236,7 @@ func IsMethodNotAllowed(err error) bool {
 func IsRedirect(err error) bool {
 	return ErrCode(err) == CodeRedirect
 }
+
+func IsCodeSuspended(err error) bool {
+	return ErrCode(err) == CodeSuspended
+}
@@ -4,6 +4,7 @@
 package params
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/juju/version"
@@ -211,6 +212,11 @@ type Settings map[string]string
 type SettingsResult struct {
 	Error    *Error   `json:"error,omitempty"`
 	Settings Settings `json:"settings"`
+
+	// Hash, if set, identifies the content of Settings: it changes
+	// when the content does, but not when settings are rewritten
+	// unchanged.
+	Hash string `json:"hash,omitempty"`
 }
 
 // SettingsResults holds the result of an API calls that
@@ -373,6 +379,35 @@ type InstancesInfo struct {
 	Machines []InstanceInfo `json:"machines"`
 }
 
+// ProvisionerMetadataArg holds the details recorded by the provisioner
+// about how a machine's instance was configured. Entries whose keys
+// start with any of SecretPrefixes are discarded before the metadata
+// is stored.
+type ProvisionerMetadataArg struct {
+	Tag            string            `json:"tag"`
+	Metadata       map[string]string `json:"metadata"`
+	SecretPrefixes []string          `json:"secret-prefixes,omitempty"`
+}
+
+// ProvisionerMetadataArgs holds the parameters for making a
+// SetProvisionerMetadata call for multiple machines.
+type ProvisionerMetadataArgs struct {
+	Args []ProvisionerMetadataArg `json:"args"`
+}
+
+// InstanceTagsArg holds the tags that the provisioner set on a
+// machine's instance.
+type InstanceTagsArg struct {
+	Tag  string            `json:"tag"`
+	Tags map[string]string `json:"tags"`
+}
+
+// InstanceTagsArgs holds the parameters for making a
+// SetAppliedInstanceTags call for multiple machines.
+type InstanceTagsArgs struct {
+	Args []InstanceTagsArg `json:"args"`
+}
+
 // EntityStatus holds the status of an entity.
 type EntityStatus struct {
 	Status status.Status          `json:"status"`
@@ -722,3 +757,34 @@ type LogMessage struct {
 	Location  string    `json:"loc"`
 	Message   string    `json:"msg"`
 }
+
+// WatcherId identifies a server-side watcher by the facade that
+// serves it and its id.
+type WatcherId struct {
+	Facade  string `json:"facade"`
+	Version int    `json:"version"`
+	Id      string `json:"id"`
+}
+
+// NextBatchArgs holds the watchers for which a WatcherMultiplexer
+// NextBatch call should wait for changes. MaxWait bounds the time the
+// server waits before returning, even if no watcher has changed.
+type NextBatchArgs struct {
+	Watchers []WatcherId   `json:"watchers"`
+	MaxWait  time.Duration `json:"max-wait"`
+}
+
+// NextBatchResult holds the result of a Next call for a single
+// watcher; Result holds the JSON encoding of the value the watcher's
+// own Next call would have returned.
+type NextBatchResult struct {
+	Watcher WatcherId       `json:"watcher"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// NextBatchResults holds the results of a WatcherMultiplexer NextBatch
+// call, for those watchers that have changed.
+type NextBatchResults struct {
+	Results []NextBatchResult `json:"results"`
+}
@@ -0,0 +1,376 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package params
+
+import (
+	"time"
+
+	"gopkg.in/macaroon.v1"
+)
+
+// RemoteEndpoint describes the endpoint of an application that is
+// involved in a cross-model relation.
+type RemoteEndpoint struct {
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	Interface string `json:"interface"`
+	Limit     int    `json:"limit"`
+	Scope     string `json:"scope"`
+}
+
+// RemoteApplication describes the current state of an application
+// consumed from another model.
+type RemoteApplication struct {
+	Name      string           `json:"name"`
+	OfferURL  string           `json:"offer-url"`
+	Life      Life             `json:"life"`
+	ModelUUID string           `json:"model-uuid"`
+	Endpoints []RemoteEndpoint `json:"endpoints,omitempty"`
+}
+
+// RemoteApplicationResult holds a remote application and an error.
+type RemoteApplicationResult struct {
+	Result *RemoteApplication `json:"result,omitempty"`
+	Error  *Error             `json:"error,omitempty"`
+}
+
+// RemoteApplicationResults holds a set of remote application results.
+type RemoteApplicationResults struct {
+	Results []RemoteApplicationResult `json:"results,omitempty"`
+}
+
+// RemoteRelation describes the current state of a cross-model relation
+// from the perspective of the local model.
+type RemoteRelation struct {
+	Life                  Life           `json:"life"`
+	Id                    int            `json:"id"`
+	Key                   string         `json:"key"`
+	ApplicationName       string         `json:"application-name"`
+	Endpoint              RemoteEndpoint `json:"endpoint"`
+	RemoteApplicationName string         `json:"remote-application-name"`
+	RemoteEndpointName    string         `json:"remote-endpoint-name"`
+	SourceModelUUID       string         `json:"source-model-uuid"`
+}
+
+// RemoteRelationResult holds a remote relation and an error.
+type RemoteRelationResult struct {
+	Result *RemoteRelation `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// RemoteRelationResults holds a set of remote relation results.
+type RemoteRelationResults struct {
+	Results []RemoteRelationResult `json:"results,omitempty"`
+}
+
+// RemoteRelationUnitChange describes a relation unit change
+// which has occurred in a remote model.
+type RemoteRelationUnitChange struct {
+	// UnitId uniquely identifies the remote unit.
+	UnitId int `json:"unit-id"`
+
+	// Settings is the current set of relation settings for the unit.
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// RemoteRelationChangeEvent is pushed to the remote model to communicate
+// changes to relation units from the local model.
+type RemoteRelationChangeEvent struct {
+	// RelationToken is the token of the relation.
+	RelationToken string `json:"relation-token"`
+
+	// ApplicationToken is the token of the application.
+	ApplicationToken string `json:"application-token"`
+
+	// Life is the current lifecycle state of the relation.
+	Life Life `json:"life"`
+
+	// ChangedUnits holds the units whose settings have changed.
+	ChangedUnits []RemoteRelationUnitChange `json:"changed-units,omitempty"`
+
+	// DepartedUnits contains the ids of units that have departed
+	// the relation since the last change.
+	DepartedUnits []int `json:"departed-units,omitempty"`
+}
+
+// RemoteRelationsChanges holds a set of RemoteRelationChangeEvent structures.
+type RemoteRelationsChanges struct {
+	Changes []RemoteRelationChangeEvent `json:"changes,omitempty"`
+}
+
+// IngressNetworksChangeEvent is pushed to the offering model to
+// communicate the networks from which the consuming model's traffic
+// for a relation originates, so that the offering model can open its
+// firewall to them.
+type IngressNetworksChangeEvent struct {
+	// RelationToken is the token of the relation.
+	RelationToken string `json:"relation-token"`
+
+	// ApplicationToken is the token of the application.
+	ApplicationToken string `json:"application-token"`
+
+	// Networks holds the CIDRs from which traffic originates.
+	Networks []string `json:"networks,omitempty"`
+
+	// IngressRequired is true if the relation needs traffic from the
+	// networks to be let in, and false if it no longer does.
+	IngressRequired bool `json:"ingress-required"`
+}
+
+// IngressNetworksChanges holds a set of IngressNetworksChangeEvent
+// structures.
+type IngressNetworksChanges struct {
+	Changes []IngressNetworksChangeEvent `json:"changes,omitempty"`
+}
+
+// TokenResult holds a token and an error.
+type TokenResult struct {
+	Token string `json:"token,omitempty"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// TokenResults has a set of token results.
+type TokenResults struct {
+	Results []TokenResult `json:"results,omitempty"`
+}
+
+// RemoteEntityArg holds a remote entity tag and the token that
+// identifies it in the remote model.
+type RemoteEntityArg struct {
+	Tag   string `json:"tag"`
+	Token string `json:"token"`
+}
+
+// RemoteEntityArgs holds arguments to functions dealing with remote
+// entity tokens.
+type RemoteEntityArgs struct {
+	Args []RemoteEntityArg `json:"args"`
+}
+
+// RemoteTokens holds tokens that identify entities in the remote
+// model.
+type RemoteTokens struct {
+	Tokens []string `json:"tokens"`
+}
+
+// RelationKeyResult holds the key of the local relation identified by
+// a token, and an error.
+type RelationKeyResult struct {
+	Key   string `json:"key,omitempty"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// RelationKeyResults holds a set of relation key results.
+type RelationKeyResults struct {
+	Results []RelationKeyResult `json:"results,omitempty"`
+}
+
+// EntityMacaroonArg holds a macaroon and entity which we want to save.
+type EntityMacaroonArg struct {
+	Macaroon *macaroon.Macaroon `json:"macaroon"`
+	Tag      string             `json:"tag"`
+}
+
+// EntityMacaroonArgs holds arguments for saving multiple macaroons.
+type EntityMacaroonArgs struct {
+	Args []EntityMacaroonArg `json:"args"`
+}
+
+// RelationLifeSuspendedStatusChange describes the life
+// and suspended status of a relation.
+type RelationLifeSuspendedStatusChange struct {
+	Key             string `json:"key"`
+	Life            Life   `json:"life"`
+	Suspended       bool   `json:"suspended"`
+	SuspendedReason string `json:"suspended-reason"`
+}
+
+// RelationLifeSuspendedStatusWatchResult holds a RelationStatusWatcher
+// id, baseline state (in the Changes field), and an error (if any).
+type RelationLifeSuspendedStatusWatchResult struct {
+	RelationStatusWatcherId string                              `json:"watcher-id"`
+	Changes                 []RelationLifeSuspendedStatusChange `json:"changes"`
+	Error                   *Error                              `json:"error,omitempty"`
+}
+
+// RelationStatusWatchResults holds the results for any API call which
+// ends up returning a list of RelationStatusWatchers.
+type RelationStatusWatchResults struct {
+	Results []RelationLifeSuspendedStatusWatchResult `json:"results"`
+}
+
+// OfferArg holds the UUID of an application offer.
+type OfferArg struct {
+	OfferUUID string `json:"offer-uuid"`
+}
+
+// OfferArgs holds arguments to functions operating on application
+// offers.
+type OfferArgs struct {
+	Args []OfferArg `json:"args"`
+}
+
+// OfferStatusChange describes the status of an application offer.
+type OfferStatusChange struct {
+	OfferName string     `json:"offer-name"`
+	Status    string     `json:"status"`
+	Info      string     `json:"info"`
+	Since     *time.Time `json:"since,omitempty"`
+}
+
+// OfferStatusWatchResult holds an OfferStatusWatcher id, baseline
+// state (in the Changes field), and an error (if any).
+type OfferStatusWatchResult struct {
+	OfferStatusWatcherId string              `json:"watcher-id"`
+	Changes              []OfferStatusChange `json:"changes"`
+	Error                *Error              `json:"error,omitempty"`
+}
+
+// OfferStatusWatchResults holds the results for any API call which
+// ends up returning a list of OfferStatusWatchers.
+type OfferStatusWatchResults struct {
+	Results []OfferStatusWatchResult `json:"results"`
+}
+
+// RelationSuspendedArg holds the new suspended status value for a
+// relation, and the reason for the change.
+type RelationSuspendedArg struct {
+	Tag       string `json:"tag"`
+	Message   string `json:"message"`
+	Suspended bool   `json:"suspended"`
+}
+
+// RelationSuspendedArgs holds the parameters for setting the
+// suspended status of relations.
+type RelationSuspendedArgs struct {
+	Args []RelationSuspendedArg `json:"args"`
+}
+
+// ControllerAPIInfoResult holds the API connection details for the
+// controller hosting a model, and an error.
+type ControllerAPIInfoResult struct {
+	ControllerTag string   `json:"controller-tag"`
+	Addresses     []string `json:"addresses"`
+	CACert        string   `json:"cacert"`
+	Error         *Error   `json:"error,omitempty"`
+}
+
+// ControllerAPIInfoResults holds a set of controller API info results.
+type ControllerAPIInfoResults struct {
+	Results []ControllerAPIInfoResult `json:"results"`
+}
+
+// ExternalControllerInfo holds the details of a controller hosting
+// models that are related to models in this controller.
+type ExternalControllerInfo struct {
+	ControllerTag string   `json:"controller-tag"`
+	Alias         string   `json:"controller-alias,omitempty"`
+	Addrs         []string `json:"addrs"`
+	CACert        string   `json:"ca-cert"`
+}
+
+// UpdateControllerForModel holds the external controller details to
+// record for a model.
+type UpdateControllerForModel struct {
+	ModelTag string                 `json:"model-tag"`
+	Info     ExternalControllerInfo `json:"info"`
+}
+
+// UpdateControllersForModelsParams holds the parameters for recording
+// the external controllers hosting a set of models.
+type UpdateControllersForModelsParams struct {
+	Changes []UpdateControllerForModel `json:"changes"`
+}
+
+// RelationUnitSettingsVersion holds the version of the settings of a
+// unit in a relation.
+type RelationUnitSettingsVersion struct {
+	UnitTag string `json:"unit-tag"`
+	Version int64  `json:"version"`
+}
+
+// ApplicationRelationChange describes a change to one of the relations
+// of an application, as reported by an ApplicationRelationsWatcher.
+type ApplicationRelationChange struct {
+	RelationTag   string                        `json:"relation-tag"`
+	Life          Life                          `json:"life"`
+	Suspended     bool                          `json:"suspended"`
+	ChangedUnits  []RelationUnitSettingsVersion `json:"changed-units,omitempty"`
+	DepartedUnits []string                      `json:"departed-units,omitempty"`
+}
+
+// ApplicationRelationsWatchResult holds an ApplicationRelationsWatcher
+// id, baseline state (in the Changes field), and an error (if any).
+type ApplicationRelationsWatchResult struct {
+	ApplicationRelationsWatcherId string                      `json:"watcher-id"`
+	Changes                       []ApplicationRelationChange `json:"changes"`
+	Error                         *Error                      `json:"error,omitempty"`
+}
+
+// ApplicationRelationsWatchResults holds the results for any API call
+// which ends up returning a list of ApplicationRelationsWatchers.
+type ApplicationRelationsWatchResults struct {
+	Results []ApplicationRelationsWatchResult `json:"results"`
+}
+
+// OfferConnection describes a relation made to an application offer
+// from a consuming model.
+type OfferConnection struct {
+	Username       string       `json:"username"`
+	SourceModelTag string       `json:"source-model-tag"`
+	RelationKey    string       `json:"relation-key"`
+	Status         EntityStatus `json:"status"`
+}
+
+// OfferConnectionsResult holds the connections to an application
+// offer, and an error (if any).
+type OfferConnectionsResult struct {
+	Connections []OfferConnection `json:"connections"`
+	Error       *Error            `json:"error,omitempty"`
+}
+
+// OfferConnectionsResults holds the results of a bulk call to get the
+// connections to application offers.
+type OfferConnectionsResults struct {
+	Results []OfferConnectionsResult `json:"results"`
+}
+
+// FullRelationUnitSettingsArg identifies a relation whose local unit
+// settings are requested, and the page of them to return.
+type FullRelationUnitSettingsArg struct {
+	RelationTag string `json:"relation-tag"`
+
+	// PageToken, if set, is the NextPageToken of the previous
+	// result, and requests the page following it.
+	PageToken string `json:"page-token,omitempty"`
+}
+
+// FullRelationUnitSettingsArgs holds the arguments of a bulk call to
+// get the settings of all local units in relations.
+type FullRelationUnitSettingsArgs struct {
+	Args []FullRelationUnitSettingsArg `json:"args"`
+}
+
+// RelationUnitFullSettings holds the settings of a unit in scope of a
+// relation, and their version.
+type RelationUnitFullSettings struct {
+	UnitTag  string   `json:"unit-tag"`
+	Settings Settings `json:"settings"`
+	Version  int64    `json:"version"`
+}
+
+// FullRelationUnitSettingsResult holds a page of the settings of the
+// local units in a relation, and an error (if any). NextPageToken is
+// set if there are more units to fetch.
+type FullRelationUnitSettingsResult struct {
+	Units         []RelationUnitFullSettings `json:"units"`
+	NextPageToken string                     `json:"next-page-token,omitempty"`
+	Error         *Error                     `json:"error,omitempty"`
+}
+
+// FullRelationUnitSettingsResults holds the results of a bulk call to
+// get the settings of all local units in relations.
+type FullRelationUnitSettingsResults struct {
+	Results []FullRelationUnitSettingsResult `json:"results"`
+}
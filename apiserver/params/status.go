@@ -69,6 +69,10 @@ type MachineStatus struct {
 	Jobs      []multiwatcher.MachineJob `json:"jobs"`
 	HasVote   bool                      `json:"has-vote"`
 	WantsVote bool                      `json:"wants-vote"`
+
+	// ProvisionerMetadata holds the details recorded by the
+	// provisioner about how the machine's instance was configured.
+	ProvisionerMetadata map[string]string `json:"provisioner-metadata,omitempty"`
 }
 
 // ApplicationStatus holds status info about an application.
@@ -412,7 +412,7 @@ func commonServiceInstances(st *state.State, m *state.Machine) ([]instance.Id, e
 		if !unit.IsPrincipal() {
 			continue
 		}
-		instanceIds, err := state.ServiceInstances(st, unit.ApplicationName())
+		instanceIds, err := st.ServiceInstances(unit.ApplicationName())
 		if err != nil {
 			return nil, err
 		}
@@ -504,6 +504,58 @@ func (p *ProvisionerAPI) SetInstanceInfo(args params.InstancesInfo) (params.Erro
 	return result, nil
 }
 
+// SetProvisionerMetadata records, for each given machine, details
+// about how its instance was configured, after discarding the entries
+// whose keys start with any of the supplied secret prefixes.
+func (p *ProvisionerAPI) SetProvisionerMetadata(args params.ProvisionerMetadataArgs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Args)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, arg := range args.Args {
+		tag, err := names.ParseMachineTag(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			metadata := state.FilterProvisionerMetadata(arg.Metadata, arg.SecretPrefixes...)
+			err = machine.SetProvisionerMetadata(metadata)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// SetAppliedInstanceTags records, for each given machine, the tags
+// that were set on its instance.
+func (p *ProvisionerAPI) SetAppliedInstanceTags(args params.InstanceTagsArgs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Args)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, arg := range args.Args {
+		tag, err := names.ParseMachineTag(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			err = machine.SetAppliedInstanceTags(arg.Tags)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // WatchMachineErrorRetry returns a NotifyWatcher that notifies when
 // the provisioner should retry provisioning machines with transient errors.
 func (p *ProvisionerAPI) WatchMachineErrorRetry() (params.NotifyWatchResult, error) {
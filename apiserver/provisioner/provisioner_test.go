@@ -1092,6 +1092,65 @@ func (s *withoutControllerSuite) TestSetSupportedContainers(c *gc.C) {
 	c.Assert(containers, gc.DeepEquals, []instance.ContainerType{instance.LXD, instance.KVM})
 }
 
+func (s *withoutControllerSuite) TestSetProvisionerMetadata(c *gc.C) {
+	args := params.ProvisionerMetadataArgs{Args: []params.ProvisionerMetadataArg{{
+		Tag: "machine-0",
+		Metadata: map[string]string{
+			"apt-mirror":      "http://mirror.example.com",
+			"secret-password": "sekrit",
+		},
+		SecretPrefixes: []string{"secret-"},
+	}, {
+		Tag:      "machine-42",
+		Metadata: map[string]string{"apt-mirror": "http://mirror.example.com"},
+	}, {
+		Tag:      "application-bar",
+		Metadata: map[string]string{"apt-mirror": "http://mirror.example.com"},
+	}}}
+	results, err := s.provisioner.SetProvisionerMetadata(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{nil},
+			{apiservertesting.NotFoundError("machine 42")},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	m0, err := s.State.Machine("0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.ProvisionerMetadata(), jc.DeepEquals, map[string]string{
+		"apt-mirror": "http://mirror.example.com",
+	})
+}
+
+func (s *withoutControllerSuite) TestSetAppliedInstanceTags(c *gc.C) {
+	applied := map[string]string{"juju-model-uuid": "deadbeef", "owner": "fred"}
+	args := params.InstanceTagsArgs{Args: []params.InstanceTagsArg{{
+		Tag:  "machine-0",
+		Tags: applied,
+	}, {
+		Tag:  "machine-42",
+		Tags: applied,
+	}, {
+		Tag:  "application-bar",
+		Tags: applied,
+	}}}
+	results, err := s.provisioner.SetAppliedInstanceTags(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{nil},
+			{apiservertesting.NotFoundError("machine 42")},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	m0, err := s.State.Machine("0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.AppliedInstanceTags(), jc.DeepEquals, applied)
+}
+
 func (s *withoutControllerSuite) TestSetSupportedContainersPermissions(c *gc.C) {
 	// Login as a machine agent for machine 0.
 	anAuthorizer := s.authorizer
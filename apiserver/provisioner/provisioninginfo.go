@@ -16,7 +16,6 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/common/storagecommon"
 	"github.com/juju/juju/apiserver/params"
-	"github.com/juju/juju/cloudconfig/instancecfg"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/imagemetadata"
 	"github.com/juju/juju/environs/simplestreams"
@@ -67,7 +66,7 @@ func (p *ProvisionerAPI) getProvisioningInfo(m *state.Machine) (*params.Provisio
 		jobs = append(jobs, job.ToParams())
 	}
 
-	tags, err := p.machineTags(m, jobs)
+	tags, err := p.machineTags(m)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -175,7 +174,7 @@ func (p *ProvisionerAPI) machineVolumeParams(m *state.Machine) ([]params.VolumeP
 }
 
 // machineTags returns machine-specific tags to set on the instance.
-func (p *ProvisionerAPI) machineTags(m *state.Machine, jobs []multiwatcher.MachineJob) (map[string]string, error) {
+func (p *ProvisionerAPI) machineTags(m *state.Machine) (map[string]string, error) {
 	// Names of all units deployed to the machine.
 	//
 	// TODO(axw) 2015-06-02 #1461358
@@ -194,15 +193,10 @@ func (p *ProvisionerAPI) machineTags(m *state.Machine, jobs []multiwatcher.Machi
 	}
 	sort.Strings(unitNames)
 
-	cfg, err := p.st.ModelConfig()
+	machineTags, err := m.InstanceTags()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	controllerCfg, err := p.st.ControllerConfig()
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	machineTags := instancecfg.InstanceTags(cfg.UUID(), controllerCfg.ControllerUUID(), cfg, jobs)
 	if len(unitNames) > 0 {
 		machineTags[tags.JujuUnitsDeployed] = strings.Join(unitNames, " ")
 	}
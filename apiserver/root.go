@@ -58,18 +58,22 @@ type apiHandler struct {
 	// serverHost is the host:port of the API server that the client
 	// connected to.
 	serverHost string
+
+	// remoteAddr is the network address of the client.
+	remoteAddr string
 }
 
 var _ = (*apiHandler)(nil)
 
 // newAPIHandler returns a new apiHandler.
-func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID string, serverHost string) (*apiHandler, error) {
+func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID, serverHost, remoteAddr string) (*apiHandler, error) {
 	r := &apiHandler{
 		state:      st,
 		resources:  common.NewResources(),
 		rpcConn:    rpcConn,
 		modelUUID:  modelUUID,
 		serverHost: serverHost,
+		remoteAddr: remoteAddr,
 	}
 	if err := r.resources.RegisterNamed("machineID", common.StringResource(srv.tag.Id())); err != nil {
 		return nil, errors.Trace(err)
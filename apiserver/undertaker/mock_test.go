@@ -29,9 +29,7 @@ var _ undertaker.State = (*mockState)(nil)
 
 func newMockState(envOwner names.UserTag, envName string, isSystem bool) *mockState {
 	machine := &mockMachine{
-		watcher: &mockWatcher{
-			changes: make(chan struct{}, 1),
-		},
+		id: "0",
 	}
 	service := &mockService{
 		watcher: &mockWatcher{
@@ -82,6 +80,12 @@ func (m *mockState) AllMachines() ([]undertaker.Machine, error) {
 	return m.machines, nil
 }
 
+func (m *mockState) WatchMachines(ids ...string) state.StringsWatcher {
+	return &mockStringsWatcher{
+		changes: make(chan []string, 1),
+	}
+}
+
 func (m *mockState) AllApplications() ([]undertaker.Service, error) {
 	return m.services, nil
 }
@@ -154,12 +158,12 @@ func (m *mockModel) SetStatus(sInfo status.StatusInfo) error {
 }
 
 type mockMachine struct {
-	watcher state.NotifyWatcher
-	err     error
+	id  string
+	err error
 }
 
-func (m *mockMachine) Watch() state.NotifyWatcher {
-	return m.watcher
+func (m *mockMachine) Id() string {
+	return m.id
 }
 
 type mockService struct {
@@ -179,3 +183,12 @@ type mockWatcher struct {
 func (w *mockWatcher) Changes() <-chan struct{} {
 	return w.changes
 }
+
+type mockStringsWatcher struct {
+	state.StringsWatcher
+	changes chan []string
+}
+
+func (w *mockStringsWatcher) Changes() <-chan []string {
+	return w.changes
+}
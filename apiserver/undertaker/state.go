@@ -34,6 +34,10 @@ type State interface {
 	// AllMachines returns all machines in the model ordered by id.
 	AllMachines() ([]Machine, error)
 
+	// WatchMachines returns a watcher reporting changes to the
+	// machines with the given ids.
+	WatchMachines(ids ...string) state.StringsWatcher
+
 	// AllApplications returns all deployed services in the model.
 	AllApplications() ([]Service, error)
 
@@ -59,11 +63,15 @@ func (s *stateShim) AllMachines() ([]Machine, error) {
 	return machines, nil
 }
 
+func (s *stateShim) WatchMachines(ids ...string) state.StringsWatcher {
+	return s.State.WatchMachines(ids...)
+}
+
 // Machine defines the needed methods of state.Machine for
 // the work of the undertaker API.
 type Machine interface {
-	// Watch returns a watcher for observing changes to a machine.
-	Watch() state.NotifyWatcher
+	// Id returns the machine id.
+	Id() string
 }
 
 func (s *stateShim) AllApplications() ([]Service, error) {
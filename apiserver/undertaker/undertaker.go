@@ -101,9 +101,14 @@ func (u *UndertakerAPI) environResourceWatcher() params.NotifyWatchResult {
 		nothing.Error = common.ServerError(err)
 		return nothing
 	}
-	var watchers []state.NotifyWatcher
-	for _, machine := range machines {
-		watchers = append(watchers, machine.Watch())
+	// A single watcher for all the machines avoids running a
+	// goroutine per machine in large models.
+	machineIds := make([]string, len(machines))
+	for i, machine := range machines {
+		machineIds[i] = machine.Id()
+	}
+	watchers := []state.NotifyWatcher{
+		common.NewStringsNotifyWatcher(u.st.WatchMachines(machineIds...)),
 	}
 	for _, service := range services {
 		watchers = append(watchers, service.Watch())
@@ -0,0 +1,41 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+)
+
+// CheckArgs checks that exactly one positional argument was supplied
+// for each of the given argument names. A missing argument is reported
+// as "missing <name>", and surplus arguments are reported in the same
+// way as cmd.CheckEmpty reports them.
+//
+// A typical Init method becomes:
+//
+//	func (c *someCommand) Init(args []string) error {
+//		if err := CheckArgs(args, "application name", "unit count"); err != nil {
+//			return err
+//		}
+//		c.application, c.count = args[0], args[1]
+//		return nil
+//	}
+func CheckArgs(args []string, names ...string) error {
+	if err := CheckMinArgs(args, names...); err != nil {
+		return err
+	}
+	return cmd.CheckEmpty(args[len(names):])
+}
+
+// CheckMinArgs checks that at least one positional argument was
+// supplied for each of the given argument names, reporting the first
+// one that is missing. Any further arguments are left for the caller to
+// interpret.
+func CheckMinArgs(args []string, names ...string) error {
+	if len(args) < len(names) {
+		return errors.Errorf("missing %s", names[len(args)])
+	}
+	return nil
+}
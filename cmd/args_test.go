@@ -0,0 +1,94 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type argsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&argsSuite{})
+
+var checkArgsTests = []struct {
+	args  []string
+	names []string
+	err   string
+}{
+	{nil, nil, ""},
+	{[]string{"a"}, nil, `unrecognized args: \["a"\]`},
+	{nil, []string{"application name"}, "missing application name"},
+	{[]string{"mysql"}, []string{"application name"}, ""},
+	{[]string{"mysql"}, []string{"application name", "unit count"}, "missing unit count"},
+	{[]string{"mysql", "2"}, []string{"application name", "unit count"}, ""},
+	{[]string{"mysql", "2", "x", "y"}, []string{"application name", "unit count"}, `unrecognized args: \["x" "y"\]`},
+}
+
+func (*argsSuite) TestCheckArgs(c *gc.C) {
+	for i, test := range checkArgsTests {
+		c.Logf("test %d: %q %q", i, test.args, test.names)
+		err := jujucmd.CheckArgs(test.args, test.names...)
+		if test.err == "" {
+			c.Check(err, jc.ErrorIsNil)
+		} else {
+			c.Check(err, gc.ErrorMatches, test.err)
+		}
+	}
+}
+
+func (*argsSuite) TestCheckMinArgs(c *gc.C) {
+	err := jujucmd.CheckMinArgs(nil, "unit")
+	c.Assert(err, gc.ErrorMatches, "missing unit")
+	err = jujucmd.CheckMinArgs([]string{"u/0"}, "unit", "action")
+	c.Assert(err, gc.ErrorMatches, "missing action")
+	err = jujucmd.CheckMinArgs([]string{"u/0", "backup", "key=value"}, "unit", "action")
+	c.Assert(err, jc.ErrorIsNil)
+	err = jujucmd.CheckMinArgs(nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// scaleCommand shows an Init implementation reduced to a single check.
+type scaleCommand struct {
+	cmd.CommandBase
+	application string
+	count       string
+}
+
+func (c *scaleCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "scale", Args: "<application> <count>"}
+}
+
+func (c *scaleCommand) Init(args []string) error {
+	if err := jujucmd.CheckArgs(args, "application name", "unit count"); err != nil {
+		return err
+	}
+	c.application, c.count = args[0], args[1]
+	return nil
+}
+
+func (c *scaleCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (*argsSuite) TestCommandInit(c *gc.C) {
+	command := &scaleCommand{}
+	err := coretesting.InitCommand(command, []string{"mysql", "3"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(command.application, gc.Equals, "mysql")
+	c.Assert(command.count, gc.Equals, "3")
+
+	err = coretesting.InitCommand(&scaleCommand{}, []string{"mysql"})
+	c.Assert(err, gc.ErrorMatches, "missing unit count")
+
+	err = coretesting.InitCommand(&scaleCommand{}, []string{"mysql", "3", "4"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["4"\]`)
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"sync"
+
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/cmd/output"
+)
+
+// brokenPipeCommand wraps a subcommand so that a broken pipe error
+// from Run, as reported by output.IsBrokenPipe, is kept on the wrapper
+// and cmd.ErrSilent is returned in its place. cmd.SuperCommand reports
+// the errors of its subcommands itself, and returns cmd.ErrSilent for
+// them, so without this Main could not tell that it should exit with
+// BrokenPipeExitCode; nor would any CommandRunner be silenced.
+type brokenPipeCommand struct {
+	cmd.Command
+	err error
+}
+
+// HiddenFlags implements HiddenFlagsCommand, so that wrapping a
+// command does not reveal its hidden flags.
+func (c *brokenPipeCommand) HiddenFlags() []string {
+	if hc, ok := c.Command.(HiddenFlagsCommand); ok {
+		return hc.HiddenFlags()
+	}
+	return nil
+}
+
+// Run implements cmd.Command.
+func (c *brokenPipeCommand) Run(ctx *cmd.Context) error {
+	err := c.Command.Run(ctx)
+	if output.IsBrokenPipe(err) {
+		c.err = err
+		return cmd.ErrSilent
+	}
+	return err
+}
+
+// brokenPipes holds the subcommands a supercommand has wrapped in
+// brokenPipeCommand, so that it can return the broken pipe error kept
+// by the one that ran.
+type brokenPipes struct {
+	mu       sync.Mutex
+	commands []*brokenPipeCommand
+}
+
+// wrap returns c wrapped in a brokenPipeCommand held by p.
+func (p *brokenPipes) wrap(c cmd.Command) cmd.Command {
+	wrapped := &brokenPipeCommand{Command: c}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands = append(p.commands, wrapped)
+	return wrapped
+}
+
+// runError returns the broken pipe error kept by a subcommand held by
+// p, if there is one, and forgets it. Otherwise it returns err, the
+// error returned by running the supercommand.
+func (p *brokenPipes) runError(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.commands {
+		if c.err != nil {
+			pipeErr := c.err
+			c.err = nil
+			return pipeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,368 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+
+	"github.com/juju/juju/juju/osenv"
+)
+
+// redactedValue replaces the values of secret flags in a capture.
+const redactedValue = "REDACTED"
+
+// sensitiveFlagWords are the words whose presence in a flag name marks
+// its value as secret.
+var sensitiveFlagWords = []string{"password", "token", "key", "secret"}
+
+// capturedEnvVars are the environment variables recorded in a capture,
+// when set, as they affect how juju commands behave.
+var capturedEnvVars = []string{
+	osenv.JujuModelEnvKey,
+	osenv.JujuXDGDataHomeEnvKey,
+	osenv.JujuLoggingConfigEnvKey,
+	osenv.JujuFeatureFlagEnvKey,
+	osenv.JujuStatusIsoTimeEnvKey,
+	osenv.JujuErrorFormatEnvKey,
+	osenv.JujuCLIDefaultsEnvKey,
+}
+
+// captureNow returns the current time. It is patched in tests.
+var captureNow = time.Now
+
+var (
+	redactedFlagsMu sync.Mutex
+	redactedFlags   = make(map[string]bool)
+)
+
+// RegisterRedactedFlags adds the named flags to those whose values are
+// never written to a capture file, for flags holding secrets whose
+// names do not contain "password", "token", "key" or "secret", which
+// are redacted anyway.
+func RegisterRedactedFlags(names ...string) {
+	redactedFlagsMu.Lock()
+	defer redactedFlagsMu.Unlock()
+	for _, name := range names {
+		redactedFlags[name] = true
+	}
+}
+
+// isRedactedFlag reports whether the value of the named flag must not
+// be captured.
+func isRedactedFlag(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveFlagWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	redactedFlagsMu.Lock()
+	defer redactedFlagsMu.Unlock()
+	return redactedFlags[name]
+}
+
+// CaptureRecord records a command invocation made by Main while the
+// JUJU_CAPTURE_FILE environment variable names a capture file. Each
+// record is appended to the file as a line of JSON.
+//
+// The values of secret flags are replaced with "REDACTED", both in
+// Flags and in Args, so a replayed invocation sees that in their place.
+type CaptureRecord struct {
+	// Command is the name of the command run, including the names
+	// of any supercommands, for example "juju status".
+	Command string `json:"command"`
+
+	// Args holds the arguments given to the command.
+	Args []string `json:"args"`
+
+	// Flags holds the values of the flags given, keyed by flag name.
+	// Flags of a subcommand are recorded only when it is registered
+	// with a RunnerSuperCommand and gets as far as running.
+	Flags map[string]string `json:"flags,omitempty"`
+
+	// Env holds the values of those juju environment variables that
+	// were set.
+	Env map[string]string `json:"env,omitempty"`
+
+	// ExitCode holds the exit code of the invocation.
+	ExitCode int `json:"exit-code"`
+
+	// Started holds the time at which the invocation started.
+	Started time.Time `json:"started"`
+
+	// Parse, Init and Run hold the time spent parsing the flags,
+	// initialising the command and running it.
+	Parse time.Duration `json:"parse"`
+	Init  time.Duration `json:"init"`
+	Run   time.Duration `json:"run"`
+}
+
+// ReadCaptures returns the records read from the contents of a
+// capture file.
+func ReadCaptures(r io.Reader) ([]CaptureRecord, error) {
+	var records []CaptureRecord
+	decoder := json.NewDecoder(r)
+	for {
+		var record CaptureRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, errors.Annotate(err, "cannot read capture")
+		}
+		records = append(records, record)
+	}
+}
+
+// Replay runs c with the arguments and environment of the captured
+// invocation, as Main would, and returns the context it was run in
+// and the exit code. The context has the directory and environment of
+// ctx, updated with the captured environment; its stdin is empty, and
+// its stdout and stderr are *bytes.Buffers holding the output. The
+// replayed invocation is not itself captured.
+func Replay(c cmd.Command, ctx *cmd.Context, record CaptureRecord) (*cmd.Context, int) {
+	env := make(map[string]string)
+	for name, value := range ctx.Env {
+		env[name] = value
+	}
+	for name, value := range record.Env {
+		env[name] = value
+	}
+	env[osenv.JujuCaptureFileEnvKey] = ""
+	replayCtx := &cmd.Context{
+		Dir:    ctx.Dir,
+		Env:    env,
+		Stdin:  &bytes.Buffer{},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	code := Main(c, replayCtx, record.Args)
+	return replayCtx, code
+}
+
+var (
+	activeCapturesMu sync.Mutex
+	activeCaptures   = make(map[*cmd.Context]*invocationCapture)
+)
+
+// invocationCapture accumulates the record of an invocation made by
+// Main. The methods of a nil *invocationCapture do nothing, so that Main
+// need not check whether capture is enabled.
+type invocationCapture struct {
+	path   string
+	record CaptureRecord
+	flags  []*gnuflag.FlagSet
+
+	// mark holds the time at which the current phase started.
+	mark      time.Time
+	parseDone bool
+	initDone  bool
+}
+
+// startCapture returns a capture for the invocation of a command with
+// args in ctx, or nil if capture is not enabled in ctx.
+func startCapture(ctx *cmd.Context, args []string) *invocationCapture {
+	path := ctx.Getenv(osenv.JujuCaptureFileEnvKey)
+	if path == "" {
+		return nil
+	}
+	now := captureNow()
+	capture := &invocationCapture{
+		path: AbsPath(ctx, path),
+		record: CaptureRecord{
+			Args:    append([]string{}, args...),
+			Started: now,
+		},
+		mark: now,
+	}
+	for _, name := range capturedEnvVars {
+		if value := ctx.Getenv(name); value != "" {
+			if capture.record.Env == nil {
+				capture.record.Env = make(map[string]string)
+			}
+			capture.record.Env[name] = value
+		}
+	}
+	activeCapturesMu.Lock()
+	activeCaptures[ctx] = capture
+	activeCapturesMu.Unlock()
+	return capture
+}
+
+// recordFlags adds the flags given in f to the capture of the
+// invocation running in ctx, if any. It is called by commands that Main cannot
+// see, such as the subcommands of a RunnerSuperCommand.
+func recordFlags(ctx *cmd.Context, f *gnuflag.FlagSet) {
+	activeCapturesMu.Lock()
+	capture := activeCaptures[ctx]
+	activeCapturesMu.Unlock()
+	capture.addFlags(f)
+}
+
+// addFlags records the flags given in f.
+func (c *invocationCapture) addFlags(f *gnuflag.FlagSet) {
+	if c == nil || f == nil {
+		return
+	}
+	c.flags = append(c.flags, f)
+}
+
+// elapsed returns the time since the last call, or since the capture
+// started.
+func (c *invocationCapture) elapsed() time.Duration {
+	now := captureNow()
+	d := now.Sub(c.mark)
+	c.mark = now
+	return d
+}
+
+// parsed records the end of flag parsing.
+func (c *invocationCapture) parsed() {
+	if c != nil {
+		c.record.Parse = c.elapsed()
+		c.parseDone = true
+	}
+}
+
+// initialised records the end of initialisation.
+func (c *invocationCapture) initialised() {
+	if c != nil {
+		c.record.Init = c.elapsed()
+		c.initDone = true
+	}
+}
+
+// finish records the end of the run of command com in ctx with the given
+// exit code, and appends the record to the capture file. Failure to
+// write the record is logged, but does not affect the command.
+func (c *invocationCapture) finish(com cmd.Command, ctx *cmd.Context, code int) {
+	if c == nil {
+		return
+	}
+	activeCapturesMu.Lock()
+	delete(activeCaptures, ctx)
+	activeCapturesMu.Unlock()
+
+	switch {
+	case !c.parseDone:
+		c.record.Parse = c.elapsed()
+	case !c.initDone:
+		c.record.Init = c.elapsed()
+	default:
+		c.record.Run = c.elapsed()
+	}
+	c.record.Command = com.Info().Name
+	c.record.ExitCode = code
+	c.record.Flags = c.flagValues()
+	c.record.Args = redactArgs(c.record.Args, c.lookupFlag)
+	if err := appendCapture(c.path, c.record); err != nil {
+		logger.Warningf("cannot capture command: %v", err)
+	}
+}
+
+// flagValues returns the values of the flags given, with the values
+// of secret flags redacted.
+func (c *invocationCapture) flagValues() map[string]string {
+	var values map[string]string
+	for _, f := range c.flags {
+		f.Visit(func(flag *gnuflag.Flag) {
+			if values == nil {
+				values = make(map[string]string)
+			}
+			value := flag.Value.String()
+			if isRedactedFlag(flag.Name) {
+				value = redactedValue
+			}
+			values[flag.Name] = value
+		})
+	}
+	return values
+}
+
+// lookupFlag returns the recorded flag with the given name, or nil.
+func (c *invocationCapture) lookupFlag(name string) *gnuflag.Flag {
+	for i := len(c.flags) - 1; i >= 0; i-- {
+		if flag := c.flags[i].Lookup(name); flag != nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// redactArgs returns args with the values of secret flags replaced,
+// whether given as "--name=value" or "--name value". A secret flag not
+// known to be boolean is taken to consume the following argument.
+func redactArgs(args []string, lookup func(string) *gnuflag.Flag) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		hasValue := false
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value, hasValue = name[:eq], name[eq+1:], true
+		}
+		if !isRedactedFlag(name) {
+			continue
+		}
+		if hasValue {
+			redacted[i] = arg[:len(arg)-len(value)] + redactedValue
+			continue
+		}
+		if isBoolFlag(lookup(name)) {
+			continue
+		}
+		if i+1 < len(redacted) {
+			i++
+			redacted[i] = redactedValue
+		}
+	}
+	return redacted
+}
+
+// isBoolFlag reports whether flag is a boolean flag, which takes no
+// separate value.
+func isBoolFlag(flag *gnuflag.Flag) bool {
+	if flag == nil {
+		return false
+	}
+	b, ok := flag.Value.(interface {
+		IsBoolFlag() bool
+	})
+	return ok && b.IsBoolFlag()
+}
+
+// appendCapture appends record to the capture file at path, as a line
+// of JSON.
+func appendCapture(path string, record CaptureRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	return errors.Trace(f.Close())
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type captureSuite struct {
+	testing.IsolationSuite
+	ctx  *cmd.Context
+	path string
+}
+
+var _ = gc.Suite(&captureSuite{})
+
+func (s *captureSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.ctx = coretesting.Context(c)
+	s.path = filepath.Join(s.ctx.Dir, "capture.json")
+	s.ctx.Env = map[string]string{
+		"JUJU_CAPTURE_FILE": "capture.json",
+		"JUJU_MODEL":        "admin/default",
+		"HOME":              s.ctx.Dir,
+	}
+	start := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	jujucmd.PatchCaptureNow(s, func() time.Time {
+		now := start.Add(time.Duration(calls) * time.Second)
+		calls++
+		return now
+	})
+	jujucmd.RegisterRedactedFlags("otp")
+}
+
+// connectCommand connects to a target with credentials.
+type connectCommand struct {
+	cmd.CommandBase
+	user     string
+	password string
+	otp      string
+	proxy    bool
+	target   string
+}
+
+func (c *connectCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "connect", Args: "<target>", Purpose: "Connect to a target."}
+}
+
+func (c *connectCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.user, "user", "admin", "The user to connect as")
+	f.StringVar(&c.password, "password", "", "The password of the user")
+	f.StringVar(&c.otp, "otp", "", "A one time password")
+	f.BoolVar(&c.proxy, "proxy", false, "Proxy through the controller")
+}
+
+func (c *connectCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no target specified")
+	}
+	c.target = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *connectCommand) Run(ctx *cmd.Context) error {
+	if c.target == "unreachable" {
+		return errors.Errorf("cannot connect to %s", c.target)
+	}
+	fmt.Fprintf(ctx.Stdout, "connected to %s as %s in %s\n", c.target, c.user, ctx.Getenv("JUJU_MODEL"))
+	return nil
+}
+
+func newCaptureSuper() cmd.Command {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	}))
+	super.Register(&connectCommand{})
+	return super
+}
+
+func (s *captureSuite) readCaptures(c *gc.C) []jujucmd.CaptureRecord {
+	f, err := os.Open(s.path)
+	c.Assert(err, jc.ErrorIsNil)
+	defer f.Close()
+	records, err := jujucmd.ReadCaptures(f)
+	c.Assert(err, jc.ErrorIsNil)
+	return records
+}
+
+func (s *captureSuite) TestCaptureAndReplay(c *gc.C) {
+	args := []string{"--user", "bob", "--password", "s3cret", "--otp=123456", "--proxy", "10.0.0.1"}
+	code := jujucmd.Main(&connectCommand{}, s.ctx, args)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(s.ctx), gc.Equals, "connected to 10.0.0.1 as bob in admin/default\n")
+
+	records := s.readCaptures(c)
+	c.Assert(records, gc.HasLen, 1)
+	c.Assert(records[0], jc.DeepEquals, jujucmd.CaptureRecord{
+		Command: "connect",
+		Args:    []string{"--user", "bob", "--password", "REDACTED", "--otp=REDACTED", "--proxy", "10.0.0.1"},
+		Flags: map[string]string{
+			"user":     "bob",
+			"password": "REDACTED",
+			"otp":      "REDACTED",
+			"proxy":    "true",
+		},
+		Env:      map[string]string{"JUJU_MODEL": "admin/default"},
+		ExitCode: 0,
+		Started:  time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC),
+		Parse:    time.Second,
+		Init:     time.Second,
+		Run:      time.Second,
+	})
+
+	replayCtx, replayCode := jujucmd.Replay(&connectCommand{}, coretesting.Context(c), records[0])
+	c.Assert(replayCode, gc.Equals, code)
+	c.Assert(coretesting.Stdout(replayCtx), gc.Equals, coretesting.Stdout(s.ctx))
+
+	// The replayed invocation is not captured.
+	c.Assert(s.readCaptures(c), gc.HasLen, 1)
+}
+
+func (s *captureSuite) TestCaptureSubcommand(c *gc.C) {
+	code := jujucmd.Main(newCaptureSuper(), s.ctx, []string{"connect", "--password", "s3cret", "unreachable"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(s.ctx), gc.Matches, "(?s).*ERROR cannot connect to unreachable\n")
+
+	records := s.readCaptures(c)
+	c.Assert(records, gc.HasLen, 1)
+	c.Check(records[0].Command, gc.Equals, "juju connect")
+	c.Check(records[0].Args, jc.DeepEquals, []string{"connect", "--password", "REDACTED", "unreachable"})
+	c.Check(records[0].Flags, jc.DeepEquals, map[string]string{"password": "REDACTED"})
+	c.Check(records[0].ExitCode, gc.Equals, 1)
+
+	replayCtx, replayCode := jujucmd.Replay(newCaptureSuper(), coretesting.Context(c), records[0])
+	c.Assert(replayCode, gc.Equals, code)
+	c.Assert(coretesting.Stderr(replayCtx), gc.Equals, coretesting.Stderr(s.ctx))
+}
+
+func (s *captureSuite) TestCaptureUsageError(c *gc.C) {
+	code := jujucmd.Main(&connectCommand{}, s.ctx, []string{"--password"})
+	c.Assert(code, gc.Equals, 2)
+
+	records := s.readCaptures(c)
+	c.Assert(records, gc.HasLen, 1)
+	c.Check(records[0].Args, jc.DeepEquals, []string{"--password"})
+	c.Check(records[0].Flags, gc.HasLen, 0)
+	c.Check(records[0].ExitCode, gc.Equals, 2)
+	c.Check(records[0].Parse, gc.Equals, time.Second)
+	c.Check(records[0].Init, gc.Equals, time.Duration(0))
+
+	replayCtx, replayCode := jujucmd.Replay(&connectCommand{}, coretesting.Context(c), records[0])
+	c.Assert(replayCode, gc.Equals, code)
+	c.Assert(coretesting.Stderr(replayCtx), gc.Equals, coretesting.Stderr(s.ctx))
+}
+
+func (s *captureSuite) TestCaptureAppends(c *gc.C) {
+	jujucmd.Main(&connectCommand{}, s.ctx, []string{"a"})
+	jujucmd.Main(&connectCommand{}, s.ctx, []string{"b"})
+
+	records := s.readCaptures(c)
+	c.Assert(records, gc.HasLen, 2)
+	c.Assert(records[0].Args, jc.DeepEquals, []string{"a"})
+	c.Assert(records[1].Args, jc.DeepEquals, []string{"b"})
+}
+
+func (s *captureSuite) TestCaptureDisabled(c *gc.C) {
+	delete(s.ctx.Env, "JUJU_CAPTURE_FILE")
+	s.PatchEnvironment("JUJU_CAPTURE_FILE", "")
+	code := jujucmd.Main(&connectCommand{}, s.ctx, []string{"10.0.0.1"})
+	c.Assert(code, gc.Equals, 0)
+	_, err := os.Stat(s.path)
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package cmdtesting provides helpers for running commands in tests
+// and inspecting their output.
+package cmdtesting
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	gc "gopkg.in/check.v1"
+)
+
+// Context returns a command context whose current directory is a new
+// directory within the test directory, with an empty stdin and with
+// stdout and stderr captured in buffers.
+func Context(c *gc.C) *cmd.Context {
+	return ContextWithStdin(c, "")
+}
+
+// ContextWithStdin is like Context, but the command reads the given
+// text from stdin.
+func ContextWithStdin(c *gc.C, stdin string) *cmd.Context {
+	return &cmd.Context{
+		Dir:    c.MkDir(),
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+}
+
+// NullContext returns a command context that reads nothing from stdin
+// and discards all output.
+func NullContext(c *gc.C) *cmd.Context {
+	return &cmd.Context{
+		Dir:    c.MkDir(),
+		Stdin:  io.LimitReader(nil, 0),
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+}
+
+// Stdout returns the output written to the stdout of a context
+// created by this package.
+func Stdout(ctx *cmd.Context) string {
+	return bufferString(ctx.Stdout)
+}
+
+// Stderr returns the output written to the stderr of a context
+// created by this package.
+func Stderr(ctx *cmd.Context) string {
+	return bufferString(ctx.Stderr)
+}
+
+func bufferString(w io.Writer) string {
+	if buf, ok := w.(*bytes.Buffer); ok {
+		return buf.String()
+	}
+	panic("context was not created by cmdtesting")
+}
+
+// NewFlagSet returns a flag set that reports errors to the caller
+// rather than writing them out.
+func NewFlagSet() *gnuflag.FlagSet {
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	return f
+}
+
+// InitCommand calls SetFlags on the command, parses args and calls
+// Init with the remaining positional arguments, without running the
+// command.
+func InitCommand(com cmd.Command, args []string) error {
+	f := NewFlagSet()
+	com.SetFlags(f)
+	if err := f.Parse(com.AllowInterspersedFlags(), args); err != nil {
+		return err
+	}
+	return com.Init(f.Args())
+}
+
+// RunCommand initialises and runs the command with the given args. The
+// returned error may come from parsing the arguments, from Init or from
+// Run; its output is available through the returned context.
+func RunCommand(c *gc.C, com cmd.Command, args ...string) (*cmd.Context, error) {
+	return RunCommandWithStdin(c, "", com, args...)
+}
+
+// RunCommandWithStdin is like RunCommand, but the command reads the
+// given text from stdin.
+func RunCommandWithStdin(c *gc.C, stdin string, com cmd.Command, args ...string) (*cmd.Context, error) {
+	ctx := ContextWithStdin(c, stdin)
+	if err := InitCommand(com, args); err != nil {
+		return ctx, err
+	}
+	return ctx, com.Run(ctx)
+}
+
+// RunMain runs the command through cmd.Main, as the command line tools
+// do, and returns the context along with the exit code.
+func RunMain(c *gc.C, com cmd.Command, args ...string) (*cmd.Context, int) {
+	ctx := Context(c)
+	return ctx, cmd.Main(com, ctx, args)
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmdtesting_test
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/cmdtesting"
+)
+
+type cmdtestingSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&cmdtestingSuite{})
+
+type catCommand struct {
+	cmd.CommandBase
+	prefix string
+	fail   bool
+}
+
+func (c *catCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "cat"}
+}
+
+func (c *catCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.prefix, "prefix", "", "")
+	f.BoolVar(&c.fail, "fail", false, "")
+}
+
+func (c *catCommand) Run(ctx *cmd.Context) error {
+	data, err := ioutil.ReadAll(ctx.Stdin)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Fprintf(ctx.Stdout, "%s%s", c.prefix, data)
+	if c.fail {
+		fmt.Fprintln(ctx.Stderr, "failing")
+		return cmd.NewRcPassthroughError(3)
+	}
+	return nil
+}
+
+func (*cmdtestingSuite) TestInitCommand(c *gc.C) {
+	com := &catCommand{}
+	err := cmdtesting.InitCommand(com, []string{"--prefix", "> "})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(com.prefix, gc.Equals, "> ")
+
+	err = cmdtesting.InitCommand(&catCommand{}, []string{"extra"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+
+	err = cmdtesting.InitCommand(&catCommand{}, []string{"--unknown"})
+	c.Assert(err, gc.ErrorMatches, `flag provided but not defined: --unknown`)
+}
+
+func (*cmdtestingSuite) TestRunCommandWithStdin(c *gc.C) {
+	ctx, err := cmdtesting.RunCommandWithStdin(c, "hello\n", &catCommand{}, "--prefix", "> ")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "> hello\n")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*cmdtestingSuite) TestRunCommandInitError(c *gc.C) {
+	ctx, err := cmdtesting.RunCommand(c, &catCommand{}, "extra")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (*cmdtestingSuite) TestRunMainExitCode(c *gc.C) {
+	ctx, code := cmdtesting.RunMain(c, &catCommand{}, "--fail")
+	c.Assert(code, gc.Equals, 3)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "failing\n")
+
+	ctx, code = cmdtesting.RunMain(c, &catCommand{}, "extra")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), jc.Contains, "unrecognized args")
+}
+
+func (*cmdtestingSuite) TestNullContext(c *gc.C) {
+	ctx := cmdtesting.NullContext(c)
+	err := (&catCommand{prefix: "x"}).Run(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(func() { cmdtesting.Stdout(ctx) }, gc.PanicMatches, "context was not created by cmdtesting")
+}
@@ -0,0 +1,326 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigSourceKind identifies the kind of argument that gave a value to
+// a ConfigFlag.
+type ConfigSourceKind string
+
+const (
+	// ConfigFromPair is the kind of a key=value argument.
+	ConfigFromPair ConfigSourceKind = "pair"
+
+	// ConfigFromJSON is the kind of an argument holding a JSON
+	// object.
+	ConfigFromJSON ConfigSourceKind = "json"
+
+	// ConfigFromFile is the kind of an argument naming a YAML or
+	// JSON file, or "-" for stdin.
+	ConfigFromFile ConfigSourceKind = "file"
+)
+
+// ConfigSource identifies the argument that gave a value to a
+// ConfigFlag.
+type ConfigSource struct {
+	// Kind holds the kind of the argument.
+	Kind ConfigSourceKind
+
+	// Arg holds the argument as given on the command line.
+	Arg string
+}
+
+// String returns a description of the source for use in messages.
+func (s ConfigSource) String() string {
+	switch s.Kind {
+	case ConfigFromPair:
+		return fmt.Sprintf("argument %q", s.Arg)
+	case ConfigFromJSON:
+		return fmt.Sprintf("inline JSON %q", s.Arg)
+	case ConfigFromFile:
+		if s.Arg == "-" {
+			return "stdin"
+		}
+		return fmt.Sprintf("file %q", s.Arg)
+	}
+	return fmt.Sprintf("%s %q", s.Kind, s.Arg)
+}
+
+// ConfigValues holds the values of a ConfigFlag.
+type ConfigValues struct {
+	// Attrs holds the merged values, keyed by name.
+	Attrs map[string]interface{}
+
+	// Sources holds, for each key in Attrs, the argument that gave
+	// it the value it has.
+	Sources map[string]ConfigSource
+}
+
+// ConfigFlag is a gnuflag.Value for a flag, such as --config, that
+// gives a command a map of values. It may be given any number of
+// times, each time with one of:
+//
+//	key=value   a single value, which is always a string
+//	{...}       a JSON object, whose values keep their JSON types
+//	path        a YAML or JSON file holding a map, whose values keep
+//	            their types; "-" reads the map from stdin
+//
+// A value given inline, as key=value or JSON, takes precedence over
+// the same key given in a file, wherever the arguments appear; among
+// inline values or among files, a later argument takes precedence over
+// an earlier one.
+//
+// Values are coerced as follows, so that commands see the same types
+// however the value was given. A key=value value is never coerced: it
+// stays a string even if it looks like a number or boolean, and quotes
+// are not removed. Typed values from JSON and YAML are kept, except
+// that integers are always int64, other numbers float64, nested maps
+// map[string]interface{} and lists []interface{}. A null value, or a
+// map key that is not a string, is an error naming the key and the
+// argument that gave it.
+type ConfigFlag struct {
+	args []configArg
+}
+
+var _ gnuflag.Value = (*ConfigFlag)(nil)
+
+// configArg holds one occurrence of a ConfigFlag. The attrs of a file
+// are read by ConfigFlag.Read.
+type configArg struct {
+	source ConfigSource
+	attrs  map[string]interface{}
+}
+
+// Set implements gnuflag.Value.
+func (f *ConfigFlag) Set(s string) error {
+	arg, err := parseConfigArg(s)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if s == "-" {
+		for _, other := range f.args {
+			if other.source.Kind == ConfigFromFile && other.source.Arg == "-" {
+				return errors.New("stdin may be given only once")
+			}
+		}
+	}
+	f.args = append(f.args, arg)
+	return nil
+}
+
+// parseConfigArg returns the occurrence of a ConfigFlag given by s.
+func parseConfigArg(s string) (configArg, error) {
+	trimmed := strings.TrimSpace(s)
+	switch {
+	case trimmed == "":
+		return configArg{}, errors.New("empty config value")
+	case strings.HasPrefix(trimmed, "{"):
+		source := ConfigSource{Kind: ConfigFromJSON, Arg: s}
+		attrs, err := parseConfigJSON([]byte(s), source)
+		if err != nil {
+			return configArg{}, errors.Trace(err)
+		}
+		return configArg{source: source, attrs: attrs}, nil
+	case strings.Contains(s, "="):
+		source := ConfigSource{Kind: ConfigFromPair, Arg: s}
+		parts := strings.SplitN(s, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			return configArg{}, errors.Errorf("%s: empty key", source)
+		}
+		return configArg{source: source, attrs: map[string]interface{}{key: parts[1]}}, nil
+	}
+	return configArg{source: ConfigSource{Kind: ConfigFromFile, Arg: s}}, nil
+}
+
+// String implements gnuflag.Value.
+func (f *ConfigFlag) String() string {
+	args := make([]string, len(f.args))
+	for i, arg := range f.args {
+		args[i] = arg.source.Arg
+	}
+	return strings.Join(args, " ")
+}
+
+// Read reads the files given to f, relative to the directory of ctx,
+// and returns the merged values.
+func (f *ConfigFlag) Read(ctx *cmd.Context) (ConfigValues, error) {
+	values := ConfigValues{
+		Attrs:   make(map[string]interface{}),
+		Sources: make(map[string]ConfigSource),
+	}
+	merge := func(arg configArg) {
+		for key, value := range arg.attrs {
+			values.Attrs[key] = value
+			values.Sources[key] = arg.source
+		}
+	}
+	for _, arg := range f.args {
+		if arg.source.Kind != ConfigFromFile {
+			continue
+		}
+		attrs, err := readConfigFile(ctx, arg.source)
+		if err != nil {
+			return ConfigValues{}, errors.Trace(err)
+		}
+		arg.attrs = attrs
+		merge(arg)
+	}
+	for _, arg := range f.args {
+		if arg.source.Kind != ConfigFromFile {
+			merge(arg)
+		}
+	}
+	return values, nil
+}
+
+// ReadAttrs is like Read, but returns only the merged values.
+func (f *ConfigFlag) ReadAttrs(ctx *cmd.Context) (map[string]interface{}, error) {
+	values, err := f.Read(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return values.Attrs, nil
+}
+
+// readConfigFile returns the map held in the YAML or JSON file, or
+// stdin, given by source.
+func readConfigFile(ctx *cmd.Context, source ConfigSource) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if source.Arg == "-" {
+		data, err = ioutil.ReadAll(ctx.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(AbsPath(ctx, source.Arg))
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read %s", source)
+	}
+	// YAML is a superset of JSON, so one parser serves for both.
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Annotatef(err, "cannot parse %s", source)
+	}
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+	value, err := coerceConfigValue(raw, "", source)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	attrs, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%s does not hold a map", source)
+	}
+	return attrs, nil
+}
+
+// parseConfigJSON returns the JSON object in data.
+func parseConfigJSON(data []byte, source ConfigSource) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, errors.Annotatef(err, "cannot parse %s", source)
+	}
+	if decoder.More() {
+		return nil, errors.Errorf("cannot parse %s: unexpected data after object", source)
+	}
+	value, err := coerceConfigValue(raw, "", source)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	attrs, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%s does not hold an object", source)
+	}
+	return attrs, nil
+}
+
+// coerceConfigValue returns value, read from JSON or YAML, with the
+// types described by ConfigFlag. key holds the dotted path of the key
+// holding value, for use in errors.
+func coerceConfigValue(value interface{}, key string, source ConfigSource) (interface{}, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, errors.Errorf("key %q in %s has a null value", key, source)
+	case string, bool, int64, float64:
+		return value, nil
+	case int:
+		return int64(value), nil
+	case uint64:
+		if value > math.MaxInt64 {
+			return float64(value), nil
+		}
+		return int64(value), nil
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, errors.Errorf("key %q in %s has invalid number %s", key, source, value)
+		}
+		return f, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			coerced, err := coerceConfigValue(v, joinConfigKey(key, k), source)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			result[k] = coerced
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			name, ok := k.(string)
+			if !ok && key == "" {
+				return nil, errors.Errorf("key %v in %s is not a string", k, source)
+			} else if !ok {
+				return nil, errors.Errorf("key %v under %q in %s is not a string", k, key, source)
+			}
+			coerced, err := coerceConfigValue(v, joinConfigKey(key, name), source)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			result[name] = coerced
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			coerced, err := coerceConfigValue(v, fmt.Sprintf("%s[%d]", key, i), source)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			result[i] = coerced
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("key %q in %s has unsupported value of type %T", key, source, value)
+}
+
+// joinConfigKey returns the dotted path of key within the map at
+// parent.
+func joinConfigKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
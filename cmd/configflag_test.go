@@ -0,0 +1,210 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type configFlagSuite struct {
+	testing.IsolationSuite
+	ctx *cmd.Context
+}
+
+var _ = gc.Suite(&configFlagSuite{})
+
+func (s *configFlagSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.ctx = coretesting.Context(c)
+}
+
+func (s *configFlagSuite) writeFile(c *gc.C, name, content string) {
+	err := ioutil.WriteFile(filepath.Join(s.ctx.Dir, name), []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *configFlagSuite) parse(c *gc.C, args ...string) (*jujucmd.ConfigFlag, error) {
+	var config jujucmd.ConfigFlag
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	f.Var(&config, "config", "")
+	return &config, f.Parse(true, args)
+}
+
+func (s *configFlagSuite) read(c *gc.C, args ...string) jujucmd.ConfigValues {
+	config, err := s.parse(c, args...)
+	c.Assert(err, jc.ErrorIsNil)
+	values, err := config.Read(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	return values
+}
+
+func (s *configFlagSuite) TestPairsStayStrings(c *gc.C) {
+	values := s.read(c, "--config", "a=1", "--config", "b=true", "--config", `c="quoted"`, "--config", "d=", "--config", "e=x=y")
+	c.Assert(values.Attrs, jc.DeepEquals, map[string]interface{}{
+		"a": "1",
+		"b": "true",
+		"c": `"quoted"`,
+		"d": "",
+		"e": "x=y",
+	})
+	c.Assert(values.Sources["a"], jc.DeepEquals, jujucmd.ConfigSource{Kind: jujucmd.ConfigFromPair, Arg: "a=1"})
+}
+
+func (s *configFlagSuite) TestInlineJSONTyped(c *gc.C) {
+	values := s.read(c, "--config", `{"a": 1, "b": true, "c": "1", "d": 1.5, "e": {"f": [1, "x"]}}`)
+	c.Assert(values.Attrs, jc.DeepEquals, map[string]interface{}{
+		"a": int64(1),
+		"b": true,
+		"c": "1",
+		"d": 1.5,
+		"e": map[string]interface{}{"f": []interface{}{int64(1), "x"}},
+	})
+	c.Assert(values.Sources["a"].Kind, gc.Equals, jujucmd.ConfigFromJSON)
+}
+
+func (s *configFlagSuite) TestFileTyped(c *gc.C) {
+	s.writeFile(c, "config.yaml", "a: 1\nb: true\nc: \"1\"\nd: 1.5\ne:\n  f: [1, x]\n")
+	s.writeFile(c, "config.json", `{"g": 2}`)
+	values := s.read(c, "--config", "config.yaml", "--config", "config.json")
+	c.Assert(values.Attrs, jc.DeepEquals, map[string]interface{}{
+		"a": int64(1),
+		"b": true,
+		"c": "1",
+		"d": 1.5,
+		"e": map[string]interface{}{"f": []interface{}{int64(1), "x"}},
+		"g": int64(2),
+	})
+	c.Assert(values.Sources["a"], jc.DeepEquals, jujucmd.ConfigSource{Kind: jujucmd.ConfigFromFile, Arg: "config.yaml"})
+	c.Assert(values.Sources["g"], jc.DeepEquals, jujucmd.ConfigSource{Kind: jujucmd.ConfigFromFile, Arg: "config.json"})
+}
+
+func (s *configFlagSuite) TestStdin(c *gc.C) {
+	s.ctx.Stdin = bytes.NewBufferString("a: 1\n")
+	values := s.read(c, "--config", "-")
+	c.Assert(values.Attrs, jc.DeepEquals, map[string]interface{}{"a": int64(1)})
+	c.Assert(values.Sources["a"].String(), gc.Equals, "stdin")
+}
+
+func (s *configFlagSuite) TestStdinOnlyOnce(c *gc.C) {
+	_, err := s.parse(c, "--config", "-", "--config", "-")
+	c.Assert(err, gc.ErrorMatches, `invalid value "-" for flag --config: stdin may be given only once`)
+}
+
+func (s *configFlagSuite) TestPrecedence(c *gc.C) {
+	s.writeFile(c, "one.yaml", "a: file-one\nb: file-one\nc: file-one\nd: file-one\n")
+	s.writeFile(c, "two.yaml", "b: file-two\nc: file-two\nd: file-two\n")
+	// Inline values win over files wherever they appear, and later
+	// arguments win over earlier ones of the same kind.
+	values := s.read(c,
+		"--config", "c=pair",
+		"--config", `{"c": "json", "d": "json"}`,
+		"--config", "one.yaml",
+		"--config", "two.yaml",
+	)
+	c.Assert(values.Attrs, jc.DeepEquals, map[string]interface{}{
+		"a": "file-one",
+		"b": "file-two",
+		"c": "json",
+		"d": "json",
+	})
+	c.Assert(values.Sources, jc.DeepEquals, map[string]jujucmd.ConfigSource{
+		"a": {Kind: jujucmd.ConfigFromFile, Arg: "one.yaml"},
+		"b": {Kind: jujucmd.ConfigFromFile, Arg: "two.yaml"},
+		"c": {Kind: jujucmd.ConfigFromJSON, Arg: `{"c": "json", "d": "json"}`},
+		"d": {Kind: jujucmd.ConfigFromJSON, Arg: `{"c": "json", "d": "json"}`},
+	})
+}
+
+func (s *configFlagSuite) TestEmpty(c *gc.C) {
+	values := s.read(c)
+	c.Assert(values.Attrs, gc.HasLen, 0)
+	c.Assert(values.Sources, gc.HasLen, 0)
+
+	s.writeFile(c, "empty.yaml", "")
+	values = s.read(c, "--config", "empty.yaml")
+	c.Assert(values.Attrs, gc.HasLen, 0)
+}
+
+func (s *configFlagSuite) TestString(c *gc.C) {
+	config, err := s.parse(c, "--config", "a=1", "--config", "config.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config.String(), gc.Equals, "a=1 config.yaml")
+}
+
+func (s *configFlagSuite) TestParseErrors(c *gc.C) {
+	for i, test := range []struct {
+		arg string
+		err string
+	}{{
+		arg: "",
+		err: "empty config value",
+	}, {
+		arg: "=value",
+		err: `argument "=value": empty key`,
+	}, {
+		arg: `{"a": 1`,
+		err: `cannot parse inline JSON "{\\"a\\": 1": unexpected EOF`,
+	}, {
+		arg: `{"a": 1} x`,
+		err: `cannot parse inline JSON .*: unexpected data after object`,
+	}, {
+		arg: `{"a": {"b": null}}`,
+		err: `key "a.b" in inline JSON .* has a null value`,
+	}} {
+		c.Logf("test %d: %q", i, test.arg)
+		_, err := s.parse(c, "--config", test.arg)
+		c.Check(err, gc.ErrorMatches, `invalid value .* for flag --config: `+test.err)
+	}
+}
+
+func (s *configFlagSuite) TestFileErrors(c *gc.C) {
+	for i, test := range []struct {
+		content string
+		err     string
+	}{{
+		content: "a: [",
+		err:     `cannot parse file "config.yaml": .*`,
+	}, {
+		content: "- a\n- b\n",
+		err:     `file "config.yaml" does not hold a map`,
+	}, {
+		content: "a:\n",
+		err:     `key "a" in file "config.yaml" has a null value`,
+	}, {
+		content: "a:\n  b: [1, ~]\n",
+		err:     `key "a.b\[1\]" in file "config.yaml" has a null value`,
+	}, {
+		content: "1: a\n",
+		err:     `key 1 in file "config.yaml" is not a string`,
+	}, {
+		content: "a:\n  2: b\n",
+		err:     `key 2 under "a" in file "config.yaml" is not a string`,
+	}} {
+		c.Logf("test %d: %q", i, test.content)
+		s.writeFile(c, "config.yaml", test.content)
+		config, err := s.parse(c, "--config", "config.yaml")
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = config.Read(s.ctx)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *configFlagSuite) TestMissingFile(c *gc.C) {
+	config, err := s.parse(c, "--config", "missing.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = config.ReadAttrs(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `cannot read file "missing.yaml": .*`)
+}
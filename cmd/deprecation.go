@@ -0,0 +1,101 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// deprecationCheck implements cmd.DeprecationCheck for commands that
+// have been renamed or removed in favour of another command.
+type deprecationCheck struct {
+	replacement string
+}
+
+// Deprecated implements cmd.DeprecationCheck.
+func (d deprecationCheck) Deprecated() (bool, string) {
+	return true, d.replacement
+}
+
+// Obsolete implements cmd.DeprecationCheck. A command that has been
+// removed is registered with RegisterObsolete instead.
+func (d deprecationCheck) Obsolete() bool {
+	return false
+}
+
+// DeprecatedBy returns a cmd.DeprecationCheck for a command name that
+// still works but has been superseded by replacement. When passed to
+// SuperCommand.RegisterAlias or RegisterDeprecated, invoking the old
+// name prints a notice naming the replacement to stderr before the
+// command runs, and the old name is left out of the help listing.
+func DeprecatedBy(replacement string) cmd.DeprecationCheck {
+	return deprecationCheck{replacement: replacement}
+}
+
+// RegisterObsolete registers name with the supercommand as a command
+// that has been removed in favour of replacement. Invoking it fails
+// with an error naming the replacement, and "help <name>" explains
+// the same. Registering a name that is already in use panics, as with
+// any other registration.
+//
+// The supercommand parses the flags of its subcommand before the
+// subcommand's Init is called, so flags given straight after name are
+// rejected as undefined. VersionSuperCommand.RegisterObsolete avoids
+// this by ignoring every argument after name.
+func RegisterObsolete(super *cmd.SuperCommand, name, replacement string) {
+	super.RegisterDeprecated(newObsoleteCommand(name, replacement), DeprecatedBy(replacement))
+}
+
+// newObsoleteCommand returns a command standing in for the removed
+// command name. It is registered as deprecated rather than obsolete,
+// because obsolete commands are dropped entirely by the supercommand,
+// and we want the user to be told where to go.
+func newObsoleteCommand(name, replacement string) cmd.Command {
+	return &obsoleteCommand{
+		name:        name,
+		replacement: replacement,
+	}
+}
+
+// obsoleteCommand stands in for a command that has been removed.
+type obsoleteCommand struct {
+	cmd.CommandBase
+	name        string
+	replacement string
+}
+
+// Info implements cmd.Command.
+func (c *obsoleteCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    c.name,
+		Args:    "...",
+		Purpose: "Obsolete; use " + c.replacement + " instead.",
+		Doc:     "The " + c.name + " command has been removed; use " + c.replacement + " instead.\n",
+	}
+}
+
+// SetFlags implements cmd.Command.
+func (c *obsoleteCommand) SetFlags(f *gnuflag.FlagSet) {}
+
+// AllowInterspersedFlags implements cmd.Command. Flags meant for the
+// removed command must not cause a usage error that would hide the
+// real problem, so once an argument has been given, everything after
+// it is passed to Init untouched; flags given first are dropped by
+// VersionSuperCommand, as described in RegisterObsolete.
+func (c *obsoleteCommand) AllowInterspersedFlags() bool {
+	return false
+}
+
+// Init implements cmd.Command. All arguments are accepted, and
+// ignored.
+func (c *obsoleteCommand) Init(args []string) error {
+	return nil
+}
+
+// Run implements cmd.Command.
+func (c *obsoleteCommand) Run(ctx *cmd.Context) error {
+	return errors.Errorf("%q has been removed, please use %q", c.name, c.replacement)
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type deprecationSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&deprecationSuite{})
+
+type echoCommand struct {
+	cmd.CommandBase
+	name string
+}
+
+func (c *echoCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: c.name, Purpose: "echo " + c.name}
+}
+
+func (c *echoCommand) Run(ctx *cmd.Context) error {
+	ctx.Stdout.Write([]byte(c.name + "\n"))
+	return nil
+}
+
+func newDeprecationSuper() *cmd.SuperCommand {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	super.Register(&echoCommand{name: "list-models"})
+	super.RegisterAlias("models", "list-models", nil)
+	super.RegisterAlias("environments", "list-models", jujucmd.DeprecatedBy("list-models"))
+	super.RegisterDeprecated(&echoCommand{name: "destroy-environment"}, jujucmd.DeprecatedBy("destroy-model"))
+	jujucmd.RegisterObsolete(super, "upgrade-gui", "upgrade-juju")
+	return super
+}
+
+func (*deprecationSuite) TestDeprecatedCheck(c *gc.C) {
+	check := jujucmd.DeprecatedBy("models")
+	deprecated, replacement := check.Deprecated()
+	c.Assert(deprecated, jc.IsTrue)
+	c.Assert(replacement, gc.Equals, "models")
+	c.Assert(check.Obsolete(), jc.IsFalse)
+}
+
+func (*deprecationSuite) TestAliasRuns(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"models"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "list-models\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*deprecationSuite) TestDeprecatedAliasWarns(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"environments"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "list-models\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `(?s).*"environments" is deprecated, please use "list-models".*`)
+}
+
+func (*deprecationSuite) TestDeprecatedCommandWarns(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"destroy-environment"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "destroy-environment\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `(?s).*"destroy-environment" is deprecated, please use "destroy-model".*`)
+}
+
+func (*deprecationSuite) TestObsoleteCommandFails(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"upgrade-gui", "1.2.3"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `(?s).*"upgrade-gui" has been removed, please use "upgrade-juju".*`)
+}
+
+func (*deprecationSuite) TestObsoleteCommandIgnoresFlags(c *gc.C) {
+	for i, args := range [][]string{
+		{"upgrade-gui", "--force"},
+		{"upgrade-gui", "--version", "1.2.3", "-v"},
+		{"upgrade-gui", "1.2.3", "--force"},
+	} {
+		c.Logf("test %d: %v", i, args)
+		super := jujucmd.NewVersionSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+		super.RegisterObsolete("upgrade-gui", "upgrade-juju")
+		ctx := coretesting.Context(c)
+		code := cmd.Main(super, ctx, args)
+		c.Check(code, gc.Equals, 1)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, "")
+		c.Check(coretesting.Stderr(ctx), gc.Matches, `(?s).*"upgrade-gui" has been removed, please use "upgrade-juju".*`)
+		c.Check(coretesting.Stderr(ctx), gc.Not(jc.Contains), "flag provided but not defined")
+	}
+}
+
+func (*deprecationSuite) TestHelpListingOmitsDeprecated(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"help", "commands"})
+	c.Assert(code, gc.Equals, 0)
+	out := coretesting.Stdout(ctx)
+	c.Assert(out, jc.Contains, "list-models")
+	c.Assert(out, gc.Not(jc.Contains), "destroy-environment")
+	c.Assert(out, gc.Not(jc.Contains), "upgrade-gui")
+}
+
+func (*deprecationSuite) TestHelpResolvesDeprecated(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDeprecationSuper(), ctx, []string{"help", "upgrade-gui"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), jc.Contains, "use upgrade-juju instead")
+}
+
+func (*deprecationSuite) TestConflictingRegistrationPanics(c *gc.C) {
+	super := newDeprecationSuper()
+	c.Assert(func() {
+		jujucmd.RegisterObsolete(super, "list-models", "models")
+	}, gc.PanicMatches, `command already registered: "list-models"`)
+}
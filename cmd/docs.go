@@ -0,0 +1,293 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/utils/set"
+)
+
+// DocCommand describes a command for the purposes of generating
+// documentation.
+type DocCommand struct {
+	// Name is the name the command is registered as.
+	Name string
+
+	// Info is the command's own description of itself.
+	Info *cmd.Info
+
+	// Aliases holds all the other names the command may be invoked
+	// as, sorted.
+	Aliases []string
+
+	// Flags holds the command's flags, sorted by name.
+	Flags []DocFlag
+
+	// Deprecated holds whether the command is deprecated, and
+	// Replacement the command to use instead.
+	Deprecated  bool
+	Replacement string
+}
+
+// DocFlag describes a single flag of a command. Flags that share a
+// value, such as -m and --model, are described together.
+type DocFlag struct {
+	Names   []string
+	Default string
+	Usage   string
+}
+
+// DocRegistry implements the registration methods of cmd.SuperCommand
+// so that the commands of a supercommand can be collected for
+// generating documentation.
+type DocRegistry struct {
+	commands map[string]*DocCommand
+	aliases  map[string][]string
+}
+
+// Register records the given command.
+func (r *DocRegistry) Register(c cmd.Command) {
+	r.add(c, nil)
+}
+
+// RegisterDeprecated records the given command as deprecated.
+// Obsolete commands are ignored, as they are not available.
+func (r *DocRegistry) RegisterDeprecated(c cmd.Command, check cmd.DeprecationCheck) {
+	if check != nil && check.Obsolete() {
+		return
+	}
+	r.add(c, check)
+}
+
+// RegisterAlias records name as an alias of the command forName.
+func (r *DocRegistry) RegisterAlias(name, forName string, check cmd.DeprecationCheck) {
+	if check != nil && check.Obsolete() {
+		return
+	}
+	if r.aliases == nil {
+		r.aliases = make(map[string][]string)
+	}
+	r.aliases[forName] = append(r.aliases[forName], name)
+}
+
+// RegisterSuperAlias records name as an alias of the command forName
+// of the given supercommand.
+func (r *DocRegistry) RegisterSuperAlias(name, super, forName string, check cmd.DeprecationCheck) {
+	r.RegisterAlias(name, super+" "+forName, check)
+}
+
+func (r *DocRegistry) add(c cmd.Command, check cmd.DeprecationCheck) {
+	if r.commands == nil {
+		r.commands = make(map[string]*DocCommand)
+	}
+	info := c.Info()
+	doc := &DocCommand{
+		Name:  info.Name,
+		Info:  info,
+		Flags: docFlags(c),
+	}
+	if check != nil {
+		doc.Deprecated, doc.Replacement = check.Deprecated()
+	}
+	r.commands[info.Name] = doc
+}
+
+// Commands returns the recorded commands sorted by name. Deprecated
+// commands are only included if includeDeprecated is true.
+func (r *DocRegistry) Commands(includeDeprecated bool) []*DocCommand {
+	var names []string
+	for name, doc := range r.commands {
+		if includeDeprecated || !doc.Deprecated {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := make([]*DocCommand, len(names))
+	for i, name := range names {
+		doc := *r.commands[name]
+		aliases := set.NewStrings(doc.Info.Aliases...)
+		aliases = aliases.Union(set.NewStrings(r.aliases[name]...))
+		doc.Aliases = aliases.SortedValues()
+		result[i] = &doc
+	}
+	return result
+}
+
+// docFlags returns the visible flags of the given command.
+func docFlags(c cmd.Command) []DocFlag {
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	c.SetFlags(f)
+	if hc, ok := c.(HiddenFlagsCommand); ok {
+		f = VisibleFlags(f, hc.HiddenFlags())
+	}
+	byValue := make(map[gnuflag.Value]*DocFlag)
+	var flags []*DocFlag
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		if doc, ok := byValue[flag.Value]; ok {
+			doc.Names = append(doc.Names, flag.Name)
+			return
+		}
+		doc := &DocFlag{
+			Names:   []string{flag.Name},
+			Default: flag.DefValue,
+			Usage:   flag.Usage,
+		}
+		byValue[flag.Value] = doc
+		flags = append(flags, doc)
+	})
+	result := make([]DocFlag, len(flags))
+	for i, flag := range flags {
+		sort.Sort(byFlagLength(flag.Names))
+		result[i] = *flag
+	}
+	sort.Sort(byFlagName(result))
+	return result
+}
+
+// flagText returns the flag names as they are written on the command
+// line, for example "-m, --model".
+func (f DocFlag) flagText() string {
+	names := make([]string, len(f.Names))
+	for i, name := range f.Names {
+		if len(name) == 1 {
+			names[i] = "-" + name
+		} else {
+			names[i] = "--" + name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+type byFlagLength []string
+
+func (s byFlagLength) Len() int      { return len(s) }
+func (s byFlagLength) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byFlagLength) Less(i, j int) bool {
+	if len(s[i]) != len(s[j]) {
+		return len(s[i]) < len(s[j])
+	}
+	return s[i] < s[j]
+}
+
+type byFlagName []DocFlag
+
+func (s byFlagName) Len() int           { return len(s) }
+func (s byFlagName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFlagName) Less(i, j int) bool { return s[i].Names[0] < s[j].Names[0] }
+
+// WriteMarkdown writes documentation for the given commands of the
+// program to w as a single markdown document.
+func WriteMarkdown(w io.Writer, program string, commands []*DocCommand) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", program)
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "* [%s](#%s) - %s\n", c.Name, c.Name, c.Info.Purpose)
+	}
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "\n## %s\n\n", c.Name)
+		if c.Deprecated {
+			fmt.Fprintf(&buf, "**Deprecated**: use `%s` instead.\n\n", c.Replacement)
+		}
+		fmt.Fprintf(&buf, "%s\n\n", c.Info.Purpose)
+		fmt.Fprintf(&buf, "### Usage\n\n    %s\n", strings.TrimSpace(usageLine(program, c)))
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(&buf, "\n### Aliases\n\n%s\n", strings.Join(c.Aliases, ", "))
+		}
+		if len(c.Flags) > 0 {
+			fmt.Fprintf(&buf, "\n### Options\n\n")
+			for _, flag := range c.Flags {
+				fmt.Fprintf(&buf, "* `%s`", flag.flagText())
+				if flag.Default != "" {
+					fmt.Fprintf(&buf, " (default `%s`)", flag.Default)
+				}
+				fmt.Fprintf(&buf, ": %s\n", flag.Usage)
+			}
+		}
+		if doc := strings.TrimSpace(c.Info.Doc); doc != "" {
+			fmt.Fprintf(&buf, "\n### Details\n\n%s\n", doc)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return errors.Trace(err)
+}
+
+// WriteManPages writes a troff man page for each of the given commands
+// of the program into dir, named for example "juju-deploy.1", along
+// with an index page named for the program.
+func WriteManPages(dir, program string, commands []*DocCommand) error {
+	var index bytes.Buffer
+	writeManHeader(&index, program)
+	fmt.Fprintf(&index, ".SH NAME\n%s\n.SH COMMANDS\n", manEscape(program))
+	for _, c := range commands {
+		fmt.Fprintf(&index, ".TP\n.B %s\n%s\n", manEscape(c.Name), manEscape(c.Info.Purpose))
+	}
+	if err := writeManPage(dir, program, index.Bytes()); err != nil {
+		return errors.Trace(err)
+	}
+	for _, c := range commands {
+		var page bytes.Buffer
+		name := program + "-" + c.Name
+		writeManHeader(&page, name)
+		fmt.Fprintf(&page, ".SH NAME\n%s \\- %s\n", manEscape(name), manEscape(c.Info.Purpose))
+		fmt.Fprintf(&page, ".SH SYNOPSIS\n%s\n", manEscape(strings.TrimSpace(usageLine(program, c))))
+		if c.Deprecated {
+			fmt.Fprintf(&page, ".SH DEPRECATED\nUse %s instead.\n", manEscape(c.Replacement))
+		}
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(&page, ".SH ALIASES\n%s\n", manEscape(strings.Join(c.Aliases, ", ")))
+		}
+		if len(c.Flags) > 0 {
+			fmt.Fprintf(&page, ".SH OPTIONS\n")
+			for _, flag := range c.Flags {
+				fmt.Fprintf(&page, ".TP\n.B %s\n", manEscape(flag.flagText()))
+				usage := flag.Usage
+				if flag.Default != "" {
+					usage += " (default " + flag.Default + ")"
+				}
+				fmt.Fprintf(&page, "%s\n", manEscape(usage))
+			}
+		}
+		if doc := strings.TrimSpace(c.Info.Doc); doc != "" {
+			fmt.Fprintf(&page, ".SH DESCRIPTION\n%s\n", manEscape(doc))
+		}
+		if err := writeManPage(dir, name, page.Bytes()); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func usageLine(program string, c *DocCommand) string {
+	return program + " " + c.Name + " [options] " + c.Info.Args
+}
+
+func writeManHeader(w io.Writer, name string) {
+	fmt.Fprintf(w, ".TH %s 1\n", strings.ToUpper(manEscape(name)))
+}
+
+func writeManPage(dir, name string, data []byte) error {
+	return errors.Trace(ioutil.WriteFile(filepath.Join(dir, name+".1"), data, 0644))
+}
+
+// manEscape escapes text so that troff does not interpret it.
+func manEscape(text string) string {
+	text = strings.Replace(text, `\`, `\e`, -1)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
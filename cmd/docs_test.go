@@ -0,0 +1,200 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+)
+
+type docsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&docsSuite{})
+
+type deployCommand struct {
+	cmd.CommandBase
+	series string
+	force  bool
+	debug  bool
+}
+
+func (c *deployCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "deploy",
+		Args:    "<charm>",
+		Purpose: "Deploy a charm.",
+		Doc:     ".hidden looking line\nDeploys a charm.\n",
+		Aliases: []string{"dep"},
+	}
+}
+
+func (c *deployCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.series, "series", "xenial", "The series")
+	f.StringVar(&c.series, "s", "xenial", "")
+	f.BoolVar(&c.force, "force", false, "Force it")
+	f.BoolVar(&c.debug, "debug-internal", false, "Internal")
+}
+
+func (c *deployCommand) HiddenFlags() []string {
+	return []string{"debug-internal"}
+}
+
+func (c *deployCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func newDocRegistry() *jujucmd.DocRegistry {
+	r := &jujucmd.DocRegistry{}
+	r.Register(&echoCommand{name: "status"})
+	r.Register(&deployCommand{})
+	r.RegisterAlias("install", "deploy", nil)
+	r.RegisterDeprecated(&echoCommand{name: "destroy-environment"}, jujucmd.DeprecatedBy("destroy-model"))
+	return r
+}
+
+func (*docsSuite) TestCommandsSorted(c *gc.C) {
+	commands := newDocRegistry().Commands(true)
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+	c.Assert(sort.StringsAreSorted(names), jc.IsTrue)
+	c.Assert(names, jc.DeepEquals, []string{"deploy", "destroy-environment", "status"})
+}
+
+func (*docsSuite) TestDeprecatedSkipped(c *gc.C) {
+	commands := newDocRegistry().Commands(false)
+	c.Assert(commands, gc.HasLen, 2)
+	c.Assert(commands[0].Name, gc.Equals, "deploy")
+	c.Assert(commands[1].Name, gc.Equals, "status")
+}
+
+func (*docsSuite) TestCommandDetails(c *gc.C) {
+	deploy := newDocRegistry().Commands(false)[0]
+	c.Assert(deploy.Aliases, jc.DeepEquals, []string{"dep", "install"})
+	c.Assert(deploy.Flags, jc.DeepEquals, []jujucmd.DocFlag{
+		{Names: []string{"force"}, Default: "false", Usage: "Force it"},
+		{Names: []string{"s", "series"}, Default: "xenial", Usage: "The series"},
+	})
+}
+
+func (*docsSuite) TestMarkdown(c *gc.C) {
+	var buf bytes.Buffer
+	err := jujucmd.WriteMarkdown(&buf, "juju", newDocRegistry().Commands(true))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, `# juju
+
+* [deploy](#deploy) - Deploy a charm.
+* [destroy-environment](#destroy-environment) - echo destroy-environment
+* [status](#status) - echo status
+
+## deploy
+
+Deploy a charm.
+
+### Usage
+
+    juju deploy [options] <charm>
+
+### Aliases
+
+dep, install
+
+### Options
+
+* `+"`--force`"+` (default `+"`false`"+`): Force it
+* `+"`-s, --series`"+` (default `+"`xenial`"+`): The series
+
+### Details
+
+.hidden looking line
+Deploys a charm.
+
+## destroy-environment
+
+**Deprecated**: use `+"`destroy-model`"+` instead.
+
+echo destroy-environment
+
+### Usage
+
+    juju destroy-environment [options]
+
+## status
+
+echo status
+
+### Usage
+
+    juju status [options]
+`)
+}
+
+func (*docsSuite) TestMarkdownDeterministic(c *gc.C) {
+	var first, second bytes.Buffer
+	err := jujucmd.WriteMarkdown(&first, "juju", newDocRegistry().Commands(true))
+	c.Assert(err, jc.ErrorIsNil)
+	err = jujucmd.WriteMarkdown(&second, "juju", newDocRegistry().Commands(true))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(first.String(), gc.Equals, second.String())
+}
+
+func (*docsSuite) TestManPages(c *gc.C) {
+	dir := c.MkDir()
+	err := jujucmd.WriteManPages(dir, "juju", newDocRegistry().Commands(false))
+	c.Assert(err, jc.ErrorIsNil)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(err, jc.ErrorIsNil)
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	c.Assert(files, jc.DeepEquals, []string{"juju-deploy.1", "juju-status.1", "juju.1"})
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "juju-deploy.1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, `.TH JUJU-DEPLOY 1
+.SH NAME
+juju-deploy \- Deploy a charm.
+.SH SYNOPSIS
+juju deploy [options] <charm>
+.SH ALIASES
+dep, install
+.SH OPTIONS
+.TP
+.B --force
+Force it (default false)
+.TP
+.B -s, --series
+The series (default xenial)
+.SH DESCRIPTION
+\&.hidden looking line
+Deploys a charm.
+`)
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "juju.1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, `.TH JUJU 1
+.SH NAME
+juju
+.SH COMMANDS
+.TP
+.B deploy
+Deploy a charm.
+.TP
+.B status
+echo status
+`)
+}
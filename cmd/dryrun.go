@@ -0,0 +1,191 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+
+	"github.com/juju/juju/cmd/output"
+)
+
+// DryRunFlag is the name of the flag registered by DryRun.AddFlags.
+const DryRunFlag = "dry-run"
+
+// DryRun gives a command that changes things the conventional
+// --dry-run flag. The command builds a plan of what it would do with a
+// PlanWriter, whether or not the flag is given, and then either renders
+// the plan or carries it out, so that both paths share the planning
+// code:
+//
+//	func (c *removeCommand) Run(ctx *cmd.Context) error {
+//		plan := c.dryRun.Plan()
+//		for _, id := range c.ids {
+//			plan.Add("remove", "machine "+id, "")
+//		}
+//		if plan.DryRun() {
+//			return plan.Render(ctx, c.out.Name())
+//		}
+//		if err := plan.Confirm(ctx); err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// During a dry run, PlanWriter.Confirm refuses without prompting, and
+// Main exits with 0 if the command fails only because of that, once
+// its plan has been rendered.
+type DryRun struct {
+	enabled bool
+	plan    *PlanWriter
+}
+
+// AddFlags registers the --dry-run flag in f.
+func (d *DryRun) AddFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&d.enabled, DryRunFlag, false, "Show what would be done, without doing it")
+}
+
+// IsDryRun returns whether --dry-run was given.
+func (d *DryRun) IsDryRun() bool {
+	return d.enabled
+}
+
+// Plan returns the PlanWriter of the command. Every call returns the
+// same PlanWriter.
+func (d *DryRun) Plan() *PlanWriter {
+	if d.plan == nil {
+		d.plan = &PlanWriter{dryRun: d.enabled}
+	}
+	return d.plan
+}
+
+// dryRunError is returned by PlanWriter.Confirm during a dry run. It
+// satisfies IsUserAbortedError.
+type dryRunError struct {
+	rendered bool
+}
+
+func (e *dryRunError) Error() string {
+	return "not confirmed during dry run"
+}
+
+// isRenderedDryRun returns whether err was returned by
+// PlanWriter.Confirm during a dry run whose plan had been rendered.
+func isRenderedDryRun(err error) bool {
+	e, ok := errors.Cause(err).(*dryRunError)
+	return ok && e.rendered
+}
+
+// PlanStep is an action planned by a command.
+type PlanStep struct {
+	// Verb says what would be done, for example "destroy".
+	Verb string `json:"verb"`
+
+	// Target identifies what it would be done to, for example
+	// "machine 0".
+	Target string `json:"target"`
+
+	// Detail optionally adds anything else worth knowing.
+	Detail string `json:"detail,omitempty"`
+}
+
+// plannedOutput is the structured form of a rendered plan.
+type plannedOutput struct {
+	DryRun bool       `json:"dry-run"`
+	Plan   []PlanStep `json:"plan"`
+}
+
+// PlanWriter accumulates the actions planned by a command, and renders
+// them for a dry run. It is obtained from DryRun.Plan.
+type PlanWriter struct {
+	dryRun bool
+
+	mu       sync.Mutex
+	steps    []PlanStep
+	rendered bool
+}
+
+// DryRun returns whether the plan is for a dry run, and so should be
+// rendered rather than carried out.
+func (p *PlanWriter) DryRun() bool {
+	return p.dryRun
+}
+
+// Add adds an action to the plan.
+func (p *PlanWriter) Add(verb, target, detail string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, PlanStep{Verb: verb, Target: target, Detail: detail})
+}
+
+// Steps returns the actions planned so far, in the order they were
+// added.
+func (p *PlanWriter) Steps() []PlanStep {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlanStep(nil), p.steps...)
+}
+
+// Confirm asks the user to confirm the plan, as UserConfirmYes does.
+// During a dry run it returns an error satisfying IsUserAbortedError
+// without reading anything.
+func (p *PlanWriter) Confirm(ctx *cmd.Context) error {
+	if p.dryRun {
+		return errors.Trace(&dryRunError{rendered: p.isRendered()})
+	}
+	return UserConfirmYes(ctx)
+}
+
+// Render writes the plan to ctx.Stdout. With the "json" format, the
+// plan is written as a JSON object holding the steps; otherwise it is
+// written as an aligned list, one step to a line.
+func (p *PlanWriter) Render(ctx *cmd.Context, format string) error {
+	steps := p.Steps()
+	if format == "json" {
+		data, err := json.Marshal(plannedOutput{DryRun: p.dryRun, Plan: steps})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := fmt.Fprintf(ctx.Stdout, "%s\n", data); err != nil {
+			return errors.Trace(err)
+		}
+	} else if err := renderPlan(ctx, steps); err != nil {
+		return errors.Trace(err)
+	}
+	p.mu.Lock()
+	p.rendered = true
+	p.mu.Unlock()
+	return nil
+}
+
+// renderPlan writes steps to ctx.Stdout for people to read.
+func renderPlan(ctx *cmd.Context, steps []PlanStep) error {
+	if len(steps) == 0 {
+		_, err := fmt.Fprintln(ctx.Stdout, T("Nothing would be done."))
+		return err
+	}
+	if _, err := fmt.Fprintln(ctx.Stdout, T("Would:")); err != nil {
+		return err
+	}
+	tw := output.TabWriter(ctx.Stdout)
+	for _, step := range steps {
+		fmt.Fprintf(tw, "  %s\t%s", step.Verb, step.Target)
+		if step.Detail != "" {
+			fmt.Fprintf(tw, "\t%s", step.Detail)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func (p *PlanWriter) isRendered() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rendered
+}
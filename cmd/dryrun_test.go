@@ -0,0 +1,186 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type dryRunSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&dryRunSuite{})
+
+// removeCommand removes machines and their containers, asking for
+// confirmation first. It plans its work in the same way whether or not
+// it is making a dry run.
+type removeCommand struct {
+	cmd.CommandBase
+	dryRun jujucmd.DryRun
+	out    cmd.Output
+	ids    []string
+
+	// containers holds the containers of each machine.
+	containers map[string][]string
+	// removed records what was removed.
+	removed []string
+}
+
+func (c *removeCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "remove-machine", Args: "<machine> ...", Purpose: "Remove machines."}
+}
+
+func (c *removeCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.dryRun.AddFlags(f)
+	c.out.AddFlags(f, "smart", cmd.DefaultFormatters)
+}
+
+func (c *removeCommand) Init(args []string) error {
+	c.ids = args
+	return nil
+}
+
+func (c *removeCommand) Run(ctx *cmd.Context) error {
+	plan := c.dryRun.Plan()
+	for _, id := range c.ids {
+		for _, container := range c.containers[id] {
+			plan.Add("remove", "container "+container, "hosted on machine "+id)
+		}
+		plan.Add("remove", "machine "+id, "")
+	}
+	if plan.DryRun() {
+		if err := plan.Render(ctx, c.out.Name()); err != nil {
+			return err
+		}
+	}
+	if err := plan.Confirm(ctx); err != nil {
+		return errors.Annotate(err, "machine removal")
+	}
+	for _, step := range plan.Steps() {
+		c.removed = append(c.removed, step.Target)
+	}
+	return nil
+}
+
+func newRemoveCommand() *removeCommand {
+	return &removeCommand{
+		containers: map[string][]string{
+			"0": {"0/lxd/0", "0/lxd/1"},
+		},
+	}
+}
+
+func (s *dryRunSuite) TestWetRun(c *gc.C) {
+	command := newRemoveCommand()
+	ctx := coretesting.Context(c)
+	ctx.Stdin = strings.NewReader("y\n")
+	code := jujucmd.Main(command, ctx, []string{"0", "1"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(command.dryRun.IsDryRun(), jc.IsFalse)
+	c.Check(command.removed, jc.DeepEquals, []string{
+		"container 0/lxd/0", "container 0/lxd/1", "machine 0", "machine 1",
+	})
+	c.Check(coretesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (s *dryRunSuite) TestWetRunNotConfirmed(c *gc.C) {
+	command := newRemoveCommand()
+	ctx := coretesting.Context(c)
+	ctx.Stdin = strings.NewReader("n\n")
+	code := jujucmd.Main(command, ctx, []string{"1"})
+	c.Check(code, gc.Equals, 1)
+	c.Check(command.removed, gc.HasLen, 0)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "ERROR machine removal: aborted\n")
+}
+
+func (s *dryRunSuite) TestDryRun(c *gc.C) {
+	command := newRemoveCommand()
+	ctx := coretesting.Context(c)
+	// Nothing is read from stdin during a dry run.
+	ctx.Stdin = strings.NewReader("y\n")
+	code := jujucmd.Main(command, ctx, []string{"--dry-run", "0", "1"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(command.removed, gc.HasLen, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, ""+
+		"Would:\n"+
+		"  remove  container 0/lxd/0  hosted on machine 0\n"+
+		"  remove  container 0/lxd/1  hosted on machine 0\n"+
+		"  remove  machine 0\n"+
+		"  remove  machine 1\n",
+	)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *dryRunSuite) TestDryRunJSON(c *gc.C) {
+	command := newRemoveCommand()
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(command, ctx, []string{"--dry-run", "--format", "json", "0"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, `{"dry-run":true,"plan":[`+
+		`{"verb":"remove","target":"container 0/lxd/0","detail":"hosted on machine 0"},`+
+		`{"verb":"remove","target":"container 0/lxd/1","detail":"hosted on machine 0"},`+
+		`{"verb":"remove","target":"machine 0"}]}`+"\n",
+	)
+}
+
+func (s *dryRunSuite) TestDryRunNothingToDo(c *gc.C) {
+	command := newRemoveCommand()
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(command, ctx, []string{"--dry-run"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, "Nothing would be done.\n")
+}
+
+func (s *dryRunSuite) TestIsDryRun(c *gc.C) {
+	var dryRun jujucmd.DryRun
+	c.Check(dryRun.IsDryRun(), jc.IsFalse)
+
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	dryRun.AddFlags(f)
+	c.Assert(f.Parse(true, []string{"--" + jujucmd.DryRunFlag}), jc.ErrorIsNil)
+	c.Check(dryRun.IsDryRun(), jc.IsTrue)
+
+	plan := dryRun.Plan()
+	c.Check(plan.DryRun(), jc.IsTrue)
+	c.Check(dryRun.Plan(), gc.Equals, plan)
+	err := plan.Confirm(coretesting.Context(c))
+	c.Check(err, jc.Satisfies, jujucmd.IsUserAbortedError)
+}
+
+func (s *dryRunSuite) TestDryRunNotRendered(c *gc.C) {
+	command := &confirmOnlyCommand{}
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(command, ctx, []string{"--dry-run"})
+	c.Check(code, gc.Equals, 1)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "ERROR not confirmed during dry run\n")
+}
+
+// confirmOnlyCommand asks for confirmation without rendering its plan.
+type confirmOnlyCommand struct {
+	cmd.CommandBase
+	dryRun jujucmd.DryRun
+}
+
+func (c *confirmOnlyCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "confirm"}
+}
+
+func (c *confirmOnlyCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.dryRun.AddFlags(f)
+}
+
+func (c *confirmOnlyCommand) Run(ctx *cmd.Context) error {
+	return c.dryRun.Plan().Confirm(ctx)
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// WrapEnvDefaults wraps a command so that the flags named in envVars
+// take their default value from the given environment variables, as
+// read with ctx.Getenv; ctx must be the context the command runs in.
+// The help text of each such flag notes the variable, for example
+// "(default from $JUJU_MODEL)".
+//
+// A value given on the command line always takes precedence over the
+// environment, which in turn takes precedence over the default set by
+// the command. The environment is read once the command's flags are
+// defined and before they are parsed, so that the command's Init sees
+// the values taken from it.
+func WrapEnvDefaults(ctx *cmd.Context, c cmd.Command, envVars map[string]string) cmd.Command {
+	return &envDefaultsCommand{
+		Command: c,
+		ctx:     ctx,
+		envVars: envVars,
+	}
+}
+
+type envDefaultsCommand struct {
+	cmd.Command
+	ctx     *cmd.Context
+	envVars map[string]string
+	err     error
+}
+
+// SetFlags implements cmd.Command.
+func (c *envDefaultsCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.Command.SetFlags(f)
+	for _, name := range c.flagNames() {
+		target := f.Lookup(name)
+		if target == nil {
+			continue
+		}
+		// Annotate every name for the flag that has a description,
+		// as help shows the description of aliased flags only once.
+		note := fmt.Sprintf(" (default from $%s)", c.envVars[name])
+		f.VisitAll(func(flag *gnuflag.Flag) {
+			if flag.Value == target.Value && flag.Usage != "" {
+				flag.Usage += note
+			}
+		})
+	}
+	// SetFlags cannot fail, so any error is reported by Init.
+	c.err = ApplyEnvDefaults(f, c.envVars, c.ctx.Getenv)
+}
+
+// Init implements cmd.Command.
+func (c *envDefaultsCommand) Init(args []string) error {
+	if c.err != nil {
+		return errors.Trace(c.err)
+	}
+	return c.Command.Init(args)
+}
+
+// HiddenFlags implements HiddenFlagsCommand.
+func (c *envDefaultsCommand) HiddenFlags() []string {
+	if hc, ok := c.Command.(HiddenFlagsCommand); ok {
+		return hc.HiddenFlags()
+	}
+	return nil
+}
+
+func (c *envDefaultsCommand) flagNames() []string {
+	names := make([]string, 0, len(c.envVars))
+	for name := range c.envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyEnvDefaults sets each flag named in envVars to the value of the
+// corresponding environment variable, as returned by getenv, if that
+// is not empty. The flag is not marked as given, so it must be called
+// after the flags are defined and before they are parsed, for a value
+// given on the command line to take precedence.
+func ApplyEnvDefaults(f *gnuflag.FlagSet, envVars map[string]string, getenv func(string) string) error {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := f.Lookup(name)
+		if flag == nil {
+			return errors.Errorf("flag --%s not defined", name)
+		}
+		envVar := envVars[name]
+		value := getenv(envVar)
+		if value == "" {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return errors.Annotatef(err, "invalid value %q for --%s from $%s", value, name, envVar)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type envDefaultsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&envDefaultsSuite{})
+
+type modelCommand struct {
+	cmd.CommandBase
+	model   string
+	timeout int
+}
+
+func (c *modelCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "show-model"}
+}
+
+func (c *modelCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.model, "m", "default", "Model to operate in")
+	f.StringVar(&c.model, "model", "default", "")
+	f.IntVar(&c.timeout, "timeout", 10, "Timeout in seconds")
+}
+
+func (c *modelCommand) Run(ctx *cmd.Context) error {
+	fmt.Fprintf(ctx.Stdout, "%s %d\n", c.model, c.timeout)
+	return nil
+}
+
+func newEnvDefaultsCommand(ctx *cmd.Context) cmd.Command {
+	return jujucmd.WrapEnvDefaults(ctx, &modelCommand{}, map[string]string{
+		"model":   "TEST_MODEL",
+		"timeout": "TEST_TIMEOUT",
+	})
+}
+
+var envDefaultsTests = []struct {
+	about    string
+	env      map[string]string
+	args     []string
+	expected string
+	err      string
+}{{
+	about:    "hardcoded defaults",
+	expected: "default 10\n",
+}, {
+	about:    "env overrides hardcoded",
+	env:      map[string]string{"TEST_MODEL": "env", "TEST_TIMEOUT": "20"},
+	expected: "env 20\n",
+}, {
+	about:    "flag overrides env",
+	env:      map[string]string{"TEST_MODEL": "env", "TEST_TIMEOUT": "20"},
+	args:     []string{"--model", "flag", "--timeout", "30"},
+	expected: "flag 30\n",
+}, {
+	about:    "short flag overrides env",
+	env:      map[string]string{"TEST_MODEL": "env"},
+	args:     []string{"-m", "flag"},
+	expected: "flag 10\n",
+}, {
+	about:    "flag overrides hardcoded",
+	args:     []string{"--timeout", "30"},
+	expected: "default 30\n",
+}, {
+	about: "invalid env value",
+	env:   map[string]string{"TEST_TIMEOUT": "soon"},
+	err:   `invalid value "soon" for --timeout from \$TEST_TIMEOUT: .*`,
+}}
+
+func (s *envDefaultsSuite) TestPrecedence(c *gc.C) {
+	for i, test := range envDefaultsTests {
+		c.Logf("test %d: %s", i, test.about)
+		ctx := coretesting.Context(c)
+		ctx.Env = test.env
+		command := newEnvDefaultsCommand(ctx)
+		err := coretesting.InitCommand(command, test.args)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		err = command.Run(ctx)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, test.expected)
+	}
+}
+
+// requiredModelCommand rejects an empty model in Init.
+type requiredModelCommand struct {
+	modelCommand
+}
+
+func (c *requiredModelCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.model, "m", "", "Model to operate in")
+	f.StringVar(&c.model, "model", "", "")
+}
+
+func (c *requiredModelCommand) Init(args []string) error {
+	if c.model == "" {
+		return errors.New("no model specified")
+	}
+	return cmd.CheckEmpty(args)
+}
+
+func (s *envDefaultsSuite) TestInitSeesEnvValue(c *gc.C) {
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"TEST_MODEL": "env"}
+	command := jujucmd.WrapEnvDefaults(ctx, &requiredModelCommand{}, map[string]string{"model": "TEST_MODEL"})
+	code := cmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, "env 0\n")
+}
+
+func (s *envDefaultsSuite) TestInitRejectsEmptyValue(c *gc.C) {
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"TEST_MODEL": ""}
+	command := jujucmd.WrapEnvDefaults(ctx, &requiredModelCommand{}, map[string]string{"model": "TEST_MODEL"})
+	code := cmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 2)
+	c.Check(coretesting.Stderr(ctx), jc.Contains, "error: no model specified\n")
+}
+
+func (s *envDefaultsSuite) TestContextEnvironmentOverridesProcess(c *gc.C) {
+	s.PatchEnvironment("TEST_MODEL", "process")
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"TEST_MODEL": "context"}
+	command := newEnvDefaultsCommand(ctx)
+	err := coretesting.InitCommand(command, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = command.Run(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "context 10\n")
+}
+
+func (s *envDefaultsSuite) TestHelpNotesEnvVar(c *gc.C) {
+	help := string(jujucmd.CommandHelp(newEnvDefaultsCommand(coretesting.Context(c)), "show-model", false))
+	c.Assert(help, jc.Contains, "Model to operate in (default from $TEST_MODEL)")
+	c.Assert(help, jc.Contains, "Timeout in seconds (default from $TEST_TIMEOUT)")
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/output"
+	"github.com/juju/juju/juju/osenv"
+)
+
+// ErrorFormatJSON is the value of the JUJU_ERROR_FORMAT environment
+// variable that selects machine-readable error output.
+const ErrorFormatJSON = "json"
+
+// commandError is the machine-readable representation of a command
+// failure.
+type commandError struct {
+	Error     string `json:"error"`
+	ErrorCode string `json:"error-code,omitempty"`
+}
+
+// ErrorFormatRunner is a CommandRunner that, when the JUJU_ERROR_FORMAT
+// environment variable of the context is set to "json", reports errors
+// returned by Run as a single JSON object on stderr, for example:
+//
+//	{"error":"application \"foo\" not found","error-code":"not found"}
+//
+// The error is then replaced with cmd.ErrSilent so that cmd.Main
+// exits with the usual status without printing it again. cmd.ErrSilent,
+// errors carrying an explicit exit code and broken pipe errors, which
+// Main reports with an exit code of its own, are passed through
+// untouched. Only errors returned by Run are formatted: errors from
+// parsing the flags or from Init never reach a runner, so they are
+// reported in the usual "error: ..." form, with an exit code of 2.
+func ErrorFormatRunner(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error {
+	err := next(ctx)
+	if err == nil || err == cmd.ErrSilent || cmd.IsRcPassthroughError(err) || output.IsBrokenPipe(err) {
+		return err
+	}
+	if ctx.Getenv(osenv.JujuErrorFormatEnvKey) != ErrorFormatJSON {
+		return err
+	}
+	if writeErr := WriteJSONError(ctx, err); writeErr != nil {
+		logger.Errorf("cannot write error as JSON: %v", writeErr)
+		return err
+	}
+	return cmd.ErrSilent
+}
+
+// WriteJSONError writes err to the context's stderr as a JSON object
+// holding the error message and, where one is available, its error
+// code.
+func WriteJSONError(ctx *cmd.Context, err error) error {
+	data, marshalErr := json.Marshal(commandError{
+		Error:     err.Error(),
+		ErrorCode: params.ErrCode(err),
+	})
+	if marshalErr != nil {
+		return errors.Trace(marshalErr)
+	}
+	_, writeErr := fmt.Fprintf(ctx.Stderr, "%s\n", data)
+	return errors.Trace(writeErr)
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"encoding/json"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	jujucmd "github.com/juju/juju/cmd"
+	"github.com/juju/juju/juju/osenv"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type errorFormatSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&errorFormatSuite{})
+
+func (s *errorFormatSuite) run(c *gc.C, runErr error, format string, args ...string) (*cmd.Context, int) {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.AddCommandRunner(jujucmd.ErrorFormatRunner)
+	super.Register(&failingCommand{err: runErr})
+	ctx := coretesting.Context(c)
+	s.PatchEnvironment(osenv.JujuErrorFormatEnvKey, format)
+	return ctx, cmd.Main(super, ctx, args)
+}
+
+func (s *errorFormatSuite) TestJSONError(c *gc.C) {
+	err := &params.Error{Message: `application "foo" not found`, Code: params.CodeNotFound}
+	ctx, code := s.run(c, errors.Annotate(err, "cannot deploy"), "json", "fail")
+	c.Assert(code, gc.Equals, 1)
+	var out map[string]string
+	c.Assert(json.Unmarshal([]byte(coretesting.Stderr(ctx)), &out), jc.ErrorIsNil)
+	c.Assert(out, jc.DeepEquals, map[string]string{
+		"error":      `cannot deploy: application "foo" not found`,
+		"error-code": "not found",
+	})
+}
+
+func (s *errorFormatSuite) TestJSONErrorWithoutCode(c *gc.C) {
+	ctx, code := s.run(c, errors.New("boom"), "json", "fail")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, `{"error":"boom"}`+"\n")
+}
+
+func (s *errorFormatSuite) TestHumanErrorByDefault(c *gc.C) {
+	ctx, code := s.run(c, errors.New("boom"), "", "fail")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, "(?s)ERROR boom\n")
+}
+
+func (s *errorFormatSuite) TestSilentErrorStaysSilent(c *gc.C) {
+	ctx, code := s.run(c, cmd.ErrSilent, "json", "fail")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *errorFormatSuite) TestExitCodePassedThrough(c *gc.C) {
+	ctx, code := s.run(c, cmd.NewRcPassthroughError(3), "json", "fail")
+	c.Assert(code, gc.Equals, 3)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *errorFormatSuite) TestUsageErrorIsHuman(c *gc.C) {
+	ctx, code := s.run(c, nil, "json", "fail", "extra")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, `error: unrecognized args: ["extra"]`+"\n")
+}
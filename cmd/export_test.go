@@ -0,0 +1,35 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"os"
+	"time"
+)
+
+var (
+	Wrap                   = wrap
+	RetryLoopInterruptible = retryLoop
+)
+
+func PatchTerminal(p patcher, terminal bool, height int) {
+	p.PatchValue(&isTerminal, func(*os.File) bool { return terminal })
+	p.PatchValue(&terminalHeight, func(*os.File) int { return height })
+}
+
+type patcher interface {
+	PatchValue(dest, value interface{})
+}
+
+func PatchRandFloat64(p patcher, f func() float64) {
+	p.PatchValue(&randFloat64, f)
+}
+
+func PatchWarnOnceNow(p patcher, now func() time.Time) {
+	p.PatchValue(&warnOnceNow, now)
+}
+
+func PatchCaptureNow(p patcher, now func() time.Time) {
+	p.PatchValue(&captureNow, now)
+}
@@ -0,0 +1,160 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// WrapFlagDefaults wraps a command so that flags not given on the
+// command line take their values from a defaults file, if the given
+// environment variable names one (see osenv.JujuCLIDefaultsEnvKey).
+// The variable is read with ctx.Getenv; ctx must be the context the
+// command runs in. The file holds one key=value pair per line, where
+// each key is the name of a flag without its leading dashes, for
+// example:
+//
+//	# Defaults for juju commands run in this project.
+//	format=json
+//	model=staging
+//
+// Blank lines and lines starting with "#" are ignored, and a later
+// line for the same flag overrides an earlier one. A value given on
+// the command line always takes precedence over the file. Keys that
+// do not name a flag of the command are ignored, with a warning shown
+// only with --verbose.
+//
+// The file is read once the command's flags are defined and before
+// they are parsed, so that the command's Init sees the values taken
+// from it.
+func WrapFlagDefaults(ctx *cmd.Context, c cmd.Command, envVar string) cmd.Command {
+	return &flagDefaultsCommand{
+		Command: c,
+		ctx:     ctx,
+		envVar:  envVar,
+	}
+}
+
+type flagDefaultsCommand struct {
+	cmd.Command
+	ctx     *cmd.Context
+	envVar  string
+	ignored []string
+	err     error
+}
+
+// SetFlags implements cmd.Command.
+func (c *flagDefaultsCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.Command.SetFlags(f)
+	// SetFlags cannot fail, so any error is reported by Init.
+	c.ignored, c.err = ApplyFlagDefaultsFile(c.ctx, f, c.envVar)
+}
+
+// Init implements cmd.Command.
+func (c *flagDefaultsCommand) Init(args []string) error {
+	if c.err != nil {
+		return errors.Trace(c.err)
+	}
+	return c.Command.Init(args)
+}
+
+// HiddenFlags implements HiddenFlagsCommand.
+func (c *flagDefaultsCommand) HiddenFlags() []string {
+	if hc, ok := c.Command.(HiddenFlagsCommand); ok {
+		return hc.HiddenFlags()
+	}
+	return nil
+}
+
+// Run implements cmd.Command. The keys ignored in the defaults file
+// are reported here, as --verbose takes effect only once the command
+// is run.
+func (c *flagDefaultsCommand) Run(ctx *cmd.Context) error {
+	for _, name := range c.ignored {
+		ctx.Verbosef("ignoring unknown flag %q in %s", name, AbsPath(ctx, ctx.Getenv(c.envVar)))
+	}
+	return c.Command.Run(ctx)
+}
+
+// ApplyFlagDefaultsFile reads the defaults file named by the given
+// environment variable in the context, and sets each flag in f that
+// it holds a value for. The flags are not marked as given, so it must
+// be called after the flags are defined and before they are parsed,
+// for a value given on the command line to take precedence. It returns
+// the keys in the file, each named once, that do not name a flag in f.
+// If the variable is empty, nothing is done.
+func ApplyFlagDefaultsFile(ctx *cmd.Context, f *gnuflag.FlagSet, envVar string) ([]string, error) {
+	path := ctx.Getenv(envVar)
+	if path == "" {
+		return nil, nil
+	}
+	path = AbsPath(ctx, path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read flag defaults from $%s", envVar)
+	}
+	defer file.Close()
+	defaults, err := readFlagDefaults(file)
+	if err != nil {
+		return nil, errors.Annotatef(err, "flag defaults file %q", path)
+	}
+
+	var ignored []string
+	seen := make(map[string]bool)
+	for _, d := range defaults {
+		flag := f.Lookup(d.name)
+		if flag == nil {
+			if !seen[d.name] {
+				ignored = append(ignored, d.name)
+				seen[d.name] = true
+			}
+			continue
+		}
+		if err := flag.Value.Set(d.value); err != nil {
+			return nil, errors.Annotatef(err, "invalid value %q for --%s at %s line %d", d.value, d.name, path, d.line)
+		}
+	}
+	return ignored, nil
+}
+
+// flagDefault holds a single entry from a flag defaults file.
+type flagDefault struct {
+	name  string
+	value string
+	line  int
+}
+
+// readFlagDefaults parses the contents of a flag defaults file, as
+// described in WrapFlagDefaults.
+func readFlagDefaults(r io.Reader) ([]flagDefault, error) {
+	var defaults []flagDefault
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.SplitN(text, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, errors.Errorf("line %d: expected key=value, got %q", line, text)
+		}
+		defaults = append(defaults, flagDefault{
+			name:  name,
+			value: strings.TrimSpace(parts[1]),
+			line:  line,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return defaults, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type flagDefaultsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&flagDefaultsSuite{})
+
+func newFlagDefaultsSuper(ctx *cmd.Context) *jujucmd.RunnerSuperCommand {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	}))
+	super.EnableFlagDefaults(ctx, "TEST_DEFAULTS")
+	super.Register(&modelCommand{})
+	return super
+}
+
+// runWithDefaults runs the show-model command with the given defaults
+// file contents and arguments.
+func runWithDefaults(c *gc.C, defaults string, args ...string) (*cmd.Context, int) {
+	ctx := coretesting.Context(c)
+	path := filepath.Join(ctx.Dir, "defaults")
+	err := ioutil.WriteFile(path, []byte(defaults), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	ctx.Env = map[string]string{"TEST_DEFAULTS": path}
+	code := cmd.Main(newFlagDefaultsSuper(ctx), ctx, append([]string{"show-model"}, args...))
+	return ctx, code
+}
+
+var flagDefaultsTests = []struct {
+	about    string
+	defaults string
+	args     []string
+	expected string
+}{{
+	about:    "empty file",
+	expected: "default 10\n",
+}, {
+	about: "file overrides hardcoded",
+	defaults: `
+# A comment.
+model = file
+
+timeout=20
+`,
+	expected: "file 20\n",
+}, {
+	about:    "later line wins",
+	defaults: "model=first\nmodel=second\n",
+	expected: "second 10\n",
+}, {
+	about:    "flag overrides file",
+	defaults: "model=file\ntimeout=20\n",
+	args:     []string{"--model", "flag"},
+	expected: "flag 20\n",
+}, {
+	about:    "short flag overrides file",
+	defaults: "model=file\ntimeout=20\n",
+	args:     []string{"-m", "flag", "--timeout", "30"},
+	expected: "flag 30\n",
+}}
+
+func (s *flagDefaultsSuite) TestPrecedence(c *gc.C) {
+	for i, test := range flagDefaultsTests {
+		c.Logf("test %d: %s", i, test.about)
+		ctx, code := runWithDefaults(c, test.defaults, test.args...)
+		c.Check(coretesting.Stderr(ctx), gc.Equals, "")
+		c.Check(code, gc.Equals, 0)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, test.expected)
+	}
+}
+
+func (s *flagDefaultsSuite) TestNoFile(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newFlagDefaultsSuper(ctx), ctx, []string{"show-model"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "default 10\n")
+}
+
+func (s *flagDefaultsSuite) TestMissingFile(c *gc.C) {
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"TEST_DEFAULTS": "missing"}
+	code := cmd.Main(newFlagDefaultsSuper(ctx), ctx, []string{"show-model"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `error: cannot read flag defaults from \$TEST_DEFAULTS: .*missing.*\n`)
+}
+
+func (s *flagDefaultsSuite) TestUnknownKey(c *gc.C) {
+	defaults := "colour=red\nmodel=file\ncolour=blue\n"
+	ctx, code := runWithDefaults(c, defaults)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "file 10\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+
+	ctx, code = runWithDefaults(c, defaults, "--verbose")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "file 10\n")
+	stderr := coretesting.Stderr(ctx)
+	c.Assert(stderr, jc.Contains, `ignoring unknown flag "colour" in `)
+	c.Assert(strings.Count(stderr, "colour"), gc.Equals, 1)
+}
+
+func (s *flagDefaultsSuite) TestMalformedFile(c *gc.C) {
+	ctx, code := runWithDefaults(c, "model=file\n\njson\n")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `error: flag defaults file ".*defaults": line 3: expected key=value, got "json"\n`)
+}
+
+func (s *flagDefaultsSuite) TestInvalidValue(c *gc.C) {
+	ctx, code := runWithDefaults(c, "timeout=soon\n")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `error: invalid value "soon" for --timeout at .*defaults line 1: .*\n`)
+}
+
+func (s *flagDefaultsSuite) TestInitSeesFileValue(c *gc.C) {
+	ctx := coretesting.Context(c)
+	path := filepath.Join(ctx.Dir, "defaults")
+	err := ioutil.WriteFile(path, []byte("model=file\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	ctx.Env = map[string]string{"TEST_DEFAULTS": path}
+	command := jujucmd.WrapFlagDefaults(ctx, &requiredModelCommand{}, "TEST_DEFAULTS")
+	code := cmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, "file 0\n")
+}
@@ -0,0 +1,212 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// FlagGroups declares rules about which of the flags in a flag set may
+// be given together, so that commands need not check them in Init:
+//
+//	jujucmd.NewFlagGroups(f).
+//		MutuallyExclusive("to", "constraints").
+//		RequireOne("file", "data")
+//
+// The rules are evaluated by Check once the command line has been
+// parsed. A flag counts as given if it, or another name for the same
+// flag value such as -m for --model, appears on the command line, even
+// if it is given its default value.
+type FlagGroups struct {
+	f        *gnuflag.FlagSet
+	rules    []flagRule
+	showHelp bool
+}
+
+type flagRuleKind int
+
+const (
+	mutuallyExclusive flagRuleKind = iota
+	requireOne
+)
+
+type flagRule struct {
+	kind  flagRuleKind
+	names []string
+}
+
+// NewFlagGroups returns a FlagGroups for the flags defined in f. Rules
+// may only name flags that have already been defined.
+func NewFlagGroups(f *gnuflag.FlagSet) *FlagGroups {
+	return &FlagGroups{f: f}
+}
+
+// MutuallyExclusive declares that at most one of the named flags may
+// be given.
+func (g *FlagGroups) MutuallyExclusive(names ...string) *FlagGroups {
+	if len(names) < 2 {
+		panic("mutually exclusive flag group needs at least two flags")
+	}
+	return g.add(flagRule{kind: mutuallyExclusive, names: names})
+}
+
+// RequireOne declares that at least one of the named flags must be
+// given. Combine it with MutuallyExclusive to require exactly one.
+func (g *FlagGroups) RequireOne(names ...string) *FlagGroups {
+	if len(names) < 1 {
+		panic("required flag group needs at least one flag")
+	}
+	return g.add(flagRule{kind: requireOne, names: names})
+}
+
+// ShowInHelp causes the usage of each flag in a group, including
+// groups declared later, to note the rules that apply to it, for
+// example "(cannot be used with --to)".
+func (g *FlagGroups) ShowInHelp() *FlagGroups {
+	if !g.showHelp {
+		g.showHelp = true
+		for _, rule := range g.rules {
+			g.note(rule)
+		}
+	}
+	return g
+}
+
+func (g *FlagGroups) add(rule flagRule) *FlagGroups {
+	for _, name := range rule.names {
+		if g.f.Lookup(name) == nil {
+			panic(fmt.Sprintf("flag group refers to undefined flag %q", name))
+		}
+	}
+	g.rules = append(g.rules, rule)
+	if g.showHelp {
+		g.note(rule)
+	}
+	return g
+}
+
+// note appends a description of rule to the usage of its flags.
+func (g *FlagGroups) note(rule flagRule) {
+	for i, name := range rule.names {
+		others := make([]string, 0, len(rule.names)-1)
+		others = append(others, rule.names[:i]...)
+		others = append(others, rule.names[i+1:]...)
+		var note string
+		switch {
+		case rule.kind == mutuallyExclusive:
+			note = fmt.Sprintf(" (cannot be used with %s)", joinFlags(others, "or"))
+		case len(others) == 0:
+			note = " (required)"
+		default:
+			note = fmt.Sprintf(" (required unless %s is given)", joinFlags(others, "or"))
+		}
+		// As in WrapEnvDefaults, help shows the description of
+		// aliased flags only once, so annotate every name.
+		target := g.f.Lookup(name)
+		g.f.VisitAll(func(flag *gnuflag.Flag) {
+			if flag.Value == target.Value && flag.Usage != "" {
+				flag.Usage += note
+			}
+		})
+	}
+}
+
+// Check returns an error describing the first rule broken by the
+// flags given on the command line, naming the flags concerned. It
+// must be called after the flag set has been parsed.
+func (g *FlagGroups) Check() error {
+	given := make(map[gnuflag.Value]bool)
+	g.f.Visit(func(flag *gnuflag.Flag) {
+		given[flag.Value] = true
+	})
+	for _, rule := range g.rules {
+		var set []string
+		for _, name := range rule.names {
+			if given[g.f.Lookup(name).Value] {
+				set = append(set, name)
+			}
+		}
+		switch {
+		case rule.kind == mutuallyExclusive && len(set) == 2:
+			return errors.Errorf("cannot specify both %s", joinFlags(set, "and"))
+		case rule.kind == mutuallyExclusive && len(set) > 2:
+			return errors.Errorf("cannot specify %s together", joinFlags(set, "and"))
+		case rule.kind == requireOne && len(set) == 0 && len(rule.names) == 1:
+			return errors.Errorf("%s is required", joinFlags(rule.names, "or"))
+		case rule.kind == requireOne && len(set) == 0 && len(rule.names) == 2:
+			return errors.Errorf("either %s is required", joinFlags(rule.names, "or"))
+		case rule.kind == requireOne && len(set) == 0:
+			return errors.Errorf("one of %s is required", joinFlags(rule.names, "or"))
+		}
+	}
+	return nil
+}
+
+// joinFlags returns the names as they are written on the command line,
+// in a list ending with conjunction: "--a, --b or --c".
+func joinFlags(names []string, conjunction string) string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		if len(name) == 1 {
+			flags[i] = "-" + name
+		} else {
+			flags[i] = "--" + name
+		}
+	}
+	if len(flags) == 1 {
+		return flags[0]
+	}
+	last := len(flags) - 1
+	return strings.Join(flags[:last], ", ") + " " + conjunction + " " + flags[last]
+}
+
+// FlagGroupsCommand is implemented by commands that declare flag
+// groups, to be checked by WrapFlagGroups.
+type FlagGroupsCommand interface {
+	cmd.Command
+
+	// FlagGroups declares the command's rules on g, which holds
+	// the flags defined by SetFlags.
+	FlagGroups(g *FlagGroups)
+}
+
+// WrapFlagGroups wraps a command so that the flag groups it declares
+// are checked after its flags are parsed and before its Init method is
+// called, so Init may assume that the rules hold.
+func WrapFlagGroups(c FlagGroupsCommand) cmd.Command {
+	return &flagGroupsCommand{FlagGroupsCommand: c}
+}
+
+type flagGroupsCommand struct {
+	FlagGroupsCommand
+	groups *FlagGroups
+}
+
+// SetFlags implements cmd.Command.
+func (c *flagGroupsCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.FlagGroupsCommand.SetFlags(f)
+	c.groups = NewFlagGroups(f)
+	c.FlagGroupsCommand.FlagGroups(c.groups)
+}
+
+// HiddenFlags implements HiddenFlagsCommand.
+func (c *flagGroupsCommand) HiddenFlags() []string {
+	if hc, ok := c.FlagGroupsCommand.(HiddenFlagsCommand); ok {
+		return hc.HiddenFlags()
+	}
+	return nil
+}
+
+// Init implements cmd.Command.
+func (c *flagGroupsCommand) Init(args []string) error {
+	if err := c.groups.Check(); err != nil {
+		return err
+	}
+	return c.FlagGroupsCommand.Init(args)
+}
@@ -0,0 +1,222 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type flagGroupsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&flagGroupsSuite{})
+
+// addMachineCommand declares that --to and --constraints conflict, and
+// that exactly one of --file and --data is required.
+type addMachineCommand struct {
+	cmd.CommandBase
+	to          string
+	constraints string
+	file        string
+	data        string
+	inited      bool
+}
+
+func (c *addMachineCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "add-machine"}
+}
+
+func (c *addMachineCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.to, "to", "", "Placement directive")
+	f.StringVar(&c.constraints, "constraints", "", "Machine constraints")
+	f.StringVar(&c.file, "f", "", "Read the machine definition from a file")
+	f.StringVar(&c.file, "file", "", "")
+	f.StringVar(&c.data, "data", "", "Machine definition")
+}
+
+func (c *addMachineCommand) FlagGroups(g *jujucmd.FlagGroups) {
+	g.MutuallyExclusive("to", "constraints").
+		MutuallyExclusive("file", "data").
+		RequireOne("file", "data")
+}
+
+func (c *addMachineCommand) Init(args []string) error {
+	c.inited = true
+	return cmd.CheckEmpty(args)
+}
+
+func (c *addMachineCommand) Run(ctx *cmd.Context) error {
+	fmt.Fprintf(ctx.Stdout, "to=%q constraints=%q file=%q data=%q\n", c.to, c.constraints, c.file, c.data)
+	return nil
+}
+
+var flagGroupsTests = []struct {
+	about string
+	args  []string
+	err   string
+}{{
+	about: "nothing given",
+	err:   "either --file or --data is required",
+}, {
+	about: "file given",
+	args:  []string{"--file", "m.yaml"},
+}, {
+	about: "file given by alias",
+	args:  []string{"-f", "m.yaml"},
+}, {
+	about: "data given",
+	args:  []string{"--data", "{}"},
+}, {
+	about: "data given empty",
+	args:  []string{"--data", ""},
+}, {
+	about: "file and data given",
+	args:  []string{"--file", "m.yaml", "--data", "{}"},
+	err:   "cannot specify both --file and --data",
+}, {
+	about: "alias and data given",
+	args:  []string{"-f", "m.yaml", "--data", "{}"},
+	err:   "cannot specify both --file and --data",
+}, {
+	about: "to given",
+	args:  []string{"--to", "lxd:0", "--data", "{}"},
+}, {
+	about: "constraints given",
+	args:  []string{"--constraints", "mem=4G", "--data", "{}"},
+}, {
+	about: "to and constraints given",
+	args:  []string{"--to", "lxd:0", "--constraints", "mem=4G", "--data", "{}"},
+	err:   "cannot specify both --to and --constraints",
+}, {
+	about: "first broken rule reported",
+	args:  []string{"--to", "lxd:0", "--constraints", "mem=4G"},
+	err:   "cannot specify both --to and --constraints",
+}}
+
+func (*flagGroupsSuite) TestWrapFlagGroups(c *gc.C) {
+	for i, test := range flagGroupsTests {
+		c.Logf("test %d: %s", i, test.about)
+		command := &addMachineCommand{}
+		_, err := coretesting.RunCommand(c, jujucmd.WrapFlagGroups(command), test.args...)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			c.Check(command.inited, jc.IsFalse)
+			continue
+		}
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(command.inited, jc.IsTrue)
+	}
+}
+
+func parseGroups(c *gc.C, args ...string) *jujucmd.FlagGroups {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	for _, name := range []string{"a", "b", "c"} {
+		f.Bool(name, false, "")
+	}
+	c.Assert(f.Parse(true, args), jc.ErrorIsNil)
+	return jujucmd.NewFlagGroups(f)
+}
+
+var mutuallyExclusiveTests = []struct {
+	args []string
+	err  string
+}{
+	{args: nil},
+	{args: []string{"-a"}},
+	{args: []string{"-b"}},
+	{args: []string{"-c"}},
+	{args: []string{"-a", "-b"}, err: "cannot specify both -a and -b"},
+	{args: []string{"-b", "-c"}, err: "cannot specify both -b and -c"},
+	{args: []string{"-c", "-a"}, err: "cannot specify both -a and -c"},
+	{args: []string{"-a", "-b", "-c"}, err: "cannot specify -a, -b and -c together"},
+}
+
+func (*flagGroupsSuite) TestMutuallyExclusive(c *gc.C) {
+	for i, test := range mutuallyExclusiveTests {
+		c.Logf("test %d: %q", i, test.args)
+		err := parseGroups(c, test.args...).MutuallyExclusive("a", "b", "c").Check()
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+		} else {
+			c.Check(err, jc.ErrorIsNil)
+		}
+	}
+}
+
+var requireOneTests = []struct {
+	names []string
+	args  []string
+	err   string
+}{
+	{names: []string{"a"}, err: "-a is required"},
+	{names: []string{"a"}, args: []string{"-a"}},
+	{names: []string{"a"}, args: []string{"-b"}, err: "-a is required"},
+	{names: []string{"a", "b"}, err: "either -a or -b is required"},
+	{names: []string{"a", "b"}, args: []string{"-a"}},
+	{names: []string{"a", "b"}, args: []string{"-b"}},
+	{names: []string{"a", "b"}, args: []string{"-a", "-b"}},
+	{names: []string{"a", "b"}, args: []string{"-c"}, err: "either -a or -b is required"},
+	{names: []string{"a", "b", "c"}, err: "one of -a, -b or -c is required"},
+	{names: []string{"a", "b", "c"}, args: []string{"-c"}},
+}
+
+func (*flagGroupsSuite) TestRequireOne(c *gc.C) {
+	for i, test := range requireOneTests {
+		c.Logf("test %d: %q %q", i, test.names, test.args)
+		err := parseGroups(c, test.args...).RequireOne(test.names...).Check()
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+		} else {
+			c.Check(err, jc.ErrorIsNil)
+		}
+	}
+}
+
+func (*flagGroupsSuite) TestUndefinedFlagPanics(c *gc.C) {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	f.Bool("a", false, "")
+	g := jujucmd.NewFlagGroups(f)
+	c.Assert(func() { g.MutuallyExclusive("a", "nope") }, gc.PanicMatches, `flag group refers to undefined flag "nope"`)
+	c.Assert(func() { g.MutuallyExclusive("a") }, gc.PanicMatches, "mutually exclusive flag group needs at least two flags")
+}
+
+func (*flagGroupsSuite) TestShowInHelp(c *gc.C) {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	f.String("to", "", "Placement directive")
+	f.String("constraints", "", "Machine constraints")
+	var file string
+	f.StringVar(&file, "f", "", "Machine definition file")
+	f.StringVar(&file, "file", "", "")
+	f.String("data", "", "Machine definition")
+	jujucmd.NewFlagGroups(f).
+		MutuallyExclusive("to", "constraints").
+		ShowInHelp().
+		RequireOne("file", "data")
+	usage := func(name string) string {
+		return f.Lookup(name).Usage
+	}
+	c.Check(usage("to"), gc.Equals, "Placement directive (cannot be used with --constraints)")
+	c.Check(usage("constraints"), gc.Equals, "Machine constraints (cannot be used with --to)")
+	c.Check(usage("f"), gc.Equals, "Machine definition file (required unless --data is given)")
+	c.Check(usage("file"), gc.Equals, "")
+	c.Check(usage("data"), gc.Equals, "Machine definition (required unless --file is given)")
+}
+
+func (*flagGroupsSuite) TestHelpUnchangedByDefault(c *gc.C) {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	f.String("to", "", "Placement directive")
+	f.String("constraints", "", "Machine constraints")
+	jujucmd.NewFlagGroups(f).MutuallyExclusive("to", "constraints")
+	c.Check(f.Lookup("to").Usage, gc.Equals, "Placement directive")
+}
@@ -0,0 +1,266 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// StringsValue implements gnuflag.Value for a comma separated list of
+// strings. Each occurrence of the flag replaces the whole value, so
+// only the last occurrence on the command line takes effect.
+type StringsValue []string
+
+var _ gnuflag.Value = (*StringsValue)(nil)
+
+// NewStringsValue is used to create the type passed into the
+// gnuflag.FlagSet Var function.
+func NewStringsValue(defaultValue []string, target *[]string) *StringsValue {
+	value := (*StringsValue)(target)
+	*value = defaultValue
+	return value
+}
+
+// Set implements gnuflag.Value. Empty elements, such as those left by a
+// trailing comma, are dropped, and the empty string resets the value
+// to nil.
+func (v *StringsValue) Set(s string) error {
+	*v = splitStrings(s)
+	return nil
+}
+
+// String implements gnuflag.Value.
+func (v *StringsValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+// AppendStringsValue implements gnuflag.Value for a list of strings
+// that may be specified more than once. Each occurrence of the flag
+// appends its comma separated elements to the value.
+type AppendStringsValue []string
+
+var _ gnuflag.Value = (*AppendStringsValue)(nil)
+
+// NewAppendStringsValue is used to create the type passed into the
+// gnuflag.FlagSet Var function.
+func NewAppendStringsValue(target *[]string) *AppendStringsValue {
+	return (*AppendStringsValue)(target)
+}
+
+// Set implements gnuflag.Value. Empty elements are dropped, and the
+// empty string resets the value to nil so that a default may be
+// cleared from the command line.
+func (v *AppendStringsValue) Set(s string) error {
+	values := splitStrings(s)
+	if values == nil {
+		*v = nil
+		return nil
+	}
+	*v = append(*v, values...)
+	return nil
+}
+
+// String implements gnuflag.Value.
+func (v *AppendStringsValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+// splitStrings splits s on commas, discarding empty elements. It
+// returns nil if no elements remain.
+func splitStrings(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// DurationValue implements gnuflag.Value for a non-negative duration,
+// such as a timeout, written as accepted by time.ParseDuration
+// ("30s", "5m", "1h30m").
+type DurationValue time.Duration
+
+var _ gnuflag.Value = (*DurationValue)(nil)
+
+// NewDurationValue is used to create the type passed into the
+// gnuflag.FlagSet Var function.
+func NewDurationValue(defaultValue time.Duration, target *time.Duration) *DurationValue {
+	value := (*DurationValue)(target)
+	*value = DurationValue(defaultValue)
+	return value
+}
+
+// Set implements gnuflag.Value. A number without a unit is rejected,
+// rather than guessing whether seconds or minutes were meant, except
+// for "0".
+func (v *DurationValue) Set(s string) error {
+	d, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = DurationValue(d)
+	return nil
+}
+
+// String implements gnuflag.Value, rendering the value as by
+// FormatDuration.
+func (v *DurationValue) String() string {
+	return FormatDuration(time.Duration(*v))
+}
+
+// ParseDuration parses s as a non-negative duration, as does a
+// DurationValue.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New(`expected a duration such as "30s" or "5m"`)
+	}
+	if strings.HasPrefix(s, "-") {
+		return 0, errors.Errorf("duration %q must not be negative", s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil && s != "0" {
+		return 0, errors.Errorf("duration %q has no unit; use for example %q or %q", s, s+"s", s+"m")
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Errorf("expected a duration such as \"30s\" or \"5m\", got %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration returns d as written on the command line, without the
+// zero minutes and seconds that time.Duration.String includes: one
+// hour is "1h" rather than "1h0m0s".
+func FormatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// SizeValue implements gnuflag.Value for a size, such as an amount of
+// memory or disk, held in megabytes (MiB). Sizes are written as a
+// non-negative number with an optional suffix: K, M, G, T or P, or
+// the same followed by "iB". All units are binary and are not case
+// sensitive, so "1g", "1G" and "1GiB" are all 1024 megabytes; a number
+// without a suffix is in megabytes. Decimal units such as "GB" are
+// rejected because they are ambiguous.
+//
+// Sizes that are not a whole number of megabytes, such as "0.1G" or
+// "512K", are rounded up to the next megabyte, so the value is never
+// smaller than the size requested.
+type SizeValue uint64
+
+var _ gnuflag.Value = (*SizeValue)(nil)
+
+// NewSizeValue is used to create the type passed into the
+// gnuflag.FlagSet Var function. The default value and target are in
+// megabytes.
+func NewSizeValue(defaultValue uint64, target *uint64) *SizeValue {
+	value := (*SizeValue)(target)
+	*value = SizeValue(defaultValue)
+	return value
+}
+
+// Set implements gnuflag.Value.
+func (v *SizeValue) Set(s string) error {
+	size, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*v = SizeValue(size)
+	return nil
+}
+
+// String implements gnuflag.Value, rendering the value as by
+// FormatSize.
+func (v *SizeValue) String() string {
+	return FormatSize(uint64(*v))
+}
+
+var sizePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?|\.[0-9]+) *([a-zA-Z]*)$`)
+
+// sizeUnits holds the number of megabytes in each unit accepted by
+// ParseSize, keyed by the upper case suffix.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"K":   1.0 / 1024,
+	"KIB": 1.0 / 1024,
+	"M":   1,
+	"MIB": 1,
+	"G":   1 << 10,
+	"GIB": 1 << 10,
+	"T":   1 << 20,
+	"TIB": 1 << 20,
+	"P":   1 << 30,
+	"PIB": 1 << 30,
+}
+
+// ParseSize parses s as a size in megabytes, as does a SizeValue.
+func ParseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return 0, errors.Errorf("size %q must not be negative", s)
+	}
+	match := sizePattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, errors.Errorf("expected a size such as \"512M\" or \"2G\", got %q", s)
+	}
+	unit := strings.ToUpper(match[2])
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		// "GB" may mean either 1000 or 1024 megabytes.
+		if prefix := strings.TrimSuffix(unit, "B"); prefix != unit && prefix != "" {
+			if _, ok := sizeUnits[prefix]; ok {
+				return 0, errors.Errorf("size unit %q is ambiguous; use %q or %q", match[2], prefix, prefix+"iB")
+			}
+		}
+		return 0, errors.Errorf("unknown size unit %q; use K, M, G, T or P", match[2])
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, errors.Errorf("expected a size such as \"512M\" or \"2G\", got %q", s)
+	}
+	size := math.Ceil(value * multiplier)
+	if size >= math.MaxUint64 {
+		return 0, errors.Errorf("size %q is too large", s)
+	}
+	return uint64(size), nil
+}
+
+// FormatSize returns a size in megabytes as written on the command
+// line, in the largest unit that holds it exactly: 2048 is "2G" and
+// 1536 is "1536M".
+func FormatSize(size uint64) string {
+	if size == 0 {
+		return "0"
+	}
+	for _, unit := range []struct {
+		suffix string
+		size   uint64
+	}{
+		{"P", 1 << 30},
+		{"T", 1 << 20},
+		{"G", 1 << 10},
+	} {
+		if size%unit.size == 0 {
+			return fmt.Sprintf("%d%s", size/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dM", size)
+}
@@ -0,0 +1,369 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+)
+
+type flagsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&flagsSuite{})
+
+// listCommand shows how the list flag values are wired up in SetFlags.
+type listCommand struct {
+	cmd.CommandBase
+	series []string
+	to     []string
+}
+
+func (c *listCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "list"}
+}
+
+func (c *listCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.Var(jujucmd.NewStringsValue([]string{"xenial"}, &c.series), "series", "Comma separated series")
+	f.Var(jujucmd.NewAppendStringsValue(&c.to), "to", "Placement directives")
+}
+
+func (c *listCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+func (c *listCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func parseList(c *gc.C, args ...string) *listCommand {
+	command := &listCommand{}
+	f := gnuflag.NewFlagSet("list", gnuflag.ContinueOnError)
+	command.SetFlags(f)
+	err := cmd.ParseArgs(command, f, args)
+	c.Assert(err, jc.ErrorIsNil)
+	return command
+}
+
+var stringsValueTests = []struct {
+	about    string
+	args     []string
+	expected []string
+}{{
+	about:    "default",
+	expected: []string{"xenial"},
+}, {
+	about:    "single value",
+	args:     []string{"--series", "trusty"},
+	expected: []string{"trusty"},
+}, {
+	about:    "comma separated",
+	args:     []string{"--series", "trusty,xenial"},
+	expected: []string{"trusty", "xenial"},
+}, {
+	about:    "trailing comma",
+	args:     []string{"--series", "trusty,,xenial,"},
+	expected: []string{"trusty", "xenial"},
+}, {
+	about:    "last occurrence wins",
+	args:     []string{"--series", "trusty", "--series", "precise,yakkety"},
+	expected: []string{"precise", "yakkety"},
+}, {
+	about: "empty resets",
+	args:  []string{"--series", ""},
+}}
+
+func (*flagsSuite) TestStringsValue(c *gc.C) {
+	for i, test := range stringsValueTests {
+		c.Logf("test %d: %s", i, test.about)
+		command := parseList(c, test.args...)
+		c.Check(command.series, jc.DeepEquals, test.expected)
+	}
+}
+
+var appendStringsValueTests = []struct {
+	about    string
+	args     []string
+	expected []string
+}{{
+	about: "default",
+}, {
+	about:    "single value",
+	args:     []string{"--to", "0"},
+	expected: []string{"0"},
+}, {
+	about:    "occurrences append",
+	args:     []string{"--to", "0", "--to", "lxd:1,2"},
+	expected: []string{"0", "lxd:1", "2"},
+}, {
+	about:    "trailing comma",
+	args:     []string{"--to", "0,", "--to", ",1"},
+	expected: []string{"0", "1"},
+}, {
+	about:    "empty resets",
+	args:     []string{"--to", "0", "--to", "", "--to", "1"},
+	expected: []string{"1"},
+}}
+
+func (*flagsSuite) TestAppendStringsValue(c *gc.C) {
+	for i, test := range appendStringsValueTests {
+		c.Logf("test %d: %s", i, test.about)
+		command := parseList(c, test.args...)
+		c.Check(command.to, jc.DeepEquals, test.expected)
+	}
+}
+
+func (*flagsSuite) TestString(c *gc.C) {
+	var series, to []string
+	c.Assert(jujucmd.NewStringsValue([]string{"trusty", "xenial"}, &series).String(), gc.Equals, "trusty,xenial")
+	c.Assert(jujucmd.NewAppendStringsValue(&to).String(), gc.Equals, "")
+	to = []string{"0", "1"}
+	c.Assert(jujucmd.NewAppendStringsValue(&to).String(), gc.Equals, "0,1")
+}
+
+// limitsCommand shows how the duration and size flag values are wired
+// up in SetFlags.
+type limitsCommand struct {
+	cmd.CommandBase
+	timeout time.Duration
+	memory  uint64
+}
+
+func (c *limitsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "limits"}
+}
+
+func (c *limitsCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.Var(jujucmd.NewDurationValue(5*time.Minute, &c.timeout), "timeout", "Time to wait")
+	f.Var(jujucmd.NewSizeValue(512, &c.memory), "mem", "Memory to allocate")
+}
+
+func (c *limitsCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+func (c *limitsCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func parseLimits(args ...string) (*limitsCommand, error) {
+	command := &limitsCommand{}
+	f := gnuflag.NewFlagSet("limits", gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	command.SetFlags(f)
+	return command, cmd.ParseArgs(command, f, args)
+}
+
+var durationValueTests = []struct {
+	value    string
+	expected time.Duration
+	err      string
+}{{
+	value:    "30s",
+	expected: 30 * time.Second,
+}, {
+	value:    "1h30m",
+	expected: 90 * time.Minute,
+}, {
+	value:    "1.5h",
+	expected: 90 * time.Minute,
+}, {
+	value:    "250ms",
+	expected: 250 * time.Millisecond,
+}, {
+	value:    " 2m ",
+	expected: 2 * time.Minute,
+}, {
+	value: "0",
+}, {
+	value: "0s",
+}, {
+	value: "90",
+	err:   `duration "90" has no unit; use for example "90s" or "90m"`,
+}, {
+	value: "1.5",
+	err:   `duration "1.5" has no unit; use for example "1.5s" or "1.5m"`,
+}, {
+	value: "-5m",
+	err:   `duration "-5m" must not be negative`,
+}, {
+	value: "5 minutes",
+	err:   `expected a duration such as "30s" or "5m", got "5 minutes"`,
+}, {
+	value: "1H",
+	err:   `expected a duration such as "30s" or "5m", got "1H"`,
+}, {
+	value: "",
+	err:   `expected a duration such as "30s" or "5m"`,
+}}
+
+func (*flagsSuite) TestDurationValue(c *gc.C) {
+	command, err := parseLimits()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(command.timeout, gc.Equals, 5*time.Minute)
+
+	for i, test := range durationValueTests {
+		c.Logf("test %d: %q", i, test.value)
+		command, err := parseLimits("--timeout", test.value)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, `invalid value ".*" for flag --timeout: `+regexp.QuoteMeta(test.err))
+			continue
+		}
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(command.timeout, gc.Equals, test.expected)
+	}
+}
+
+var sizeValueTests = []struct {
+	value    string
+	expected uint64
+	err      string
+}{{
+	value:    "512",
+	expected: 512,
+}, {
+	value:    "512M",
+	expected: 512,
+}, {
+	value:    "512MiB",
+	expected: 512,
+}, {
+	value:    "2G",
+	expected: 2048,
+}, {
+	value:    "1g",
+	expected: 1024,
+}, {
+	value:    "1G",
+	expected: 1024,
+}, {
+	value:    "1gib",
+	expected: 1024,
+}, {
+	value:    "1.5GiB",
+	expected: 1536,
+}, {
+	value:    "1.5 G",
+	expected: 1536,
+}, {
+	value:    ".5G",
+	expected: 512,
+}, {
+	value:    "1T",
+	expected: 1024 * 1024,
+}, {
+	value:    "2P",
+	expected: 2 * 1024 * 1024 * 1024,
+}, {
+	value: "0",
+}, {
+	value:    "0.1G",
+	expected: 103, // 102.4 rounds up
+}, {
+	value:    "512K",
+	expected: 1, // 0.5 rounds up
+}, {
+	value:    "1.0001M",
+	expected: 2,
+}, {
+	value: "-1G",
+	err:   `size "-1G" must not be negative`,
+}, {
+	value: "1GB",
+	err:   `size unit "GB" is ambiguous; use "G" or "GiB"`,
+}, {
+	value: "1gb",
+	err:   `size unit "gb" is ambiguous; use "G" or "GiB"`,
+}, {
+	value: "100MB",
+	err:   `size unit "MB" is ambiguous; use "M" or "MiB"`,
+}, {
+	value: "1X",
+	err:   `unknown size unit "X"; use K, M, G, T or P`,
+}, {
+	value: "1B",
+	err:   `unknown size unit "B"; use K, M, G, T or P`,
+}, {
+	value: "G",
+	err:   `expected a size such as "512M" or "2G", got "G"`,
+}, {
+	value: "1,5G",
+	err:   `expected a size such as "512M" or "2G", got "1,5G"`,
+}, {
+	value: "",
+	err:   `expected a size such as "512M" or "2G", got ""`,
+}, {
+	value: "99999999999999999999P",
+	err:   `size "99999999999999999999P" is too large`,
+}}
+
+func (*flagsSuite) TestSizeValue(c *gc.C) {
+	command, err := parseLimits()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(command.memory, gc.Equals, uint64(512))
+
+	for i, test := range sizeValueTests {
+		c.Logf("test %d: %q", i, test.value)
+		command, err := parseLimits("--mem", test.value)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, `invalid value ".*" for flag --mem: `+regexp.QuoteMeta(test.err))
+			continue
+		}
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(command.memory, gc.Equals, test.expected)
+	}
+}
+
+func (*flagsSuite) TestFormatDuration(c *gc.C) {
+	for _, test := range []struct {
+		value    time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{time.Hour + time.Second, "1h0m1s"},
+		{250 * time.Millisecond, "250ms"},
+	} {
+		c.Check(jujucmd.FormatDuration(test.value), gc.Equals, test.expected)
+	}
+}
+
+func (*flagsSuite) TestFormatSize(c *gc.C) {
+	for _, test := range []struct {
+		value    uint64
+		expected string
+	}{
+		{0, "0"},
+		{1, "1M"},
+		{512, "512M"},
+		{1536, "1536M"},
+		{2048, "2G"},
+		{1024 * 1024, "1T"},
+		{3 * 1024 * 1024 * 1024, "3P"},
+	} {
+		c.Check(jujucmd.FormatSize(test.value), gc.Equals, test.expected)
+	}
+}
+
+func (*flagsSuite) TestDefaultsInHelp(c *gc.C) {
+	var timeout time.Duration
+	var memory uint64
+	c.Assert(jujucmd.NewDurationValue(time.Hour, &timeout).String(), gc.Equals, "1h")
+	c.Assert(timeout, gc.Equals, time.Hour)
+	c.Assert(jujucmd.NewSizeValue(4096, &memory).String(), gc.Equals, "4G")
+	c.Assert(memory, gc.Equals, uint64(4096))
+}
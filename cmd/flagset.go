@@ -0,0 +1,182 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// FlagSet wraps a gnuflag.FlagSet to add support for renaming flags.
+// The methods of gnuflag.FlagSet are available through embedding, so
+// flags are defined on a FlagSet exactly as on a gnuflag.FlagSet.
+// Flag descriptions are translated when help is shown, as described
+// in SetTranslator.
+type FlagSet struct {
+	*gnuflag.FlagSet
+	aliases []*flagAlias
+}
+
+// flagAlias records a deprecated name for a flag, and the values given
+// for it on the command line.
+type flagAlias struct {
+	old, new string
+	target   gnuflag.Value
+	values   []string
+}
+
+// NewFlagSet returns a FlagSet that defines flags in f.
+func NewFlagSet(f *gnuflag.FlagSet) *FlagSet {
+	return &FlagSet{FlagSet: f}
+}
+
+// RegisterDeprecatedAlias defines old as a deprecated name for the
+// flag new, which must already be defined. A value given with the old
+// name sets the same value as the new name does, once ResolveAliases
+// is called. RegisterDeprecatedAlias panics if old is already defined.
+func (f *FlagSet) RegisterDeprecatedAlias(old, new string) {
+	flag := f.Lookup(new)
+	if flag == nil {
+		panic(fmt.Sprintf("deprecated alias %q refers to undefined flag %q", old, new))
+	}
+	alias := &flagAlias{old: old, new: new, target: flag.Value}
+	f.Var(&aliasValue{alias}, old, fmt.Sprintf(T("Deprecated; use %s instead"), joinFlags([]string{new}, "")))
+	f.aliases = append(f.aliases, alias)
+}
+
+// DeprecatedAliases returns the names of the deprecated aliases
+// registered with f, so that they can be hidden from help.
+func (f *FlagSet) DeprecatedAliases() []string {
+	names := make([]string, len(f.aliases))
+	for i, alias := range f.aliases {
+		names[i] = alias.old
+	}
+	return names
+}
+
+// ResolveAliases sets the flags given on the command line by a
+// deprecated name. It returns an error if a flag was also given by
+// another name with a different value. It must be called after the
+// flag set has been parsed.
+func (f *FlagSet) ResolveAliases() error {
+	given := make(map[gnuflag.Value]bool)
+	f.Visit(func(flag *gnuflag.Flag) {
+		given[flag.Value] = true
+	})
+	for _, alias := range f.aliases {
+		if len(alias.values) == 0 {
+			continue
+		}
+		before := alias.target.String()
+		for _, value := range alias.values {
+			if err := alias.target.Set(value); err != nil {
+				return errors.Annotatef(err, "invalid value %q for flag %s", value, joinFlags([]string{alias.old}, ""))
+			}
+		}
+		if given[alias.target] && alias.target.String() != before {
+			return errors.Errorf("cannot specify both %s with different values", joinFlags([]string{alias.new, alias.old}, "and"))
+		}
+	}
+	return nil
+}
+
+// WarnDeprecated writes a warning to ctx.Stderr for each deprecated
+// alias given on the command line, naming the flag to use instead.
+// Each warning is shown at most once a day, as described in WarnOnce.
+func (f *FlagSet) WarnDeprecated(ctx *cmd.Context) {
+	for _, alias := range f.aliases {
+		if len(alias.values) > 0 {
+			WarnOnce(ctx, "flag-alias:"+alias.old+":"+alias.new,
+				T("WARNING flag %s is deprecated, please use %s\n"),
+				joinFlags([]string{alias.old}, ""), joinFlags([]string{alias.new}, ""))
+		}
+	}
+}
+
+// aliasValue is the gnuflag.Value of a deprecated alias. It records
+// the values given so that they can be checked against the flag's
+// other names before they are applied.
+type aliasValue struct {
+	alias *flagAlias
+}
+
+// Set implements gnuflag.Value.
+func (v *aliasValue) Set(s string) error {
+	v.alias.values = append(v.alias.values, s)
+	return nil
+}
+
+// String implements gnuflag.Value.
+func (v *aliasValue) String() string {
+	return v.alias.target.String()
+}
+
+// IsBoolFlag allows an alias for a boolean flag to be given without a
+// value, as the flag itself can be.
+func (v *aliasValue) IsBoolFlag() bool {
+	b, ok := v.alias.target.(interface {
+		IsBoolFlag() bool
+	})
+	return ok && b.IsBoolFlag()
+}
+
+// FlagSetCommand is implemented by commands that define their flags
+// on a FlagSet, to be used with WrapFlagSet.
+type FlagSetCommand interface {
+	cmd.Command
+
+	// SetFlagSet defines the command's flags on f. It is called
+	// in place of SetFlags.
+	SetFlagSet(f *FlagSet)
+}
+
+// WrapFlagSet wraps a command so that its flags are defined on a
+// FlagSet. Deprecated aliases are hidden from help and resolved after
+// the flags are parsed and before the command's Init method is called,
+// so that conflicting values are reported as usage errors. A warning
+// is written to stderr for each alias used when the command is run,
+// at most once a day.
+func WrapFlagSet(c FlagSetCommand) cmd.Command {
+	return &flagSetCommand{FlagSetCommand: c}
+}
+
+type flagSetCommand struct {
+	FlagSetCommand
+	flags *FlagSet
+}
+
+// SetFlags implements cmd.Command.
+func (c *flagSetCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.flags = NewFlagSet(f)
+	c.FlagSetCommand.SetFlagSet(c.flags)
+}
+
+// HiddenFlags implements HiddenFlagsCommand.
+func (c *flagSetCommand) HiddenFlags() []string {
+	var hidden []string
+	if c.flags != nil {
+		hidden = c.flags.DeprecatedAliases()
+	}
+	if hc, ok := c.FlagSetCommand.(HiddenFlagsCommand); ok {
+		hidden = append(hidden, hc.HiddenFlags()...)
+	}
+	return hidden
+}
+
+// Init implements cmd.Command.
+func (c *flagSetCommand) Init(args []string) error {
+	if err := c.flags.ResolveAliases(); err != nil {
+		return err
+	}
+	return c.FlagSetCommand.Init(args)
+}
+
+// Run implements cmd.Command.
+func (c *flagSetCommand) Run(ctx *cmd.Context) error {
+	c.flags.WarnDeprecated(ctx)
+	return c.FlagSetCommand.Run(ctx)
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type flagSetSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&flagSetSuite{})
+
+// destroyModelCommand has had its --environment flag renamed to
+// --model, and --force renamed to --yes.
+type destroyModelCommand struct {
+	cmd.CommandBase
+	model string
+	yes   bool
+}
+
+func (c *destroyModelCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "destroy-model"}
+}
+
+func (c *destroyModelCommand) SetFlagSet(f *jujucmd.FlagSet) {
+	f.StringVar(&c.model, "m", "default", "Model to operate in")
+	f.StringVar(&c.model, "model", "default", "")
+	f.BoolVar(&c.yes, "yes", false, "Do not prompt for confirmation")
+	f.RegisterDeprecatedAlias("environment", "model")
+	f.RegisterDeprecatedAlias("force", "yes")
+}
+
+func (c *destroyModelCommand) Run(ctx *cmd.Context) error {
+	fmt.Fprintf(ctx.Stdout, "%s %v\n", c.model, c.yes)
+	return nil
+}
+
+func newDestroyModelCommand() cmd.Command {
+	return jujucmd.WrapFlagSet(&destroyModelCommand{})
+}
+
+var flagSetTests = []struct {
+	about    string
+	args     []string
+	expected string
+	warnings []string
+	err      string
+}{{
+	about:    "defaults",
+	expected: "default false\n",
+}, {
+	about:    "new names",
+	args:     []string{"--model", "foo", "--yes"},
+	expected: "foo true\n",
+}, {
+	about:    "old names",
+	args:     []string{"--environment", "foo", "--force"},
+	expected: "foo true\n",
+	warnings: []string{"--environment", "--force"},
+}, {
+	about:    "old name given twice",
+	args:     []string{"--environment", "foo", "--environment", "bar"},
+	expected: "bar false\n",
+	warnings: []string{"--environment"},
+}, {
+	about:    "both names with the same value",
+	args:     []string{"--model", "foo", "--environment", "foo"},
+	expected: "foo false\n",
+	warnings: []string{"--environment"},
+}, {
+	about: "both names with different values",
+	args:  []string{"--model", "foo", "--environment", "bar"},
+	err:   "cannot specify both --model and --environment with different values",
+}, {
+	about: "short name and old name with different values",
+	args:  []string{"--environment", "bar", "-m", "foo"},
+	err:   "cannot specify both --model and --environment with different values",
+}, {
+	about: "invalid value for old name",
+	args:  []string{"--force=maybe"},
+	err:   `invalid value "maybe" for flag --force: .*`,
+}}
+
+func (s *flagSetSuite) TestAliases(c *gc.C) {
+	for i, test := range flagSetTests {
+		c.Logf("test %d: %s", i, test.about)
+		ctx, err := coretesting.RunCommand(c, newDestroyModelCommand(), test.args...)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, test.expected)
+		var expectedStderr string
+		for _, old := range test.warnings {
+			replacement := map[string]string{"--environment": "--model", "--force": "--yes"}[old]
+			expectedStderr += fmt.Sprintf("WARNING flag %s is deprecated, please use %s\n", old, replacement)
+		}
+		c.Check(coretesting.Stderr(ctx), gc.Equals, expectedStderr)
+	}
+}
+
+func (s *flagSetSuite) TestConflictIsUsageError(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newDestroyModelCommand(), ctx, []string{"--model", "foo", "--environment", "bar"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "cannot specify both --model and --environment with different values")
+}
+
+func (s *flagSetSuite) TestAliasesHiddenFromHelp(c *gc.C) {
+	help := string(jujucmd.CommandHelp(newDestroyModelCommand(), "destroy-model", false))
+	c.Assert(help, jc.Contains, "--model")
+	c.Assert(help, gc.Not(jc.Contains), "environment")
+	c.Assert(help, gc.Not(jc.Contains), "force")
+
+	help = string(jujucmd.CommandHelp(newDestroyModelCommand(), "destroy-model", true))
+	c.Assert(strings.Count(help, "Deprecated; use"), gc.Equals, 2)
+}
+
+func (s *flagSetSuite) TestUndefinedFlagPanics(c *gc.C) {
+	f := jujucmd.NewFlagSet(coretesting.NewFlagSet())
+	c.Assert(func() { f.RegisterDeprecatedAlias("environment", "model") }, gc.PanicMatches,
+		`deprecated alias "environment" refers to undefined flag "model"`)
+}
@@ -0,0 +1,168 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/juju/cmd"
+)
+
+// otherGroup is the group of the commands registered without one,
+// which is listed last.
+const otherGroup = "other"
+
+// GroupedSuperCommand is a cmd.SuperCommand whose help lists its
+// subcommands under a heading for each group, rather than in one long
+// list. Groups are listed in the order in which their first command
+// was registered, and commands registered without a group are listed
+// last, under "other". Each group is also a help topic, so that
+// "help <group>" lists just the commands in that group.
+//
+// The flat list of all commands remains available, for searching, as
+// the "commands" help topic.
+type GroupedSuperCommand struct {
+	*cmd.SuperCommand
+
+	mu     sync.Mutex
+	order  []string
+	groups map[string][]groupedCommand
+}
+
+// groupedCommand holds the details of a command shown in the listing
+// of its group.
+type groupedCommand struct {
+	name    string
+	purpose string
+}
+
+// NewGroupedSuperCommand returns a GroupedSuperCommand wrapping super.
+func NewGroupedSuperCommand(super *cmd.SuperCommand) *GroupedSuperCommand {
+	return &GroupedSuperCommand{
+		SuperCommand: super,
+		groups:       make(map[string][]groupedCommand),
+	}
+}
+
+// Register overrides cmd.SuperCommand.Register so that c is listed in
+// the "other" group.
+func (s *GroupedSuperCommand) Register(c cmd.Command) {
+	s.RegisterInGroup(otherGroup, c)
+}
+
+// RegisterInGroup registers c with the supercommand, listing it in the
+// given group. Registering a group with the same name as a command
+// panics, as "help <group>" would show the command's help instead.
+func (s *GroupedSuperCommand) RegisterInGroup(group string, c cmd.Command) {
+	if group == "" {
+		group = otherGroup
+	}
+	info := c.Info()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[info.Name]; ok {
+		panic(fmt.Sprintf("command %q has the same name as a command group", info.Name))
+	}
+	for _, commands := range s.groups {
+		for _, command := range commands {
+			if command.name == group {
+				panic(fmt.Sprintf("command group %q has the same name as a command", group))
+			}
+		}
+	}
+	s.SuperCommand.Register(c)
+	if _, ok := s.groups[group]; !ok {
+		s.order = append(s.order, group)
+		s.SuperCommand.AddHelpTopicCallback(group, fmt.Sprintf("List the %s commands", group), func() string {
+			return s.describeGroup(group)
+		})
+	}
+	s.groups[group] = append(s.groups[group], groupedCommand{
+		name:    info.Name,
+		purpose: info.Purpose,
+	})
+}
+
+// Info implements cmd.Command. When no subcommand has been chosen, the
+// description of the supercommand lists the subcommands by group.
+func (s *GroupedSuperCommand) Info() *cmd.Info {
+	info := s.SuperCommand.Info()
+	if info.Name != s.Name {
+		// The info is that of the chosen subcommand.
+		return info
+	}
+	var docParts []string
+	if doc := strings.TrimSpace(s.Doc); doc != "" {
+		docParts = append(docParts, doc)
+	}
+	if groups := s.describeGroups(); groups != "" {
+		docParts = append(docParts, groups)
+	}
+	info.Doc = strings.Join(docParts, "\n\n")
+	return info
+}
+
+// describeGroups returns the listing of every group, each under its
+// own heading.
+func (s *GroupedSuperCommand) describeGroups() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sections []string
+	for _, group := range s.orderedGroups() {
+		sections = append(sections, s.listGroup(group))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// describeGroup returns the listing of the commands in the given
+// group, as shown by "help <group>".
+func (s *GroupedSuperCommand) describeGroup(group string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listGroup(group)
+}
+
+// orderedGroups returns the names of the groups in the order in which
+// they are listed. It must be called with s.mu held.
+func (s *GroupedSuperCommand) orderedGroups() []string {
+	groups := make([]string, 0, len(s.order))
+	for _, group := range s.order {
+		if group != otherGroup {
+			groups = append(groups, group)
+		}
+	}
+	if _, ok := s.groups[otherGroup]; ok {
+		groups = append(groups, otherGroup)
+	}
+	return groups
+}
+
+// listGroup returns the commands in the given group under a heading,
+// sorted by name, with their purposes aligned. It must be called with
+// s.mu held.
+func (s *GroupedSuperCommand) listGroup(group string) string {
+	commands := append(byName(nil), s.groups[group]...)
+	sort.Sort(commands)
+	longest := 0
+	for _, command := range commands {
+		if len(command.name) > longest {
+			longest = len(command.name)
+		}
+	}
+	lines := make([]string, len(commands))
+	for i, command := range commands {
+		lines[i] = fmt.Sprintf("    %-*s - %s", longest, command.name, command.purpose)
+	}
+	return group + ":\n" + strings.Join(lines, "\n")
+}
+
+// byName sorts commands by name.
+type byName []groupedCommand
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return b[i].name < b[j].name }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
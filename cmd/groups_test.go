@@ -0,0 +1,116 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type groupsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&groupsSuite{})
+
+// purposeCommand is a command that does nothing but describe itself.
+type purposeCommand struct {
+	cmd.CommandBase
+	name    string
+	purpose string
+}
+
+func (c *purposeCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: c.name, Purpose: c.purpose}
+}
+
+func (c *purposeCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func newGroupedSuper() *jujucmd.GroupedSuperCommand {
+	super := jujucmd.NewGroupedSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Doc:  "Juju manages models.",
+	}))
+	super.RegisterInGroup("machines", &purposeCommand{name: "remove-machine", purpose: "Remove a machine."})
+	super.Register(&purposeCommand{name: "debug-log", purpose: "Show the log."})
+	super.RegisterInGroup("models", &purposeCommand{name: "add-model", purpose: "Add a model."})
+	super.RegisterInGroup("machines", &purposeCommand{name: "add-machine", purpose: "Add a machine."})
+	return super
+}
+
+func runGroupedSuper(c *gc.C, args ...string) string {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newGroupedSuper(), ctx, args)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+	return coretesting.Stdout(ctx)
+}
+
+func (s *groupsSuite) TestHelpListsGroups(c *gc.C) {
+	help := runGroupedSuper(c, "--help")
+	c.Assert(help, jc.Contains, `
+Juju manages models.
+
+machines:
+    add-machine    - Add a machine.
+    remove-machine - Remove a machine.
+
+models:
+    add-model - Add a model.
+
+other:
+    debug-log - Show the log.
+`)
+}
+
+func (s *groupsSuite) TestHelpGroup(c *gc.C) {
+	help := runGroupedSuper(c, "help", "machines")
+	c.Assert(help, gc.Equals, ""+
+		"machines:\n"+
+		"    add-machine    - Add a machine.\n"+
+		"    remove-machine - Remove a machine.\n")
+}
+
+func (s *groupsSuite) TestHelpOtherGroup(c *gc.C) {
+	help := runGroupedSuper(c, "help", "other")
+	c.Assert(help, gc.Equals, "other:\n    debug-log - Show the log.\n")
+}
+
+func (s *groupsSuite) TestHelpCommandsIsFlat(c *gc.C) {
+	help := runGroupedSuper(c, "help", "commands")
+	c.Assert(help, gc.Not(jc.Contains), "machines:")
+	lines := strings.Split(strings.TrimSpace(help), "\n")
+	var names []string
+	for _, line := range lines {
+		names = append(names, strings.Fields(line)[0])
+	}
+	c.Assert(names, jc.SameContents, []string{
+		"add-machine", "add-model", "debug-log", "help", "remove-machine",
+	})
+}
+
+func (s *groupsSuite) TestSubcommandHelpUnchanged(c *gc.C) {
+	help := runGroupedSuper(c, "add-machine", "--help")
+	c.Assert(help, jc.Contains, "Add a machine.")
+	c.Assert(help, gc.Not(jc.Contains), "models:")
+}
+
+func (s *groupsSuite) TestGroupNameConflicts(c *gc.C) {
+	super := newGroupedSuper()
+	c.Assert(func() {
+		super.RegisterInGroup("add-model", &purposeCommand{name: "destroy-model"})
+	}, gc.PanicMatches, `command group "add-model" has the same name as a command`)
+	c.Assert(func() {
+		super.Register(&purposeCommand{name: "models"})
+	}, gc.PanicMatches, `command "models" has the same name as a command group`)
+}
@@ -0,0 +1,226 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/utils/set"
+)
+
+// HiddenFlagsCommand is implemented by commands that have flags which
+// must keep working but should not be advertised in the default help
+// output, such as internal testing switches.
+type HiddenFlagsCommand interface {
+	cmd.Command
+
+	// HiddenFlags returns the names of the flags to omit from help.
+	HiddenFlags() []string
+}
+
+// VisibleFlags returns a flag set holding the flags of f that are not
+// named in hidden. The returned flag set shares values with f, and is
+// intended only for rendering help; f is still used for parsing, so
+// hidden flags continue to work.
+func VisibleFlags(f *gnuflag.FlagSet, hidden []string) *gnuflag.FlagSet {
+	if len(hidden) == 0 {
+		return f
+	}
+	hide := set.NewStrings(hidden...)
+	visible := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		if !hide.Contains(flag.Name) {
+			visible.Var(flag.Value, flag.Name, flag.Usage)
+		}
+	})
+	return visible
+}
+
+// CommandHelp returns the help text for the given command, as shown
+// when the command is invoked as name. Flags reported by a
+// HiddenFlagsCommand are left out unless all is true, which is the
+// behaviour of a verbose --help-all style request. The help is
+// translated as described in SetTranslator.
+func CommandHelp(c cmd.Command, name string, all bool) []byte {
+	return commandHelp(c, name, all, 0)
+}
+
+// commandHelp implements CommandHelp and WrappedHelp. If width is
+// positive, the paragraphs of the command's documentation are
+// re-wrapped to it, after being translated.
+func commandHelp(c cmd.Command, name string, all bool, width int) []byte {
+	info := c.Info()
+	info.Name = name
+	translateInfo(info)
+	if width > 0 {
+		info.Doc = wrap(info.Doc, width)
+	}
+	f := gnuflag.NewFlagSet(info.Name, gnuflag.ContinueOnError)
+	c.SetFlags(f)
+	if hc, ok := c.(HiddenFlagsCommand); ok && !all {
+		f = VisibleFlags(f, hc.HiddenFlags())
+	}
+	return translateHelp(info.Help(translateFlags(f)))
+}
+
+// helpAllFlag is the flag that asks for the help of a subcommand to
+// include its hidden flags.
+const helpAllFlag = "help-all"
+
+// HelpSuperCommand is a cmd.SuperCommand that shows the help of its
+// subcommands with ShowHelp, so that the flags reported by a
+// HiddenFlagsCommand are left out, unless the help is asked for with
+// "help --all <command>" or "<command> --help-all".
+type HelpSuperCommand struct {
+	*cmd.SuperCommand
+
+	flags *gnuflag.FlagSet
+
+	mu          sync.Mutex
+	subcommands map[string]cmd.Command
+
+	// help holds the subcommand help asked for, if any.
+	help *subcommandHelp
+}
+
+// subcommandHelp describes a request for the help of a subcommand.
+type subcommandHelp struct {
+	name    string
+	command cmd.Command
+	all     bool
+}
+
+// NewHelpSuperCommand returns a HelpSuperCommand wrapping super.
+func NewHelpSuperCommand(super *cmd.SuperCommand) *HelpSuperCommand {
+	return &HelpSuperCommand{
+		SuperCommand: super,
+		subcommands:  make(map[string]cmd.Command),
+	}
+}
+
+// Register overrides cmd.SuperCommand.Register so that the help of c
+// can be shown.
+func (s *HelpSuperCommand) Register(c cmd.Command) {
+	s.SuperCommand.Register(c)
+	s.addSubcommand(c)
+}
+
+// RegisterDeprecated overrides cmd.SuperCommand.RegisterDeprecated so
+// that the help of c can be shown.
+func (s *HelpSuperCommand) RegisterDeprecated(c cmd.Command, check cmd.DeprecationCheck) {
+	s.SuperCommand.RegisterDeprecated(c, check)
+	if check == nil || !check.Obsolete() {
+		s.addSubcommand(c)
+	}
+}
+
+func (s *HelpSuperCommand) addSubcommand(c cmd.Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subcommands[c.Info().Name] = c
+}
+
+// SetFlags implements cmd.Command.
+func (s *HelpSuperCommand) SetFlags(f *gnuflag.FlagSet) {
+	s.SuperCommand.SetFlags(f)
+	s.flags = f
+}
+
+// Init implements cmd.Command. A request for the help of a subcommand
+// is handled here, as described in helpRequest.
+func (s *HelpSuperCommand) Init(args []string) error {
+	if help := s.helpRequest(args); help != nil {
+		s.help = help
+		return nil
+	}
+	return s.SuperCommand.Init(args)
+}
+
+// Run implements cmd.Command.
+func (s *HelpSuperCommand) Run(ctx *cmd.Context) error {
+	if s.help != nil {
+		err := ShowHelp(ctx, s.help.command, s.Name+" "+s.help.name, s.help.all)
+		return errors.Trace(err)
+	}
+	return s.SuperCommand.Run(ctx)
+}
+
+// helpRequest returns the subcommand help asked for by args, which may
+// be "help [--all] <command>", or "<command>" with --help given to the
+// supercommand, or "<command>" followed by --help or --help-all among
+// its flags. It returns nil if args ask for anything else, or name a
+// supercommand, an alias or a help topic, which are left to
+// cmd.SuperCommand.
+func (s *HelpSuperCommand) helpRequest(args []string) *subcommandHelp {
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] == "help" {
+		all := false
+		var names []string
+		for _, arg := range args[1:] {
+			if arg == "--all" {
+				all = true
+			} else {
+				names = append(names, arg)
+			}
+		}
+		if len(names) != 1 {
+			return nil
+		}
+		return s.subcommandHelp(names[0], all)
+	}
+	help := s.subcommandHelp(args[0], false)
+	if help == nil {
+		return nil
+	}
+	if s.helpFlagSet() {
+		if len(args) == 1 {
+			return help
+		}
+		return nil
+	}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--":
+			return nil
+		case "-h", "--help":
+			return help
+		case "--" + helpAllFlag:
+			help.all = true
+			return help
+		}
+		if !strings.HasPrefix(arg, "-") && !help.command.AllowInterspersedFlags() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// subcommandHelp returns a request for the help of the named
+// subcommand, or nil if there is no such subcommand, or it is itself a
+// supercommand.
+func (s *HelpSuperCommand) subcommandHelp(name string, all bool) *subcommandHelp {
+	s.mu.Lock()
+	c, ok := s.subcommands[name]
+	s.mu.Unlock()
+	if !ok || c.IsSuperCommand() {
+		return nil
+	}
+	return &subcommandHelp{name: name, command: c, all: all}
+}
+
+// helpFlagSet reports whether --help was given to the supercommand
+// itself.
+func (s *HelpSuperCommand) helpFlagSet() bool {
+	if s.flags == nil {
+		return false
+	}
+	flag := s.flags.Lookup("help")
+	return flag != nil && flag.Value.String() == "true"
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type helpSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&helpSuite{})
+
+type hiddenFlagsCommand struct {
+	cmd.CommandBase
+	series      string
+	uploadTools bool
+}
+
+func (c *hiddenFlagsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "bootstrap", Purpose: "Start a controller."}
+}
+
+func (c *hiddenFlagsCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.series, "series", "", "The series to bootstrap")
+	f.BoolVar(&c.uploadTools, "upload-tools", false, "Upload local tools")
+}
+
+func (c *hiddenFlagsCommand) HiddenFlags() []string {
+	return []string{"upload-tools"}
+}
+
+func (c *hiddenFlagsCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (*helpSuite) TestHiddenFlagParses(c *gc.C) {
+	command := &hiddenFlagsCommand{}
+	err := coretesting.InitCommand(command, []string{"--upload-tools", "--series", "xenial"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(command.uploadTools, jc.IsTrue)
+	c.Assert(command.series, gc.Equals, "xenial")
+}
+
+func (*helpSuite) TestHiddenFlagOmittedFromHelp(c *gc.C) {
+	help := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "bootstrap", false))
+	c.Assert(help, jc.Contains, "--series")
+	c.Assert(help, gc.Not(jc.Contains), "upload-tools")
+}
+
+func (*helpSuite) TestHelpAllShowsHiddenFlag(c *gc.C) {
+	help := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "bootstrap", true))
+	c.Assert(help, jc.Contains, "--series")
+	c.Assert(help, jc.Contains, "--upload-tools")
+}
+
+func (*helpSuite) TestVisibleFlagsSharesValues(c *gc.C) {
+	var series string
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	f.StringVar(&series, "series", "", "")
+	f.Bool("hidden", false, "")
+	visible := jujucmd.VisibleFlags(f, []string{"hidden"})
+	c.Assert(visible.Lookup("hidden"), gc.IsNil)
+	c.Assert(visible.Lookup("series"), gc.NotNil)
+	err := visible.Set("series", "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(series, gc.Equals, "trusty")
+}
+
+func newHelpSuper() *jujucmd.HelpSuperCommand {
+	super := jujucmd.NewHelpSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.Register(&hiddenFlagsCommand{})
+	return super
+}
+
+func (*helpSuite) TestSubcommandHelpOmitsHiddenFlags(c *gc.C) {
+	expected := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "juju bootstrap", false))
+	c.Assert(expected, gc.Not(jc.Contains), "upload-tools")
+	for i, args := range [][]string{
+		{"help", "bootstrap"},
+		{"--help", "bootstrap"},
+		{"bootstrap", "--help"},
+		{"bootstrap", "--series", "xenial", "-h"},
+	} {
+		c.Logf("test %d: %v", i, args)
+		ctx := coretesting.Context(c)
+		code := cmd.Main(newHelpSuper(), ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, expected)
+	}
+}
+
+func (*helpSuite) TestSubcommandHelpAll(c *gc.C) {
+	expected := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "juju bootstrap", true))
+	c.Assert(expected, jc.Contains, "--upload-tools")
+	for i, args := range [][]string{
+		{"help", "--all", "bootstrap"},
+		{"bootstrap", "--help-all"},
+	} {
+		c.Logf("test %d: %v", i, args)
+		ctx := coretesting.Context(c)
+		code := cmd.Main(newHelpSuper(), ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, expected)
+	}
+}
+
+func (*helpSuite) TestSubcommandHiddenFlagStillParses(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newHelpSuper(), ctx, []string{"bootstrap", "--upload-tools"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "")
+}
@@ -21,10 +21,14 @@ func (e userAbortedError) Error() string {
 	return string(e)
 }
 
-// IsUserAbortedError returns true if err is of type userAbortedError.
+// IsUserAbortedError returns true if err is of type userAbortedError,
+// or was returned by PlanWriter.Confirm during a dry run.
 func IsUserAbortedError(err error) bool {
-	_, ok := errors.Cause(err).(userAbortedError)
-	return ok
+	switch errors.Cause(err).(type) {
+	case userAbortedError, *dryRunError:
+		return true
+	}
+	return false
 }
 
 // UserConfirmYes returns an error if we do not read a "y" or "yes" from user
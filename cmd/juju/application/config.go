@@ -16,6 +16,7 @@ import (
 
 	"github.com/juju/juju/api/application"
 	"github.com/juju/juju/apiserver/params"
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/cmd/juju/block"
 	"github.com/juju/juju/cmd/modelcmd"
 	"github.com/juju/juju/cmd/output"
@@ -379,7 +380,7 @@ func (c *configCommand) validateValues(ctx *cmd.Context) (map[string]string, err
 // An empty content is valid, like in parsing the options. The upper
 // size is 5M.
 func readValue(ctx *cmd.Context, filename string) (string, error) {
-	absFilename := ctx.AbsPath(filename)
+	absFilename := jujucmd.AbsPath(ctx, filename)
 	fi, err := os.Stat(absFilename)
 	if err != nil {
 		return "", errors.Errorf("cannot read option from file %q: %v", filename, err)
@@ -387,7 +388,7 @@ func readValue(ctx *cmd.Context, filename string) (string, error) {
 	if fi.Size() > maxValueSize {
 		return "", errors.Errorf("size of option file is larger than 5M")
 	}
-	content, err := ioutil.ReadFile(ctx.AbsPath(filename))
+	content, err := ioutil.ReadFile(jujucmd.AbsPath(ctx, filename))
 	if err != nil {
 		return "", errors.Errorf("cannot read option from file %q: %v", filename, err)
 	}
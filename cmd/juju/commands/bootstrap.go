@@ -20,6 +20,7 @@ import (
 	"gopkg.in/juju/charm.v6-unstable"
 
 	jujucloud "github.com/juju/juju/cloud"
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/cmd/juju/common"
 	"github.com/juju/juju/cmd/modelcmd"
 	"github.com/juju/juju/constraints"
@@ -727,7 +728,7 @@ See `[1:] + "`juju kill-controller`" + `.`)
 	// SyncTools can use it, and also upload any image metadata.
 	var metadataDir string
 	if c.MetadataSource != "" {
-		metadataDir = ctx.AbsPath(c.MetadataSource)
+		metadataDir = jujucmd.AbsPath(ctx, c.MetadataSource)
 	}
 
 	// Merge environ and bootstrap-specific constraints.
@@ -0,0 +1,88 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package commands
+
+import (
+	"os"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+
+	jujucmd "github.com/juju/juju/cmd"
+)
+
+const documentationDoc = `
+Generates documentation for all juju commands from their built in help.
+
+By default a single markdown document is written to stdout. With
+--format man, a troff man page is written for each command, along with
+an index page, into the directory given by --out.
+
+Examples:
+
+    juju documentation --out juju.md
+    juju documentation --format man --out /tmp/man1
+`
+
+func newDocumentationCommand() cmd.Command {
+	return &documentationCommand{}
+}
+
+// documentationCommand generates documentation for the juju commands.
+type documentationCommand struct {
+	cmd.CommandBase
+	format            string
+	out               string
+	includeDeprecated bool
+}
+
+// Info implements cmd.Command.
+func (c *documentationCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "documentation",
+		Purpose: "Generate documentation for juju commands.",
+		Doc:     documentationDoc,
+	}
+}
+
+// SetFlags implements cmd.Command.
+func (c *documentationCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.format, "format", "markdown", "Documentation format: markdown or man")
+	f.StringVar(&c.out, "out", "", "File (markdown) or directory (man) to write to")
+	f.BoolVar(&c.includeDeprecated, "include-deprecated", false, "Include deprecated commands")
+}
+
+// Init implements cmd.Command.
+func (c *documentationCommand) Init(args []string) error {
+	switch c.format {
+	case "markdown":
+	case "man":
+		if c.out == "" {
+			return errors.New("--out is required with --format man")
+		}
+	default:
+		return errors.NotValidf("format %q", c.format)
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// Run implements cmd.Command.
+func (c *documentationCommand) Run(ctx *cmd.Context) error {
+	var registry jujucmd.DocRegistry
+	registerCommands(&registry, ctx)
+	commands := registry.Commands(c.includeDeprecated)
+	if c.format == "man" {
+		return jujucmd.WriteManPages(jujucmd.AbsPath(ctx, c.out), "juju", commands)
+	}
+	if c.out == "" {
+		return jujucmd.WriteMarkdown(ctx.Stdout, "juju", commands)
+	}
+	f, err := os.Create(jujucmd.AbsPath(ctx, c.out))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer f.Close()
+	return jujucmd.WriteMarkdown(f, "juju", commands)
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing"
+)
+
+type DocumentationSuite struct {
+	testing.FakeJujuXDGDataHomeSuite
+}
+
+var _ = gc.Suite(&DocumentationSuite{})
+
+func (s *DocumentationSuite) TestInit(c *gc.C) {
+	err := testing.InitCommand(newDocumentationCommand(), []string{"--format", "pdf"})
+	c.Assert(err, gc.ErrorMatches, `format "pdf" not valid`)
+	err = testing.InitCommand(newDocumentationCommand(), []string{"--format", "man"})
+	c.Assert(err, gc.ErrorMatches, `--out is required with --format man`)
+	err = testing.InitCommand(newDocumentationCommand(), []string{"extra"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+}
+
+func (s *DocumentationSuite) TestMarkdown(c *gc.C) {
+	ctx, err := testing.RunCommand(c, newDocumentationCommand())
+	c.Assert(err, jc.ErrorIsNil)
+	out := testing.Stdout(ctx)
+	c.Assert(out, jc.HasPrefix, "# juju\n")
+	c.Assert(out, jc.Contains, "\n## deploy\n")
+	c.Assert(out, jc.Contains, "\n## documentation\n")
+}
+
+func (s *DocumentationSuite) TestManPages(c *gc.C) {
+	dir := c.MkDir()
+	_, err := testing.RunCommand(c, newDocumentationCommand(), "--format", "man", "--out", dir)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "juju-deploy.1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), jc.HasPrefix, ".TH JUJU-DEPLOY 1\n")
+	_, err = ioutil.ReadFile(filepath.Join(dir, "juju.1"))
+	c.Assert(err, jc.ErrorIsNil)
+}
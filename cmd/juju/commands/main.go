@@ -154,7 +154,7 @@ func (m main) Run(args []string) int {
 	}
 
 	jcmd := NewJujuCommand(ctx)
-	return cmd.Main(jcmd, ctx, args[1:])
+	return jujucmd.Main(jcmd, ctx, args[1:])
 }
 
 func (m main) maybeWarnJuju1x() (newInstall bool) {
@@ -208,10 +208,10 @@ func juju2xConfigDataExists() bool {
 
 // NewJujuCommand ...
 func NewJujuCommand(ctx *cmd.Context) cmd.Command {
-	jcmd := jujucmd.NewSuperCommand(cmd.SuperCommandParams{
+	jcmd := jujucmd.NewVersionedSuperCommand(cmd.SuperCommandParams{
 		Name:                "juju",
 		Doc:                 jujuDoc,
-		MissingCallback:     RunPlugin,
+		MissingCallback:     jujucmd.MissingCallbacks(RunPlugin),
 		UserAliasesFilename: osenv.JujuXDGDataHomePath("aliases"),
 	})
 	jcmd.AddHelpTopic("basics", "Basic Help Summary", usageHelp)
@@ -261,6 +261,7 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 
 	// Charm tool commands.
 	r.Register(newHelpToolCommand())
+	r.Register(newDocumentationCommand())
 	r.Register(charmcmd.NewSuperCommand())
 
 	// Manage backups.
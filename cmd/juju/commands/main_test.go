@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/juju/cmd"
 	gitjujutesting "github.com/juju/testing"
@@ -22,6 +23,7 @@ import (
 	"github.com/juju/version"
 	gc "gopkg.in/check.v1"
 
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/cmd/juju/application"
 	"github.com/juju/juju/cmd/juju/cloud"
 	"github.com/juju/juju/cmd/modelcmd"
@@ -102,17 +104,22 @@ func (s *MainSuite) TestRunMain(c *gc.C) {
 		summary: "unknown option before command",
 		args:    []string{"--cheese", "bootstrap"},
 		code:    2,
-		out:     "error: flag provided but not defined: --cheese\n",
+		out: "error: flag provided but not defined: --cheese\n" +
+			"options of a command must be given after the command name\n" +
+			"usage: juju [options] <command> ...\n",
 	}, {
 		summary: "unknown option after command",
 		args:    []string{"bootstrap", "--cheese"},
 		code:    2,
-		out:     "error: flag provided but not defined: --cheese\n",
+		out: "error: flag provided but not defined: --cheese\n" +
+			"usage: juju bootstrap [options] [<cloud name>[/region] [<controller name>]]\n",
 	}, {
 		summary: "known option, but specified before command",
 		args:    []string{"--model", "blah", "bootstrap"},
 		code:    2,
-		out:     "error: flag provided but not defined: --model\n",
+		out: "error: flag provided but not defined: --model\n" +
+			"options of a command must be given after the command name\n" +
+			"usage: juju [options] <command> ...\n",
 	}, {
 		summary: "juju sync-tools registered properly",
 		args:    []string{"sync-tools", "--help"},
@@ -159,6 +166,21 @@ func (s *MainSuite) TestActualRunJujuArgOrder(c *gc.C) {
 	}
 }
 
+// brokenPipeWriter behaves like a pipe whose reader has gone away.
+type brokenPipeWriter struct{}
+
+func (brokenPipeWriter) Write([]byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+}
+
+func (s *MainSuite) TestBrokenPipe(c *gc.C) {
+	ctx := testing.Context(c)
+	ctx.Stdout = brokenPipeWriter{}
+	code := jujucmd.Main(NewJujuCommand(ctx), ctx, []string{"version"})
+	c.Assert(code, gc.Equals, jujucmd.BrokenPipeExitCode)
+	c.Assert(testing.Stderr(ctx), gc.Equals, "")
+}
+
 func (s *MainSuite) TestFirstRun2xFrom1xOnUbuntu(c *gc.C) {
 	if runtime.GOOS == "windows" {
 		// This test can't work on Windows and shouldn't need to
@@ -425,6 +447,7 @@ var commandNames = []string{
 	"disable-command",
 	"disable-user",
 	"disabled-commands",
+	"documentation",
 	"download-backup",
 	"enable-ha",
 	"enable-command",
@@ -18,6 +18,7 @@ import (
 	"github.com/juju/cmd"
 	"github.com/juju/gnuflag"
 
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/cmd/modelcmd"
 	"github.com/juju/juju/juju/osenv"
 )
@@ -47,6 +48,8 @@ func extractJujuArgs(args []string) []string {
 	return jujuArgs
 }
 
+// RunPlugin runs the juju plugin for the given subcommand, returning
+// jujucmd.ErrUnrecognized if no such plugin is installed.
 func RunPlugin(ctx *cmd.Context, subcommand string, args []string) error {
 	cmdName := JujuPluginPrefix + subcommand
 	plugin := modelcmd.Wrap(&PluginCommand{name: cmdName})
@@ -71,7 +74,7 @@ func RunPlugin(ctx *cmd.Context, subcommand string, args []string) error {
 	if !execError {
 		return err
 	}
-	return &cmd.UnrecognizedCommand{Name: subcommand}
+	return jujucmd.ErrUnrecognized
 }
 
 type PluginCommand struct {
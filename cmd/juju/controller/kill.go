@@ -13,6 +13,7 @@ import (
 	"github.com/juju/utils/clock"
 
 	"github.com/juju/juju/apiserver/common"
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/cmd/modelcmd"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/config"
@@ -78,8 +79,8 @@ type killCommand struct {
 // SetFlags implements Command.SetFlags.
 func (c *killCommand) SetFlags(f *gnuflag.FlagSet) {
 	c.destroyCommandBase.SetFlags(f)
-	f.Var(newDurationValue(time.Minute*5, &c.timeout), "t", "Timeout before direct destruction")
-	f.Var(newDurationValue(time.Minute*5, &c.timeout), "timeout", "")
+	f.Var(jujucmd.NewDurationValue(time.Minute*5, &c.timeout), "t", "Timeout before direct destruction")
+	f.Var(jujucmd.NewDurationValue(time.Minute*5, &c.timeout), "timeout", "")
 }
 
 // Info implements Command.Info.
@@ -227,21 +228,3 @@ func (c *killCommand) WaitForModels(ctx *cmd.Context, api destroyControllerAPI,
 	}
 	return nil
 }
-
-type durationValue time.Duration
-
-func newDurationValue(value time.Duration, p *time.Duration) *durationValue {
-	*p = value
-	return (*durationValue)(p)
-}
-
-func (d *durationValue) Set(s string) error {
-	v, err := time.ParseDuration(s)
-	if err != nil {
-		return err
-	}
-	*d = durationValue(v)
-	return err
-}
-
-func (d *durationValue) String() string { return (*time.Duration)(d).String() }
@@ -50,6 +50,7 @@ type machineStatus struct {
 	Containers    map[string]machineStatus `json:"containers,omitempty" yaml:"containers,omitempty"`
 	Hardware      string                   `json:"hardware,omitempty" yaml:"hardware,omitempty"`
 	HAStatus      string                   `json:"controller-member-status,omitempty" yaml:"controller-member-status,omitempty"`
+	Provisioning  map[string]string        `json:"provisioning,omitempty" yaml:"provisioning,omitempty"`
 }
 
 // A goyaml bug means we can't declare these types
@@ -21,6 +21,10 @@ type statusFormatter struct {
 	controllerName string
 	relations      map[int]params.RelationStatus
 	isoTime        bool
+
+	// includeProvisioning determines whether the details recorded by
+	// the provisioner are included in each machine's status.
+	includeProvisioning bool
 }
 
 // NewStatusFormatter takes stored model information (params.FullStatus) and populates
@@ -110,6 +114,10 @@ func (sf *statusFormatter) formatMachine(machine params.MachineStatus) machineSt
 		Hardware:      machine.Hardware,
 	}
 
+	if sf.includeProvisioning {
+		out.Provisioning = machine.ProvisionerMetadata
+	}
+
 	for k, m := range machine.Containers {
 		out.Containers[k] = sf.formatMachine(m)
 	}
@@ -40,6 +40,8 @@ type statusCommand struct {
 	api      statusAPI
 
 	color bool
+
+	includeProvisioning bool
 }
 
 var usageSummary = `
@@ -73,10 +75,15 @@ The available output formats are:
 - json: Displays information about the model, machines, applications, and units
       in structured JSON format.
 
+The --include-provisioning option adds the details recorded when each
+machine was provisioned, such as the apt mirror, proxy settings and tools
+URL used, to the yaml and json formats.
+
 Examples:
     juju show-status
     juju show-status mysql
     juju show-status nova-*
+    juju show-status --format yaml --include-provisioning
 
 See also:
     machines
@@ -99,6 +106,7 @@ func (c *statusCommand) SetFlags(f *gnuflag.FlagSet) {
 	c.ModelCommandBase.SetFlags(f)
 	f.BoolVar(&c.isoTime, "utc", false, "Display time as UTC in RFC3339 format")
 	f.BoolVar(&c.color, "color", false, "Force use of ANSI color codes")
+	f.BoolVar(&c.includeProvisioning, "include-provisioning", false, "Include the details recorded when provisioning each machine")
 
 	defaultFormat := "tabular"
 
@@ -153,6 +161,7 @@ func (c *statusCommand) Run(ctx *cmd.Context) error {
 	}
 
 	formatter := newStatusFormatter(status, c.ControllerName(), c.isoTime)
+	formatter.includeProvisioning = c.includeProvisioning
 	formatted, err := formatter.format()
 	if err != nil {
 		return err
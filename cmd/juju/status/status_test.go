@@ -4344,6 +4344,33 @@ func (s *StatusSuite) TestFormatProvisioningError(c *gc.C) {
 	})
 }
 
+func (s *StatusSuite) TestFormatIncludeProvisioning(c *gc.C) {
+	status := &params.FullStatus{
+		Machines: map[string]params.MachineStatus{
+			"0": {
+				InstanceId: "inst-0",
+				Series:     "trusty",
+				Id:         "0",
+				Jobs:       []multiwatcher.MachineJob{"JobHostUnits"},
+				ProvisionerMetadata: map[string]string{
+					"apt-mirror": "http://mirror.example.com",
+				},
+			},
+		},
+	}
+	formatter := NewStatusFormatter(status, true)
+	formatted, err := formatter.format()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(formatted.Machines["0"].Provisioning, gc.IsNil)
+
+	formatter.includeProvisioning = true
+	formatted, err = formatter.format()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(formatted.Machines["0"].Provisioning, jc.DeepEquals, map[string]string{
+		"apt-mirror": "http://mirror.example.com",
+	})
+}
+
 type tableSections map[string][]string
 
 func sectionTitle(lines []string) string {
@@ -137,7 +137,7 @@ func jujuDMain(args []string, ctx *cmd.Context) (code int, err error) {
 		return 1, errors.Trace(err)
 	}
 
-	jujud := jujucmd.NewSuperCommand(cmd.SuperCommandParams{
+	jujud := jujucmd.NewVersionedSuperCommand(cmd.SuperCommandParams{
 		Name: "jujud",
 		Doc:  jujudDoc,
 	})
@@ -0,0 +1,68 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+)
+
+// ContextLogWriter is a loggo.Writer that sends log records at
+// WARNING and above to the stderr of a command context, and anything
+// less severe to an optional fallback writer, such as the one writing
+// the configured log file. Each record is written with a single write
+// while holding a lock, so records logged concurrently by goroutines
+// started from Run are never interleaved mid-line.
+type ContextLogWriter struct {
+	mu       sync.Mutex
+	ctx      *cmd.Context
+	fallback loggo.Writer
+}
+
+var _ loggo.Writer = (*ContextLogWriter)(nil)
+
+// NewContextLogWriter returns a ContextLogWriter writing to the given
+// context. The fallback writer may be nil, in which case records below
+// WARNING are discarded.
+func NewContextLogWriter(ctx *cmd.Context, fallback loggo.Writer) *ContextLogWriter {
+	return &ContextLogWriter{
+		ctx:      ctx,
+		fallback: fallback,
+	}
+}
+
+// Write implements loggo.Writer.
+func (w *ContextLogWriter) Write(entry loggo.Entry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if entry.Level < loggo.WARNING {
+		if w.fallback != nil {
+			w.fallback.Write(entry)
+		}
+		return
+	}
+	fmt.Fprintf(w.ctx.Stderr, "%s %s\n", entry.Level, entry.Message)
+}
+
+// RegisterContextLogWriter registers a ContextLogWriter for the given
+// context with loggo under the given name, and returns a function that
+// removes it again. Typical use in a Run method is:
+//
+//	remove, err := RegisterContextLogWriter("my-command", ctx, nil)
+//	if err != nil {
+//		return errors.Trace(err)
+//	}
+//	defer remove()
+func RegisterContextLogWriter(name string, ctx *cmd.Context, fallback loggo.Writer) (func(), error) {
+	if err := loggo.RegisterWriter(name, NewContextLogWriter(ctx, fallback)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return func() {
+		loggo.RemoveWriter(name)
+	}, nil
+}
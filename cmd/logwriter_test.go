@@ -0,0 +1,84 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/juju/loggo"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type logWriterSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&logWriterSuite{})
+
+func (*logWriterSuite) TestLevels(c *gc.C) {
+	ctx := coretesting.Context(c)
+	var fallback loggo.TestWriter
+	w := jujucmd.NewContextLogWriter(ctx, &fallback)
+	w.Write(loggo.Entry{Level: loggo.DEBUG, Message: "debug"})
+	w.Write(loggo.Entry{Level: loggo.INFO, Message: "info"})
+	w.Write(loggo.Entry{Level: loggo.WARNING, Message: "warning"})
+	w.Write(loggo.Entry{Level: loggo.ERROR, Message: "error"})
+
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "WARNING warning\nERROR error\n")
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(fallback.Log(), jc.LogMatches, []jc.SimpleMessage{
+		{loggo.DEBUG, "debug"},
+		{loggo.INFO, "info"},
+	})
+}
+
+func (*logWriterSuite) TestNoFallback(c *gc.C) {
+	ctx := coretesting.Context(c)
+	w := jujucmd.NewContextLogWriter(ctx, nil)
+	w.Write(loggo.Entry{Level: loggo.INFO, Message: "info"})
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*logWriterSuite) TestRegister(c *gc.C) {
+	ctx := coretesting.Context(c)
+	logger := loggo.GetLogger("juju.cmd.logwriter.test")
+	remove, err := jujucmd.RegisterContextLogWriter("logwriter-test", ctx, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	logger.Warningf("careful now")
+	remove()
+	logger.Warningf("not seen")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "WARNING careful now\n")
+}
+
+func (*logWriterSuite) TestConcurrentWritesNotInterleaved(c *gc.C) {
+	ctx := coretesting.Context(c)
+	w := jujucmd.NewContextLogWriter(ctx, nil)
+	const writers, records = 10, 100
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				w.Write(loggo.Entry{
+					Level:   loggo.WARNING,
+					Message: fmt.Sprintf("writer %d record %d", i, j),
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSuffix(coretesting.Stderr(ctx), "\n"), "\n")
+	c.Assert(lines, gc.HasLen, writers*records)
+	for _, line := range lines {
+		c.Assert(line, gc.Matches, `WARNING writer \d+ record \d+`)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+)
+
+// ErrUnrecognized may be returned by a MissingCallback to indicate that
+// it does not handle the named command, so that the next callback, or
+// failing that the supercommand's usual "unrecognized command" error
+// handling, is used instead.
+var ErrUnrecognized = errors.New("unrecognized command")
+
+// MissingCallback is called by a supercommand when no registered
+// subcommand matches name. Any flags given before name have already
+// been parsed by the supercommand; args holds the arguments following
+// name. If the callback returns nil the command is considered handled;
+// any other error except ErrUnrecognized is handled by cmd.Main in the
+// usual way, including any exit code it carries.
+type MissingCallback func(ctx *cmd.Context, name string, args []string) error
+
+// MissingCallbacks returns a callback suitable for
+// cmd.SuperCommandParams.MissingCallback that tries each of the given
+// callbacks in turn until one handles the command.
+func MissingCallbacks(callbacks ...MissingCallback) cmd.MissingCallback {
+	return func(ctx *cmd.Context, name string, args []string) error {
+		for _, callback := range callbacks {
+			err := callback(ctx, name, args)
+			if errors.Cause(err) != ErrUnrecognized {
+				return err
+			}
+		}
+		return &cmd.UnrecognizedCommand{Name: name}
+	}
+}
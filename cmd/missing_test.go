@@ -0,0 +1,98 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type missingSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&missingSuite{})
+
+type missingCall struct {
+	name string
+	args []string
+}
+
+func newMissingSuper(calls *[]missingCall, callbacks ...jujucmd.MissingCallback) *cmd.SuperCommand {
+	record := func(ctx *cmd.Context, name string, args []string) error {
+		*calls = append(*calls, missingCall{name, args})
+		return jujucmd.ErrUnrecognized
+	}
+	callbacks = append([]jujucmd.MissingCallback{record}, callbacks...)
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:            "juju",
+		MissingCallback: jujucmd.MissingCallbacks(callbacks...),
+	})
+	super.Register(&echoCommand{name: "echo"})
+	return super
+}
+
+func (*missingSuite) TestHandled(c *gc.C) {
+	var calls []missingCall
+	plugin := func(ctx *cmd.Context, name string, args []string) error {
+		if name != "plugin" {
+			return jujucmd.ErrUnrecognized
+		}
+		fmt.Fprintf(ctx.Stdout, "plugin %q\n", args)
+		return nil
+	}
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newMissingSuper(&calls, plugin), ctx, []string{"plugin", "a", "--b"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, `plugin ["a" "--b"]`+"\n")
+	c.Assert(calls, jc.DeepEquals, []missingCall{{"plugin", []string{"a", "--b"}}})
+}
+
+func (*missingSuite) TestFallBackToUnrecognized(c *gc.C) {
+	var calls []missingCall
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newMissingSuper(&calls), ctx, []string{"unknown"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "unrecognized command: juju unknown")
+	c.Assert(calls, gc.HasLen, 1)
+}
+
+func (*missingSuite) TestAnnotatedSentinel(c *gc.C) {
+	var calls []missingCall
+	annotated := func(*cmd.Context, string, []string) error {
+		return errors.Annotate(jujucmd.ErrUnrecognized, "no such plugin")
+	}
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newMissingSuper(&calls, annotated), ctx, []string{"unknown"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "unrecognized command: juju unknown")
+}
+
+func (*missingSuite) TestErrorFlowsThroughMain(c *gc.C) {
+	var calls []missingCall
+	failing := func(*cmd.Context, string, []string) error {
+		return cmd.NewRcPassthroughError(4)
+	}
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newMissingSuper(&calls, failing), ctx, []string{"unknown"})
+	c.Assert(code, gc.Equals, 4)
+}
+
+func (*missingSuite) TestFlagsBeforeName(c *gc.C) {
+	var calls []missingCall
+	super := newMissingSuper(&calls)
+	super.Log = &cmd.Log{}
+	ctx := coretesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"--debug", "unknown", "--model", "foo"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(calls, jc.DeepEquals, []missingCall{{"unknown", []string{"--model", "foo"}}})
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+)
+
+// NamespaceCommand is a SuperCommand that groups related commands
+// under a common name, such as "juju storage", and which may itself be
+// registered inside another SuperCommand or NamespaceCommand.
+type NamespaceCommand struct {
+	*cmd.SuperCommand
+
+	usagePrefix string
+	names       []string
+}
+
+// NewNamespaceCommand returns a NamespaceCommand to be registered with
+// the command found at usagePrefix, for example "juju" for "juju
+// storage". The usage strings of its subcommands show the full command
+// path, and an unrecognised subcommand is reported with the list of
+// available ones.
+func NewNamespaceCommand(usagePrefix string, p cmd.SuperCommandParams) *NamespaceCommand {
+	ns := &NamespaceCommand{usagePrefix: usagePrefix}
+	p.UsagePrefix = usagePrefix
+	p.NotifyRun = runNotifier
+	p.MissingCallback = ns.missing
+	ns.SuperCommand = cmd.NewSuperCommand(p)
+	return ns
+}
+
+// NewNamespace returns a NamespaceCommand nested within ns. The caller
+// is responsible for registering it with ns.
+func (ns *NamespaceCommand) NewNamespace(p cmd.SuperCommandParams) *NamespaceCommand {
+	return NewNamespaceCommand(ns.Path(), p)
+}
+
+// Path returns the full command path of the namespace, for example
+// "juju storage".
+func (ns *NamespaceCommand) Path() string {
+	return strings.TrimSpace(ns.usagePrefix + " " + ns.Name)
+}
+
+// Register overrides cmd.SuperCommand.Register to keep track of the
+// names the command may be invoked as.
+func (ns *NamespaceCommand) Register(c cmd.Command) {
+	ns.SuperCommand.Register(c)
+	info := c.Info()
+	ns.names = append(ns.names, info.Name)
+	ns.names = append(ns.names, info.Aliases...)
+}
+
+// missing implements cmd.MissingCallback.
+func (ns *NamespaceCommand) missing(ctx *cmd.Context, subcommand string, args []string) error {
+	names := append([]string(nil), ns.names...)
+	sort.Strings(names)
+	return errors.Errorf(
+		"unrecognized command: %s %s\navailable commands: %s",
+		ns.Path(), subcommand, strings.Join(names, ", "),
+	)
+}
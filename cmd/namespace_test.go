@@ -0,0 +1,111 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type namespaceSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&namespaceSuite{})
+
+type storageAddCommand struct {
+	cmd.CommandBase
+	pool string
+}
+
+func (c *storageAddCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "add",
+		Args:    "<unit> <storage>",
+		Purpose: "Add storage to a unit.",
+		Aliases: []string{"create"},
+	}
+}
+
+func (c *storageAddCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.pool, "pool", "", "Storage pool")
+}
+
+func (c *storageAddCommand) Init(args []string) error {
+	return nil
+}
+
+func (c *storageAddCommand) Run(ctx *cmd.Context) error {
+	ctx.Stdout.Write([]byte("added from " + c.pool + "\n"))
+	return nil
+}
+
+func newNamespaceSuper() *cmd.SuperCommand {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	storage := jujucmd.NewNamespaceCommand("juju", cmd.SuperCommandParams{
+		Name:    "storage",
+		Purpose: "Manage storage.",
+		Aliases: []string{"st"},
+	})
+	storage.Register(&storageAddCommand{})
+	storage.Register(&echoCommand{name: "list"})
+	pool := storage.NewNamespace(cmd.SuperCommandParams{
+		Name:    "pool",
+		Purpose: "Manage storage pools.",
+	})
+	pool.Register(&echoCommand{name: "create"})
+	storage.Register(pool)
+	super.Register(storage)
+	return super
+}
+
+func (*namespaceSuite) TestRunNested(c *gc.C) {
+	for _, args := range [][]string{
+		{"storage", "add", "--pool", "ebs", "u/0", "data"},
+		{"storage", "add", "u/0", "--pool", "ebs", "data"},
+		{"st", "create", "--pool", "ebs", "u/0", "data"},
+	} {
+		c.Logf("args: %q", args)
+		ctx := coretesting.Context(c)
+		code := cmd.Main(newNamespaceSuper(), ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, "added from ebs\n")
+	}
+}
+
+func (*namespaceSuite) TestRunTwoLevelsDown(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newNamespaceSuper(), ctx, []string{"storage", "pool", "create"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "create\n")
+}
+
+func (*namespaceSuite) TestPath(c *gc.C) {
+	storage := jujucmd.NewNamespaceCommand("juju", cmd.SuperCommandParams{Name: "storage"})
+	c.Assert(storage.Path(), gc.Equals, "juju storage")
+	pool := storage.NewNamespace(cmd.SuperCommandParams{Name: "pool"})
+	c.Assert(pool.Path(), gc.Equals, "juju storage pool")
+}
+
+func (*namespaceSuite) TestHelpShowsFullPath(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newNamespaceSuper(), ctx, []string{"storage", "add", "--help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), jc.Contains, "juju storage add")
+}
+
+func (*namespaceSuite) TestUnknownSubcommandListsAvailable(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newNamespaceSuper(), ctx, []string{"storage", "remove"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `(?s)ERROR unrecognized command: juju storage remove
+available commands: add, create, list, pool
+`)
+}
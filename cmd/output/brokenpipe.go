@@ -0,0 +1,28 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// IsBrokenPipe reports whether err is the error returned by a write
+// to a pipe whose reader has gone away, as happens when the output of
+// a command is piped into "head". Only errors from writing to a file,
+// such as stdout, are recognised: a broken network connection is a
+// genuine failure, and is not reported as a broken pipe.
+func IsBrokenPipe(err error) bool {
+	switch err := errors.Cause(err).(type) {
+	case syscall.Errno:
+		return err == syscall.EPIPE
+	case *os.PathError:
+		return err.Err == syscall.EPIPE
+	case *os.SyscallError:
+		return err.Err == syscall.EPIPE
+	}
+	return false
+}
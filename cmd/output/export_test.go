@@ -0,0 +1,9 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+var (
+	IsTerminal = &isTerminal
+	Now        = &now
+)
@@ -0,0 +1,197 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/loggo"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var logger = loggo.GetLogger("juju.cmd.output")
+
+// isTerminal and now are variables so that tests can pretend stderr is
+// a terminal, and control the time shown in progress lines.
+var (
+	isTerminal = func(w io.Writer) bool {
+		f, ok := w.(*os.File)
+		return ok && terminal.IsTerminal(int(f.Fd()))
+	}
+	now = time.Now
+)
+
+// Progress event kinds, as reported in the "progress" field of the
+// events written to a Stream.
+const (
+	ProgressStart = "start"
+	ProgressStep  = "step"
+	ProgressDone  = "done"
+	ProgressFail  = "fail"
+)
+
+// ProgressEvent is written to a Stream for each change in the state of
+// a Progress.
+type ProgressEvent struct {
+	// Progress is the kind of event: ProgressStart, ProgressStep,
+	// ProgressDone or ProgressFail.
+	Progress string `json:"progress"`
+
+	// Title is the title of the Progress the event belongs to.
+	Title string `json:"title"`
+
+	// Depth is the number of Progresses the Progress is nested in.
+	Depth int `json:"depth"`
+
+	// Message is the step's message, or the error a Progress failed
+	// with.
+	Message string `json:"message,omitempty"`
+}
+
+// Progress reports the progress of a multi-step operation to a
+// command's user. It is created by StartProgress, or by
+// Stream.StartProgress for commands that support streaming output.
+//
+// Progress is written to stderr with ctx.Infof, so nothing is shown
+// when the command is run with --quiet. When stderr is a terminal,
+// steps are shown as an indented list; otherwise each line starts with
+// the time, so that logs of the output can be followed. Progress
+// started from a Progress is nested within it, and indented further.
+type Progress struct {
+	ctx    *cmd.Context
+	title  string
+	depth  int
+	render func(ProgressEvent)
+
+	mu       sync.Mutex
+	finished bool
+}
+
+// StartProgress reports the start of an operation with the given
+// title, and returns a Progress with which to report its steps and
+// its completion.
+func StartProgress(ctx *cmd.Context, title string) *Progress {
+	var render func(ProgressEvent)
+	if isTerminal(ctx.Stderr) {
+		render = func(e ProgressEvent) { renderTerminalProgress(ctx, e) }
+	} else {
+		render = func(e ProgressEvent) { renderPlainProgress(ctx, e) }
+	}
+	return startProgress(ctx, title, 0, render)
+}
+
+// StartProgress is like the StartProgress function, except that when
+// the output is Streaming, the progress is written to stdout as
+// ProgressEvents rather than to stderr.
+func (s *Stream) StartProgress(ctx *cmd.Context, title string) *Progress {
+	if !s.Streaming() {
+		return StartProgress(ctx, title)
+	}
+	render := func(e ProgressEvent) {
+		// A broken pipe is reported by the command's own writes;
+		// warning about every progress event would only add noise.
+		if err := s.WriteStream(ctx, e); err != nil && !IsBrokenPipe(err) {
+			logger.Warningf("cannot write progress: %v", err)
+		}
+	}
+	return startProgress(ctx, title, 0, render)
+}
+
+func startProgress(ctx *cmd.Context, title string, depth int, render func(ProgressEvent)) *Progress {
+	p := &Progress{
+		ctx:    ctx,
+		title:  title,
+		depth:  depth,
+		render: render,
+	}
+	p.emit(ProgressStart, "")
+	return p
+}
+
+// StartProgress reports the start of a sub-operation of p, and returns
+// a Progress for it.
+func (p *Progress) StartProgress(title string) *Progress {
+	return startProgress(p.ctx, title, p.depth+1, p.render)
+}
+
+// Step reports that the operation has reached the step described by
+// message.
+func (p *Progress) Step(message string) {
+	p.emit(ProgressStep, message)
+}
+
+// Done reports that the operation has completed successfully. Only
+// the first call to Done or Fail is reported.
+func (p *Progress) Done() {
+	if p.finish() {
+		p.emit(ProgressDone, "")
+	}
+}
+
+// Fail reports that the operation has failed with the given error.
+// Only the first call to Done or Fail is reported.
+func (p *Progress) Fail(err error) {
+	if p.finish() {
+		p.emit(ProgressFail, err.Error())
+	}
+}
+
+// finish marks p as finished, and returns whether it was not already.
+func (p *Progress) finish() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return false
+	}
+	p.finished = true
+	return true
+}
+
+func (p *Progress) emit(kind, message string) {
+	p.render(ProgressEvent{
+		Progress: kind,
+		Title:    p.title,
+		Depth:    p.depth,
+		Message:  message,
+	})
+}
+
+// renderPlainProgress writes e as a line starting with the time, for
+// output that is not a terminal. A Progress's steps are indented
+// below its title.
+func renderPlainProgress(ctx *cmd.Context, e ProgressEvent) {
+	indent := strings.Repeat("  ", e.Depth)
+	var line string
+	switch e.Progress {
+	case ProgressStart:
+		line = indent + e.Title
+	case ProgressStep:
+		line = indent + "  " + e.Message
+	case ProgressDone:
+		line = indent + e.Title + ": done"
+	case ProgressFail:
+		line = indent + e.Title + ": failed: " + e.Message
+	}
+	ctx.Infof("%s %s", now().Format("15:04:05"), line)
+}
+
+// renderTerminalProgress writes e for output to a terminal.
+func renderTerminalProgress(ctx *cmd.Context, e ProgressEvent) {
+	indent := strings.Repeat("  ", e.Depth)
+	switch e.Progress {
+	case ProgressStart:
+		ctx.Infof("%s%s...", indent, e.Title)
+	case ProgressStep:
+		ctx.Infof("%s  - %s", indent, e.Message)
+	case ProgressDone:
+		ctx.Infof("%s✓ %s", indent, e.Title)
+	case ProgressFail:
+		ctx.Infof("%s✗ %s: %s", indent, e.Title, e.Message)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output_test
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/output"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type progressSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&progressSuite{})
+
+func (s *progressSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.PatchValue(output.Now, func() time.Time {
+		return time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	})
+}
+
+// upgradeCommand reports the progress of an operation with nested
+// steps, failing at the end.
+type upgradeCommand struct {
+	cmd.CommandBase
+	out output.Stream
+}
+
+func (c *upgradeCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "upgrade"}
+}
+
+func (c *upgradeCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", output.DefaultFormatters)
+}
+
+func (c *upgradeCommand) Run(ctx *cmd.Context) error {
+	p := c.out.StartProgress(ctx, "Upgrading")
+	p.Step("uploading tools")
+	units := p.StartProgress("Restarting agents")
+	units.Step("machine-0")
+	units.Step("machine-1")
+	units.Done()
+	units.Done()
+	p.Fail(errors.New("boom"))
+	p.Done()
+	return nil
+}
+
+func (s *progressSuite) TestPlain(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, &upgradeCommand{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, `
+12:00:00 Upgrading
+12:00:00   uploading tools
+12:00:00   Restarting agents
+12:00:00     machine-0
+12:00:00     machine-1
+12:00:00   Restarting agents: done
+12:00:00 Upgrading: failed: boom
+`[1:])
+}
+
+func (s *progressSuite) TestTerminal(c *gc.C) {
+	s.PatchValue(output.IsTerminal, func(io.Writer) bool { return true })
+	ctx, err := coretesting.RunCommand(c, &upgradeCommand{})
+	c.Assert(err, jc.ErrorIsNil)
+	stderr := coretesting.Stderr(ctx)
+	c.Check(stderr, gc.Not(jc.Contains), "12:00:00")
+	c.Check(stderr, jc.Contains, "Upgrading...\n")
+	c.Check(stderr, jc.Contains, "    - machine-1\n")
+	c.Check(stderr, jc.Contains, "  ✓ Restarting agents\n")
+	c.Check(stderr, jc.Contains, "✗ Upgrading: boom\n")
+}
+
+func (s *progressSuite) TestStreaming(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, &upgradeCommand{}, "--format", "json")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+
+	var events []output.ProgressEvent
+	for _, line := range strings.Split(strings.TrimSuffix(coretesting.Stdout(ctx), "\n"), "\n") {
+		var event output.ProgressEvent
+		err := json.Unmarshal([]byte(line), &event)
+		c.Assert(err, jc.ErrorIsNil, gc.Commentf("line %q", line))
+		events = append(events, event)
+	}
+	c.Assert(events, jc.DeepEquals, []output.ProgressEvent{
+		{Progress: output.ProgressStart, Title: "Upgrading"},
+		{Progress: output.ProgressStep, Title: "Upgrading", Message: "uploading tools"},
+		{Progress: output.ProgressStart, Title: "Restarting agents", Depth: 1},
+		{Progress: output.ProgressStep, Title: "Restarting agents", Depth: 1, Message: "machine-0"},
+		{Progress: output.ProgressStep, Title: "Restarting agents", Depth: 1, Message: "machine-1"},
+		{Progress: output.ProgressDone, Title: "Restarting agents", Depth: 1},
+		{Progress: output.ProgressFail, Title: "Upgrading", Message: "boom"},
+	})
+}
+
+func (s *progressSuite) TestQuiet(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	})
+	super.Register(&upgradeCommand{})
+	ctx, err := coretesting.RunCommand(c, super, "--quiet", "upgrade")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
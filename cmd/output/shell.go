@@ -0,0 +1,184 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// ShellFormat is the name of the output format in which variables are
+// written as commands that set them in a shell, for use as in
+//
+//	eval "$(juju some-command --format shell)"
+//
+// The output of command substitution must be quoted, as above, for
+// values holding newlines or spaces to be set faithfully.
+const ShellFormat = "shell"
+
+// The shells whose syntax the shell format can use.
+const (
+	// ShellPOSIX selects the syntax of sh, bash, zsh and other
+	// POSIX shells. It is the default.
+	ShellPOSIX = "sh"
+
+	// ShellFish selects the syntax of fish.
+	ShellFish = "fish"
+
+	// ShellPowerShell selects the syntax of PowerShell.
+	ShellPowerShell = "powershell"
+)
+
+// shellQuoters holds the function that writes the command setting a
+// variable in each supported shell.
+var shellQuoters = map[string]func(name, value string) string{
+	ShellPOSIX:      posixExport,
+	ShellFish:       fishExport,
+	ShellPowerShell: powerShellExport,
+}
+
+// validVariableName matches the names of variables that can be set in
+// all the supported shells.
+var validVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ShellOutput provides the shell output format, and a --shell flag to
+// choose the shell whose syntax it uses. Commands that export variables
+// hold one alongside their cmd.Output:
+//
+//	func (c *someCommand) SetFlags(f *gnuflag.FlagSet) {
+//		c.shell.SetFlags(f)
+//		c.out.AddFlags(f, output.ShellFormat, map[string]cmd.Formatter{
+//			output.ShellFormat: c.shell.Formatter(),
+//			"yaml":             cmd.FormatYaml,
+//		})
+//	}
+//
+// The value written must be a map[string]string of variable names to
+// values.
+type ShellOutput struct {
+	shell string
+}
+
+// SetFlags adds the --shell flag to f.
+func (s *ShellOutput) SetFlags(f *gnuflag.FlagSet) {
+	s.shell = ShellPOSIX
+	f.Var(shellValue{&s.shell}, "shell", fmt.Sprintf(
+		"Specify the shell whose syntax is used by the %s format: %s",
+		ShellFormat, strings.Join(shellNames(), "|"),
+	))
+}
+
+// Formatter returns the formatter for the shell format, using the
+// syntax of the shell chosen with --shell.
+func (s *ShellOutput) Formatter() cmd.Formatter {
+	return func(value interface{}) ([]byte, error) {
+		shell := s.shell
+		if shell == "" {
+			shell = ShellPOSIX
+		}
+		return FormatShell(shell, value)
+	}
+}
+
+// FormatShell returns the commands that set the variables in value,
+// which must be a map[string]string, in the syntax of the given shell.
+// The commands are written one per line, sorted by variable name, and
+// each value is quoted so that it is set exactly, whatever characters
+// it holds. Names that are not valid variable names, and values holding
+// NUL bytes, which no shell can represent, are rejected.
+func FormatShell(shell string, value interface{}) ([]byte, error) {
+	quote, ok := shellQuoters[shell]
+	if !ok {
+		return nil, errors.NotValidf("shell %q", shell)
+	}
+	vars, ok := value.(map[string]string)
+	if !ok {
+		return nil, errors.Errorf("%s format requires variables as map[string]string, got %T", ShellFormat, value)
+	}
+	names := make([]string, 0, len(vars))
+	for name, value := range vars {
+		if !validVariableName.MatchString(name) {
+			return nil, errors.NotValidf("variable name %q", name)
+		}
+		if strings.ContainsRune(value, 0) {
+			return nil, errors.Errorf("value of %s holds a NUL byte, which cannot be set in a shell", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = quote(name, vars[name])
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// posixExport returns the command exporting a variable in a POSIX
+// shell. Nothing is special within single quotes, so a single quote is
+// written by closing the quotes, escaping it, and opening them again.
+func posixExport(name, value string) string {
+	return fmt.Sprintf("export %s='%s';", name, strings.Replace(value, `'`, `'\''`, -1))
+}
+
+// fishQuoter escapes the two characters that are special within single
+// quotes in fish.
+var fishQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// fishExport returns the command exporting a variable in fish.
+func fishExport(name, value string) string {
+	return fmt.Sprintf("set -gx %s '%s';", name, fishQuoter.Replace(value))
+}
+
+// powerShellQuoter doubles the characters that end a single quoted
+// string in PowerShell, which include the typographic single quotes.
+var powerShellQuoter = strings.NewReplacer(
+	"'", "''",
+	"‘", "‘‘",
+	"’", "’’",
+	"‚", "‚‚",
+	"‛", "‛‛",
+)
+
+// powerShellExport returns the command setting an environment variable
+// in PowerShell.
+func powerShellExport(name, value string) string {
+	return fmt.Sprintf("$Env:%s = '%s';", name, powerShellQuoter.Replace(value))
+}
+
+func shellNames() []string {
+	names := make([]string, 0, len(shellQuoters))
+	for name := range shellQuoters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shellValue is a gnuflag.Value that accepts only supported shells.
+type shellValue struct {
+	shell *string
+}
+
+// Set implements gnuflag.Value.
+func (v shellValue) Set(s string) error {
+	if _, ok := shellQuoters[s]; !ok {
+		return errors.Errorf("unknown shell %q, expected one of %s", s, strings.Join(shellNames(), ", "))
+	}
+	*v.shell = s
+	return nil
+}
+
+// String implements gnuflag.Value.
+func (v shellValue) String() string {
+	if v.shell == nil {
+		return ""
+	}
+	return *v.shell
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output_test
+
+import (
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/output"
+)
+
+type shellSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&shellSuite{})
+
+// adversarialValues hold characters that are special to shells, in
+// and out of quotes.
+var adversarialValues = []string{
+	"",
+	"plain",
+	"it's",
+	"''",
+	`'\''`,
+	"two\nlines",
+	"trailing newlines\n\n",
+	`$HOME ${HOME} $(id) ` + "`id`",
+	`back\slash \' \\'`,
+	`"double" quotes`,
+	"; rm -rf / #",
+	"tab\tand *glob* ?",
+	"‘typographic’ ‚quotes‛",
+	"ünïcødé",
+}
+
+func (s *shellSuite) TestPOSIX(c *gc.C) {
+	out, err := output.FormatShell(output.ShellPOSIX, map[string]string{
+		"JUJU_MODEL": "admin/default",
+		"QUOTED":     "it's",
+		"LINES":      "a\nb",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(out), gc.Equals, ""+
+		"export JUJU_MODEL='admin/default';\n"+
+		"export LINES='a\nb';\n"+
+		`export QUOTED='it'\''s';`)
+}
+
+func (s *shellSuite) TestFish(c *gc.C) {
+	out, err := output.FormatShell(output.ShellFish, map[string]string{
+		"JUJU_MODEL": "admin/default",
+		"QUOTED":     `it's a \`,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(out), gc.Equals, ""+
+		"set -gx JUJU_MODEL 'admin/default';\n"+
+		`set -gx QUOTED 'it\'s a \\';`)
+}
+
+func (s *shellSuite) TestPowerShell(c *gc.C) {
+	out, err := output.FormatShell(output.ShellPowerShell, map[string]string{
+		"JUJU_MODEL": "admin/default",
+		"QUOTED":     "it's ‘typographic’ $HOME",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(out), gc.Equals, ""+
+		"$Env:JUJU_MODEL = 'admin/default';\n"+
+		"$Env:QUOTED = 'it''s ‘‘typographic’’ $HOME';")
+}
+
+func (s *shellSuite) TestPOSIXRoundTrip(c *gc.C) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		c.Skip("no sh found")
+	}
+	for i, value := range adversarialValues {
+		c.Logf("test %d: %q", i, value)
+		out, err := output.FormatShell(output.ShellPOSIX, map[string]string{
+			"VALUE": value,
+			"OTHER": "other",
+		})
+		c.Assert(err, jc.ErrorIsNil)
+		script := `eval "$1"; printf '%s|%s' "$VALUE" "$OTHER"`
+		got, err := exec.Command(sh, "-c", script, "sh", string(out)).Output()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(string(got), gc.Equals, value+"|other")
+	}
+}
+
+func (s *shellSuite) TestInvalidNames(c *gc.C) {
+	for i, name := range []string{
+		"", "1ABC", "A-B", "A B", "A=B", "$(id)", "A;B", "ÜBER", "A\nB",
+	} {
+		c.Logf("test %d: %q", i, name)
+		for _, shell := range []string{output.ShellPOSIX, output.ShellFish, output.ShellPowerShell} {
+			_, err := output.FormatShell(shell, map[string]string{name: "value"})
+			c.Check(err, gc.ErrorMatches, `variable name ".*" not valid`)
+		}
+	}
+}
+
+func (s *shellSuite) TestNULRejected(c *gc.C) {
+	_, err := output.FormatShell(output.ShellPOSIX, map[string]string{"A": "a\x00b"})
+	c.Assert(err, gc.ErrorMatches, "value of A holds a NUL byte, which cannot be set in a shell")
+}
+
+func (s *shellSuite) TestNonMapRejected(c *gc.C) {
+	for i, value := range []interface{}{
+		nil,
+		"A=b",
+		[]string{"A=b"},
+		map[string]interface{}{"A": "b"},
+		struct{ A string }{"b"},
+	} {
+		c.Logf("test %d: %#v", i, value)
+		_, err := output.FormatShell(output.ShellPOSIX, value)
+		c.Check(err, gc.ErrorMatches, `shell format requires variables as map\[string\]string, got .*`)
+	}
+}
+
+func (s *shellSuite) TestUnknownShell(c *gc.C) {
+	_, err := output.FormatShell("csh", map[string]string{"A": "b"})
+	c.Assert(err, gc.ErrorMatches, `shell "csh" not valid`)
+}
+
+func (s *shellSuite) TestEmpty(c *gc.C) {
+	out, err := output.FormatShell(output.ShellPOSIX, map[string]string{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(out, gc.HasLen, 0)
+}
+
+func (s *shellSuite) TestShellFlag(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		expect string
+	}{{
+		expect: "export A='b';",
+	}, {
+		args:   []string{"--shell", "sh"},
+		expect: "export A='b';",
+	}, {
+		args:   []string{"--shell=fish"},
+		expect: "set -gx A 'b';",
+	}, {
+		args:   []string{"--shell", "powershell"},
+		expect: "$Env:A = 'b';",
+	}} {
+		c.Logf("test %d: %q", i, test.args)
+		var shell output.ShellOutput
+		f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+		shell.SetFlags(f)
+		c.Assert(f.Parse(true, test.args), jc.ErrorIsNil)
+		out, err := shell.Formatter()(map[string]string{"A": "b"})
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(string(out), gc.Equals, test.expect)
+	}
+}
+
+func (s *shellSuite) TestShellFlagUnknown(c *gc.C) {
+	var shell output.ShellOutput
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	shell.SetFlags(f)
+	err := f.Parse(true, []string{"--shell", "csh"})
+	c.Assert(err, gc.ErrorMatches, `invalid value "csh" for flag --shell: unknown shell "csh", expected one of fish, powershell, sh`)
+}
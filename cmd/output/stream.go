@@ -0,0 +1,98 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+)
+
+// StreamFormat is the output format in which a command's progress
+// events are streamed.
+const StreamFormat = "json"
+
+// Stream extends cmd.Output for long-running commands that report
+// their progress as a series of events. When the command is run with
+// --format json, each event passed to WriteStream is written to stdout
+// as a single JSON object on its own line (the JSON Lines format), so
+// that tools can process the events as they happen; WriteSummary then
+// writes the final result on one more line.
+//
+// In other formats, events are not written: the command should keep
+// reporting its progress as it does for humans, and WriteSummary
+// behaves like Write.
+//
+// Once a write fails because stdout is a broken pipe (see
+// IsBrokenPipe), nothing more is written, and every later write
+// returns the same error.
+type Stream struct {
+	cmd.Output
+
+	mu     sync.Mutex
+	broken error
+}
+
+// Streaming returns whether events passed to WriteStream are written.
+func (s *Stream) Streaming() bool {
+	return s.Name() == StreamFormat
+}
+
+// WriteStream writes event to ctx.Stdout as a line of JSON, if the
+// output is Streaming. Each event is written with a single call to
+// Write, and flushed if ctx.Stdout supports it, so events are seen
+// as soon as they happen, and lines are never interleaved with each
+// other. Logging and other diagnostics must be written to ctx.Stderr.
+func (s *Stream) WriteStream(ctx *cmd.Context, event interface{}) error {
+	if !s.Streaming() {
+		return nil
+	}
+	return s.writeLine(ctx, event)
+}
+
+// WriteSummary writes the command's final result. If the output is
+// Streaming, the result is written as the last line of JSON; otherwise
+// it is written as by Write.
+func (s *Stream) WriteSummary(ctx *cmd.Context, summary interface{}) error {
+	if !s.Streaming() {
+		return s.Write(ctx, summary)
+	}
+	return s.writeLine(ctx, summary)
+}
+
+func (s *Stream) writeLine(ctx *cmd.Context, value interface{}) error {
+	// json.Marshal escapes any newlines within strings, so the
+	// result is always a single line.
+	line, err := json.Marshal(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken != nil {
+		return s.broken
+	}
+	err = s.write(ctx, line)
+	if IsBrokenPipe(err) {
+		s.broken = err
+	}
+	return err
+}
+
+// write writes line to ctx.Stdout, and flushes it if possible.
+func (s *Stream) write(ctx *cmd.Context, line []byte) error {
+	if _, err := ctx.Stdout.Write(line); err != nil {
+		return errors.Trace(err)
+	}
+	if flusher, ok := ctx.Stdout.(interface {
+		Flush() error
+	}); ok {
+		return errors.Trace(flusher.Flush())
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/output"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type streamSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&streamSuite{})
+
+type progressEvent struct {
+	Machine string `json:"machine"`
+	Message string `json:"message"`
+}
+
+type progressSummary struct {
+	Started int `json:"started"`
+}
+
+// deployCommand reports the progress of starting several machines at
+// once, logging to stderr as it goes.
+type deployCommand struct {
+	cmd.CommandBase
+	out output.Stream
+}
+
+func (c *deployCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "deploy-machines"}
+}
+
+func (c *deployCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", output.DefaultFormatters)
+}
+
+func (c *deployCommand) Run(ctx *cmd.Context) error {
+	const machines = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, machines)
+	for i := 0; i < machines; i++ {
+		id := fmt.Sprint(i)
+		ctx.Infof("starting machine %s\nwith a multi-line log message", id)
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			errs <- c.out.WriteStream(ctx, progressEvent{
+				Machine: id,
+				Message: "started\n(on the first attempt)",
+			})
+		}(id)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return c.out.WriteSummary(ctx, progressSummary{Started: machines})
+}
+
+func (*streamSuite) TestStreamJSONLines(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, &deployCommand{}, "--format", "json")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, "(?s)(starting machine \\d\nwith a multi-line log message\n){10}")
+
+	scanner := bufio.NewScanner(ctx.Stdout.(*bytes.Buffer))
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	c.Assert(scanner.Err(), jc.ErrorIsNil)
+	c.Assert(lines, gc.HasLen, 11)
+
+	seen := make(map[string]bool)
+	for _, line := range lines[:10] {
+		var event progressEvent
+		err := json.Unmarshal([]byte(line), &event)
+		c.Assert(err, jc.ErrorIsNil, gc.Commentf("line %q", line))
+		c.Check(event.Message, gc.Equals, "started\n(on the first attempt)")
+		c.Check(seen[event.Machine], jc.IsFalse)
+		seen[event.Machine] = true
+	}
+	c.Check(seen, gc.HasLen, 10)
+
+	var summary progressSummary
+	err = json.Unmarshal([]byte(lines[10]), &summary)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(summary, jc.DeepEquals, progressSummary{Started: 10})
+}
+
+func (*streamSuite) TestStreamOtherFormats(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, &deployCommand{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "started: 10\n")
+}
+
+type flushWriter struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (w *flushWriter) Flush() error {
+	w.flushed = append(w.flushed, w.String())
+	return nil
+}
+
+func (*streamSuite) TestWriteStreamFlushes(c *gc.C) {
+	var out output.Stream
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	out.AddFlags(f, "yaml", output.DefaultFormatters)
+	c.Assert(f.Parse(false, []string{"--format", "json"}), jc.ErrorIsNil)
+	c.Assert(out.Streaming(), jc.IsTrue)
+
+	stdout := &flushWriter{}
+	ctx := coretesting.Context(c)
+	ctx.Stdout = stdout
+	c.Assert(out.WriteStream(ctx, progressEvent{Machine: "0", Message: "started"}), jc.ErrorIsNil)
+	c.Assert(stdout.flushed, jc.DeepEquals, []string{
+		`{"machine":"0","message":"started"}` + "\n",
+	})
+}
+
+// brokenPipeWriter fails every write as a pipe whose reader has gone
+// away would, counting the writes.
+type brokenPipeWriter struct {
+	writes int
+}
+
+func (w *brokenPipeWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+}
+
+func (*streamSuite) TestWriteStreamStopsAfterBrokenPipe(c *gc.C) {
+	var out output.Stream
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	out.AddFlags(f, "yaml", output.DefaultFormatters)
+	c.Assert(f.Parse(false, []string{"--format", "json"}), jc.ErrorIsNil)
+
+	stdout := &brokenPipeWriter{}
+	ctx := coretesting.Context(c)
+	ctx.Stdout = stdout
+	err := out.WriteStream(ctx, progressEvent{Machine: "0", Message: "started"})
+	c.Assert(output.IsBrokenPipe(err), jc.IsTrue)
+	err = out.WriteStream(ctx, progressEvent{Machine: "1", Message: "started"})
+	c.Assert(output.IsBrokenPipe(err), jc.IsTrue)
+	err = out.WriteSummary(ctx, progressSummary{Started: 2})
+	c.Assert(output.IsBrokenPipe(err), jc.IsTrue)
+	c.Assert(stdout.writes, gc.Equals, 1)
+}
+
+func (*streamSuite) TestIsBrokenPipe(c *gc.C) {
+	for i, test := range []struct {
+		err    error
+		expect bool
+	}{{
+		err:    syscall.EPIPE,
+		expect: true,
+	}, {
+		err:    &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE},
+		expect: true,
+	}, {
+		err:    errors.Trace(os.NewSyscallError("write", syscall.EPIPE)),
+		expect: true,
+	}, {
+		err: &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.ENOSPC},
+	}, {
+		err: &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE},
+	}, {
+		err: errors.New("broken pipe"),
+	}, {
+		err: nil,
+	}} {
+		c.Logf("test %d: %v", i, test.err)
+		c.Check(output.IsBrokenPipe(test.err), gc.Equals, test.expect)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+)
+
+// columnGap is the space left between adjacent columns of a Table.
+const columnGap = 2
+
+// Table holds a tabular rendering of a value, for listing commands.
+type Table struct {
+	// Header holds the column headings. It may be empty.
+	Header []string
+
+	// Rows holds the cells of each row.
+	Rows [][]string
+
+	// NotesColumn, if positive, is one more than the index of the
+	// last column, holding free-form text that is truncated to fit
+	// Width. It is one-based so that the zero value means no notes
+	// column.
+	NotesColumn int
+
+	// Width, if non-zero, is the width of the terminal the table is
+	// written to; it is used to truncate the notes column.
+	Width int
+}
+
+// Write writes the table to w, padding each column to the width of
+// its widest cell. Wide characters, such as CJK ideographs, are
+// counted as two columns.
+func (t *Table) Write(w io.Writer) error {
+	rows := t.Rows
+	if len(t.Header) > 0 {
+		rows = append([][]string{t.Header}, rows...)
+	}
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := textWidth(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+	for _, row := range rows {
+		var line string
+		for i, cell := range row {
+			if i == t.NotesColumn-1 && t.Width > 0 {
+				cell = truncate(cell, t.Width-textWidth(line))
+			}
+			line += cell
+			if i < len(row)-1 {
+				line += strings.Repeat(" ", widths[i]-textWidth(cell)+columnGap)
+			}
+		}
+		if _, err := io.WriteString(w, strings.TrimRight(line, " ")+"\n"); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// TabularFormatter returns a cmd.Formatter, to be registered as
+// "tabular" alongside DefaultFormatters, that renders a value as the
+// Table returned by toTable. When another format such as json or yaml
+// is selected, the same value is rendered structurally instead.
+func TabularFormatter(toTable func(value interface{}) (*Table, error)) cmd.Formatter {
+	return func(w io.Writer, value interface{}) error {
+		table, err := toTable(value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return table.Write(w)
+	}
+}
+
+// truncate shortens s so that it takes at most width columns, marking
+// the truncation with an ellipsis.
+func truncate(s string, width int) string {
+	if textWidth(s) <= width {
+		return s
+	}
+	const ellipsis = "..."
+	if width <= len(ellipsis) {
+		return ""
+	}
+	var result []rune
+	used := 0
+	for _, r := range s {
+		if used+runeWidth(r) > width-len(ellipsis) {
+			break
+		}
+		result = append(result, r)
+		used += runeWidth(r)
+	}
+	return string(result) + ellipsis
+}
+
+// textWidth returns the number of terminal columns s occupies.
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth returns the number of terminal columns r occupies. This
+// is an approximation which treats the common East Asian wide scripts
+// as double width.
+func runeWidth(r rune) int {
+	if unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		(r >= 0xFF01 && r <= 0xFF60) {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/yaml.v2"
+
+	"github.com/juju/juju/cmd/output"
+	coretesting "github.com/juju/juju/testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update the golden files in testdata")
+
+type tableSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&tableSuite{})
+
+func checkGolden(c *gc.C, name string, actual []byte) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		err := ioutil.WriteFile(path, actual, 0644)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	expected, err := ioutil.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(actual), gc.Equals, string(expected))
+}
+
+type machine struct {
+	Id     string `json:"id" yaml:"id"`
+	State  string `json:"state" yaml:"state"`
+	Series string `json:"series" yaml:"series"`
+	Notes  string `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+var machines = []machine{
+	{"0", "started", "xenial", "controller"},
+	{"1", "pending", "trusty", "waiting for the provider to allocate an instance in the requested zone"},
+	{"10", "down", "xenial", ""},
+}
+
+func machinesTable(value interface{}) (*output.Table, error) {
+	table := &output.Table{
+		Header:      []string{"MACHINE", "STATE", "SERIES", "NOTES"},
+		NotesColumn: 4,
+		Width:       50,
+	}
+	for _, m := range value.([]machine) {
+		table.Rows = append(table.Rows, []string{m.Id, m.State, m.Series, m.Notes})
+	}
+	return table, nil
+}
+
+func (*tableSuite) TestTable(c *gc.C) {
+	var buf bytes.Buffer
+	table, err := machinesTable(machines)
+	c.Assert(err, jc.ErrorIsNil)
+	table.Width = 0
+	c.Assert(table.Write(&buf), jc.ErrorIsNil)
+	checkGolden(c, "table", buf.Bytes())
+}
+
+func (*tableSuite) TestTableTruncatesNotes(c *gc.C) {
+	var buf bytes.Buffer
+	table, err := machinesTable(machines)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(table.Write(&buf), jc.ErrorIsNil)
+	checkGolden(c, "table-truncated", buf.Bytes())
+}
+
+func (*tableSuite) TestTableWideCharacters(c *gc.C) {
+	var buf bytes.Buffer
+	table := &output.Table{
+		Header: []string{"NAME", "OWNER"},
+		Rows: [][]string{
+			{"模型", "admin"},
+			{"model", "管理者"},
+		},
+	}
+	c.Assert(table.Write(&buf), jc.ErrorIsNil)
+	checkGolden(c, "table-wide", buf.Bytes())
+}
+
+func (*tableSuite) TestTableRaggedRows(c *gc.C) {
+	var buf bytes.Buffer
+	table := &output.Table{
+		Rows: [][]string{{"a"}, {"bb", "c", "d"}, {}},
+	}
+	c.Assert(table.Write(&buf), jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, "a\nbb  c  d\n\n")
+}
+
+type listCommand struct {
+	cmd.CommandBase
+	out cmd.Output
+}
+
+func (c *listCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "list-machines"}
+}
+
+func (c *listCommand) SetFlags(f *gnuflag.FlagSet) {
+	formatters := map[string]cmd.Formatter{
+		"tabular": output.TabularFormatter(machinesTable),
+	}
+	for name, formatter := range output.DefaultFormatters {
+		formatters[name] = formatter
+	}
+	c.out.AddFlags(f, "tabular", formatters)
+}
+
+func (c *listCommand) Run(ctx *cmd.Context) error {
+	return c.out.Write(ctx, machines)
+}
+
+func (*tableSuite) TestTabularFormat(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, &listCommand{})
+	c.Assert(err, jc.ErrorIsNil)
+	checkGolden(c, "table-truncated", ctx.Stdout.(*bytes.Buffer).Bytes())
+}
+
+func (*tableSuite) TestStructuredFormats(c *gc.C) {
+	unmarshalers := map[string]func([]byte, interface{}) error{
+		"json": json.Unmarshal,
+		"yaml": yaml.Unmarshal,
+	}
+	for format, unmarshal := range unmarshalers {
+		c.Logf("format %s", format)
+		ctx, err := coretesting.RunCommand(c, &listCommand{}, "--format", format)
+		c.Assert(err, jc.ErrorIsNil)
+		var result []machine
+		err = unmarshal(ctx.Stdout.(*bytes.Buffer).Bytes(), &result)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(result, jc.DeepEquals, machines)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DefaultPager is the pager used for help output when $PAGER is not
+// set. The flags make less exit if the text fits on one screen, pass
+// colour escapes through and leave the text on screen afterwards.
+const DefaultPager = "less -FRX"
+
+// isTerminal and terminalHeight are variables so that tests can
+// pretend stdout is a terminal. terminalHeight returns 0 if the height
+// cannot be determined.
+var (
+	isTerminal = func(f *os.File) bool {
+		return terminal.IsTerminal(int(f.Fd()))
+	}
+	terminalHeight = func(f *os.File) int {
+		_, height, err := terminal.GetSize(int(f.Fd()))
+		if err != nil {
+			return 0
+		}
+		return height
+	}
+)
+
+// PageHelp writes help text to the context's stdout. If stdout is a
+// terminal of known height and the text will not fit on it, the text
+// is piped through $PAGER, or DefaultPager if that is not set. If the
+// pager cannot be started the text is written directly; a pager
+// exiting with a non-zero status, for example because the user quit
+// it early, is not treated as an error.
+//
+// PageHelp must only be used for help and usage output, never for the
+// data output of a command.
+func PageHelp(ctx *cmd.Context, text []byte) error {
+	f, ok := ctx.Stdout.(*os.File)
+	if !ok || !isTerminal(f) {
+		return writeAll(ctx, text)
+	}
+	if height := terminalHeight(f); height <= 0 || bytes.Count(text, []byte("\n")) < height {
+		return writeAll(ctx, text)
+	}
+	pager := strings.Fields(ctx.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = strings.Fields(DefaultPager)
+	}
+	command := exec.Command(pager[0], pager[1:]...)
+	command.Dir = ctx.Dir
+	command.Env = contextEnviron(ctx)
+	command.Stdin = bytes.NewReader(text)
+	command.Stdout = ctx.Stdout
+	command.Stderr = ctx.Stderr
+	if err := command.Start(); err != nil {
+		logger.Debugf("cannot start pager %q: %v", pager[0], err)
+		return writeAll(ctx, text)
+	}
+	if err := command.Wait(); err != nil {
+		logger.Debugf("pager %q: %v", pager[0], err)
+	}
+	return nil
+}
+
+// ShowHelp writes the wrapped help text for the given command, invoked
+// as name, to the context's stdout using PageHelp. Hidden flags are
+// left out unless all is true, as with WrappedHelp.
+func ShowHelp(ctx *cmd.Context, c cmd.Command, name string, all bool) error {
+	return PageHelp(ctx, WrappedHelp(ctx, c, name, all))
+}
+
+func writeAll(ctx *cmd.Context, text []byte) error {
+	_, err := ctx.Stdout.Write(text)
+	return errors.Trace(err)
+}
+
+// contextEnviron returns the process environment with the context's
+// environment applied on top of it.
+func contextEnviron(ctx *cmd.Context) []string {
+	env := os.Environ()
+	for key, value := range ctx.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
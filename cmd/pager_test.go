@@ -0,0 +1,128 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// +build !windows
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type pagerSuite struct {
+	testing.IsolationSuite
+
+	ctx    *cmd.Context
+	stdout string
+}
+
+var _ = gc.Suite(&pagerSuite{})
+
+var longHelp = []byte(strings.Repeat("help line\n", 50))
+
+func (s *pagerSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	dir := c.MkDir()
+	s.stdout = filepath.Join(dir, "stdout")
+	f, err := os.Create(s.stdout)
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddCleanup(func(*gc.C) { f.Close() })
+	s.ctx = coretesting.Context(c)
+	s.ctx.Stdout = f
+}
+
+func (s *pagerSuite) output(c *gc.C) string {
+	data, err := ioutil.ReadFile(s.stdout)
+	c.Assert(err, jc.ErrorIsNil)
+	return string(data)
+}
+
+func (s *pagerSuite) TestNotTerminal(c *gc.C) {
+	jujucmd.PatchTerminal(s, false, 10)
+	s.PatchEnvironment("PAGER", "false")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, string(longHelp))
+}
+
+func (s *pagerSuite) TestFitsOnScreen(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 100)
+	s.PatchEnvironment("PAGER", "false")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, string(longHelp))
+}
+
+func (s *pagerSuite) TestPaged(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 10)
+	s.PatchEnvironment("PAGER", "sed s/help/paged/")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, strings.Replace(string(longHelp), "help", "paged", -1))
+}
+
+func (s *pagerSuite) TestPagerFailureIgnored(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 10)
+	s.PatchEnvironment("PAGER", "false")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *pagerSuite) TestPagerNotFound(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 10)
+	s.PatchEnvironment("PAGER", "/no/such/pager")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, string(longHelp))
+}
+
+func (s *pagerSuite) TestPagerInheritsContextEnvironment(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 10)
+	s.PatchEnvironment("PAGER", "")
+	s.ctx.Env = map[string]string{
+		"PAGER":    "printenv PAGED_BY",
+		"PAGED_BY": "context",
+	}
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, "context\n")
+}
+
+func (s *pagerSuite) TestUnknownHeight(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 0)
+	s.PatchEnvironment("PAGER", "false")
+	err := jujucmd.PageHelp(s.ctx, longHelp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.output(c), gc.Equals, string(longHelp))
+}
+
+func (s *pagerSuite) TestSubcommandHelpPaged(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 2)
+	s.PatchEnvironment("PAGER", "sed s/word/paged/g")
+	super := jujucmd.NewHelpSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.Register(&docCommand{})
+	code := cmd.Main(super, s.ctx, []string{"help", "doc"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(s.output(c), jc.Contains, "paged paged")
+	c.Assert(s.output(c), gc.Not(jc.Contains), "word")
+}
+
+func (s *pagerSuite) TestMainPagesHelp(c *gc.C) {
+	jujucmd.PatchTerminal(s, true, 2)
+	s.PatchEnvironment("PAGER", "sed s/word/paged/g")
+	code := jujucmd.Main(&docCommand{}, s.ctx, []string{"--help"})
+	c.Assert(code, gc.Equals, 0)
+	help := string(jujucmd.WrappedHelp(s.ctx, &docCommand{}, "doc", false))
+	c.Assert(s.output(c), gc.Equals, strings.Replace(help, "word", "paged", -1))
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/juju/cmd"
+)
+
+// windowsPathPattern matches paths with a drive letter or UNC prefix,
+// which must be left alone whatever platform we are running on.
+var windowsPathPattern = regexp.MustCompile(`^([a-zA-Z]:|\\\\)`)
+
+// AbsPath is like cmd.Context.AbsPath, but it also expands a leading
+// "~" or "~/" to the home directory taken from the context's
+// environment, and cleans the result. Paths that are already absolute,
+// including Windows paths with a drive letter, are returned unchanged.
+func AbsPath(ctx *cmd.Context, path string) string {
+	if filepath.IsAbs(path) || windowsPathPattern.MatchString(path) {
+		return path
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home := ctx.Getenv("HOME"); home != "" {
+			return filepath.Clean(filepath.Join(home, path[1:]))
+		}
+	}
+	return filepath.Clean(filepath.Join(ctx.Dir, path))
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+)
+
+type pathSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&pathSuite{})
+
+var absPathTests = []struct {
+	path     string
+	expected string
+}{
+	{"~", "/home/bob"},
+	{"~/", "/home/bob"},
+	{"~/x", "/home/bob/x"},
+	{"~/x/../y/", "/home/bob/y"},
+	{"~bob/x", "/work/dir/~bob/x"},
+	{"x", "/work/dir/x"},
+	{"./x", "/work/dir/x"},
+	{"../x", "/work/x"},
+	{"x//y/.", "/work/dir/x/y"},
+	{"/abs/path", "/abs/path"},
+	{"/abs/../unclean", "/abs/../unclean"},
+	{`C:\Users\bob`, `C:\Users\bob`},
+	{`\\server\share`, `\\server\share`},
+}
+
+func (s *pathSuite) TestAbsPath(c *gc.C) {
+	s.PatchEnvironment("HOME", "/home/bob")
+	ctx := &cmd.Context{Dir: "/work/dir"}
+	for i, test := range absPathTests {
+		c.Logf("test %d: %q", i, test.path)
+		c.Check(jujucmd.AbsPath(ctx, test.path), gc.Equals, test.expected)
+	}
+}
+
+func (s *pathSuite) TestAbsPathNoHome(c *gc.C) {
+	s.PatchEnvironment("HOME", "")
+	ctx := &cmd.Context{Dir: "/work/dir"}
+	c.Assert(jujucmd.AbsPath(ctx, "~/x"), gc.Equals, "/work/dir/~/x")
+}
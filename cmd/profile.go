@@ -0,0 +1,123 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// profilingFlags holds the names of the flags added by WrapProfiling.
+var profilingFlags = []string{"profile", "cpu-profile"}
+
+// WrapProfiling wraps a command so that it accepts the hidden
+// --profile and --cpu-profile flags. With --profile, the wall clock
+// time spent parsing flags, in Init and in Run is written to stderr
+// when Run completes. With --cpu-profile, a pprof CPU profile of Run
+// is written to the given path. The profile is stopped and flushed
+// even if Run fails or the command is interrupted.
+func WrapProfiling(c cmd.Command) cmd.Command {
+	return &profilingCommand{Command: c}
+}
+
+type profilingCommand struct {
+	cmd.Command
+
+	profile    bool
+	cpuProfile string
+
+	start     time.Time
+	parseTime time.Duration
+	initTime  time.Duration
+}
+
+// HiddenFlags implements HiddenFlagsCommand.
+func (c *profilingCommand) HiddenFlags() []string {
+	hidden := append([]string(nil), profilingFlags...)
+	if hc, ok := c.Command.(HiddenFlagsCommand); ok {
+		hidden = append(hidden, hc.HiddenFlags()...)
+	}
+	return hidden
+}
+
+// SetFlags implements cmd.Command.
+func (c *profilingCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.Command.SetFlags(f)
+	f.BoolVar(&c.profile, "profile", false, "Report time spent parsing, initialising and running the command")
+	f.StringVar(&c.cpuProfile, "cpu-profile", "", "Write a CPU profile of the command to this file")
+	c.start = time.Now()
+}
+
+// Init implements cmd.Command.
+func (c *profilingCommand) Init(args []string) error {
+	initStart := time.Now()
+	c.parseTime = initStart.Sub(c.start)
+	err := c.Command.Init(args)
+	c.initTime = time.Since(initStart)
+	return err
+}
+
+// Run implements cmd.Command.
+func (c *profilingCommand) Run(ctx *cmd.Context) (err error) {
+	if c.cpuProfile != "" {
+		stop, err := startCPUProfile(ctx, AbsPath(ctx, c.cpuProfile))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer stop()
+	}
+	if c.profile {
+		runStart := time.Now()
+		defer func() {
+			fmt.Fprintf(ctx.Stderr, "profile: parse %v, init %v, run %v\n",
+				c.parseTime, c.initTime, time.Since(runStart))
+		}()
+	}
+	return c.Command.Run(ctx)
+}
+
+// startCPUProfile starts writing a CPU profile to path and returns a
+// function that stops it. The profile is also stopped if the command
+// is interrupted.
+func startCPUProfile(ctx *cmd.Context, path string) (func(), error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot create CPU profile")
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, errors.Annotate(err, "cannot start CPU profile")
+	}
+	var once sync.Once
+	interrupted := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	stop := func() {
+		once.Do(func() {
+			pprof.StopCPUProfile()
+			if err := f.Close(); err != nil {
+				logger.Errorf("cannot write CPU profile: %v", err)
+			}
+		})
+	}
+	ctx.InterruptNotify(interrupted)
+	go func() {
+		select {
+		case <-interrupted:
+			stop()
+		case <-done:
+		}
+	}()
+	return func() {
+		ctx.StopInterruptNotify(interrupted)
+		close(done)
+		stop()
+	}, nil
+}
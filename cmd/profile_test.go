@@ -0,0 +1,70 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type profileSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&profileSuite{})
+
+func newProfilingSuper() *jujucmd.RunnerSuperCommand {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.EnableProfiling()
+	super.Register(&echoCommand{name: "echo"})
+	super.Register(&failingCommand{err: errors.New("boom")})
+	return super
+}
+
+func (*profileSuite) TestNoProfileByDefault(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newProfilingSuper(), ctx, []string{"echo"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*profileSuite) TestProfileSummary(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newProfilingSuper(), ctx, []string{"echo", "--profile"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "echo\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `profile: parse \S+, init \S+, run \S+\n`)
+}
+
+func (*profileSuite) TestProfileSummaryOnError(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newProfilingSuper(), ctx, []string{"fail", "--profile"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Matches, `(?s)profile: parse \S+, init \S+, run \S+\n.*boom.*`)
+}
+
+func (*profileSuite) TestCPUProfileWrittenOnError(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newProfilingSuper(), ctx, []string{"fail", "--cpu-profile", "cpu.prof"})
+	c.Assert(code, gc.Equals, 1)
+	info, err := os.Stat(filepath.Join(ctx.Dir, "cpu.prof"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Size() > 0, jc.IsTrue)
+}
+
+func (*profileSuite) TestFlagsHidden(c *gc.C) {
+	help := string(jujucmd.CommandHelp(jujucmd.WrapProfiling(&echoCommand{name: "echo"}), "echo", false))
+	c.Assert(help, gc.Not(jc.Contains), "profile")
+	help = string(jujucmd.CommandHelp(jujucmd.WrapProfiling(&echoCommand{name: "echo"}), "echo", true))
+	c.Assert(help, jc.Contains, "--cpu-profile")
+}
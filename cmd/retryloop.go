@@ -0,0 +1,192 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/utils/clock"
+)
+
+// ErrInterrupted is returned by RetryLoop when the command is
+// interrupted while waiting to retry.
+var ErrInterrupted = errors.New("interrupted")
+
+// randFloat64 returns the random numbers used to add jitter to retry
+// delays. It is patched in tests.
+var randFloat64 = rand.Float64
+
+// RetrySpec describes how often, and for how long, RetryLoop retries.
+type RetrySpec struct {
+	// Delay is the time to wait after the first attempt. It must be
+	// positive.
+	Delay time.Duration
+
+	// BackoffFactor is the factor by which the delay is multiplied
+	// after each attempt. If it is zero, the delay is constant;
+	// otherwise it must be at least 1.
+	BackoffFactor float64
+
+	// MaxDelay, if positive, limits the delay between attempts.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction, between 0 and 1, by which each delay
+	// is randomly reduced, so that commands started together do not
+	// retry in lockstep.
+	Jitter float64
+
+	// MaxAttempts, if positive, is the number of attempts after
+	// which RetryLoop gives up.
+	MaxAttempts int
+
+	// MaxDuration, if positive, is the time after which RetryLoop
+	// gives up. A last attempt is made when it has elapsed.
+	MaxDuration time.Duration
+
+	// IsTransient reports whether an error returned by the retried
+	// function is transient, in which case the function is tried
+	// again. If it is nil, all errors are terminal.
+	IsTransient func(error) bool
+
+	// Progress causes a dot to be written to stderr each time
+	// RetryLoop waits, followed by a newline when it finishes.
+	Progress bool
+
+	// Clock is used to measure delays. If it is nil, the wall clock
+	// is used.
+	Clock clock.Clock
+}
+
+// Validate returns an error if the spec is not valid.
+func (spec RetrySpec) Validate() error {
+	if spec.Delay <= 0 {
+		return errors.NotValidf("retry delay %v", spec.Delay)
+	}
+	if spec.BackoffFactor != 0 && spec.BackoffFactor < 1 {
+		return errors.NotValidf("backoff factor %v", spec.BackoffFactor)
+	}
+	if spec.Jitter < 0 || spec.Jitter > 1 {
+		return errors.NotValidf("jitter %v", spec.Jitter)
+	}
+	return nil
+}
+
+// RetryExhaustedError is returned by RetryLoop when it gives up
+// because the spec's MaxAttempts or MaxDuration has been reached.
+type RetryExhaustedError struct {
+	// Attempts holds the number of attempts made.
+	Attempts int
+
+	// Elapsed holds the time spent retrying.
+	Elapsed time.Duration
+
+	// LastError holds the transient error returned by the last
+	// attempt, if any.
+	LastError error
+}
+
+// Error implements error.
+func (e *RetryExhaustedError) Error() string {
+	msg := fmt.Sprintf("giving up after %d attempts in %v", e.Attempts, e.Elapsed)
+	if e.LastError != nil {
+		msg += ": " + e.LastError.Error()
+	}
+	return msg
+}
+
+// IsRetryExhausted reports whether err was returned by RetryLoop
+// because it gave up.
+func IsRetryExhausted(err error) bool {
+	_, ok := errors.Cause(err).(*RetryExhaustedError)
+	return ok
+}
+
+// RetryLoop calls fn until it reports that it is done, waiting between
+// attempts as described by spec. It is intended for commands that wait
+// for the state of the model to converge, such as for an agent to come
+// alive.
+//
+// A terminal error from fn is returned immediately; a transient one,
+// as reported by spec.IsTransient, is logged and fn is tried again. If
+// the command is interrupted while waiting, ErrInterrupted is returned.
+// If the spec's limits are reached first, a *RetryExhaustedError is
+// returned.
+func RetryLoop(ctx *cmd.Context, spec RetrySpec, fn func() (done bool, err error)) error {
+	interrupted := make(chan os.Signal, 1)
+	ctx.InterruptNotify(interrupted)
+	defer ctx.StopInterruptNotify(interrupted)
+	return retryLoop(ctx, spec, interrupted, fn)
+}
+
+// retryLoop implements RetryLoop, stopping when a value is received on
+// interrupted.
+func retryLoop(ctx *cmd.Context, spec RetrySpec, interrupted <-chan os.Signal, fn func() (bool, error)) error {
+	if err := spec.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	clk := spec.Clock
+	if clk == nil {
+		clk = clock.WallClock
+	}
+	waited := false
+	defer func() {
+		if waited {
+			fmt.Fprintln(ctx.Stderr)
+		}
+	}()
+	start := clk.Now()
+	delay := spec.Delay
+	for attempt := 1; ; attempt++ {
+		done, err := fn()
+		if err != nil {
+			if spec.IsTransient == nil || !spec.IsTransient(err) {
+				return errors.Trace(err)
+			}
+			logger.Debugf("attempt %d failed: %v", attempt, err)
+		} else if done {
+			return nil
+		}
+		exhausted := &RetryExhaustedError{
+			Attempts:  attempt,
+			Elapsed:   clk.Now().Sub(start),
+			LastError: err,
+		}
+		if spec.MaxAttempts > 0 && attempt >= spec.MaxAttempts {
+			return exhausted
+		}
+		wait := delay
+		if spec.Jitter > 0 {
+			wait -= time.Duration(spec.Jitter * randFloat64() * float64(wait))
+		}
+		if spec.MaxDuration > 0 {
+			remaining := spec.MaxDuration - exhausted.Elapsed
+			if remaining <= 0 {
+				return exhausted
+			}
+			if wait > remaining {
+				wait = remaining
+			}
+		}
+		if spec.Progress {
+			fmt.Fprint(ctx.Stderr, ".")
+			waited = true
+		}
+		select {
+		case <-interrupted:
+			return ErrInterrupted
+		case <-clk.After(wait):
+		}
+		if spec.BackoffFactor > 1 {
+			delay = time.Duration(float64(delay) * spec.BackoffFactor)
+		}
+		if spec.MaxDelay > 0 && delay > spec.MaxDelay {
+			delay = spec.MaxDelay
+		}
+	}
+}
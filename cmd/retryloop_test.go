@@ -0,0 +1,230 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type retryLoopSuite struct {
+	testing.IsolationSuite
+
+	clock       *testing.Clock
+	start       time.Time
+	interrupted chan os.Signal
+	attempts    []time.Duration
+}
+
+var _ = gc.Suite(&retryLoopSuite{})
+
+func (s *retryLoopSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.start = time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC)
+	s.clock = testing.NewClock(s.start)
+	s.interrupted = make(chan os.Signal, 1)
+	s.attempts = nil
+}
+
+// attempt returns a function for RetryLoop that records the time of
+// each attempt and returns the given results in turn.
+func (s *retryLoopSuite) attempt(results ...error) func() (bool, error) {
+	return func() (bool, error) {
+		s.attempts = append(s.attempts, s.clock.Now().Sub(s.start))
+		if len(results) == 0 {
+			return false, nil
+		}
+		err := results[0]
+		results = results[1:]
+		return err == nil, err
+	}
+}
+
+// run starts a retry loop, returning its context and a channel on
+// which its result is sent.
+func (s *retryLoopSuite) run(c *gc.C, spec jujucmd.RetrySpec, fn func() (bool, error)) (*cmd.Context, <-chan error) {
+	spec.Clock = s.clock
+	ctx := coretesting.Context(c)
+	result := make(chan error, 1)
+	go func() {
+		result <- jujucmd.RetryLoopInterruptible(ctx, spec, s.interrupted, fn)
+	}()
+	return ctx, result
+}
+
+// advance waits for the retry loop to start waiting, then advances the
+// clock by each of the given durations in turn.
+func (s *retryLoopSuite) advance(c *gc.C, ds ...time.Duration) {
+	for _, d := range ds {
+		select {
+		case <-s.clock.Alarms():
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("retry loop did not wait")
+		}
+		s.clock.Advance(d)
+	}
+}
+
+func waitResult(c *gc.C, result <-chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("retry loop did not finish")
+	}
+	panic("unreachable")
+}
+
+var notDone = errors.New("not done")
+
+func isNotDone(err error) bool {
+	return errors.Cause(err) == notDone
+}
+
+func (s *retryLoopSuite) TestDoneFirstTime(c *gc.C) {
+	_, result := s.run(c, jujucmd.RetrySpec{Delay: time.Second}, s.attempt(nil))
+	c.Assert(waitResult(c, result), jc.ErrorIsNil)
+	c.Assert(s.attempts, jc.DeepEquals, []time.Duration{0})
+}
+
+func (s *retryLoopSuite) TestBackoff(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:         time.Second,
+		BackoffFactor: 2,
+		MaxDelay:      5 * time.Second,
+		IsTransient:   isNotDone,
+	}
+	_, result := s.run(c, spec, s.attempt(notDone, notDone, notDone, notDone, notDone, nil))
+	s.advance(c, time.Second, 2*time.Second, 4*time.Second, 5*time.Second, 5*time.Second)
+	c.Assert(waitResult(c, result), jc.ErrorIsNil)
+	c.Assert(s.attempts, jc.DeepEquals, []time.Duration{
+		0, time.Second, 3 * time.Second, 7 * time.Second, 12 * time.Second, 17 * time.Second,
+	})
+}
+
+func (s *retryLoopSuite) TestJitter(c *gc.C) {
+	jujucmd.PatchRandFloat64(s, func() float64 { return 0.5 })
+	spec := jujucmd.RetrySpec{
+		Delay:         4 * time.Second,
+		BackoffFactor: 2,
+		Jitter:        0.5,
+	}
+	_, result := s.run(c, spec, s.attempt())
+	s.advance(c, 3*time.Second, 6*time.Second)
+	s.interrupted <- os.Interrupt
+	c.Assert(waitResult(c, result), gc.Equals, jujucmd.ErrInterrupted)
+	c.Assert(s.attempts, jc.DeepEquals, []time.Duration{0, 3 * time.Second, 9 * time.Second})
+}
+
+func (s *retryLoopSuite) TestTerminalError(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:       time.Second,
+		IsTransient: isNotDone,
+	}
+	_, result := s.run(c, spec, s.attempt(notDone, errors.New("boom")))
+	s.advance(c, time.Second)
+	err := waitResult(c, result)
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Assert(jujucmd.IsRetryExhausted(err), jc.IsFalse)
+	c.Assert(s.attempts, gc.HasLen, 2)
+}
+
+func (s *retryLoopSuite) TestErrorsTerminalByDefault(c *gc.C) {
+	_, result := s.run(c, jujucmd.RetrySpec{Delay: time.Second}, s.attempt(notDone))
+	c.Assert(waitResult(c, result), gc.ErrorMatches, "not done")
+	c.Assert(s.attempts, gc.HasLen, 1)
+}
+
+func (s *retryLoopSuite) TestMaxAttempts(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:       time.Second,
+		MaxAttempts: 3,
+		IsTransient: isNotDone,
+	}
+	_, result := s.run(c, spec, s.attempt(notDone, notDone, notDone))
+	s.advance(c, time.Second, time.Second)
+	err := waitResult(c, result)
+	c.Assert(err, gc.ErrorMatches, "giving up after 3 attempts in 2s: not done")
+	c.Assert(err, jc.Satisfies, jujucmd.IsRetryExhausted)
+	c.Assert(s.attempts, gc.HasLen, 3)
+}
+
+func (s *retryLoopSuite) TestMaxDuration(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:       time.Second,
+		MaxDuration: 2500 * time.Millisecond,
+	}
+	_, result := s.run(c, spec, s.attempt())
+	s.advance(c, time.Second, time.Second, 500*time.Millisecond)
+	err := waitResult(c, result)
+	c.Assert(err, gc.ErrorMatches, `giving up after 4 attempts in 2.5s`)
+	c.Assert(err, jc.Satisfies, jujucmd.IsRetryExhausted)
+	c.Assert(s.attempts, jc.DeepEquals, []time.Duration{
+		0, time.Second, 2 * time.Second, 2500 * time.Millisecond,
+	})
+}
+
+func (s *retryLoopSuite) TestInterrupted(c *gc.C) {
+	_, result := s.run(c, jujucmd.RetrySpec{Delay: time.Minute}, s.attempt())
+	s.advance(c, time.Second)
+	s.interrupted <- os.Interrupt
+	c.Assert(waitResult(c, result), gc.Equals, jujucmd.ErrInterrupted)
+	c.Assert(s.attempts, gc.HasLen, 1)
+}
+
+func (s *retryLoopSuite) TestProgress(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:       time.Second,
+		IsTransient: isNotDone,
+		Progress:    true,
+	}
+	ctx, result := s.run(c, spec, s.attempt(notDone, notDone, nil))
+	s.advance(c, time.Second, time.Second)
+	c.Assert(waitResult(c, result), jc.ErrorIsNil)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "..\n")
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (s *retryLoopSuite) TestNoProgressWithoutWaiting(c *gc.C) {
+	spec := jujucmd.RetrySpec{
+		Delay:    time.Second,
+		Progress: true,
+	}
+	ctx, result := s.run(c, spec, s.attempt(nil))
+	c.Assert(waitResult(c, result), jc.ErrorIsNil)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *retryLoopSuite) TestInvalidSpec(c *gc.C) {
+	for i, test := range []struct {
+		spec jujucmd.RetrySpec
+		err  string
+	}{{
+		spec: jujucmd.RetrySpec{},
+		err:  "retry delay 0s not valid",
+	}, {
+		spec: jujucmd.RetrySpec{Delay: time.Second, BackoffFactor: 0.5},
+		err:  "backoff factor 0.5 not valid",
+	}, {
+		spec: jujucmd.RetrySpec{Delay: time.Second, Jitter: 2},
+		err:  "jitter 2 not valid",
+	}} {
+		c.Logf("test %d", i)
+		s.attempts = nil
+		_, result := s.run(c, test.spec, s.attempt(nil))
+		err := waitResult(c, result)
+		c.Check(err, gc.ErrorMatches, test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		c.Check(s.attempts, gc.HasLen, 0)
+	}
+}
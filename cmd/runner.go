@@ -0,0 +1,180 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+)
+
+// CommandRunner is a hook invoked around the Run method of a
+// subcommand. It must call next to run the command (or deliberately
+// not, to prevent it running), and may act on the returned error
+// before returning it. Any error returned is handled by cmd.Main in
+// the usual way.
+//
+// Flag parsing and Init happen before any CommandRunner is invoked,
+// so usage errors never reach a runner.
+type CommandRunner func(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error
+
+// RunnerSuperCommand is a cmd.SuperCommand whose registered
+// subcommands are run through a chain of CommandRunners, for example
+// to time every command, write an audit record or release a lock file
+// regardless of the outcome.
+type RunnerSuperCommand struct {
+	*cmd.SuperCommand
+
+	mu                 sync.Mutex
+	runners            []CommandRunner
+	profiling          bool
+	flagDefaultsCtx    *cmd.Context
+	flagDefaultsEnvVar string
+	pipes              brokenPipes
+	warnings           subcommandWarnings
+}
+
+// NewRunnerSuperCommand returns a RunnerSuperCommand wrapping super.
+func NewRunnerSuperCommand(super *cmd.SuperCommand) *RunnerSuperCommand {
+	return &RunnerSuperCommand{SuperCommand: super}
+}
+
+// AddCommandRunner adds a runner to the chain. Runners compose in the
+// order they are added: the first runner added is the outermost, and
+// so sees the result of all the others. Runners apply to every
+// command registered through the RunnerSuperCommand, including those
+// registered before the runner was added.
+func (s *RunnerSuperCommand) AddCommandRunner(runner CommandRunner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners = append(s.runners, runner)
+}
+
+// EnableProfiling causes every command registered after it is called
+// to accept the hidden profiling flags described in WrapProfiling.
+func (s *RunnerSuperCommand) EnableProfiling() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiling = true
+}
+
+// EnableFlagDefaults causes every command registered after it is
+// called to take default flag values from the file named by the given
+// environment variable, as described in WrapFlagDefaults; ctx must be
+// the context the commands run in.
+func (s *RunnerSuperCommand) EnableFlagDefaults(ctx *cmd.Context, envVar string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flagDefaultsCtx = ctx
+	s.flagDefaultsEnvVar = envVar
+}
+
+// Register overrides cmd.SuperCommand.Register so that c is run
+// through the runner chain.
+func (s *RunnerSuperCommand) Register(c cmd.Command) {
+	s.SuperCommand.Register(s.wrap(c))
+}
+
+// RegisterDeprecated overrides cmd.SuperCommand.RegisterDeprecated so
+// that c is run through the runner chain.
+func (s *RunnerSuperCommand) RegisterDeprecated(c cmd.Command, check cmd.DeprecationCheck) {
+	s.SuperCommand.RegisterDeprecated(s.wrap(c), check)
+}
+
+func (s *RunnerSuperCommand) wrap(c cmd.Command) cmd.Command {
+	warnings, _ := c.(warningsCommand)
+	s.mu.Lock()
+	profiling := s.profiling
+	flagDefaultsCtx := s.flagDefaultsCtx
+	flagDefaultsEnvVar := s.flagDefaultsEnvVar
+	s.mu.Unlock()
+	if profiling {
+		c = WrapProfiling(c)
+	}
+	if flagDefaultsEnvVar != "" {
+		c = WrapFlagDefaults(flagDefaultsCtx, c, flagDefaultsEnvVar)
+	}
+	// A broken pipe is caught before the runners see it, so that
+	// they do not report it either.
+	wrapped := &runnerCommand{Command: s.pipes.wrap(c), super: s, warnings: warnings}
+	s.warnings.add(wrapped)
+	return wrapped
+}
+
+// Run overrides cmd.SuperCommand.Run so that, if the subcommand fails
+// because its stdout is a broken pipe, that error is returned rather
+// than cmd.ErrSilent, and Main can exit quietly.
+func (s *RunnerSuperCommand) Run(ctx *cmd.Context) error {
+	return s.pipes.runError(s.SuperCommand.Run(ctx))
+}
+
+// commandWarnings implements warningsCommand, passing on the warnings
+// of the subcommand that ran.
+func (s *RunnerSuperCommand) commandWarnings() ([]string, []*gnuflag.FlagSet) {
+	return s.warnings.commandWarnings()
+}
+
+// chain returns a copy of the current runners.
+func (s *RunnerSuperCommand) chain() []CommandRunner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CommandRunner(nil), s.runners...)
+}
+
+// runnerCommand wraps a command so that Run goes through the runner
+// chain of the supercommand it was registered with.
+type runnerCommand struct {
+	cmd.Command
+	super    *RunnerSuperCommand
+	flags    *gnuflag.FlagSet
+	warnings warningsCommand
+}
+
+// SetFlags implements cmd.Command, keeping the flag set so that the
+// flags given can be captured when the command runs.
+func (c *runnerCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.Command.SetFlags(f)
+	c.flags = f
+}
+
+// HiddenFlags implements HiddenFlagsCommand, so that wrapping a
+// command does not reveal its hidden flags.
+func (c *runnerCommand) HiddenFlags() []string {
+	if hc, ok := c.Command.(HiddenFlagsCommand); ok {
+		return hc.HiddenFlags()
+	}
+	return nil
+}
+
+// commandWarnings implements warningsCommand, so that the warnings of
+// the wrapped command are written with its own flags consulted.
+func (c *runnerCommand) commandWarnings() ([]string, []*gnuflag.FlagSet) {
+	if c.warnings == nil {
+		return nil, nil
+	}
+	messages, flags := c.warnings.commandWarnings()
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return messages, append(flags, c.flags)
+}
+
+// Run implements cmd.Command.
+func (c *runnerCommand) Run(ctx *cmd.Context) error {
+	recordFlags(ctx, c.flags)
+	info := c.Command.Info()
+	next := c.Command.Run
+	runners := c.super.chain()
+	for i := len(runners) - 1; i >= 0; i-- {
+		next = bindRunner(runners[i], next, info)
+	}
+	return next(ctx)
+}
+
+func bindRunner(runner CommandRunner, next func(*cmd.Context) error, info *cmd.Info) func(*cmd.Context) error {
+	return func(ctx *cmd.Context) error {
+		return runner(next, info, ctx)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type runnerSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&runnerSuite{})
+
+type failingCommand struct {
+	cmd.CommandBase
+	err error
+}
+
+func (c *failingCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "fail"}
+}
+
+func (c *failingCommand) Run(*cmd.Context) error {
+	return c.err
+}
+
+func recordingRunner(name string, calls *[]string) jujucmd.CommandRunner {
+	return func(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error {
+		*calls = append(*calls, name+" before "+info.Name)
+		err := next(ctx)
+		*calls = append(*calls, name+" after "+info.Name)
+		return err
+	}
+}
+
+func newRunnerSuper(calls *[]string) *jujucmd.RunnerSuperCommand {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.AddCommandRunner(recordingRunner("outer", calls))
+	super.Register(&echoCommand{name: "echo"})
+	super.Register(&failingCommand{err: errors.New("boom")})
+	super.AddCommandRunner(recordingRunner("inner", calls))
+	return super
+}
+
+func (*runnerSuite) TestRunnersCompose(c *gc.C) {
+	var calls []string
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newRunnerSuper(&calls), ctx, []string{"echo"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "echo\n")
+	c.Assert(calls, jc.DeepEquals, []string{
+		"outer before echo",
+		"inner before echo",
+		"inner after echo",
+		"outer after echo",
+	})
+}
+
+func (*runnerSuite) TestRunErrorSeenByRunners(c *gc.C) {
+	var calls []string
+	var seen error
+	super := newRunnerSuper(&calls)
+	super.AddCommandRunner(func(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error {
+		seen = next(ctx)
+		return seen
+	})
+	ctx := coretesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"fail"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(seen, gc.ErrorMatches, "boom")
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "boom")
+	c.Assert(calls, gc.HasLen, 4)
+}
+
+func (*runnerSuite) TestRunnerErrorHandledByMain(c *gc.C) {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.Register(&echoCommand{name: "echo"})
+	super.AddCommandRunner(func(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error {
+		return errors.New("cannot acquire lock")
+	})
+	ctx := coretesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"echo"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "cannot acquire lock")
+}
+
+func (*runnerSuite) TestUsageErrorsBypassRunners(c *gc.C) {
+	var calls []string
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newRunnerSuper(&calls), ctx, []string{"echo", "extra"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(calls, gc.HasLen, 0)
+}
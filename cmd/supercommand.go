@@ -35,6 +35,19 @@ var logger = loggo.GetLogger("juju.cmd")
 // - The version is configured to the current juju version;
 // - The command emits a log message when a command runs.
 func NewSuperCommand(p cmd.SuperCommandParams) *cmd.SuperCommand {
+	return cmd.NewSuperCommand(superCommandParams(p))
+}
+
+// NewVersionedSuperCommand is like NewSuperCommand, but returns a
+// VersionSuperCommand, so that the current juju version is also
+// reported by the "version" subcommand and the --version flag.
+func NewVersionedSuperCommand(p cmd.SuperCommandParams) *VersionSuperCommand {
+	return NewVersionSuperCommand(superCommandParams(p))
+}
+
+// superCommandParams returns p with the juju-specific settings
+// described in NewSuperCommand.
+func superCommandParams(p cmd.SuperCommandParams) cmd.SuperCommandParams {
 	p.Log = &cmd.Log{
 		DefaultConfig: os.Getenv(osenv.JujuLoggingConfigEnvKey),
 	}
@@ -49,7 +62,7 @@ func NewSuperCommand(p cmd.SuperCommandParams) *cmd.SuperCommand {
 	// tests to assert that this string value is correct.
 	p.Version = current.String()
 	p.NotifyRun = runNotifier
-	return cmd.NewSuperCommand(p)
+	return p
 }
 
 // NewSubSuperCommand should be used to create a SuperCommand
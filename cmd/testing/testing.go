@@ -4,7 +4,6 @@
 package testing
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"io"
@@ -13,10 +12,10 @@ import (
 	"os/exec"
 
 	"github.com/juju/cmd"
-	"github.com/juju/gnuflag"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
+	jujucmd "github.com/juju/juju/cmd"
 	"github.com/juju/juju/juju/osenv"
 	"github.com/juju/juju/provider/dummy"
 	coretesting "github.com/juju/juju/testing"
@@ -38,15 +37,12 @@ func BadRun(c *gc.C, exit int, args ...string) string {
 	return string(output)
 }
 
-// HelpText returns a command's formatted help text.
+// HelpText returns a command's formatted help text, omitting any
+// hidden flags, as the juju command writes it to a stdout that is not
+// a terminal.
 func HelpText(command cmd.Command, name string) string {
-	buff := &bytes.Buffer{}
-	info := command.Info()
-	info.Name = name
-	f := gnuflag.NewFlagSet(info.Name, gnuflag.ContinueOnError)
-	command.SetFlags(f)
-	buff.Write(info.Help(f))
-	return buff.String()
+	ctx := &cmd.Context{Stdout: ioutil.Discard}
+	return string(jujucmd.WrappedHelp(ctx, command, name, false))
 }
 
 // NullContext returns a no-op command context.
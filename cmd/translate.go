@@ -0,0 +1,149 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+)
+
+var (
+	translatorMu sync.RWMutex
+	translator   func(string) string
+)
+
+// SetTranslator sets the function used to translate the help and
+// error strings shown to the user: the purpose and documentation of
+// commands, the descriptions of their flags, the section headers of
+// their help, and the prefix of errors reported by
+// TranslatedErrorRunner. Passing nil restores the default, which
+// leaves strings untouched.
+//
+// A translation that does not keep the formatting verbs of the
+// original, such as %v or %q, is not used: a warning is logged and the
+// original string is shown instead.
+func SetTranslator(t func(string) string) {
+	translatorMu.Lock()
+	defer translatorMu.Unlock()
+	translator = t
+}
+
+// T returns the translation of s made by the function passed to
+// SetTranslator, or s itself if there is none or the translation
+// changes the formatting verbs of s.
+func T(s string) string {
+	translatorMu.RLock()
+	t := translator
+	translatorMu.RUnlock()
+	if t == nil || s == "" {
+		return s
+	}
+	translated := t(s)
+	if !sameVerbs(s, translated) {
+		logger.Warningf("ignoring translation %q of %q: formatting verbs differ", translated, s)
+		return s
+	}
+	return translated
+}
+
+// translating reports whether a translator has been set.
+func translating() bool {
+	translatorMu.RLock()
+	defer translatorMu.RUnlock()
+	return translator != nil
+}
+
+// verbPattern matches the formatting verbs understood by package fmt,
+// with any flags, width, precision and explicit argument indexes.
+var verbPattern = regexp.MustCompile(`%[-+# 0]*(\[\d+\])?(\*|\d+)?(\.(\[\d+\])?(\*|\d+)?)?(\[\d+\])?[a-zA-Z%]`)
+
+// argIndexPattern matches explicit argument indexes, which a
+// translation may use to reorder its arguments.
+var argIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// sameVerbs reports whether a and b hold the same formatting verbs,
+// in any order.
+func sameVerbs(a, b string) bool {
+	verbsA, verbsB := verbs(a), verbs(b)
+	if len(verbsA) != len(verbsB) {
+		return false
+	}
+	for i := range verbsA {
+		if verbsA[i] != verbsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// verbs returns the sorted formatting verbs of s, without explicit
+// argument indexes.
+func verbs(s string) []string {
+	found := verbPattern.FindAllString(s, -1)
+	for i, verb := range found {
+		found[i] = argIndexPattern.ReplaceAllString(verb, "")
+	}
+	sort.Strings(found)
+	return found
+}
+
+// helpHeaderPattern matches the section headers of the help generated
+// by cmd.Info.Help.
+var helpHeaderPattern = regexp.MustCompile(`(?m)^(Usage|Summary|Options|Details|Aliases):`)
+
+// translateInfo translates the purpose and documentation of info in
+// place.
+func translateInfo(info *cmd.Info) {
+	info.Purpose = T(info.Purpose)
+	info.Doc = T(info.Doc)
+}
+
+// translateFlags returns a flag set holding the flags of f with their
+// descriptions translated, sharing values with f. Like the flag set
+// returned by VisibleFlags, it is intended only for rendering help.
+func translateFlags(f *gnuflag.FlagSet) *gnuflag.FlagSet {
+	if !translating() {
+		return f
+	}
+	translated := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		translated.Var(flag.Value, flag.Name, T(flag.Usage))
+	})
+	return translated
+}
+
+// translateHelp translates the section headers of help generated by
+// cmd.Info.Help.
+func translateHelp(help []byte) []byte {
+	if !translating() {
+		return help
+	}
+	return helpHeaderPattern.ReplaceAllFunc(help, func(header []byte) []byte {
+		return []byte(T(string(header)))
+	})
+}
+
+// TranslatedErrorRunner is a CommandRunner that, when a translator has
+// been set with SetTranslator, reports errors returned by Run itself,
+// with the "ERROR" prefix translated, in place of cmd.Main. Like
+// ErrorFormatRunner, it then returns cmd.ErrSilent, and passes
+// cmd.ErrSilent and errors carrying an explicit exit code through
+// untouched. Usage errors never reach a runner, and so are reported
+// untranslated by cmd.Main.
+func TranslatedErrorRunner(next func(*cmd.Context) error, info *cmd.Info, ctx *cmd.Context) error {
+	err := next(ctx)
+	if err == nil || err == cmd.ErrSilent || cmd.IsRcPassthroughError(err) {
+		return err
+	}
+	if !translating() {
+		return err
+	}
+	fmt.Fprintf(ctx.Stderr, "%s %v\n", T("ERROR"), err)
+	return cmd.ErrSilent
+}
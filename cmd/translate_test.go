@@ -0,0 +1,148 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type translateSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&translateSuite{})
+
+func (s *translateSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.AddCleanup(func(*gc.C) { jujucmd.SetTranslator(nil) })
+}
+
+// setTranslations sets a translator that translates the given strings,
+// and leaves all others untouched.
+func setTranslations(translations map[string]string) {
+	jujucmd.SetTranslator(func(s string) string {
+		if t, ok := translations[s]; ok {
+			return t
+		}
+		return s
+	})
+}
+
+func (s *translateSuite) TestNoTranslator(c *gc.C) {
+	c.Assert(jujucmd.T("cannot find %q"), gc.Equals, "cannot find %q")
+	help := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "bootstrap", false))
+	c.Assert(help, jc.Contains, "Start a controller.")
+	c.Assert(help, jc.Contains, "Options:")
+}
+
+func (s *translateSuite) TestHelpTranslated(c *gc.C) {
+	setTranslations(map[string]string{
+		"Start a controller.":     "Démarrer un contrôleur.",
+		"The series to bootstrap": "La série à démarrer",
+		"Usage:":                  "Utilisation :",
+		"Summary:":                "Résumé :",
+		"Options:":                "Options :",
+	})
+	help := string(jujucmd.CommandHelp(&hiddenFlagsCommand{}, "bootstrap", false))
+	c.Assert(help, gc.Matches, `(?s)Utilisation : bootstrap.*`)
+	c.Assert(help, jc.Contains, "\nRésumé :\nDémarrer un contrôleur.\n")
+	c.Assert(help, jc.Contains, "\nOptions :\n")
+	c.Assert(help, jc.Contains, "La série à démarrer")
+	c.Assert(help, gc.Not(jc.Contains), "upload-tools")
+}
+
+func (s *translateSuite) TestDeprecatedAliasTranslated(c *gc.C) {
+	setTranslations(map[string]string{
+		"Deprecated; use %s instead": "Obsolète ; utilisez %s",
+	})
+	help := string(jujucmd.CommandHelp(newDestroyModelCommand(), "destroy-model", true))
+	c.Assert(help, jc.Contains, "Obsolète ; utilisez --model")
+	c.Assert(help, gc.Not(jc.Contains), "Deprecated")
+}
+
+var verbTests = []struct {
+	about       string
+	original    string
+	translation string
+	expected    string
+}{{
+	about:       "no verbs",
+	original:    "Options:",
+	translation: "Optionen:",
+	expected:    "Optionen:",
+}, {
+	about:       "same verbs",
+	original:    "unit %q not found after %d attempts",
+	translation: "Einheit %q nach %d Versuchen nicht gefunden",
+	expected:    "Einheit %q nach %d Versuchen nicht gefunden",
+}, {
+	about:       "reordered verbs",
+	original:    "unit %q not found after %d attempts",
+	translation: "nach %[2]d Versuchen Einheit %[1]q nicht gefunden",
+	expected:    "nach %[2]d Versuchen Einheit %[1]q nicht gefunden",
+}, {
+	about:       "changed verb",
+	original:    "unit %q not found",
+	translation: "Einheit %v nicht gefunden",
+	expected:    "unit %q not found",
+}, {
+	about:       "missing verb",
+	original:    "cannot connect: %v",
+	translation: "Verbindung fehlgeschlagen",
+	expected:    "cannot connect: %v",
+}, {
+	about:       "extra verb",
+	original:    "Deprecated; use %s instead",
+	translation: "Veraltet; %s statt %s verwenden",
+	expected:    "Deprecated; use %s instead",
+}}
+
+func (s *translateSuite) TestVerbsPreserved(c *gc.C) {
+	for i, test := range verbTests {
+		c.Logf("test %d: %s", i, test.about)
+		setTranslations(map[string]string{test.original: test.translation})
+		c.Check(jujucmd.T(test.original), gc.Equals, test.expected)
+	}
+}
+
+func (s *translateSuite) TestMangledTranslationWarns(c *gc.C) {
+	setTranslations(map[string]string{
+		"Deprecated; use %s instead": "Obsolète ; utilisez %v",
+	})
+	help := string(jujucmd.CommandHelp(newDestroyModelCommand(), "destroy-model", true))
+	c.Assert(help, jc.Contains, "Deprecated; use --model instead")
+	c.Assert(c.GetTestLog(), jc.Contains,
+		`ignoring translation "Obsolète ; utilisez %v" of "Deprecated; use %s instead": formatting verbs differ`)
+}
+
+func (s *translateSuite) TestTranslatedErrorRunner(c *gc.C) {
+	failing := func(*cmd.Context) error {
+		return errors.New("boom")
+	}
+	info := &cmd.Info{Name: "boom"}
+
+	ctx := coretesting.Context(c)
+	err := jujucmd.TranslatedErrorRunner(failing, info, ctx)
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+
+	setTranslations(map[string]string{"ERROR": "FEHLER"})
+	ctx = coretesting.Context(c)
+	err = jujucmd.TranslatedErrorRunner(failing, info, ctx)
+	c.Assert(err, gc.Equals, cmd.ErrSilent)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "FEHLER boom\n")
+
+	ctx = coretesting.Context(c)
+	silent := func(*cmd.Context) error { return cmd.ErrSilent }
+	err = jujucmd.TranslatedErrorRunner(silent, info, ctx)
+	c.Assert(err, gc.Equals, cmd.ErrSilent)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
@@ -0,0 +1,192 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+
+	"github.com/juju/juju/cmd/output"
+)
+
+// BrokenPipeExitCode is the exit code of a command whose stdout was
+// closed before it finished writing, as when its output is piped into
+// "head". It is the code a shell reports for a process killed by
+// SIGPIPE.
+const BrokenPipeExitCode = 141
+
+// Main runs the given command with the given arguments, in the same
+// way as cmd.Main, and returns the exit code. It differs only in how
+// usage errors are reported: an error parsing the arguments, or an
+// error from cmd.CheckEmpty, is followed by the usage line of the
+// command that rejected them, and, when the error is likely to be due
+// to the order of the arguments, by a note explaining the rule.
+//
+// There are two such ordering mistakes. Flags of a subcommand must
+// follow its name, so "juju --format json status" fails, as the
+// supercommand has no --format flag. And a command that does not
+// allow interspersed flags treats everything after its first
+// positional argument as arguments, so "juju ssh 0 --proxy" fails if
+// ssh does not expect another argument.
+//
+// Help asked for with --help is written with ShowHelp, so that it is
+// wrapped to the width of the terminal, and paged if it does not fit.
+//
+// If the command fails because its stdout is a broken pipe, as
+// reported by output.IsBrokenPipe, nothing more is written and Main
+// returns BrokenPipeExitCode; other errors are still reported on
+// stderr. This holds for the subcommands of a RunnerSuperCommand or
+// VersionSuperCommand too, though cmd.SuperCommand would otherwise
+// report their errors itself.
+//
+// A dry run (see DryRun) that renders its plan exits with 0, even if
+// the command then fails because PlanWriter.Confirm refused to confirm.
+//
+// Warnings added while the command runs, as described for Warnings,
+// are written to stderr after it returns, even if it fails with
+// cmd.ErrSilent.
+//
+// When the context's environment names a capture file in
+// JUJU_CAPTURE_FILE, a CaptureRecord of the invocation is appended to
+// it; see Replay.
+func Main(c cmd.Command, ctx *cmd.Context, args []string) int {
+	capture := startCapture(ctx, args)
+	f := gnuflag.NewFlagSet(c.Info().Name, gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	code := runMain(c, ctx, f, args, capture)
+	flushWarnings(c, ctx, f)
+	capture.finish(c, ctx, code)
+	return code
+}
+
+// runMain implements Main, parsing args with f and marking the phases
+// of the invocation in capture.
+func runMain(c cmd.Command, ctx *cmd.Context, f *gnuflag.FlagSet, args []string, capture *invocationCapture) int {
+	c.SetFlags(f)
+	capture.addFlags(f)
+	err := f.Parse(c.AllowInterspersedFlags(), args)
+	if rc, done := handleCommandError(c, ctx, err, true); done {
+		return rc
+	}
+	capture.parsed()
+	// A supercommand parses the flags of its subcommand in Init, so
+	// errors from Init may be usage errors too.
+	if rc, done := handleCommandError(c, ctx, c.Init(f.Args()), false); done {
+		return rc
+	}
+	capture.initialised()
+	if err := c.Run(ctx); err != nil {
+		if cmd.IsRcPassthroughError(err) {
+			return err.(*cmd.RcPassthroughError).Code
+		}
+		if output.IsBrokenPipe(err) {
+			return BrokenPipeExitCode
+		}
+		if isRenderedDryRun(err) {
+			return 0
+		}
+		if err != cmd.ErrSilent {
+			fmt.Fprintf(ctx.Stderr, "ERROR %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// handleCommandError reports an error from parsing the arguments of c,
+// or from initialising it, as cmd.Main does, adding the usage notes
+// described in Main. The info of c is read only after the error has
+// occurred, so that a supercommand describes the subcommand chosen, if
+// any. It returns the exit code, and whether the command is done.
+func handleCommandError(c cmd.Command, ctx *cmd.Context, err error, parsing bool) (int, bool) {
+	switch err {
+	case nil:
+		return 0, false
+	case gnuflag.ErrHelp:
+		if err := ShowHelp(ctx, c, c.Info().Name, false); output.IsBrokenPipe(err) {
+			return BrokenPipeExitCode, true
+		}
+		return 0, true
+	case cmd.ErrSilent:
+		return 2, true
+	}
+	fmt.Fprintf(ctx.Stderr, "error: %v\n", err)
+	info := c.Info()
+	if note := orderingNote(errors.Cause(err).Error(), parsing && c.IsSuperCommand()); note != "" {
+		fmt.Fprintln(ctx.Stderr, note)
+	}
+	if isUsageError(errors.Cause(err).Error()) {
+		fmt.Fprintln(ctx.Stderr, UsageLine(info))
+	}
+	return 2, true
+}
+
+const (
+	// unrecognizedArgsPrefix starts the errors of cmd.CheckEmpty.
+	unrecognizedArgsPrefix = "unrecognized args: "
+
+	// undefinedFlagPrefix starts the errors gnuflag returns for
+	// flags that are not defined.
+	undefinedFlagPrefix = "flag provided but not defined: "
+)
+
+// flagErrorPrefixes start the errors gnuflag returns for arguments it
+// cannot parse.
+var flagErrorPrefixes = []string{
+	undefinedFlagPrefix,
+	"flag needs an argument: ",
+	"invalid value ",
+	"invalid boolean value ",
+	"bad flag syntax: ",
+}
+
+// quotedFlagPattern matches a flag in the quoted argument list of an
+// unrecognized args error.
+var quotedFlagPattern = regexp.MustCompile(`"--?[^"-]`)
+
+// isUsageError reports whether msg is the message of an error about
+// the arguments given to a command, which is worth following with its
+// usage line.
+func isUsageError(msg string) bool {
+	if strings.HasPrefix(msg, unrecognizedArgsPrefix) {
+		return true
+	}
+	for _, prefix := range flagErrorPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderingNote returns a note explaining the ordering rule broken by
+// the arguments rejected with the given error message, or "" if the
+// error does not look like the result of misplaced arguments.
+// beforeSubcommand is true if the error arose parsing the arguments
+// of a supercommand that precede the subcommand name.
+func orderingNote(msg string, beforeSubcommand bool) string {
+	switch {
+	case beforeSubcommand && strings.HasPrefix(msg, undefinedFlagPrefix):
+		return T("options of a command must be given after the command name")
+	case strings.HasPrefix(msg, unrecognizedArgsPrefix) && quotedFlagPattern.MatchString(msg):
+		return T("options must be given before the positional arguments of this command")
+	}
+	return ""
+}
+
+// UsageLine returns the usage line of the command with the given info,
+// in the form "usage: <name> [options] <args>".
+func UsageLine(info *cmd.Info) string {
+	usage := info.Name + " [options]"
+	if info.Args != "" {
+		usage += " " + info.Args
+	}
+	return fmt.Sprintf(T("usage: %s"), usage)
+}
@@ -0,0 +1,218 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	"github.com/juju/juju/juju/osenv"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type usageSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&usageSuite{})
+
+// targetCommand takes a single target, and does not allow flags to
+// follow it.
+type targetCommand struct {
+	cmd.CommandBase
+	proxy  bool
+	target string
+}
+
+func (c *targetCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "ssh", Args: "<target>", Purpose: "Connect to a target."}
+}
+
+func (c *targetCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.proxy, "proxy", false, "Proxy through the controller")
+}
+
+func (c *targetCommand) AllowInterspersedFlags() bool {
+	return false
+}
+
+func (c *targetCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no target specified")
+	}
+	c.target = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *targetCommand) Run(*cmd.Context) error {
+	if c.target == "unreachable" {
+		return errors.Errorf("cannot connect to %s", c.target)
+	}
+	return nil
+}
+
+func newUsageSuper() *cmd.SuperCommand {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	})
+	super.Register(&targetCommand{})
+	return super
+}
+
+func runUsage(c *gc.C, com cmd.Command, args ...string) (int, string) {
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(com, ctx, args)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	return code, coretesting.Stderr(ctx)
+}
+
+func (*usageSuite) TestSuccess(c *gc.C) {
+	code, stderr := runUsage(c, newUsageSuper(), "--debug", "ssh", "--proxy", "0")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(stderr, gc.Equals, "")
+}
+
+func (*usageSuite) TestSubcommandOptionBeforeName(c *gc.C) {
+	code, stderr := runUsage(c, newUsageSuper(), "--proxy", "ssh", "0")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(stderr, gc.Equals, ""+
+		"error: flag provided but not defined: --proxy\n"+
+		"options of a command must be given after the command name\n"+
+		"usage: juju [options] <command> ...\n")
+}
+
+func (*usageSuite) TestOptionAfterArgs(c *gc.C) {
+	code, stderr := runUsage(c, newUsageSuper(), "ssh", "0", "--proxy")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(stderr, gc.Equals, ""+
+		`error: unrecognized args: ["--proxy"]`+"\n"+
+		"options must be given before the positional arguments of this command\n"+
+		"usage: juju ssh [options] <target>\n")
+}
+
+func (*usageSuite) TestUnknownSubcommandOption(c *gc.C) {
+	code, stderr := runUsage(c, newUsageSuper(), "ssh", "--cheese", "0")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(stderr, gc.Equals, ""+
+		"error: flag provided but not defined: --cheese\n"+
+		"usage: juju ssh [options] <target>\n")
+}
+
+func (*usageSuite) TestExtraArgs(c *gc.C) {
+	code, stderr := runUsage(c, &targetCommand{}, "0", "1")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(stderr, gc.Equals, ""+
+		`error: unrecognized args: ["1"]`+"\n"+
+		"usage: ssh [options] <target>\n")
+}
+
+func (*usageSuite) TestOtherInitError(c *gc.C) {
+	code, stderr := runUsage(c, &targetCommand{})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(stderr, gc.Equals, "error: no target specified\n")
+}
+
+func (*usageSuite) TestRunError(c *gc.C) {
+	code, stderr := runUsage(c, &targetCommand{}, "unreachable")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(stderr, gc.Equals, "ERROR cannot connect to unreachable\n")
+}
+
+func (*usageSuite) TestUsageLine(c *gc.C) {
+	c.Assert(jujucmd.UsageLine(&cmd.Info{Name: "juju status"}), gc.Equals, "usage: juju status [options]")
+	c.Assert(jujucmd.UsageLine(&cmd.Info{Name: "juju ssh", Args: "<target>"}), gc.Equals, "usage: juju ssh [options] <target>")
+}
+
+// pipeWriter behaves like a pipe whose reader goes away after reading
+// limit bytes.
+type pipeWriter struct {
+	limit   int
+	written []byte
+	failed  int
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	if len(w.written)+len(p) > w.limit {
+		w.failed++
+		return 0, &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+	}
+	w.written = append(w.written, p...)
+	return len(p), nil
+}
+
+// pipeListCommand writes lines to stdout until it has written them all, or
+// a write fails, and then returns err.
+type pipeListCommand struct {
+	cmd.CommandBase
+	err error
+}
+
+func (c *pipeListCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "list"}
+}
+
+func (c *pipeListCommand) Run(ctx *cmd.Context) error {
+	for i := 0; i < 100; i++ {
+		if _, err := fmt.Fprintf(ctx.Stdout, "line %d\n", i); err != nil {
+			return errors.Annotate(err, "cannot write list")
+		}
+	}
+	return c.err
+}
+
+func (*usageSuite) TestBrokenPipe(c *gc.C) {
+	ctx := coretesting.Context(c)
+	stdout := &pipeWriter{limit: 14}
+	ctx.Stdout = stdout
+	code := jujucmd.Main(&pipeListCommand{}, ctx, nil)
+	c.Assert(code, gc.Equals, jujucmd.BrokenPipeExitCode)
+	c.Assert(string(stdout.written), gc.Equals, "line 0\nline 1\n")
+	c.Assert(stdout.failed, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*usageSuite) TestBrokenPipeJSONErrors(c *gc.C) {
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{osenv.JujuErrorFormatEnvKey: jujucmd.ErrorFormatJSON}
+	stdout := &pipeWriter{limit: 7}
+	ctx.Stdout = stdout
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.AddCommandRunner(jujucmd.ErrorFormatRunner)
+	super.Register(&pipeListCommand{})
+	// The broken pipe is caught before cmd.SuperCommand can report it
+	// and replace it with cmd.ErrSilent, so neither the supercommand
+	// nor ErrorFormatRunner writes anything to stderr.
+	code := jujucmd.Main(super, ctx, []string{"list"})
+	c.Assert(code, gc.Equals, jujucmd.BrokenPipeExitCode)
+	c.Assert(string(stdout.written), gc.Equals, "line 0\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*usageSuite) TestBrokenPipeVersionSuperCommand(c *gc.C) {
+	ctx := coretesting.Context(c)
+	ctx.Stdout = &pipeWriter{}
+	super := jujucmd.NewVersionSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	super.Register(&pipeListCommand{})
+	code := jujucmd.Main(super, ctx, []string{"list"})
+	c.Assert(code, gc.Equals, jujucmd.BrokenPipeExitCode)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*usageSuite) TestBrokenNetworkPipeIsReported(c *gc.C) {
+	ctx := coretesting.Context(c)
+	err := &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}
+	code := jujucmd.Main(&pipeListCommand{err: err}, ctx, nil)
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "ERROR write tcp: broken pipe\n")
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/utils/set"
+)
+
+// VersionSuperCommand is a HelpSuperCommand that reports its version,
+// both with a "version" subcommand and with a --version flag given
+// before any subcommand.
+type VersionSuperCommand struct {
+	*HelpSuperCommand
+
+	version     string
+	showVersion bool
+	pipes       brokenPipes
+	warnings    subcommandWarnings
+
+	mu       sync.Mutex
+	obsolete set.Strings
+}
+
+// NewVersionSuperCommand returns a VersionSuperCommand reporting
+// p.Version. If p.Version is empty, neither the "version" subcommand
+// nor the --version flag is available, rather than reporting an
+// unknown version.
+func NewVersionSuperCommand(p cmd.SuperCommandParams) *VersionSuperCommand {
+	s := &VersionSuperCommand{
+		version:  p.Version,
+		obsolete: set.NewStrings(),
+	}
+	// The version is reported here rather than by cmd.SuperCommand,
+	// so that both the subcommand and the flag behave the same way.
+	p.Version = ""
+	s.HelpSuperCommand = NewHelpSuperCommand(cmd.NewSuperCommand(p))
+	if s.version != "" {
+		s.Register(&versionCommand{version: s.version})
+	}
+	return s
+}
+
+// Register overrides HelpSuperCommand.Register so that Main can tell
+// when c fails because its stdout is a broken pipe, and can write the
+// warnings of c.
+func (s *VersionSuperCommand) Register(c cmd.Command) {
+	s.warnings.add(c)
+	s.HelpSuperCommand.Register(s.pipes.wrap(c))
+}
+
+// RegisterDeprecated overrides HelpSuperCommand.RegisterDeprecated so
+// that Main can tell when c fails because its stdout is a broken pipe,
+// and can write the warnings of c.
+func (s *VersionSuperCommand) RegisterDeprecated(c cmd.Command, check cmd.DeprecationCheck) {
+	s.warnings.add(c)
+	s.HelpSuperCommand.RegisterDeprecated(s.pipes.wrap(c), check)
+}
+
+// RegisterObsolete registers name as a command that has been removed
+// in favour of replacement, as the RegisterObsolete function does.
+// Any arguments given after name, flags included, are ignored, so
+// that flags meant for the removed command do not cause a usage error
+// that would hide the real problem.
+func (s *VersionSuperCommand) RegisterObsolete(name, replacement string) {
+	s.RegisterDeprecated(newObsoleteCommand(name, replacement), DeprecatedBy(replacement))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.obsolete.Add(name)
+}
+
+// SetFlags implements cmd.Command.
+func (s *VersionSuperCommand) SetFlags(f *gnuflag.FlagSet) {
+	s.HelpSuperCommand.SetFlags(f)
+	if s.version != "" {
+		f.BoolVar(&s.showVersion, "version", false, "Show the version and exit")
+	}
+}
+
+// Init implements cmd.Command. With --version, the remaining arguments
+// are ignored and no subcommand is run. The arguments following the
+// name of an obsolete command are dropped, as described in
+// RegisterObsolete.
+func (s *VersionSuperCommand) Init(args []string) error {
+	if s.showVersion {
+		return nil
+	}
+	return s.HelpSuperCommand.Init(s.obsoleteArgs(args))
+}
+
+// obsoleteArgs returns args, or just the first of them if it names a
+// command registered with RegisterObsolete.
+func (s *VersionSuperCommand) obsoleteArgs(args []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(args) > 0 && s.obsolete.Contains(args[0]) {
+		return args[:1]
+	}
+	return args
+}
+
+// commandWarnings implements warningsCommand, passing on the warnings
+// of the subcommand that ran.
+func (s *VersionSuperCommand) commandWarnings() ([]string, []*gnuflag.FlagSet) {
+	return s.warnings.commandWarnings()
+}
+
+// Run implements cmd.Command. If the subcommand fails because its
+// stdout is a broken pipe, that error is returned, rather than the
+// cmd.ErrSilent that cmd.SuperCommand would return, so that Main can
+// exit quietly.
+func (s *VersionSuperCommand) Run(ctx *cmd.Context) error {
+	if s.showVersion {
+		_, err := fmt.Fprintln(ctx.Stdout, s.version)
+		return errors.Trace(err)
+	}
+	return s.pipes.runError(s.HelpSuperCommand.Run(ctx))
+}
+
+const versionDoc = `
+Print the version of the command, as a plain string by default, or
+as {"version": "..."} with --format json.
+`
+
+// versionCommand is the "version" subcommand registered by
+// NewVersionSuperCommand.
+type versionCommand struct {
+	cmd.CommandBase
+	out     cmd.Output
+	version string
+}
+
+// versionInfo is the structured form of the version, as written with
+// --format json or yaml.
+type versionInfo struct {
+	Version string `json:"version" yaml:"version"`
+}
+
+func (c *versionCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "version",
+		Purpose: "Print the current version.",
+		Doc:     versionDoc,
+	}
+}
+
+func (c *versionCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "smart", map[string]cmd.Formatter{
+		"smart": formatVersionSmart,
+		"json":  cmd.FormatJson,
+		"yaml":  cmd.FormatYaml,
+	})
+}
+
+func (c *versionCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+func (c *versionCommand) Run(ctx *cmd.Context) error {
+	return c.out.Write(ctx, versionInfo{Version: c.version})
+}
+
+// formatVersionSmart writes just the version string.
+func formatVersionSmart(w io.Writer, value interface{}) error {
+	info, ok := value.(versionInfo)
+	if !ok {
+		return errors.Errorf("expected version, got %T", value)
+	}
+	_, err := fmt.Fprintln(w, info.Version)
+	return errors.Trace(err)
+}
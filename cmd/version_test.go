@@ -0,0 +1,81 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type versionSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&versionSuite{})
+
+func newVersionSuper(version string) *jujucmd.VersionSuperCommand {
+	super := jujucmd.NewVersionSuperCommand(cmd.SuperCommandParams{
+		Name:    "juju",
+		Version: version,
+	})
+	super.Register(&echoCommand{name: "status"})
+	return super
+}
+
+func (*versionSuite) TestVersionCommand(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, newVersionSuper("2.0.1-xenial-amd64"), "version")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "2.0.1-xenial-amd64\n")
+}
+
+func (*versionSuite) TestVersionCommandFormats(c *gc.C) {
+	for format, expected := range map[string]string{
+		"json": `{"version":"2.0.1-xenial-amd64"}` + "\n",
+		"yaml": "version: 2.0.1-xenial-amd64\n",
+	} {
+		c.Logf("format %s", format)
+		ctx, err := coretesting.RunCommand(c, newVersionSuper("2.0.1-xenial-amd64"), "version", "--format", format)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(coretesting.Stdout(ctx), gc.Equals, expected)
+	}
+}
+
+func (*versionSuite) TestVersionFlag(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newVersionSuper("2.0.1-xenial-amd64"), ctx, []string{"--version"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "2.0.1-xenial-amd64\n")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (*versionSuite) TestVersionFlagBeforeSubcommand(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newVersionSuper("2.0.1-xenial-amd64"), ctx, []string{"--version", "status"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "2.0.1-xenial-amd64\n")
+}
+
+func (*versionSuite) TestSubcommandsStillRun(c *gc.C) {
+	ctx, err := coretesting.RunCommand(c, newVersionSuper("2.0.1-xenial-amd64"), "status")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "status\n")
+}
+
+func (*versionSuite) TestNoVersion(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := cmd.Main(newVersionSuper(""), ctx, []string{"version"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "unrecognized command: juju version")
+
+	ctx = coretesting.Context(c)
+	code = cmd.Main(newVersionSuper(""), ctx, []string{"--version"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "flag provided but not defined: --version")
+}
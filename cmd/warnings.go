@@ -0,0 +1,151 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/gnuflag"
+
+	"github.com/juju/juju/juju/osenv"
+)
+
+// Warnings collects warnings about the run of a command. Rather than
+// interrupting the command's output, Main writes them after Run
+// returns, whether or not it succeeded, each prefixed with "warning:"
+// and in the order they were first added; repeats of a warning are
+// dropped. When the command is run with --format json, or with
+// JUJU_ERROR_FORMAT set to "json", the warnings are instead written as
+// a single JSON object holding a "warnings" array:
+//
+//	{"warnings":["tools were not uploaded for arch arm64"]}
+//
+// They are written to stderr in either case, so that the primary
+// output remains a single document.
+//
+// A command embeds Warnings and calls AddWarning during Run. The
+// warnings of a subcommand are written too, if it is registered with
+// a RunnerSuperCommand or VersionSuperCommand.
+type Warnings struct {
+	mu       sync.Mutex
+	messages []string
+	seen     map[string]bool
+}
+
+// AddWarning records a warning, described by format and args. It is
+// safe to call concurrently.
+func (w *Warnings) AddWarning(format string, args ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seen[msg] {
+		return
+	}
+	if w.seen == nil {
+		w.seen = make(map[string]bool)
+	}
+	w.seen[msg] = true
+	w.messages = append(w.messages, msg)
+}
+
+// commandWarnings implements warningsCommand.
+func (w *Warnings) commandWarnings() ([]string, []*gnuflag.FlagSet) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.messages...), nil
+}
+
+// warningsCommand is implemented by commands whose warnings Main
+// writes: those embedding Warnings, and the supercommands that pass on
+// the warnings of their subcommands.
+type warningsCommand interface {
+	// commandWarnings returns the warnings added while the command
+	// ran, and any flag sets, beyond the one Main parses, consulted
+	// to choose how they are written.
+	commandWarnings() ([]string, []*gnuflag.FlagSet)
+}
+
+// subcommandWarnings holds the subcommands registered with a
+// supercommand that collect warnings.
+type subcommandWarnings struct {
+	mu       sync.Mutex
+	commands []warningsCommand
+}
+
+// add records c, if it collects warnings.
+func (s *subcommandWarnings) add(c cmd.Command) {
+	wc, ok := c.(warningsCommand)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, wc)
+}
+
+// commandWarnings returns the warnings added by the subcommands held
+// by s. Only the subcommand that ran can have added any.
+func (s *subcommandWarnings) commandWarnings() ([]string, []*gnuflag.FlagSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		messages []string
+		flags    []*gnuflag.FlagSet
+	)
+	for _, c := range s.commands {
+		m, f := c.commandWarnings()
+		messages = append(messages, m...)
+		flags = append(flags, f...)
+	}
+	return messages, flags
+}
+
+// flushWarnings writes the warnings collected by c, if any, to
+// ctx.Stderr, as described for Warnings. f is the flag set Main
+// parsed.
+func flushWarnings(c cmd.Command, ctx *cmd.Context, f *gnuflag.FlagSet) {
+	wc, ok := c.(warningsCommand)
+	if !ok {
+		return
+	}
+	messages, flags := wc.commandWarnings()
+	if len(messages) == 0 {
+		return
+	}
+	if structuredWarnings(ctx, append([]*gnuflag.FlagSet{f}, flags...)) {
+		data, err := json.Marshal(struct {
+			Warnings []string `json:"warnings"`
+		}{messages})
+		if err != nil {
+			logger.Errorf("cannot write warnings as JSON: %v", err)
+		} else {
+			fmt.Fprintf(ctx.Stderr, "%s\n", data)
+			return
+		}
+	}
+	for _, msg := range messages {
+		fmt.Fprintf(ctx.Stderr, "warning: %s\n", msg)
+	}
+}
+
+// structuredWarnings reports whether the warnings should be written as
+// JSON. The last of flags to define --format decides.
+func structuredWarnings(ctx *cmd.Context, flags []*gnuflag.FlagSet) bool {
+	if ctx.Getenv(osenv.JujuErrorFormatEnvKey) == ErrorFormatJSON {
+		return true
+	}
+	for i := len(flags) - 1; i >= 0; i-- {
+		if flags[i] == nil {
+			continue
+		}
+		if flag := flags[i].Lookup("format"); flag != nil {
+			return flag.Value.String() == "json"
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	"github.com/juju/juju/juju/osenv"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type warningsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&warningsSuite{})
+
+// warningCommand writes its output, adding the given warnings along
+// the way, and then returns err.
+type warningCommand struct {
+	cmd.CommandBase
+	jujucmd.Warnings
+	out      cmd.Output
+	warnings []string
+	err      error
+}
+
+func (c *warningCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "sync-tools", Purpose: "Upload tools."}
+}
+
+func (c *warningCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "smart", cmd.DefaultFormatters)
+}
+
+func (c *warningCommand) Run(ctx *cmd.Context) error {
+	for i, warning := range c.warnings {
+		c.AddWarning("%s", warning)
+		if i == 0 {
+			if err := c.out.Write(ctx, map[string]string{"uploaded": "amd64"}); err != nil {
+				return err
+			}
+		}
+	}
+	return c.err
+}
+
+func (s *warningsSuite) TestWarningsFollowOutput(c *gc.C) {
+	ctx := coretesting.Context(c)
+	command := &warningCommand{warnings: []string{
+		"tools were not uploaded for arch arm64",
+		"tools were not uploaded for arch s390x",
+		"tools were not uploaded for arch arm64",
+	}}
+	code := jujucmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, "uploaded: amd64\n")
+	c.Check(coretesting.Stderr(ctx), gc.Equals, ""+
+		"warning: tools were not uploaded for arch arm64\n"+
+		"warning: tools were not uploaded for arch s390x\n",
+	)
+}
+
+func (s *warningsSuite) TestWarningsJSON(c *gc.C) {
+	ctx := coretesting.Context(c)
+	command := &warningCommand{warnings: []string{"first", "second", "first"}}
+	code := jujucmd.Main(command, ctx, []string{"--format", "json"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stdout(ctx), gc.Equals, `{"uploaded":"amd64"}`+"\n")
+	c.Check(coretesting.Stderr(ctx), gc.Equals, `{"warnings":["first","second"]}`+"\n")
+}
+
+func (s *warningsSuite) TestWarningsJSONErrorFormat(c *gc.C) {
+	s.PatchEnvironment(osenv.JujuErrorFormatEnvKey, jujucmd.ErrorFormatJSON)
+	ctx := coretesting.Context(c)
+	command := &warningCommand{warnings: []string{"first"}}
+	code := jujucmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, `{"warnings":["first"]}`+"\n")
+}
+
+func (s *warningsSuite) TestWarningsAfterError(c *gc.C) {
+	ctx := coretesting.Context(c)
+	command := &warningCommand{warnings: []string{"first"}, err: errors.New("boom")}
+	code := jujucmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 1)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "ERROR boom\nwarning: first\n")
+}
+
+func (s *warningsSuite) TestWarningsNotSilenced(c *gc.C) {
+	ctx := coretesting.Context(c)
+	command := &warningCommand{warnings: []string{"first"}, err: cmd.ErrSilent}
+	code := jujucmd.Main(command, ctx, nil)
+	c.Check(code, gc.Equals, 1)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "warning: first\n")
+}
+
+func (s *warningsSuite) TestWarningsFromRunnerSubcommand(c *gc.C) {
+	super := jujucmd.NewRunnerSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.Register(&warningCommand{warnings: []string{"first"}})
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(super, ctx, []string{"sync-tools", "--format", "json"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, `{"warnings":["first"]}`+"\n")
+}
+
+func (s *warningsSuite) TestWarningsFromVersionSubcommand(c *gc.C) {
+	super := jujucmd.NewVersionSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	super.Register(&warningCommand{warnings: []string{"first", "first"}})
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(super, ctx, []string{"sync-tools"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(coretesting.Stderr(ctx), gc.Equals, "warning: first\n")
+}
+
+// concurrentWarningCommand adds warnings from several goroutines.
+type concurrentWarningCommand struct {
+	cmd.CommandBase
+	jujucmd.Warnings
+}
+
+func (c *concurrentWarningCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "concurrent"}
+}
+
+func (c *concurrentWarningCommand) Run(ctx *cmd.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.AddWarning("worker %d step %d", i, j)
+				c.AddWarning("shared warning")
+			}
+		}(i)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *warningsSuite) TestConcurrentWarnings(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(&concurrentWarningCommand{}, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+
+	lines := strings.Split(strings.TrimSuffix(coretesting.Stderr(ctx), "\n"), "\n")
+	c.Assert(lines, gc.HasLen, 5*20+1)
+	// Each worker's warnings appear once, in the order it added them.
+	next := make(map[int]int)
+	shared := 0
+	for _, line := range lines {
+		if line == "warning: shared warning" {
+			shared++
+			continue
+		}
+		var i, j int
+		_, err := fmt.Sscanf(line, "warning: worker %d step %d", &i, &j)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(j, gc.Equals, next[i])
+		next[i] = j + 1
+	}
+	c.Check(shared, gc.Equals, 1)
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/juju/osenv"
+)
+
+// warnOnceInterval is the minimum time between repeats of a warning
+// given to WarnOnce.
+const warnOnceInterval = 24 * time.Hour
+
+// warningsFile is the name of the file, in the juju data directory,
+// that records when each warning given to WarnOnce was last shown.
+const warningsFile = "warnings.json"
+
+// warnOnceNow returns the current time. It is patched in tests.
+var warnOnceNow = time.Now
+
+// WarnOnce writes the warning described by format and args to
+// ctx.Stderr, unless the warning with the same key has already been
+// shown to the user in the last 24 hours. This keeps scripts that run
+// a deprecated command in a loop from flooding their output. Warnings
+// are always shown when --verbose is given.
+//
+// The times at which warnings are shown are recorded in a file in the
+// juju data directory named by the context's environment. If the file
+// cannot be written, every warning is shown.
+func WarnOnce(ctx *cmd.Context, key, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	if !warnOnceDue(ctx, key) {
+		// The warning has been shown recently, but it is still
+		// wanted when the user asks for verbose output.
+		ctx.Verbosef("%s", msg)
+		return
+	}
+	fmt.Fprint(ctx.Stderr, msg)
+}
+
+// warnOnceDue reports whether the warning with the given key should be
+// shown, recording that it has been if so.
+func warnOnceDue(ctx *cmd.Context, key string) bool {
+	path := warningsPath(ctx)
+	if path == "" {
+		return true
+	}
+	shown, err := readWarnings(path)
+	if err != nil {
+		logger.Debugf("cannot read shown warnings: %v", err)
+		shown = make(map[string]time.Time)
+	}
+	now := warnOnceNow()
+	if last, ok := shown[key]; ok && now.Sub(last) < warnOnceInterval && !last.After(now) {
+		return false
+	}
+	shown[key] = now
+	if err := writeWarnings(path, shown); err != nil {
+		logger.Debugf("cannot record shown warning %q: %v", key, err)
+	}
+	return true
+}
+
+// warningsPath returns the path of the file recording shown warnings,
+// found from the context's environment in the same way as the juju
+// data directory, or "" if there is none.
+func warningsPath(ctx *cmd.Context) string {
+	dir := ctx.Getenv(osenv.JujuXDGDataHomeEnvKey)
+	if dir == "" {
+		if xdg := ctx.Getenv(osenv.XDGDataHome); xdg != "" {
+			dir = filepath.Join(xdg, "juju")
+		} else if home := ctx.Getenv("HOME"); home != "" {
+			dir = filepath.Join(home, ".local", "share", "juju")
+		} else {
+			return ""
+		}
+	}
+	return filepath.Join(AbsPath(ctx, dir), warningsFile)
+}
+
+// readWarnings returns the times at which warnings were last shown,
+// as recorded in the file at path. A missing file records nothing.
+func readWarnings(path string) (map[string]time.Time, error) {
+	shown := make(map[string]time.Time)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return shown, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := json.Unmarshal(data, &shown); err != nil {
+		return nil, errors.Annotatef(err, "cannot parse %s", path)
+	}
+	return shown, nil
+}
+
+// writeWarnings records the times at which warnings were last shown in
+// the file at path. The file is replaced atomically, so that commands
+// run concurrently never see it half written; one of them may lose the
+// other's update, at worst causing a warning to be repeated.
+func writeWarnings(path string, shown map[string]time.Time) error {
+	data, err := json.Marshal(shown)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(utils.AtomicWriteFile(path, data, 0600))
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type warnOnceSuite struct {
+	testing.IsolationSuite
+
+	dataDir string
+	now     time.Time
+}
+
+var _ = gc.Suite(&warnOnceSuite{})
+
+func (s *warnOnceSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.dataDir = filepath.Join(c.MkDir(), "juju")
+	s.now = time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC)
+	jujucmd.PatchWarnOnceNow(s, func() time.Time { return s.now })
+}
+
+// warn calls WarnOnce with a fresh context, as each invocation of a
+// command would, and returns what was written to stderr.
+func (s *warnOnceSuite) warn(c *gc.C, key string) string {
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"JUJU_DATA": s.dataDir}
+	jujucmd.WarnOnce(ctx, key, "WARNING %s is deprecated", key)
+	return coretesting.Stderr(ctx)
+}
+
+func (s *warnOnceSuite) TestWarnsOncePerDay(c *gc.C) {
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	c.Assert(s.warn(c, "foo"), gc.Equals, "")
+
+	s.now = s.now.Add(23 * time.Hour)
+	c.Assert(s.warn(c, "foo"), gc.Equals, "")
+
+	s.now = s.now.Add(time.Hour)
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	c.Assert(s.warn(c, "foo"), gc.Equals, "")
+}
+
+func (s *warnOnceSuite) TestKeysIndependent(c *gc.C) {
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	c.Assert(s.warn(c, "bar"), gc.Equals, "WARNING bar is deprecated\n")
+	c.Assert(s.warn(c, "foo"), gc.Equals, "")
+	c.Assert(s.warn(c, "bar"), gc.Equals, "")
+}
+
+func (s *warnOnceSuite) TestClockGoesBackwards(c *gc.C) {
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	s.now = s.now.Add(-time.Hour)
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+}
+
+func (s *warnOnceSuite) TestCorruptFile(c *gc.C) {
+	err := os.MkdirAll(s.dataDir, 0700)
+	c.Assert(err, jc.ErrorIsNil)
+	path := filepath.Join(s.dataDir, "warnings.json")
+	err = ioutil.WriteFile(path, []byte("{not json"), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	c.Assert(s.warn(c, "foo"), gc.Equals, "")
+}
+
+func (s *warnOnceSuite) TestUnwritableDirectory(c *gc.C) {
+	// A file in the way of the data directory means that nothing can
+	// be recorded, so every warning is shown.
+	err := ioutil.WriteFile(s.dataDir, nil, 0600)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+	c.Assert(s.warn(c, "foo"), gc.Equals, "WARNING foo is deprecated\n")
+}
+
+func (s *warnOnceSuite) TestNoDataDirectory(c *gc.C) {
+	for i := 0; i < 2; i++ {
+		ctx := coretesting.Context(c)
+		ctx.Env = map[string]string{}
+		jujucmd.WarnOnce(ctx, "foo", "WARNING foo is deprecated")
+		c.Assert(coretesting.Stderr(ctx), gc.Equals, "WARNING foo is deprecated\n")
+	}
+}
+
+func (s *warnOnceSuite) TestDataDirectoryFromXDG(c *gc.C) {
+	xdg := c.MkDir()
+	ctx := coretesting.Context(c)
+	ctx.Env = map[string]string{"XDG_DATA_HOME": xdg}
+	jujucmd.WarnOnce(ctx, "foo", "WARNING foo is deprecated")
+	_, err := os.Stat(filepath.Join(xdg, "juju", "warnings.json"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *warnOnceSuite) TestVerbose(c *gc.C) {
+	run := func(args ...string) string {
+		super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name: "juju",
+			Log:  &cmd.Log{},
+		})
+		super.Register(newDestroyModelCommand())
+		ctx := coretesting.Context(c)
+		ctx.Env = map[string]string{"JUJU_DATA": s.dataDir}
+		code := cmd.Main(super, ctx, append([]string{"destroy-model", "--environment", "foo"}, args...))
+		c.Assert(code, gc.Equals, 0)
+		return coretesting.Stderr(ctx)
+	}
+	const warning = "WARNING flag --environment is deprecated, please use --model\n"
+	c.Assert(run(), gc.Equals, warning)
+	c.Assert(run(), gc.Equals, "")
+	c.Assert(run("--verbose"), jc.Contains, warning)
+}
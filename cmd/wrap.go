@@ -0,0 +1,111 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/juju/cmd"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// DefaultHelpWidth is the width help text is wrapped to when the
+	// width of the terminal cannot be determined.
+	DefaultHelpWidth = 80
+
+	// MaxHelpWidth is the widest that help text will be wrapped to,
+	// however wide the terminal is, so that paragraphs stay readable.
+	MaxHelpWidth = 100
+)
+
+// HelpWidth returns the width to wrap help text written to w to. If w
+// is a terminal, its width is used, capped at MaxHelpWidth; otherwise
+// DefaultHelpWidth is returned.
+func HelpWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !terminal.IsTerminal(int(f.Fd())) {
+		return DefaultHelpWidth
+	}
+	width, _, err := terminal.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return DefaultHelpWidth
+	}
+	if width > MaxHelpWidth {
+		width = MaxHelpWidth
+	}
+	return width
+}
+
+// WrappedHelp returns the help text for the given command, as shown
+// when invoked as name, with the paragraphs of its documentation
+// re-wrapped for the context's stdout. The flag defaults section is
+// left as gnuflag renders it. Hidden flags are left out unless all is
+// true, as with CommandHelp. The help is translated, before it is
+// wrapped, as described in SetTranslator.
+func WrappedHelp(ctx *cmd.Context, c cmd.Command, name string, all bool) []byte {
+	return commandHelp(c, name, all, HelpWidth(ctx.Stdout))
+}
+
+// bulletPattern matches lines that begin a list item.
+var bulletPattern = regexp.MustCompile(`^([-*+]|\d+[.)])\s`)
+
+// wrap re-wraps the paragraphs of text so that no line is longer than
+// width, unless a single word is. Consecutive lines that start at the
+// left margin form a paragraph and are joined before being wrapped.
+// Blank lines, indented lines and list items are treated as literal
+// and are left untouched, so examples and sample output are preserved.
+func wrap(text string, width int) string {
+	var out []string
+	var words []string
+	flush := func() {
+		if len(words) > 0 {
+			out = append(out, fill(words, width)...)
+			words = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if isLiteralLine(line) {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// isLiteralLine reports whether line should be kept as it is rather
+// than wrapped with its neighbours.
+func isLiteralLine(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return true
+	}
+	if line[0] == ' ' || line[0] == '\t' {
+		return true
+	}
+	return bulletPattern.MatchString(line)
+}
+
+// fill lays out words in lines no longer than width.
+func fill(words []string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range words {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	return append(lines, line)
+}
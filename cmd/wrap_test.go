@@ -0,0 +1,154 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	jujucmd "github.com/juju/juju/cmd"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type wrapSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&wrapSuite{})
+
+var wrapTests = []struct {
+	about    string
+	text     string
+	width    int
+	expected string
+}{{
+	about:    "empty",
+	text:     "",
+	width:    20,
+	expected: "",
+}, {
+	about:    "short line unchanged",
+	text:     "hello world",
+	width:    20,
+	expected: "hello world",
+}, {
+	about:    "long line wrapped",
+	text:     "the quick brown fox jumps over the lazy dog",
+	width:    15,
+	expected: "the quick brown\nfox jumps over\nthe lazy dog",
+}, {
+	about:    "hard wrapped paragraph joined",
+	text:     "the quick\nbrown fox\njumps over\nthe lazy dog\n",
+	width:    40,
+	expected: "the quick brown fox jumps over the lazy\ndog\n",
+}, {
+	about:    "exact width",
+	text:     "abcde fghij",
+	width:    11,
+	expected: "abcde fghij",
+}, {
+	about:    "long word kept whole",
+	text:     "see https://jujucharms.com/docs/stable/introducing-2 now",
+	width:    10,
+	expected: "see\nhttps://jujucharms.com/docs/stable/introducing-2\nnow",
+}, {
+	about:    "paragraphs separated by blank lines",
+	text:     "one two three\n\nfour five six",
+	width:    8,
+	expected: "one two\nthree\n\nfour\nfive six",
+}, {
+	about:    "indented example untouched",
+	text:     "Examples:\n    juju deploy mysql --to 0 --constraints \"mem=8G cores=4\"\nmore text here",
+	width:    20,
+	expected: "Examples:\n    juju deploy mysql --to 0 --constraints \"mem=8G cores=4\"\nmore text here",
+}, {
+	about:    "tab indented untouched",
+	text:     "\ta very long literal line which should not be wrapped at all",
+	width:    10,
+	expected: "\ta very long literal line which should not be wrapped at all",
+}, {
+	about:    "bullet list untouched",
+	text:     "Options are:\n- first item which is rather long indeed\n* second item\n1. numbered item\n2) another",
+	width:    10,
+	expected: "Options\nare:\n- first item which is rather long indeed\n* second item\n1. numbered item\n2) another",
+}, {
+	about:    "whitespace only lines preserved",
+	text:     "a b\n   \nc d",
+	width:    1,
+	expected: "a\nb\n   \nc\nd",
+}, {
+	about:    "hyphenated word not a bullet",
+	text:     "-- not a bullet because no space follows --really",
+	width:    20,
+	expected: "-- not a bullet\nbecause no space\nfollows --really",
+}}
+
+func (*wrapSuite) TestWrap(c *gc.C) {
+	for i, test := range wrapTests {
+		c.Logf("test %d: %s", i, test.about)
+		c.Check(jujucmd.Wrap(test.text, test.width), gc.Equals, test.expected)
+	}
+}
+
+func (*wrapSuite) TestWrapIdempotent(c *gc.C) {
+	for i, test := range wrapTests {
+		c.Logf("test %d: %s", i, test.about)
+		once := jujucmd.Wrap(test.text, test.width)
+		c.Check(jujucmd.Wrap(once, test.width), gc.Equals, once)
+	}
+}
+
+func (*wrapSuite) TestHelpWidthNotTerminal(c *gc.C) {
+	ctx := coretesting.Context(c)
+	c.Assert(jujucmd.HelpWidth(ctx.Stdout), gc.Equals, jujucmd.DefaultHelpWidth)
+}
+
+type docCommand struct {
+	cmd.CommandBase
+}
+
+func (c *docCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "doc",
+		Purpose: "Show documentation.",
+		Doc:     strings.Repeat("word ", 40) + "\n\n    literal " + strings.Repeat("x", 100) + "\n",
+	}
+}
+
+func (c *docCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (*wrapSuite) TestWrappedHelp(c *gc.C) {
+	ctx := coretesting.Context(c)
+	help := string(jujucmd.WrappedHelp(ctx, &docCommand{}, "doc", false))
+	c.Assert(help, jc.Contains, "    literal "+strings.Repeat("x", 100))
+	for _, line := range strings.Split(help, "\n") {
+		if strings.HasPrefix(line, "word") {
+			c.Check(len(line) <= jujucmd.DefaultHelpWidth, jc.IsTrue)
+		}
+	}
+}
+
+func (*wrapSuite) TestHelpSuperCommandWrapsHelp(c *gc.C) {
+	super := jujucmd.NewHelpSuperCommand(cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"}))
+	super.Register(&docCommand{})
+	ctx := coretesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"help", "doc"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, string(jujucmd.WrappedHelp(ctx, &docCommand{}, "juju doc", false)))
+	c.Assert(coretesting.Stdout(ctx), gc.Not(jc.Contains), strings.Repeat("word ", 20))
+}
+
+func (*wrapSuite) TestMainWrapsHelp(c *gc.C) {
+	ctx := coretesting.Context(c)
+	code := jujucmd.Main(&docCommand{}, ctx, []string{"--help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, string(jujucmd.WrappedHelp(ctx, &docCommand{}, "doc", false)))
+	c.Assert(coretesting.Stdout(ctx), gc.Not(jc.Contains), strings.Repeat("word ", 20))
+}
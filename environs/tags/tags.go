@@ -3,7 +3,14 @@
 
 package tags
 
-import "gopkg.in/juju/names.v2"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v2"
+)
 
 const (
 	// JujuTagPrefix is the prefix for Juju-managed tags.
@@ -62,3 +69,43 @@ func ResourceTags(modelTag names.ModelTag, controllerTag names.ControllerTag, ta
 	allTags[JujuController] = controllerTag.Id()
 	return allTags
 }
+
+const (
+	// MaxInstanceTagKeyLength and MaxInstanceTagValueLength are the
+	// longest tag key and value that every supported cloud accepts.
+	MaxInstanceTagKeyLength   = 63
+	MaxInstanceTagValueLength = 63
+)
+
+var (
+	// Some clouds only accept lower case letters, digits, "_" and "-"
+	// in tag keys and values, and require keys to start with a letter.
+	validInstanceTagKey   = regexp.MustCompile("^[a-z][a-z0-9_-]*$")
+	validInstanceTagValue = regexp.MustCompile("^[a-z0-9_-]*$")
+)
+
+// ValidateInstanceTags returns an error satisfying errors.IsNotValid
+// if any of the supplied user-defined instance tags could not be set
+// on instances in every cloud that supports tags, or would clash with
+// the tags that Juju manages.
+func ValidateInstanceTags(tags map[string]string) error {
+	for key, value := range tags {
+		var problem string
+		switch {
+		case strings.HasPrefix(key, JujuTagPrefix):
+			problem = fmt.Sprintf("prefix %q is reserved", JujuTagPrefix)
+		case len(key) > MaxInstanceTagKeyLength:
+			problem = fmt.Sprintf("key longer than %d characters", MaxInstanceTagKeyLength)
+		case !validInstanceTagKey.MatchString(key):
+			problem = "key must start with a lower case letter, and contain only lower case letters, digits, \"_\" and \"-\""
+		case len(value) > MaxInstanceTagValueLength:
+			problem = fmt.Sprintf("value longer than %d characters", MaxInstanceTagValueLength)
+		case !validInstanceTagValue.MatchString(value):
+			problem = "value must contain only lower case letters, digits, \"_\" and \"-\""
+		default:
+			continue
+		}
+		return errors.NewNotValid(nil, fmt.Sprintf("invalid tag %q: %s", key, problem))
+	}
+	return nil
+}
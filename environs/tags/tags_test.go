@@ -4,6 +4,9 @@
 package tags_test
 
 import (
+	"strings"
+
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/names.v2"
@@ -61,6 +64,46 @@ func (*tagsSuite) TestResourceTagsResourceTaggers(c *gc.C) {
 	})
 }
 
+func (*tagsSuite) TestValidateInstanceTags(c *gc.C) {
+	err := tags.ValidateInstanceTags(map[string]string{
+		"team":                  "ops",
+		"cost-centre_1":         "",
+		strings.Repeat("k", 63): strings.Repeat("v", 63),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	for i, test := range []struct {
+		tags map[string]string
+		err  string
+	}{{
+		tags: map[string]string{"juju-model": "foo"},
+		err:  `invalid tag "juju-model": prefix "juju-" is reserved`,
+	}, {
+		tags: map[string]string{strings.Repeat("k", 64): "foo"},
+		err:  `invalid tag "k+": key longer than 63 characters`,
+	}, {
+		tags: map[string]string{"": "foo"},
+		err:  `invalid tag "": key must start with a lower case letter, .*`,
+	}, {
+		tags: map[string]string{"1team": "foo"},
+		err:  `invalid tag "1team": key must start with a lower case letter, .*`,
+	}, {
+		tags: map[string]string{"Team": "foo"},
+		err:  `invalid tag "Team": key must start with a lower case letter, .*`,
+	}, {
+		tags: map[string]string{"team": strings.Repeat("v", 64)},
+		err:  `invalid tag "team": value longer than 63 characters`,
+	}, {
+		tags: map[string]string{"team": "Ops team"},
+		err:  `invalid tag "team": value must contain only lower case letters, .*`,
+	}} {
+		c.Logf("test %d: %v", i, test.tags)
+		err := tags.ValidateInstanceTags(test.tags)
+		c.Check(err, gc.ErrorMatches, test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+}
+
 func testResourceTags(c *gc.C, controller names.ControllerTag, model names.ModelTag, taggers []tags.ResourceTagger, expectTags map[string]string) {
 	tags := tags.ResourceTags(model, controller, taggers...)
 	c.Assert(tags, jc.DeepEquals, expectTags)
@@ -43,6 +43,20 @@ const (
 	// timestamps to be written in RFC3339 format.
 	JujuStatusIsoTimeEnvKey = "JUJU_STATUS_ISO_TIME"
 
+	// JujuErrorFormatEnvKey is the env var which if set to "json",
+	// will cause command failures to be reported as JSON objects on
+	// stderr so that they can be consumed by scripts.
+	JujuErrorFormatEnvKey = "JUJU_ERROR_FORMAT"
+
+	// JujuCLIDefaultsEnvKey is the env var which may name a file of
+	// key=value default flag values for juju commands.
+	JujuCLIDefaultsEnvKey = "JUJU_CLI_DEFAULTS"
+
+	// JujuCaptureFileEnvKey is the env var which may name a file to
+	// which a record of each juju command invocation is appended, so
+	// that it can be replayed when reproducing a problem.
+	JujuCaptureFileEnvKey = "JUJU_CAPTURE_FILE"
+
 	// XDGDataHome is a path where data for the running user
 	// should be stored according to the xdg standard.
 	XDGDataHome = "XDG_DATA_HOME"
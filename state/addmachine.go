@@ -5,16 +5,17 @@ package state
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/juju/errors"
 	"github.com/juju/replicaset"
 	jujutxn "github.com/juju/txn"
+	"github.com/juju/utils/set"
 	"gopkg.in/juju/names.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
 
 	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/environs/tags"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/status"
@@ -91,6 +92,18 @@ type MachineTemplate struct {
 	// with the machine.
 	Placement string
 
+	// InstanceTags holds user-defined tags to set on the machine's
+	// instance, in addition to those that Juju manages. See
+	// Machine.InstanceTags.
+	InstanceTags map[string]string
+
+	// CreationKey, if not empty, is a key chosen by the client to
+	// identify its request to add the machine. If a machine has
+	// already been added with the same key, that machine is returned
+	// instead of a new one being added, so that a client that is
+	// unsure whether its request succeeded can safely retry it.
+	CreationKey string
+
 	// principals holds the principal units that will
 	// associated with the machine.
 	principals []string
@@ -114,21 +127,27 @@ type MachineFilesystemParams struct {
 // of the given type inside another new machine. The two given templates
 // specify the form of the child and parent respectively.
 func (st *State) AddMachineInsideNewMachine(template, parentTemplate MachineTemplate, containerType instance.ContainerType) (*Machine, error) {
+	if m, err := st.machineForCreationKey(template); m != nil || err != nil {
+		return m, errors.Annotate(err, "cannot add a new machine")
+	}
 	mdoc, ops, err := st.addMachineInsideNewMachineOps(template, parentTemplate, containerType)
 	if err != nil {
 		return nil, errors.Annotate(err, "cannot add a new machine")
 	}
-	return st.addMachine(mdoc, ops)
+	return st.addMachine(template, mdoc, ops)
 }
 
 // AddMachineInsideMachine adds a machine inside a container of the
 // given type on the existing machine with id=parentId.
 func (st *State) AddMachineInsideMachine(template MachineTemplate, parentId string, containerType instance.ContainerType) (*Machine, error) {
+	if m, err := st.machineForCreationKey(template); m != nil || err != nil {
+		return m, errors.Annotate(err, "cannot add a new machine")
+	}
 	mdoc, ops, err := st.addMachineInsideMachineOps(template, parentId, containerType)
 	if err != nil {
 		return nil, errors.Annotate(err, "cannot add a new machine")
 	}
-	return st.addMachine(mdoc, ops)
+	return st.addMachine(template, mdoc, ops)
 }
 
 // AddMachine adds a machine with the given series and jobs.
@@ -159,41 +178,78 @@ func (st *State) AddOneMachine(template MachineTemplate) (*Machine, error) {
 func (st *State) AddMachines(templates ...MachineTemplate) (_ []*Machine, err error) {
 	defer errors.DeferredAnnotatef(&err, "cannot add a new machine")
 	var ms []*Machine
-	var ops []txn.Op
-	var mdocs []*machineDoc
-	for _, template := range templates {
-		mdoc, addOps, err := st.addMachineOps(template)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		mdocs = append(mdocs, mdoc)
-		ms = append(ms, newMachine(st, mdoc))
-		ops = append(ops, addOps...)
-	}
-	ssOps, err := st.maintainControllersOps(mdocs, nil)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	ops = append(ops, ssOps...)
-	ops = append(ops, assertModelActiveOp(st.ModelUUID()))
-	if err := st.runTransaction(ops); err != nil {
-		if errors.Cause(err) == txn.ErrAborted {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
 			if err := checkModelActive(st); err != nil {
 				return nil, errors.Trace(err)
 			}
 		}
+		// The chosen machine ids may have been taken by another
+		// connection; if reusing ids keeps colliding, make sure
+		// of progress by taking new ids from the sequence.
+		policy := st.MachineIdPolicy()
+		if attempt > 1 {
+			policy = IncreasingMachineIds
+		}
+		ms = nil
+		var ops []txn.Op
+		var mdocs []*machineDoc
+		reserved := make(set.Strings)
+		byKey := make(map[string]*Machine)
+		for _, template := range templates {
+			// A template with a creation key that has already
+			// been used gets the machine added with it.
+			if m := byKey[template.CreationKey]; m != nil {
+				ms = append(ms, m)
+				continue
+			}
+			m, err := st.machineForCreationKey(template)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if m != nil {
+				ms = append(ms, m)
+				continue
+			}
+			mdoc, addOps, err := st.addMachineOps(template, policy, reserved)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			reserved.Add(mdoc.Id)
+			mdocs = append(mdocs, mdoc)
+			m = newMachine(st, mdoc)
+			if template.CreationKey != "" {
+				byKey[template.CreationKey] = m
+			}
+			ms = append(ms, m)
+			ops = append(ops, addOps...)
+		}
+		ssOps, err := st.maintainControllersOps(mdocs, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ops = append(ops, ssOps...)
+		ops = append(ops, assertModelActiveOp(st.ModelUUID()))
+		return ops, nil
+	}
+	if err := st.run(buildTxn); err != nil {
 		return nil, errors.Trace(err)
 	}
 	return ms, nil
 }
 
-func (st *State) addMachine(mdoc *machineDoc, ops []txn.Op) (*Machine, error) {
+func (st *State) addMachine(template MachineTemplate, mdoc *machineDoc, ops []txn.Op) (*Machine, error) {
 	ops = append([]txn.Op{assertModelActiveOp(st.ModelUUID())}, ops...)
 	if err := st.runTransaction(ops); err != nil {
 		if errors.Cause(err) == txn.ErrAborted {
 			if err := checkModelActive(st); err != nil {
 				return nil, errors.Trace(err)
 			}
+			// Another client may have added a machine with the
+			// same creation key first.
+			if m, err := st.machineForCreationKey(template); m != nil || err != nil {
+				return m, errors.Trace(err)
+			}
 		}
 		return nil, errors.Trace(err)
 	}
@@ -235,6 +291,9 @@ func (st *State) effectiveMachineTemplate(p MachineTemplate, allowController boo
 	if err != nil {
 		return tmpl, err
 	}
+	if err := tags.ValidateInstanceTags(p.InstanceTags); err != nil {
+		return tmpl, err
+	}
 
 	if len(p.Jobs) == 0 {
 		return tmpl, errors.New("no jobs specified")
@@ -255,9 +314,10 @@ func (st *State) effectiveMachineTemplate(p MachineTemplate, allowController boo
 }
 
 // addMachineOps returns operations to add a new top level machine
-// based on the given template. It also returns the machine document
-// that will be inserted.
-func (st *State) addMachineOps(template MachineTemplate) (*machineDoc, []txn.Op, error) {
+// based on the given template, with an id chosen according to policy
+// that is not in reserved. It also returns the machine document that
+// will be inserted.
+func (st *State) addMachineOps(template MachineTemplate, policy MachineIdPolicy, reserved set.Strings) (*machineDoc, []txn.Op, error) {
 	template, err := st.effectiveMachineTemplate(template, st.IsController())
 	if err != nil {
 		return nil, nil, err
@@ -267,11 +327,14 @@ func (st *State) addMachineOps(template MachineTemplate) (*machineDoc, []txn.Op,
 			return nil, nil, err
 		}
 	}
-	seq, err := st.sequence("machine")
+	id, err := st.newMachineId(policy, reserved)
 	if err != nil {
 		return nil, nil, err
 	}
-	mdoc := st.machineDocForTemplate(template, strconv.Itoa(seq))
+	mdoc := st.machineDocForTemplate(template, id)
+	if err := st.precheckMachine(mdoc, MachineChange{Operation: MachineAdd}); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
 	prereqOps, machineOp, err := st.insertNewMachineOps(mdoc, template)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -351,6 +414,9 @@ func (st *State) addMachineInsideMachineOps(template MachineTemplate, parentId s
 	}
 	mdoc := st.machineDocForTemplate(template, newId)
 	mdoc.ContainerType = string(containerType)
+	if err := st.precheckMachine(mdoc, MachineChange{Operation: MachineAdd}); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
 	prereqOps, machineOp, err := st.insertNewMachineOps(mdoc, template)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -382,7 +448,7 @@ func (st *State) addMachineInsideNewMachineOps(template, parentTemplate MachineT
 	if template.InstanceId != "" || parentTemplate.InstanceId != "" {
 		return nil, nil, errors.New("cannot specify instance id for a new container")
 	}
-	seq, err := st.sequence("machine")
+	parentId, err := st.newMachineId(st.MachineIdPolicy(), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -399,7 +465,7 @@ func (st *State) addMachineInsideNewMachineOps(template, parentTemplate MachineT
 		}
 	}
 
-	parentDoc := st.machineDocForTemplate(parentTemplate, strconv.Itoa(seq))
+	parentDoc := st.machineDocForTemplate(parentTemplate, parentId)
 	newId, err := st.newContainerId(parentDoc.Id, containerType)
 	if err != nil {
 		return nil, nil, err
@@ -410,6 +476,11 @@ func (st *State) addMachineInsideNewMachineOps(template, parentTemplate MachineT
 	}
 	mdoc := st.machineDocForTemplate(template, newId)
 	mdoc.ContainerType = string(containerType)
+	for _, doc := range []*machineDoc{parentDoc, mdoc} {
+		if err := st.precheckMachine(doc, MachineChange{Operation: MachineAdd}); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	}
 	parentPrereqOps, parentOp, err := st.insertNewMachineOps(parentDoc, parentTemplate)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -454,6 +525,8 @@ func (st *State) machineDocForTemplate(template MachineTemplate, id string) *mac
 		PreferredPublicAddress:  fromNetworkAddress(publicAddr, OriginMachine),
 		NoVote:                  template.NoVote,
 		Placement:               template.Placement,
+		InstanceTags:            copyStringMap(template.InstanceTags),
+		CreationKey:             template.CreationKey,
 	}
 }
 
@@ -498,6 +571,9 @@ func (st *State) insertNewMachineOps(mdoc *machineDoc, template MachineTemplate)
 		mdoc.Filesystems = append(mdoc.Filesystems, a.tag.Id())
 	}
 	prereqOps = append(prereqOps, storageOps...)
+	if mdoc.CreationKey != "" {
+		prereqOps = append(prereqOps, st.insertMachineCreationKeyOp(mdoc))
+	}
 
 	// At the last moment we still have statusDoc in scope, set the initial
 	// history entry. This is risky, and may lead to extra entries, but that's
@@ -856,6 +932,7 @@ func (st *State) enableHAIntentionOps(
 		return result
 	}
 	mdocs := make([]*machineDoc, intent.newCount)
+	reserved := make(set.Strings)
 	for i := range mdocs {
 		template := MachineTemplate{
 			Series: series,
@@ -866,10 +943,11 @@ func (st *State) enableHAIntentionOps(
 			Constraints: cons,
 			Placement:   getPlacement(),
 		}
-		mdoc, addOps, err := st.addMachineOps(template)
+		mdoc, addOps, err := st.addMachineOps(template, st.MachineIdPolicy(), reserved)
 		if err != nil {
 			return nil, ControllersChanges{}, err
 		}
+		reserved.Add(mdoc.Id)
 		mdocs[i] = mdoc
 		ops = append(ops, addOps...)
 		change.Added = append(change.Added, mdoc.Id)
@@ -0,0 +1,130 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/state/presence"
+	"github.com/juju/juju/tools"
+)
+
+// The functions in this file implement the parts of AgentEntity that
+// are common to machines and units, so that each entity type only
+// supplies the details of where its agent's data is stored.
+
+// agentTools returns a copy of the tools recorded for an agent, or an
+// error satisfying errors.IsNotFound that refers to the agent's entity
+// as described by entity, if none have been recorded.
+func agentTools(current *tools.Tools, entity string) (*tools.Tools, error) {
+	if current == nil {
+		return nil, errors.NotFoundf("agent tools for %s", entity)
+	}
+	tools := *current
+	return &tools, nil
+}
+
+// setAgentVersion records v as the version of the agent responsible
+// for the entity with the supplied collection and document id, using
+// run to apply the transaction, and returns the recorded tools. It
+// returns ErrDead if the entity is dead.
+func setAgentVersion(run func([]txn.Op) error, coll, docID string, v version.Binary) (*tools.Tools, error) {
+	if err := checkVersionValidity(v); err != nil {
+		return nil, err
+	}
+	return setAgentTools(run, coll, docID, &tools.Tools{Version: v})
+}
+
+// setAgentTools records a copy of t as the tools of the agent
+// responsible for the entity with the supplied collection and document
+// id, as setAgentVersion does, and returns the recorded tools.
+func setAgentTools(run func([]txn.Op) error, coll, docID string, t *tools.Tools) (*tools.Tools, error) {
+	tools := *t
+	ops := []txn.Op{{
+		C:      coll,
+		Id:     docID,
+		Assert: notDeadDoc,
+		Update: bson.D{{"$set", bson.D{{"tools", &tools}}}},
+	}}
+	if err := run(ops); err != nil {
+		return nil, onAbort(err, ErrDead)
+	}
+	return &tools, nil
+}
+
+// validateAgentTools returns an error if t cannot be recorded as the
+// tools of an agent: its version must be valid, and exactly one of its
+// URL and StorageRef must be set, the latter referring to the stored
+// tools for its version.
+func validateAgentTools(t *tools.Tools) error {
+	if t == nil {
+		return errors.NotValidf("nil agent tools")
+	}
+	if err := checkVersionValidity(t.Version); err != nil {
+		return err
+	}
+	switch {
+	case t.URL == "" && t.StorageRef == "":
+		return errors.NotValidf("agent tools with neither URL nor storage reference")
+	case t.URL != "" && t.StorageRef != "":
+		return errors.NotValidf("agent tools with both URL and storage reference")
+	case t.StorageRef != "" && t.StorageRef != agentToolsStorageRef(t.Version):
+		return errors.NotValidf("storage reference %q for agent tools %v", t.StorageRef, t.Version)
+	}
+	return nil
+}
+
+// agentPresence returns whether the agent with the supplied presence
+// key is alive.
+func agentPresence(st *State, key string) (bool, error) {
+	pwatcher := st.workers.PresenceWatcher()
+	return pwatcher.Alive(key)
+}
+
+// waitAgentPresence blocks until the agent with the supplied presence
+// key is alive, or the timeout expires.
+func waitAgentPresence(st *State, key string, timeout time.Duration) error {
+	ch := make(chan presence.Change)
+	pwatcher := st.workers.PresenceWatcher()
+	pwatcher.Watch(key, ch)
+	defer pwatcher.Unwatch(key, ch)
+	// The presence watcher may report the agent's status more than
+	// once without it changing; for example, after it has been
+	// restarted.
+	timeoutCh := time.After(timeout)
+	for {
+		select {
+		case change := <-ch:
+			if change.Alive {
+				return nil
+			}
+		case <-timeoutCh:
+			// TODO(fwereade): 2016-03-17 lp:1558657
+			return fmt.Errorf("still not alive after timeout")
+		case <-pwatcher.Dead():
+			return pwatcher.Err()
+		}
+	}
+}
+
+// setAgentPresence starts and returns a pinger signalling that the
+// agent with the supplied presence key is alive. Pingers write to the
+// database, so it returns ErrReadOnly for a read-only State.
+func setAgentPresence(st *State, key string) (*presence.Pinger, error) {
+	if st.readOnly {
+		return nil, ErrReadOnly
+	}
+	presenceCollection := st.getPresenceCollection()
+	p := presence.NewPinger(presenceCollection, st.ModelTag(), key)
+	if err := p.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
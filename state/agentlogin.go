@@ -0,0 +1,122 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MaxAgentLoginHistory is the number of logins recorded for each
+// machine agent.
+const MaxAgentLoginHistory = 20
+
+// AgentLogin describes an attempt by a machine agent to log in to the
+// API server.
+type AgentLogin struct {
+	// Time is when the login was attempted, to the nearest second.
+	Time time.Time
+
+	// Address is the remote address the agent connected from.
+	Address string
+
+	// Success records whether the agent's credentials were accepted.
+	Success bool
+}
+
+type agentLoginDoc struct {
+	Time    time.Time `bson:"time"`
+	Address string    `bson:"address"`
+	Success bool      `bson:"success"`
+}
+
+// machineAgentLoginsDoc is updated by the apiserver whenever a machine
+// agent tries to log in. This update is not done using mgo.txn, so that
+// it stays cheap, and as such the document should NEVER appear in any
+// transaction asserts. It is informational only, for auditing.
+type machineAgentLoginsDoc struct {
+	DocID     string          `bson:"_id"`
+	ModelUUID string          `bson:"model-uuid"`
+	Logins    []agentLoginDoc `bson:"logins"`
+
+	// LastSuccess is the most recent successful login, which is kept
+	// even if a burst of failed logins pushes it out of Logins.
+	LastSuccess *agentLoginDoc `bson:"last-success,omitempty"`
+}
+
+// RecordAgentLogin records an attempt by the machine's agent to log
+// in from the given remote address. Only the most recent
+// MaxAgentLoginHistory logins are kept, along with the most recent
+// successful one.
+func (m *Machine) RecordAgentLogin(addr string, ok bool) error {
+	logins, closer := m.st.getCollection(machineAgentLoginsC)
+	defer closer()
+
+	loginsW := logins.Writeable()
+
+	// Update the safe mode of the underlying session to not require
+	// write majority, nor sync to disk.
+	session := loginsW.Underlying().Database.Session
+	session.SetSafe(&mgo.Safe{})
+
+	login := agentLoginDoc{
+		Time:    m.st.NowToTheSecond(),
+		Address: addr,
+		Success: ok,
+	}
+	set := bson.D{{"model-uuid", m.st.ModelUUID()}}
+	if ok {
+		set = append(set, bson.DocElem{"last-success", login})
+	}
+	_, err := loginsW.UpsertId(m.doc.DocID, bson.D{
+		{"$set", set},
+		{"$push", bson.D{{"logins", bson.D{
+			{"$each", []agentLoginDoc{login}},
+			{"$slice", -MaxAgentLoginHistory},
+		}}}},
+	})
+	return errors.Trace(err)
+}
+
+// AgentLoginHistory returns the recent logins recorded for the
+// machine's agent, oldest first. If the most recent successful login
+// is older than all of the others, it is returned first, so the
+// result may hold one more than MaxAgentLoginHistory logins.
+func (m *Machine) AgentLoginHistory() ([]AgentLogin, error) {
+	logins, closer := m.st.getRawCollection(machineAgentLoginsC)
+	defer closer()
+
+	var doc machineAgentLoginsDoc
+	err := logins.FindId(m.doc.DocID).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot get agent login history for machine %v", m)
+	}
+	docs := doc.Logins
+	if doc.LastSuccess != nil && !containsAgentLogin(docs, *doc.LastSuccess) {
+		docs = append([]agentLoginDoc{*doc.LastSuccess}, docs...)
+	}
+	result := make([]AgentLogin, len(docs))
+	for i, doc := range docs {
+		result[i] = AgentLogin{
+			Time:    doc.Time.UTC(),
+			Address: doc.Address,
+			Success: doc.Success,
+		}
+	}
+	return result, nil
+}
+
+func containsAgentLogin(logins []agentLoginDoc, login agentLoginDoc) bool {
+	for _, l := range logins {
+		if l.Success == login.Success && l.Address == login.Address && l.Time.Equal(login.Time) {
+			return true
+		}
+	}
+	return false
+}
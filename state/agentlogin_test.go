@@ -0,0 +1,100 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type AgentLoginSuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&AgentLoginSuite{})
+
+func (s *AgentLoginSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *AgentLoginSuite) TestNoHistory(c *gc.C) {
+	history, err := s.machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 0)
+}
+
+func (s *AgentLoginSuite) TestRecordAgentLogin(c *gc.C) {
+	start := s.State.NowToTheSecond()
+	err := s.machine.RecordAgentLogin("10.0.0.1:1234", false)
+	c.Assert(err, jc.ErrorIsNil)
+	s.Clock.Advance(time.Minute)
+	err = s.machine.RecordAgentLogin("10.0.0.2:1234", true)
+	c.Assert(err, jc.ErrorIsNil)
+
+	history, err := s.machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, jc.DeepEquals, []state.AgentLogin{{
+		Time:    start,
+		Address: "10.0.0.1:1234",
+		Success: false,
+	}, {
+		Time:    start.Add(time.Minute),
+		Address: "10.0.0.2:1234",
+		Success: true,
+	}})
+}
+
+func (s *AgentLoginSuite) TestHistoryIsCapped(c *gc.C) {
+	for i := 0; i < state.MaxAgentLoginHistory+5; i++ {
+		err := s.machine.RecordAgentLogin("10.0.0.1:1234", true)
+		c.Assert(err, jc.ErrorIsNil)
+		s.Clock.Advance(time.Second)
+	}
+	history, err := s.machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, state.MaxAgentLoginHistory)
+}
+
+func (s *AgentLoginSuite) TestFailuresKeepLastSuccess(c *gc.C) {
+	success := s.State.NowToTheSecond()
+	err := s.machine.RecordAgentLogin("10.0.0.1:1234", true)
+	c.Assert(err, jc.ErrorIsNil)
+	for i := 0; i < state.MaxAgentLoginHistory+5; i++ {
+		s.Clock.Advance(time.Second)
+		err := s.machine.RecordAgentLogin("192.168.1.1:1234", false)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	history, err := s.machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, state.MaxAgentLoginHistory+1)
+	c.Assert(history[0], jc.DeepEquals, state.AgentLogin{
+		Time:    success,
+		Address: "10.0.0.1:1234",
+		Success: true,
+	})
+	for _, login := range history[1:] {
+		c.Check(login.Success, jc.IsFalse)
+	}
+}
+
+func (s *AgentLoginSuite) TestHistoryRemovedWithMachine(c *gc.C) {
+	err := s.machine.RecordAgentLogin("10.0.0.1:1234", true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(s.machine.Remove(), jc.ErrorIsNil)
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	history, err := s.machine.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 0)
+}
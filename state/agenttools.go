@@ -0,0 +1,150 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"github.com/juju/version"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/state/storage"
+)
+
+// AgentToolsStorage stores agent tools tarballs in the controller, so
+// that the tools recorded for a machine's agent can refer to them with
+// a StorageRef rather than a URL. Each tarball is identified by the
+// binary version, including the series and architecture, of the tools
+// it holds.
+type AgentToolsStorage interface {
+	// Put stores the tarball read from r, which must have the given
+	// size and SHA-256 hash, as the tools for v, replacing any stored
+	// already. It returns the reference by which tools refer to it.
+	Put(v version.Binary, r io.Reader, size int64, sha256 string) (string, error)
+
+	// Open returns the stored tarball of the tools for v. It returns
+	// an error satisfying errors.IsNotFound if there is none.
+	Open(v version.Binary) (io.ReadCloser, error)
+
+	// Delete removes the stored tarball of the tools for v. It fails
+	// while the agent tools of any machine refer to the tarball.
+	Delete(v version.Binary) error
+}
+
+// AgentToolsStorage returns the controller's AgentToolsStorage, which
+// holds tools tarballs in GridFS. It is distinct from ToolsStorage,
+// which holds the catalogue of tools available to the model.
+func (st *State) AgentToolsStorage() AgentToolsStorage {
+	return &agentToolsStorage{
+		st:      st,
+		storage: storage.NewStorage(st.controllerModelTag.Id(), st.MongoSession()),
+	}
+}
+
+// agentToolsStorageRef returns the storage reference, and the path in
+// the controller's storage, of the tools tarball for v.
+func agentToolsStorageRef(v version.Binary) string {
+	return "tools/" + v.String()
+}
+
+type agentToolsStorage struct {
+	st      *State
+	storage storage.Storage
+}
+
+// Put is part of the AgentToolsStorage interface.
+func (s *agentToolsStorage) Put(v version.Binary, r io.Reader, size int64, sha256Hash string) (_ string, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot store agent tools %v", v)
+	if err := checkVersionValidity(v); err != nil {
+		return "", err
+	}
+	ref := agentToolsStorageRef(v)
+	hasher := sha256.New()
+	if err := s.storage.Put(ref, io.TeeReader(r, hasher), size); err != nil {
+		return "", errors.Trace(err)
+	}
+	if actual := fmt.Sprintf("%x", hasher.Sum(nil)); actual != sha256Hash {
+		if err := s.storage.Remove(ref); err != nil {
+			logger.Errorf("cannot remove agent tools %v with bad hash: %v", v, err)
+		}
+		return "", errors.Errorf("SHA-256 hash mismatch (%v/%v)", actual, sha256Hash)
+	}
+	return ref, nil
+}
+
+// Open is part of the AgentToolsStorage interface.
+func (s *agentToolsStorage) Open(v version.Binary) (io.ReadCloser, error) {
+	r, _, err := s.storage.Get(agentToolsStorageRef(v))
+	if errors.IsNotFound(err) {
+		return nil, errors.NotFoundf("stored agent tools %v", v)
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot open agent tools %v", v)
+	}
+	return r, nil
+}
+
+// Delete is part of the AgentToolsStorage interface.
+func (s *agentToolsStorage) Delete(v version.Binary) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot delete agent tools %v", v)
+	ref := agentToolsStorageRef(v)
+	// Tools are stored for the whole controller, so the machines of
+	// every model are checked.
+	machines, closer := s.st.getRawCollection(machinesC)
+	defer closer()
+	count, err := machines.Find(bson.D{{"tools.storageref", ref}}).Count()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if count > 0 {
+		return errors.Errorf("tools are in use by %d machine(s)", count)
+	}
+	err = s.storage.Remove(ref)
+	if errors.IsNotFound(err) {
+		return errors.NotFoundf("stored agent tools %v", v)
+	}
+	return errors.Trace(err)
+}
+
+// openToolsURL returns the body of the response to a GET request for
+// the given URL. It is patched in tests.
+var openToolsURL = func(url string) (io.ReadCloser, error) {
+	resp, err := utils.GetValidatingHTTPClient().Get(url)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("bad HTTP response: %v", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// OpenAgentTools returns the tarball of the tools that m's agent is
+// running, read from the controller's AgentToolsStorage or fetched
+// from their URL, whichever the tools refer to. It returns an error
+// satisfying errors.IsNotFound if the tools are not set, or have
+// neither a storage reference nor a URL, as is the case for tools
+// recorded with SetAgentVersion.
+func OpenAgentTools(m *Machine) (io.ReadCloser, error) {
+	t, err := m.AgentTools()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch {
+	case t.StorageRef != "":
+		return m.st.AgentToolsStorage().Open(t.Version)
+	case t.URL != "":
+		r, err := openToolsURL(t.URL)
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot fetch agent tools from %q", t.URL)
+		}
+		return r, nil
+	}
+	return nil, errors.NotFoundf("location of agent tools for machine %v", m)
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/tools"
+)
+
+type AgentToolsSuite struct {
+	ConnSuite
+	machine *state.Machine
+	version version.Binary
+	storage state.AgentToolsStorage
+}
+
+var _ = gc.Suite(&AgentToolsSuite{})
+
+func (s *AgentToolsSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.version = version.MustParseBinary("2.0.1-quantal-amd64")
+	s.storage = s.State.AgentToolsStorage()
+}
+
+func (s *AgentToolsSuite) put(c *gc.C, content string) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+	ref, err := s.storage.Put(s.version, bytes.NewBufferString(content), int64(len(content)), hash)
+	c.Assert(err, jc.ErrorIsNil)
+	return ref
+}
+
+func readAll(c *gc.C, r io.ReadCloser) string {
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, jc.ErrorIsNil)
+	return string(data)
+}
+
+func (s *AgentToolsSuite) TestPutOpen(c *gc.C) {
+	ref := s.put(c, "tools tarball")
+	c.Assert(ref, gc.Equals, "tools/2.0.1-quantal-amd64")
+
+	r, err := s.storage.Open(s.version)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(readAll(c, r), gc.Equals, "tools tarball")
+
+	_, err = s.storage.Open(version.MustParseBinary("2.0.1-xenial-amd64"))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *AgentToolsSuite) TestPutHashMismatch(c *gc.C) {
+	content := "tools tarball"
+	_, err := s.storage.Put(s.version, bytes.NewBufferString(content), int64(len(content)), "deadbeef")
+	c.Assert(err, gc.ErrorMatches, `cannot store agent tools 2.0.1-quantal-amd64: SHA-256 hash mismatch \(.*/deadbeef\)`)
+
+	_, err = s.storage.Open(s.version)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *AgentToolsSuite) TestSetAgentToolsStorageRef(c *gc.C) {
+	ref := s.put(c, "tools tarball")
+	agentTools := &tools.Tools{
+		Version:    s.version,
+		StorageRef: ref,
+		SHA256:     "abc",
+		Size:       13,
+	}
+	err := s.machine.SetAgentTools(agentTools)
+	c.Assert(err, jc.ErrorIsNil)
+
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	got, err := m.AgentTools()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, jc.DeepEquals, agentTools)
+
+	r, err := state.OpenAgentTools(m)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(readAll(c, r), gc.Equals, "tools tarball")
+}
+
+func (s *AgentToolsSuite) TestSetAgentToolsURL(c *gc.C) {
+	s.PatchValue(state.OpenToolsURL, func(url string) (io.ReadCloser, error) {
+		c.Check(url, gc.Equals, "https://example.com/tools.tgz")
+		return ioutil.NopCloser(bytes.NewBufferString("downloaded")), nil
+	})
+	agentTools := &tools.Tools{
+		Version: s.version,
+		URL:     "https://example.com/tools.tgz",
+	}
+	err := s.machine.SetAgentTools(agentTools)
+	c.Assert(err, jc.ErrorIsNil)
+
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	got, err := m.AgentTools()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, jc.DeepEquals, agentTools)
+
+	r, err := state.OpenAgentTools(m)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(readAll(c, r), gc.Equals, "downloaded")
+}
+
+func (s *AgentToolsSuite) TestSetAgentToolsInvalid(c *gc.C) {
+	for i, test := range []struct {
+		tools *tools.Tools
+		err   string
+	}{{
+		tools: &tools.Tools{Version: s.version},
+		err:   "agent tools with neither URL nor storage reference not valid",
+	}, {
+		tools: &tools.Tools{Version: s.version, URL: "https://example.com/tools.tgz", StorageRef: "tools/2.0.1-quantal-amd64"},
+		err:   "agent tools with both URL and storage reference not valid",
+	}, {
+		tools: &tools.Tools{Version: s.version, StorageRef: "tools/2.0.1-xenial-amd64"},
+		err:   `storage reference "tools/2.0.1-xenial-amd64" for agent tools 2.0.1-quantal-amd64 not valid`,
+	}, {
+		tools: &tools.Tools{Version: version.Binary{Number: s.version.Number, Arch: "amd64"}, URL: "https://example.com/tools.tgz"},
+		err:   ".*series.*",
+	}} {
+		c.Logf("test %d", i)
+		err := s.machine.SetAgentTools(test.tools)
+		c.Check(err, gc.ErrorMatches, "cannot set agent tools for machine 0: "+test.err)
+	}
+	_, err := s.machine.AgentTools()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *AgentToolsSuite) TestOpenAgentToolsVersionOnly(c *gc.C) {
+	err := s.machine.SetAgentVersion(s.version)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = state.OpenAgentTools(s.machine)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *AgentToolsSuite) TestDeleteRefusedWhileReferenced(c *gc.C) {
+	ref := s.put(c, "tools tarball")
+	err := s.machine.SetAgentTools(&tools.Tools{Version: s.version, StorageRef: ref})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.storage.Delete(s.version)
+	c.Assert(err, gc.ErrorMatches, `cannot delete agent tools 2.0.1-quantal-amd64: tools are in use by 1 machine\(s\)`)
+	r, err := s.storage.Open(s.version)
+	c.Assert(err, jc.ErrorIsNil)
+	r.Close()
+
+	err = s.machine.SetAgentTools(&tools.Tools{Version: s.version, URL: "https://example.com/tools.tgz"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.storage.Delete(s.version)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.storage.Open(s.version)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.storage.Delete(s.version)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
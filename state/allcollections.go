@@ -203,6 +203,12 @@ func allCollections() collectionSchema {
 			rawAccess: true,
 		},
 
+		// This collection holds the recent logins of each machine
+		// agent.
+		machineAgentLoginsC: {
+			rawAccess: true,
+		},
+
 		// -----------------
 
 		// Local collections
@@ -282,6 +288,10 @@ func allCollections() collectionSchema {
 		// that needs to be cleaned up in the provider.
 		machineRemovalsC: {},
 
+		// This collection holds the locks of machines whose series is
+		// being upgraded.
+		machineUpgradeSeriesLocksC: {},
+
 		// -----
 
 		// These collections hold information associated with storage.
@@ -326,6 +336,18 @@ func allCollections() collectionSchema {
 		endpointBindingsC:     {},
 		openedPortsC:          {},
 
+		// This collection holds the creation keys given when adding
+		// machines, ensuring that each key creates only one machine.
+		machineCreationKeysC: {},
+
+		// This collection holds the network config observed by each
+		// machine agent, one document per interface.
+		machineNetworkConfigC: {
+			indexes: []mgo.Index{{
+				Key: []string{"model-uuid", "machine-id"},
+			}},
+		},
+
 		// -----
 
 		// These collections hold information associated with actions.
@@ -403,79 +425,83 @@ func allCollections() collectionSchema {
 // it in allCollections, above; and please keep this list sorted for easy
 // inspection.
 const (
-	actionNotificationsC     = "actionnotifications"
-	actionresultsC           = "actionresults"
-	actionsC                 = "actions"
-	annotationsC             = "annotations"
-	autocertCacheC           = "autocertCache"
-	assignUnitC              = "assignUnits"
-	auditingC                = "audit.log"
-	bakeryStorageItemsC      = "bakeryStorageItems"
-	blockDevicesC            = "blockdevices"
-	blocksC                  = "blocks"
-	charmsC                  = "charms"
-	cleanupsC                = "cleanups"
-	cloudimagemetadataC      = "cloudimagemetadata"
-	cloudsC                  = "clouds"
-	cloudCredentialsC        = "cloudCredentials"
-	constraintsC             = "constraints"
-	containerRefsC           = "containerRefs"
-	controllersC             = "controllers"
-	controllerUsersC         = "controllerusers"
-	filesystemAttachmentsC   = "filesystemAttachments"
-	filesystemsC             = "filesystems"
-	globalSettingsC          = "globalSettings"
-	guimetadataC             = "guimetadata"
-	guisettingsC             = "guisettings"
-	instanceDataC            = "instanceData"
-	leasesC                  = "leases"
-	machinesC                = "machines"
-	machineRemovalsC         = "machineremovals"
-	meterStatusC             = "meterStatus"
-	metricsC                 = "metrics"
-	metricsManagerC          = "metricsmanager"
-	minUnitsC                = "minunits"
-	migrationsActiveC        = "migrations.active"
-	migrationsC              = "migrations"
-	migrationsMinionSyncC    = "migrations.minionsync"
-	migrationsStatusC        = "migrations.status"
-	modelUserLastConnectionC = "modelUserLastConnection"
-	modelUsersC              = "modelusers"
-	modelsC                  = "models"
-	modelEntityRefsC         = "modelEntityRefs"
-	openedPortsC             = "openedPorts"
-	payloadsC                = "payloads"
-	permissionsC             = "permissions"
-	providerIDsC             = "providerIDs"
-	rebootC                  = "reboot"
-	relationScopesC          = "relationscopes"
-	relationsC               = "relations"
-	restoreInfoC             = "restoreInfo"
-	sequenceC                = "sequence"
-	applicationsC            = "applications"
-	endpointBindingsC        = "endpointbindings"
-	settingsC                = "settings"
-	refcountsC               = "refcounts"
-	sshHostKeysC             = "sshhostkeys"
-	spacesC                  = "spaces"
-	statusesC                = "statuses"
-	statusesHistoryC         = "statuseshistory"
-	storageAttachmentsC      = "storageattachments"
-	storageConstraintsC      = "storageconstraints"
-	storageInstancesC        = "storageinstances"
-	subnetsC                 = "subnets"
-	linkLayerDevicesC        = "linklayerdevices"
-	linkLayerDevicesRefsC    = "linklayerdevicesrefs"
-	ipAddressesC             = "ip.addresses"
-	toolsmetadataC           = "toolsmetadata"
-	txnLogC                  = "txns.log"
-	txnsC                    = "txns"
-	unitsC                   = "units"
-	upgradeInfoC             = "upgradeInfo"
-	userLastLoginC           = "userLastLogin"
-	usermodelnameC           = "usermodelname"
-	usersC                   = "users"
-	volumeAttachmentsC       = "volumeattachments"
-	volumesC                 = "volumes"
+	actionNotificationsC       = "actionnotifications"
+	actionresultsC             = "actionresults"
+	actionsC                   = "actions"
+	annotationsC               = "annotations"
+	autocertCacheC             = "autocertCache"
+	assignUnitC                = "assignUnits"
+	auditingC                  = "audit.log"
+	bakeryStorageItemsC        = "bakeryStorageItems"
+	blockDevicesC              = "blockdevices"
+	blocksC                    = "blocks"
+	charmsC                    = "charms"
+	cleanupsC                  = "cleanups"
+	cloudimagemetadataC        = "cloudimagemetadata"
+	cloudsC                    = "clouds"
+	cloudCredentialsC          = "cloudCredentials"
+	constraintsC               = "constraints"
+	containerRefsC             = "containerRefs"
+	controllersC               = "controllers"
+	controllerUsersC           = "controllerusers"
+	filesystemAttachmentsC     = "filesystemAttachments"
+	filesystemsC               = "filesystems"
+	globalSettingsC            = "globalSettings"
+	guimetadataC               = "guimetadata"
+	guisettingsC               = "guisettings"
+	instanceDataC              = "instanceData"
+	leasesC                    = "leases"
+	machinesC                  = "machines"
+	machineAgentLoginsC        = "machineAgentLogins"
+	machineCreationKeysC       = "machinecreationkeys"
+	machineNetworkConfigC      = "machineNetworkConfig"
+	machineRemovalsC           = "machineremovals"
+	machineUpgradeSeriesLocksC = "machineUpgradeSeriesLocks"
+	meterStatusC               = "meterStatus"
+	metricsC                   = "metrics"
+	metricsManagerC            = "metricsmanager"
+	minUnitsC                  = "minunits"
+	migrationsActiveC          = "migrations.active"
+	migrationsC                = "migrations"
+	migrationsMinionSyncC      = "migrations.minionsync"
+	migrationsStatusC          = "migrations.status"
+	modelUserLastConnectionC   = "modelUserLastConnection"
+	modelUsersC                = "modelusers"
+	modelsC                    = "models"
+	modelEntityRefsC           = "modelEntityRefs"
+	openedPortsC               = "openedPorts"
+	payloadsC                  = "payloads"
+	permissionsC               = "permissions"
+	providerIDsC               = "providerIDs"
+	rebootC                    = "reboot"
+	relationScopesC            = "relationscopes"
+	relationsC                 = "relations"
+	restoreInfoC               = "restoreInfo"
+	sequenceC                  = "sequence"
+	applicationsC              = "applications"
+	endpointBindingsC          = "endpointbindings"
+	settingsC                  = "settings"
+	refcountsC                 = "refcounts"
+	sshHostKeysC               = "sshhostkeys"
+	spacesC                    = "spaces"
+	statusesC                  = "statuses"
+	statusesHistoryC           = "statuseshistory"
+	storageAttachmentsC        = "storageattachments"
+	storageConstraintsC        = "storageconstraints"
+	storageInstancesC          = "storageinstances"
+	subnetsC                   = "subnets"
+	linkLayerDevicesC          = "linklayerdevices"
+	linkLayerDevicesRefsC      = "linklayerdevicesrefs"
+	ipAddressesC               = "ip.addresses"
+	toolsmetadataC             = "toolsmetadata"
+	txnLogC                    = "txns.log"
+	txnsC                      = "txns"
+	unitsC                     = "units"
+	upgradeInfoC               = "upgradeInfo"
+	userLastLoginC             = "userLastLogin"
+	usermodelnameC             = "usermodelname"
+	usersC                     = "users"
+	volumeAttachmentsC         = "volumeattachments"
+	volumesC                   = "volumes"
 	// "resources" (see resource/persistence/mongo.go)
 )
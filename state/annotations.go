@@ -26,7 +26,8 @@ type annotatorDoc struct {
 	Annotations map[string]string `bson:"annotations"`
 }
 
-// SetAnnotations adds key/value pairs to annotations in MongoDB.
+// SetAnnotations adds key/value pairs to annotations in MongoDB. The
+// pairs set by a single call must fit within the State's DocumentLimits.
 func (st *State) SetAnnotations(entity GlobalEntity, annotations map[string]string) (err error) {
 	defer errors.DeferredAnnotatef(&err, "cannot update annotations on %s", entity.Tag())
 	if len(annotations) == 0 {
@@ -47,6 +48,9 @@ func (st *State) SetAnnotations(entity GlobalEntity, annotations map[string]stri
 			toUpdate[key] = value
 		}
 	}
+	if err := checkSize("annotations", toInsert, st.limits.Annotations); err != nil {
+		return errors.Trace(err)
+	}
 	// Set up and call the necessary transactions - if the document does not
 	// already exist, one of the clients will create it and the others will
 	// fail, then all the rest of the clients should succeed on their second
@@ -96,6 +100,12 @@ func (st *State) Annotation(entity GlobalEntity, key string) (string, error) {
 	return ann[key], nil
 }
 
+// WatchAnnotations returns a NotifyWatcher that notifies of changes to
+// the annotations of the given entity.
+func (st *State) WatchAnnotations(entity GlobalEntity) NotifyWatcher {
+	return newEntityWatcher(st, annotationsC, st.docID(entity.globalKey()))
+}
+
 // insertAnnotationsOps returns the operations required to insert annotations in MongoDB.
 func insertAnnotationsOps(st *State, entity GlobalEntity, toInsert map[string]string) ([]txn.Op, error) {
 	tag := entity.Tag()
@@ -11,6 +11,7 @@ import (
 	"gopkg.in/juju/names.v2"
 
 	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
 	"github.com/juju/juju/storage"
 	"github.com/juju/juju/testing"
 )
@@ -134,6 +135,71 @@ func (s *AnnotationsSuite) TestSetAnnotationsConcurrently(c *gc.C) {
 	assertAnnotation(c, s.State, s.testEntity, key, last)
 }
 
+func (s *AnnotationsSuite) TestSetAnnotationsConcurrentlyMergesKeys(c *gc.C) {
+	s.assertSetAnnotation(c, "existing", "value")
+
+	setAnnotations := func() {
+		err := s.State.SetAnnotations(s.testEntity, map[string]string{
+			"first":    "alpha",
+			"existing": "",
+		})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	defer state.SetBeforeHooks(c, s.State, setAnnotations).Check()
+	s.assertSetAnnotation(c, "last", "omega")
+
+	annts, err := s.State.Annotations(s.testEntity)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(annts, jc.DeepEquals, map[string]string{
+		"first": "alpha",
+		"last":  "omega",
+	})
+}
+
+func (s *AnnotationsSuite) TestMachineAnnotator(c *gc.C) {
+	var annotator state.Annotator = s.testEntity
+	err := annotator.SetAnnotations(map[string]string{"a": "1", "b": "2"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = annotator.SetAnnotations(map[string]string{"a": ""})
+	c.Assert(err, jc.ErrorIsNil)
+
+	annts, err := annotator.Annotations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(annts, jc.DeepEquals, map[string]string{"b": "2"})
+	value, err := annotator.Annotation("b")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "2")
+	assertAnnotation(c, s.State, s.testEntity, "a", "")
+}
+
+func (s *AnnotationsSuite) TestWatchAnnotations(c *gc.C) {
+	w := s.testEntity.WatchAnnotations()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	s.assertSetAnnotation(c, "key", "value")
+	wc.AssertOneChange()
+	s.assertSetAnnotation(c, "other", "value")
+	wc.AssertOneChange()
+	s.assertSetAnnotation(c, "key", "")
+	wc.AssertOneChange()
+
+	// Annotations on other entities are not reported.
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.SetAnnotations(map[string]string{"key": "value"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Removing the entity removes its annotations.
+	err = s.testEntity.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.testEntity.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
+
 type AnnotationsEnvSuite struct {
 	ConnSuite
 }
@@ -76,13 +76,14 @@ func (st *State) strictLocalID(ID string) (string, error) {
 // returned collection will automatically perform model
 // filtering where possible. See modelStateCollection below.
 func (st *State) getCollection(name string) (mongo.Collection, func()) {
-	return st.database.GetCollection(name)
+	collection, closer := st.database.GetCollection(name)
+	return st.timedCollection(collection), closer
 }
 
 func (st *State) getCollectionFor(modelUUID, name string) (mongo.Collection, func()) {
 	database, dbcloser := st.database.CopyForModel(modelUUID)
 	collection, closer := database.GetCollection(name)
-	return collection, func() {
+	return st.timedCollection(collection), func() {
 		closer()
 		dbcloser()
 	}
@@ -38,6 +38,7 @@ func testAgentTools(c *gc.C, obj tooler, agent string) {
 	t, err := obj.AgentTools()
 	c.Assert(t, gc.IsNil)
 	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("agent tools for %s not found", agent))
 
 	err = obj.SetAgentVersion(version.Binary{})
 	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("cannot set agent version for %s: empty series or arch", agent))
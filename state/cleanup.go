@@ -31,6 +31,8 @@ const (
 	cleanupAttachmentsForDyingFilesystem cleanupKind = "filesystemAttachments"
 	cleanupModelsForDyingController      cleanupKind = "models"
 	cleanupMachinesForDyingModel         cleanupKind = "modelMachines"
+	cleanupContainersForDyingMachine     cleanupKind = "machineContainers"
+	cleanupRemovedMachine                cleanupKind = "removedMachine"
 )
 
 // cleanupDoc originally represented a set of documents that should be
@@ -107,6 +109,10 @@ func (st *State) Cleanup() (err error) {
 			err = st.cleanupModelsForDyingController()
 		case cleanupMachinesForDyingModel:
 			err = st.cleanupMachinesForDyingModel()
+		case cleanupContainersForDyingMachine:
+			err = st.cleanupContainersForDyingMachine(doc.Prefix)
+		case cleanupRemovedMachine:
+			err = st.cleanupRemovedMachine(doc.Prefix)
 		default:
 			handler, ok := cleanupHandlers[doc.Kind]
 			if !ok {
@@ -422,6 +428,11 @@ func (st *State) cleanupForceDestroyedMachine(machineId string) error {
 	} else if err != nil {
 		return err
 	}
+	// Forcing the machine's destruction abandons any change to its
+	// series, which would otherwise keep it from becoming Dead.
+	if err := machine.RemoveUpgradeSeriesLock(); err != nil {
+		return err
+	}
 	// TODO(fwereade): 2013-11-11 bug 1250104
 	// If this fails, it's *probably* due to a race in which new dependencies
 	// were added while we cleaned up the old ones. If the cleanup doesn't run
@@ -457,7 +468,7 @@ func (st *State) cleanupContainers(machine *Machine) error {
 		}
 		container, err := st.Machine(containerId)
 		if errors.IsNotFound(err) {
-			return nil
+			continue
 		} else if err != nil {
 			return err
 		}
@@ -468,6 +479,53 @@ func (st *State) cleanupContainers(machine *Machine) error {
 	return nil
 }
 
+// cleanupContainersForDyingMachine destroys and removes the containers
+// hosted by the supplied machine. It is queued alongside the machine's
+// own force-destroy cleanup, and either may run first: each tolerates
+// the containers having already been removed by the other.
+func (st *State) cleanupContainersForDyingMachine(machineId string) error {
+	machine, err := st.Machine(machineId)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return st.cleanupContainers(machine)
+}
+
+// cleanupRemovedMachine removes the documents that refer to a machine
+// but cannot be removed in the same transaction as the machine itself:
+// its status history and agent login history, which are not managed by
+// transactions, and its instance data.
+func (st *State) cleanupRemovedMachine(machineId string) error {
+	history, closer := st.getCollection(statusesHistoryC)
+	defer closer()
+	historyW := history.Writeable()
+	for _, key := range []string{
+		machineGlobalKey(machineId),
+		machineGlobalInstanceKey(machineId),
+	} {
+		if _, err := historyW.RemoveAll(bson.D{{"statusid", key}}); err != nil {
+			return errors.Annotatef(err, "removing status history for %q", key)
+		}
+	}
+	logins, closer := st.getCollection(machineAgentLoginsC)
+	defer closer()
+	err := logins.Writeable().RemoveId(st.docID(machineId))
+	if err != nil && err != mgo.ErrNotFound {
+		return errors.Annotatef(err, "removing agent login history for machine %s", machineId)
+	}
+	ops := []txn.Op{{
+		C:      instanceDataC,
+		Id:     st.docID(machineId),
+		Remove: true,
+	}}
+	if err := st.runTransaction(ops); err != nil {
+		return errors.Annotatef(err, "removing instance data for machine %s", machineId)
+	}
+	return nil
+}
+
 func cleanupDyingMachineResources(m *Machine) error {
 	volumeAttachments, err := m.st.MachineVolumeAttachments(m.MachineTag())
 	if err != nil {
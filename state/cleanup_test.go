@@ -11,10 +11,13 @@ import (
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/charm.v6-unstable"
 	"gopkg.in/juju/names.v2"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/storage"
+	"github.com/juju/juju/status"
+	"github.com/juju/juju/testing"
 	"github.com/juju/juju/testing/factory"
 )
 
@@ -29,6 +32,20 @@ func (s *CleanupSuite) SetUpTest(c *gc.C) {
 	s.assertDoesNotNeedCleanup(c)
 }
 
+// assertCleanupsComplete runs cleanups until none remain, for use when
+// the number of passes required is not interesting.
+func (s *CleanupSuite) assertCleanupsComplete(c *gc.C) {
+	for i := 0; i < 10; i++ {
+		needed, err := s.State.NeedsCleanup()
+		c.Assert(err, jc.ErrorIsNil)
+		if !needed {
+			return
+		}
+		s.assertCleanupRuns(c)
+	}
+	c.Fatalf("cleanups still pending after 10 passes")
+}
+
 func (s *CleanupSuite) TestCleanupDyingServiceUnits(c *gc.C) {
 	// Create a service with some units.
 	mysql := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
@@ -383,6 +400,113 @@ func (s *CleanupSuite) TestCleanupForceDestroyedMachineWithContainer(c *gc.C) {
 	assertLife(c, machine, state.Dead)
 }
 
+func (s *CleanupSuite) TestCleanupMachineContainersInterrupted(c *gc.C) {
+	// Create a machine with two containers.
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container0, err := s.State.AddMachineInsideMachine(template, machine.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	container1, err := s.State.AddMachineInsideMachine(template, machine.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = machine.ForceDestroy()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertNeedsCleanup(c)
+
+	// Interrupt the cleanup pass as soon as it has changed anything,
+	// leaving the work partially done...
+	state.SetAfterHooks(c, s.State, func() {
+		panic("cleanup interrupted")
+	})
+	c.Assert(func() { s.State.Cleanup() }, gc.PanicMatches, "cleanup interrupted")
+	s.assertNeedsCleanup(c)
+
+	// ...and check that running it again completes the work.
+	s.assertCleanupsComplete(c)
+	assertMachineRemoved(c, container0)
+	assertMachineRemoved(c, container1)
+	assertLife(c, machine, state.Dead)
+}
+
+func (s *CleanupSuite) TestCleanupMachineContainersAlreadyRemoved(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	container, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, machine.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = machine.ForceDestroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Remove the container and the machine before the cleanups run.
+	err = container.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = container.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.assertCleanupCount(c, 1)
+}
+
+func (s *CleanupSuite) TestCleanupRemovedMachine(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.SetProvisioned("inst-0", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	now := testing.ZeroTime()
+	err = machine.SetInstanceStatus(status.StatusInfo{
+		Status: status.Running,
+		Since:  &now,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertDoesNotNeedCleanup(c)
+
+	err = machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertNeedsCleanup(c)
+	s.assertMachineDependents(c, machine, true)
+
+	// Interrupt the cleanup pass after its first transaction, and check
+	// that running it again completes the work.
+	state.SetAfterHooks(c, s.State, func() {
+		panic("cleanup interrupted")
+	})
+	c.Assert(func() { s.State.Cleanup() }, gc.PanicMatches, "cleanup interrupted")
+	s.assertNeedsCleanup(c)
+	s.assertCleanupCount(c, 1)
+	s.assertMachineDependents(c, machine, false)
+}
+
+// assertMachineDependents checks whether the documents removed by the
+// removed machine cleanup exist.
+func (s *CleanupSuite) assertMachineDependents(c *gc.C, machine *state.Machine, exist bool) {
+	history, err := machine.StatusHistory(status.StatusHistoryFilter{Size: 10})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(len(history) > 0, gc.Equals, exist)
+	history, err = machine.InstanceStatusHistory(status.StatusHistoryFilter{Size: 10})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(len(history) > 0, gc.Equals, exist)
+	count, err := s.instanceData.Find(bson.D{{"machineid", machine.Id()}}).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(count > 0, gc.Equals, exist)
+}
+
+func assertMachineRemoved(c *gc.C, machine *state.Machine) {
+	err := machine.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *CleanupSuite) TestCleanupDyingUnit(c *gc.C) {
 	// Create active unit, in a relation.
 	prr := NewProReqRelation(c, &s.ConnSuite, charm.ScopeGlobal)
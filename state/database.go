@@ -168,6 +168,21 @@ func (schema collectionSchema) Load(db *mgo.Database, modelUUID string) (Databas
 	}, nil
 }
 
+// LoadReadOnly returns a Database for the schema without creating any
+// collections or indexes. The returned Database will refuse to change
+// anything in the underlying database; see ErrReadOnly.
+func (schema collectionSchema) LoadReadOnly(db *mgo.Database, modelUUID string) (Database, error) {
+	if !names.IsValidModel(modelUUID) {
+		return nil, errors.New("invalid model UUID")
+	}
+	return &database{
+		raw:       db,
+		schema:    schema,
+		modelUUID: modelUUID,
+		readOnly:  true,
+	}, nil
+}
+
 // createCollection swallows collection-already-exists errors.
 func createCollection(raw *mgo.Collection, spec *mgo.CollectionInfo) error {
 	err := raw.Create(spec)
@@ -200,6 +215,10 @@ type database struct {
 	// ownSession is used to avoid copying additional sessions in a database
 	// resulting from Copy.
 	ownSession bool
+
+	// readOnly, if true, causes the database to reject transactions
+	// and writes made through its collections with ErrReadOnly.
+	readOnly bool
 }
 
 func (db *database) copySession(modelUUID string) (*database, SessionCloser) {
@@ -210,6 +229,7 @@ func (db *database) copySession(modelUUID string) (*database, SessionCloser) {
 		modelUUID:  modelUUID,
 		runner:     db.runner,
 		ownSession: true,
+		readOnly:   db.readOnly,
 	}, session.Close
 
 }
@@ -253,11 +273,18 @@ func (db *database) GetCollection(name string) (collection mongo.Collection, clo
 		// interface a bit to drop Writeable in this situation, but it's
 		// not convenient yet.
 	}
+
+	if db.readOnly {
+		collection = readOnlyCollection{collection}
+	}
 	return collection, closer
 }
 
 // TransactionRunner is part of the Database interface.
 func (db *database) TransactionRunner() (runner jujutxn.Runner, closer SessionCloser) {
+	if db.readOnly {
+		return readOnlyRunner{}, dontCloseAnything
+	}
 	runner = db.runner
 	closer = dontCloseAnything
 	if runner == nil {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/juju/juju/instance"
 )
@@ -31,7 +33,7 @@ func distributeUnit(u *Unit, candidates []instance.Id) ([]instance.Id, error) {
 	if distributor == nil {
 		return nil, fmt.Errorf("policy returned nil instance distributor without an error")
 	}
-	distributionGroup, err := ServiceInstances(u.st, u.doc.Application)
+	distributionGroup, err := u.st.ServiceInstances(u.doc.Application)
 	if err != nil {
 		return nil, err
 	}
@@ -41,33 +43,67 @@ func distributeUnit(u *Unit, candidates []instance.Id) ([]instance.Id, error) {
 	return distributor.DistributeInstances(candidates, distributionGroup)
 }
 
-// ServiceInstances returns the instance IDs of provisioned
-// machines that are assigned units of the specified application.
-func ServiceInstances(st *State, application string) ([]instance.Id, error) {
-	units, err := allUnits(st, application)
+// ServiceInstances returns the instance IDs of the provisioned
+// machines that host units of the specified application. Units of a
+// subordinate application are counted on their principal's machine.
+// Unassigned units and unprovisioned machines are skipped.
+func (st *State) ServiceInstances(application string) ([]instance.Id, error) {
+	instanceIds, err := st.applicationInstances(application, "")
+	return instanceIds, errors.Annotatef(err, "cannot get instances of application %q", application)
+}
+
+// DistributionGroup returns the instance IDs of the provisioned
+// machines that host the other units of the specified unit's
+// application, as passed to the InstanceDistributor policy when
+// choosing a machine for the unit. A subordinate unit's peers are
+// counted on their principals' machines.
+func (st *State) DistributionGroup(unitName string) ([]instance.Id, error) {
+	unit, err := st.Unit(unitName)
 	if err != nil {
-		return nil, err
+		return nil, errors.Trace(err)
 	}
-	instanceIds := make([]instance.Id, 0, len(units))
-	for _, unit := range units {
-		machineId, err := unit.AssignedMachineId()
-		if errors.IsNotAssigned(err) {
-			continue
-		} else if err != nil {
-			return nil, err
+	instanceIds, err := st.applicationInstances(unit.ApplicationName(), unitName)
+	return instanceIds, errors.Annotatef(err, "cannot get distribution group of unit %q", unitName)
+}
+
+// applicationInstances returns the instance IDs of the provisioned
+// machines hosting units of the application, other than excludeUnit.
+// It reads the units of the application, then the principals of any
+// subordinate units, then the instance data of their machines, rather
+// than loading each unit and machine in turn.
+func (st *State) applicationInstances(application, excludeUnit string) ([]instance.Id, error) {
+	units, closer := st.getCollection(unitsC)
+	defer closer()
+
+	var docs []unitDoc
+	query := bson.D{{"application", application}}
+	if excludeUnit != "" {
+		query = append(query, bson.DocElem{"name", bson.D{{"$ne", excludeUnit}}})
+	}
+	fields := bson.D{{"principal", 1}, {"machineid", 1}}
+	if err := units.Find(query).Select(fields).All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	machineIds := make(set.Strings)
+	var principals []string
+	for _, doc := range docs {
+		if doc.Principal != "" {
+			principals = append(principals, doc.Principal)
+		} else if doc.MachineId != "" {
+			machineIds.Add(doc.MachineId)
 		}
-		machine, err := st.Machine(machineId)
-		if err != nil {
-			return nil, err
+	}
+	if len(principals) > 0 {
+		docs = nil
+		query := bson.D{{"name", bson.D{{"$in", principals}}}}
+		if err := units.Find(query).Select(fields).All(&docs); err != nil {
+			return nil, errors.Trace(err)
 		}
-		instanceId, err := machine.InstanceId()
-		if err == nil {
-			instanceIds = append(instanceIds, instanceId)
-		} else if errors.IsNotProvisioned(err) {
-			continue
-		} else {
-			return nil, err
+		for _, doc := range docs {
+			if doc.MachineId != "" {
+				machineIds.Add(doc.MachineId)
+			}
 		}
 	}
-	return instanceIds, nil
+	return machineInstanceIds(st, machineIds.SortedValues())
 }
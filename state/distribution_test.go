@@ -180,3 +180,93 @@ func (s *InstanceDistributorSuite) TestDistributeInstancesNoPolicy(c *gc.C) {
 	_, err = unit.AssignToCleanMachine()
 	c.Assert(err, jc.ErrorIsNil)
 }
+
+// addUnits adds a wordpress unit to each of the machines, and
+// provisions the machines with the given indices.
+func (s *InstanceDistributorSuite) addUnits(c *gc.C, provisioned ...int) []*state.Unit {
+	units := make([]*state.Unit, len(s.machines))
+	for i, m := range s.machines {
+		unit, err := s.wordpress.AddUnit()
+		c.Assert(err, jc.ErrorIsNil)
+		err = unit.AssignToMachine(m)
+		c.Assert(err, jc.ErrorIsNil)
+		units[i] = unit
+	}
+	for _, i := range provisioned {
+		instId := instance.Id(fmt.Sprintf("i-blah-%d", i))
+		err := s.machines[i].SetProvisioned(instId, "fake-nonce", nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return units
+}
+
+func (s *InstanceDistributorSuite) TestServiceInstances(c *gc.C) {
+	s.addUnits(c, 0, 2)
+	// An unassigned unit is skipped.
+	_, err := s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	instanceIds, err := s.State.ServiceInstances("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, jc.SameContents, []instance.Id{"i-blah-0", "i-blah-2"})
+}
+
+func (s *InstanceDistributorSuite) TestServiceInstancesSharedMachine(c *gc.C) {
+	s.addUnits(c, 0)
+	unit, err := s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.AssignToMachine(s.machines[0])
+	c.Assert(err, jc.ErrorIsNil)
+
+	instanceIds, err := s.State.ServiceInstances("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, jc.DeepEquals, []instance.Id{"i-blah-0"})
+}
+
+func (s *InstanceDistributorSuite) TestServiceInstancesNoUnits(c *gc.C) {
+	instanceIds, err := s.State.ServiceInstances("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, gc.HasLen, 0)
+}
+
+func (s *InstanceDistributorSuite) TestDistributionGroup(c *gc.C) {
+	units := s.addUnits(c, 0, 1, 2)
+
+	instanceIds, err := s.State.DistributionGroup(units[1].Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, jc.SameContents, []instance.Id{"i-blah-0", "i-blah-2"})
+}
+
+func (s *InstanceDistributorSuite) TestDistributionGroupUnitNotFound(c *gc.C) {
+	_, err := s.State.DistributionGroup("wordpress/42")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/42" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *InstanceDistributorSuite) TestDistributionGroupSubordinates(c *gc.C) {
+	units := s.addUnits(c, 0, 1)
+	s.AddTestingService(c, "logging", s.AddTestingCharm(c, "logging"))
+	eps, err := s.State.InferEndpoints("logging", "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.AddRelation(eps...)
+	c.Assert(err, jc.ErrorIsNil)
+	var subordinates []string
+	for _, unit := range units[:2] {
+		ru, err := rel.Unit(unit)
+		c.Assert(err, jc.ErrorIsNil)
+		err = ru.EnterScope(nil)
+		c.Assert(err, jc.ErrorIsNil)
+		err = unit.Refresh()
+		c.Assert(err, jc.ErrorIsNil)
+		subordinates = append(subordinates, unit.SubordinateNames()...)
+	}
+	c.Assert(subordinates, gc.HasLen, 2)
+
+	instanceIds, err := s.State.ServiceInstances("logging")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, jc.SameContents, []instance.Id{"i-blah-0", "i-blah-1"})
+
+	instanceIds, err = s.State.DistributionGroup(subordinates[0])
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceIds, jc.DeepEquals, []instance.Id{"i-blah-1"})
+}
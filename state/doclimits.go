@@ -0,0 +1,84 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DocumentLimits holds the maximum sizes, in marshalled bytes, of
+// values that clients may write into the database. Values larger than
+// their limit are rejected with an ErrTooLarge error before any
+// transaction is attempted, rather than bloating (or failing to fit
+// in) the documents that hold them.
+type DocumentLimits struct {
+	// Annotations limits the annotations set by a single call to
+	// SetAnnotations.
+	Annotations int
+
+	// ProvisionerMetadata limits the metadata recorded by
+	// Machine.SetProvisionerMetadata.
+	ProvisionerMetadata int
+
+	// StatusMessage limits the message of a status.
+	StatusMessage int
+
+	// StatusData limits the data of a status.
+	StatusData int
+}
+
+// DefaultDocumentLimits returns the limits used by a State opened with
+// Open.
+func DefaultDocumentLimits() DocumentLimits {
+	return DocumentLimits{
+		Annotations:         64 * 1024,
+		ProvisionerMetadata: MaxProvisionerMetadataSize,
+		StatusMessage:       16 * 1024,
+		StatusData:          64 * 1024,
+	}
+}
+
+// Validate returns an error if any of the limits is not positive.
+func (l DocumentLimits) Validate() error {
+	for _, limit := range []struct {
+		field string
+		value int
+	}{
+		{"annotations", l.Annotations},
+		{"provisioner metadata", l.ProvisionerMetadata},
+		{"status message", l.StatusMessage},
+		{"status data", l.StatusData},
+	} {
+		if limit.value <= 0 {
+			return errors.NotValidf("%s limit %d", limit.field, limit.value)
+		}
+	}
+	return nil
+}
+
+// marshalledSize returns the number of bytes taken by value when it is
+// marshalled as the value of a field in a document.
+func marshalledSize(value interface{}) (int, error) {
+	data, err := bson.Marshal(bson.D{{"v", value}})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	// Don't count the enclosing document: its length (4 bytes), the
+	// type (1) and name (2) of the field, and its terminator (1).
+	return len(data) - 8, nil
+}
+
+// checkSize returns an ErrTooLarge error if the marshalled size of
+// value, which will be stored in field, exceeds limit.
+func checkSize(field string, value interface{}, limit int) error {
+	size, err := marshalledSize(value)
+	if err != nil {
+		return errors.Annotatef(err, "cannot measure %s", field)
+	}
+	if size > limit {
+		return &ErrTooLarge{Field: field, Size: size, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/mongo/mongotest"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/status"
+)
+
+type DocumentLimitsSuite struct {
+	ConnSuite
+	limited *state.State
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&DocumentLimitsSuite{})
+
+var testLimits = state.DocumentLimits{
+	Annotations:         1000,
+	ProvisionerMetadata: 2000,
+	StatusMessage:       3000,
+	StatusData:          4000,
+}
+
+func (s *DocumentLimitsSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	st, err := state.OpenWithLimits(
+		s.modelTag, s.State.ControllerTag(),
+		statetesting.NewMongoInfo(), mongotest.DialOpts(), nil,
+		testLimits,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	s.limited = st
+	s.AddCleanup(func(c *gc.C) {
+		c.Check(st.Close(), jc.ErrorIsNil)
+	})
+
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.machine, err = s.limited.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// sizedString returns a string whose marshalled size is size bytes.
+func sizedString(c *gc.C, size int) string {
+	value := strings.Repeat("x", size-5)
+	actual, err := state.MarshalledSize(value)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actual, gc.Equals, size)
+	return value
+}
+
+// sizedMap returns a map with a single entry whose marshalled size is
+// size bytes.
+func sizedMap(c *gc.C, size int) map[string]string {
+	value := map[string]string{"k": strings.Repeat("x", size-13)}
+	actual, err := state.MarshalledSize(value)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actual, gc.Equals, size)
+	return value
+}
+
+func assertTooLarge(c *gc.C, err error, field string, size, limit int) {
+	c.Assert(err, jc.Satisfies, state.IsTooLargeError)
+	tooLarge := errors.Cause(err).(*state.ErrTooLarge)
+	c.Assert(*tooLarge, jc.DeepEquals, state.ErrTooLarge{
+		Field: field,
+		Size:  size,
+		Limit: limit,
+	})
+}
+
+func (s *DocumentLimitsSuite) TestOpenWithInvalidLimits(c *gc.C) {
+	limits := testLimits
+	limits.StatusData = 0
+	_, err := state.OpenWithLimits(
+		s.modelTag, s.State.ControllerTag(),
+		statetesting.NewMongoInfo(), mongotest.DialOpts(), nil,
+		limits,
+	)
+	c.Assert(err, gc.ErrorMatches, "status data limit 0 not valid")
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *DocumentLimitsSuite) TestDefaultLimitsValid(c *gc.C) {
+	c.Assert(state.DefaultDocumentLimits().Validate(), jc.ErrorIsNil)
+}
+
+func (s *DocumentLimitsSuite) TestAnnotations(c *gc.C) {
+	under := sizedMap(c, testLimits.Annotations-1)
+	err := s.limited.SetAnnotations(s.machine, under)
+	c.Assert(err, jc.ErrorIsNil)
+
+	over := sizedMap(c, testLimits.Annotations+1)
+	err = s.limited.SetAnnotations(s.machine, over)
+	c.Assert(err, gc.ErrorMatches, `cannot update annotations on machine-0: annotations of 1001 bytes exceeds limit of 1000 bytes`)
+	assertTooLarge(c, err, "annotations", 1001, 1000)
+
+	annotations, err := s.State.Annotations(s.machine)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(annotations, jc.DeepEquals, under)
+}
+
+func (s *DocumentLimitsSuite) TestProvisionerMetadata(c *gc.C) {
+	under := sizedMap(c, testLimits.ProvisionerMetadata-1)
+	err := s.machine.SetProvisionerMetadata(under)
+	c.Assert(err, jc.ErrorIsNil)
+
+	over := sizedMap(c, testLimits.ProvisionerMetadata+1)
+	err = s.machine.SetProvisionerMetadata(over)
+	c.Assert(err, gc.ErrorMatches, `cannot set provisioner metadata for machine 0: provisioner metadata of 2001 bytes exceeds limit of 2000 bytes`)
+	assertTooLarge(c, err, "provisioner metadata", 2001, 2000)
+
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.ProvisionerMetadata(), jc.DeepEquals, under)
+}
+
+func (s *DocumentLimitsSuite) TestStatusMessage(c *gc.C) {
+	under := sizedString(c, testLimits.StatusMessage-1)
+	err := s.machine.SetStatus(status.StatusInfo{
+		Status:  status.Started,
+		Message: under,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.machine.SetStatus(status.StatusInfo{
+		Status:  status.Started,
+		Message: sizedString(c, testLimits.StatusMessage+1),
+	})
+	c.Assert(err, gc.ErrorMatches, `cannot set status: status message of 3001 bytes exceeds limit of 3000 bytes`)
+	assertTooLarge(c, err, "status message", 3001, 3000)
+
+	info, err := s.machine.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Message, gc.Equals, under)
+}
+
+func (s *DocumentLimitsSuite) TestStatusData(c *gc.C) {
+	under := sizedMap(c, testLimits.StatusData-1)
+	err := s.machine.SetStatus(status.StatusInfo{
+		Status: status.Started,
+		Data:   map[string]interface{}{"k": under["k"]},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	over := sizedMap(c, testLimits.StatusData+1)
+	err = s.machine.SetStatus(status.StatusInfo{
+		Status: status.Started,
+		Data:   map[string]interface{}{"k": over["k"]},
+	})
+	c.Assert(err, gc.ErrorMatches, `cannot set status: status data of 4001 bytes exceeds limit of 4000 bytes`)
+	assertTooLarge(c, err, "status data", 4001, 4000)
+
+	info, err := s.machine.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Data, jc.DeepEquals, map[string]interface{}{"k": under["k"]})
+}
+
+func (s *DocumentLimitsSuite) TestForModelKeepsLimits(c *gc.C) {
+	st, err := s.limited.ForModel(s.modelTag)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	err = st.SetAnnotations(s.machine, sizedMap(c, testLimits.Annotations+1))
+	assertTooLarge(c, err, "annotations", 1001, 1000)
+}
@@ -134,3 +134,36 @@ func IsParentDeviceHasChildrenError(err interface{}) bool {
 	_, ok := value.(*ErrParentDeviceHasChildren)
 	return ok
 }
+
+// ErrTooLarge is returned when a value is too large to be written to
+// the database; see DocumentLimits.
+type ErrTooLarge struct {
+	// Field describes the value that was rejected.
+	Field string
+
+	// Size is the marshalled size of the value, in bytes.
+	Size int
+
+	// Limit is the maximum allowed size of the value, in bytes.
+	Limit int
+}
+
+func (e *ErrTooLarge) Error() string {
+	return fmt.Sprintf("%s of %d bytes exceeds limit of %d bytes", e.Field, e.Size, e.Limit)
+}
+
+// IsTooLargeError returns if the given error or its cause is
+// ErrTooLarge.
+func IsTooLargeError(err interface{}) bool {
+	if err == nil {
+		return false
+	}
+	// In case of a wrapped error, check the cause first.
+	value := err
+	cause := errors.Cause(err.(error))
+	if cause != nil {
+		value = cause
+	}
+	_, ok := value.(*ErrTooLarge)
+	return ok
+}
@@ -44,10 +44,14 @@ const (
 	GUISettingsC      = guisettingsC
 	GlobalSettingsC   = globalSettingsC
 	SettingsC         = settingsC
+	StatusesC         = statusesC
+	UnitsC            = unitsC
 )
 
 var (
 	BinarystorageNew                     = &binarystorageNew
+	VerifyPageSize                       = &verifyPageSize
+	OpenToolsURL                         = &openToolsURL
 	ImageStorageNewStorage               = &imageStorageNewStorage
 	MachineIdLessThan                    = machineIdLessThan
 	ControllerAvailable                  = &controllerAvailable
@@ -61,6 +65,8 @@ var (
 	ModelGlobalKey                       = modelGlobalKey
 	MergeBindings                        = mergeBindings
 	UpgradeInProgressError               = errUpgradeInProgress
+	MarshalledSize                       = marshalledSize
+	SummariseTxnValue                    = summariseTxnValue
 )
 
 type (
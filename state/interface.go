@@ -13,6 +13,7 @@ import (
 	"github.com/juju/juju/controller"
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state/presence"
 	"github.com/juju/juju/status"
 	"github.com/juju/juju/tools"
 )
@@ -86,6 +87,14 @@ type NotifyWatcherFactory interface {
 	Watch() NotifyWatcher
 }
 
+// AgentPresencer represents an entity whose agent's presence
+// is tracked.
+type AgentPresencer interface {
+	AgentPresence() (bool, error)
+	WaitAgentPresence(timeout time.Duration) error
+	SetAgentPresence() (*presence.Pinger, error)
+}
+
 // AgentEntity represents an entity that can
 // have an agent responsible for it.
 type AgentEntity interface {
@@ -93,6 +102,7 @@ type AgentEntity interface {
 	Lifer
 	Authenticator
 	AgentTooler
+	AgentPresencer
 	status.StatusSetter
 	EnsureDeader
 	Remover
@@ -188,6 +198,27 @@ type GlobalEntity interface {
 	Tag() names.Tag
 }
 
+// Annotator is implemented by entities whose annotations can be read,
+// written and watched through the entity itself.
+type Annotator interface {
+	GlobalEntity
+
+	// SetAnnotations adds, updates or (for empty values) removes
+	// the given annotations, leaving any others unchanged.
+	SetAnnotations(annotations map[string]string) error
+
+	// Annotations returns all the entity's annotations.
+	Annotations() (map[string]string, error)
+
+	// Annotation returns the value of the given annotation, or an
+	// empty string if it is not set.
+	Annotation(key string) (string, error)
+
+	// WatchAnnotations returns a watcher that notifies of changes
+	// to the entity's annotations.
+	WatchAnnotations() NotifyWatcher
+}
+
 // Action represents  an instance of an action designated for a unit or machine
 // in the model.
 type Action interface {
@@ -37,6 +37,9 @@ var (
 	_ NotifyWatcherFactory = (*Application)(nil)
 	_ NotifyWatcherFactory = (*Model)(nil)
 
+	_ AgentPresencer = (*Machine)(nil)
+	_ AgentPresencer = (*Unit)(nil)
+
 	_ AgentEntity = (*Machine)(nil)
 	_ AgentEntity = (*Unit)(nil)
 
@@ -145,9 +145,35 @@ type machineDoc struct {
 	// an instance for the machine.
 	Placement string `bson:",omitempty"`
 
+	// ProvisionerMetadata holds details recorded by the provisioner
+	// about how the machine's instance was configured.
+	ProvisionerMetadata map[string]string `bson:"provisionermetadata,omitempty"`
+
+	// InstanceTags holds the user-defined tags to set on the
+	// machine's instance, in addition to those that Juju manages.
+	InstanceTags map[string]string `bson:"instancetags,omitempty"`
+
+	// AppliedInstanceTags holds the tags that the provisioner last
+	// reported setting on the machine's instance.
+	AppliedInstanceTags map[string]string `bson:"appliedinstancetags,omitempty"`
+
+	// InstanceTagsChanged is set when InstanceTags changes after
+	// the machine has been provisioned, and cleared when the
+	// provisioner reports having applied the new tags.
+	InstanceTagsChanged bool `bson:"instancetagschanged,omitempty"`
+
+	// CreationKey holds the key, if any, given by the client that
+	// added the machine. See MachineTemplate.CreationKey.
+	CreationKey string `bson:"creationkey,omitempty"`
+
 	// StopMongoUntilVersion holds the version that must be checked to
 	// know if mongo must be stopped.
 	StopMongoUntilVersion string `bson:",omitempty"`
+
+	// PresencePolicy holds the AgentPresencePolicy of the machine in
+	// the form read by ParseAgentPresencePolicy, or is empty for the
+	// standard policy.
+	PresencePolicy string `bson:"presencepolicy,omitempty"`
 }
 
 func newMachine(st *State, doc *machineDoc) *Machine {
@@ -249,11 +275,34 @@ func getInstanceData(st *State, id string) (instanceData, error) {
 		return instanceData{}, errors.NotFoundf("instance data for machine %v", id)
 	}
 	if err != nil {
-		return instanceData{}, fmt.Errorf("cannot get instance data for machine %v: %v", id, err)
+		return instanceData{}, errors.Annotatef(err, "cannot get instance data for machine %v", id)
 	}
 	return instData, nil
 }
 
+// machineInstanceIds returns the instance ids of those of the given
+// machines that have been provisioned, in a single query. Machines
+// that are not provisioned are skipped.
+func machineInstanceIds(st *State, machineIds []string) ([]instance.Id, error) {
+	if len(machineIds) == 0 {
+		return nil, nil
+	}
+	instanceDataCollection, closer := st.getCollection(instanceDataC)
+	defer closer()
+
+	var docs []instanceData
+	query := bson.D{{"machineid", bson.D{{"$in", machineIds}}}}
+	err := instanceDataCollection.Find(query).Select(bson.D{{"instanceid", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get instance data for machines %v", machineIds)
+	}
+	instanceIds := make([]instance.Id, len(docs))
+	for i, doc := range docs {
+		instanceIds[i] = doc.InstanceId
+	}
+	return instanceIds, nil
+}
+
 // Tag returns a tag identifying the machine. The String method provides a
 // string representation that is safe to use as a file name. The returned name
 // will be different from other Tag values returned by any other entities
@@ -300,7 +349,7 @@ func (m *Machine) SetHasVote(hasVote bool) error {
 		Assert: notDeadDoc,
 		Update: bson.D{{"$set", bson.D{{"hasvote", hasVote}}}},
 	}}
-	if err := m.st.runTransaction(ops); err != nil {
+	if err := m.st.runNamedTransaction("SetHasVote", ops); err != nil {
 		return fmt.Errorf("cannot set HasVote of machine %v: %v", m, onAbort(err, ErrDead))
 	}
 	m.doc.HasVote = hasVote
@@ -316,7 +365,7 @@ func (m *Machine) SetStopMongoUntilVersion(v mongo.Version) error {
 		Id:     m.doc.DocID,
 		Update: bson.D{{"$set", bson.D{{"stopmongountilversion", v.String()}}}},
 	}}
-	if err := m.st.runTransaction(ops); err != nil {
+	if err := m.st.runNamedTransaction("SetStopMongoUntilVersion", ops); err != nil {
 		return fmt.Errorf("cannot set StopMongoUntilVersion %v: %v", m, onAbort(err, ErrDead))
 	}
 	m.doc.StopMongoUntilVersion = v.String()
@@ -360,41 +409,50 @@ func (m *Machine) IsManual() (bool, error) {
 // It returns an error that satisfies errors.IsNotFound if the tools
 // have not yet been set.
 func (m *Machine) AgentTools() (*tools.Tools, error) {
-	if m.doc.Tools == nil {
-		return nil, errors.NotFoundf("agent tools for machine %v", m)
-	}
-	tools := *m.doc.Tools
-	return &tools, nil
+	return agentTools(m.doc.Tools, fmt.Sprintf("machine %v", m))
 }
 
 // checkVersionValidity checks whether the given version is suitable
 // for passing to SetAgentVersion.
 func checkVersionValidity(v version.Binary) error {
-	if v.Series == "" || v.Arch == "" {
-		return fmt.Errorf("empty series or arch")
-	}
-	return nil
+	return tools.ValidateVersion(v, false)
 }
 
 // SetAgentVersion sets the version of juju that the agent is
 // currently running.
 func (m *Machine) SetAgentVersion(v version.Binary) (err error) {
 	defer errors.DeferredAnnotatef(&err, "cannot set agent version for machine %v", m)
-	if err = checkVersionValidity(v); err != nil {
-		return err
-	}
-	tools := &tools.Tools{Version: v}
-	ops := []txn.Op{{
-		C:      machinesC,
-		Id:     m.doc.DocID,
-		Assert: notDeadDoc,
-		Update: bson.D{{"$set", bson.D{{"tools", tools}}}},
-	}}
 	// A "raw" transaction is needed here because this function gets
 	// called before database migraions have run so we don't
 	// necessarily want the env UUID added to the id.
-	if err := m.st.runRawTransaction(ops); err != nil {
-		return onAbort(err, ErrDead)
+	run := func(ops []txn.Op) error {
+		return m.st.runNamedRawTransaction("SetAgentVersion", ops)
+	}
+	tools, err := setAgentVersion(run, machinesC, m.doc.DocID, v)
+	if err != nil {
+		return err
+	}
+	m.doc.Tools = tools
+	return nil
+}
+
+// SetAgentTools records t as the tools that the machine's agent is
+// running. Exactly one of t.URL and t.StorageRef must be set; a
+// storage reference is that returned when the tools were put in the
+// controller's AgentToolsStorage.
+func (m *Machine) SetAgentTools(t *tools.Tools) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set agent tools for machine %v", m)
+	if err := validateAgentTools(t); err != nil {
+		return err
+	}
+	// A "raw" transaction is used for the same reason as in
+	// SetAgentVersion.
+	run := func(ops []txn.Op) error {
+		return m.st.runNamedRawTransaction("SetAgentTools", ops)
+	}
+	tools, err := setAgentTools(run, machinesC, m.doc.DocID, t)
+	if err != nil {
+		return err
 	}
 	m.doc.Tools = tools
 	return nil
@@ -404,6 +462,11 @@ func (m *Machine) SetAgentVersion(v version.Binary) (err error) {
 // should use to communicate with the controllers.  Previous passwords
 // are invalidated.
 func (m *Machine) SetMongoPassword(password string) error {
+	// The password is changed directly through the session rather
+	// than by a transaction, so it must be checked for here.
+	if m.st.readOnly {
+		return ErrReadOnly
+	}
 	if !m.IsManager() {
 		return errors.NotSupportedf("setting mongo password for non-controller machine %v", m)
 	}
@@ -432,7 +495,7 @@ func (m *Machine) setPasswordHash(passwordHash string) error {
 	// before the machine env UUID DB migration has run. In this case
 	// we don't want the automatic env UUID prefixing to the doc _id
 	// to occur.
-	if err := m.st.runRawTransaction(ops); err != nil {
+	if err := m.st.runNamedRawTransaction("SetPassword", ops); err != nil {
 		return fmt.Errorf("cannot set password of machine %v: %v", m, onAbort(err, ErrDead))
 	}
 	m.doc.PasswordHash = passwordHash
@@ -463,13 +526,15 @@ func (m *Machine) Destroy() error {
 }
 
 // ForceDestroy queues the machine for complete removal, including the
-// destruction of all units and containers on the machine.
+// destruction of all units and containers on the machine. Any change
+// to the machine's series in progress is abandoned, by removing its
+// upgrade series lock.
 func (m *Machine) ForceDestroy() error {
 	ops, err := m.forceDestroyOps()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if err := m.st.runTransaction(ops); err != txn.ErrAborted {
+	if err := m.st.runNamedTransaction("ForceDestroy", ops); err != txn.ErrAborted {
 		return errors.Trace(err)
 	}
 	return nil
@@ -481,12 +546,20 @@ func (m *Machine) forceDestroyOps() ([]txn.Op, error) {
 	if m.IsManager() {
 		return nil, errors.Trace(managerMachineError)
 	}
+	if err := m.st.precheckMachine(&m.doc, MachineChange{Operation: MachineForceDestroy}); err != nil {
+		return nil, errors.Trace(err)
+	}
 
-	return []txn.Op{{
+	assertOp := txn.Op{
 		C:      machinesC,
 		Id:     m.doc.DocID,
 		Assert: bson.D{{"jobs", bson.D{{"$nin", []MachineJob{JobManageModel}}}}},
-	}, newCleanupOp(cleanupForceDestroyedMachine, m.doc.Id)}, nil
+	}
+	return []txn.Op{
+		assertOp,
+		newCleanupOp(cleanupContainersForDyingMachine, m.doc.Id),
+		newCleanupOp(cleanupForceDestroyedMachine, m.doc.Id),
+	}, nil
 }
 
 // EnsureDead sets the machine lifecycle to Dead if it is Alive or Dying.
@@ -586,16 +659,6 @@ func IsHasAttachmentsError(err error) bool {
 // the machine has any responsibilities that preclude a valid change in
 // lifecycle, it will return an error.
 func (original *Machine) advanceLifecycle(life Life) (err error) {
-	containers, err := original.Containers()
-	if err != nil {
-		return err
-	}
-	if len(containers) > 0 {
-		return &HasContainersError{
-			MachineId:    original.doc.Id,
-			ContainerIds: containers,
-		}
-	}
 	m := original
 	defer func() {
 		if err == nil {
@@ -610,27 +673,9 @@ func (original *Machine) advanceLifecycle(life Life) (err error) {
 			original.doc.Life = life
 		}
 	}()
-	// op and
-	op := txn.Op{
-		C:      machinesC,
-		Id:     m.doc.DocID,
-		Update: bson.D{{"$set", bson.D{{"life", life}}}},
-	}
-	// noUnits asserts that the machine has no principal units.
-	noUnits := bson.DocElem{
-		"$or", []bson.D{
-			{{"principals", bson.D{{"$size", 0}}}},
-			{{"principals", bson.D{{"$exists", false}}}},
-		},
-	}
-	cleanupOp := newCleanupOp(cleanupDyingMachine, m.doc.Id)
 	// multiple attempts: one with original data, one with refreshed data, and a final
 	// one intended to determine the cause of failure of the preceding attempt.
 	buildTxn := func(attempt int) ([]txn.Op, error) {
-		advanceAsserts := bson.D{
-			{"jobs", bson.D{{"$nin", []MachineJob{JobManageModel}}}},
-			{"hasvote", bson.D{{"$ne", true}}},
-		}
 		// Grab a fresh copy of the machine data.
 		// We don't write to original, because the expectation is that state-
 		// changing methods only set the requested change on the receiver; a case
@@ -642,109 +687,209 @@ func (original *Machine) advanceLifecycle(life Life) (err error) {
 		} else if err != nil {
 			return nil, err
 		}
-		// Check that the life change is sane, and collect the assertions
-		// necessary to determine that it remains so.
 		switch life {
 		case Dying:
-			if m.doc.Life != Alive {
-				return nil, jujutxn.ErrNoOperations
-			}
-			advanceAsserts = append(advanceAsserts, isAliveDoc...)
+			return machineDyingOps(m)
 		case Dead:
-			if m.doc.Life == Dead {
-				return nil, jujutxn.ErrNoOperations
-			}
-			advanceAsserts = append(advanceAsserts, notDeadDoc...)
-		default:
-			panic(fmt.Errorf("cannot advance lifecycle to %v", life))
+			return machineDeadOps(m)
 		}
-		// Check that the machine does not have any responsibilities that
-		// prevent a lifecycle change.
-		if hasJob(m.doc.Jobs, JobManageModel) {
-			// (NOTE: When we enable multiple JobManageModel machines,
-			// this restriction will be lifted, but we will assert that the
-			// machine is not voting)
-			return nil, fmt.Errorf("machine %s is required by the model", m.doc.Id)
+		panic(fmt.Errorf("cannot advance lifecycle to %v", life))
+	}
+	operation := "Destroy"
+	if life == Dead {
+		operation = "EnsureDead"
+	}
+	if err = m.st.runNamed(operation, buildTxn); err == jujutxn.ErrExcessiveContention {
+		err = errors.Annotatef(err, "machine %s cannot advance lifecycle", m)
+	}
+	return err
+}
+
+// machineDyingOps returns the operations that set the machine to
+// Dying and queue its cleanup, without running them, so that they can
+// be composed with other operations into a single transaction. The
+// operations assert the machine's current life and whatever else makes
+// the change valid, so the transaction is aborted if they cease to
+// hold. m should have been read freshly from the database; if it is
+// not Alive, jujutxn.ErrNoOperations is returned. Machine.Destroy is
+// implemented with machineDyingOps.
+func machineDyingOps(m *Machine) ([]txn.Op, error) {
+	if err := m.checkNoContainers(); err != nil {
+		return nil, err
+	}
+	if m.doc.Life != Alive {
+		return nil, jujutxn.ErrNoOperations
+	}
+	if err := m.st.precheckMachine(&m.doc, MachineChange{Operation: MachineDestroy}); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return m.advanceLifecycleOps(Dying, nil)
+}
+
+// machineDeadOps returns the operations that set the machine to Dead
+// and queue its cleanup, as machineDyingOps does for Dying. The given
+// assertions are added to those made on the machine document. If m is
+// already Dead, jujutxn.ErrNoOperations is returned; if it is locked
+// for a series upgrade, an UpgradeSeriesLockError is.
+// Machine.EnsureDead is implemented with machineDeadOps.
+func machineDeadOps(m *Machine, assertions ...bson.DocElem) ([]txn.Op, error) {
+	if err := m.checkNoContainers(); err != nil {
+		return nil, err
+	}
+	if m.doc.Life == Dead {
+		return nil, jujutxn.ErrNoOperations
+	}
+	if err := m.st.precheckMachine(&m.doc, MachineChange{Operation: MachineEnsureDead}); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if locked, err := m.IsLockedForSeriesUpgrade(); err != nil {
+		return nil, errors.Trace(err)
+	} else if locked {
+		return nil, &UpgradeSeriesLockError{MachineId: m.doc.Id}
+	}
+	ops, err := m.advanceLifecycleOps(Dead, assertions)
+	if err != nil {
+		return nil, err
+	}
+	return append(ops, assertNoUpgradeSeriesLockOp(m.doc.DocID)), nil
+}
+
+// checkNoContainers returns a HasContainersError if the machine hosts
+// any containers.
+func (m *Machine) checkNoContainers() error {
+	containers, err := m.Containers()
+	if err != nil {
+		return err
+	}
+	if len(containers) > 0 {
+		return &HasContainersError{
+			MachineId:    m.doc.Id,
+			ContainerIds: containers,
 		}
-		if m.doc.HasVote {
-			return nil, fmt.Errorf("machine %s is a voting replica set member", m.doc.Id)
+	}
+	return nil
+}
+
+// advanceLifecycleOps returns the operations that advance the
+// machine's life to the supplied value, asserting the machine's
+// current life and responsibilities, and any extra assertions given.
+func (m *Machine) advanceLifecycleOps(life Life, extraAsserts bson.D) ([]txn.Op, error) {
+	op := txn.Op{
+		C:      machinesC,
+		Id:     m.doc.DocID,
+		Update: bson.D{{"$set", bson.D{{"life", life}}}},
+	}
+	// noUnits asserts that the machine has no principal units.
+	noUnits := bson.DocElem{
+		"$or", []bson.D{
+			{{"principals", bson.D{{"$size", 0}}}},
+			{{"principals", bson.D{{"$exists", false}}}},
+		},
+	}
+	cleanupOp := newCleanupOp(cleanupDyingMachine, m.doc.Id)
+	advanceAsserts := bson.D{
+		{"jobs", bson.D{{"$nin", []MachineJob{JobManageModel}}}},
+		{"hasvote", bson.D{{"$ne", true}}},
+	}
+	advanceAsserts = append(advanceAsserts, extraAsserts...)
+	// Check that the life change is sane, and collect the assertions
+	// necessary to determine that it remains so.
+	switch life {
+	case Dying:
+		if m.doc.Life != Alive {
+			return nil, jujutxn.ErrNoOperations
 		}
-		// If there are no alive units left on the machine, or all the services are dying,
-		// then the machine may be soon destroyed by a cleanup worker.
-		// In that case, we don't want to return any error about not being able to
-		// destroy a machine with units as it will be a lie.
-		if life == Dying {
-			canDie := true
-			var principalUnitnames []string
-			for _, principalUnit := range m.doc.Principals {
-				principalUnitnames = append(principalUnitnames, principalUnit)
-				u, err := m.st.Unit(principalUnit)
-				if err != nil {
-					return nil, errors.Annotatef(err, "reading machine %s principal unit %v", m, m.doc.Principals[0])
-				}
-				svc, err := u.Application()
-				if err != nil {
-					return nil, errors.Annotatef(err, "reading machine %s principal unit service %v", m, u.doc.Application)
-				}
-				if u.Life() == Alive && svc.Life() == Alive {
-					canDie = false
-					break
-				}
+		advanceAsserts = append(advanceAsserts, isAliveDoc...)
+	case Dead:
+		if m.doc.Life == Dead {
+			return nil, jujutxn.ErrNoOperations
+		}
+		advanceAsserts = append(advanceAsserts, notDeadDoc...)
+	default:
+		panic(fmt.Errorf("cannot advance lifecycle to %v", life))
+	}
+	// Check that the machine does not have any responsibilities that
+	// prevent a lifecycle change.
+	if hasJob(m.doc.Jobs, JobManageModel) {
+		// (NOTE: When we enable multiple JobManageModel machines,
+		// this restriction will be lifted, but we will assert that the
+		// machine is not voting)
+		return nil, fmt.Errorf("machine %s is required by the model", m.doc.Id)
+	}
+	if m.doc.HasVote {
+		return nil, fmt.Errorf("machine %s is a voting replica set member", m.doc.Id)
+	}
+	// If there are no alive units left on the machine, or all the services are dying,
+	// then the machine may be soon destroyed by a cleanup worker.
+	// In that case, we don't want to return any error about not being able to
+	// destroy a machine with units as it will be a lie.
+	if life == Dying {
+		canDie := true
+		var principalUnitnames []string
+		for _, principalUnit := range m.doc.Principals {
+			principalUnitnames = append(principalUnitnames, principalUnit)
+			u, err := m.st.Unit(principalUnit)
+			if err != nil {
+				return nil, errors.Annotatef(err, "reading machine %s principal unit %v", m, m.doc.Principals[0])
 			}
-			if canDie {
-				containers, err := m.Containers()
-				if err != nil {
-					return nil, errors.Annotatef(err, "reading machine %s containers", m)
-				}
-				canDie = len(containers) == 0
+			svc, err := u.Application()
+			if err != nil {
+				return nil, errors.Annotatef(err, "reading machine %s principal unit service %v", m, u.doc.Application)
 			}
-			if canDie {
-				checkUnits := bson.DocElem{
-					"$or", []bson.D{
-						{{"principals", principalUnitnames}},
-						{{"principals", bson.D{{"$size", 0}}}},
-						{{"principals", bson.D{{"$exists", false}}}},
-					},
-				}
-				op.Assert = append(advanceAsserts, checkUnits)
-				containerCheck := txn.Op{
-					C:  containerRefsC,
-					Id: m.doc.DocID,
-					Assert: bson.D{{"$or", []bson.D{
-						{{"children", bson.D{{"$size", 0}}}},
-						{{"children", bson.D{{"$exists", false}}}},
-					}}},
-				}
-				return []txn.Op{op, containerCheck, cleanupOp}, nil
+			if u.Life() == Alive && svc.Life() == Alive {
+				canDie = false
+				break
 			}
 		}
-
-		if len(m.doc.Principals) > 0 {
-			return nil, &HasAssignedUnitsError{
-				MachineId: m.doc.Id,
-				UnitNames: m.doc.Principals,
+		if canDie {
+			containers, err := m.Containers()
+			if err != nil {
+				return nil, errors.Annotatef(err, "reading machine %s containers", m)
 			}
+			canDie = len(containers) == 0
 		}
-		advanceAsserts = append(advanceAsserts, noUnits)
-
-		if life == Dead {
-			// A machine may not become Dead until it has no more
-			// attachments to inherently machine-bound storage.
-			storageAsserts, err := m.assertNoPersistentStorage()
-			if err != nil {
-				return nil, errors.Trace(err)
+		if canDie {
+			checkUnits := bson.DocElem{
+				"$or", []bson.D{
+					{{"principals", principalUnitnames}},
+					{{"principals", bson.D{{"$size", 0}}}},
+					{{"principals", bson.D{{"$exists", false}}}},
+				},
+			}
+			op.Assert = append(advanceAsserts, checkUnits)
+			containerCheck := txn.Op{
+				C:  containerRefsC,
+				Id: m.doc.DocID,
+				Assert: bson.D{{"$or", []bson.D{
+					{{"children", bson.D{{"$size", 0}}}},
+					{{"children", bson.D{{"$exists", false}}}},
+				}}},
 			}
-			advanceAsserts = append(advanceAsserts, storageAsserts...)
+			return []txn.Op{op, containerCheck, cleanupOp}, nil
 		}
+	}
 
-		// Add the additional asserts needed for this transaction.
-		op.Assert = advanceAsserts
-		return []txn.Op{op, cleanupOp}, nil
+	if len(m.doc.Principals) > 0 {
+		return nil, &HasAssignedUnitsError{
+			MachineId: m.doc.Id,
+			UnitNames: m.doc.Principals,
+		}
 	}
-	if err = m.st.run(buildTxn); err == jujutxn.ErrExcessiveContention {
-		err = errors.Annotatef(err, "machine %s cannot advance lifecycle", m)
+	advanceAsserts = append(advanceAsserts, noUnits)
+
+	if life == Dead {
+		// A machine may not become Dead until it has no more
+		// attachments to inherently machine-bound storage.
+		storageAsserts, err := m.assertNoPersistentStorage()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		advanceAsserts = append(advanceAsserts, storageAsserts...)
 	}
-	return err
+
+	// Add the additional asserts needed for this transaction.
+	op.Assert = advanceAsserts
+	return []txn.Op{op, cleanupOp}, nil
 }
 
 // assertNoPersistentStorage ensures that there are no persistent volumes or
@@ -848,6 +993,7 @@ func (m *Machine) removeOps() ([]txn.Op, error) {
 		removeMachineBlockDevicesOp(m.Id()),
 		removeModelMachineRefOp(m.st, m.Id()),
 		removeSSHHostKeyOp(m.st, m.globalKey()),
+		newCleanupOp(cleanupRemovedMachine, m.doc.Id),
 	}
 	linkLayerDevicesOps, err := m.removeAllLinkLayerDevicesOps()
 	if err != nil {
@@ -857,6 +1003,10 @@ func (m *Machine) removeOps() ([]txn.Op, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	networkConfigOps, err := m.removeObservedNetworkConfigOps()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	portsOps, err := m.removePortsOps()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -871,6 +1021,8 @@ func (m *Machine) removeOps() ([]txn.Op, error) {
 	}
 	ops = append(ops, linkLayerDevicesOps...)
 	ops = append(ops, devicesAddressesOps...)
+	ops = append(ops, networkConfigOps...)
+	ops = append(ops, m.removeMachineCreationKeyOps()...)
 	ops = append(ops, portsOps...)
 	ops = append(ops, removeContainerRefOps(m.st, m.Id())...)
 	ops = append(ops, filesystemOps...)
@@ -903,7 +1055,29 @@ func (m *Machine) Remove() (err error) {
 		}
 		return ops, nil
 	}
-	return m.st.run(buildTxn)
+	return m.st.runNamed("Remove", buildTxn)
+}
+
+var _ Annotator = (*Machine)(nil)
+
+// SetAnnotations is part of the Annotator interface.
+func (m *Machine) SetAnnotations(annotations map[string]string) error {
+	return m.st.SetAnnotations(m, annotations)
+}
+
+// Annotations is part of the Annotator interface.
+func (m *Machine) Annotations() (map[string]string, error) {
+	return m.st.Annotations(m)
+}
+
+// Annotation is part of the Annotator interface.
+func (m *Machine) Annotation(key string) (string, error) {
+	return m.st.Annotation(m, key)
+}
+
+// WatchAnnotations is part of the Annotator interface.
+func (m *Machine) WatchAnnotations() NotifyWatcher {
+	return m.st.WatchAnnotations(m)
 }
 
 // Refresh refreshes the contents of the machine from the underlying
@@ -921,41 +1095,33 @@ func (m *Machine) Refresh() error {
 	return nil
 }
 
-// AgentPresence returns whether the respective remote agent is alive.
+// AgentPresence returns whether the respective remote agent is alive,
+// as judged by AgentPresenceState. An unmonitored agent is never
+// reported as not alive.
 func (m *Machine) AgentPresence() (bool, error) {
-	pwatcher := m.st.workers.PresenceWatcher()
-	return pwatcher.Alive(m.globalKey())
+	state, err := m.AgentPresenceState()
+	if err != nil {
+		return false, err
+	}
+	return state != AgentPresenceDown, nil
 }
 
-// WaitAgentPresence blocks until the respective agent is alive.
+// WaitAgentPresence blocks until the respective agent is alive. The
+// timeout is multiplied by the number of periods of an extended
+// timeout policy; an unmonitored agent is not waited for.
 func (m *Machine) WaitAgentPresence(timeout time.Duration) (err error) {
 	defer errors.DeferredAnnotatef(&err, "waiting for agent of machine %v", m)
-	ch := make(chan presence.Change)
-	pwatcher := m.st.workers.PresenceWatcher()
-	pwatcher.Watch(m.globalKey(), ch)
-	defer pwatcher.Unwatch(m.globalKey(), ch)
-	for i := 0; i < 2; i++ {
-		select {
-		case change := <-ch:
-			if change.Alive {
-				return nil
-			}
-		case <-time.After(timeout):
-			// TODO(fwereade): 2016-03-17 lp:1558657
-			return fmt.Errorf("still not alive after timeout")
-		case <-pwatcher.Dead():
-			return pwatcher.Err()
-		}
+	policy := m.AgentPresencePolicy()
+	if policy.Mode == PresenceUnmonitored {
+		return nil
 	}
-	panic(fmt.Sprintf("presence reported dead status twice in a row for machine %v", m))
+	return waitAgentPresence(m.st, m.globalKey(), timeout*time.Duration(policy.timeoutMultiplier()))
 }
 
 // SetAgentPresence signals that the agent for machine m is alive.
 // It returns the started pinger.
 func (m *Machine) SetAgentPresence() (*presence.Pinger, error) {
-	presenceCollection := m.st.getPresenceCollection()
-	p := presence.NewPinger(presenceCollection, m.st.modelTag, m.globalKey())
-	err := p.Start()
+	p, err := setAgentPresence(m.st, m.globalKey())
 	if err != nil {
 		return nil, err
 	}
@@ -972,6 +1138,25 @@ func (m *Machine) SetAgentPresence() (*presence.Pinger, error) {
 	return p, nil
 }
 
+// RestartAgentPresence abandons the given pinger, which should have
+// been returned by SetAgentPresence for this machine, and starts a new
+// one for the machine's agent. It is intended for recovering after the
+// old pinger has failed, for example because the connection to the
+// database was lost. Any error the old pinger encountered is logged.
+func (m *Machine) RestartAgentPresence(old *presence.Pinger) (*presence.Pinger, error) {
+	if old != nil {
+		old.Kill()
+		if err := old.Wait(); err != nil {
+			logger.Debugf("old presence pinger for machine %v failed: %v", m.Id(), err)
+		}
+	}
+	p, err := m.SetAgentPresence()
+	if err != nil {
+		return nil, errors.Annotatef(err, "restarting presence for machine %v", m.Id())
+	}
+	return p, nil
+}
+
 // InstanceId returns the provider specific instance id for this
 // machine, or a NotProvisionedError, if not set.
 func (m *Machine) InstanceId() (instance.Id, error) {
@@ -1080,6 +1265,13 @@ func (m *Machine) SetProvisioned(id instance.Id, nonce string, characteristics *
 		return fmt.Errorf("instance id and nonce cannot be empty")
 	}
 
+	if err := m.st.precheckMachine(&m.doc, MachineChange{
+		Operation:  MachineSetProvisioned,
+		InstanceId: id,
+		Nonce:      nonce,
+	}); err != nil {
+		return errors.Trace(err)
+	}
 	if characteristics == nil {
 		characteristics = &instance.HardwareCharacteristics{}
 	}
@@ -1111,7 +1303,7 @@ func (m *Machine) SetProvisioned(id instance.Id, nonce string, characteristics *
 		},
 	}
 
-	if err = m.st.runTransaction(ops); err == nil {
+	if err = m.st.runNamedTransaction("SetProvisioned", ops); err == nil {
 		m.doc.Nonce = nonce
 		return nil
 	} else if err != txn.ErrAborted {
@@ -1327,7 +1519,7 @@ func (m *Machine) SetProviderAddresses(addresses ...network.Address) (err error)
 		return errors.Annotatef(err, "cannot refresh provider addresses for machine %s", m)
 	}
 	if err = m.setAddresses(addresses, &mdoc.Addresses, "addresses"); err != nil {
-		return fmt.Errorf("cannot set addresses of machine %v: %v", m, err)
+		return errors.Annotatef(err, "cannot set addresses of machine %v", m)
 	}
 	m.doc.Addresses = mdoc.Addresses
 	return nil
@@ -1359,7 +1551,7 @@ func (m *Machine) SetMachineAddresses(addresses ...network.Address) (err error)
 		return errors.Annotatef(err, "cannot refresh machine addresses for machine %s", m)
 	}
 	if err = m.setAddresses(addresses, &mdoc.MachineAddresses, "machineaddresses"); err != nil {
-		return fmt.Errorf("cannot set machine addresses of machine %v: %v", m, err)
+		return errors.Annotatef(err, "cannot set machine addresses of machine %v", m)
 	}
 	m.doc.MachineAddresses = mdoc.MachineAddresses
 	return nil
@@ -1375,9 +1567,9 @@ func (m *Machine) setAddresses(addresses []network.Address, field *[]address, fi
 
 	// Update addresses now.
 	network.SortAddresses(addressesToSet)
-	origin := OriginProvider
+	origin, operation := OriginProvider, "SetProviderAddresses"
 	if fieldName == "machineaddresses" {
-		origin = OriginMachine
+		origin, operation = OriginMachine, "SetMachineAddresses"
 	}
 	stateAddresses := fromNetworkAddresses(addressesToSet, origin)
 
@@ -1420,7 +1612,7 @@ func (m *Machine) setAddresses(addresses []network.Address, field *[]address, fi
 		ops = append(ops, setPublicAddressOps...)
 		return ops, nil
 	}
-	err = m.st.run(buildTxn)
+	err = m.st.runNamed(operation, buildTxn)
 	if err == txn.ErrAborted {
 		return ErrDead
 	} else if err != nil {
@@ -1463,6 +1655,79 @@ func (m *Machine) Placement() string {
 	return m.doc.Placement
 }
 
+// MaxProvisionerMetadataSize is the default maximum marshalled size, in
+// bytes, of the metadata recorded by SetProvisionerMetadata.
+const MaxProvisionerMetadataSize = 16 * 1024
+
+// ProvisionerMetadata returns the details recorded by the provisioner
+// about how the machine's instance was configured, such as the apt
+// mirror, proxy settings and tools URL used.
+func (m *Machine) ProvisionerMetadata() map[string]string {
+	return copyStringMap(m.doc.ProvisionerMetadata)
+}
+
+// copyStringMap returns a copy of in, or nil if in is empty.
+func copyStringMap(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(in))
+	for k, v := range in {
+		result[k] = v
+	}
+	return result
+}
+
+// SetProvisionerMetadata records details about how the machine's
+// instance was configured, replacing any previously recorded. The
+// metadata must fit within the State's DocumentLimits, and keys may
+// not contain "." or start with "$". Secrets should be removed from the metadata before it is
+// recorded; see FilterProvisionerMetadata.
+func (m *Machine) SetProvisionerMetadata(metadata map[string]string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set provisioner metadata for machine %v", m)
+	for k := range metadata {
+		if k == "" || strings.Contains(k, ".") || strings.HasPrefix(k, "$") {
+			return errors.NotValidf("key %q", k)
+		}
+	}
+	stored := copyStringMap(metadata)
+	if err := checkSize("provisioner metadata", stored, m.st.limits.ProvisionerMetadata); err != nil {
+		return errors.Trace(err)
+	}
+	update := bson.D{{"$unset", bson.D{{"provisionermetadata", nil}}}}
+	if stored != nil {
+		update = bson.D{{"$set", bson.D{{"provisionermetadata", stored}}}}
+	}
+	ops := []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.DocID,
+		Assert: notDeadDoc,
+		Update: update,
+	}}
+	if err := m.st.runNamedTransaction("SetProvisionerMetadata", ops); err != nil {
+		return onAbort(err, ErrDead)
+	}
+	m.doc.ProvisionerMetadata = stored
+	return nil
+}
+
+// FilterProvisionerMetadata returns a copy of metadata without the
+// entries whose keys start with any of the given prefixes. It is
+// intended for removing secrets before metadata is recorded.
+func FilterProvisionerMetadata(metadata map[string]string, prefixes ...string) map[string]string {
+	filtered := make(map[string]string)
+outer:
+	for k, v := range metadata {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(k, prefix) {
+				continue outer
+			}
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
 // Constraints returns the exact constraints that should apply when provisioning
 // an instance for the machine.
 func (m *Machine) Constraints() (constraints.Value, error) {
@@ -1514,7 +1779,7 @@ func (m *Machine) SetConstraints(cons constraints.Value) (err error) {
 		}
 		return ops, nil
 	}
-	return m.st.run(buildTxn)
+	return m.st.runNamed("SetConstraints", buildTxn)
 }
 
 // Status returns the status of the machine.
@@ -1583,7 +1848,7 @@ func (m *Machine) SupportedContainers() ([]instance.ContainerType, bool) {
 
 // SupportsNoContainers records the fact that this machine doesn't support any containers.
 func (m *Machine) SupportsNoContainers() (err error) {
-	if err = m.updateSupportedContainers([]instance.ContainerType{}); err != nil {
+	if err = m.updateSupportedContainers("SupportsNoContainers", []instance.ContainerType{}); err != nil {
 		return err
 	}
 	return m.markInvalidContainers()
@@ -1599,7 +1864,7 @@ func (m *Machine) SetSupportedContainers(containers []instance.ContainerType) (e
 			return fmt.Errorf("%q is not a valid container type", container)
 		}
 	}
-	if err = m.updateSupportedContainers(containers); err != nil {
+	if err = m.updateSupportedContainers("SetSupportedContainers", containers); err != nil {
 		return err
 	}
 	return m.markInvalidContainers()
@@ -1614,8 +1879,9 @@ func isSupportedContainer(container instance.ContainerType, supportedContainers
 	return false
 }
 
-// updateSupportedContainers sets the supported containers on this host machine.
-func (m *Machine) updateSupportedContainers(supportedContainers []instance.ContainerType) (err error) {
+// updateSupportedContainers sets the supported containers on this host
+// machine, as part of the named operation.
+func (m *Machine) updateSupportedContainers(operation string, supportedContainers []instance.ContainerType) (err error) {
 	ops := []txn.Op{
 		{
 			C:      machinesC,
@@ -1628,7 +1894,7 @@ func (m *Machine) updateSupportedContainers(supportedContainers []instance.Conta
 				}}},
 		},
 	}
-	if err = m.st.runTransaction(ops); err != nil {
+	if err = m.st.runNamedTransaction(operation, ops); err != nil {
 		err = onAbort(err, ErrDead)
 		logger.Errorf("cannot update supported containers of machine %v: %v", m, err)
 		return err
@@ -0,0 +1,173 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	jujutxn "github.com/juju/txn"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+type internalMachineSuite struct {
+	internalStateSuite
+}
+
+var _ = gc.Suite(&internalMachineSuite{})
+
+// settingsOp returns an operation, unrelated to any machine, that
+// creates settings with the given key.
+func settingsOp(key string) txn.Op {
+	return createSettingsOp(settingsC, key, map[string]interface{}{"composed": true})
+}
+
+func (s *internalMachineSuite) assertSettings(c *gc.C, key string, exist bool) {
+	_, err := readSettings(s.state, settingsC, key)
+	if exist {
+		c.Assert(err, jc.ErrorIsNil)
+	} else {
+		c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	}
+}
+
+func (s *internalMachineSuite) TestMachineDyingOpsComposed(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ops, err := machineDyingOps(m)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = m.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Life(), gc.Equals, Dying)
+	s.assertSettings(c, "composed", true)
+}
+
+func (s *internalMachineSuite) TestMachineDyingOpsAssertLife(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	ops, err := machineDyingOps(m)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The machine's life changes before the ops are run, so the
+	// whole transaction is aborted.
+	err = m.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, gc.Equals, txn.ErrAborted)
+	s.assertSettings(c, "composed", false)
+
+	// Now that the machine is Dying, there is nothing to do.
+	_, err = machineDyingOps(m)
+	c.Assert(err, gc.Equals, jujutxn.ErrNoOperations)
+}
+
+func (s *internalMachineSuite) TestMachineDyingOpsAssertUnits(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	ops, err := machineDyingOps(m)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A unit is assigned before the ops are run.
+	ch := AddTestingCharm(c, s.state, "wordpress")
+	app := AddTestingService(c, s.state, "wordpress", ch)
+	unit, err := app.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.AssignToMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, gc.Equals, txn.ErrAborted)
+	s.assertSettings(c, "composed", false)
+	err = m.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Life(), gc.Equals, Alive)
+}
+
+func (s *internalMachineSuite) TestMachineDeadOpsComposed(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ops, err := machineDeadOps(m)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = m.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Life(), gc.Equals, Dead)
+	s.assertSettings(c, "composed", true)
+
+	_, err = machineDeadOps(m)
+	c.Assert(err, gc.Equals, jujutxn.ErrNoOperations)
+}
+
+func (s *internalMachineSuite) TestMachineDeadOpsExtraAssertions(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ops, err := machineDeadOps(m, bson.DocElem{"nonce", "not-the-nonce"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, gc.Equals, txn.ErrAborted)
+	s.assertSettings(c, "composed", false)
+
+	ops, err = machineDeadOps(m, bson.DocElem{"nonce", ""})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(append(ops, settingsOp("composed")))
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertSettings(c, "composed", true)
+}
+
+func (s *internalMachineSuite) TestMachineLifeOpsWithContainers(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.state.AddMachineInsideMachine(MachineTemplate{
+		Series: "quantal",
+		Jobs:   []MachineJob{JobHostUnits},
+	}, m.Id(), "lxd")
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = machineDyingOps(m)
+	c.Assert(err, gc.FitsTypeOf, &HasContainersError{})
+	_, err = machineDeadOps(m)
+	c.Assert(err, gc.FitsTypeOf, &HasContainersError{})
+}
+
+func (s *internalMachineSuite) TestMachineDeadOpsUpgradeSeriesLock(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A lock created after the operations are built aborts them.
+	ops, err := machineDeadOps(m)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.CreateUpgradeSeriesLock(nil, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.state.runTransaction(ops)
+	c.Assert(err, gc.Equals, txn.ErrAborted)
+
+	_, err = machineDeadOps(m)
+	c.Assert(err, jc.Satisfies, IsUpgradeSeriesLockError)
+}
+
+func (s *internalMachineSuite) TestAgentSeenTrackerExtendedTimeout(c *gc.C) {
+	var tracker agentSeenTracker
+	policy := AgentPresencePolicy{Mode: PresenceExtendedTimeout, Periods: 3}
+	start := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	c.Check(tracker.judge("m#0", policy, true, start), gc.Equals, AgentPresenceAlive)
+	c.Check(tracker.judge("m#0", policy, false, start.Add(89*time.Second)), gc.Equals, AgentPresenceAlive)
+	c.Check(tracker.judge("m#0", policy, false, start.Add(90*time.Second)), gc.Equals, AgentPresenceDown)
+
+	// Other agents, and the standard policy, get no grace.
+	c.Check(tracker.judge("m#1", policy, false, start), gc.Equals, AgentPresenceDown)
+	standard := AgentPresencePolicy{Mode: PresenceStandard}
+	c.Check(tracker.judge("m#0", standard, false, start.Add(time.Second)), gc.Equals, AgentPresenceDown)
+}
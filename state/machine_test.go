@@ -4,7 +4,12 @@
 package state_test
 
 import (
+	"fmt"
+	"runtime"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
@@ -591,6 +596,24 @@ func (s *MachineSuite) TestMachineSetAgentPresence(c *gc.C) {
 	c.Assert(alive, jc.IsTrue)
 }
 
+func (s *MachineSuite) TestMachineRestartAgentPresence(c *gc.C) {
+	old, err := s.machine.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+
+	pinger, err := s.machine.RestartAgentPresence(old)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pinger, gc.NotNil)
+	defer func() {
+		c.Assert(worker.Stop(pinger), jc.ErrorIsNil)
+	}()
+	c.Check(pinger, gc.Not(gc.Equals), old)
+
+	s.State.StartSync()
+	alive, err := s.machine.AgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alive, jc.IsTrue)
+}
+
 func (s *MachineSuite) TestTag(c *gc.C) {
 	tag := s.machine.MachineTag()
 	c.Assert(tag.Kind(), gc.Equals, names.MachineTagKind)
@@ -895,6 +918,82 @@ func (s *MachineSuite) TestMachineSetProvisionedWhenNotAlive(c *gc.C) {
 	})
 }
 
+func (s *MachineSuite) TestProvisionerMetadata(c *gc.C) {
+	c.Assert(s.machine.ProvisionerMetadata(), gc.IsNil)
+	metadata := map[string]string{
+		"apt-mirror": "http://mirror.example.com",
+		"tools-url":  "https://streams.example.com/tools",
+	}
+	err := s.machine.SetProvisionerMetadata(metadata)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.ProvisionerMetadata(), jc.DeepEquals, metadata)
+
+	// Changes to the returned map do not affect the machine.
+	s.machine.ProvisionerMetadata()["apt-mirror"] = "changed"
+	c.Assert(s.machine.ProvisionerMetadata(), jc.DeepEquals, metadata)
+
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.ProvisionerMetadata(), jc.DeepEquals, metadata)
+
+	// Setting empty metadata removes it.
+	err = s.machine.SetProvisionerMetadata(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.machine.ProvisionerMetadata(), gc.IsNil)
+}
+
+func (s *MachineSuite) TestSetProvisionerMetadataInvalid(c *gc.C) {
+	for i, test := range []struct {
+		metadata map[string]string
+		err      string
+	}{{
+		metadata: map[string]string{"proxy.http": "x"},
+		err:      `key "proxy.http" not valid`,
+	}, {
+		metadata: map[string]string{"$set": "x"},
+		err:      `key "\$set" not valid`,
+	}, {
+		metadata: map[string]string{"": "x"},
+		err:      `key "" not valid`,
+	}} {
+		c.Logf("test %d", i)
+		err := s.machine.SetProvisionerMetadata(test.metadata)
+		c.Check(err, gc.ErrorMatches, "cannot set provisioner metadata for machine 1: "+test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+	c.Assert(s.machine.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.machine.ProvisionerMetadata(), gc.IsNil)
+}
+
+func (s *MachineSuite) TestSetProvisionerMetadataTooLarge(c *gc.C) {
+	metadata := map[string]string{"big": strings.Repeat("x", state.MaxProvisionerMetadataSize)}
+	err := s.machine.SetProvisionerMetadata(metadata)
+	c.Check(err, gc.ErrorMatches, "cannot set provisioner metadata for machine 1: provisioner metadata of 16399 bytes exceeds limit of 16384 bytes")
+	c.Check(err, jc.Satisfies, state.IsTooLargeError)
+	c.Assert(s.machine.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.machine.ProvisionerMetadata(), gc.IsNil)
+}
+
+func (s *MachineSuite) TestSetProvisionerMetadataDead(c *gc.C) {
+	c.Assert(s.machine.EnsureDead(), jc.ErrorIsNil)
+	err := s.machine.SetProvisionerMetadata(map[string]string{"a": "b"})
+	c.Assert(err, gc.ErrorMatches, "cannot set provisioner metadata for machine 1: not found or dead")
+}
+
+func (s *MachineSuite) TestFilterProvisionerMetadata(c *gc.C) {
+	metadata := map[string]string{
+		"apt-mirror":    "http://mirror.example.com",
+		"secret-token":  "hunter2",
+		"password-root": "hunter2",
+	}
+	filtered := state.FilterProvisionerMetadata(metadata, "secret-", "password-")
+	c.Assert(filtered, jc.DeepEquals, map[string]string{
+		"apt-mirror": "http://mirror.example.com",
+	})
+	c.Assert(metadata, gc.HasLen, 3)
+}
+
 func (s *MachineSuite) TestMachineSetInstanceStatus(c *gc.C) {
 	// Machine needs to be provisioned first.
 	err := s.machine.SetProvisioned("umbrella/0", "fake_nonce", nil)
@@ -1107,6 +1206,207 @@ func (s *MachineSuite) TestWatchMachine(c *gc.C) {
 	testing.NewNotifyWatcherC(c, s.State, w).AssertOneChange()
 }
 
+func (s *MachineSuite) TestConcurrentReadsAndWrites(c *gc.C) {
+	// Reads run on their own copies of the State's session, so
+	// concurrent reads and transactional writes must neither fail
+	// nor block one another.
+	const workers = 8
+	const iterations = 10
+	errs := make(chan error, workers*2)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if _, err := s.State.AddMachine("quantal", state.JobHostUnits); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			m, err := s.State.Machine(s.machine.Id())
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < iterations; j++ {
+				if _, err := s.State.AllMachines(); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := m.Units(); err != nil {
+					errs <- err
+					return
+				}
+				if err := m.Refresh(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("concurrent reads and writes did not complete")
+	}
+	close(errs)
+	for err := range errs {
+		c.Check(err, jc.ErrorIsNil)
+	}
+	machines, err := s.State.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, 2+workers*iterations)
+}
+
+func (s *MachineSuite) TestWatchMachines(c *gc.C) {
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	w := s.State.WatchMachines(s.machine0.Id(), s.machine.Id())
+	defer testing.AssertStop(c, w)
+
+	// Initial event.
+	wc := testing.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChange(s.machine0.Id(), s.machine.Id())
+	wc.AssertNoChange()
+
+	// Change a watched machine, check it's reported.
+	err = s.machine.SetAgentVersion(version.MustParseBinary("0.0.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(s.machine.Id())
+	wc.AssertNoChange()
+
+	// Change an unwatched machine, check nothing is reported.
+	err = other.SetAgentVersion(version.MustParseBinary("0.0.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Add the machine, check it's reported immediately and on change.
+	err = w.Add(other.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(other.Id())
+	err = other.SetAgentVersion(version.MustParseBinary("0.0.4-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(other.Id())
+	wc.AssertNoChange()
+
+	// Remove a machine, check it's not reported.
+	err = w.Remove(s.machine0.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine0.SetAgentVersion(version.MustParseBinary("0.0.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Stop, check closed.
+	testing.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *MachineSuite) TestWatchMachinesRemoveDropsPendingChange(c *gc.C) {
+	w := s.State.WatchMachines(s.machine.Id())
+	defer testing.AssertStop(c, w)
+	wc := testing.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChange(s.machine.Id())
+
+	// Deliver a change to the watcher without reading it, then
+	// remove the machine; the change must not be reported.
+	err := s.machine.SetAgentVersion(version.MustParseBinary("0.0.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	s.State.StartSync()
+	time.Sleep(coretesting.ShortWait)
+	err = w.Remove(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+}
+
+func (s *MachineSuite) TestWatchMachinesRemovedMachine(c *gc.C) {
+	w := s.State.WatchMachines(s.machine.Id())
+	defer testing.AssertStop(c, w)
+	wc := testing.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChange(s.machine.Id())
+
+	err := s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(s.machine.Id())
+	wc.AssertNoChange()
+}
+
+// addBenchmarkMachines adds count machines and returns them.
+func (s *MachineSuite) addBenchmarkMachines(c *gc.C, count int) []*state.Machine {
+	machines := make([]*state.Machine, count)
+	for i := range machines {
+		m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+		c.Assert(err, jc.ErrorIsNil)
+		machines[i] = m
+	}
+	return machines
+}
+
+// mutateMachines changes each of the given machines once per iteration,
+// calling wait after each change.
+func mutateMachines(c *gc.C, machines []*state.Machine, n int, wait func()) {
+	for i := 0; i < n; i++ {
+		m := machines[i%len(machines)]
+		err := m.SetAgentVersion(version.MustParseBinary(fmt.Sprintf("0.0.%d-quantal-amd64", i+1)))
+		c.Assert(err, jc.ErrorIsNil)
+		wait()
+	}
+}
+
+func (s *MachineSuite) BenchmarkWatchEachMachine(c *gc.C) {
+	machines := s.addBenchmarkMachines(c, 500)
+	before := runtime.NumGoroutine()
+	in := make(chan struct{}, len(machines))
+	var watchers []state.NotifyWatcher
+	for _, m := range machines {
+		w := m.Watch()
+		defer testing.AssertStop(c, w)
+		<-w.Changes()
+		watchers = append(watchers, w)
+	}
+	c.Logf("%d goroutines for %d watchers", runtime.NumGoroutine()-before, len(watchers))
+	for _, w := range watchers {
+		go func(w state.NotifyWatcher) {
+			for range w.Changes() {
+				in <- struct{}{}
+			}
+		}(w)
+	}
+	c.ResetTimer()
+	mutateMachines(c, machines, c.N, func() {
+		s.State.StartSync()
+		<-in
+	})
+}
+
+func (s *MachineSuite) BenchmarkWatchMachines(c *gc.C) {
+	machines := s.addBenchmarkMachines(c, 500)
+	before := runtime.NumGoroutine()
+	ids := make([]string, len(machines))
+	for i, m := range machines {
+		ids[i] = m.Id()
+	}
+	w := s.State.WatchMachines(ids...)
+	defer testing.AssertStop(c, w)
+	<-w.Changes()
+	c.Logf("%d goroutines for 1 watcher", runtime.NumGoroutine()-before)
+	c.ResetTimer()
+	mutateMachines(c, machines, c.N, func() {
+		s.State.StartSync()
+		<-w.Changes()
+	})
+}
+
 func (s *MachineSuite) TestWatchDiesOnStateClose(c *gc.C) {
 	// This test is testing logic in watcher.entityWatcher, which
 	// is also used by:
@@ -1474,6 +1774,41 @@ func (s *MachineSuite) TestSetProviderAddresses(c *gc.C) {
 	c.Assert(machine.Addresses(), jc.DeepEquals, expectedAddresses)
 }
 
+func (s *MachineSuite) TestSetProviderAddressesMachineRemoved(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	defer state.SetBeforeHooks(c, s.State, func() {
+		removeMachine(c, s.State, machine.Id())
+	}).Check()
+
+	err = machine.SetProviderAddresses(network.NewAddress("8.8.8.8"))
+	c.Assert(err, gc.ErrorMatches, `cannot set addresses of machine \d+: machine \d+ not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MachineSuite) TestSetMachineAddressesMachineRemoved(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	defer state.SetBeforeHooks(c, s.State, func() {
+		removeMachine(c, s.State, machine.Id())
+	}).Check()
+
+	err = machine.SetMachineAddresses(network.NewAddress("10.0.0.1"))
+	c.Assert(err, gc.ErrorMatches, `cannot set machine addresses of machine \d+: machine \d+ not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+// removeMachine removes the machine with the given id, via a separate
+// Machine so that the caller's copy is left stale.
+func removeMachine(c *gc.C, st *state.State, id string) {
+	m, err := st.Machine(id)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(m.Remove(), jc.ErrorIsNil)
+}
+
 func (s *MachineSuite) TestSetProviderAddressesWithContainers(c *gc.C) {
 	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -0,0 +1,123 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// machineCreationKeyDoc records the machine added with a creation key.
+// Its id is the key, so that inserting it fails if the key has already
+// been used; a unique index on the machines collection would not be
+// respected by transactions.
+type machineCreationKeyDoc struct {
+	DocID     string `bson:"_id"`
+	ModelUUID string `bson:"model-uuid"`
+	MachineId string `bson:"machineid"`
+}
+
+// insertMachineCreationKeyOp returns an operation that records the
+// creation key of the given machine, aborting if the key is already
+// in use.
+func (st *State) insertMachineCreationKeyOp(mdoc *machineDoc) txn.Op {
+	return txn.Op{
+		C:      machineCreationKeysC,
+		Id:     st.docID(mdoc.CreationKey),
+		Assert: txn.DocMissing,
+		Insert: &machineCreationKeyDoc{
+			DocID:     st.docID(mdoc.CreationKey),
+			ModelUUID: st.ModelUUID(),
+			MachineId: mdoc.Id,
+		},
+	}
+}
+
+// removeMachineCreationKeyOps returns the operations needed to release
+// the creation key of the machine, if it has one.
+func (m *Machine) removeMachineCreationKeyOps() []txn.Op {
+	if m.doc.CreationKey == "" {
+		return nil
+	}
+	return []txn.Op{{
+		C:      machineCreationKeysC,
+		Id:     m.st.docID(m.doc.CreationKey),
+		Assert: bson.D{{"machineid", m.doc.Id}},
+		Remove: true,
+	}}
+}
+
+// MachineByCreationKey returns the machine that was added with the
+// given creation key. See MachineTemplate.CreationKey.
+func (st *State) MachineByCreationKey(key string) (*Machine, error) {
+	if key == "" {
+		return nil, errors.NotValidf("empty creation key")
+	}
+	keys, closer := st.getCollection(machineCreationKeysC)
+	defer closer()
+
+	var doc machineCreationKeyDoc
+	err := keys.FindId(key).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("machine with creation key %q", key)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get machine with creation key %q", key)
+	}
+	return st.Machine(doc.MachineId)
+}
+
+// machineForCreationKey returns the machine already added with the
+// creation key of the given template, or nil if the template has no
+// key or no machine has been added with it.
+func (st *State) machineForCreationKey(template MachineTemplate) (*Machine, error) {
+	if template.CreationKey == "" {
+		return nil, nil
+	}
+	m, err := st.MachineByCreationKey(template.CreationKey)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return m, nil
+}
+
+// CreationKey returns the key given when the machine was added, or ""
+// if there was none or it has been cleared.
+func (m *Machine) CreationKey() string {
+	return m.doc.CreationKey
+}
+
+// ClearCreationKey releases the machine's creation key, so that a
+// later request to add a machine with the same key adds a new one.
+func (m *Machine) ClearCreationKey() (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot clear creation key of machine %s", m)
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.CreationKey == "" {
+			return nil, jujutxn.ErrNoOperations
+		}
+		ops := []txn.Op{{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: bson.D{{"creationkey", m.doc.CreationKey}},
+			Update: bson.D{{"$unset", bson.D{{"creationkey", nil}}}},
+		}}
+		return append(ops, m.removeMachineCreationKeyOps()...), nil
+	}
+	if err := m.st.run(buildTxn); err != nil {
+		return errors.Trace(err)
+	}
+	m.doc.CreationKey = ""
+	return nil
+}
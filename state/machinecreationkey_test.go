@@ -0,0 +1,185 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+)
+
+type MachineCreationKeySuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&MachineCreationKeySuite{})
+
+func keyedTemplate(key string) state.MachineTemplate {
+	return state.MachineTemplate{
+		Series:      "quantal",
+		Jobs:        []state.MachineJob{state.JobHostUnits},
+		CreationKey: key,
+	}
+}
+
+func (s *MachineCreationKeySuite) assertMachineCount(c *gc.C, expect int) {
+	machines, err := s.State.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, expect)
+}
+
+func (s *MachineCreationKeySuite) TestAddMachineRetried(c *gc.C) {
+	m0, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.CreationKey(), gc.Equals, "request-1")
+
+	m1, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m1.Id(), gc.Equals, m0.Id())
+	s.assertMachineCount(c, 1)
+
+	m2, err := s.State.AddOneMachine(keyedTemplate("request-2"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m2.Id(), gc.Not(gc.Equals), m0.Id())
+	s.assertMachineCount(c, 2)
+}
+
+func (s *MachineCreationKeySuite) TestAddMachinesSameKey(c *gc.C) {
+	ms, err := s.State.AddMachines(keyedTemplate("request-1"), keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ms, gc.HasLen, 2)
+	c.Assert(ms[0].Id(), gc.Equals, ms[1].Id())
+	s.assertMachineCount(c, 1)
+}
+
+func (s *MachineCreationKeySuite) TestMachinesWithoutKey(c *gc.C) {
+	m0, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.CreationKey(), gc.Equals, "")
+	m1, err := s.State.AddOneMachine(keyedTemplate(""))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m1.Id(), gc.Not(gc.Equals), m0.Id())
+	s.assertMachineCount(c, 2)
+
+	_, err = s.State.MachineByCreationKey("")
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *MachineCreationKeySuite) TestMachineByCreationKey(c *gc.C) {
+	m, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	found, err := s.State.MachineByCreationKey("request-1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(found.Id(), gc.Equals, m.Id())
+
+	_, err = s.State.MachineByCreationKey("request-2")
+	c.Assert(err, gc.ErrorMatches, `machine with creation key "request-2" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MachineCreationKeySuite) TestAddContainerRetried(c *gc.C) {
+	host, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	m0, err := s.State.AddMachineInsideMachine(keyedTemplate("request-1"), host.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	m1, err := s.State.AddMachineInsideMachine(keyedTemplate("request-1"), host.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m1.Id(), gc.Equals, m0.Id())
+
+	m2, err := s.State.AddMachineInsideNewMachine(keyedTemplate("request-1"), keyedTemplate(""), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m2.Id(), gc.Equals, m0.Id())
+	s.assertMachineCount(c, 2)
+}
+
+func (s *MachineCreationKeySuite) TestAddMachineConcurrentSameKey(c *gc.C) {
+	var added *state.Machine
+	defer state.SetBeforeHooks(c, s.State, func() {
+		var err error
+		added, err = s.State.AddOneMachine(keyedTemplate("request-1"))
+		c.Assert(err, jc.ErrorIsNil)
+	}).Check()
+
+	m, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Id(), gc.Equals, added.Id())
+	s.assertMachineCount(c, 1)
+}
+
+func (s *MachineCreationKeySuite) TestAddContainerConcurrentSameKey(c *gc.C) {
+	host, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	var added *state.Machine
+	defer state.SetBeforeHooks(c, s.State, func() {
+		var err error
+		added, err = s.State.AddMachineInsideMachine(keyedTemplate("request-1"), host.Id(), instance.LXD)
+		c.Assert(err, jc.ErrorIsNil)
+	}).Check()
+
+	m, err := s.State.AddMachineInsideMachine(keyedTemplate("request-1"), host.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Id(), gc.Equals, added.Id())
+	s.assertMachineCount(c, 2)
+}
+
+func (s *MachineCreationKeySuite) TestAddMachineRace(c *gc.C) {
+	const clients = 2
+	var wg sync.WaitGroup
+	ids := make([]string, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+			c.Check(err, jc.ErrorIsNil)
+			if m != nil {
+				ids[i] = m.Id()
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(ids[0], gc.Not(gc.Equals), "")
+	c.Assert(ids[1], gc.Equals, ids[0])
+	s.assertMachineCount(c, 1)
+}
+
+func (s *MachineCreationKeySuite) TestClearCreationKey(c *gc.C) {
+	m0, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = m0.ClearCreationKey()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.CreationKey(), gc.Equals, "")
+	err = m0.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.CreationKey(), gc.Equals, "")
+
+	_, err = s.State.MachineByCreationKey("request-1")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	m1, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m1.Id(), gc.Not(gc.Equals), m0.Id())
+
+	// Clearing a key that is not set does nothing.
+	err = m0.ClearCreationKey()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MachineCreationKeySuite) TestRemoveReleasesKey(c *gc.C) {
+	m0, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m0.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(m0.Remove(), jc.ErrorIsNil)
+
+	_, err = s.State.MachineByCreationKey("request-1")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	m1, err := s.State.AddOneMachine(keyedTemplate("request-1"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m1.Id(), gc.Not(gc.Equals), m0.Id())
+}
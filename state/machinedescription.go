@@ -0,0 +1,453 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/status"
+	"github.com/juju/juju/tools"
+)
+
+// MachineDescriptionVersion is the version of MachineDescription
+// written by ExportMachine, and the only version that ImportMachine
+// accepts.
+const MachineDescriptionVersion = 1
+
+// MachineDescription is a serializable snapshot of a machine, as
+// written by ExportMachine and read by State.ImportMachine.
+//
+// It holds the machine's own data: its document, agent tools, status,
+// instance, block devices, annotations and constraints. Presence is
+// not recorded, and nor are the units, storage and network devices
+// associated with the machine, which are entities in their own right.
+type MachineDescription struct {
+	Version int    `yaml:"version"`
+	Id      string `yaml:"id"`
+
+	Nonce                    string   `yaml:"nonce,omitempty"`
+	Series                   string   `yaml:"series"`
+	ContainerType            string   `yaml:"container-type,omitempty"`
+	Life                     Life     `yaml:"life"`
+	Jobs                     []string `yaml:"jobs"`
+	NoVote                   bool     `yaml:"no-vote,omitempty"`
+	HasVote                  bool     `yaml:"has-vote,omitempty"`
+	PasswordHash             string   `yaml:"password-hash,omitempty"`
+	Placement                string   `yaml:"placement,omitempty"`
+	SupportedContainersKnown bool     `yaml:"supported-containers-known,omitempty"`
+	SupportedContainers      []string `yaml:"supported-containers,omitempty"`
+
+	ProviderAddresses       []AddressDescription `yaml:"provider-addresses,omitempty"`
+	MachineAddresses        []AddressDescription `yaml:"machine-addresses,omitempty"`
+	PreferredPublicAddress  AddressDescription   `yaml:"preferred-public-address,omitempty"`
+	PreferredPrivateAddress AddressDescription   `yaml:"preferred-private-address,omitempty"`
+
+	ProvisionerMetadata map[string]string `yaml:"provisioner-metadata,omitempty"`
+	InstanceTags        map[string]string `yaml:"instance-tags,omitempty"`
+	AppliedInstanceTags map[string]string `yaml:"applied-instance-tags,omitempty"`
+	InstanceTagsChanged bool              `yaml:"instance-tags-changed,omitempty"`
+
+	Tools          *AgentToolsDescription      `yaml:"tools,omitempty"`
+	Status         StatusDescription           `yaml:"status"`
+	InstanceStatus StatusDescription           `yaml:"instance-status"`
+	Instance       *MachineInstanceDescription `yaml:"instance,omitempty"`
+	BlockDevices   []BlockDeviceInfo           `yaml:"block-devices,omitempty"`
+	Annotations    map[string]string           `yaml:"annotations,omitempty"`
+	Constraints    constraints.Value           `yaml:"constraints,omitempty"`
+
+	// Unknown collects any fields, read from a serialized
+	// description, that this version of Juju does not recognise.
+	// ImportMachine refuses descriptions with unknown fields rather
+	// than silently dropping data.
+	Unknown map[string]interface{} `yaml:",inline"`
+}
+
+// AddressDescription describes one of a machine's addresses.
+type AddressDescription struct {
+	Value     string `yaml:"value,omitempty"`
+	Type      string `yaml:"type,omitempty"`
+	Scope     string `yaml:"scope,omitempty"`
+	Origin    string `yaml:"origin,omitempty"`
+	SpaceName string `yaml:"space-name,omitempty"`
+}
+
+// AgentToolsDescription describes the tools of a machine's agent.
+type AgentToolsDescription struct {
+	Version string `yaml:"version"`
+	URL     string `yaml:"url,omitempty"`
+	SHA256  string `yaml:"sha256,omitempty"`
+	Size    int64  `yaml:"size,omitempty"`
+}
+
+// StatusDescription describes a machine or instance status. Updated
+// is in nanoseconds since the Unix epoch, as stored in the database.
+type StatusDescription struct {
+	Status  string                 `yaml:"status"`
+	Message string                 `yaml:"message,omitempty"`
+	Data    map[string]interface{} `yaml:"data,omitempty"`
+	Updated int64                  `yaml:"updated,omitempty"`
+}
+
+// MachineInstanceDescription describes the instance a machine has been
+// provisioned as.
+type MachineInstanceDescription struct {
+	InstanceId string                           `yaml:"instance-id"`
+	Status     string                           `yaml:"status,omitempty"`
+	Hardware   instance.HardwareCharacteristics `yaml:"hardware,omitempty"`
+}
+
+// ExportMachine returns a description of m's current state in the
+// database, which can be passed to State.ImportMachine.
+func ExportMachine(m *Machine) (_ *MachineDescription, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot export machine %v", m)
+	st := m.st
+	doc := m.doc
+	d := &MachineDescription{
+		Version:                  MachineDescriptionVersion,
+		Id:                       doc.Id,
+		Nonce:                    doc.Nonce,
+		Series:                   doc.Series,
+		ContainerType:            doc.ContainerType,
+		Life:                     doc.Life,
+		NoVote:                   doc.NoVote,
+		HasVote:                  doc.HasVote,
+		PasswordHash:             doc.PasswordHash,
+		Placement:                doc.Placement,
+		SupportedContainersKnown: doc.SupportedContainersKnown,
+		ProviderAddresses:        addressDescriptions(doc.Addresses),
+		MachineAddresses:         addressDescriptions(doc.MachineAddresses),
+		PreferredPublicAddress:   addressDescription(doc.PreferredPublicAddress),
+		PreferredPrivateAddress:  addressDescription(doc.PreferredPrivateAddress),
+		ProvisionerMetadata:      copyStringMap(doc.ProvisionerMetadata),
+		InstanceTags:             copyStringMap(doc.InstanceTags),
+		AppliedInstanceTags:      m.AppliedInstanceTags(),
+		InstanceTagsChanged:      doc.InstanceTagsChanged,
+	}
+	for _, job := range doc.Jobs {
+		value, ok := jobMigrationValue[job]
+		if !ok {
+			return nil, errors.Errorf("unknown machine job %d", job)
+		}
+		d.Jobs = append(d.Jobs, value)
+	}
+	for _, ctype := range doc.SupportedContainers {
+		d.SupportedContainers = append(d.SupportedContainers, string(ctype))
+	}
+	if doc.Tools != nil {
+		d.Tools = &AgentToolsDescription{
+			Version: doc.Tools.Version.String(),
+			URL:     doc.Tools.URL,
+			SHA256:  doc.Tools.SHA256,
+			Size:    doc.Tools.Size,
+		}
+	}
+	if d.Status, err = exportStatus(st, m.globalKey()); err != nil {
+		return nil, errors.Annotate(err, "machine status")
+	}
+	if d.InstanceStatus, err = exportStatus(st, m.globalInstanceKey()); err != nil {
+		return nil, errors.Annotate(err, "instance status")
+	}
+	instData, err := getInstanceData(st, doc.Id)
+	if err == nil {
+		d.Instance = &MachineInstanceDescription{
+			InstanceId: string(instData.InstanceId),
+			Status:     instData.Status,
+			Hardware:   *hardwareCharacteristics(instData),
+		}
+	} else if !errors.IsNotFound(err) {
+		return nil, errors.Trace(err)
+	}
+	if d.BlockDevices, err = getBlockDevices(st, doc.Id); err != nil {
+		return nil, errors.Trace(err)
+	}
+	annotations, err := st.Annotations(m)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(annotations) > 0 {
+		d.Annotations = annotations
+	}
+	if d.Constraints, err = readConstraints(st, m.globalKey()); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return d, nil
+}
+
+// ImportMachine adds a machine to the model from a description written
+// by ExportMachine, in a single transaction. It fails if a machine
+// with the same id already exists, or, for a container, if its parent
+// does not exist. Descriptions with an unsupported version, or with
+// fields that are not recognised, are rejected.
+func (st *State) ImportMachine(d *MachineDescription) (_ *Machine, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot import machine %q", d.Id)
+	if d.Version != MachineDescriptionVersion {
+		return nil, errors.NotValidf("machine description version %d", d.Version)
+	}
+	if len(d.Unknown) > 0 {
+		fields := make([]string, 0, len(d.Unknown))
+		for field := range d.Unknown {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return nil, errors.NotValidf("machine description with unknown fields %q", fields)
+	}
+	if !names.IsValidMachine(d.Id) {
+		return nil, errors.NotValidf("machine id")
+	}
+	mdoc, err := st.machineDocForDescription(d)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err := st.Machine(d.Id); err == nil {
+		return nil, errors.AlreadyExistsf("machine %s", d.Id)
+	} else if !errors.IsNotFound(err) {
+		return nil, errors.Trace(err)
+	}
+	parentId := ParentId(d.Id)
+	if parentId != "" {
+		if _, err := st.Machine(parentId); err != nil {
+			return nil, errors.Annotate(err, "parent")
+		}
+	}
+
+	// Make sure that machines added later will not be given the id of
+	// the imported machine. The sequences are not updated in
+	// transactions, so this is done first; if the import then fails,
+	// at worst an id is skipped.
+	seqName := "machine"
+	if parentId != "" {
+		seqName = fmt.Sprintf("machine%s%sContainer", parentId, d.ContainerType)
+	}
+	n, err := strconv.Atoi(d.Id[strings.LastIndex(d.Id, "/")+1:])
+	if err != nil {
+		return nil, errors.NotValidf("machine id")
+	}
+	if err := st.ensureSequenceAbove(seqName, n); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	ops := st.importMachineOps(mdoc, d)
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		if _, err := st.Machine(d.Id); err == nil {
+			return nil, errors.AlreadyExistsf("machine %s", d.Id)
+		}
+		return nil, errors.New("machine documents already exist, or parent machine removed")
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newMachine(st, mdoc), nil
+}
+
+// machineDocForDescription returns the machine document described
+// by d. The machine has no units or storage, so it is clean.
+func (st *State) machineDocForDescription(d *MachineDescription) (*machineDoc, error) {
+	mdoc := &machineDoc{
+		DocID:                    st.docID(d.Id),
+		Id:                       d.Id,
+		ModelUUID:                st.ModelUUID(),
+		Nonce:                    d.Nonce,
+		Series:                   d.Series,
+		ContainerType:            d.ContainerType,
+		Life:                     d.Life,
+		NoVote:                   d.NoVote,
+		HasVote:                  d.HasVote,
+		PasswordHash:             d.PasswordHash,
+		Clean:                    true,
+		Placement:                d.Placement,
+		SupportedContainersKnown: d.SupportedContainersKnown,
+		Addresses:                addressesFromDescriptions(d.ProviderAddresses),
+		MachineAddresses:         addressesFromDescriptions(d.MachineAddresses),
+		PreferredPublicAddress:   addressFromDescription(d.PreferredPublicAddress),
+		PreferredPrivateAddress:  addressFromDescription(d.PreferredPrivateAddress),
+		ProvisionerMetadata:      copyStringMap(d.ProvisionerMetadata),
+		InstanceTags:             copyStringMap(d.InstanceTags),
+		AppliedInstanceTags:      replaceTagKeys(d.AppliedInstanceTags, escapeReplacer),
+		InstanceTagsChanged:      d.InstanceTagsChanged,
+	}
+	if ContainerTypeFromId(d.Id) != instance.ContainerType(d.ContainerType) {
+		return nil, errors.NotValidf("container type %q for machine %s", d.ContainerType, d.Id)
+	}
+	for _, value := range d.Jobs {
+		job, err := machineJobFromMigrationValue(value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		mdoc.Jobs = append(mdoc.Jobs, job)
+	}
+	for _, ctype := range d.SupportedContainers {
+		mdoc.SupportedContainers = append(mdoc.SupportedContainers, instance.ContainerType(ctype))
+	}
+	if d.Tools != nil {
+		v, err := version.ParseBinary(d.Tools.Version)
+		if err != nil {
+			return nil, errors.Annotate(err, "agent tools")
+		}
+		mdoc.Tools = &tools.Tools{
+			Version: v,
+			URL:     d.Tools.URL,
+			SHA256:  d.Tools.SHA256,
+			Size:    d.Tools.Size,
+		}
+	}
+	return mdoc, nil
+}
+
+// importMachineOps returns the operations that add the machine
+// described by d, whose document is mdoc.
+func (st *State) importMachineOps(mdoc *machineDoc, d *MachineDescription) []txn.Op {
+	globalKey := machineGlobalKey(mdoc.Id)
+	ops := []txn.Op{
+		createConstraintsOp(st, globalKey, d.Constraints),
+		createStatusOp(st, globalKey, statusDocFromDescription(st, d.Status)),
+		createStatusOp(st, machineGlobalInstanceKey(mdoc.Id), statusDocFromDescription(st, d.InstanceStatus)),
+		{
+			C:      blockDevicesC,
+			Id:     mdoc.Id,
+			Assert: txn.DocMissing,
+			Insert: &blockDevicesDoc{
+				Machine:      mdoc.Id,
+				BlockDevices: d.BlockDevices,
+			},
+		},
+		addModelMachineRefOp(st, mdoc.Id),
+		st.insertNewContainerRefOp(mdoc.Id),
+	}
+	if parentId := ParentId(mdoc.Id); parentId != "" {
+		ops = append(ops, st.addChildToContainerRefOp(parentId, mdoc.Id))
+	}
+	if inst := d.Instance; inst != nil {
+		hc := inst.Hardware
+		ops = append(ops, txn.Op{
+			C:      instanceDataC,
+			Id:     mdoc.DocID,
+			Assert: txn.DocMissing,
+			Insert: &instanceData{
+				DocID:      mdoc.DocID,
+				MachineId:  mdoc.Id,
+				InstanceId: instance.Id(inst.InstanceId),
+				ModelUUID:  mdoc.ModelUUID,
+				Status:     inst.Status,
+				Arch:       hc.Arch,
+				Mem:        hc.Mem,
+				RootDisk:   hc.RootDisk,
+				CpuCores:   hc.CpuCores,
+				CpuPower:   hc.CpuPower,
+				Tags:       hc.Tags,
+				AvailZone:  hc.AvailabilityZone,
+			},
+		})
+	}
+	if len(d.Annotations) > 0 {
+		ops = append(ops, txn.Op{
+			C:      annotationsC,
+			Id:     st.docID(globalKey),
+			Assert: txn.DocMissing,
+			Insert: &annotatorDoc{
+				GlobalKey:   globalKey,
+				Tag:         names.NewMachineTag(mdoc.Id).String(),
+				Annotations: d.Annotations,
+			},
+		})
+	}
+	ops = append(ops, txn.Op{
+		C:      machinesC,
+		Id:     mdoc.DocID,
+		Assert: txn.DocMissing,
+		Insert: mdoc,
+	})
+	return ops
+}
+
+// exportStatus returns a description of the status document with the
+// given global key.
+func exportStatus(st *State, globalKey string) (StatusDescription, error) {
+	statuses, closer := st.getCollection(statusesC)
+	defer closer()
+	var doc statusDoc
+	if err := statuses.FindId(globalKey).One(&doc); err == mgo.ErrNotFound {
+		return StatusDescription{}, errors.NotFoundf("status")
+	} else if err != nil {
+		return StatusDescription{}, errors.Trace(err)
+	}
+	return StatusDescription{
+		Status:  string(doc.Status),
+		Message: doc.StatusInfo,
+		Data:    doc.StatusData,
+		Updated: doc.Updated,
+	}, nil
+}
+
+func statusDocFromDescription(st *State, d StatusDescription) statusDoc {
+	return statusDoc{
+		ModelUUID:  st.ModelUUID(),
+		Status:     status.Status(d.Status),
+		StatusInfo: d.Message,
+		StatusData: d.Data,
+		Updated:    d.Updated,
+	}
+}
+
+func addressDescription(addr address) AddressDescription {
+	return AddressDescription{
+		Value:     addr.Value,
+		Type:      addr.AddressType,
+		Scope:     addr.Scope,
+		Origin:    addr.Origin,
+		SpaceName: addr.SpaceName,
+	}
+}
+
+func addressDescriptions(addrs []address) []AddressDescription {
+	if len(addrs) == 0 {
+		return nil
+	}
+	result := make([]AddressDescription, len(addrs))
+	for i, addr := range addrs {
+		result[i] = addressDescription(addr)
+	}
+	return result
+}
+
+func addressFromDescription(d AddressDescription) address {
+	return address{
+		Value:       d.Value,
+		AddressType: d.Type,
+		Scope:       d.Scope,
+		Origin:      d.Origin,
+		SpaceName:   d.SpaceName,
+	}
+}
+
+func addressesFromDescriptions(ds []AddressDescription) []address {
+	if len(ds) == 0 {
+		return nil
+	}
+	result := make([]address, len(ds))
+	for i, d := range ds {
+		result[i] = addressFromDescription(d)
+	}
+	return result
+}
+
+// machineJobFromMigrationValue returns the job whose MigrationValue
+// is value.
+func machineJobFromMigrationValue(value string) (MachineJob, error) {
+	for job, jobValue := range jobMigrationValue {
+		if jobValue == value {
+			return job, nil
+		}
+	}
+	return 0, errors.NotValidf("machine job %q", value)
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/yaml.v2"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/status"
+)
+
+type MachineDescriptionSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&MachineDescriptionSuite{})
+
+// makeMachine returns a provisioned machine with as much of its state
+// set as possible.
+func (s *MachineDescriptionSuite) makeMachine(c *gc.C) *state.Machine {
+	m, err := s.State.AddOneMachine(state.MachineTemplate{
+		Series:       "quantal",
+		Jobs:         []state.MachineJob{state.JobHostUnits},
+		Constraints:  constraints.MustParse("mem=4G cores=2"),
+		Placement:    "zone=az1",
+		InstanceTags: map[string]string{"team": "web"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	arch := "amd64"
+	mem := uint64(4096)
+	zone := "az1"
+	err = m.SetProvisioned("i-blah", "fake-nonce", &instance.HardwareCharacteristics{
+		Arch:             &arch,
+		Mem:              &mem,
+		AvailabilityZone: &zone,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetAgentVersion(version.MustParseBinary("1.2.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetPassword("password-1234567890")
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetStatus(status.StatusInfo{
+		Status:  status.Started,
+		Message: "ready",
+		Data:    map[string]interface{}{"foo": "bar"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetProviderAddresses(network.NewScopedAddress("54.0.0.1", network.ScopePublic))
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetMachineAddresses(network.NewScopedAddress("10.0.0.1", network.ScopeCloudLocal))
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetSupportedContainers([]instance.ContainerType{instance.LXD})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetMachineBlockDevices(state.BlockDeviceInfo{DeviceName: "sda", Size: 1024})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetProvisionerMetadata(map[string]string{"apt-mirror": "http://mirror"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetAppliedInstanceTags(map[string]string{"team": "web", "kubernetes.io/role": "node"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetAnnotations(map[string]string{"owner": "fred"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	return m
+}
+
+func (s *MachineDescriptionSuite) TestRoundTrip(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(exported.Version, gc.Equals, state.MachineDescriptionVersion)
+	c.Check(exported.Instance, gc.NotNil)
+	c.Check(exported.Tools, gc.NotNil)
+
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	imported, err := other.ImportMachine(exported)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(imported.Id(), gc.Equals, m.Id())
+
+	reimported, err := other.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	reexported, err := state.ExportMachine(reimported)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(reexported, jc.DeepEquals, exported)
+
+	instId, err := reimported.InstanceId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instId, gc.Equals, instance.Id("i-blah"))
+	c.Assert(reimported.PasswordValid("password-1234567890"), jc.IsTrue)
+}
+
+func (s *MachineDescriptionSuite) TestRoundTripSerialized(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	bytes, err := yaml.Marshal(exported)
+	c.Assert(err, jc.ErrorIsNil)
+
+	var description state.MachineDescription
+	err = yaml.Unmarshal(bytes, &description)
+	c.Assert(err, jc.ErrorIsNil)
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	imported, err := other.ImportMachine(&description)
+	c.Assert(err, jc.ErrorIsNil)
+
+	reexported, err := state.ExportMachine(imported)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(reexported, jc.DeepEquals, exported)
+}
+
+func (s *MachineDescriptionSuite) TestImportUnknownFields(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	bytes, err := yaml.Marshal(exported)
+	c.Assert(err, jc.ErrorIsNil)
+	bytes = append(bytes, "quota: 42\nflavour: large\n"...)
+
+	var description state.MachineDescription
+	err = yaml.Unmarshal(bytes, &description)
+	c.Assert(err, jc.ErrorIsNil)
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	_, err = other.ImportMachine(&description)
+	c.Assert(err, gc.ErrorMatches, `cannot import machine "0": machine description with unknown fields \["flavour" "quota"\] not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+	_, err = other.Machine("0")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MachineDescriptionSuite) TestImportUnsupportedVersion(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	exported.Version = state.MachineDescriptionVersion + 1
+
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	_, err = other.ImportMachine(exported)
+	c.Assert(err, gc.ErrorMatches, `cannot import machine "0": machine description version 2 not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *MachineDescriptionSuite) TestImportExistingMachine(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.ImportMachine(exported)
+	c.Assert(err, gc.ErrorMatches, `cannot import machine "0": machine 0 already exists`)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *MachineDescriptionSuite) TestImportContainer(c *gc.C) {
+	parent := s.makeMachine(c)
+	container, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, parent.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	exportedParent, err := state.ExportMachine(parent)
+	c.Assert(err, jc.ErrorIsNil)
+	exportedContainer, err := state.ExportMachine(container)
+	c.Assert(err, jc.ErrorIsNil)
+
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	_, err = other.ImportMachine(exportedContainer)
+	c.Assert(err, gc.ErrorMatches, `cannot import machine "0/lxd/0": parent: machine 0 not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	_, err = other.ImportMachine(exportedParent)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = other.ImportMachine(exportedContainer)
+	c.Assert(err, jc.ErrorIsNil)
+
+	importedParent, err := other.Machine(parent.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	containers, err := importedParent.Containers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(containers, jc.DeepEquals, []string{"0/lxd/0"})
+
+	// New containers do not reuse the imported container's id.
+	added, err := other.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, parent.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added.Id(), gc.Equals, "0/lxd/1")
+}
+
+func (s *MachineDescriptionSuite) TestImportAdvancesMachineSequence(c *gc.C) {
+	m := s.makeMachine(c)
+	exported, err := state.ExportMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	exported.Id = "5"
+
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	_, err = other.ImportMachine(exported)
+	c.Assert(err, jc.ErrorIsNil)
+
+	added, err := other.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added.Id(), gc.Equals, "6")
+}
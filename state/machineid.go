@@ -0,0 +1,132 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"strconv"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MachineIdPolicy determines how ids are chosen for new top-level
+// machines.
+type MachineIdPolicy string
+
+const (
+	// IncreasingMachineIds gives each new machine the next id in the
+	// model's machine sequence, so that the ids of removed machines
+	// are never reused. It is the default policy.
+	IncreasingMachineIds MachineIdPolicy = "increasing"
+
+	// ReuseMachineIds gives each new machine the lowest id that has
+	// been allocated before but is not currently in use, falling back
+	// to the next id in the model's machine sequence when there are
+	// no such ids.
+	ReuseMachineIds MachineIdPolicy = "reuse"
+)
+
+// Validate returns an error if the policy is not recognised.
+func (p MachineIdPolicy) Validate() error {
+	switch p {
+	case IncreasingMachineIds, ReuseMachineIds:
+		return nil
+	}
+	return errors.NotValidf("machine id policy %q", p)
+}
+
+// SetMachineIdPolicy sets the policy used to choose the ids of
+// top-level machines added through st. It does not affect machines
+// added through other State connections.
+func (st *State) SetMachineIdPolicy(policy MachineIdPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	st.machineIdMu.Lock()
+	defer st.machineIdMu.Unlock()
+	st.machineIdPolicy = policy
+	return nil
+}
+
+// MachineIdPolicy returns the policy used to choose the ids of
+// top-level machines added through st.
+func (st *State) MachineIdPolicy() MachineIdPolicy {
+	st.machineIdMu.Lock()
+	defer st.machineIdMu.Unlock()
+	if st.machineIdPolicy == "" {
+		return IncreasingMachineIds
+	}
+	return st.machineIdPolicy
+}
+
+// newMachineId returns an id for a new top-level machine, chosen
+// according to policy, that is not in reserved. The returned id is
+// not guaranteed to remain free: the transaction that adds the
+// machine must assert that it does not exist, and be retried if it
+// does.
+func (st *State) newMachineId(policy MachineIdPolicy, reserved set.Strings) (string, error) {
+	if policy == ReuseMachineIds {
+		id, err := st.lowestFreeMachineId(reserved)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if id != "" {
+			return id, nil
+		}
+	}
+	seq, err := st.sequence("machine")
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(seq), nil
+}
+
+// lowestFreeMachineId returns the lowest top-level machine id that
+// has already been allocated from the machine sequence, but is neither
+// in use nor in reserved. It returns "" if there is no such id.
+//
+// Because every returned id is lower than the sequence's current
+// value, reusing ids never leaves the sequence behind the ids in use.
+func (st *State) lowestFreeMachineId(reserved set.Strings) (string, error) {
+	next, err := st.currentSequence("machine")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	unavailable := set.NewStrings(reserved.Values()...)
+
+	machines, closer := st.getCollection(machinesC)
+	defer closer()
+	var mdoc struct {
+		Id string `bson:"machineid"`
+	}
+	iter := machines.Find(nil).Select(bson.D{{"machineid", 1}}).Iter()
+	for iter.Next(&mdoc) {
+		unavailable.Add(mdoc.Id)
+	}
+	if err := iter.Close(); err != nil {
+		return "", errors.Annotate(err, "cannot read machine ids")
+	}
+
+	// A removed machine's id may not be reused until the cleanup of its
+	// remaining documents has run, lest the cleanup remove documents
+	// belonging to the new machine.
+	cleanups, closer := st.getCollection(cleanupsC)
+	defer closer()
+	var cdoc cleanupDoc
+	iter = cleanups.Find(bson.D{{"kind", cleanupRemovedMachine}}).Iter()
+	for iter.Next(&cdoc) {
+		unavailable.Add(cdoc.Prefix)
+	}
+	if err := iter.Close(); err != nil {
+		return "", errors.Annotate(err, "cannot read machine cleanups")
+	}
+
+	for i := 0; i < next; i++ {
+		if id := strconv.Itoa(i); !unavailable.Contains(id) {
+			return id, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/set"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type MachineIdSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&MachineIdSuite{})
+
+func (s *MachineIdSuite) addMachines(c *gc.C, st *state.State, count int) []string {
+	var ids []string
+	for i := 0; i < count; i++ {
+		m, err := st.AddMachine("quantal", state.JobHostUnits)
+		c.Assert(err, jc.ErrorIsNil)
+		ids = append(ids, m.Id())
+	}
+	return ids
+}
+
+func (s *MachineIdSuite) removeMachines(c *gc.C, ids ...string) {
+	for _, id := range ids {
+		m, err := s.State.Machine(id)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(m.EnsureDead(), jc.ErrorIsNil)
+		c.Assert(m.Remove(), jc.ErrorIsNil)
+	}
+}
+
+func (s *MachineIdSuite) TestDefaultPolicy(c *gc.C) {
+	c.Assert(s.State.MachineIdPolicy(), gc.Equals, state.IncreasingMachineIds)
+}
+
+func (s *MachineIdSuite) TestSetInvalidPolicy(c *gc.C) {
+	err := s.State.SetMachineIdPolicy("random")
+	c.Assert(err, gc.ErrorMatches, `machine id policy "random" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+	c.Assert(s.State.MachineIdPolicy(), gc.Equals, state.IncreasingMachineIds)
+}
+
+func (s *MachineIdSuite) TestIncreasingNeverReuses(c *gc.C) {
+	c.Assert(s.addMachines(c, s.State, 3), jc.DeepEquals, []string{"0", "1", "2"})
+	s.removeMachines(c, "0", "1")
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	c.Assert(s.addMachines(c, s.State, 2), jc.DeepEquals, []string{"3", "4"})
+}
+
+func (s *MachineIdSuite) TestReuseLowestFree(c *gc.C) {
+	err := s.State.SetMachineIdPolicy(state.ReuseMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.addMachines(c, s.State, 4), jc.DeepEquals, []string{"0", "1", "2", "3"})
+	s.removeMachines(c, "2", "0")
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	c.Assert(s.addMachines(c, s.State, 3), jc.DeepEquals, []string{"0", "2", "4"})
+}
+
+func (s *MachineIdSuite) TestReuseBatch(c *gc.C) {
+	err := s.State.SetMachineIdPolicy(state.ReuseMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	s.addMachines(c, s.State, 3)
+	s.removeMachines(c, "1")
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	machines, err := s.State.AddMachines(template, template)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, 2)
+	c.Check(machines[0].Id(), gc.Equals, "1")
+	c.Check(machines[1].Id(), gc.Equals, "3")
+}
+
+func (s *MachineIdSuite) TestReuseWaitsForCleanup(c *gc.C) {
+	err := s.State.SetMachineIdPolicy(state.ReuseMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	s.addMachines(c, s.State, 2)
+	s.removeMachines(c, "0")
+
+	// The removed machine's documents have not been cleaned up yet,
+	// so its id is not available.
+	c.Assert(s.addMachines(c, s.State, 1), jc.DeepEquals, []string{"2"})
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+	c.Assert(s.addMachines(c, s.State, 1), jc.DeepEquals, []string{"0"})
+}
+
+func (s *MachineIdSuite) TestReuseCollision(c *gc.C) {
+	err := s.State.SetMachineIdPolicy(state.ReuseMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	s.addMachines(c, s.State, 3)
+	s.removeMachines(c, "0", "1")
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	// Another connection takes the lowest free id while the first
+	// attempt is being built; the retry chooses the next one.
+	other, err := s.State.ForModel(s.State.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	defer other.Close()
+	err = other.SetMachineIdPolicy(state.ReuseMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	defer state.SetBeforeHooks(c, s.State, func() {
+		c.Assert(s.addMachines(c, other, 1), jc.DeepEquals, []string{"0"})
+	}).Check()
+
+	c.Assert(s.addMachines(c, s.State, 1), jc.DeepEquals, []string{"1"})
+}
+
+func (s *MachineIdSuite) TestConcurrentAddIncreasing(c *gc.C) {
+	s.assertConcurrentAdd(c, state.IncreasingMachineIds)
+}
+
+func (s *MachineIdSuite) TestConcurrentAddReuse(c *gc.C) {
+	s.assertConcurrentAdd(c, state.ReuseMachineIds)
+}
+
+// assertConcurrentAdd adds machines concurrently through several State
+// connections using the given policy, some of which have gaps in their
+// ids to fill, and checks that every machine gets a distinct id.
+func (s *MachineIdSuite) assertConcurrentAdd(c *gc.C, policy state.MachineIdPolicy) {
+	s.addMachines(c, s.State, 10)
+	s.removeMachines(c, "1", "3", "5", "7")
+	c.Assert(s.State.Cleanup(), jc.ErrorIsNil)
+
+	const connections = 4
+	const iterations = 10
+	var states []*state.State
+	for i := 0; i < connections; i++ {
+		st, err := s.State.ForModel(s.State.ModelTag())
+		c.Assert(err, jc.ErrorIsNil)
+		defer st.Close()
+		err = st.SetMachineIdPolicy(policy)
+		c.Assert(err, jc.ErrorIsNil)
+		states = append(states, st)
+	}
+
+	ids := make(chan string, connections*iterations)
+	errs := make(chan error, connections*iterations)
+	var wg sync.WaitGroup
+	for _, st := range states {
+		wg.Add(1)
+		go func(st *state.State) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				m, err := st.AddMachine("quantal", state.JobHostUnits)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				ids <- m.Id()
+			}
+		}(st)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("concurrent adds did not complete")
+	}
+	close(ids)
+	close(errs)
+	for err := range errs {
+		c.Check(err, jc.ErrorIsNil)
+	}
+
+	seen := set.NewStrings()
+	for id := range ids {
+		c.Check(seen.Contains(id), jc.IsFalse, gc.Commentf("duplicate id %s", id))
+		seen.Add(id)
+	}
+	c.Assert(seen.Size(), gc.Equals, connections*iterations)
+	machines, err := s.State.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, 6+connections*iterations)
+
+	// The sequence must still be ahead of every id in use, so that
+	// adding a machine with the default policy succeeds.
+	err = s.State.SetMachineIdPolicy(state.IncreasingMachineIds)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
@@ -0,0 +1,376 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// NetworkConfig describes a network interface, and its addresses and
+// routes, as observed by the agent of the machine it is on.
+type NetworkConfig struct {
+	// InterfaceName is the name of the interface on the machine, such
+	// as "eth0". It identifies the interface within the machine's
+	// config, so it must be unique.
+	InterfaceName string
+
+	// ParentInterfaceName is the name of the interface's parent, such
+	// as the bridge it is a port of, if any.
+	ParentInterfaceName string
+
+	// InterfaceType is the type of the interface, such as "ethernet"
+	// or "bridge".
+	InterfaceType string
+
+	// MACAddress is the interface's hardware address, if it has one.
+	MACAddress string
+
+	// MTU is the interface's maximum transmission unit.
+	MTU uint
+
+	// Disabled records whether the interface is down.
+	Disabled bool
+
+	// Addresses holds the IP addresses of the interface, in CIDR
+	// notation (such as "10.0.0.4/24") when the prefix length is
+	// known. The order of the addresses is not significant.
+	Addresses []string
+
+	// DNSServers holds the addresses of the DNS servers used by the
+	// interface, in order of preference.
+	DNSServers []string
+
+	// Routes holds the routes through the interface. The order of
+	// the routes is not significant.
+	Routes []NetworkRoute
+}
+
+// NetworkRoute describes a route through a network interface.
+type NetworkRoute struct {
+	// DestinationCIDR is the network reached by the route, such as
+	// "0.0.0.0/0" for a default route.
+	DestinationCIDR string
+
+	// GatewayIP is the address of the gateway for the route.
+	GatewayIP string
+
+	// Metric is the route's priority; lower metrics are preferred.
+	Metric int
+}
+
+// networkConfigDoc holds the observed config of one interface of a
+// machine. Each interface is kept in its own document, rather than in
+// the machine document, so that a change to one interface leaves the
+// others, and the machine, alone, and so that machines with very many
+// addresses do not inflate the machine document.
+type networkConfigDoc struct {
+	DocID               string            `bson:"_id"`
+	ModelUUID           string            `bson:"model-uuid"`
+	MachineID           string            `bson:"machine-id"`
+	InterfaceName       string            `bson:"interface-name"`
+	ParentInterfaceName string            `bson:"parent-interface-name,omitempty"`
+	InterfaceType       string            `bson:"interface-type,omitempty"`
+	MACAddress          string            `bson:"mac-address,omitempty"`
+	MTU                 uint              `bson:"mtu,omitempty"`
+	Disabled            bool              `bson:"disabled,omitempty"`
+	Addresses           []string          `bson:"addresses,omitempty"`
+	DNSServers          []string          `bson:"dns-servers,omitempty"`
+	Routes              []networkRouteDoc `bson:"routes,omitempty"`
+}
+
+type networkRouteDoc struct {
+	DestinationCIDR string `bson:"destination-cidr"`
+	GatewayIP       string `bson:"gateway-ip"`
+	Metric          int    `bson:"metric"`
+}
+
+// networkConfigDocIDPrefix returns the prefix of the local ids of the
+// machine's observed network config documents.
+func (m *Machine) networkConfigDocIDPrefix() string {
+	return m.globalKey() + "#netconfig#"
+}
+
+func (m *Machine) networkConfigDocID(interfaceName string) string {
+	return m.st.docID(m.networkConfigDocIDPrefix() + interfaceName)
+}
+
+// ObservedNetworkConfig returns the network config most recently
+// recorded by SetObservedNetworkConfig, ordered by interface name.
+func (m *Machine) ObservedNetworkConfig() ([]NetworkConfig, error) {
+	docs, err := m.networkConfigDocs()
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get observed network config for machine %v", m)
+	}
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]NetworkConfig, len(names))
+	for i, name := range names {
+		result[i] = docs[name].networkConfig()
+	}
+	return result, nil
+}
+
+// SetObservedNetworkConfig records the network config observed on the
+// machine, replacing any previously recorded. Only the interfaces whose
+// config has changed are written, so reporting an unchanged config
+// does not touch the database, and neither the order of the interfaces
+// nor that of their addresses and routes is significant.
+func (m *Machine) SetObservedNetworkConfig(config []NetworkConfig) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set observed network config for machine %v", m)
+	wanted := make(map[string]networkConfigDoc, len(config))
+	for _, c := range config {
+		if err := c.validate(); err != nil {
+			return errors.Trace(err)
+		}
+		if _, ok := wanted[c.InterfaceName]; ok {
+			return errors.NotValidf("duplicate interface %q", c.InterfaceName)
+		}
+		wanted[c.InterfaceName] = m.newNetworkConfigDoc(c)
+	}
+
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.Life == Dead {
+			return nil, ErrDead
+		}
+		existing, err := m.networkConfigDocs()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ops := networkConfigDiffOps(existing, wanted)
+		if len(ops) == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+		return append([]txn.Op{{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: notDeadDoc,
+		}}, ops...), nil
+	}
+	return m.st.runNamed("SetObservedNetworkConfig", buildTxn)
+}
+
+// networkConfigDiffOps returns the operations needed to change the
+// existing documents, keyed by interface name, into the wanted ones.
+func networkConfigDiffOps(existing, wanted map[string]networkConfigDoc) []txn.Op {
+	var ops []txn.Op
+	for name, doc := range existing {
+		if _, ok := wanted[name]; !ok {
+			ops = append(ops, txn.Op{
+				C:      machineNetworkConfigC,
+				Id:     doc.DocID,
+				Assert: txn.DocExists,
+				Remove: true,
+			})
+		}
+	}
+	for name, doc := range wanted {
+		old, ok := existing[name]
+		if !ok {
+			doc := doc
+			ops = append(ops, txn.Op{
+				C:      machineNetworkConfigC,
+				Id:     doc.DocID,
+				Assert: txn.DocMissing,
+				Insert: &doc,
+			})
+			continue
+		}
+		// The model uuid is filled in by the transaction runner, so
+		// it cannot differ.
+		doc.ModelUUID = old.ModelUUID
+		if reflect.DeepEqual(old, doc) {
+			continue
+		}
+		ops = append(ops, txn.Op{
+			C:      machineNetworkConfigC,
+			Id:     doc.DocID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{
+				{"parent-interface-name", doc.ParentInterfaceName},
+				{"interface-type", doc.InterfaceType},
+				{"mac-address", doc.MACAddress},
+				{"mtu", doc.MTU},
+				{"disabled", doc.Disabled},
+				{"addresses", doc.Addresses},
+				{"dns-servers", doc.DNSServers},
+				{"routes", doc.Routes},
+			}}},
+		})
+	}
+	return ops
+}
+
+// networkConfigDocs returns the machine's observed network config
+// documents, keyed by interface name.
+func (m *Machine) networkConfigDocs() (map[string]networkConfigDoc, error) {
+	coll, closer := m.st.getCollection(machineNetworkConfigC)
+	defer closer()
+
+	var docs []networkConfigDoc
+	if err := coll.Find(bson.D{{"machine-id", m.doc.Id}}).All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make(map[string]networkConfigDoc, len(docs))
+	for _, doc := range docs {
+		result[doc.InterfaceName] = normaliseNetworkConfigDoc(doc)
+	}
+	return result, nil
+}
+
+// removeObservedNetworkConfigOps returns the operations needed to
+// remove the machine's observed network config.
+func (m *Machine) removeObservedNetworkConfigOps() ([]txn.Op, error) {
+	docs, err := m.networkConfigDocs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ops := make([]txn.Op, 0, len(docs))
+	for _, doc := range docs {
+		ops = append(ops, txn.Op{
+			C:      machineNetworkConfigC,
+			Id:     doc.DocID,
+			Remove: true,
+		})
+	}
+	return ops, nil
+}
+
+// WatchObservedNetworkConfig returns a NotifyWatcher that notifies of
+// changes to the machine's observed network config.
+func (m *Machine) WatchObservedNetworkConfig() NotifyWatcher {
+	prefix := m.networkConfigDocIDPrefix()
+	filter := func(key interface{}) bool {
+		if id, ok := key.(string); ok {
+			if id, err := m.st.strictLocalID(id); err == nil {
+				return strings.HasPrefix(id, prefix)
+			}
+		}
+		return false
+	}
+	return newNotifyCollWatcher(m.st, machineNetworkConfigC, filter)
+}
+
+func (c NetworkConfig) validate() error {
+	if c.InterfaceName == "" {
+		return errors.NotValidf("empty interface name")
+	}
+	for _, addr := range c.Addresses {
+		if !isIPOrCIDR(addr) {
+			return errors.NotValidf("interface %q address %q", c.InterfaceName, addr)
+		}
+	}
+	for _, route := range c.Routes {
+		if _, _, err := net.ParseCIDR(route.DestinationCIDR); err != nil {
+			return errors.NotValidf("interface %q route destination %q", c.InterfaceName, route.DestinationCIDR)
+		}
+		if net.ParseIP(route.GatewayIP) == nil {
+			return errors.NotValidf("interface %q route gateway %q", c.InterfaceName, route.GatewayIP)
+		}
+	}
+	return nil
+}
+
+func isIPOrCIDR(addr string) bool {
+	if net.ParseIP(addr) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(addr)
+	return err == nil
+}
+
+func (m *Machine) newNetworkConfigDoc(c NetworkConfig) networkConfigDoc {
+	doc := networkConfigDoc{
+		DocID:               m.networkConfigDocID(c.InterfaceName),
+		MachineID:           m.doc.Id,
+		InterfaceName:       c.InterfaceName,
+		ParentInterfaceName: c.ParentInterfaceName,
+		InterfaceType:       c.InterfaceType,
+		MACAddress:          c.MACAddress,
+		MTU:                 c.MTU,
+		Disabled:            c.Disabled,
+		Addresses:           c.Addresses,
+		DNSServers:          c.DNSServers,
+	}
+	for _, route := range c.Routes {
+		doc.Routes = append(doc.Routes, networkRouteDoc{
+			DestinationCIDR: route.DestinationCIDR,
+			GatewayIP:       route.GatewayIP,
+			Metric:          route.Metric,
+		})
+	}
+	return normaliseNetworkConfigDoc(doc)
+}
+
+// normaliseNetworkConfigDoc returns a copy of doc with its addresses
+// and routes sorted, and empty slices replaced by nil, so that
+// documents with equivalent config compare equal.
+func normaliseNetworkConfigDoc(doc networkConfigDoc) networkConfigDoc {
+	if len(doc.Addresses) == 0 {
+		doc.Addresses = nil
+	} else {
+		doc.Addresses = append([]string(nil), doc.Addresses...)
+		sort.Strings(doc.Addresses)
+	}
+	if len(doc.DNSServers) == 0 {
+		doc.DNSServers = nil
+	}
+	if len(doc.Routes) == 0 {
+		doc.Routes = nil
+	} else {
+		doc.Routes = append([]networkRouteDoc(nil), doc.Routes...)
+		sort.Sort(networkRouteDocs(doc.Routes))
+	}
+	return doc
+}
+
+type networkRouteDocs []networkRouteDoc
+
+func (r networkRouteDocs) Len() int      { return len(r) }
+func (r networkRouteDocs) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r networkRouteDocs) Less(i, j int) bool {
+	if r[i].DestinationCIDR != r[j].DestinationCIDR {
+		return r[i].DestinationCIDR < r[j].DestinationCIDR
+	}
+	if r[i].GatewayIP != r[j].GatewayIP {
+		return r[i].GatewayIP < r[j].GatewayIP
+	}
+	return r[i].Metric < r[j].Metric
+}
+
+func (doc networkConfigDoc) networkConfig() NetworkConfig {
+	c := NetworkConfig{
+		InterfaceName:       doc.InterfaceName,
+		ParentInterfaceName: doc.ParentInterfaceName,
+		InterfaceType:       doc.InterfaceType,
+		MACAddress:          doc.MACAddress,
+		MTU:                 doc.MTU,
+		Disabled:            doc.Disabled,
+		Addresses:           doc.Addresses,
+		DNSServers:          doc.DNSServers,
+	}
+	for _, route := range doc.Routes {
+		c.Routes = append(c.Routes, NetworkRoute{
+			DestinationCIDR: route.DestinationCIDR,
+			GatewayIP:       route.GatewayIP,
+			Metric:          route.Metric,
+		})
+	}
+	return c
+}
@@ -0,0 +1,247 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type MachineNetworkConfigSuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&MachineNetworkConfigSuite{})
+
+func (s *MachineNetworkConfigSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MachineNetworkConfigSuite) txnRevno(c *gc.C, interfaceName string) int64 {
+	revno, err := state.TxnRevno(s.State, "machineNetworkConfig", "m#0#netconfig#"+interfaceName)
+	c.Assert(err, jc.ErrorIsNil)
+	return revno
+}
+
+func (s *MachineNetworkConfigSuite) txnCount(c *gc.C) int {
+	count, err := s.State.MongoSession().DB("juju").C("txns").Count()
+	c.Assert(err, jc.ErrorIsNil)
+	return count
+}
+
+func (s *MachineNetworkConfigSuite) assertObserved(c *gc.C, expected []state.NetworkConfig) {
+	config, err := s.machine.ObservedNetworkConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config, jc.DeepEquals, expected)
+}
+
+var testNetworkConfig = []state.NetworkConfig{{
+	InterfaceName: "br-eth0",
+	InterfaceType: "bridge",
+	MACAddress:    "aa:bb:cc:dd:ee:f0",
+	MTU:           1500,
+	Addresses:     []string{"10.0.0.4/24", "10.0.0.10/24"},
+	DNSServers:    []string{"10.0.0.2", "10.0.0.1"},
+	Routes: []state.NetworkRoute{{
+		DestinationCIDR: "192.168.0.0/16",
+		GatewayIP:       "10.0.0.254",
+		Metric:          100,
+	}, {
+		DestinationCIDR: "0.0.0.0/0",
+		GatewayIP:       "10.0.0.1",
+	}},
+}, {
+	InterfaceName:       "eth0",
+	ParentInterfaceName: "br-eth0",
+	InterfaceType:       "ethernet",
+	MACAddress:          "aa:bb:cc:dd:ee:f0",
+	MTU:                 1500,
+}, {
+	InterfaceName: "eth1",
+	InterfaceType: "ethernet",
+	Disabled:      true,
+}}
+
+func (s *MachineNetworkConfigSuite) TestObservedNetworkConfigEmpty(c *gc.C) {
+	s.assertObserved(c, []state.NetworkConfig{})
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfig(c *gc.C) {
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+
+	expected := append([]state.NetworkConfig(nil), testNetworkConfig...)
+	expected[0].Addresses = []string{"10.0.0.10/24", "10.0.0.4/24"}
+	expected[0].Routes = []state.NetworkRoute{
+		testNetworkConfig[0].Routes[1],
+		testNetworkConfig[0].Routes[0],
+	}
+	s.assertObserved(c, expected)
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigUnchanged(c *gc.C) {
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	before := s.txnCount(c)
+
+	// Neither the order of the interfaces nor of their addresses
+	// and routes matters.
+	reordered := []state.NetworkConfig{testNetworkConfig[2], testNetworkConfig[1], testNetworkConfig[0]}
+	reordered[2].Addresses = []string{"10.0.0.10/24", "10.0.0.4/24"}
+	reordered[2].Routes = []state.NetworkRoute{
+		testNetworkConfig[0].Routes[1],
+		testNetworkConfig[0].Routes[0],
+	}
+	err = s.machine.SetObservedNetworkConfig(reordered)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.txnCount(c), gc.Equals, before)
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigOnlyWritesChanges(c *gc.C) {
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	bridgeRevno := s.txnRevno(c, "br-eth0")
+	eth1Revno := s.txnRevno(c, "eth1")
+
+	changed := append([]state.NetworkConfig(nil), testNetworkConfig...)
+	changed[2].Disabled = false
+	changed[2].Addresses = []string{"192.168.1.5/24"}
+	err = s.machine.SetObservedNetworkConfig(changed)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(s.txnRevno(c, "br-eth0"), gc.Equals, bridgeRevno)
+	c.Assert(s.txnRevno(c, "eth1"), gc.Not(gc.Equals), eth1Revno)
+	config, err := s.machine.ObservedNetworkConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config[2], jc.DeepEquals, changed[2])
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigRemovesInterfaces(c *gc.C) {
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.machine.SetObservedNetworkConfig(testNetworkConfig[1:2])
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertObserved(c, testNetworkConfig[1:2])
+
+	err = s.machine.SetObservedNetworkConfig(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertObserved(c, []state.NetworkConfig{})
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigManyAddresses(c *gc.C) {
+	addresses := make([]string, 500)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("10.%d.%d.1/24", i/256, i%256)
+	}
+	config := []state.NetworkConfig{{
+		InterfaceName: "eth0",
+		Addresses:     addresses,
+	}}
+	err := s.machine.SetObservedNetworkConfig(config)
+	c.Assert(err, jc.ErrorIsNil)
+
+	observed, err := s.machine.ObservedNetworkConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, gc.HasLen, 1)
+	c.Assert(observed[0].Addresses, jc.SameContents, addresses)
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigInvalid(c *gc.C) {
+	for i, test := range []struct {
+		config []state.NetworkConfig
+		err    string
+	}{{
+		config: []state.NetworkConfig{{}},
+		err:    `empty interface name not valid`,
+	}, {
+		config: []state.NetworkConfig{{InterfaceName: "eth0"}, {InterfaceName: "eth0"}},
+		err:    `duplicate interface "eth0" not valid`,
+	}, {
+		config: []state.NetworkConfig{{InterfaceName: "eth0", Addresses: []string{"10.0.0.300"}}},
+		err:    `interface "eth0" address "10.0.0.300" not valid`,
+	}, {
+		config: []state.NetworkConfig{{
+			InterfaceName: "eth0",
+			Routes:        []state.NetworkRoute{{DestinationCIDR: "default", GatewayIP: "10.0.0.1"}},
+		}},
+		err: `interface "eth0" route destination "default" not valid`,
+	}, {
+		config: []state.NetworkConfig{{
+			InterfaceName: "eth0",
+			Routes:        []state.NetworkRoute{{DestinationCIDR: "0.0.0.0/0"}},
+		}},
+		err: `interface "eth0" route gateway "" not valid`,
+	}} {
+		c.Logf("test %d", i)
+		err := s.machine.SetObservedNetworkConfig(test.config)
+		c.Check(err, gc.ErrorMatches, "cannot set observed network config for machine 0: "+test.err)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+	s.assertObserved(c, []state.NetworkConfig{})
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigDead(c *gc.C) {
+	c.Assert(s.machine.EnsureDead(), jc.ErrorIsNil)
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, gc.ErrorMatches, "cannot set observed network config for machine 0: not found or dead")
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrDead)
+}
+
+func (s *MachineNetworkConfigSuite) TestSetObservedNetworkConfigDeadAfterLoad(c *gc.C) {
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.EnsureDead(), jc.ErrorIsNil)
+	err = s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrDead)
+}
+
+func (s *MachineNetworkConfigSuite) TestRemoveMachineRemovesNetworkConfig(c *gc.C) {
+	err := s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(s.machine.Remove(), jc.ErrorIsNil)
+	s.assertObserved(c, []state.NetworkConfig{})
+}
+
+func (s *MachineNetworkConfigSuite) TestWatchObservedNetworkConfig(c *gc.C) {
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.machine.WatchObservedNetworkConfig()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange() // Initial event.
+
+	err = s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Reporting the same config does not trigger an event.
+	err = s.machine.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Nor does a change to another machine's config.
+	err = other.SetObservedNetworkConfig(testNetworkConfig)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	err = s.machine.SetObservedNetworkConfig(testNetworkConfig[:1])
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
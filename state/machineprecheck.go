@@ -0,0 +1,142 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/instance"
+)
+
+// MachinePrechecker may veto operations on machines. Unlike the
+// Prechecker supplied by a Policy, which checks that an instance
+// could be created by the provider, a MachinePrechecker expresses the
+// wishes of the operator of the environment, for example that
+// protected machines must not be destroyed. See
+// State.SetMachinePrechecker.
+type MachinePrechecker interface {
+	// PrecheckMachine is called synchronously before the
+	// transaction that makes the given change to the machine is
+	// run. If it returns an error, the operation is abandoned and
+	// the error is returned to the caller. It may be called more
+	// than once for an operation, if the operation's transaction
+	// is retried.
+	PrecheckMachine(machine MachineView, change MachineChange) error
+}
+
+// MachineOperation identifies an operation checked by a
+// MachinePrechecker.
+type MachineOperation string
+
+const (
+	// MachineAdd is the addition of a new machine, by AddMachines
+	// and the other methods that add machines.
+	MachineAdd MachineOperation = "add"
+
+	// MachineSetProvisioned is the recording of a machine's
+	// instance, by Machine.SetProvisioned.
+	MachineSetProvisioned MachineOperation = "set-provisioned"
+
+	// MachineDestroy is the advancement of a machine's life to
+	// Dying, by Machine.Destroy.
+	MachineDestroy MachineOperation = "destroy"
+
+	// MachineForceDestroy is the queueing of a machine for forced
+	// removal, by Machine.ForceDestroy.
+	MachineForceDestroy MachineOperation = "force-destroy"
+
+	// MachineEnsureDead is the advancement of a machine's life to
+	// Dead, by Machine.EnsureDead.
+	MachineEnsureDead MachineOperation = "ensure-dead"
+)
+
+// MachineChange describes the change that an operation intends to make
+// to a machine.
+type MachineChange struct {
+	// Operation identifies the operation.
+	Operation MachineOperation
+
+	// InstanceId and Nonce hold the instance id and nonce being
+	// recorded by MachineSetProvisioned.
+	InstanceId instance.Id
+	Nonce      string
+}
+
+// MachineView is a read-only view of a machine, as passed to a
+// MachinePrechecker. For MachineAdd, it describes the machine about to
+// be added.
+type MachineView struct {
+	doc machineDoc
+}
+
+// Id returns the machine's id.
+func (v MachineView) Id() string {
+	return v.doc.Id
+}
+
+// Series returns the machine's series.
+func (v MachineView) Series() string {
+	return v.doc.Series
+}
+
+// Life returns the machine's life.
+func (v MachineView) Life() Life {
+	return v.doc.Life
+}
+
+// Jobs returns the machine's jobs.
+func (v MachineView) Jobs() []MachineJob {
+	return append([]MachineJob(nil), v.doc.Jobs...)
+}
+
+// Principals returns the names of the principal units assigned to the
+// machine.
+func (v MachineView) Principals() []string {
+	return append([]string(nil), v.doc.Principals...)
+}
+
+// Nonce returns the machine's provisioning nonce, which is empty if
+// the machine has not been provisioned.
+func (v MachineView) Nonce() string {
+	return v.doc.Nonce
+}
+
+// Placement returns the placement directive used to add the machine.
+func (v MachineView) Placement() string {
+	return v.doc.Placement
+}
+
+// InstanceTags returns the user-defined tags to apply to the machine's
+// instance.
+func (v MachineView) InstanceTags() map[string]string {
+	return copyStringMap(v.doc.InstanceTags)
+}
+
+// IsContainer reports whether the machine is a container.
+func (v MachineView) IsContainer() bool {
+	return ParentId(v.doc.Id) != ""
+}
+
+// SetMachinePrechecker sets the prechecker consulted before machines
+// are added, provisioned and destroyed. A nil prechecker, the default,
+// allows every operation.
+//
+// The prechecker is not inherited by States returned from ForModel.
+func (st *State) SetMachinePrechecker(prechecker MachinePrechecker) {
+	st.machinePrecheckerMu.Lock()
+	defer st.machinePrecheckerMu.Unlock()
+	st.machinePrechecker = prechecker
+}
+
+// precheckMachine consults st's MachinePrechecker, if any, about the
+// given change to the machine with the given document.
+func (st *State) precheckMachine(doc *machineDoc, change MachineChange) error {
+	st.machinePrecheckerMu.Lock()
+	prechecker := st.machinePrechecker
+	st.machinePrecheckerMu.Unlock()
+	if prechecker == nil {
+		return nil
+	}
+	return errors.Trace(prechecker.PrecheckMachine(MachineView{doc: *doc}, change))
+}
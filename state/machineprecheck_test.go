@@ -0,0 +1,187 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type MachinePrecheckerSuite struct {
+	ConnSuite
+	machine    *state.Machine
+	prechecker *statetesting.VetoingMachinePrechecker
+}
+
+var _ = gc.Suite(&MachinePrecheckerSuite{})
+
+func (s *MachinePrecheckerSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.prechecker = statetesting.NewVetoingMachinePrechecker()
+	s.State.SetMachinePrechecker(s.prechecker)
+}
+
+func (s *MachinePrecheckerSuite) veto(id string) {
+	s.prechecker.Vetoed.Add(id)
+}
+
+func (s *MachinePrecheckerSuite) assertChecks(c *gc.C, expected ...statetesting.MachineCheck) {
+	c.Assert(s.prechecker.Checks(), jc.DeepEquals, expected)
+}
+
+func (s *MachinePrecheckerSuite) TestAddMachineAllowed(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertChecks(c, machineCheck(m.Id(), state.MachineAdd))
+}
+
+func (s *MachinePrecheckerSuite) TestAddMachineVetoed(c *gc.C) {
+	s.veto("1")
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, gc.ErrorMatches, "cannot add a new machine: add of machine 1 vetoed")
+	_, err = s.State.Machine("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	s.assertChecks(c, machineCheck("1", state.MachineAdd))
+}
+
+func (s *MachinePrecheckerSuite) TestAddContainerVetoed(c *gc.C) {
+	s.veto("0/lxd/0")
+	_, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, s.machine.Id(), instance.LXD)
+	c.Assert(err, gc.ErrorMatches, "cannot add a new machine: add of machine 0/lxd/0 vetoed")
+	containers, err := s.machine.Containers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(containers, gc.HasLen, 0)
+	s.assertChecks(c, machineCheck("0/lxd/0", state.MachineAdd))
+}
+
+func (s *MachinePrecheckerSuite) TestAddMachineInsideNewMachineVetoed(c *gc.C) {
+	s.veto("1")
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	_, err := s.State.AddMachineInsideNewMachine(template, template, instance.LXD)
+	c.Assert(err, gc.ErrorMatches, "cannot add a new machine: add of machine 1 vetoed")
+	_, err = s.State.Machine("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	s.assertChecks(c, machineCheck("1", state.MachineAdd))
+}
+
+func (s *MachinePrecheckerSuite) TestSetProvisioned(c *gc.C) {
+	s.veto("0")
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, gc.ErrorMatches, `cannot set instance data for machine "0": set-provisioned of machine 0 vetoed`)
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.machine.InstanceId()
+	c.Assert(err, jc.Satisfies, errors.IsNotProvisioned)
+
+	s.prechecker.Vetoed.Remove("0")
+	err = s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertChecks(c,
+		machineCheck("0", state.MachineSetProvisioned),
+		machineCheck("0", state.MachineSetProvisioned),
+	)
+}
+
+func (s *MachinePrecheckerSuite) TestChangeSetProvisioned(c *gc.C) {
+	var changes []state.MachineChange
+	s.State.SetMachinePrechecker(machinePrecheckerFunc(func(_ state.MachineView, change state.MachineChange) error {
+		changes = append(changes, change)
+		return nil
+	}))
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(changes, jc.DeepEquals, []state.MachineChange{{
+		Operation:  state.MachineSetProvisioned,
+		InstanceId: "i-123",
+		Nonce:      "fake_nonce",
+	}})
+}
+
+func (s *MachinePrecheckerSuite) TestDestroyPathsVetoed(c *gc.C) {
+	s.veto("0")
+	for i, test := range []struct {
+		operation state.MachineOperation
+		destroy   func() error
+	}{
+		{state.MachineDestroy, s.machine.Destroy},
+		{state.MachineEnsureDead, s.machine.EnsureDead},
+		{state.MachineForceDestroy, s.machine.ForceDestroy},
+	} {
+		c.Logf("test %d: %s", i, test.operation)
+		s.prechecker.Reset()
+		err := test.destroy()
+		c.Check(err, gc.ErrorMatches, string(test.operation)+" of machine 0 vetoed")
+		s.assertChecks(c, machineCheck("0", test.operation))
+	}
+	err := s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.Life(), gc.Equals, state.Alive)
+}
+
+func (s *MachinePrecheckerSuite) TestDestroyPathsAllowed(c *gc.C) {
+	err := s.machine.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertChecks(c,
+		machineCheck("0", state.MachineDestroy),
+		machineCheck("0", state.MachineEnsureDead),
+	)
+
+	// Nothing is checked when there is nothing to do.
+	s.prechecker.Reset()
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertChecks(c)
+}
+
+func (s *MachinePrecheckerSuite) TestViewCannotMutateMachine(c *gc.C) {
+	s.State.SetMachinePrechecker(machinePrecheckerFunc(func(view state.MachineView, _ state.MachineChange) error {
+		jobs := view.Jobs()
+		jobs[0] = state.JobManageModel
+		tags := view.InstanceTags()
+		if tags != nil {
+			tags["protected"] = "true"
+		}
+		return nil
+	}))
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.Jobs(), jc.DeepEquals, []state.MachineJob{state.JobHostUnits})
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.Jobs(), jc.DeepEquals, []state.MachineJob{state.JobHostUnits})
+}
+
+func (s *MachinePrecheckerSuite) TestUnset(c *gc.C) {
+	s.veto("0")
+	s.State.SetMachinePrechecker(nil)
+	err := s.machine.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.prechecker.Checks(), gc.HasLen, 0)
+}
+
+func machineCheck(id string, operation state.MachineOperation) statetesting.MachineCheck {
+	return statetesting.MachineCheck{MachineId: id, Operation: operation}
+}
+
+type machinePrecheckerFunc func(state.MachineView, state.MachineChange) error
+
+func (f machinePrecheckerFunc) PrecheckMachine(view state.MachineView, change state.MachineChange) error {
+	return f(view, change)
+}
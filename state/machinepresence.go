@@ -0,0 +1,232 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// AgentPresenceMode names a way of judging whether a machine's agent
+// is alive.
+type AgentPresenceMode string
+
+const (
+	// PresenceStandard judges the agent by the presence watcher
+	// alone. It is the mode of machines with no policy set.
+	PresenceStandard AgentPresenceMode = "standard"
+
+	// PresenceExtendedTimeout judges the agent alive for a number of
+	// presence periods after it was last seen alive, so that a
+	// machine behind a flaky link is not reported down each time a
+	// ping is missed.
+	PresenceExtendedTimeout AgentPresenceMode = "extended-timeout"
+
+	// PresenceUnmonitored does not judge the agent at all, for
+	// machines, such as manually provisioned ones that are often
+	// offline, whose agents are not expected to stay connected.
+	PresenceUnmonitored AgentPresenceMode = "unmonitored"
+)
+
+// agentPresencePeriod is the length of the presence watcher's time
+// slots, by which an extended timeout is measured.
+const agentPresencePeriod = 30 * time.Second
+
+// AgentPresencePolicy determines how the presence of a machine's agent
+// is judged by AgentPresence, AgentPresenceState and
+// WaitAgentPresence.
+type AgentPresencePolicy struct {
+	// Mode holds the way in which the agent is judged.
+	Mode AgentPresenceMode
+
+	// Periods holds the number of presence periods for which an agent
+	// with the PresenceExtendedTimeout mode is judged alive after it
+	// was last seen alive. It must be at least 2 for that mode, and
+	// zero otherwise.
+	Periods int
+}
+
+var extendedTimeoutPattern = regexp.MustCompile(`^` + string(PresenceExtendedTimeout) + `\((\d+)\)$`)
+
+// ParseAgentPresencePolicy returns the policy described by s, which is
+// in the form returned by AgentPresencePolicy.String: "standard",
+// "extended-timeout(<periods>)" or "unmonitored".
+func ParseAgentPresencePolicy(s string) (AgentPresencePolicy, error) {
+	var policy AgentPresencePolicy
+	switch s {
+	case string(PresenceStandard), "":
+		policy.Mode = PresenceStandard
+	case string(PresenceUnmonitored):
+		policy.Mode = PresenceUnmonitored
+	default:
+		match := extendedTimeoutPattern.FindStringSubmatch(s)
+		if match == nil {
+			return AgentPresencePolicy{}, errors.NotValidf("agent presence policy %q", s)
+		}
+		periods, err := strconv.Atoi(match[1])
+		if err != nil {
+			return AgentPresencePolicy{}, errors.NotValidf("agent presence policy %q", s)
+		}
+		policy = AgentPresencePolicy{Mode: PresenceExtendedTimeout, Periods: periods}
+	}
+	if err := policy.Validate(); err != nil {
+		return AgentPresencePolicy{}, errors.Trace(err)
+	}
+	return policy, nil
+}
+
+// String returns the policy in the form read by
+// ParseAgentPresencePolicy.
+func (p AgentPresencePolicy) String() string {
+	if p.Mode == PresenceExtendedTimeout {
+		return fmt.Sprintf("%s(%d)", p.Mode, p.Periods)
+	}
+	return string(p.Mode)
+}
+
+// Validate returns an error if the policy is not valid.
+func (p AgentPresencePolicy) Validate() error {
+	switch p.Mode {
+	case PresenceStandard, PresenceUnmonitored:
+		if p.Periods != 0 {
+			return errors.NotValidf("%d periods for %s agent presence", p.Periods, p.Mode)
+		}
+	case PresenceExtendedTimeout:
+		if p.Periods < 2 {
+			return errors.NotValidf("extended timeout of %d periods", p.Periods)
+		}
+	default:
+		return errors.NotValidf("agent presence mode %q", p.Mode)
+	}
+	return nil
+}
+
+// timeoutMultiplier returns the factor by which the policy extends the
+// time for which an agent may go unseen.
+func (p AgentPresencePolicy) timeoutMultiplier() int {
+	if p.Mode == PresenceExtendedTimeout {
+		return p.Periods
+	}
+	return 1
+}
+
+// AgentPresenceState describes the presence of a machine's agent, as
+// judged according to the machine's AgentPresencePolicy.
+type AgentPresenceState string
+
+const (
+	// AgentPresenceAlive is the state of an agent judged alive.
+	AgentPresenceAlive AgentPresenceState = "alive"
+
+	// AgentPresenceDown is the state of an agent judged not alive.
+	AgentPresenceDown AgentPresenceState = "down"
+
+	// AgentPresenceUnmonitored is the state of the agent of a machine
+	// with the PresenceUnmonitored policy, which is never judged.
+	AgentPresenceUnmonitored AgentPresenceState = "unmonitored"
+)
+
+// AgentPresencePolicy returns the policy by which the presence of m's
+// agent is judged, as of m's last refresh.
+func (m *Machine) AgentPresencePolicy() AgentPresencePolicy {
+	policy, err := ParseAgentPresencePolicy(m.doc.PresencePolicy)
+	if err != nil {
+		logger.Warningf("machine %v has invalid agent presence policy %q; using standard", m, m.doc.PresencePolicy)
+		return AgentPresencePolicy{Mode: PresenceStandard}
+	}
+	return policy
+}
+
+// SetAgentPresencePolicy sets the policy by which the presence of m's
+// agent is judged.
+func (m *Machine) SetAgentPresencePolicy(policy AgentPresencePolicy) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set agent presence policy of machine %v", m)
+	if err := policy.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	value := policy.String()
+	if policy.Mode == PresenceStandard {
+		// Machines without a policy use the standard one, so the
+		// field is cleared rather than set.
+		value = ""
+	}
+	ops := []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.DocID,
+		Assert: notDeadDoc,
+		Update: bson.D{{"$set", bson.D{{"presencepolicy", value}}}},
+	}}
+	if err := m.st.runTransaction(ops); err != nil {
+		return onAbort(err, ErrDead)
+	}
+	m.doc.PresencePolicy = value
+	return nil
+}
+
+// AgentPresenceState returns the presence of m's agent, judged
+// according to m's AgentPresencePolicy as of its last refresh. The
+// presence of an unmonitored agent is not looked up.
+func (m *Machine) AgentPresenceState() (AgentPresenceState, error) {
+	policy := m.AgentPresencePolicy()
+	if policy.Mode == PresenceUnmonitored {
+		return AgentPresenceUnmonitored, nil
+	}
+	alive, err := agentPresence(m.st, m.globalKey())
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return m.st.agentSeen.judge(m.globalKey(), policy, alive, m.st.clock.Now()), nil
+}
+
+// AgentPresenceStates returns the presence of the agents of the given
+// machines, in the same order, each judged according to the machine's
+// AgentPresencePolicy as AgentPresenceState would.
+func (st *State) AgentPresenceStates(machines []*Machine) ([]AgentPresenceState, error) {
+	states := make([]AgentPresenceState, len(machines))
+	for i, m := range machines {
+		state, err := m.AgentPresenceState()
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot get agent presence of machine %v", m)
+		}
+		states[i] = state
+	}
+	return states, nil
+}
+
+// agentSeenTracker records when agents were last seen alive, so that
+// an extended timeout can be applied to them without changing the
+// presence watcher. Its zero value is ready to use.
+type agentSeenTracker struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+// judge returns the presence state of the agent with the given
+// presence key, which the presence watcher reports as alive or not,
+// according to policy.
+func (t *agentSeenTracker) judge(key string, policy AgentPresencePolicy, alive bool, now time.Time) AgentPresenceState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if alive {
+		if t.lastSeen == nil {
+			t.lastSeen = make(map[string]time.Time)
+		}
+		t.lastSeen[key] = now
+		return AgentPresenceAlive
+	}
+	if policy.Mode == PresenceExtendedTimeout {
+		grace := time.Duration(policy.Periods) * agentPresencePeriod
+		if lastSeen, ok := t.lastSeen[key]; ok && now.Sub(lastSeen) < grace {
+			return AgentPresenceAlive
+		}
+	}
+	return AgentPresenceDown
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+)
+
+type MachinePresenceSuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&MachinePresenceSuite{})
+
+func (s *MachinePresenceSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MachinePresenceSuite) setPolicy(c *gc.C, policy string) {
+	p, err := state.ParseAgentPresencePolicy(policy)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.SetAgentPresencePolicy(p), jc.ErrorIsNil)
+}
+
+func (s *MachinePresenceSuite) assertPresence(c *gc.C, expectState state.AgentPresenceState, expectAlive bool) {
+	s.State.StartSync()
+	presence, err := s.machine.AgentPresenceState()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(presence, gc.Equals, expectState)
+	alive, err := s.machine.AgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(alive, gc.Equals, expectAlive)
+}
+
+func (s *MachinePresenceSuite) TestParseAgentPresencePolicy(c *gc.C) {
+	for _, test := range []struct {
+		in     string
+		expect state.AgentPresencePolicy
+		err    string
+	}{{
+		in:     "standard",
+		expect: state.AgentPresencePolicy{Mode: state.PresenceStandard},
+	}, {
+		in:     "unmonitored",
+		expect: state.AgentPresencePolicy{Mode: state.PresenceUnmonitored},
+	}, {
+		in:     "extended-timeout(4)",
+		expect: state.AgentPresencePolicy{Mode: state.PresenceExtendedTimeout, Periods: 4},
+	}, {
+		in:  "extended-timeout(1)",
+		err: "extended timeout of 1 periods not valid",
+	}, {
+		in:  "extended-timeout",
+		err: `agent presence policy "extended-timeout" not valid`,
+	}, {
+		in:  "sometimes",
+		err: `agent presence policy "sometimes" not valid`,
+	}} {
+		c.Logf("policy %q", test.in)
+		policy, err := state.ParseAgentPresencePolicy(test.in)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(policy, jc.DeepEquals, test.expect)
+		c.Check(policy.String(), gc.Equals, test.in)
+	}
+}
+
+func (s *MachinePresenceSuite) TestSetAgentPresencePolicy(c *gc.C) {
+	c.Assert(s.machine.AgentPresencePolicy(), jc.DeepEquals, state.AgentPresencePolicy{Mode: state.PresenceStandard})
+
+	s.setPolicy(c, "extended-timeout(3)")
+	m, err := s.State.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.AgentPresencePolicy(), jc.DeepEquals, state.AgentPresencePolicy{
+		Mode:    state.PresenceExtendedTimeout,
+		Periods: 3,
+	})
+
+	s.setPolicy(c, "standard")
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(m.AgentPresencePolicy(), jc.DeepEquals, state.AgentPresencePolicy{Mode: state.PresenceStandard})
+}
+
+func (s *MachinePresenceSuite) TestSetAgentPresencePolicyInvalid(c *gc.C) {
+	err := s.machine.SetAgentPresencePolicy(state.AgentPresencePolicy{Mode: state.PresenceUnmonitored, Periods: 2})
+	c.Assert(err, gc.ErrorMatches, `cannot set agent presence policy of machine 0: 2 periods for unmonitored agent presence not valid`)
+}
+
+func (s *MachinePresenceSuite) TestSetAgentPresencePolicyDead(c *gc.C) {
+	c.Assert(s.machine.EnsureDead(), jc.ErrorIsNil)
+	err := s.machine.SetAgentPresencePolicy(state.AgentPresencePolicy{Mode: state.PresenceUnmonitored})
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrDead)
+}
+
+func (s *MachinePresenceSuite) TestStandard(c *gc.C) {
+	s.assertPresence(c, state.AgentPresenceDown, false)
+
+	pinger, err := s.machine.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	defer worker.Stop(pinger)
+	s.assertPresence(c, state.AgentPresenceAlive, true)
+
+	c.Assert(pinger.KillForTesting(), jc.ErrorIsNil)
+	s.assertPresence(c, state.AgentPresenceDown, false)
+}
+
+func (s *MachinePresenceSuite) TestExtendedTimeout(c *gc.C) {
+	s.setPolicy(c, "extended-timeout(3)")
+	// An agent never seen is down.
+	s.assertPresence(c, state.AgentPresenceDown, false)
+
+	pinger, err := s.machine.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	defer worker.Stop(pinger)
+	s.assertPresence(c, state.AgentPresenceAlive, true)
+
+	// An agent seen recently stays alive while it is unseen.
+	c.Assert(pinger.KillForTesting(), jc.ErrorIsNil)
+	s.assertPresence(c, state.AgentPresenceAlive, true)
+}
+
+func (s *MachinePresenceSuite) TestUnmonitored(c *gc.C) {
+	s.setPolicy(c, "unmonitored")
+	s.assertPresence(c, state.AgentPresenceUnmonitored, true)
+
+	pinger, err := s.machine.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	defer worker.Stop(pinger)
+	s.assertPresence(c, state.AgentPresenceUnmonitored, true)
+}
+
+func (s *MachinePresenceSuite) TestAgentPresenceStates(c *gc.C) {
+	unmonitored, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = unmonitored.SetAgentPresencePolicy(state.AgentPresencePolicy{Mode: state.PresenceUnmonitored})
+	c.Assert(err, jc.ErrorIsNil)
+
+	states, err := s.State.AgentPresenceStates([]*state.Machine{s.machine, unmonitored})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(states, jc.DeepEquals, []state.AgentPresenceState{
+		state.AgentPresenceDown,
+		state.AgentPresenceUnmonitored,
+	})
+}
+
+// waitAgentPresence returns how long the machine's WaitAgentPresence
+// took with the given timeout, and the error it returned.
+func (s *MachinePresenceSuite) waitAgentPresence(timeout time.Duration) (time.Duration, error) {
+	s.State.StartSync()
+	start := time.Now()
+	err := s.machine.WaitAgentPresence(timeout)
+	return time.Since(start), err
+}
+
+func (s *MachinePresenceSuite) TestWaitAgentPresenceStandard(c *gc.C) {
+	elapsed, err := s.waitAgentPresence(coretesting.ShortWait)
+	c.Assert(err, gc.ErrorMatches, `waiting for agent of machine 0: still not alive after timeout`)
+	c.Check(elapsed >= coretesting.ShortWait, jc.IsTrue)
+}
+
+func (s *MachinePresenceSuite) TestWaitAgentPresenceExtendedTimeout(c *gc.C) {
+	s.setPolicy(c, "extended-timeout(3)")
+	elapsed, err := s.waitAgentPresence(coretesting.ShortWait)
+	c.Assert(err, gc.ErrorMatches, `waiting for agent of machine 0: still not alive after timeout`)
+	c.Check(elapsed >= 3*coretesting.ShortWait, jc.IsTrue, gc.Commentf("elapsed %v", elapsed))
+}
+
+func (s *MachinePresenceSuite) TestWaitAgentPresenceUnmonitored(c *gc.C) {
+	s.setPolicy(c, "unmonitored")
+	elapsed, err := s.waitAgentPresence(coretesting.LongWait)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(elapsed < coretesting.LongWait, jc.IsTrue)
+}
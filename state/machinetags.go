@@ -0,0 +1,212 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/environs/tags"
+)
+
+// InstanceTags returns the tags that should be set on the machine's
+// instance. They are made up of the model's resource-tags, which may
+// be inherited from controller-wide defaults; the tags Juju uses to
+// identify the instance's model and controller; and the machine's
+// user-defined tags, which take precedence over the resource-tags.
+func (m *Machine) InstanceTags() (map[string]string, error) {
+	cfg, err := m.st.ModelConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	instanceTags := tags.ResourceTags(
+		m.st.ModelTag(),
+		m.st.ControllerTag(),
+		cfg,
+	)
+	if m.IsManager() {
+		instanceTags[tags.JujuIsController] = "true"
+	}
+	for k, v := range m.doc.InstanceTags {
+		instanceTags[k] = v
+	}
+	return instanceTags, nil
+}
+
+// UserInstanceTags returns the machine's user-defined instance tags.
+func (m *Machine) UserInstanceTags() map[string]string {
+	return copyStringMap(m.doc.InstanceTags)
+}
+
+// SetInstanceTags replaces the machine's user-defined instance tags.
+// The tags must satisfy tags.ValidateInstanceTags. If the machine has
+// already been provisioned, it is marked as needing its instance's
+// tags updated; see InstanceTagsChanged.
+func (m *Machine) SetInstanceTags(instanceTags map[string]string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set instance tags for machine %v", m)
+	if err := tags.ValidateInstanceTags(instanceTags); err != nil {
+		return errors.Trace(err)
+	}
+	stored := copyStringMap(instanceTags)
+	var provisioned bool
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.Life == Dead {
+			return nil, ErrDead
+		}
+		if stringMapsEqual(m.doc.InstanceTags, stored) {
+			return nil, jujutxn.ErrNoOperations
+		}
+		// The instance's tags need updating only if they were
+		// set when it was provisioned.
+		provisioned = m.doc.Nonce != ""
+		set := bson.D{{"instancetagschanged", provisioned}}
+		update := bson.D{{"$set", set}}
+		if stored == nil {
+			update = append(update, bson.DocElem{"$unset", bson.D{{"instancetags", nil}}})
+		} else {
+			update[0].Value = append(set, bson.DocElem{"instancetags", stored})
+		}
+		return []txn.Op{{
+			C:  machinesC,
+			Id: m.doc.DocID,
+			Assert: bson.D{
+				{"life", bson.D{{"$ne", Dead}}},
+				{"nonce", m.doc.Nonce},
+			},
+			Update: update,
+		}}, nil
+	}
+	if err := m.st.run(buildTxn); err != nil {
+		return err
+	}
+	m.doc.InstanceTags = stored
+	m.doc.InstanceTagsChanged = provisioned
+	return nil
+}
+
+// AppliedInstanceTags returns the tags that the provisioner last
+// reported setting on the machine's instance.
+func (m *Machine) AppliedInstanceTags() map[string]string {
+	return replaceTagKeys(m.doc.AppliedInstanceTags, unescapeReplacer)
+}
+
+// SetAppliedInstanceTags records the tags that were actually set on
+// the machine's instance. If they include all of the machine's current
+// user-defined tags, the machine is no longer marked as needing its
+// instance's tags updated.
+func (m *Machine) SetAppliedInstanceTags(applied map[string]string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set applied instance tags for machine %v", m)
+	// Unlike user-defined tags, the applied tags may include the
+	// model's resource-tags, whose keys are not restricted.
+	stored := replaceTagKeys(applied, escapeReplacer)
+	var changed bool
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.Life == Dead {
+			return nil, ErrDead
+		}
+		// The revno assertion ensures that the user-defined tags
+		// we compare against are still current.
+		revno, err := m.st.readTxnRevno(machinesC, m.doc.DocID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		changed = m.doc.InstanceTagsChanged && !instanceTagsApplied(m.doc.InstanceTags, applied)
+		set := bson.D{{"instancetagschanged", changed}}
+		update := bson.D{{"$set", set}}
+		if stored == nil {
+			update = append(update, bson.DocElem{"$unset", bson.D{{"appliedinstancetags", nil}}})
+		} else {
+			update[0].Value = append(set, bson.DocElem{"appliedinstancetags", stored})
+		}
+		return []txn.Op{{
+			C:  machinesC,
+			Id: m.doc.DocID,
+			Assert: bson.D{
+				{"life", bson.D{{"$ne", Dead}}},
+				{"txn-revno", revno},
+			},
+			Update: update,
+		}}, nil
+	}
+	if err := m.st.run(buildTxn); err != nil {
+		return err
+	}
+	m.doc.AppliedInstanceTags = stored
+	m.doc.InstanceTagsChanged = changed
+	return nil
+}
+
+// InstanceTagsChanged returns whether the machine's user-defined tags
+// have changed since its instance was provisioned, and the provisioner
+// has not yet reported applying them.
+func (m *Machine) InstanceTagsChanged() bool {
+	return m.doc.InstanceTagsChanged
+}
+
+// InstanceTagsDrift returns the tags that should be set on the
+// machine's instance, according to InstanceTags, but whose applied
+// value is missing or different. Tags that have been applied but are
+// not expected are ignored, because the provisioner adds tags of its
+// own, such as the units deployed to the machine.
+func (m *Machine) InstanceTagsDrift() (map[string]string, error) {
+	expected, err := m.InstanceTags()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	allApplied := m.AppliedInstanceTags()
+	drift := make(map[string]string)
+	for k, v := range expected {
+		if applied, ok := allApplied[k]; !ok || applied != v {
+			drift[k] = v
+		}
+	}
+	return drift, nil
+}
+
+// instanceTagsApplied returns whether every tag in expected has been
+// applied with the same value.
+func instanceTagsApplied(expected, applied map[string]string) bool {
+	for k, v := range expected {
+		if appliedValue, ok := applied[k]; !ok || appliedValue != v {
+			return false
+		}
+	}
+	return true
+}
+
+// stringMapsEqual returns whether a and b hold the same entries; nil
+// and empty maps are considered equal.
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return instanceTagsApplied(a, b)
+}
+
+// replaceTagKeys returns a copy of in with each key transformed by
+// replacer, or nil if in is empty.
+func replaceTagKeys(in map[string]string, replacer *strings.Replacer) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(in))
+	for k, v := range in {
+		result[replacer.Replace(k)] = v
+	}
+	return result
+}
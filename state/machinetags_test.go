@@ -0,0 +1,169 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/environs/tags"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type MachineTagsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&MachineTagsSuite{})
+
+func (s *MachineTagsSuite) addMachine(c *gc.C, instanceTags map[string]string) *state.Machine {
+	m, err := s.State.AddOneMachine(state.MachineTemplate{
+		Series:       "quantal",
+		Jobs:         []state.MachineJob{state.JobHostUnits},
+		InstanceTags: instanceTags,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return m
+}
+
+func (s *MachineTagsSuite) TestAddMachineInstanceTags(c *gc.C) {
+	m := s.addMachine(c, map[string]string{"team": "web"})
+	c.Assert(m.UserInstanceTags(), jc.DeepEquals, map[string]string{"team": "web"})
+	c.Assert(m.InstanceTagsChanged(), jc.IsFalse)
+
+	m, err := s.State.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.UserInstanceTags(), jc.DeepEquals, map[string]string{"team": "web"})
+}
+
+func (s *MachineTagsSuite) TestAddMachineInvalidInstanceTags(c *gc.C) {
+	_, err := s.State.AddOneMachine(state.MachineTemplate{
+		Series:       "quantal",
+		Jobs:         []state.MachineJob{state.JobHostUnits},
+		InstanceTags: map[string]string{"Team": "web"},
+	})
+	c.Assert(err, gc.ErrorMatches, `cannot add a new machine: invalid tag "Team": .*`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *MachineTagsSuite) TestInstanceTags(c *gc.C) {
+	err := s.State.UpdateModelConfig(map[string]interface{}{
+		"resource-tags": "owner=fred team=db",
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	m := s.addMachine(c, map[string]string{"team": "web"})
+
+	instanceTags, err := m.InstanceTags()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instanceTags, jc.DeepEquals, map[string]string{
+		tags.JujuModel:      s.State.ModelUUID(),
+		tags.JujuController: s.State.ControllerUUID(),
+		"owner":             "fred",
+		"team":              "web",
+	})
+}
+
+func (s *MachineTagsSuite) TestSetInstanceTagsBeforeProvisioning(c *gc.C) {
+	m := s.addMachine(c, nil)
+	err := m.SetInstanceTags(map[string]string{"team": "web"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.InstanceTagsChanged(), jc.IsFalse)
+
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(m.UserInstanceTags(), jc.DeepEquals, map[string]string{"team": "web"})
+	c.Assert(m.InstanceTagsChanged(), jc.IsFalse)
+
+	// Setting empty tags removes them.
+	err = m.SetInstanceTags(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(m.UserInstanceTags(), gc.IsNil)
+}
+
+func (s *MachineTagsSuite) TestSetInstanceTagsInvalid(c *gc.C) {
+	m := s.addMachine(c, nil)
+	err := m.SetInstanceTags(map[string]string{"juju-team": "web"})
+	c.Assert(err, gc.ErrorMatches, `cannot set instance tags for machine 0: invalid tag "juju-team": prefix "juju-" is reserved`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *MachineTagsSuite) TestSetInstanceTagsDead(c *gc.C) {
+	m := s.addMachine(c, nil)
+	c.Assert(m.EnsureDead(), jc.ErrorIsNil)
+	err := m.SetInstanceTags(map[string]string{"team": "web"})
+	c.Assert(err, gc.ErrorMatches, `cannot set instance tags for machine 0: not found or dead`)
+}
+
+func (s *MachineTagsSuite) TestSetInstanceTagsAfterProvisioning(c *gc.C) {
+	m := s.addMachine(c, map[string]string{"team": "web"})
+	err := m.SetProvisioned("i-blah", "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	expected, err := m.InstanceTags()
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetAppliedInstanceTags(expected)
+	c.Assert(err, jc.ErrorIsNil)
+	drift, err := m.InstanceTagsDrift()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, gc.HasLen, 0)
+
+	err = m.SetInstanceTags(map[string]string{"team": "db"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.InstanceTagsChanged(), jc.IsTrue)
+	drift, err = m.InstanceTagsDrift()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, jc.DeepEquals, map[string]string{"team": "db"})
+
+	// Applying stale tags leaves the machine marked as changed.
+	err = m.SetAppliedInstanceTags(expected)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.InstanceTagsChanged(), jc.IsTrue)
+
+	expected["team"] = "db"
+	err = m.SetAppliedInstanceTags(expected)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.InstanceTagsChanged(), jc.IsFalse)
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(m.InstanceTagsChanged(), jc.IsFalse)
+	c.Assert(m.AppliedInstanceTags(), jc.DeepEquals, expected)
+}
+
+func (s *MachineTagsSuite) TestAppliedInstanceTagsEscapedKeys(c *gc.C) {
+	m := s.addMachine(c, nil)
+	applied := map[string]string{"kubernetes.io/cluster": "owned", "$owner": "fred"}
+	err := m.SetAppliedInstanceTags(applied)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.AppliedInstanceTags(), jc.DeepEquals, applied)
+
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(m.AppliedInstanceTags(), jc.DeepEquals, applied)
+}
+
+func (s *MachineTagsSuite) TestWatchInstanceTags(c *gc.C) {
+	m := s.addMachine(c, nil)
+	w := m.WatchInstanceTags()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Unrelated changes are not reported.
+	err := m.SetProvisioned("i-blah", "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	err = m.SetInstanceTags(map[string]string{"team": "web"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Applying the tags clears the changed flag.
+	err = m.SetAppliedInstanceTags(map[string]string{"team": "web"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Applying them again changes nothing.
+	err = m.SetAppliedInstanceTags(map[string]string{"team": "web"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/tomb.v1"
+
+	"github.com/juju/juju/state/watcher"
+)
+
+// MachineWorker names a group of workers that a machine agent runs
+// only when the machine needs them.
+type MachineWorker string
+
+const (
+	// WorkerUnitDeployer deploys units to the machine. It is required
+	// by machines with JobHostUnits.
+	WorkerUnitDeployer MachineWorker = "unit-deployer"
+
+	// WorkerController runs the controller. It is required by
+	// machines with JobManageModel.
+	WorkerController MachineWorker = "controller"
+
+	// WorkerContainerProvisioner provisions containers on the machine.
+	// It is required once the machine is known to support at least
+	// one kind of container.
+	WorkerContainerProvisioner MachineWorker = "container-provisioner"
+
+	// WorkerContainerNetworking configures the machine's network so
+	// that its containers can be reached. It is required while the
+	// machine hosts any containers.
+	WorkerContainerNetworking MachineWorker = "container-networking"
+)
+
+// machineWorkersInputs holds everything from which the workers a
+// machine requires are computed.
+type machineWorkersInputs struct {
+	jobs               []MachineJob
+	supportsContainers bool
+	hostsContainers    bool
+}
+
+// requiredWorkers returns the workers required by a machine with the
+// given inputs, sorted by name.
+func (in machineWorkersInputs) requiredWorkers() []MachineWorker {
+	var workers []MachineWorker
+	for _, job := range in.jobs {
+		switch job {
+		case JobHostUnits:
+			workers = append(workers, WorkerUnitDeployer)
+		case JobManageModel:
+			workers = append(workers, WorkerController)
+		}
+	}
+	if in.supportsContainers {
+		workers = append(workers, WorkerContainerProvisioner)
+	}
+	if in.hostsContainers {
+		workers = append(workers, WorkerContainerNetworking)
+	}
+	sort.Sort(machineWorkersByName(workers))
+	return workers
+}
+
+type machineWorkersByName []MachineWorker
+
+func (w machineWorkersByName) Len() int           { return len(w) }
+func (w machineWorkersByName) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
+func (w machineWorkersByName) Less(i, j int) bool { return w[i] < w[j] }
+
+// workersInputs returns the inputs from which the workers required by
+// m are computed, as of m's last refresh, reading its containers from
+// the database.
+func (m *Machine) workersInputs() (machineWorkersInputs, error) {
+	in := machineWorkersInputs{
+		jobs:               m.doc.Jobs,
+		supportsContainers: m.doc.SupportedContainersKnown && len(m.doc.SupportedContainers) > 0,
+	}
+	containers, err := m.Containers()
+	if err != nil && !errors.IsNotFound(err) {
+		return machineWorkersInputs{}, errors.Trace(err)
+	}
+	in.hostsContainers = len(containers) > 0
+	return in, nil
+}
+
+// RequiredWorkers returns the workers that m's agent must run, as
+// determined by m's jobs, as of its last refresh, and by the
+// containers it supports and hosts. Unlike the jobs, the result
+// changes over the life of the machine; use WatchRequiredWorkers to
+// follow it.
+func (m *Machine) RequiredWorkers() ([]MachineWorker, error) {
+	in, err := m.workersInputs()
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get required workers of machine %v", m)
+	}
+	return in.requiredWorkers(), nil
+}
+
+// machineWorkersWatcher notifies about changes to the workers required
+// by a machine.
+type machineWorkersWatcher struct {
+	commonWatcher
+	machine *Machine
+	out     chan struct{}
+}
+
+var _ Watcher = (*machineWorkersWatcher)(nil)
+
+// WatchRequiredWorkers returns a NotifyWatcher that sends an event
+// when the result of RequiredWorkers changes. Changes to the machine
+// or its containers that leave the required workers as they were are
+// not reported.
+func (m *Machine) WatchRequiredWorkers() NotifyWatcher {
+	w := &machineWorkersWatcher{
+		commonWatcher: newCommonWatcher(m.st),
+		out:           make(chan struct{}),
+		machine:       &Machine{st: m.st, doc: m.doc}, // Copy so it may be freely refreshed
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *machineWorkersWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *machineWorkersWatcher) loop() error {
+	docID := w.machine.doc.DocID
+	machineCh := make(chan watcher.Change)
+	containersCh := make(chan watcher.Change)
+	for _, item := range []struct {
+		coll string
+		ch   chan watcher.Change
+	}{
+		{machinesC, machineCh},
+		{containerRefsC, containersCh},
+	} {
+		coll, closer := w.st.getCollection(item.coll)
+		revno, err := getTxnRevno(coll, docID)
+		closer()
+		if err != nil {
+			return err
+		}
+		w.watcher.Watch(item.coll, docID, revno, item.ch)
+		defer w.watcher.Unwatch(item.coll, docID, item.ch)
+	}
+	if err := w.machine.Refresh(); err != nil {
+		return err
+	}
+	workers, err := w.machine.RequiredWorkers()
+	if err != nil {
+		return err
+	}
+	out := w.out
+	update := func() error {
+		newWorkers, err := w.machine.RequiredWorkers()
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(newWorkers, workers) {
+			workers = newWorkers
+			out = w.out
+		}
+		return nil
+	}
+	for {
+		select {
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-machineCh:
+			if err := w.machine.Refresh(); err != nil {
+				return err
+			}
+			if err := update(); err != nil {
+				return err
+			}
+		case <-containersCh:
+			if err := update(); err != nil {
+				return err
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
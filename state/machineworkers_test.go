@@ -0,0 +1,137 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/testing"
+)
+
+type MachineWorkersSuite struct {
+	ConnSuite
+	controller *state.Machine
+	machine    *state.Machine
+}
+
+var _ = gc.Suite(&MachineWorkersSuite{})
+
+func (s *MachineWorkersSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.controller, err = s.State.AddMachine("quantal", state.JobHostUnits, state.JobManageModel)
+	c.Assert(err, jc.ErrorIsNil)
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MachineWorkersSuite) assertRequiredWorkers(c *gc.C, m *state.Machine, expect ...state.MachineWorker) {
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	workers, err := m.RequiredWorkers()
+	c.Assert(err, jc.ErrorIsNil)
+	if len(expect) == 0 {
+		c.Assert(workers, gc.HasLen, 0)
+	} else {
+		c.Assert(workers, jc.DeepEquals, expect)
+	}
+}
+
+func (s *MachineWorkersSuite) addContainer(c *gc.C) *state.Machine {
+	container, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, s.machine.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	return container
+}
+
+func (s *MachineWorkersSuite) TestRequiredWorkersFromJobs(c *gc.C) {
+	s.assertRequiredWorkers(c, s.controller, state.WorkerController, state.WorkerUnitDeployer)
+	s.assertRequiredWorkers(c, s.machine, state.WorkerUnitDeployer)
+}
+
+func (s *MachineWorkersSuite) TestRequiredWorkersFromContainers(c *gc.C) {
+	c.Assert(s.machine.SupportsNoContainers(), jc.ErrorIsNil)
+	s.assertRequiredWorkers(c, s.machine, state.WorkerUnitDeployer)
+
+	c.Assert(s.machine.SetSupportedContainers([]instance.ContainerType{instance.LXD}), jc.ErrorIsNil)
+	s.assertRequiredWorkers(c, s.machine, state.WorkerContainerProvisioner, state.WorkerUnitDeployer)
+
+	container := s.addContainer(c)
+	s.assertRequiredWorkers(c, s.machine,
+		state.WorkerContainerNetworking,
+		state.WorkerContainerProvisioner,
+		state.WorkerUnitDeployer,
+	)
+	s.assertRequiredWorkers(c, container, state.WorkerUnitDeployer)
+}
+
+func (s *MachineWorkersSuite) TestRequiredWorkersAfterEnableHA(c *gc.C) {
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnableHA(3, constraints.Value{}, "quantal", []string{s.machine.Id(), "2"})
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertRequiredWorkers(c, s.machine, state.WorkerController, state.WorkerUnitDeployer)
+}
+
+func (s *MachineWorkersSuite) TestWatchRequiredWorkers(c *gc.C) {
+	w := s.machine.WatchRequiredWorkers()
+	defer testing.AssertStop(c, w)
+
+	// Initial event.
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Changes to the machine that leave the workers alone: no event.
+	err := s.machine.SetProviderAddresses(network.NewAddress("0.1.2.3"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.SupportsNoContainers(), jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Support containers: one event.
+	c.Assert(s.machine.SetSupportedContainers([]instance.ContainerType{instance.LXD}), jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Host a container: one event.
+	container := s.addContainer(c)
+	wc.AssertOneChange()
+
+	// Host another container: no event.
+	s.addContainer(c)
+	wc.AssertNoChange()
+
+	// Stop hosting one of them: no event.
+	c.Assert(container.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(container.Remove(), jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Gain the manager role: one event.
+	_, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnableHA(3, constraints.Value{}, "quantal", []string{s.machine.Id(), "2"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	testing.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *MachineWorkersSuite) TestWatchRequiredWorkersLastContainerRemoved(c *gc.C) {
+	c.Assert(s.machine.SetSupportedContainers([]instance.ContainerType{instance.LXD}), jc.ErrorIsNil)
+	container := s.addContainer(c)
+
+	w := s.machine.WatchRequiredWorkers()
+	defer testing.AssertStop(c, w)
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	c.Assert(container.EnsureDead(), jc.ErrorIsNil)
+	c.Assert(container.Remove(), jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
@@ -155,6 +155,18 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 
 		// Recreated whilst migrating actions.
 		actionNotificationsC,
+
+		// Agent login history is only kept for auditing; the agents
+		// log in again to the target controller.
+		machineAgentLoginsC,
+
+		// Observed network config is reported again by the machine
+		// agents once they connect to the target controller.
+		machineNetworkConfigC,
+
+		// Creation keys only guard against clients retrying requests
+		// to add machines, which do not span a migration.
+		machineCreationKeysC,
 	)
 
 	// THIS SET WILL BE REMOVED WHEN MIGRATIONS ARE COMPLETE
@@ -164,6 +176,7 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 
 		// machine
 		rebootC,
+		machineUpgradeSeriesLocksC,
 
 		// service / unit
 		charmsC,
@@ -272,6 +285,21 @@ func (s *MigrationSuite) TestMachineDocFields(c *gc.C) {
 		// Ignored at this stage, could be an issue if mongo 3.0 isn't
 		// available.
 		"StopMongoUntilVersion",
+		// ProvisionerMetadata describes how the instance was
+		// provisioned in the source model, for debugging only.
+		"ProvisionerMetadata",
+		// TODO: the migration format cannot yet describe
+		// user-defined instance tags; the applied tags and
+		// reconcile flag are refreshed by the target's provisioner.
+		"InstanceTags",
+		"AppliedInstanceTags",
+		"InstanceTagsChanged",
+		// Creation keys are not migrated; see machineCreationKeysC.
+		"CreationKey",
+		// TODO: the migration format cannot yet describe agent
+		// presence policies; migrated machines use the standard
+		// policy.
+		"PresencePolicy",
 	)
 	migrated := set.NewStrings(
 		"Addresses",
@@ -41,7 +41,48 @@ func Open(
 	info *mongo.MongoInfo, opts mongo.DialOpts,
 	newPolicy NewPolicyFunc,
 ) (*State, error) {
-	st, err := open(controllerModelTag, info, opts, newPolicy, clock.WallClock)
+	return openAndStart(controllerModelTag, controllerTag, info, opts, newPolicy, false, DefaultDocumentLimits())
+}
+
+// OpenWithLimits is like Open, but the returned State, and any State
+// derived from it with ForModel, rejects values larger than the given
+// limits instead of DefaultDocumentLimits.
+func OpenWithLimits(
+	controllerModelTag names.ModelTag,
+	controllerTag names.ControllerTag,
+	info *mongo.MongoInfo, opts mongo.DialOpts,
+	newPolicy NewPolicyFunc,
+	limits DocumentLimits,
+) (*State, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return openAndStart(controllerModelTag, controllerTag, info, opts, newPolicy, false, limits)
+}
+
+// OpenReadOnly is like Open, but the returned State will not change
+// the database. Reads, Refresh and watchers work as usual, but every
+// attempt to run a transaction, write through a collection, or start
+// a presence pinger fails with ErrReadOnly. It is intended for tools
+// that inspect a running controller's database.
+func OpenReadOnly(
+	controllerModelTag names.ModelTag,
+	controllerTag names.ControllerTag,
+	info *mongo.MongoInfo, opts mongo.DialOpts,
+	newPolicy NewPolicyFunc,
+) (*State, error) {
+	return openAndStart(controllerModelTag, controllerTag, info, opts, newPolicy, true, DefaultDocumentLimits())
+}
+
+func openAndStart(
+	controllerModelTag names.ModelTag,
+	controllerTag names.ControllerTag,
+	info *mongo.MongoInfo, opts mongo.DialOpts,
+	newPolicy NewPolicyFunc,
+	readOnly bool,
+	limits DocumentLimits,
+) (*State, error) {
+	st, err := open(controllerModelTag, info, opts, newPolicy, clock.WallClock, readOnly, limits)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -65,6 +106,8 @@ func open(
 	info *mongo.MongoInfo, opts mongo.DialOpts,
 	newPolicy NewPolicyFunc,
 	clock clock.Clock,
+	readOnly bool,
+	limits DocumentLimits,
 ) (*State, error) {
 	logger.Infof("opening state, mongo addresses: %q; entity %v", info.Addrs, info.Tag)
 	logger.Debugf("dialing mongo")
@@ -81,7 +124,7 @@ func open(
 	}
 	logger.Debugf("mongodb login successful")
 
-	st, err := newState(controllerModelTag, controllerModelTag, session, info, newPolicy, clock)
+	st, err := newState(controllerModelTag, controllerModelTag, session, info, newPolicy, clock, readOnly, limits)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -210,7 +253,7 @@ func Initialize(args InitializeParams) (_ *State, err error) {
 	// When creating the controller model, the new model
 	// UUID is also used as the controller UUID.
 	modelTag := names.NewModelTag(args.ControllerModelArgs.Config.UUID())
-	st, err := open(modelTag, args.MongoInfo, args.MongoDialOpts, args.NewPolicy, args.Clock)
+	st, err := open(modelTag, args.MongoInfo, args.MongoDialOpts, args.NewPolicy, args.Clock, false, DefaultDocumentLimits())
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -474,6 +517,8 @@ func newState(
 	session *mgo.Session, mongoInfo *mongo.MongoInfo,
 	newPolicy NewPolicyFunc,
 	clock clock.Clock,
+	readOnly bool,
+	limits DocumentLimits,
 ) (_ *State, err error) {
 
 	defer func() {
@@ -484,12 +529,19 @@ func newState(
 
 	// Set up database.
 	rawDB := session.DB(jujuDB)
-	database, err := allCollections().Load(rawDB, modelTag.Id())
+	var database Database
+	if readOnly {
+		database, err = allCollections().LoadReadOnly(rawDB, modelTag.Id())
+	} else {
+		database, err = allCollections().Load(rawDB, modelTag.Id())
+	}
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	if err := InitDbLogs(session); err != nil {
-		return nil, errors.Trace(err)
+	if !readOnly {
+		if err := InitDbLogs(session); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 
 	// Create State.
@@ -501,6 +553,8 @@ func newState(
 		session:            session,
 		database:           database,
 		newPolicy:          newPolicy,
+		readOnly:           readOnly,
+		limits:             limits,
 	}
 	if newPolicy != nil {
 		st.policy = newPolicy(st)
@@ -509,10 +509,14 @@ func (w *Watcher) sync() error {
 
 // Pinger periodically reports that a specific key is alive, so that
 // watchers interested on that fact can react appropriately.
+//
+// A Pinger may be started again once it has been stopped or killed,
+// or has failed; each run allocates a fresh sequence for the key, so
+// the slots written by earlier runs are never reused.
 type Pinger struct {
 	modelUUID string
 	mu        sync.Mutex
-	tomb      tomb.Tomb
+	tomb      *tomb.Tomb // replaced, with mu held, on each Start
 	base      *mgo.Collection
 	pings     *mgo.Collection
 	started   bool
@@ -532,17 +536,27 @@ func NewPinger(base *mgo.Collection, modelTag names.ModelTag, key string) *Pinge
 		pings:     pingsC(base),
 		beingKey:  key,
 		modelUUID: modelTag.Id(),
+		tomb:      new(tomb.Tomb),
 	}
 }
 
-// Start starts periodically reporting that p's key is alive.
+// Start starts periodically reporting that p's key is alive. A pinger
+// that has been stopped or killed, or whose periodic ping has failed,
+// may be started again; a pinger that is still running may not.
 func (p *Pinger) Start() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.started {
-		return errors.Errorf("pinger already started")
+		select {
+		case <-p.tomb.Dead():
+			// The previous run was killed or failed, and
+			// its loop has exited, so it's safe to restart.
+		default:
+			return errors.Errorf("pinger already started")
+		}
 	}
-	p.tomb = tomb.Tomb{}
+	p.started = false
+	p.tomb = new(tomb.Tomb)
 	if err := p.prepare(); err != nil {
 		return errors.Trace(err)
 	}
@@ -551,8 +565,9 @@ func (p *Pinger) Start() error {
 		return errors.Trace(err)
 	}
 	p.started = true
+	t := p.tomb
 	go func() {
-		err := p.loop()
+		err := p.loop(t)
 		cause := errors.Cause(err)
 		// tomb expects ErrDying or ErrStillAlive as
 		// exact values, so we need to log and unwrap
@@ -560,38 +575,52 @@ func (p *Pinger) Start() error {
 		if err != nil && cause != tomb.ErrDying {
 			logger.Infof("pinger loop failed: %v", err)
 		}
-		p.tomb.Kill(cause)
-		p.tomb.Done()
+		t.Kill(cause)
+		t.Done()
 	}()
 	return nil
 }
 
-// Kill is part of the worker.Worker interface.
+// currentTomb returns the tomb tracking the pinger's current run.
+func (p *Pinger) currentTomb() *tomb.Tomb {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tomb
+}
+
+// Kill is part of the worker.Worker interface. It abandons the
+// pinger's current run immediately, without waiting for a ping in
+// progress to complete or writing a final ping.
 func (p *Pinger) Kill() {
-	p.tomb.Kill(nil)
+	p.currentTomb().Kill(nil)
 }
 
-// Wait returns when the Pinger has stopped, and returns the first error
-// it encountered.
+// Wait returns when the Pinger's current run has stopped, and returns
+// the first error it encountered.
 func (p *Pinger) Wait() error {
-	return p.tomb.Wait()
+	return p.currentTomb().Wait()
 }
 
-// Stop stops p's periodical ping.
-// Watchers will not notice p has stopped pinging until the
-// previous ping times out.
+// Stop stops p's periodical ping, waiting for any ping in progress to
+// complete, and then pings once more so that watchers will not notice
+// p has stopped pinging until a full period has passed. Stopping a
+// pinger that is not running has no effect.
 func (p *Pinger) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.started {
-		logger.Tracef("stopping pinger for %q with seq=%d", p.beingKey, p.beingSeq)
+	if !p.started {
+		return nil
 	}
+	logger.Tracef("stopping pinger for %q with seq=%d", p.beingKey, p.beingSeq)
 	p.tomb.Kill(nil)
 	err := p.tomb.Wait()
-	// TODO ping one more time to guarantee a late timeout.
 	p.started = false
-	return errors.Trace(err)
-
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The loop has exited, so the final ping cannot race with
+	// a periodic one on the slot document.
+	return errors.Trace(p.ping())
 }
 
 // KillForTesting stops p's periodical ping and immediately reports that it is dead.
@@ -652,10 +681,10 @@ func (p *Pinger) killStopped() error {
 
 // loop is the main pinger loop that runs while it is
 // in started state.
-func (p *Pinger) loop() error {
+func (p *Pinger) loop(t *tomb.Tomb) error {
 	for {
 		select {
-		case <-p.tomb.Dying():
+		case <-t.Dying():
 			return errors.Trace(tomb.ErrDying)
 		case <-time.After(time.Duration(float64(period+1)*0.75) * time.Second):
 			if err := p.ping(); err != nil {
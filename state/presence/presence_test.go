@@ -407,6 +407,76 @@ func (s *PresenceSuite) TestPingerPeriodAndResilience(c *gc.C) {
 	assertAlive(c, w, "a", true)
 }
 
+func (s *PresenceSuite) TestPingerStartWhileRunning(c *gc.C) {
+	p := presence.NewPinger(s.presence, s.modelTag, "a")
+	c.Assert(p.Start(), gc.IsNil)
+	defer assertStopped(c, p)
+	c.Assert(p.Start(), gc.ErrorMatches, "pinger already started")
+}
+
+func (s *PresenceSuite) TestPingerStopNotStarted(c *gc.C) {
+	p := presence.NewPinger(s.presence, s.modelTag, "a")
+	c.Assert(p.Stop(), gc.IsNil)
+	c.Assert(p.Stop(), gc.IsNil)
+}
+
+func (s *PresenceSuite) TestPingerRestartAfterKill(c *gc.C) {
+	w := presence.NewWatcher(s.presence, s.modelTag)
+	p := presence.NewPinger(s.presence, s.modelTag, "a")
+	defer assertStopped(c, w)
+
+	c.Assert(p.Start(), gc.IsNil)
+	p.Kill()
+	c.Assert(p.Wait(), gc.IsNil)
+
+	// A killed pinger can be started again, with a fresh sequence.
+	c.Assert(p.Start(), gc.IsNil)
+	defer assertStopped(c, p)
+	w.Sync()
+	alive, err := w.Alive("a")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alive, jc.IsTrue)
+}
+
+func (s *PresenceSuite) TestPingerRecoversAfterDatabaseError(c *gc.C) {
+	const period = 1
+	presence.FakePeriod(period)
+	presence.RealTimeSlot()
+
+	w := presence.NewWatcher(s.presence, s.modelTag)
+	defer assertStopped(c, w)
+
+	// Run a pinger on its own session, and close the session to
+	// make its next periodic ping fail.
+	session := s.MgoSuite.Session.Copy()
+	p1 := presence.NewPinger(s.presence.With(session), s.modelTag, "a")
+	c.Assert(p1.Start(), gc.IsNil)
+	session.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p1.Wait()
+	}()
+	select {
+	case err := <-done:
+		c.Assert(err, gc.NotNil)
+	case <-time.After(testing.LongWait):
+		c.Fatalf("pinger did not fail")
+	}
+	c.Assert(p1.Stop(), gc.NotNil)
+
+	// A fresh pinger for the same key restores presence within
+	// one period.
+	p2 := presence.NewPinger(s.presence, s.modelTag, "a")
+	c.Assert(p2.Start(), gc.IsNil)
+	defer assertStopped(c, p2)
+	time.Sleep(period * time.Second)
+	w.Sync()
+	alive, err := w.Alive("a")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alive, jc.IsTrue)
+}
+
 func (s *PresenceSuite) TestStartSync(c *gc.C) {
 	w := presence.NewWatcher(s.presence, s.modelTag)
 	p := presence.NewPinger(s.presence, s.modelTag, "a")
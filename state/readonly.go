@@ -0,0 +1,208 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/mongo"
+)
+
+// ErrReadOnly is returned when an attempt is made to change the
+// database through a State opened with OpenReadOnly.
+var ErrReadOnly = errors.New("state is read-only")
+
+// readOnlyRunner is the jujutxn.Runner used by a read-only Database.
+// It never writes to the database.
+type readOnlyRunner struct{}
+
+// RunTransaction is part of the jujutxn.Runner interface. It always
+// returns ErrReadOnly.
+func (readOnlyRunner) RunTransaction(ops []txn.Op) error {
+	return ErrReadOnly
+}
+
+// Run is part of the jujutxn.Runner interface. It builds the first
+// attempt's operations, so that transactions that find nothing to do
+// (for example, those that create a document only if it is missing)
+// succeed as usual; it returns ErrReadOnly if there is anything to
+// write.
+func (readOnlyRunner) Run(transactions jujutxn.TransactionSource) error {
+	ops, err := transactions(0)
+	if err == jujutxn.ErrNoOperations {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(ops) > 0 {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// ResumeTransactions is part of the jujutxn.Runner interface. It always
+// returns ErrReadOnly.
+func (readOnlyRunner) ResumeTransactions() error {
+	return ErrReadOnly
+}
+
+// MaybePruneTransactions is part of the jujutxn.Runner interface. It
+// always returns ErrReadOnly.
+func (readOnlyRunner) MaybePruneTransactions(pruneFactor float32) error {
+	return ErrReadOnly
+}
+
+// readOnlyCollection wraps a mongo.Collection so that its Writeable
+// view, and any queries made with it, refuse to change the collection.
+type readOnlyCollection struct {
+	mongo.Collection
+}
+
+// Find is part of the mongo.Collection interface.
+func (c readOnlyCollection) Find(query interface{}) mongo.Query {
+	return readOnlyQuery{c.Collection.Find(query)}
+}
+
+// FindId is part of the mongo.Collection interface.
+func (c readOnlyCollection) FindId(id interface{}) mongo.Query {
+	return readOnlyQuery{c.Collection.FindId(id)}
+}
+
+// Writeable is part of the mongo.Collection interface.
+func (c readOnlyCollection) Writeable() mongo.WriteCollection {
+	return readOnlyWriteCollection{
+		WriteCollection: c.Collection.Writeable(),
+		collection:      c,
+	}
+}
+
+// readOnlyWriteCollection implements mongo.WriteCollection, returning
+// ErrReadOnly from every method that would change the collection.
+//
+// Underlying still returns the raw *mgo.Collection, which is used for
+// reads that the mongo.Collection interface does not support; code that
+// writes through it is not protected.
+type readOnlyWriteCollection struct {
+	mongo.WriteCollection
+	collection readOnlyCollection
+}
+
+// Find is part of the mongo.Collection interface.
+func (c readOnlyWriteCollection) Find(query interface{}) mongo.Query {
+	return c.collection.Find(query)
+}
+
+// FindId is part of the mongo.Collection interface.
+func (c readOnlyWriteCollection) FindId(id interface{}) mongo.Query {
+	return c.collection.FindId(id)
+}
+
+// Writeable is part of the mongo.Collection interface.
+func (c readOnlyWriteCollection) Writeable() mongo.WriteCollection {
+	return c
+}
+
+// Insert is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) Insert(docs ...interface{}) error {
+	return ErrReadOnly
+}
+
+// Upsert is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) Upsert(selector interface{}, update interface{}) (*mgo.ChangeInfo, error) {
+	return nil, ErrReadOnly
+}
+
+// UpsertId is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) UpsertId(id interface{}, update interface{}) (*mgo.ChangeInfo, error) {
+	return nil, ErrReadOnly
+}
+
+// Update is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) Update(selector interface{}, update interface{}) error {
+	return ErrReadOnly
+}
+
+// UpdateId is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) UpdateId(id interface{}, update interface{}) error {
+	return ErrReadOnly
+}
+
+// Remove is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) Remove(sel interface{}) error {
+	return ErrReadOnly
+}
+
+// RemoveId is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) RemoveId(id interface{}) error {
+	return ErrReadOnly
+}
+
+// RemoveAll is part of the mongo.WriteCollection interface.
+func (readOnlyWriteCollection) RemoveAll(sel interface{}) (*mgo.ChangeInfo, error) {
+	return nil, ErrReadOnly
+}
+
+// readOnlyQuery wraps a mongo.Query so that Apply, which modifies the
+// matching document, returns ErrReadOnly.
+type readOnlyQuery struct {
+	mongo.Query
+}
+
+// Apply is part of the mongo.Query interface.
+func (readOnlyQuery) Apply(change mgo.Change, result interface{}) (*mgo.ChangeInfo, error) {
+	return nil, ErrReadOnly
+}
+
+func (q readOnlyQuery) Batch(n int) mongo.Query {
+	return readOnlyQuery{q.Query.Batch(n)}
+}
+
+func (q readOnlyQuery) Comment(comment string) mongo.Query {
+	return readOnlyQuery{q.Query.Comment(comment)}
+}
+
+func (q readOnlyQuery) Hint(indexKey ...string) mongo.Query {
+	return readOnlyQuery{q.Query.Hint(indexKey...)}
+}
+
+func (q readOnlyQuery) Limit(n int) mongo.Query {
+	return readOnlyQuery{q.Query.Limit(n)}
+}
+
+func (q readOnlyQuery) LogReplay() mongo.Query {
+	return readOnlyQuery{q.Query.LogReplay()}
+}
+
+func (q readOnlyQuery) Prefetch(p float64) mongo.Query {
+	return readOnlyQuery{q.Query.Prefetch(p)}
+}
+
+func (q readOnlyQuery) Select(selector interface{}) mongo.Query {
+	return readOnlyQuery{q.Query.Select(selector)}
+}
+
+func (q readOnlyQuery) SetMaxScan(n int) mongo.Query {
+	return readOnlyQuery{q.Query.SetMaxScan(n)}
+}
+
+func (q readOnlyQuery) SetMaxTime(d time.Duration) mongo.Query {
+	return readOnlyQuery{q.Query.SetMaxTime(d)}
+}
+
+func (q readOnlyQuery) Skip(n int) mongo.Query {
+	return readOnlyQuery{q.Query.Skip(n)}
+}
+
+func (q readOnlyQuery) Snapshot() mongo.Query {
+	return readOnlyQuery{q.Query.Snapshot()}
+}
+
+func (q readOnlyQuery) Sort(fields ...string) mongo.Query {
+	return readOnlyQuery{q.Query.Sort(fields...)}
+}
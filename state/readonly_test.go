@@ -0,0 +1,377 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/mongo"
+	"github.com/juju/juju/mongo/mongotest"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/status"
+	"github.com/juju/juju/tools"
+)
+
+type ReadOnlySuite struct {
+	ConnSuite
+	readOnly *state.State
+}
+
+var _ = gc.Suite(&ReadOnlySuite{})
+
+func (s *ReadOnlySuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	st, err := state.OpenReadOnly(
+		s.modelTag, s.State.ControllerTag(),
+		statetesting.NewMongoInfo(), mongotest.DialOpts(), nil,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	s.readOnly = st
+	s.AddCleanup(func(c *gc.C) {
+		c.Check(st.Close(), jc.ErrorIsNil)
+	})
+}
+
+func (s *ReadOnlySuite) txnCount(c *gc.C) int {
+	count, err := s.State.MongoSession().DB("juju").C("txns").Count()
+	c.Assert(err, jc.ErrorIsNil)
+	return count
+}
+
+func (s *ReadOnlySuite) TestReadOnly(c *gc.C) {
+	c.Check(s.State.ReadOnly(), jc.IsFalse)
+	c.Check(s.readOnly.ReadOnly(), jc.IsTrue)
+
+	st, err := s.readOnly.ForModel(s.modelTag)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	c.Check(st.ReadOnly(), jc.IsTrue)
+}
+
+func (s *ReadOnlySuite) TestReads(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	machine, err := s.readOnly.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = machine.InstanceId()
+	c.Check(err, jc.Satisfies, errors.IsNotProvisioned)
+
+	err = m.SetProvisioned("i-blah", "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	instId, err := machine.InstanceId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(instId, gc.Equals, instance.Id("i-blah"))
+
+	machines, err := s.readOnly.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(machines, gc.HasLen, 1)
+}
+
+func (s *ReadOnlySuite) TestWatchers(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	machine, err := s.readOnly.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := machine.Watch()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.readOnly, w)
+	wc.AssertOneChange()
+
+	err = m.SetAgentVersion(version.MustParseBinary("1.2.3-quantal-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
+
+func (s *ReadOnlySuite) TestAddMachine(c *gc.C) {
+	before := s.txnCount(c)
+	_, err := s.readOnly.AddMachine("quantal", state.JobHostUnits)
+	c.Check(err, gc.ErrorMatches, ".*state is read-only")
+	c.Check(s.txnCount(c), gc.Equals, before)
+}
+
+func (s *ReadOnlySuite) TestMachineMutators(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetParentLinkLayerDevicesBeforeTheirChildren([]state.LinkLayerDeviceArgs{{
+		Name: "eth0",
+		Type: state.EthernetDevice,
+	}, {
+		Name: "br-eth1",
+		Type: state.BridgeDevice,
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = m.AddAction("juju-run", map[string]interface{}{"command": "uptime", "timeout": 5})
+	c.Assert(err, jc.ErrorIsNil)
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, m.Id(), instance.LXD)
+	c.Assert(err, jc.ErrorIsNil)
+	dead, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dead.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	keyed, err := s.State.AddOneMachine(state.MachineTemplate{
+		Series:      "quantal",
+		Jobs:        []state.MachineJob{state.JobHostUnits},
+		CreationKey: "creation-key",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	locked, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = locked.CreateUpgradeSeriesLock([]string{"wordpress/0"}, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	preparing, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = preparing.CreateUpgradeSeriesLock([]string{"wordpress/1"}, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = preparing.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+
+	machine, err := s.readOnly.Machine(m.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	containerMachine, err := s.readOnly.Machine(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	deadMachine, err := s.readOnly.Machine(dead.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	keyedMachine, err := s.readOnly.Machine(keyed.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	lockedMachine, err := s.readOnly.Machine(locked.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	preparingMachine, err := s.readOnly.Machine(preparing.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	actions, err := machine.PendingActions()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, gc.HasLen, 1)
+
+	linkLayerDevice := state.LinkLayerDeviceArgs{
+		Name: "eth2",
+		Type: state.EthernetDevice,
+	}
+	deviceAddress := state.LinkLayerDeviceAddress{
+		DeviceName:   "eth0",
+		ConfigMethod: state.StaticAddress,
+		CIDRAddress:  "10.20.0.42/24",
+	}
+	mutators := []struct {
+		about  string
+		mutate func() error
+	}{{
+		"AddAction", func() error {
+			_, err := machine.AddAction("juju-run", map[string]interface{}{"command": "uptime", "timeout": 5})
+			return err
+		},
+	}, {
+		"CancelAction", func() error {
+			_, err := machine.CancelAction(actions[0])
+			return err
+		},
+	}, {
+		"ClearCreationKey", keyedMachine.ClearCreationKey,
+	}, {
+		"CreateUpgradeSeriesLock", func() error {
+			return machine.CreateUpgradeSeriesLock([]string{"wordpress/2"}, "xenial")
+		},
+	}, {
+		"Destroy", machine.Destroy,
+	}, {
+		"EnsureDead", machine.EnsureDead,
+	}, {
+		"ForceDestroy", machine.ForceDestroy,
+	}, {
+		"MarkForRemoval", deadMachine.MarkForRemoval,
+	}, {
+		"RecordAgentLogin", func() error {
+			return machine.RecordAgentLogin("10.0.0.1:1234", true)
+		},
+	}, {
+		"Remove", deadMachine.Remove,
+	}, {
+		"RemoveAllAddresses", machine.RemoveAllAddresses,
+	}, {
+		"RemoveAllLinkLayerDevices", machine.RemoveAllLinkLayerDevices,
+	}, {
+		"RemoveUpgradeSeriesLock", lockedMachine.RemoveUpgradeSeriesLock,
+	}, {
+		"RestartAgentPresence", func() error {
+			_, err := machine.RestartAgentPresence(nil)
+			return err
+		},
+	}, {
+		"SetAgentPresence", func() error {
+			_, err := machine.SetAgentPresence()
+			return err
+		},
+	}, {
+		"SetAgentPresencePolicy", func() error {
+			return machine.SetAgentPresencePolicy(state.AgentPresencePolicy{Mode: state.PresenceUnmonitored})
+		},
+	}, {
+		"SetAgentTools", func() error {
+			return machine.SetAgentTools(&tools.Tools{
+				Version: version.MustParseBinary("1.2.3-quantal-amd64"),
+				URL:     "http://example.com/tools.tgz",
+			})
+		},
+	}, {
+		"SetAgentVersion", func() error {
+			return machine.SetAgentVersion(version.MustParseBinary("1.2.3-quantal-amd64"))
+		},
+	}, {
+		"SetAnnotations", func() error {
+			return machine.SetAnnotations(map[string]string{"foo": "bar"})
+		},
+	}, {
+		"SetAppliedInstanceTags", func() error {
+			return machine.SetAppliedInstanceTags(map[string]string{"team": "web"})
+		},
+	}, {
+		"SetConstraints", func() error {
+			return machine.SetConstraints(constraints.MustParse("mem=4G"))
+		},
+	}, {
+		"SetContainerLinkLayerDevices", func() error {
+			return machine.SetContainerLinkLayerDevices(containerMachine)
+		},
+	}, {
+		"SetDevicesAddresses", func() error {
+			return machine.SetDevicesAddresses(deviceAddress)
+		},
+	}, {
+		"SetDevicesAddressesIdempotently", func() error {
+			return machine.SetDevicesAddressesIdempotently([]state.LinkLayerDeviceAddress{deviceAddress})
+		},
+	}, {
+		"SetHasVote", func() error {
+			return machine.SetHasVote(true)
+		},
+	}, {
+		"SetInstanceInfo", func() error {
+			return machine.SetInstanceInfo("i-blah", "fake-nonce", nil, nil, nil, nil, nil)
+		},
+	}, {
+		"SetInstanceStatus", func() error {
+			return machine.SetInstanceStatus(status.StatusInfo{Status: status.Running})
+		},
+	}, {
+		"SetInstanceTags", func() error {
+			return machine.SetInstanceTags(map[string]string{"team": "web"})
+		},
+	}, {
+		"SetLinkLayerDevices", func() error {
+			return machine.SetLinkLayerDevices(linkLayerDevice)
+		},
+	}, {
+		"SetMachineAddresses", func() error {
+			return machine.SetMachineAddresses(network.NewAddress("10.0.0.1"))
+		},
+	}, {
+		"SetMachineBlockDevices", func() error {
+			return machine.SetMachineBlockDevices(state.BlockDeviceInfo{DeviceName: "sda"})
+		},
+	}, {
+		"SetMongoPassword", func() error {
+			return machine.SetMongoPassword("foo")
+		},
+	}, {
+		"SetObservedNetworkConfig", func() error {
+			return machine.SetObservedNetworkConfig([]state.NetworkConfig{{
+				InterfaceName: "eth0",
+				Addresses:     []string{"10.0.0.1/24"},
+			}})
+		},
+	}, {
+		"SetParentLinkLayerDevicesBeforeTheirChildren", func() error {
+			return machine.SetParentLinkLayerDevicesBeforeTheirChildren([]state.LinkLayerDeviceArgs{linkLayerDevice})
+		},
+	}, {
+		"SetPassword", func() error {
+			return machine.SetPassword("password-1234567890")
+		},
+	}, {
+		"SetProviderAddresses", func() error {
+			return machine.SetProviderAddresses(network.NewAddress("10.0.0.1"))
+		},
+	}, {
+		"SetProvisioned", func() error {
+			return machine.SetProvisioned("i-blah", "fake-nonce", nil)
+		},
+	}, {
+		"SetProvisionerMetadata", func() error {
+			return machine.SetProvisionerMetadata(map[string]string{"apt-mirror": "http://mirror"})
+		},
+	}, {
+		"SetRebootFlag", func() error {
+			return machine.SetRebootFlag(true)
+		},
+	}, {
+		"SetStatus", func() error {
+			return machine.SetStatus(status.StatusInfo{Status: status.Started})
+		},
+	}, {
+		"SetStopMongoUntilVersion", func() error {
+			return machine.SetStopMongoUntilVersion(mongo.Version{Major: 3, Minor: 2})
+		},
+	}, {
+		"SetSupportedContainers", func() error {
+			return machine.SetSupportedContainers([]instance.ContainerType{instance.LXD})
+		},
+	}, {
+		"SetUpgradeSeriesStatus", func() error {
+			return lockedMachine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+		},
+	}, {
+		"SetUpgradeSeriesUnitStatus", func() error {
+			return preparingMachine.SetUpgradeSeriesUnitStatus("wordpress/1", state.UpgradeSeriesPrepareStarted)
+		},
+	}, {
+		"SupportsNoContainers", machine.SupportsNoContainers,
+	}}
+
+	before := s.txnCount(c)
+	for i, test := range mutators {
+		c.Logf("test %d: %s", i, test.about)
+		err := test.mutate()
+		c.Check(err, gc.ErrorMatches, "(.*: )?state is read-only")
+	}
+	c.Check(s.txnCount(c), gc.Equals, before)
+
+	// The machine is unchanged.
+	err = m.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(m.Life(), gc.Equals, state.Alive)
+	c.Check(m.CheckProvisioned("fake-nonce"), jc.IsFalse)
+	annotations, err := m.Annotations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(annotations, gc.HasLen, 0)
+	history, err := m.AgentLoginHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(history, gc.HasLen, 0)
+	err = keyed.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(keyed.CreationKey(), gc.Equals, "creation-key")
+	isLocked, err := m.IsLockedForSeriesUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(isLocked, jc.IsFalse)
+	lockStatus, err := locked.UpgradeSeriesStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(lockStatus, gc.Equals, state.UpgradeSeriesNotStarted)
+	unitStatuses, err := preparing.UpgradeSeriesUnitStatuses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(unitStatuses, jc.DeepEquals, map[string]state.UpgradeSeriesStatus{
+		"wordpress/1": state.UpgradeSeriesNotStarted,
+	})
+}
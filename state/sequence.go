@@ -38,3 +38,48 @@ func (s *State) sequence(name string) (int, error) {
 	}
 	return result.Counter, nil
 }
+
+// currentSequence returns the value that the next call to sequence
+// with the supplied name will return, without changing it.
+func (s *State) currentSequence(name string) (int, error) {
+	sequences, closer := s.getCollection(sequenceC)
+	defer closer()
+	var doc sequenceDoc
+	err := sequences.FindId(name).One(&doc)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return -1, fmt.Errorf("cannot read %q sequence number: %v", name, err)
+	}
+	return doc.Counter, nil
+}
+
+// ensureSequenceAbove makes sure that the sequence with the supplied
+// name will never return value, or anything lower.
+func (s *State) ensureSequenceAbove(name string, value int) error {
+	sequences, closer := s.getCollection(sequenceC)
+	defer closer()
+	query := sequences.Find(bson.D{
+		{"_id", name},
+		{"counter", bson.D{{"$lte", value}}},
+	})
+	set := mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				"name":       name,
+				"model-uuid": s.ModelUUID(),
+				"counter":    value + 1,
+			},
+		},
+		Upsert: true,
+	}
+	_, err := query.Apply(set, &sequenceDoc{})
+	if mgo.IsDup(err) {
+		// The sequence document exists, and is already beyond value,
+		// so the upsert tried to insert a duplicate.
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("cannot update %q sequence number: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+
+	jujutxn "github.com/juju/txn"
+	"github.com/juju/utils/clock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/mongo"
+)
+
+// slowOpCollections holds the names of the collections whose
+// operations are timed when slow operation logging is enabled.
+var slowOpCollections = map[string]bool{
+	machinesC: true,
+	unitsC:    true,
+}
+
+// maxSlowOpSummary is the length at which the description of a slow
+// operation's selector is truncated.
+const maxSlowOpSummary = 200
+
+// SetSlowOpThreshold causes st to log, at warning level, any query or
+// transaction on the machines or units collections that takes longer
+// than threshold. The log message names the State method responsible,
+// using the operation names reported to the TxnObserver where they
+// are known, along with the duration and a summary of the selector or
+// transaction. A threshold of zero, the default, disables logging.
+//
+// Queries are timed until their results are read, except those read
+// with Iter or Tail, which are not timed; raw writes, which bypass
+// transactions, are not timed either.
+//
+// The threshold is not inherited by States returned from ForModel.
+func (st *State) SetSlowOpThreshold(threshold time.Duration) {
+	st.slowOpMu.Lock()
+	defer st.slowOpMu.Unlock()
+	st.slowOpThreshold = threshold
+}
+
+func (st *State) getSlowOpThreshold() time.Duration {
+	st.slowOpMu.Lock()
+	defer st.slowOpMu.Unlock()
+	return st.slowOpThreshold
+}
+
+// timedCollection returns coll, wrapped such that slow queries on it
+// are logged, if slow operation logging is enabled and the collection
+// is one whose operations are timed.
+func (st *State) timedCollection(coll mongo.Collection) mongo.Collection {
+	if !slowOpCollections[coll.Name()] {
+		return coll
+	}
+	threshold := st.getSlowOpThreshold()
+	if threshold <= 0 {
+		return coll
+	}
+	return newSlowOpCollection(coll, slowOpTimer{threshold: threshold, clock: st.clock})
+}
+
+// timedRunner returns runner, wrapped such that slow transactions
+// that touch a timed collection are logged as part of the named
+// operation, if slow operation logging is enabled.
+func (st *State) timedRunner(operation string, runner jujutxn.Runner) jujutxn.Runner {
+	threshold := st.getSlowOpThreshold()
+	if threshold <= 0 {
+		return runner
+	}
+	return &slowOpRunner{
+		Runner:    runner,
+		operation: operation,
+		timer:     slowOpTimer{threshold: threshold, clock: st.clock},
+	}
+}
+
+// slowOpTimer times operations, to find those that are slow.
+type slowOpTimer struct {
+	threshold time.Duration
+	clock     clock.Clock
+}
+
+// since returns the time since started, and whether that is longer
+// than the threshold.
+func (t slowOpTimer) since(started time.Time) (time.Duration, bool) {
+	took := t.clock.Now().Sub(started)
+	return took, took > t.threshold
+}
+
+// logSlowOp logs a slow operation of the given kind on the named
+// collection, made by the State method with the given label.
+func logSlowOp(kind, collection, label string, took time.Duration, summary string) {
+	logger.Warningf("slow %s on %s by %s took %v: %s", kind, collection, label, took, truncateSummary(summary))
+}
+
+func truncateSummary(summary string) string {
+	if len(summary) <= maxSlowOpSummary {
+		return summary
+	}
+	return summary[:maxSlowOpSummary] + "..."
+}
+
+// slowOpPlumbing holds the names of the files whose functions only
+// pass operations on to the database, and so are never the State
+// method responsible for an operation.
+var slowOpPlumbing = []string{"/slowops.go", "/txnobserver.go", "/txns.go"}
+
+// callerLabel returns the name of the State method that called into
+// the collection or transaction runner: the innermost exported
+// function or method of this package on the stack, or failing that,
+// the innermost function of this package. Only the name is returned,
+// without the receiver, to match the operation names given to
+// transactions.
+func callerLabel() string {
+	const statePackage = "github.com/juju/juju/state."
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	var fallback string
+	for _, pc := range pcs[:n] {
+		fn := runtime.FuncForPC(pc - 1)
+		if fn == nil || isSlowOpPlumbing(fn) {
+			continue
+		}
+		name := fn.Name()
+		if !strings.HasPrefix(name, statePackage) {
+			continue
+		}
+		name = name[strings.LastIndex(name, ".")+1:]
+		if name == "" || strings.HasPrefix(name, "func") {
+			continue
+		}
+		if fallback == "" {
+			fallback = name
+		}
+		if unicode.IsUpper(rune(name[0])) {
+			return name
+		}
+	}
+	if fallback == "" {
+		return "unknown"
+	}
+	return fallback
+}
+
+func isSlowOpPlumbing(fn *runtime.Func) bool {
+	file, _ := fn.FileLine(fn.Entry())
+	for _, suffix := range slowOpPlumbing {
+		if strings.HasSuffix(file, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// slowOpCollection is a mongo.Collection whose queries are timed.
+type slowOpCollection struct {
+	mongo.Collection
+	timer slowOpTimer
+}
+
+func newSlowOpCollection(coll mongo.Collection, timer slowOpTimer) *slowOpCollection {
+	return &slowOpCollection{Collection: coll, timer: timer}
+}
+
+// Count is part of the mongo.Collection interface.
+func (c *slowOpCollection) Count() (int, error) {
+	started := c.timer.clock.Now()
+	defer func() {
+		if took, slow := c.timer.since(started); slow {
+			logSlowOp("query", c.Name(), callerLabel(), took, "count all")
+		}
+	}()
+	return c.Collection.Count()
+}
+
+// Find is part of the mongo.Collection interface.
+func (c *slowOpCollection) Find(query interface{}) mongo.Query {
+	return &slowOpQuery{
+		Query:      c.Collection.Find(query),
+		collection: c.Name(),
+		selector:   query,
+		timer:      c.timer,
+	}
+}
+
+// FindId is part of the mongo.Collection interface.
+func (c *slowOpCollection) FindId(id interface{}) mongo.Query {
+	return &slowOpQuery{
+		Query:      c.Collection.FindId(id),
+		collection: c.Name(),
+		selector:   bson.D{{"_id", id}},
+		timer:      c.timer,
+	}
+}
+
+// slowOpQuery is a mongo.Query whose results are timed as they are
+// read.
+type slowOpQuery struct {
+	mongo.Query
+	collection string
+	selector   interface{}
+	timer      slowOpTimer
+}
+
+func (q *slowOpQuery) with(query mongo.Query) mongo.Query {
+	wrapped := *q
+	wrapped.Query = query
+	return &wrapped
+}
+
+// time returns a function to be deferred by the methods that read the
+// results of the query, which logs the query if it was slow.
+func (q *slowOpQuery) time() func() {
+	started := q.timer.clock.Now()
+	return func() {
+		if took, slow := q.timer.since(started); slow {
+			logSlowOp("query", q.collection, callerLabel(), took, q.summary())
+		}
+	}
+}
+
+func (q *slowOpQuery) summary() string {
+	if q.selector == nil {
+		return "all"
+	}
+	return fmt.Sprint(q.selector)
+}
+
+// All is part of the mongo.Query interface.
+func (q *slowOpQuery) All(result interface{}) error {
+	defer q.time()()
+	return q.Query.All(result)
+}
+
+// Apply is part of the mongo.Query interface.
+func (q *slowOpQuery) Apply(change mgo.Change, result interface{}) (*mgo.ChangeInfo, error) {
+	defer q.time()()
+	return q.Query.Apply(change, result)
+}
+
+// Count is part of the mongo.Query interface.
+func (q *slowOpQuery) Count() (int, error) {
+	defer q.time()()
+	return q.Query.Count()
+}
+
+// Distinct is part of the mongo.Query interface.
+func (q *slowOpQuery) Distinct(key string, result interface{}) error {
+	defer q.time()()
+	return q.Query.Distinct(key, result)
+}
+
+// For is part of the mongo.Query interface.
+func (q *slowOpQuery) For(result interface{}, f func() error) error {
+	defer q.time()()
+	return q.Query.For(result, f)
+}
+
+// One is part of the mongo.Query interface.
+func (q *slowOpQuery) One(result interface{}) error {
+	defer q.time()()
+	return q.Query.One(result)
+}
+
+// Batch is part of the mongo.Query interface.
+func (q *slowOpQuery) Batch(n int) mongo.Query {
+	return q.with(q.Query.Batch(n))
+}
+
+// Comment is part of the mongo.Query interface.
+func (q *slowOpQuery) Comment(comment string) mongo.Query {
+	return q.with(q.Query.Comment(comment))
+}
+
+// Hint is part of the mongo.Query interface.
+func (q *slowOpQuery) Hint(indexKey ...string) mongo.Query {
+	return q.with(q.Query.Hint(indexKey...))
+}
+
+// Limit is part of the mongo.Query interface.
+func (q *slowOpQuery) Limit(n int) mongo.Query {
+	return q.with(q.Query.Limit(n))
+}
+
+// LogReplay is part of the mongo.Query interface.
+func (q *slowOpQuery) LogReplay() mongo.Query {
+	return q.with(q.Query.LogReplay())
+}
+
+// Prefetch is part of the mongo.Query interface.
+func (q *slowOpQuery) Prefetch(p float64) mongo.Query {
+	return q.with(q.Query.Prefetch(p))
+}
+
+// Select is part of the mongo.Query interface.
+func (q *slowOpQuery) Select(selector interface{}) mongo.Query {
+	return q.with(q.Query.Select(selector))
+}
+
+// SetMaxScan is part of the mongo.Query interface.
+func (q *slowOpQuery) SetMaxScan(n int) mongo.Query {
+	return q.with(q.Query.SetMaxScan(n))
+}
+
+// SetMaxTime is part of the mongo.Query interface.
+func (q *slowOpQuery) SetMaxTime(d time.Duration) mongo.Query {
+	return q.with(q.Query.SetMaxTime(d))
+}
+
+// Skip is part of the mongo.Query interface.
+func (q *slowOpQuery) Skip(n int) mongo.Query {
+	return q.with(q.Query.Skip(n))
+}
+
+// Snapshot is part of the mongo.Query interface.
+func (q *slowOpQuery) Snapshot() mongo.Query {
+	return q.with(q.Query.Snapshot())
+}
+
+// Sort is part of the mongo.Query interface.
+func (q *slowOpQuery) Sort(fields ...string) mongo.Query {
+	return q.with(q.Query.Sort(fields...))
+}
+
+// slowOpRunner is a jujutxn.Runner whose transactions are timed, and
+// logged if slow and they touch a timed collection.
+type slowOpRunner struct {
+	jujutxn.Runner
+	operation string
+	timer     slowOpTimer
+}
+
+// RunTransaction is part of the jujutxn.Runner interface.
+func (r *slowOpRunner) RunTransaction(ops []txn.Op) error {
+	defer r.time(func() []txn.Op { return ops })()
+	return r.Runner.RunTransaction(ops)
+}
+
+// Run is part of the jujutxn.Runner interface. Where the operations
+// are rebuilt after a conflict, the time of every attempt is counted,
+// and the final attempt is described.
+func (r *slowOpRunner) Run(transactions jujutxn.TransactionSource) error {
+	var lastOps []txn.Op
+	defer r.time(func() []txn.Op { return lastOps })()
+	return r.Runner.Run(func(attempt int) ([]txn.Op, error) {
+		ops, err := transactions(attempt)
+		if err == nil {
+			lastOps = ops
+		}
+		return ops, err
+	})
+}
+
+// time returns a function to be deferred by the methods that run
+// transactions, which logs the transaction described by ops if it was
+// slow and it touched a timed collection.
+func (r *slowOpRunner) time(ops func() []txn.Op) func() {
+	started := r.timer.clock.Now()
+	return func() {
+		took, slow := r.timer.since(started)
+		if !slow {
+			return
+		}
+		ops := ops()
+		collection := timedCollectionOf(ops)
+		if collection == "" {
+			return
+		}
+		label := r.operation
+		if label == "" {
+			label = callerLabel()
+		}
+		logSlowOp("txn", collection, label, took, summariseSlowTxn(ops))
+	}
+}
+
+// timedCollectionOf returns the name of the first timed collection
+// touched by ops, or "" if there is none.
+func timedCollectionOf(ops []txn.Op) string {
+	for _, op := range ops {
+		if slowOpCollections[op.C] {
+			return op.C
+		}
+	}
+	return ""
+}
+
+// summariseSlowTxn describes the operations of a slow transaction by
+// the documents they touch. Their content is left out, as it may
+// hold secrets.
+func summariseSlowTxn(ops []txn.Op) string {
+	summaries := make([]string, len(ops))
+	for i, op := range ops {
+		summaries[i] = fmt.Sprintf("%s %s %v", txnOpRecord(op).Kind, op.C, op.Id)
+	}
+	return strings.Join(summaries, ", ")
+}
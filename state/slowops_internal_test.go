@@ -0,0 +1,214 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/loggo"
+	jujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	jujutxn "github.com/juju/txn"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/mongo"
+)
+
+type slowOpsSuite struct {
+	internalStateSuite
+	clock *jujutesting.Clock
+	log   *loggo.TestWriter
+}
+
+var _ = gc.Suite(&slowOpsSuite{})
+
+func (s *slowOpsSuite) SetUpTest(c *gc.C) {
+	s.internalStateSuite.SetUpTest(c)
+	s.clock = jujutesting.NewClock(time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC))
+	s.log = &loggo.TestWriter{}
+	c.Assert(loggo.RegisterWriter("slowops-tester", s.log), jc.ErrorIsNil)
+	s.AddCleanup(func(*gc.C) { loggo.RemoveWriter("slowops-tester") })
+}
+
+func (s *slowOpsSuite) timer() slowOpTimer {
+	return slowOpTimer{threshold: time.Second, clock: s.clock}
+}
+
+// delayedCollection is a mongo.Collection whose queries take the given
+// time, as measured by a testing clock.
+type delayedCollection struct {
+	mongo.Collection
+	name  string
+	clock *jujutesting.Clock
+	delay time.Duration
+}
+
+func (c *delayedCollection) Name() string {
+	return c.name
+}
+
+func (c *delayedCollection) Find(query interface{}) mongo.Query {
+	return &delayedQuery{c: c}
+}
+
+func (c *delayedCollection) FindId(id interface{}) mongo.Query {
+	return &delayedQuery{c: c}
+}
+
+type delayedQuery struct {
+	mongo.Query
+	c *delayedCollection
+}
+
+func (q *delayedQuery) Select(selector interface{}) mongo.Query {
+	return q
+}
+
+func (q *delayedQuery) One(result interface{}) error {
+	q.c.clock.Advance(q.c.delay)
+	return nil
+}
+
+// delayedRunner is a jujutxn.Runner whose transactions take the given
+// time, as measured by a testing clock.
+type delayedRunner struct {
+	jujutxn.Runner
+	clock *jujutesting.Clock
+	delay time.Duration
+}
+
+func (r *delayedRunner) RunTransaction(ops []txn.Op) error {
+	r.clock.Advance(r.delay)
+	return nil
+}
+
+func (r *delayedRunner) Run(transactions jujutxn.TransactionSource) error {
+	ops, err := transactions(0)
+	if err != nil {
+		return err
+	}
+	return r.RunTransaction(ops)
+}
+
+// slowMachine stands in for a State type whose exported methods read
+// from a timed collection.
+type slowMachine struct {
+	machines mongo.Collection
+}
+
+func (m slowMachine) Refresh() error {
+	return m.machines.FindId("0").Select(bson.D{{"life", 1}}).One(&bson.M{})
+}
+
+func (s *slowOpsSuite) TestSlowQueryLogged(c *gc.C) {
+	coll := &delayedCollection{name: machinesC, clock: s.clock, delay: 1500 * time.Millisecond}
+	m := slowMachine{machines: newSlowOpCollection(coll, s.timer())}
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.log.Log(), jc.LogMatches, jc.SimpleMessages{{
+		loggo.WARNING,
+		`slow query on machines by Refresh took 1.5s: \[\{_id 0\}\]`,
+	}})
+}
+
+func (s *slowOpsSuite) TestFastQueryNotLogged(c *gc.C) {
+	coll := &delayedCollection{name: machinesC, clock: s.clock, delay: time.Second}
+	m := slowMachine{machines: newSlowOpCollection(coll, s.timer())}
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.log.Log(), gc.HasLen, 0)
+}
+
+func (s *slowOpsSuite) TestSlowTxnLogged(c *gc.C) {
+	runner := &slowOpRunner{
+		Runner:    &delayedRunner{clock: s.clock, delay: 2 * time.Second},
+		operation: "EnsureDead",
+		timer:     s.timer(),
+	}
+	err := runner.Run(func(int) ([]txn.Op, error) {
+		return []txn.Op{{
+			C:      machinesC,
+			Id:     "uuid:0",
+			Assert: bson.D{{"life", Dying}},
+			Update: bson.D{{"$set", bson.D{{"life", Dead}}}},
+		}, {
+			C:      unitsC,
+			Id:     "uuid:wordpress/0",
+			Assert: txn.DocExists,
+		}}, nil
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.log.Log(), jc.LogMatches, jc.SimpleMessages{{
+		loggo.WARNING,
+		`slow txn on machines by EnsureDead took 2s: update machines uuid:0, assert units uuid:wordpress/0`,
+	}})
+}
+
+func (s *slowOpsSuite) TestSlowTxnOnOtherCollectionsNotLogged(c *gc.C) {
+	runner := &slowOpRunner{
+		Runner:    &delayedRunner{clock: s.clock, delay: 2 * time.Second},
+		operation: "SetConstraints",
+		timer:     s.timer(),
+	}
+	err := runner.RunTransaction([]txn.Op{{
+		C:      settingsC,
+		Id:     "uuid:a#wordpress",
+		Assert: txn.DocExists,
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.log.Log(), gc.HasLen, 0)
+}
+
+func (s *slowOpsSuite) TestSummaryTruncated(c *gc.C) {
+	summary := truncateSummary(string(make([]byte, maxSlowOpSummary+10)))
+	c.Assert(summary, gc.HasLen, maxSlowOpSummary+len("..."))
+}
+
+func (s *slowOpsSuite) TestDisabledByDefault(c *gc.C) {
+	machines, closer := s.state.getCollection(machinesC)
+	defer closer()
+	_, timed := machines.(*slowOpCollection)
+	c.Assert(timed, jc.IsFalse)
+
+	runner, closer := s.state.database.TransactionRunner()
+	defer closer()
+	c.Assert(s.state.observedRunner("", runner), gc.Equals, runner)
+}
+
+func (s *slowOpsSuite) TestEnabledForTimedCollections(c *gc.C) {
+	s.state.SetSlowOpThreshold(time.Second)
+
+	machines, closer := s.state.getCollection(machinesC)
+	defer closer()
+	_, timed := machines.(*slowOpCollection)
+	c.Assert(timed, jc.IsTrue)
+
+	units, closer := s.state.getCollection(unitsC)
+	defer closer()
+	_, timed = units.(*slowOpCollection)
+	c.Assert(timed, jc.IsTrue)
+
+	settings, closer := s.state.getCollection(settingsC)
+	defer closer()
+	_, timed = settings.(*slowOpCollection)
+	c.Assert(timed, jc.IsFalse)
+
+	s.state.SetSlowOpThreshold(0)
+	machines, closer = s.state.getCollection(machinesC)
+	defer closer()
+	_, timed = machines.(*slowOpCollection)
+	c.Assert(timed, jc.IsFalse)
+}
+
+func (s *slowOpsSuite) TestMachineRefreshLabelled(c *gc.C) {
+	m, err := s.state.AddMachine("quantal", JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.state.SetSlowOpThreshold(time.Nanosecond)
+
+	c.Assert(m.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.log.Log(), jc.LogMatches, jc.SimpleMessages{{
+		loggo.WARNING,
+		`slow query on machines by Refresh took .*: \[\{_id 0\}\]`,
+	}})
+}
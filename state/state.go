@@ -86,6 +86,13 @@ type State struct {
 	policy             Policy
 	newPolicy          NewPolicyFunc
 
+	// readOnly is true if the State was opened with OpenReadOnly,
+	// and must not change the database.
+	readOnly bool
+
+	// limits holds the maximum sizes of values written by the State.
+	limits DocumentLimits
+
 	// cloudName is the name of the cloud on which the model
 	// represented by this state runs.
 	cloudName string
@@ -104,6 +111,26 @@ type State struct {
 	// folded in as well, but that feels like its own task.
 	workers workers.Workers
 
+	// agentSeen records when machine agents were last seen alive,
+	// for their agent presence policies.
+	agentSeen agentSeenTracker
+
+	// machineIdMu guards machineIdPolicy.
+	machineIdMu     sync.Mutex
+	machineIdPolicy MachineIdPolicy
+
+	// txnObserverMu guards txnObserver.
+	txnObserverMu sync.Mutex
+	txnObserver   TxnObserver
+
+	// machinePrecheckerMu guards machinePrechecker.
+	machinePrecheckerMu sync.Mutex
+	machinePrechecker   MachinePrechecker
+
+	// slowOpMu guards slowOpThreshold.
+	slowOpMu        sync.Mutex
+	slowOpThreshold time.Duration
+
 	// mu guards allManager, allModelManager & allModelWatcherBacking
 	mu                     sync.Mutex
 	allManager             *storeManager
@@ -303,7 +330,7 @@ func (st *State) removeInCollectionOps(name string, sel interface{}) ([]txn.Op,
 func (st *State) ForModel(modelTag names.ModelTag) (*State, error) {
 	session := st.session.Copy()
 	newSt, err := newState(
-		modelTag, st.controllerModelTag, session, st.mongoInfo, st.newPolicy, st.clock,
+		modelTag, st.controllerModelTag, session, st.mongoInfo, st.newPolicy, st.clock, st.readOnly, st.limits,
 	)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -440,6 +467,12 @@ func (st *State) getSingularLeaseClient() (lease.Client, error) {
 	return client, nil
 }
 
+// ReadOnly returns whether the State was opened with OpenReadOnly,
+// and so refuses to change the database.
+func (st *State) ReadOnly() bool {
+	return st.readOnly
+}
+
 // ModelTag() returns the model tag for the model controlled by
 // this state instance.
 func (st *State) ModelTag() names.ModelTag {
@@ -2307,6 +2307,54 @@ func (s *StateSuite) TestWatchMachineHardwareCharacteristics(c *gc.C) {
 	wc.AssertNoChange()
 }
 
+func (s *StateSuite) TestWatchMachineInstanceIdProvisionedLater(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	w := machine.WatchInstanceId()
+	defer statetesting.AssertStop(c, w)
+
+	// Not yet provisioned: no initial event.
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertNoChange()
+
+	// Alter the machine: not reported.
+	err = machine.SetAgentVersion(version.MustParseBinary("1.2.3-quantal-ppc"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Provision the machine: reported.
+	err = machine.SetProvisioned(instance.Id("i-blah"), "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Alter the machine again: not reported.
+	err = machine.SetAgentVersion(version.MustParseBinary("1.2.4-quantal-ppc"))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+}
+
+func (s *StateSuite) TestWatchMachineInstanceIdAlreadyProvisioned(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.SetProvisioned(instance.Id("i-blah"), "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	w := machine.WatchInstanceId()
+	defer statetesting.AssertStop(c, w)
+
+	// Already provisioned: initial event.
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Remove the machine: not reported.
+	err = machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.Cleanup()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+}
+
 func (s *StateSuite) TestWatchControllerInfo(c *gc.C) {
 	_, err := s.State.AddMachine("quantal", state.JobManageModel)
 	c.Assert(err, jc.ErrorIsNil)
@@ -2582,6 +2630,41 @@ func (s *StateSuite) TestWatchForModelConfigChanges(c *gc.C) {
 	wc.AssertNoChange()
 }
 
+func (s *StateSuite) TestWatchForModelConfigKeyChanges(c *gc.C) {
+	w := s.State.WatchForModelConfigKeyChanges("logging-config", "http-proxy")
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	// Initially we get one change notification
+	wc.AssertOneChange()
+
+	// Changing an unwatched key does not trigger a change notification
+	err := s.State.UpdateModelConfig(attrs{"default-series": "xenial"}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Changing either watched key does
+	err = s.State.UpdateModelConfig(attrs{"logging-config": "<root>=DEBUG"}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = s.State.UpdateModelConfig(attrs{
+		"http-proxy":     "http://proxy.example.com:3128",
+		"default-series": "trusty",
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Setting a watched key to the same value does not
+	err = s.State.UpdateModelConfig(attrs{"logging-config": "<root>=DEBUG"}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	cfg, err := s.State.ModelConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.AllAttrs()["http-proxy"], gc.Equals, "http://proxy.example.com:3128")
+}
+
 func (s *StateSuite) TestWatchForModelConfigControllerChanges(c *gc.C) {
 	w := s.State.WatchForModelConfigChanges()
 	defer statetesting.AssertStop(c, w)
@@ -108,6 +108,12 @@ func setStatus(st *State, params setStatusParams) (err error) {
 		StatusData: utils.EscapeKeys(params.rawData),
 		Updated:    params.updated.UnixNano(),
 	}
+	if err := checkSize("status message", doc.StatusInfo, st.limits.StatusMessage); err != nil {
+		return errors.Trace(err)
+	}
+	if err := checkSize("status data", doc.StatusData, st.limits.StatusData); err != nil {
+		return errors.Trace(err)
+	}
 	probablyUpdateStatusHistory(st, params.globalKey, doc)
 
 	// Set the authoritative status document, or fail trying.
@@ -0,0 +1,63 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package testing
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/state"
+)
+
+// MachineCheck records a call to a MachinePrechecker.
+type MachineCheck struct {
+	MachineId string
+	Operation state.MachineOperation
+}
+
+// VetoingMachinePrechecker is a state.MachinePrechecker that vetoes
+// every operation on the machines whose ids are in Vetoed, and records
+// the checks it is asked to make.
+type VetoingMachinePrechecker struct {
+	Vetoed set.Strings
+
+	mu     sync.Mutex
+	checks []MachineCheck
+}
+
+// NewVetoingMachinePrechecker returns a VetoingMachinePrechecker that
+// vetoes operations on the machines with the given ids.
+func NewVetoingMachinePrechecker(ids ...string) *VetoingMachinePrechecker {
+	return &VetoingMachinePrechecker{Vetoed: set.NewStrings(ids...)}
+}
+
+// PrecheckMachine is part of the state.MachinePrechecker interface.
+func (p *VetoingMachinePrechecker) PrecheckMachine(machine state.MachineView, change state.MachineChange) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checks = append(p.checks, MachineCheck{
+		MachineId: machine.Id(),
+		Operation: change.Operation,
+	})
+	if p.Vetoed.Contains(machine.Id()) {
+		return fmt.Errorf("%s of machine %s vetoed", change.Operation, machine.Id())
+	}
+	return nil
+}
+
+// Checks returns the checks made so far, oldest first.
+func (p *VetoingMachinePrechecker) Checks() []MachineCheck {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MachineCheck(nil), p.checks...)
+}
+
+// Reset discards the checks made so far.
+func (p *VetoingMachinePrechecker) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checks = nil
+}
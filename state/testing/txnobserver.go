@@ -0,0 +1,38 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package testing
+
+import (
+	"sync"
+
+	"github.com/juju/juju/state"
+)
+
+// TxnBuffer is a state.TxnObserver that records the transactions it
+// observes in memory.
+type TxnBuffer struct {
+	mu      sync.Mutex
+	records []state.TxnRecord
+}
+
+// ObserveTxn is part of the state.TxnObserver interface.
+func (b *TxnBuffer) ObserveTxn(record state.TxnRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = append(b.records, record)
+}
+
+// Records returns the transactions observed so far, oldest first.
+func (b *TxnBuffer) Records() []state.TxnRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]state.TxnRecord(nil), b.records...)
+}
+
+// Reset discards the transactions observed so far.
+func (b *TxnBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	jujutxn "github.com/juju/txn"
+	"github.com/juju/utils/clock"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// TxnObserver is notified of the transactions run by a State. See
+// State.SetTxnObserver.
+type TxnObserver interface {
+	// ObserveTxn is called synchronously after each transaction
+	// run by the State completes, whether or not it succeeded.
+	ObserveTxn(TxnRecord)
+}
+
+// TxnRecord describes a transaction run by a State.
+type TxnRecord struct {
+	// Time is the time at which the transaction completed.
+	Time time.Time
+
+	// Operation names the State method that ran the transaction,
+	// such as "SetProvisioned" or "EnsureDead". It is empty for
+	// transactions run by methods that do not name themselves.
+	Operation string
+
+	// Ops describes the operations in the transaction, in order.
+	// Where the operations were rebuilt after a conflict, only the
+	// final attempt is described.
+	Ops []TxnOpRecord
+
+	// Err holds the error returned by the transaction runner.
+	Err error
+}
+
+// Kinds of txn.Op, as recorded in TxnOpRecord.Kind.
+const (
+	TxnOpAssert = "assert"
+	TxnOpInsert = "insert"
+	TxnOpUpdate = "update"
+	TxnOpRemove = "remove"
+)
+
+// TxnOpRecord describes a single txn.Op in a TxnRecord.
+type TxnOpRecord struct {
+	// C and Id identify the document the operation applies to.
+	C  string
+	Id interface{}
+
+	// Kind is one of TxnOpAssert, TxnOpInsert, TxnOpUpdate or
+	// TxnOpRemove.
+	Kind string
+
+	// AssertsLife is true if the operation asserts the life of
+	// the document.
+	AssertsLife bool
+
+	// Summary describes the inserted document or the update, with
+	// the values of password fields redacted.
+	Summary string
+}
+
+// redactedValue replaces the values of redacted fields in the
+// summaries of TxnOpRecords.
+const redactedValue = "<redacted>"
+
+// SetTxnObserver sets the observer notified of every transaction run
+// by st. A nil observer, the default, disables notification.
+//
+// The observer is not inherited by States returned from ForModel.
+func (st *State) SetTxnObserver(observer TxnObserver) {
+	st.txnObserverMu.Lock()
+	defer st.txnObserverMu.Unlock()
+	st.txnObserver = observer
+}
+
+// observedRunner returns runner, wrapped such that the transactions it
+// runs are reported to st's TxnObserver as part of the named operation,
+// and timed as described in SetSlowOpThreshold. If st has no
+// TxnObserver and slow operations are not logged, runner is returned
+// unchanged.
+func (st *State) observedRunner(operation string, runner jujutxn.Runner) jujutxn.Runner {
+	runner = st.timedRunner(operation, runner)
+	st.txnObserverMu.Lock()
+	observer := st.txnObserver
+	st.txnObserverMu.Unlock()
+	if observer == nil {
+		return runner
+	}
+	return &observingRunner{
+		Runner:    runner,
+		observer:  observer,
+		operation: operation,
+		clock:     st.clock,
+	}
+}
+
+// observingRunner is a jujutxn.Runner that reports the transactions it
+// runs to a TxnObserver.
+type observingRunner struct {
+	jujutxn.Runner
+	observer  TxnObserver
+	operation string
+	clock     clock.Clock
+}
+
+// RunTransaction is part of the jujutxn.Runner interface.
+func (r *observingRunner) RunTransaction(ops []txn.Op) error {
+	err := r.Runner.RunTransaction(ops)
+	r.observe(ops, err)
+	return err
+}
+
+// Run is part of the jujutxn.Runner interface.
+func (r *observingRunner) Run(transactions jujutxn.TransactionSource) error {
+	var lastOps []txn.Op
+	err := r.Runner.Run(func(attempt int) ([]txn.Op, error) {
+		ops, err := transactions(attempt)
+		if err == nil {
+			lastOps = ops
+		}
+		return ops, err
+	})
+	if lastOps != nil {
+		r.observe(lastOps, err)
+	}
+	return err
+}
+
+func (r *observingRunner) observe(ops []txn.Op, err error) {
+	record := TxnRecord{
+		Time:      r.clock.Now(),
+		Operation: r.operation,
+		Ops:       make([]TxnOpRecord, len(ops)),
+		Err:       err,
+	}
+	for i, op := range ops {
+		record.Ops[i] = txnOpRecord(op)
+	}
+	r.observer.ObserveTxn(record)
+}
+
+func txnOpRecord(op txn.Op) TxnOpRecord {
+	record := TxnOpRecord{
+		C:           op.C,
+		Id:          op.Id,
+		Kind:        TxnOpAssert,
+		AssertsLife: assertsLife(op.Assert),
+	}
+	switch {
+	case op.Insert != nil:
+		record.Kind = TxnOpInsert
+		record.Summary = summariseTxnValue(op.Insert)
+	case op.Update != nil:
+		record.Kind = TxnOpUpdate
+		record.Summary = summariseTxnValue(op.Update)
+	case op.Remove:
+		record.Kind = TxnOpRemove
+	}
+	return record
+}
+
+// assertsLife returns whether the given txn.Op assertion includes a
+// condition on the document's life field.
+func assertsLife(assert interface{}) bool {
+	switch assert := assert.(type) {
+	case bson.D:
+		for _, elem := range assert {
+			if elem.Name == "life" {
+				return true
+			}
+		}
+	case bson.M:
+		_, ok := assert["life"]
+		return ok
+	}
+	return false
+}
+
+// summariseTxnValue returns a description of the given document or
+// update, in which the value of any field whose name mentions a
+// password is redacted.
+func summariseTxnValue(value interface{}) string {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("<cannot marshal %T: %v>", value, err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return fmt.Sprintf("<cannot unmarshal %T: %v>", value, err)
+	}
+	return fmt.Sprint(redactTxnValue(doc))
+}
+
+func redactTxnValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case bson.D:
+		redacted := make(bson.D, len(value))
+		for i, elem := range value {
+			if strings.Contains(strings.ToLower(elem.Name), "password") {
+				redacted[i] = bson.DocElem{elem.Name, redactedValue}
+			} else {
+				redacted[i] = bson.DocElem{elem.Name, redactTxnValue(elem.Value)}
+			}
+		}
+		return redacted
+	case bson.M:
+		redacted := make(bson.M, len(value))
+		for name, elem := range value {
+			if strings.Contains(strings.ToLower(name), "password") {
+				redacted[name] = redactedValue
+			} else {
+				redacted[name] = redactTxnValue(elem)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, elem := range value {
+			redacted[i] = redactTxnValue(elem)
+		}
+		return redacted
+	}
+	return value
+}
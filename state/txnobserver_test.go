@@ -0,0 +1,121 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type TxnObserverSuite struct {
+	ConnSuite
+	machine  *state.Machine
+	observed *statetesting.TxnBuffer
+}
+
+var _ = gc.Suite(&TxnObserverSuite{})
+
+func (s *TxnObserverSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.observed = &statetesting.TxnBuffer{}
+	s.State.SetTxnObserver(s.observed)
+}
+
+func (s *TxnObserverSuite) TestSetProvisioned(c *gc.C) {
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	records := s.observed.Records()
+	c.Assert(records, gc.HasLen, 1)
+	record := records[0]
+	c.Check(record.Operation, gc.Equals, "SetProvisioned")
+	c.Check(record.Err, jc.ErrorIsNil)
+	c.Check(record.Time.IsZero(), jc.IsFalse)
+	c.Assert(record.Ops, gc.HasLen, 2)
+
+	docID := state.DocID(s.State, s.machine.Id())
+	c.Check(record.Ops[0], jc.DeepEquals, state.TxnOpRecord{
+		C:           "machines",
+		Id:          docID,
+		Kind:        state.TxnOpUpdate,
+		AssertsLife: true,
+		Summary:     "[{$set [{nonce fake_nonce}]}]",
+	})
+	c.Check(record.Ops[1].C, gc.Equals, "instanceData")
+	c.Check(record.Ops[1].Id, gc.Equals, docID)
+	c.Check(record.Ops[1].Kind, gc.Equals, state.TxnOpInsert)
+	c.Check(record.Ops[1].AssertsLife, jc.IsFalse)
+	c.Check(record.Ops[1].Summary, jc.Contains, "{instanceid i-123}")
+}
+
+func (s *TxnObserverSuite) TestSetProvisionedFailure(c *gc.C) {
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.observed.Reset()
+
+	err = s.machine.SetProvisioned(instance.Id("i-456"), "other_nonce", nil)
+	c.Assert(err, gc.ErrorMatches, `cannot set instance data for machine "0": already set`)
+
+	records := s.observed.Records()
+	c.Assert(records, gc.HasLen, 1)
+	c.Check(records[0].Operation, gc.Equals, "SetProvisioned")
+	c.Check(records[0].Err, gc.ErrorMatches, "transaction aborted")
+}
+
+func (s *TxnObserverSuite) TestPasswordRedacted(c *gc.C) {
+	err := s.machine.SetPassword("foo-12345678901234567890")
+	c.Assert(err, jc.ErrorIsNil)
+
+	records := s.observed.Records()
+	c.Assert(records, gc.HasLen, 1)
+	c.Check(records[0].Operation, gc.Equals, "SetPassword")
+	c.Assert(records[0].Ops, gc.HasLen, 1)
+	c.Check(records[0].Ops[0].Summary, gc.Equals, "[{$set [{passwordhash <redacted>}]}]")
+}
+
+func (s *TxnObserverSuite) TestRedactsNestedPasswords(c *gc.C) {
+	summary := state.SummariseTxnValue(map[string]interface{}{
+		"name": "bob",
+		"auth": map[string]interface{}{"PasswordHash": "secret"},
+		"list": []interface{}{map[string]interface{}{"password": "secret"}},
+	})
+	c.Check(summary, gc.Not(jc.Contains), "secret")
+	c.Check(summary, jc.Contains, "bob")
+	c.Check(summary, jc.Contains, "{PasswordHash <redacted>}")
+	c.Check(summary, jc.Contains, "{password <redacted>}")
+}
+
+func (s *TxnObserverSuite) TestEnsureDead(c *gc.C) {
+	err := s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	// The machine is already dead, so no transaction is run.
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+
+	records := s.observed.Records()
+	c.Assert(records, gc.HasLen, 1)
+	c.Check(records[0].Operation, gc.Equals, "EnsureDead")
+	c.Check(records[0].Err, jc.ErrorIsNil)
+	c.Assert(records[0].Ops, gc.Not(gc.HasLen), 0)
+	c.Check(records[0].Ops[0].C, gc.Equals, "machines")
+	c.Check(records[0].Ops[0].AssertsLife, jc.IsTrue)
+}
+
+func (s *TxnObserverSuite) TestUnset(c *gc.C) {
+	s.State.SetTxnObserver(nil)
+	err := s.machine.SetProvisioned("i-123", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.observed.Records(), gc.HasLen, 0)
+
+	id, err := s.machine.InstanceId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, instance.Id("i-123"))
+}
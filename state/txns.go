@@ -24,9 +24,16 @@ func (st *State) readTxnRevno(collectionName string, id interface{}) (int64, err
 
 // runTransaction is a convenience method delegating to the state's Database.
 func (st *State) runTransaction(ops []txn.Op) error {
+	return st.runNamedTransaction("", ops)
+}
+
+// runNamedTransaction is like runTransaction, except that the
+// transaction is reported to the state's TxnObserver as part of the
+// named operation.
+func (st *State) runNamedTransaction(operation string, ops []txn.Op) error {
 	runner, closer := st.database.TransactionRunner()
 	defer closer()
-	return runner.RunTransaction(ops)
+	return st.observedRunner(operation, runner).RunTransaction(ops)
 }
 
 // runTransaction is a convenience method delegating to the state's Database
@@ -36,26 +43,39 @@ func (st *State) runTransactionFor(modelUUID string, ops []txn.Op) error {
 	defer dbcloser()
 	runner, closer := database.TransactionRunner()
 	defer closer()
-	return runner.RunTransaction(ops)
+	return st.observedRunner("", runner).RunTransaction(ops)
 }
 
 // runRawTransaction is a convenience method that will run a single
 // transaction using a "raw" transaction runner that won't perform
 // model filtering.
 func (st *State) runRawTransaction(ops []txn.Op) error {
+	return st.runNamedRawTransaction("", ops)
+}
+
+// runNamedRawTransaction is like runRawTransaction, except that the
+// transaction is reported to the state's TxnObserver as part of the
+// named operation.
+func (st *State) runNamedRawTransaction(operation string, ops []txn.Op) error {
 	runner, closer := st.database.TransactionRunner()
 	defer closer()
 	if multiRunner, ok := runner.(*multiModelRunner); ok {
 		runner = multiRunner.rawRunner
 	}
-	return runner.RunTransaction(ops)
+	return st.observedRunner(operation, runner).RunTransaction(ops)
 }
 
 // run is a convenience method delegating to the state's Database.
 func (st *State) run(transactions jujutxn.TransactionSource) error {
+	return st.runNamed("", transactions)
+}
+
+// runNamed is like run, except that the transactions are reported to
+// the state's TxnObserver as part of the named operation.
+func (st *State) runNamed(operation string, transactions jujutxn.TransactionSource) error {
 	runner, closer := st.database.TransactionRunner()
 	defer closer()
-	return runner.Run(transactions)
+	return st.observedRunner(operation, runner).Run(transactions)
 }
 
 // runForModel is a convenience method that delegates to a Database for a different
@@ -65,7 +85,7 @@ func (st *State) runForModel(modelUUID string, transactions jujutxn.TransactionS
 	defer dbcloser()
 	runner, closer := database.TransactionRunner()
 	defer closer()
-	return runner.Run(transactions)
+	return st.observedRunner("", runner).Run(transactions)
 }
 
 // ResumeTransactions resumes all pending transactions.
@@ -231,30 +231,17 @@ func (u *Unit) WorkloadVersionHistory() *HistoryGetter {
 // It an error that satisfies errors.IsNotFound if the tools have not
 // yet been set.
 func (u *Unit) AgentTools() (*tools.Tools, error) {
-	if u.doc.Tools == nil {
-		return nil, errors.NotFoundf("agent tools for unit %q", u)
-	}
-	tools := *u.doc.Tools
-	return &tools, nil
+	return agentTools(u.doc.Tools, fmt.Sprintf("unit %q", u))
 }
 
 // SetAgentVersion sets the version of juju that the agent is
 // currently running.
 func (u *Unit) SetAgentVersion(v version.Binary) (err error) {
 	defer errors.DeferredAnnotatef(&err, "cannot set agent version for unit %q", u)
-	if err = checkVersionValidity(v); err != nil {
+	tools, err := setAgentVersion(u.st.runTransaction, unitsC, u.doc.DocID, v)
+	if err != nil {
 		return err
 	}
-	tools := &tools.Tools{Version: v}
-	ops := []txn.Op{{
-		C:      unitsC,
-		Id:     u.doc.DocID,
-		Assert: notDeadDoc,
-		Update: bson.D{{"$set", bson.D{{"tools", tools}}}},
-	}}
-	if err := u.st.runTransaction(ops); err != nil {
-		return onAbort(err, ErrDead)
-	}
 	u.doc.Tools = tools
 	return nil
 }
@@ -1142,8 +1129,7 @@ func (u *Unit) SetCharmURL(curl *charm.URL) error {
 
 // AgentPresence returns whether the respective remote agent is alive.
 func (u *Unit) AgentPresence() (bool, error) {
-	pwatcher := u.st.workers.PresenceWatcher()
-	return pwatcher.Alive(u.globalAgentKey())
+	return agentPresence(u.st, u.globalAgentKey())
 }
 
 // Tag returns a name identifying the unit.
@@ -1162,36 +1148,13 @@ func (u *Unit) UnitTag() names.UnitTag {
 // WaitAgentPresence blocks until the respective agent is alive.
 func (u *Unit) WaitAgentPresence(timeout time.Duration) (err error) {
 	defer errors.DeferredAnnotatef(&err, "waiting for agent of unit %q", u)
-	ch := make(chan presence.Change)
-	pwatcher := u.st.workers.PresenceWatcher()
-	pwatcher.Watch(u.globalAgentKey(), ch)
-	defer pwatcher.Unwatch(u.globalAgentKey(), ch)
-	for i := 0; i < 2; i++ {
-		select {
-		case change := <-ch:
-			if change.Alive {
-				return nil
-			}
-		case <-time.After(timeout):
-			// TODO(fwereade): 2016-03-17 lp:1558657
-			return fmt.Errorf("still not alive after timeout")
-		case <-pwatcher.Dead():
-			return pwatcher.Err()
-		}
-	}
-	panic(fmt.Sprintf("presence reported dead status twice in a row for unit %q", u))
+	return waitAgentPresence(u.st, u.globalAgentKey(), timeout)
 }
 
 // SetAgentPresence signals that the agent for unit u is alive.
 // It returns the started pinger.
 func (u *Unit) SetAgentPresence() (*presence.Pinger, error) {
-	presenceCollection := u.st.getPresenceCollection()
-	p := presence.NewPinger(presenceCollection, u.st.ModelTag(), u.globalAgentKey())
-	err := p.Start()
-	if err != nil {
-		return nil, err
-	}
-	return p, nil
+	return setAgentPresence(u.st, u.globalAgentKey())
 }
 
 func unitNotAssignedError(u *Unit) error {
@@ -1224,6 +1187,59 @@ func (u *Unit) AssignedMachineId() (id string, err error) {
 	return pudoc.MachineId, nil
 }
 
+// assignedUnitName returns the name of the unit whose machine id
+// records the assignment of the named unit: the unit itself if it is a
+// principal, or its principal if it is a subordinate.
+func (st *State) assignedUnitName(unitName string) (string, error) {
+	if !names.IsValidUnit(unitName) {
+		return "", errors.Errorf("%q is not a valid unit name", unitName)
+	}
+	units, closer := st.getCollection(unitsC)
+	defer closer()
+
+	var doc struct {
+		Principal string `bson:"principal"`
+	}
+	err := units.FindId(unitName).Select(bson.D{{"principal", 1}}).One(&doc)
+	if err == mgo.ErrNotFound {
+		return "", errors.NotFoundf("unit %q", unitName)
+	} else if err != nil {
+		return "", errors.Annotatef(err, "cannot get unit %q", unitName)
+	}
+	if doc.Principal != "" {
+		return doc.Principal, nil
+	}
+	return unitName, nil
+}
+
+// AssignedMachineId returns the id of the machine to which the named
+// unit is assigned. Unlike Unit.AssignedMachineId it reads only the
+// assignment from the database, so it is cheap enough to call whenever
+// a watcher returned by WatchUnitAssignment fires.
+func (st *State) AssignedMachineId(unitName string) (string, error) {
+	assigned, err := st.assignedUnitName(unitName)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	units, closer := st.getCollection(unitsC)
+	defer closer()
+
+	var doc struct {
+		MachineId string `bson:"machineid"`
+	}
+	err = units.FindId(assigned).Select(bson.D{{"machineid", 1}}).One(&doc)
+	if err == mgo.ErrNotFound {
+		return "", errors.NotFoundf("principal unit %q of %q", assigned, unitName)
+	} else if err != nil {
+		return "", errors.Annotatef(err, "cannot get unit %q", assigned)
+	}
+	if doc.MachineId == "" {
+		msg := fmt.Sprintf("unit %q is not assigned to a machine", unitName)
+		return "", errors.NewNotAssigned(nil, msg)
+	}
+	return doc.MachineId, nil
+}
+
 var (
 	machineNotAliveErr = errors.New("machine is not alive")
 	machineNotCleanErr = errors.New("machine is dirty")
@@ -1512,7 +1528,7 @@ func (u *Unit) assignToNewMachineOps(
 	)
 	switch {
 	case parentId == "" && containerType == "":
-		mdoc, ops, err = u.st.addMachineOps(template)
+		mdoc, ops, err = u.st.addMachineOps(template, u.st.MachineIdPolicy(), nil)
 	case parentId == "":
 		if containerType == "" {
 			return nil, nil, errors.New("assignToNewMachine called without container type (should never happen)")
@@ -1793,3 +1793,105 @@ func (s *UnitSuite) TestWorkloadVersion(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Check(version, gc.Equals, "3.combined")
 }
+
+func (s *UnitSuite) TestAssignedMachineIdByName(c *gc.C) {
+	_, err := s.State.AssignedMachineId("wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" is not assigned to a machine`)
+	c.Assert(err, jc.Satisfies, errors.IsNotAssigned)
+
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.AssignToMachine(machine)
+	c.Assert(err, jc.ErrorIsNil)
+	id, err := s.State.AssignedMachineId("wordpress/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, machine.Id())
+
+	_, err = s.State.AssignedMachineId("wordpress/1")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/1" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	_, err = s.State.AssignedMachineId("wordpress")
+	c.Assert(err, gc.ErrorMatches, `"wordpress" is not a valid unit name`)
+}
+
+func (s *UnitSuite) TestWatchAssignment(c *gc.C) {
+	machine0, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	machine1, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.unit.WatchAssignment()
+	defer testing.AssertStop(c, w)
+
+	// Initial event, although the unit is not assigned.
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Assign the unit: reported.
+	err = s.unit.AssignToMachine(machine0)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	id, err := s.State.AssignedMachineId(s.unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, machine0.Id())
+
+	// Alter the unit: not reported.
+	err = s.unit.SetPassword("arble-farble-dying-yarble")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Unassign the unit: reported.
+	err = s.unit.UnassignFromMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	_, err = s.State.AssignedMachineId(s.unit.Name())
+	c.Assert(err, jc.Satisfies, errors.IsNotAssigned)
+
+	// Reassign the unit to another machine: reported.
+	err = s.unit.AssignToMachine(machine1)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	id, err = s.State.AssignedMachineId(s.unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, machine1.Id())
+
+	testing.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *UnitSuite) TestWatchAssignmentSubordinate(c *gc.C) {
+	s.AddTestingService(c, "logging", s.AddTestingCharm(c, "logging"))
+	eps, err := s.State.InferEndpoints("logging", "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.AddRelation(eps...)
+	c.Assert(err, jc.ErrorIsNil)
+	ru, err := rel.Unit(s.unit)
+	c.Assert(err, jc.ErrorIsNil)
+	err = ru.EnterScope(nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.State.WatchUnitAssignment("logging/0")
+	defer testing.AssertStop(c, w)
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	// Assigning the principal assigns the subordinate.
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.AssignToMachine(machine)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	id, err := s.State.AssignedMachineId("logging/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, machine.Id())
+}
+
+func (s *UnitSuite) TestWatchAssignmentUnitNotFound(c *gc.C) {
+	w := s.State.WatchUnitAssignment("wordpress/1")
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertClosed()
+	err := w.Wait()
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/1" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -0,0 +1,314 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// UpgradeSeriesStatus is the phase reached by a machine, or by one of
+// its units, in changing the machine's OS series.
+type UpgradeSeriesStatus string
+
+const (
+	// UpgradeSeriesNotStarted is the phase of a newly locked machine
+	// and of its units.
+	UpgradeSeriesNotStarted UpgradeSeriesStatus = "not-started"
+
+	// UpgradeSeriesPrepareStarted is the phase in which units prepare
+	// for the series to change underneath them.
+	UpgradeSeriesPrepareStarted UpgradeSeriesStatus = "prepare-started"
+
+	// UpgradeSeriesPrepareCompleted is the phase in which all units
+	// are prepared, and the operator may upgrade the OS.
+	UpgradeSeriesPrepareCompleted UpgradeSeriesStatus = "prepare-completed"
+
+	// UpgradeSeriesCompleteStarted is the phase in which the OS has
+	// been upgraded, and units complete the change.
+	UpgradeSeriesCompleteStarted UpgradeSeriesStatus = "complete-started"
+
+	// UpgradeSeriesCompleted is the phase in which all units have
+	// completed the change.
+	UpgradeSeriesCompleted UpgradeSeriesStatus = "completed"
+)
+
+// upgradeSeriesOrder holds the phases in the order they are passed
+// through.
+var upgradeSeriesOrder = []UpgradeSeriesStatus{
+	UpgradeSeriesNotStarted,
+	UpgradeSeriesPrepareStarted,
+	UpgradeSeriesPrepareCompleted,
+	UpgradeSeriesCompleteStarted,
+	UpgradeSeriesCompleted,
+}
+
+// nextUpgradeSeriesStatus returns the phase that follows status, and
+// whether there is one.
+func nextUpgradeSeriesStatus(status UpgradeSeriesStatus) (UpgradeSeriesStatus, bool) {
+	for i, s := range upgradeSeriesOrder[:len(upgradeSeriesOrder)-1] {
+		if s == status {
+			return upgradeSeriesOrder[i+1], true
+		}
+	}
+	return "", false
+}
+
+// upgradeSeriesLockDoc records the progress of a change to the series
+// of a machine. While it exists, the machine may not become Dead.
+type upgradeSeriesLockDoc struct {
+	DocID         string                         `bson:"_id"`
+	Id            string                         `bson:"machineid"`
+	ModelUUID     string                         `bson:"model-uuid"`
+	TargetSeries  string                         `bson:"target-series"`
+	MachineStatus UpgradeSeriesStatus            `bson:"machine-status"`
+	UnitStatuses  map[string]UpgradeSeriesStatus `bson:"unit-statuses"`
+}
+
+// UpgradeSeriesLockError is returned by EnsureDead when the machine is
+// locked for a change of series.
+type UpgradeSeriesLockError struct {
+	MachineId string
+}
+
+func (e *UpgradeSeriesLockError) Error() string {
+	return fmt.Sprintf("machine %s is locked for series upgrade", e.MachineId)
+}
+
+// IsUpgradeSeriesLockError reports whether err is an
+// UpgradeSeriesLockError.
+func IsUpgradeSeriesLockError(err error) bool {
+	_, ok := errors.Cause(err).(*UpgradeSeriesLockError)
+	return ok
+}
+
+// CreateUpgradeSeriesLock locks the machine for a change to the given
+// series, to be coordinated with the named units. The machine and the
+// units start in the UpgradeSeriesNotStarted phase. It fails if the
+// machine is already locked.
+func (m *Machine) CreateUpgradeSeriesLock(units []string, targetSeries string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot lock machine %v for series upgrade", m)
+	if targetSeries == "" {
+		return errors.NotValidf("empty target series")
+	}
+	statuses := make(map[string]UpgradeSeriesStatus)
+	for _, unit := range units {
+		if !names.IsValidUnit(unit) {
+			return errors.NotValidf("unit name %q", unit)
+		}
+		statuses[unit] = UpgradeSeriesNotStarted
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if _, err := m.getUpgradeSeriesLock(); err == nil {
+				return nil, errors.AlreadyExistsf("upgrade series lock")
+			} else if !errors.IsNotFound(err) {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.Life() != Alive {
+			return nil, errors.Errorf("machine is not alive")
+		}
+		if m.Series() == targetSeries {
+			return nil, errors.Errorf("machine is already running series %q", targetSeries)
+		}
+		return []txn.Op{{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: isAliveDoc,
+		}, {
+			C:      machineUpgradeSeriesLocksC,
+			Id:     m.doc.DocID,
+			Assert: txn.DocMissing,
+			Insert: &upgradeSeriesLockDoc{
+				Id:            m.Id(),
+				TargetSeries:  targetSeries,
+				MachineStatus: UpgradeSeriesNotStarted,
+				UnitStatuses:  statuses,
+			},
+		}}, nil
+	}
+	return m.st.run(buildTxn)
+}
+
+// RemoveUpgradeSeriesLock removes the machine's upgrade series lock,
+// whatever phase it has reached, so that a change of series may be
+// abandoned. It does nothing if the machine is not locked.
+func (m *Machine) RemoveUpgradeSeriesLock() error {
+	ops := []txn.Op{{
+		C:      machineUpgradeSeriesLocksC,
+		Id:     m.doc.DocID,
+		Remove: true,
+	}}
+	if err := m.st.runTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot remove upgrade series lock of machine %v", m)
+	}
+	return nil
+}
+
+// IsLockedForSeriesUpgrade returns whether the machine has an upgrade
+// series lock.
+func (m *Machine) IsLockedForSeriesUpgrade() (bool, error) {
+	_, err := m.getUpgradeSeriesLock()
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Trace(err)
+	}
+	return true, nil
+}
+
+// UpgradeSeriesTarget returns the series to which the machine is being
+// upgraded.
+func (m *Machine) UpgradeSeriesTarget() (string, error) {
+	lock, err := m.getUpgradeSeriesLock()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return lock.TargetSeries, nil
+}
+
+// UpgradeSeriesStatus returns the phase reached by the machine in
+// changing its series.
+func (m *Machine) UpgradeSeriesStatus() (UpgradeSeriesStatus, error) {
+	lock, err := m.getUpgradeSeriesLock()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return lock.MachineStatus, nil
+}
+
+// UpgradeSeriesUnitStatuses returns the phase reached by each of the
+// units being coordinated, keyed by unit name.
+func (m *Machine) UpgradeSeriesUnitStatuses() (map[string]UpgradeSeriesStatus, error) {
+	lock, err := m.getUpgradeSeriesLock()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return lock.UnitStatuses, nil
+}
+
+// SetUpgradeSeriesStatus advances the machine to the given phase,
+// which must follow the current one. The machine may only advance to
+// UpgradeSeriesPrepareCompleted once all its units have, and to
+// UpgradeSeriesCompleted likewise.
+func (m *Machine) SetUpgradeSeriesStatus(status UpgradeSeriesStatus) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set upgrade series status of machine %v", m)
+	buildTxn := func(int) ([]txn.Op, error) {
+		lock, err := m.getUpgradeSeriesLock()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if lock.MachineStatus == status {
+			return nil, jujutxn.ErrNoOperations
+		}
+		if next, ok := nextUpgradeSeriesStatus(lock.MachineStatus); !ok || next != status {
+			return nil, errors.Errorf("cannot change from %q to %q", lock.MachineStatus, status)
+		}
+		assert := bson.D{{"machine-status", lock.MachineStatus}}
+		if status == UpgradeSeriesPrepareCompleted || status == UpgradeSeriesCompleted {
+			for unit, unitStatus := range lock.UnitStatuses {
+				if unitStatus != status {
+					return nil, errors.Errorf("unit %s is %q", unit, unitStatus)
+				}
+				assert = append(assert, bson.DocElem{"unit-statuses." + unit, status})
+			}
+		}
+		return []txn.Op{{
+			C:      machineUpgradeSeriesLocksC,
+			Id:     m.doc.DocID,
+			Assert: assert,
+			Update: bson.D{{"$set", bson.D{{"machine-status", status}}}},
+		}}, nil
+	}
+	return m.st.run(buildTxn)
+}
+
+// SetUpgradeSeriesUnitStatus advances the named unit to the given
+// phase, which must follow its current one. Units prepare while the
+// machine is UpgradeSeriesPrepareStarted, and complete while it is
+// UpgradeSeriesCompleteStarted.
+func (m *Machine) SetUpgradeSeriesUnitStatus(unit string, status UpgradeSeriesStatus) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set upgrade series status of unit %s", unit)
+	var machineStatus UpgradeSeriesStatus
+	switch status {
+	case UpgradeSeriesPrepareStarted, UpgradeSeriesPrepareCompleted:
+		machineStatus = UpgradeSeriesPrepareStarted
+	case UpgradeSeriesCompleteStarted, UpgradeSeriesCompleted:
+		machineStatus = UpgradeSeriesCompleteStarted
+	default:
+		return errors.NotValidf("unit status %q", status)
+	}
+	buildTxn := func(int) ([]txn.Op, error) {
+		lock, err := m.getUpgradeSeriesLock()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		current, ok := lock.UnitStatuses[unit]
+		if !ok {
+			return nil, errors.NotFoundf("unit %s in upgrade series lock", unit)
+		}
+		if current == status {
+			return nil, jujutxn.ErrNoOperations
+		}
+		if next, _ := nextUpgradeSeriesStatus(current); next != status {
+			return nil, errors.Errorf("cannot change from %q to %q", current, status)
+		}
+		if lock.MachineStatus != machineStatus {
+			return nil, errors.Errorf("machine %s is %q", m.Id(), lock.MachineStatus)
+		}
+		return []txn.Op{{
+			C:  machineUpgradeSeriesLocksC,
+			Id: m.doc.DocID,
+			Assert: bson.D{
+				{"machine-status", machineStatus},
+				{"unit-statuses." + unit, current},
+			},
+			Update: bson.D{{"$set", bson.D{{"unit-statuses." + unit, status}}}},
+		}}, nil
+	}
+	return m.st.run(buildTxn)
+}
+
+// WatchUpgradeSeriesNotifications returns a NotifyWatcher that
+// notifies of changes to the machine's upgrade series lock, including
+// its creation and removal.
+func (m *Machine) WatchUpgradeSeriesNotifications() NotifyWatcher {
+	return newEntityWatcher(m.st, machineUpgradeSeriesLocksC, m.doc.DocID)
+}
+
+// getUpgradeSeriesLock returns the machine's upgrade series lock, or
+// an error satisfying errors.IsNotFound if it has none.
+func (m *Machine) getUpgradeSeriesLock() (*upgradeSeriesLockDoc, error) {
+	locks, closer := m.st.getCollection(machineUpgradeSeriesLocksC)
+	defer closer()
+
+	var lock upgradeSeriesLockDoc
+	err := locks.FindId(m.doc.DocID).One(&lock)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("upgrade series lock for machine %q", m.Id())
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot get upgrade series lock for machine %q", m.Id())
+	}
+	return &lock, nil
+}
+
+// assertNoUpgradeSeriesLockOp returns an operation that asserts that
+// the machine with the given document id has no upgrade series lock.
+func assertNoUpgradeSeriesLockOp(docID string) txn.Op {
+	return txn.Op{
+		C:      machineUpgradeSeriesLocksC,
+		Id:     docID,
+		Assert: txn.DocMissing,
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/testing"
+)
+
+type UpgradeSeriesSuite struct {
+	ConnSuite
+	machine *state.Machine
+	units   []string
+}
+
+var _ = gc.Suite(&UpgradeSeriesSuite{})
+
+func (s *UpgradeSeriesSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("trusty", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.units = []string{"mysql/0", "wordpress/0"}
+}
+
+func (s *UpgradeSeriesSuite) assertStatuses(c *gc.C, machine state.UpgradeSeriesStatus, units ...state.UpgradeSeriesStatus) {
+	status, err := s.machine.UpgradeSeriesStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(status, gc.Equals, machine)
+	statuses, err := s.machine.UpgradeSeriesUnitStatuses()
+	c.Assert(err, jc.ErrorIsNil)
+	expect := make(map[string]state.UpgradeSeriesStatus)
+	for i, unit := range s.units {
+		expect[unit] = units[i]
+	}
+	c.Check(statuses, jc.DeepEquals, expect)
+}
+
+func (s *UpgradeSeriesSuite) setUnitStatuses(c *gc.C, status state.UpgradeSeriesStatus) {
+	for _, unit := range s.units {
+		err := s.machine.SetUpgradeSeriesUnitStatus(unit, status)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *UpgradeSeriesSuite) TestHappyPath(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	target, err := s.machine.UpgradeSeriesTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(target, gc.Equals, "xenial")
+	s.assertStatuses(c, state.UpgradeSeriesNotStarted, state.UpgradeSeriesNotStarted, state.UpgradeSeriesNotStarted)
+
+	// Units prepare once the machine starts preparing.
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setUnitStatuses(c, state.UpgradeSeriesPrepareStarted)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareCompleted)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertStatuses(c, state.UpgradeSeriesPrepareStarted, state.UpgradeSeriesPrepareCompleted, state.UpgradeSeriesPrepareStarted)
+
+	// The machine may not finish preparing before all its units.
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareCompleted)
+	c.Assert(err, gc.ErrorMatches, `cannot set upgrade series status of machine 0: unit wordpress/0 is "prepare-started"`)
+	err = s.machine.SetUpgradeSeriesUnitStatus("wordpress/0", state.UpgradeSeriesPrepareCompleted)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareCompleted)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertStatuses(c, state.UpgradeSeriesPrepareCompleted, state.UpgradeSeriesPrepareCompleted, state.UpgradeSeriesPrepareCompleted)
+
+	// Units may not complete until the OS has been upgraded.
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesCompleteStarted)
+	c.Assert(err, gc.ErrorMatches, `cannot set upgrade series status of unit mysql/0: machine 0 is "prepare-completed"`)
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesCompleteStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setUnitStatuses(c, state.UpgradeSeriesCompleteStarted)
+	s.setUnitStatuses(c, state.UpgradeSeriesCompleted)
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesCompleted)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertStatuses(c, state.UpgradeSeriesCompleted, state.UpgradeSeriesCompleted, state.UpgradeSeriesCompleted)
+
+	err = s.machine.RemoveUpgradeSeriesLock()
+	c.Assert(err, jc.ErrorIsNil)
+	locked, err := s.machine.IsLockedForSeriesUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(locked, jc.IsFalse)
+}
+
+func (s *UpgradeSeriesSuite) TestAbort(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The lock may be removed part way through, after which the
+	// machine is unlocked and a new upgrade may begin.
+	err = s.machine.RemoveUpgradeSeriesLock()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.machine.UpgradeSeriesStatus()
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareCompleted)
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.machine.CreateUpgradeSeriesLock(s.units, "bionic")
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertStatuses(c, state.UpgradeSeriesNotStarted, state.UpgradeSeriesNotStarted, state.UpgradeSeriesNotStarted)
+}
+
+func (s *UpgradeSeriesSuite) TestCreateLockTwice(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.CreateUpgradeSeriesLock(s.units[:1], "bionic")
+	c.Assert(err, gc.ErrorMatches, `cannot lock machine 0 for series upgrade: upgrade series lock already exists`)
+	c.Check(err, jc.Satisfies, errors.IsAlreadyExists)
+	target, err := s.machine.UpgradeSeriesTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(target, gc.Equals, "xenial")
+}
+
+func (s *UpgradeSeriesSuite) TestCreateLockValidation(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "trusty")
+	c.Check(err, gc.ErrorMatches, `cannot lock machine 0 for series upgrade: machine is already running series "trusty"`)
+	err = s.machine.CreateUpgradeSeriesLock(s.units, "")
+	c.Check(err, gc.ErrorMatches, `cannot lock machine 0 for series upgrade: empty target series not valid`)
+	err = s.machine.CreateUpgradeSeriesLock([]string{"mysql"}, "xenial")
+	c.Check(err, gc.ErrorMatches, `cannot lock machine 0 for series upgrade: unit name "mysql" not valid`)
+}
+
+func (s *UpgradeSeriesSuite) TestInvalidTransitions(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesCompleteStarted)
+	c.Check(err, gc.ErrorMatches, `.*cannot change from "not-started" to "complete-started"`)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareStarted)
+	c.Check(err, gc.ErrorMatches, `.*machine 0 is "not-started"`)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesNotStarted)
+	c.Check(err, gc.ErrorMatches, `.*unit status "not-started" not valid`)
+
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareCompleted)
+	c.Check(err, gc.ErrorMatches, `.*cannot change from "not-started" to "prepare-completed"`)
+	err = s.machine.SetUpgradeSeriesUnitStatus("mongodb/0", state.UpgradeSeriesPrepareStarted)
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+
+	// Setting the current status again does nothing.
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Check(err, jc.ErrorIsNil)
+}
+
+func (s *UpgradeSeriesSuite) TestLockBlocksEnsureDead(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, gc.ErrorMatches, "machine 0 is locked for series upgrade")
+	c.Check(err, jc.Satisfies, state.IsUpgradeSeriesLockError)
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.machine.Life(), gc.Equals, state.Alive)
+
+	err = s.machine.RemoveUpgradeSeriesLock()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UpgradeSeriesSuite) TestForceDestroyRemovesLock(c *gc.C) {
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.ForceDestroy()
+	c.Assert(err, jc.ErrorIsNil)
+	for i := 0; i < 2; i++ {
+		err = s.State.Cleanup()
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	needsCleanup, err := s.State.NeedsCleanup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(needsCleanup, jc.IsFalse)
+
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.machine.Life(), gc.Equals, state.Dead)
+	locked, err := s.machine.IsLockedForSeriesUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(locked, jc.IsFalse)
+}
+
+func (s *UpgradeSeriesSuite) TestCreateLockDyingMachine(c *gc.C) {
+	err := s.machine.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, gc.ErrorMatches, `cannot lock machine 0 for series upgrade: machine is not alive`)
+}
+
+func (s *UpgradeSeriesSuite) TestWatchUpgradeSeriesNotifications(c *gc.C) {
+	w := s.machine.WatchUpgradeSeriesNotifications()
+	defer testing.AssertStop(c, w)
+	wc := testing.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	err := s.machine.CreateUpgradeSeriesLock(s.units, "xenial")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = s.machine.SetUpgradeSeriesStatus(state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = s.machine.SetUpgradeSeriesUnitStatus("mysql/0", state.UpgradeSeriesPrepareStarted)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = s.machine.RemoveUpgradeSeriesLock()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	testing.AssertStop(c, w)
+	wc.AssertClosed()
+}
@@ -0,0 +1,392 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/mongo"
+)
+
+// verifyPageSize is the number of documents Verify reads and checks
+// at a time, so that it never holds a whole collection in memory.
+var verifyPageSize = 1000
+
+// OrphanKind identifies a class of document that refers to an entity
+// that no longer exists.
+type OrphanKind string
+
+const (
+	// OrphanedStatus is a status document of a machine, unit or
+	// application that has been removed.
+	OrphanedStatus OrphanKind = "status"
+
+	// OrphanedUnitAssignment is a unit assigned to a machine that
+	// has been removed.
+	OrphanedUnitAssignment OrphanKind = "unit-assignment"
+
+	// OrphanedPresence is an agent presence record for a machine or
+	// unit that is dead or has been removed.
+	OrphanedPresence OrphanKind = "presence"
+)
+
+// Orphan describes a document found by Verify that refers to an
+// entity that no longer exists.
+type Orphan struct {
+	// Kind identifies the class of the orphaned document.
+	Kind OrphanKind
+
+	// Id identifies the orphaned document: the global key of a
+	// status document, the name of a unit, or the id of a presence
+	// record.
+	Id string
+
+	// Entity identifies the entity that the document refers to.
+	Entity names.Tag
+}
+
+// String returns a description of the orphan.
+func (o Orphan) String() string {
+	return fmt.Sprintf("orphaned %s %q of %s", o.Kind, o.Id, o.Entity)
+}
+
+// Report holds the orphaned documents found by Verify.
+type Report struct {
+	Orphans []Orphan
+}
+
+// Empty reports whether no orphans were found.
+func (r Report) Empty() bool {
+	return len(r.Orphans) == 0
+}
+
+// RepairAction describes what Repair did, or would do, about an
+// orphan.
+type RepairAction struct {
+	Orphan Orphan
+
+	// Action describes the change made.
+	Action string
+
+	// Skipped is set if the document was no longer orphaned when
+	// Repair came to it, and so was left alone.
+	Skipped bool
+}
+
+// Verify scans the model for documents left behind by operations that
+// did not complete, such as status documents of removed machines and
+// units assigned to removed machines. It does not change anything;
+// the report can be passed to Repair.
+func (st *State) Verify() (Report, error) {
+	var report Report
+	for _, scan := range []func() ([]Orphan, error){
+		st.orphanedStatuses,
+		st.orphanedUnitAssignments,
+		st.orphanedPresence,
+	} {
+		orphans, err := scan()
+		if err != nil {
+			return Report{}, errors.Trace(err)
+		}
+		report.Orphans = append(report.Orphans, orphans...)
+	}
+	return report, nil
+}
+
+// Repair removes or fixes the orphaned documents in the report, each
+// in its own transaction that checks that the entity referred to is
+// still missing. It returns what was done; if dryRun is true, nothing
+// is changed and the actions that would have been taken are returned.
+func (st *State) Repair(report Report, dryRun bool) ([]RepairAction, error) {
+	var actions []RepairAction
+	for _, orphan := range report.Orphans {
+		action, err := st.repairOrphan(orphan, dryRun)
+		if err != nil {
+			return actions, errors.Annotatef(err, "cannot repair %s", orphan)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func (st *State) repairOrphan(orphan Orphan, dryRun bool) (RepairAction, error) {
+	action := RepairAction{Orphan: orphan}
+	var ops []txn.Op
+	switch orphan.Kind {
+	case OrphanedStatus:
+		action.Action = fmt.Sprintf("remove status %q", orphan.Id)
+		ops = []txn.Op{
+			entityMissingOp(st, orphan.Entity),
+			{
+				C:      statusesC,
+				Id:     st.docID(orphan.Id),
+				Assert: txn.DocExists,
+				Remove: true,
+			},
+		}
+	case OrphanedUnitAssignment:
+		action.Action = fmt.Sprintf("unassign unit %s from %s", orphan.Id, orphan.Entity)
+		ops = []txn.Op{
+			entityMissingOp(st, orphan.Entity),
+			{
+				C:      unitsC,
+				Id:     st.docID(orphan.Id),
+				Assert: bson.D{{"machineid", orphan.Entity.Id()}},
+				Update: bson.D{{"$set", bson.D{{"machineid", ""}}}},
+			},
+		}
+	case OrphanedPresence:
+		// Presence records are not written transactionally, so
+		// the entity is checked again before the record is
+		// removed directly.
+		action.Action = fmt.Sprintf("remove presence record %q", orphan.Id)
+		if dryRun {
+			return action, nil
+		}
+		gone, err := st.entitiesGone(orphan.Entity.Kind(), []string{orphan.Entity.Id()}, true)
+		if err != nil {
+			return action, errors.Trace(err)
+		}
+		if !gone[orphan.Entity.Id()] {
+			action.Skipped = true
+			return action, nil
+		}
+		err = st.getPresenceBeingsCollection().RemoveId(orphan.Id)
+		if err == mgo.ErrNotFound {
+			action.Skipped = true
+			return action, nil
+		}
+		return action, errors.Trace(err)
+	default:
+		return action, errors.NotValidf("orphan kind %q", orphan.Kind)
+	}
+	if dryRun {
+		return action, nil
+	}
+	err := st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		action.Skipped = true
+		return action, nil
+	}
+	return action, errors.Trace(err)
+}
+
+// entityMissingOp returns an operation asserting that the machine,
+// unit or application with the given tag does not exist.
+func entityMissingOp(st *State, tag names.Tag) txn.Op {
+	return txn.Op{
+		C:      entityCollection(tag.Kind()),
+		Id:     st.docID(tag.Id()),
+		Assert: txn.DocMissing,
+	}
+}
+
+// entityCollection returns the collection holding the entities of the
+// given kind.
+func entityCollection(kind string) string {
+	switch kind {
+	case names.MachineTagKind:
+		return machinesC
+	case names.UnitTagKind:
+		return unitsC
+	case names.ApplicationTagKind:
+		return applicationsC
+	}
+	panic(fmt.Sprintf("unexpected entity kind %q", kind))
+}
+
+// entityIdField returns the field holding the ids of the entities of
+// the given kind.
+func entityIdField(kind string) string {
+	if kind == names.MachineTagKind {
+		return "machineid"
+	}
+	return "name"
+}
+
+// entityForGlobalKey returns the tag of the machine, unit or
+// application that owns the documents with the given global key, or
+// nil if the key belongs to some other kind of entity.
+func entityForGlobalKey(key string) names.Tag {
+	parts := strings.SplitN(key, "#", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+	switch id := parts[1]; parts[0] {
+	case "m":
+		if names.IsValidMachine(id) {
+			return names.NewMachineTag(id)
+		}
+	case "u":
+		if names.IsValidUnit(id) {
+			return names.NewUnitTag(id)
+		}
+	case "a":
+		if names.IsValidApplication(id) {
+			return names.NewApplicationTag(id)
+		}
+	}
+	return nil
+}
+
+// entitiesGone returns, for each of the given ids of entities of the
+// given kind, whether the entity has been removed or, if dead is true,
+// is dead.
+func (st *State) entitiesGone(kind string, ids []string, dead bool) (map[string]bool, error) {
+	coll, closer := st.getCollection(entityCollection(kind))
+	defer closer()
+
+	field := entityIdField(kind)
+	var docs []bson.M
+	err := coll.Find(bson.D{{field, bson.D{{"$in", ids}}}}).Select(bson.D{{field, 1}, {"life", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	gone := make(map[string]bool)
+	for _, id := range ids {
+		gone[id] = true
+	}
+	for _, doc := range docs {
+		id, _ := doc[field].(string)
+		life, _ := doc["life"].(int)
+		gone[id] = dead && Life(life) == Dead
+	}
+	return gone, nil
+}
+
+// candidate is a document that refers to an entity that may have
+// been removed.
+type candidate struct {
+	id     string
+	entity names.Tag
+}
+
+// orphanedCandidates returns the orphans among the given candidates,
+// which must all be of the given kind.
+func (st *State) orphanedCandidates(kind OrphanKind, candidates []candidate, dead bool) ([]Orphan, error) {
+	byKind := make(map[string][]string)
+	for _, c := range candidates {
+		byKind[c.entity.Kind()] = append(byKind[c.entity.Kind()], c.entity.Id())
+	}
+	gone := make(map[string]map[string]bool)
+	for entityKind, ids := range byKind {
+		entitiesGone, err := st.entitiesGone(entityKind, ids, dead)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		gone[entityKind] = entitiesGone
+	}
+	var orphans []Orphan
+	for _, c := range candidates {
+		if gone[c.entity.Kind()][c.entity.Id()] {
+			orphans = append(orphans, Orphan{Kind: kind, Id: c.id, Entity: c.entity})
+		}
+	}
+	return orphans, nil
+}
+
+// scanPages reads the documents matched by query a page at a time,
+// passing each to candidateFor, and returns the orphans among the
+// candidates found.
+func (st *State) scanPages(query mongo.Query, kind OrphanKind, dead bool, candidateFor func(bson.M) *candidate) ([]Orphan, error) {
+	var orphans []Orphan
+	var page []candidate
+	flush := func() error {
+		found, err := st.orphanedCandidates(kind, page, dead)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		orphans = append(orphans, found...)
+		page = page[:0]
+		return nil
+	}
+	iter := query.Batch(verifyPageSize).Iter()
+	var doc bson.M
+	for iter.Next(&doc) {
+		if c := candidateFor(doc); c != nil {
+			page = append(page, *c)
+		}
+		if len(page) == verifyPageSize {
+			if err := flush(); err != nil {
+				iter.Close()
+				return nil, errors.Trace(err)
+			}
+		}
+		doc = nil
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := flush(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orphans, nil
+}
+
+// orphanedStatuses returns the status documents of removed machines,
+// units and applications.
+func (st *State) orphanedStatuses() ([]Orphan, error) {
+	statuses, closer := st.getCollection(statusesC)
+	defer closer()
+
+	query := statuses.Find(nil).Select(bson.D{{"_id", 1}})
+	orphans, err := st.scanPages(query, OrphanedStatus, false, func(doc bson.M) *candidate {
+		docID, _ := doc["_id"].(string)
+		key := st.localID(docID)
+		if entity := entityForGlobalKey(key); entity != nil {
+			return &candidate{id: key, entity: entity}
+		}
+		return nil
+	})
+	return orphans, errors.Annotate(err, "cannot check statuses")
+}
+
+// orphanedUnitAssignments returns the units assigned to removed
+// machines.
+func (st *State) orphanedUnitAssignments() ([]Orphan, error) {
+	units, closer := st.getCollection(unitsC)
+	defer closer()
+
+	query := units.Find(bson.D{{"machineid", bson.D{{"$ne", ""}}}}).Select(bson.D{{"name", 1}, {"machineid", 1}})
+	orphans, err := st.scanPages(query, OrphanedUnitAssignment, false, func(doc bson.M) *candidate {
+		name, _ := doc["name"].(string)
+		machineId, _ := doc["machineid"].(string)
+		if !names.IsValidMachine(machineId) {
+			return nil
+		}
+		return &candidate{id: name, entity: names.NewMachineTag(machineId)}
+	})
+	return orphans, errors.Annotate(err, "cannot check unit assignments")
+}
+
+// orphanedPresence returns the presence records of agents of dead or
+// removed machines and units.
+func (st *State) orphanedPresence() ([]Orphan, error) {
+	beings := mongo.WrapCollection(st.getPresenceBeingsCollection())
+	query := beings.Find(bson.D{{"model-uuid", st.ModelUUID()}}).Select(bson.D{{"_id", 1}, {"key", 1}})
+	orphans, err := st.scanPages(query, OrphanedPresence, true, func(doc bson.M) *candidate {
+		id, _ := doc["_id"].(string)
+		key, _ := doc["key"].(string)
+		entity := entityForGlobalKey(key)
+		if entity == nil || entity.Kind() == names.ApplicationTagKind {
+			return nil
+		}
+		return &candidate{id: id, entity: entity}
+	})
+	return orphans, errors.Annotate(err, "cannot check presence")
+}
+
+// getPresenceBeingsCollection returns the raw mongodb collection in
+// which the state/presence package maps pinger sequences to agents.
+func (st *State) getPresenceBeingsCollection() *mgo.Collection {
+	presence := st.getPresenceCollection()
+	return presence.Database.C(presence.Name + ".beings")
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/worker"
+)
+
+type VerifySuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&VerifySuite{})
+
+// insertStatus inserts a status document with the given global key,
+// without checking that its entity exists.
+func (s *VerifySuite) insertStatus(c *gc.C, key string) {
+	statuses, closer := state.GetRawCollection(s.State, state.StatusesC)
+	defer closer()
+	err := statuses.Insert(bson.M{
+		"_id":        state.DocID(s.State, key),
+		"model-uuid": s.State.ModelUUID(),
+		"status":     "active",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// removeMachineDoc removes the document of the given machine,
+// leaving everything that refers to it behind.
+func (s *VerifySuite) removeMachineDoc(c *gc.C, id string) {
+	machines, closer := state.GetRawCollection(s.State, state.MachinesC)
+	defer closer()
+	err := machines.RemoveId(state.DocID(s.State, id))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *VerifySuite) verify(c *gc.C) state.Report {
+	report, err := s.State.Verify()
+	c.Assert(err, jc.ErrorIsNil)
+	return report
+}
+
+// orphansOfKind returns the orphans of the given kind in the report.
+func orphansOfKind(report state.Report, kind state.OrphanKind) []state.Orphan {
+	var orphans []state.Orphan
+	for _, orphan := range report.Orphans {
+		if orphan.Kind == kind {
+			orphans = append(orphans, orphan)
+		}
+	}
+	return orphans
+}
+
+func (s *VerifySuite) TestHealthyModel(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	pinger, err := m.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		c.Assert(worker.Stop(pinger), jc.ErrorIsNil)
+	}()
+	app := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	u, err := app.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(u.AssignToMachine(m), jc.ErrorIsNil)
+
+	report := s.verify(c)
+	c.Assert(report.Empty(), jc.IsTrue)
+	c.Assert(report.Orphans, gc.HasLen, 0)
+
+	actions, err := s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, gc.HasLen, 0)
+}
+
+func (s *VerifySuite) TestOrphanedStatus(c *gc.C) {
+	s.PatchValue(state.VerifyPageSize, 1)
+	s.insertStatus(c, "m#42")
+	s.insertStatus(c, "u#wordpress/7#charm")
+	s.insertStatus(c, "a#mysql")
+
+	report := s.verify(c)
+	c.Assert(report.Orphans, jc.SameContents, []state.Orphan{{
+		Kind:   state.OrphanedStatus,
+		Id:     "m#42",
+		Entity: names.NewMachineTag("42"),
+	}, {
+		Kind:   state.OrphanedStatus,
+		Id:     "u#wordpress/7#charm",
+		Entity: names.NewUnitTag("wordpress/7"),
+	}, {
+		Kind:   state.OrphanedStatus,
+		Id:     "a#mysql",
+		Entity: names.NewApplicationTag("mysql"),
+	}})
+
+	actions, err := s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, gc.HasLen, 3)
+	for _, action := range actions {
+		c.Check(action.Skipped, jc.IsFalse)
+		c.Check(action.Action, gc.Equals, `remove status "`+action.Orphan.Id+`"`)
+	}
+	c.Assert(s.verify(c).Empty(), jc.IsTrue)
+}
+
+func (s *VerifySuite) TestRepairDryRun(c *gc.C) {
+	s.insertStatus(c, "m#42")
+	report := s.verify(c)
+	c.Assert(report.Orphans, gc.HasLen, 1)
+
+	actions, err := s.State.Repair(report, true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, jc.DeepEquals, []state.RepairAction{{
+		Orphan: report.Orphans[0],
+		Action: `remove status "m#42"`,
+	}})
+	c.Assert(s.verify(c), jc.DeepEquals, report)
+}
+
+func (s *VerifySuite) TestRepairSkipsRepaired(c *gc.C) {
+	s.insertStatus(c, "m#42")
+	report := s.verify(c)
+	c.Assert(report.Orphans, gc.HasLen, 1)
+
+	_, err := s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	actions, err := s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, gc.HasLen, 1)
+	c.Assert(actions[0].Skipped, jc.IsTrue)
+}
+
+func (s *VerifySuite) TestOrphanedUnitAssignment(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	app := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	u, err := app.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(u.AssignToMachine(m), jc.ErrorIsNil)
+	s.removeMachineDoc(c, m.Id())
+
+	report := s.verify(c)
+	c.Assert(orphansOfKind(report, state.OrphanedUnitAssignment), jc.DeepEquals, []state.Orphan{{
+		Kind:   state.OrphanedUnitAssignment,
+		Id:     "wordpress/0",
+		Entity: m.Tag(),
+	}})
+	// The statuses of the removed machine are orphaned too.
+	c.Assert(orphansOfKind(report, state.OrphanedStatus), gc.Not(gc.HasLen), 0)
+
+	_, err = s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.verify(c).Empty(), jc.IsTrue)
+	c.Assert(u.Refresh(), jc.ErrorIsNil)
+	_, err = u.AssignedMachineId()
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" is not assigned to a machine`)
+}
+
+func (s *VerifySuite) TestOrphanedPresence(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	pinger, err := m.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(worker.Stop(pinger), jc.ErrorIsNil)
+
+	// The presence record of a live agent is left alone, even if
+	// the agent is not pinging.
+	c.Assert(s.verify(c).Empty(), jc.IsTrue)
+
+	c.Assert(m.EnsureDead(), jc.ErrorIsNil)
+	report := s.verify(c)
+	c.Assert(report.Orphans, gc.HasLen, 1)
+	c.Assert(report.Orphans[0].Kind, gc.Equals, state.OrphanedPresence)
+	c.Assert(report.Orphans[0].Entity, gc.Equals, m.Tag())
+
+	actions, err := s.State.Repair(report, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actions, gc.HasLen, 1)
+	c.Assert(actions[0].Skipped, jc.IsFalse)
+	c.Assert(s.verify(c).Empty(), jc.IsTrue)
+}
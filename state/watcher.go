@@ -1307,6 +1307,102 @@ func (st *State) WatchForModelConfigChanges() NotifyWatcher {
 	return newEntityWatcher(st, settingsC, st.docID(modelGlobalKey))
 }
 
+// WatchForModelConfigKeyChanges returns a NotifyWatcher waiting for any
+// of the given keys of the Model Config to change. Changes to other keys
+// do not trigger a notification; use ModelConfig to read the current
+// values when one is received.
+func (st *State) WatchForModelConfigKeyChanges(keys ...string) NotifyWatcher {
+	return newModelConfigKeysWatcher(st, keys)
+}
+
+// modelConfigKeysWatcher notifies when a subset of the model's config
+// settings changes, by comparing the values of the watched keys each
+// time the settings document is written.
+type modelConfigKeysWatcher struct {
+	commonWatcher
+	keys []string
+	out  chan struct{}
+}
+
+var _ Watcher = (*modelConfigKeysWatcher)(nil)
+
+func newModelConfigKeysWatcher(st *State, keys []string) NotifyWatcher {
+	w := &modelConfigKeysWatcher{
+		commonWatcher: newCommonWatcher(st),
+		keys:          keys,
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *modelConfigKeysWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// readValues returns the current values of the watched keys. Keys that
+// are not set are absent from the result.
+func (w *modelConfigKeysWatcher) readValues() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	doc, err := readSettingsDoc(w.st, settingsC, modelGlobalKey)
+	if errors.IsNotFound(err) {
+		return values, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, key := range w.keys {
+		if value, ok := doc.Settings[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (w *modelConfigKeysWatcher) loop() error {
+	docID := w.st.docID(modelGlobalKey)
+	settings, closer := w.st.getCollection(settingsC)
+	revno, err := getTxnRevno(settings, docID)
+	closer()
+	if err != nil {
+		return err
+	}
+	in := make(chan watcher.Change)
+	w.watcher.Watch(settingsC, docID, revno, in)
+	defer w.watcher.Unwatch(settingsC, docID, in)
+	values, err := w.readValues()
+	if err != nil {
+		return err
+	}
+	out := w.out
+	for {
+		select {
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case ch := <-in:
+			if _, ok := collect(ch, in, w.tomb.Dying()); !ok {
+				return tomb.ErrDying
+			}
+			newValues, err := w.readValues()
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(newValues, values) {
+				values = newValues
+				out = w.out
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
 // WatchForUnitAssignment watches for new services that request units to be
 // assigned to machines.
 func (st *State) WatchForUnitAssignment() StringsWatcher {
@@ -1685,6 +1781,261 @@ func (w *machineAddressesWatcher) loop() error {
 	}
 }
 
+// instanceIdWatcher notifies when a machine's instance id is set, and
+// whenever it subsequently changes. Changes to the machine's other
+// instance data are not reported.
+type instanceIdWatcher struct {
+	commonWatcher
+	st        *State
+	machineId string
+	docId     string
+	out       chan struct{}
+}
+
+var _ Watcher = (*instanceIdWatcher)(nil)
+
+// WatchInstanceId returns a new NotifyWatcher watching m's instance id.
+// Unlike most NotifyWatchers, it does not send an initial event until
+// the machine has been provisioned; if it already has an instance id,
+// the first event is sent immediately.
+func (m *Machine) WatchInstanceId() NotifyWatcher {
+	w := &instanceIdWatcher{
+		commonWatcher: newCommonWatcher(m.st),
+		st:            m.st,
+		machineId:     m.doc.Id,
+		docId:         m.doc.DocID,
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *instanceIdWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// instanceId returns the machine's instance id, or "" if it has not
+// been provisioned.
+func (w *instanceIdWatcher) instanceId() (instance.Id, error) {
+	instData, err := getInstanceData(w.st, w.machineId)
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Trace(err)
+	}
+	return instData.InstanceId, nil
+}
+
+func (w *instanceIdWatcher) loop() error {
+	instanceData, closer := w.st.getCollection(instanceDataC)
+	revno, err := getTxnRevno(instanceData, w.docId)
+	closer()
+	if err != nil {
+		return err
+	}
+	instanceDataCh := make(chan watcher.Change)
+	w.watcher.Watch(instanceDataC, w.docId, revno, instanceDataCh)
+	defer w.watcher.Unwatch(instanceDataC, w.docId, instanceDataCh)
+	instanceId, err := w.instanceId()
+	if err != nil {
+		return err
+	}
+	var out chan struct{}
+	if instanceId != "" {
+		out = w.out
+	}
+	for {
+		select {
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-instanceDataCh:
+			newInstanceId, err := w.instanceId()
+			if err != nil {
+				return err
+			}
+			if newInstanceId != "" && newInstanceId != instanceId {
+				instanceId = newInstanceId
+				out = w.out
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
+// unitAssignmentWatcher notifies when the machine to which a unit is
+// assigned changes. Changes to the unit's other fields are not
+// reported.
+type unitAssignmentWatcher struct {
+	commonWatcher
+	st       *State
+	unitName string
+	out      chan struct{}
+}
+
+var _ Watcher = (*unitAssignmentWatcher)(nil)
+
+// WatchAssignment returns a new NotifyWatcher watching the machine to
+// which u is assigned. See State.WatchUnitAssignment.
+func (u *Unit) WatchAssignment() NotifyWatcher {
+	return u.st.WatchUnitAssignment(u.doc.Name)
+}
+
+// WatchUnitAssignment returns a new NotifyWatcher watching the machine
+// to which the named unit is assigned. An event is sent initially, and
+// whenever the unit is assigned, unassigned or reassigned; the new
+// assignment can be read with AssignedMachineId. A subordinate unit is
+// assigned to the machine of its principal, so for a subordinate the
+// principal's assignment is watched.
+func (st *State) WatchUnitAssignment(unitName string) NotifyWatcher {
+	w := &unitAssignmentWatcher{
+		commonWatcher: newCommonWatcher(st),
+		st:            st,
+		unitName:      unitName,
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *unitAssignmentWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// machineId returns the id of the machine to which the watched unit is
+// assigned, or "" if it is not assigned or no longer exists.
+func (w *unitAssignmentWatcher) machineId() (string, error) {
+	machineId, err := w.st.AssignedMachineId(w.unitName)
+	if errors.IsNotAssigned(err) || errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Trace(err)
+	}
+	return machineId, nil
+}
+
+func (w *unitAssignmentWatcher) loop() error {
+	assigned, err := w.st.assignedUnitName(w.unitName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	docId := w.st.docID(assigned)
+	units, closer := w.st.getCollection(unitsC)
+	revno, err := getTxnRevno(units, docId)
+	closer()
+	if err != nil {
+		return err
+	}
+	unitCh := make(chan watcher.Change)
+	w.watcher.Watch(unitsC, docId, revno, unitCh)
+	defer w.watcher.Unwatch(unitsC, docId, unitCh)
+	machineId, err := w.machineId()
+	if err != nil {
+		return err
+	}
+	out := w.out
+	for {
+		select {
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-unitCh:
+			newMachineId, err := w.machineId()
+			if err != nil {
+				return err
+			}
+			if newMachineId != machineId {
+				machineId = newMachineId
+				out = w.out
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
+// instanceTagsWatcher notifies about changes to a machine's
+// user-defined instance tags, and to whether they need to be applied
+// to its instance.
+type instanceTagsWatcher struct {
+	commonWatcher
+	machine *Machine
+	out     chan struct{}
+}
+
+var _ Watcher = (*instanceTagsWatcher)(nil)
+
+// WatchInstanceTags returns a new NotifyWatcher watching m's
+// user-defined instance tags. An event is sent initially, and whenever
+// the tags, or InstanceTagsChanged, change.
+func (m *Machine) WatchInstanceTags() NotifyWatcher {
+	w := &instanceTagsWatcher{
+		commonWatcher: newCommonWatcher(m.st),
+		out:           make(chan struct{}),
+		machine:       &Machine{st: m.st, doc: m.doc}, // Copy so it may be freely refreshed
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *instanceTagsWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *instanceTagsWatcher) loop() error {
+	machines, closer := w.st.getCollection(machinesC)
+	revno, err := getTxnRevno(machines, w.machine.doc.DocID)
+	closer()
+	if err != nil {
+		return err
+	}
+	machineCh := make(chan watcher.Change)
+	w.watcher.Watch(machinesC, w.machine.doc.DocID, revno, machineCh)
+	defer w.watcher.Unwatch(machinesC, w.machine.doc.DocID, machineCh)
+	instanceTags := w.machine.UserInstanceTags()
+	changed := w.machine.InstanceTagsChanged()
+	out := w.out
+	for {
+		select {
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-machineCh:
+			if err := w.machine.Refresh(); err != nil {
+				return err
+			}
+			newInstanceTags := w.machine.UserInstanceTags()
+			newChanged := w.machine.InstanceTagsChanged()
+			if !stringMapsEqual(newInstanceTags, instanceTags) || newChanged != changed {
+				instanceTags = newInstanceTags
+				changed = newChanged
+				out = w.out
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
 // WatchCleanups starts and returns a CleanupWatcher.
 func (st *State) WatchCleanups() NotifyWatcher {
 	return newNotifyCollWatcher(st, cleanupsC, isLocalID(st))
@@ -2544,3 +2895,119 @@ func (w *notifyCollWatcher) loop() error {
 		}
 	}
 }
+
+// WatchMachines returns a MachinesWatcher that reports changes to the
+// machines with the given ids. Further machines can be added to and
+// removed from the watched set while the watcher is running.
+func (st *State) WatchMachines(ids ...string) *MachinesWatcher {
+	w := &MachinesWatcher{
+		commonWatcher: newCommonWatcher(st),
+		requests:      make(chan machinesRequest),
+		out:           make(chan []string),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop(ids))
+	}()
+	return w
+}
+
+// MachinesWatcher is a StringsWatcher that reports the ids of watched
+// machines whose documents have changed. However many machines it
+// watches, it uses a single goroutine and a single subscription to the
+// transaction log, so it should be used in preference to Machine.Watch
+// by clients that watch many machines.
+//
+// The initial event reports all the machines initially watched.
+type MachinesWatcher struct {
+	commonWatcher
+	requests chan machinesRequest
+	out      chan []string
+}
+
+var _ StringsWatcher = (*MachinesWatcher)(nil)
+
+// machinesRequest asks a MachinesWatcher's loop to add or remove
+// machines, and is acknowledged by closing done.
+type machinesRequest struct {
+	add  bool
+	ids  []string
+	done chan struct{}
+}
+
+// Changes returns the event channel for the MachinesWatcher.
+func (w *MachinesWatcher) Changes() <-chan []string {
+	return w.out
+}
+
+// Add starts watching the machines with the given ids. Each of them
+// will be reported in the next event.
+func (w *MachinesWatcher) Add(ids ...string) error {
+	return w.send(machinesRequest{add: true, ids: ids})
+}
+
+// Remove stops watching the machines with the given ids. Once Remove
+// has returned, they will not be reported by any further event unless
+// they are added again.
+func (w *MachinesWatcher) Remove(ids ...string) error {
+	return w.send(machinesRequest{add: false, ids: ids})
+}
+
+func (w *MachinesWatcher) send(req machinesRequest) error {
+	req.done = make(chan struct{})
+	select {
+	case <-w.tomb.Dying():
+		return errors.New("machines watcher is stopping")
+	case w.requests <- req:
+	}
+	<-req.done
+	return nil
+}
+
+func (w *MachinesWatcher) loop(ids []string) error {
+	in := make(chan watcher.Change)
+	w.watcher.WatchCollectionWithFilter(machinesC, in, isLocalID(w.st))
+	defer w.watcher.UnwatchCollection(machinesC, in)
+
+	watching := set.NewStrings(ids...)
+	changes := set.NewStrings(ids...)
+	out := w.out // out set so that initial event is sent.
+	sent := false
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.watcher.Dead():
+			return stateWatcherDeadError(w.watcher.Err())
+		case change := <-in:
+			id := w.st.localID(change.Id.(string))
+			if watching.Contains(id) {
+				changes.Add(id)
+				out = w.out
+			}
+		case req := <-w.requests:
+			for _, id := range req.ids {
+				if req.add {
+					watching.Add(id)
+					changes.Add(id)
+				} else {
+					// Any pending change for the machine must
+					// be dropped along with it.
+					watching.Remove(id)
+					changes.Remove(id)
+				}
+			}
+			if !changes.IsEmpty() {
+				out = w.out
+			} else if sent {
+				out = nil
+			}
+			close(req.done)
+		case out <- changes.SortedValues():
+			changes = set.NewStrings()
+			out = nil
+			sent = true
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/juju/errors"
+	"github.com/juju/loggo"
 	"github.com/juju/utils/clock"
 
 	"github.com/juju/juju/state/presence"
@@ -27,8 +28,20 @@ func (wf workersFactory) NewTxnLogWorker() (workers.TxnLogWorker, error) {
 }
 
 func (wf workersFactory) NewPresenceWorker() (workers.PresenceWorker, error) {
-	coll := wf.st.getPresenceCollection()
-	worker := presence.NewWatcher(coll, wf.st.ModelTag())
+	worker, err := workers.NewRestartingPresence(workers.PresenceConfig{
+		Start: func() (workers.PresenceWorker, error) {
+			coll := wf.st.getPresenceCollection()
+			return presence.NewWatcher(coll, wf.st.ModelTag()), nil
+		},
+		Logger:      loggo.GetLogger(logger.Name() + ".presence"),
+		Clock:       wf.clock,
+		Delay:       time.Second,
+		MaxDelay:    time.Minute,
+		MaxAttempts: 10,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	return worker, nil
 }
 
@@ -65,3 +78,12 @@ func (wf workersFactory) NewSingularWorker() (workers.LeaseWorker, error) {
 	}
 	return manager, nil
 }
+
+// PresenceRestarts returns the number of times the state's presence
+// watcher has been rebuilt after failing. It is intended for debugging.
+func (st *State) PresenceRestarts() int {
+	if rp, ok := st.workers.PresenceWatcher().(*workers.RestartingPresence); ok {
+		return rp.Restarts()
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package workers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/utils/clock"
+	"gopkg.in/tomb.v1"
+
+	"github.com/juju/juju/state/presence"
+	"github.com/juju/juju/worker"
+)
+
+// PresenceConfig holds a RestartingPresence's dependencies and
+// configuration.
+type PresenceConfig struct {
+	// Start creates a new presence worker.
+	Start func() (PresenceWorker, error)
+
+	Logger loggo.Logger
+	Clock  clock.Clock
+
+	// Delay is the time to wait before the first attempt to replace
+	// a failed presence worker. It is doubled after each failed
+	// attempt, up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	// MaxAttempts is the number of consecutive failed attempts to
+	// replace a presence worker after which the RestartingPresence
+	// itself fails.
+	MaxAttempts int
+}
+
+// Validate returns an error if config cannot drive a RestartingPresence.
+func (config PresenceConfig) Validate() error {
+	if config.Start == nil {
+		return errors.NotValidf("nil Start")
+	}
+	if config.Logger == (loggo.Logger{}) {
+		return errors.NotValidf("uninitialized Logger")
+	}
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.Delay <= 0 {
+		return errors.NotValidf("non-positive Delay")
+	}
+	if config.MaxDelay < config.Delay {
+		return errors.NotValidf("MaxDelay less than Delay")
+	}
+	if config.MaxAttempts < 1 {
+		return errors.NotValidf("MaxAttempts %d", config.MaxAttempts)
+	}
+	return nil
+}
+
+// NewRestartingPresence returns a PresenceWorker that delegates to a
+// worker created by config.Start, and replaces that worker whenever it
+// fails. Channels registered with Watch are transparently registered
+// with each replacement, and so receive a fresh initial event for
+// their key once the replacement has synced with the database.
+//
+// If config.MaxAttempts consecutive replacements fail, the
+// RestartingPresence stops with the last error encountered.
+func NewRestartingPresence(config PresenceConfig) (*RestartingPresence, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	current, err := config.Start()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rp := &RestartingPresence{
+		config:  config,
+		current: current,
+		watches: make(map[string][]chan<- presence.Change),
+	}
+	go func() {
+		defer rp.tomb.Done()
+		rp.tomb.Kill(rp.loop())
+		rp.mu.Lock()
+		current := rp.current
+		rp.mu.Unlock()
+		if err := worker.Stop(current); err != nil {
+			rp.config.Logger.Debugf("presence watcher stopped with error: %v", err)
+		}
+	}()
+	return rp, nil
+}
+
+// RestartingPresence is a PresenceWorker that survives the failure of
+// its underlying presence worker.
+type RestartingPresence struct {
+	config PresenceConfig
+	tomb   tomb.Tomb
+
+	// mu protects the fields below, and is held while registering
+	// and unregistering channels with the current worker, so that
+	// each channel is registered with a replacement exactly once.
+	mu       sync.Mutex
+	current  PresenceWorker
+	watches  map[string][]chan<- presence.Change
+	restarts int
+}
+
+// Restarts returns the number of times the underlying presence worker
+// has been replaced. It is intended for debugging.
+func (rp *RestartingPresence) Restarts() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.restarts
+}
+
+// Kill is part of the worker.Worker interface.
+func (rp *RestartingPresence) Kill() {
+	rp.tomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (rp *RestartingPresence) Wait() error {
+	return rp.tomb.Wait()
+}
+
+// Dead is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Dead() <-chan struct{} {
+	return rp.tomb.Dead()
+}
+
+// Err is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Err() error {
+	return rp.tomb.Err()
+}
+
+// Sync is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Sync() {
+	rp.underlying().Sync()
+}
+
+// Alive is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Alive(key string) (bool, error) {
+	return rp.underlying().Alive(key)
+}
+
+// Watch is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Watch(key string, ch chan<- presence.Change) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.watches[key] = append(rp.watches[key], ch)
+	rp.current.Watch(key, ch)
+}
+
+// Unwatch is part of the PresenceWatcher interface.
+func (rp *RestartingPresence) Unwatch(key string, ch chan<- presence.Change) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	watches := rp.watches[key]
+	for i, existing := range watches {
+		if existing == ch {
+			watches[i] = watches[len(watches)-1]
+			watches = watches[:len(watches)-1]
+			break
+		}
+	}
+	if len(watches) == 0 {
+		delete(rp.watches, key)
+	} else {
+		rp.watches[key] = watches
+	}
+	rp.current.Unwatch(key, ch)
+}
+
+func (rp *RestartingPresence) underlying() PresenceWorker {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.current
+}
+
+func (rp *RestartingPresence) loop() error {
+	for {
+		current := rp.underlying()
+		select {
+		case <-rp.tomb.Dying():
+			return tomb.ErrDying
+		case <-current.Dead():
+		}
+		rp.config.Logger.Errorf("presence watcher failed: %v", current.Wait())
+
+		next, err := rp.replacement()
+		if err == tomb.ErrDying {
+			return err
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		rp.replace(next)
+	}
+}
+
+// replacement returns a new presence worker that has synced with the
+// database, backing off between failed attempts to create one.
+func (rp *RestartingPresence) replacement() (PresenceWorker, error) {
+	delay := rp.config.Delay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-rp.tomb.Dying():
+			return nil, tomb.ErrDying
+		case <-rp.config.Clock.After(delay):
+		}
+		next, err := rp.start()
+		if err == nil {
+			return next, nil
+		}
+		if attempt >= rp.config.MaxAttempts {
+			return nil, errors.Annotatef(err, "cannot restart presence watcher after %d attempts", attempt)
+		}
+		delay *= 2
+		if delay > rp.config.MaxDelay {
+			delay = rp.config.MaxDelay
+		}
+		rp.config.Logger.Warningf("cannot restart presence watcher (retrying in %v): %v", delay, err)
+	}
+}
+
+// start creates a new presence worker and waits for it to sync, so
+// that the events sent to re-registered channels reflect the current
+// state of the database.
+func (rp *RestartingPresence) start() (PresenceWorker, error) {
+	next, err := rp.config.Start()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	next.Sync()
+	if err := next.Err(); err != tomb.ErrStillAlive {
+		worker.Stop(next)
+		if err == nil {
+			err = errors.New("presence watcher stopped before syncing")
+		}
+		return nil, errors.Trace(err)
+	}
+	return next, nil
+}
+
+// replace makes next the current presence worker, and registers every
+// watched channel with it; each will receive an event reporting the
+// current status of its key.
+func (rp *RestartingPresence) replace(next PresenceWorker) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.current = next
+	rp.restarts++
+	for key, watches := range rp.watches {
+		for _, ch := range watches {
+			next.Watch(key, ch)
+		}
+	}
+	rp.config.Logger.Infof("presence watcher restarted (%d restarts)", rp.restarts)
+}
@@ -0,0 +1,316 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package workers_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/tomb.v1"
+
+	"github.com/juju/juju/state/presence"
+	"github.com/juju/juju/state/workers"
+	jujutesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/workertest"
+)
+
+type RestartingPresenceSuite struct {
+	testing.IsolationSuite
+	clock    *testing.Clock
+	starts   chan *fakePresence
+	results  []error
+	deadNext bool
+}
+
+var _ = gc.Suite(&RestartingPresenceSuite{})
+
+func (s *RestartingPresenceSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = testing.NewClock(time.Now())
+	s.starts = make(chan *fakePresence, 10)
+	s.results = nil
+	s.deadNext = false
+}
+
+func (s *RestartingPresenceSuite) config() workers.PresenceConfig {
+	return workers.PresenceConfig{
+		Start: func() (workers.PresenceWorker, error) {
+			if len(s.results) > 0 {
+				err := s.results[0]
+				s.results = s.results[1:]
+				if err != nil {
+					return nil, err
+				}
+			}
+			fp := newFakePresence()
+			if s.deadNext {
+				s.deadNext = false
+				fp.tomb.Kill(errors.New("cannot sync"))
+			}
+			s.starts <- fp
+			return fp, nil
+		},
+		Logger:      loggo.GetLogger("test"),
+		Clock:       s.clock,
+		Delay:       time.Second,
+		MaxDelay:    3 * time.Second,
+		MaxAttempts: 3,
+	}
+}
+
+func (s *RestartingPresenceSuite) nextPresence(c *gc.C) *fakePresence {
+	select {
+	case fp := <-s.starts:
+		return fp
+	case <-time.After(jujutesting.LongWait):
+		c.Fatalf("presence worker never started")
+	}
+	panic("unreachable")
+}
+
+// waitRestarts waits until rp reports the given number of restarts.
+func (s *RestartingPresenceSuite) waitRestarts(c *gc.C, rp *workers.RestartingPresence, expect int) {
+	timeout := time.After(jujutesting.LongWait)
+	for rp.Restarts() != expect {
+		select {
+		case <-timeout:
+			c.Fatalf("expected %d restarts, got %d", expect, rp.Restarts())
+		case <-time.After(jujutesting.ShortWait):
+		}
+	}
+}
+
+func (s *RestartingPresenceSuite) TestValidate(c *gc.C) {
+	for i, test := range []struct {
+		mutate func(*workers.PresenceConfig)
+		err    string
+	}{{
+		mutate: func(config *workers.PresenceConfig) { config.Start = nil },
+		err:    "nil Start not valid",
+	}, {
+		mutate: func(config *workers.PresenceConfig) { config.Logger = loggo.Logger{} },
+		err:    "uninitialized Logger not valid",
+	}, {
+		mutate: func(config *workers.PresenceConfig) { config.Clock = nil },
+		err:    "nil Clock not valid",
+	}, {
+		mutate: func(config *workers.PresenceConfig) { config.Delay = 0 },
+		err:    "non-positive Delay not valid",
+	}, {
+		mutate: func(config *workers.PresenceConfig) { config.MaxDelay = time.Millisecond },
+		err:    "MaxDelay less than Delay not valid",
+	}, {
+		mutate: func(config *workers.PresenceConfig) { config.MaxAttempts = 0 },
+		err:    "MaxAttempts 0 not valid",
+	}} {
+		c.Logf("test %d: %s", i, test.err)
+		config := s.config()
+		test.mutate(&config)
+		err := config.Validate()
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		c.Check(err, gc.ErrorMatches, test.err)
+
+		rp, err := workers.NewRestartingPresence(config)
+		c.Check(rp, gc.IsNil)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *RestartingPresenceSuite) TestStartError(c *gc.C) {
+	s.results = []error{errors.New("splat")}
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Check(rp, gc.IsNil)
+	c.Check(err, gc.ErrorMatches, "splat")
+}
+
+func (s *RestartingPresenceSuite) TestDelegates(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, rp)
+	fp := s.nextPresence(c)
+
+	ch := make(chan presence.Change)
+	rp.Watch("machine-0", ch)
+	fp.checkWatched(c, "machine-0", ch)
+	rp.Unwatch("machine-0", ch)
+	fp.checkUnwatched(c, "machine-0", ch)
+
+	alive, err := rp.Alive("machine-0")
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(alive, jc.IsTrue)
+	c.Check(rp.Restarts(), gc.Equals, 0)
+}
+
+func (s *RestartingPresenceSuite) TestRestartReregistersWatches(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, rp)
+	fp := s.nextPresence(c)
+
+	ch0 := make(chan presence.Change)
+	ch1 := make(chan presence.Change)
+	rp.Watch("machine-0", ch0)
+	fp.checkWatched(c, "machine-0", ch0)
+	rp.Watch("machine-1", ch1)
+	fp.checkWatched(c, "machine-1", ch1)
+	rp.Unwatch("machine-1", ch1)
+	fp.checkUnwatched(c, "machine-1", ch1)
+
+	fp.tomb.Kill(errors.New("query failed"))
+	WaitAlarms(c, s.clock, 1)
+	s.clock.Advance(time.Second)
+	next := s.nextPresence(c)
+
+	// Only the remaining watch is registered with the replacement.
+	next.checkWatched(c, "machine-0", ch0)
+	next.checkNoRequests(c)
+	workertest.CheckAlive(c, rp)
+	c.Check(rp.Restarts(), gc.Equals, 1)
+
+	// Subsequent calls go to the replacement.
+	rp.Unwatch("machine-0", ch0)
+	next.checkUnwatched(c, "machine-0", ch0)
+}
+
+func (s *RestartingPresenceSuite) TestRestartBacksOff(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, rp)
+	fp := s.nextPresence(c)
+
+	s.results = []error{errors.New("one"), errors.New("two")}
+	fp.tomb.Kill(errors.New("query failed"))
+
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		WaitAlarms(c, s.clock, 1)
+		s.clock.Advance(delay - time.Nanosecond)
+		select {
+		case <-s.clock.Alarms():
+			c.Fatalf("restart attempted before %v", delay)
+		case <-time.After(jujutesting.ShortWait):
+		}
+		s.clock.Advance(time.Nanosecond)
+	}
+	s.nextPresence(c)
+	s.waitRestarts(c, rp, 1)
+}
+
+func (s *RestartingPresenceSuite) TestRestartGivesUp(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.DirtyKill(c, rp)
+	fp := s.nextPresence(c)
+
+	s.results = []error{errors.New("one"), errors.New("two"), errors.New("three")}
+	fp.tomb.Kill(errors.New("query failed"))
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		WaitAlarms(c, s.clock, 1)
+		s.clock.Advance(delay)
+	}
+
+	err = workertest.CheckKilled(c, rp)
+	c.Check(err, gc.ErrorMatches, "cannot restart presence watcher after 3 attempts: three")
+	select {
+	case <-rp.Dead():
+	default:
+		c.Fatalf("presence watcher not dead")
+	}
+	c.Check(rp.Restarts(), gc.Equals, 0)
+}
+
+func (s *RestartingPresenceSuite) TestRestartDeadReplacement(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, rp)
+	fp := s.nextPresence(c)
+
+	s.deadNext = true
+	fp.tomb.Kill(errors.New("query failed"))
+	WaitAlarms(c, s.clock, 1)
+	s.clock.Advance(time.Second)
+	dead := s.nextPresence(c)
+	workertest.CheckKilled(c, dead)
+
+	// A replacement that fails before syncing counts as a failed
+	// attempt, and is retried after backing off.
+	WaitAlarms(c, s.clock, 1)
+	s.clock.Advance(2 * time.Second)
+	s.nextPresence(c)
+	s.waitRestarts(c, rp, 1)
+}
+
+func (s *RestartingPresenceSuite) TestKillStopsUnderlying(c *gc.C) {
+	rp, err := workers.NewRestartingPresence(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	fp := s.nextPresence(c)
+
+	workertest.CleanKill(c, rp)
+	workertest.CheckKilled(c, fp)
+}
+
+type watchRequest struct {
+	watch bool
+	key   string
+	ch    chan<- presence.Change
+}
+
+// fakePresence implements workers.PresenceWorker, reporting the Watch
+// and Unwatch calls made on it.
+type fakePresence struct {
+	tomb     tomb.Tomb
+	requests chan watchRequest
+}
+
+func newFakePresence() *fakePresence {
+	fp := &fakePresence{requests: make(chan watchRequest, 10)}
+	go func() {
+		defer fp.tomb.Done()
+		<-fp.tomb.Dying()
+	}()
+	return fp
+}
+
+func (fp *fakePresence) Kill()                      { fp.tomb.Kill(nil) }
+func (fp *fakePresence) Wait() error                { return fp.tomb.Wait() }
+func (fp *fakePresence) Dead() <-chan struct{}      { return fp.tomb.Dead() }
+func (fp *fakePresence) Err() error                 { return fp.tomb.Err() }
+func (fp *fakePresence) Sync()                      {}
+func (fp *fakePresence) Alive(string) (bool, error) { return true, nil }
+
+func (fp *fakePresence) Watch(key string, ch chan<- presence.Change) {
+	fp.requests <- watchRequest{true, key, ch}
+}
+
+func (fp *fakePresence) Unwatch(key string, ch chan<- presence.Change) {
+	fp.requests <- watchRequest{false, key, ch}
+}
+
+func (fp *fakePresence) checkRequest(c *gc.C, expect watchRequest) {
+	select {
+	case req := <-fp.requests:
+		c.Check(req, jc.DeepEquals, expect)
+	case <-time.After(jujutesting.LongWait):
+		c.Fatalf("never saw %+v", expect)
+	}
+}
+
+func (fp *fakePresence) checkWatched(c *gc.C, key string, ch chan presence.Change) {
+	fp.checkRequest(c, watchRequest{true, key, ch})
+}
+
+func (fp *fakePresence) checkUnwatched(c *gc.C, key string, ch chan presence.Change) {
+	fp.checkRequest(c, watchRequest{false, key, ch})
+}
+
+func (fp *fakePresence) checkNoRequests(c *gc.C) {
+	select {
+	case req := <-fp.requests:
+		c.Fatalf("unexpected request %+v", req)
+	case <-time.After(jujutesting.ShortWait):
+	}
+}
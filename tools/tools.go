@@ -4,6 +4,7 @@
 package tools
 
 import (
+	"github.com/juju/errors"
 	"github.com/juju/version"
 )
 
@@ -13,6 +14,10 @@ type Tools struct {
 	URL     string         `json:"url"`
 	SHA256  string         `json:"sha256,omitempty"`
 	Size    int64          `json:"size"`
+
+	// StorageRef, if set, refers to the tools tarball held in the
+	// controller's storage, and is set in place of URL.
+	StorageRef string `json:"storage-ref,omitempty" bson:",omitempty"`
 }
 
 // GUI represents the location and version of a GUI release archive.
@@ -22,3 +27,87 @@ type GUIArchive struct {
 	SHA256  string         `json:"sha256,omitempty"`
 	Size    int64          `json:"size"`
 }
+
+// Compare returns -1, 0 or 1 depending on whether t is older than,
+// the same as, or newer than other. Versions are ordered by their
+// numeric components, so 1.10.0 is newer than 1.9.0 and 2.0.0.1 is
+// newer than 2.0.0, and a tagged version such as 2.0-beta1 is older
+// than the corresponding release. Tools with the same version number
+// are ordered by series and then by architecture.
+func (t *Tools) Compare(other *Tools) int {
+	if result := t.Version.Number.Compare(other.Version.Number); result != 0 {
+		return result
+	}
+	switch {
+	case t.Version.Series < other.Version.Series:
+		return -1
+	case t.Version.Series > other.Version.Series:
+		return 1
+	case t.Version.Arch < other.Version.Arch:
+		return -1
+	case t.Version.Arch > other.Version.Arch:
+		return 1
+	}
+	return 0
+}
+
+// IsDevelopment returns whether v is a development version, that is,
+// one with a tag (such as "beta") or a non-zero build number.
+func IsDevelopment(v version.Number) bool {
+	return v.Tag != "" || v.Build > 0
+}
+
+// ValidateVersion returns an error if v does not identify tools that
+// an agent can run: that is, if its series or architecture is empty,
+// or if releasedOnly is true and v is a development version.
+func ValidateVersion(v version.Binary, releasedOnly bool) error {
+	if v.Series == "" || v.Arch == "" {
+		return errors.New("empty series or arch")
+	}
+	if releasedOnly && IsDevelopment(v.Number) {
+		return errors.NotValidf("development version %s", v.Number)
+	}
+	return nil
+}
+
+// Constraints holds criteria for choosing the best tools.
+type Constraints struct {
+	// Series and Arch, if not empty, cause only tools with that
+	// series and architecture to be considered.
+	Series string
+	Arch   string
+
+	// Major, if non-zero, causes only tools with that major version
+	// number to be considered, since agents cannot upgrade across
+	// major versions.
+	Major int
+
+	// ReleasedOnly causes development versions to be ignored.
+	ReleasedOnly bool
+}
+
+// BestTools returns the newest tools in list that satisfy cons. It
+// returns ErrNoMatches if there are none.
+func BestTools(list []*Tools, cons Constraints) (*Tools, error) {
+	var best *Tools
+	for _, tools := range list {
+		v := tools.Version
+		switch {
+		case cons.Series != "" && v.Series != cons.Series:
+			continue
+		case cons.Arch != "" && v.Arch != cons.Arch:
+			continue
+		case cons.Major != 0 && v.Major != cons.Major:
+			continue
+		case ValidateVersion(v, cons.ReleasedOnly) != nil:
+			continue
+		}
+		if best == nil || best.Compare(tools) < 0 {
+			best = tools
+		}
+	}
+	if best == nil {
+		return nil, ErrNoMatches
+	}
+	return best, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package tools_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/tools"
+)
+
+type ToolsSuite struct{}
+
+var _ = gc.Suite(&ToolsSuite{})
+
+var compareTests = []struct {
+	a, b   string
+	expect int
+}{{
+	a:      "1.9.0-trusty-amd64",
+	b:      "1.9.0-trusty-amd64",
+	expect: 0,
+}, {
+	a:      "1.10.0-trusty-amd64",
+	b:      "1.9.0-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "1.9.0-trusty-amd64",
+	b:      "1.10.0-trusty-amd64",
+	expect: -1,
+}, {
+	a:      "1.9.10-trusty-amd64",
+	b:      "1.9.9-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.0.0-trusty-amd64",
+	b:      "1.25.6-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.0.0.1-trusty-amd64",
+	b:      "2.0.0-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.0.0.2-trusty-amd64",
+	b:      "2.0.0.10-trusty-amd64",
+	expect: -1,
+}, {
+	a:      "2.0.1-trusty-amd64",
+	b:      "2.0.0.10-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.0-beta1-trusty-amd64",
+	b:      "2.0.0-trusty-amd64",
+	expect: -1,
+}, {
+	a:      "2.0-beta2-trusty-amd64",
+	b:      "2.0-beta10-trusty-amd64",
+	expect: -1,
+}, {
+	a:      "2.0-alpha3-trusty-amd64",
+	b:      "2.0-beta1-trusty-amd64",
+	expect: -1,
+}, {
+	a:      "2.0-rc1-trusty-amd64",
+	b:      "2.0-beta10-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.1-beta1-trusty-amd64",
+	b:      "2.0.5-trusty-amd64",
+	expect: 1,
+}, {
+	a:      "2.0.0-trusty-amd64",
+	b:      "2.0.0-xenial-amd64",
+	expect: -1,
+}, {
+	a:      "2.0.0-xenial-amd64",
+	b:      "2.0.0-xenial-arm64",
+	expect: -1,
+}, {
+	a:      "2.0.0-xenial-amd64",
+	b:      "2.0.1-trusty-amd64",
+	expect: -1,
+}}
+
+func (s *ToolsSuite) TestCompare(c *gc.C) {
+	for i, test := range compareTests {
+		c.Logf("test %d: %s vs %s", i, test.a, test.b)
+		a, b := mustParseTools(test.a), mustParseTools(test.b)
+		c.Check(a.Compare(b), gc.Equals, test.expect)
+		c.Check(b.Compare(a), gc.Equals, -test.expect)
+	}
+}
+
+var isDevelopmentTests = []struct {
+	version string
+	expect  bool
+}{
+	{"2.0.0", false},
+	{"1.25.6", false},
+	{"1.9.0", false},
+	{"2.0.0.1", true},
+	{"2.0-beta1", true},
+	{"2.0-rc2", true},
+	{"2.1-alpha1.1", true},
+}
+
+func (s *ToolsSuite) TestIsDevelopment(c *gc.C) {
+	for i, test := range isDevelopmentTests {
+		c.Logf("test %d: %s", i, test.version)
+		c.Check(tools.IsDevelopment(version.MustParse(test.version)), gc.Equals, test.expect)
+	}
+}
+
+var validateVersionTests = []struct {
+	version      version.Binary
+	releasedOnly bool
+	err          string
+}{{
+	version: version.MustParseBinary("2.0.0-trusty-amd64"),
+}, {
+	version:      version.MustParseBinary("2.0.0-trusty-amd64"),
+	releasedOnly: true,
+}, {
+	version: version.MustParseBinary("2.0-beta1-trusty-amd64"),
+}, {
+	version:      version.MustParseBinary("2.0-beta1-trusty-amd64"),
+	releasedOnly: true,
+	err:          "development version 2.0-beta1 not valid",
+}, {
+	version:      version.MustParseBinary("2.0.0.1-trusty-amd64"),
+	releasedOnly: true,
+	err:          "development version 2.0.0.1 not valid",
+}, {
+	version: version.Binary{Number: version.MustParse("2.0.0"), Arch: "amd64"},
+	err:     "empty series or arch",
+}, {
+	version: version.Binary{Number: version.MustParse("2.0.0"), Series: "trusty"},
+	err:     "empty series or arch",
+}}
+
+func (s *ToolsSuite) TestValidateVersion(c *gc.C) {
+	for i, test := range validateVersionTests {
+		c.Logf("test %d: %s (released only: %v)", i, test.version, test.releasedOnly)
+		err := tools.ValidateVersion(test.version, test.releasedOnly)
+		if test.err == "" {
+			c.Check(err, jc.ErrorIsNil)
+			continue
+		}
+		c.Check(err, gc.ErrorMatches, test.err)
+		if test.releasedOnly {
+			c.Check(err, jc.Satisfies, errors.IsNotValid)
+		}
+	}
+}
+
+var (
+	t1256trusty     = mustParseTools("1.25.6-trusty-amd64")
+	t1310trusty     = mustParseTools("1.31.0-trusty-amd64")
+	t200trusty      = mustParseTools("2.0.0-trusty-amd64")
+	t200xenial      = mustParseTools("2.0.0-xenial-amd64")
+	t200xenialArm   = mustParseTools("2.0.0-xenial-arm64")
+	t2001trusty     = mustParseTools("2.0.0.1-trusty-amd64")
+	t201trusty      = mustParseTools("2.0.1-trusty-amd64")
+	t210beta1trusty = mustParseTools("2.1-beta1-trusty-amd64")
+	bestToolsList   = []*tools.Tools{
+		t200trusty, t1310trusty, t210beta1trusty, t200xenial,
+		t200xenialArm, t2001trusty, t1256trusty, t201trusty,
+	}
+)
+
+var bestToolsTests = []struct {
+	about  string
+	cons   tools.Constraints
+	expect *tools.Tools
+}{{
+	about:  "no constraints",
+	expect: t210beta1trusty,
+}, {
+	about:  "released only",
+	cons:   tools.Constraints{ReleasedOnly: true},
+	expect: t201trusty,
+}, {
+	about:  "series",
+	cons:   tools.Constraints{Series: "xenial"},
+	expect: t200xenial,
+}, {
+	about:  "series and arch",
+	cons:   tools.Constraints{Series: "xenial", Arch: "arm64"},
+	expect: t200xenialArm,
+}, {
+	about:  "major version prefers 1.31 over 1.25",
+	cons:   tools.Constraints{Major: 1},
+	expect: t1310trusty,
+}, {
+	about:  "major version and released only",
+	cons:   tools.Constraints{Major: 2, Series: "trusty", ReleasedOnly: true},
+	expect: t201trusty,
+}, {
+	about: "no match",
+	cons:  tools.Constraints{Series: "precise"},
+}, {
+	about: "no released match",
+	cons:  tools.Constraints{Major: 2, Series: "trusty", Arch: "i386", ReleasedOnly: true},
+}}
+
+func (s *ToolsSuite) TestBestTools(c *gc.C) {
+	for i, test := range bestToolsTests {
+		c.Logf("test %d: %s", i, test.about)
+		best, err := tools.BestTools(bestToolsList, test.cons)
+		if test.expect == nil {
+			c.Check(best, gc.IsNil)
+			c.Check(err, gc.Equals, tools.ErrNoMatches)
+			continue
+		}
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(best, gc.Equals, test.expect)
+	}
+}
+
+func (s *ToolsSuite) TestBestToolsEmpty(c *gc.C) {
+	best, err := tools.BestTools(nil, tools.Constraints{})
+	c.Check(best, gc.IsNil)
+	c.Check(err, gc.Equals, tools.ErrNoMatches)
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import "github.com/juju/juju/core/life"
+
+// ApplicationRelationsChange describes a change to one of the
+// relations of an application: its life and suspended status, and the
+// units that have entered or changed their settings in, or departed
+// from, the relation.
+type ApplicationRelationsChange struct {
+	// Key is the key of the relation.
+	Key string
+
+	// Life is the life of the relation.
+	Life life.Value
+
+	// Suspended reports whether the relation is suspended.
+	Suspended bool
+
+	// Changed holds the names of the units that have entered the
+	// relation or changed their settings, with the latest known
+	// version of the settings of each.
+	Changed map[string]UnitSettings
+
+	// Departed holds the names of units that have left the relation.
+	Departed []string
+}
+
+// ApplicationRelationsChannel is a change channel as described in the
+// CoreWatcher docs.
+//
+// It sends a single value representing the current state of the
+// relations of an application, and subsequent values representing
+// changes to them.
+type ApplicationRelationsChannel <-chan []ApplicationRelationsChange
+
+// ApplicationRelationsWatcher conveniently ties an
+// ApplicationRelationsChannel to the worker.Worker that represents its
+// validity.
+type ApplicationRelationsWatcher interface {
+	CoreWatcher
+	Changes() ApplicationRelationsChannel
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import (
+	"time"
+
+	"github.com/juju/juju/status"
+)
+
+// OfferStatusChange describes the status of an application offer.
+type OfferStatusChange struct {
+	Name    string
+	Status  status.Status
+	Message string
+	Since   *time.Time
+}
+
+// OfferStatusChannel is a change channel as described in the CoreWatcher
+// docs.
+//
+// It sends a single value representing the current status of the offer
+// being watched, and subsequent values representing changes to it.
+type OfferStatusChannel <-chan []OfferStatusChange
+
+// OfferStatusWatcher conveniently ties an OfferStatusChannel to the
+// worker.Worker that represents its validity.
+type OfferStatusWatcher interface {
+	CoreWatcher
+	Changes() OfferStatusChannel
+}
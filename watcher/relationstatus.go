@@ -0,0 +1,30 @@
+// Copyright 2016 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package watcher
+
+import "github.com/juju/juju/core/life"
+
+// RelationStatusChange describes the life and suspended status of a
+// relation.
+type RelationStatusChange struct {
+	Key             string
+	Life            life.Value
+	Suspended       bool
+	SuspendedReason string
+}
+
+// RelationStatusChannel is a change channel as described in the
+// CoreWatcher docs.
+//
+// It sends a single value representing the current status of the
+// relations being watched, and subsequent values representing changes
+// to their life or suspended status.
+type RelationStatusChannel <-chan []RelationStatusChange
+
+// RelationStatusWatcher conveniently ties a RelationStatusChannel to
+// the worker.Worker that represents its validity.
+type RelationStatusWatcher interface {
+	CoreWatcher
+	Changes() RelationStatusChannel
+}
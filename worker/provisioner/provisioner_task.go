@@ -5,6 +5,7 @@ package provisioner
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/juju/errors"
@@ -745,6 +746,17 @@ func (task *provisionerTask) startMachine(
 		}
 		return errors.Annotate(err, "cannot set instance info")
 	}
+	// The metadata is recorded for debugging purposes only, so
+	// failing to record it does not affect the machine.
+	metadata := provisionerMetadata(startInstanceParams.InstanceConfig)
+	if err := machine.SetProvisionerMetadata(metadata); err != nil {
+		logger.Warningf("cannot record provisioner metadata for machine %v: %v", machine, err)
+	}
+	// Recording the applied tags lets drift be detected later; the
+	// instance is usable either way.
+	if err := machine.SetAppliedInstanceTags(startInstanceParams.InstanceConfig.Tags); err != nil {
+		logger.Warningf("cannot record applied instance tags for machine %v: %v", machine, err)
+	}
 
 	logger.Infof(
 		"started machine %s as instance %s with hardware %q, network config %+v, volumes %v, volume attachments %v, subnets to zones %v",
@@ -759,6 +771,41 @@ func (task *provisionerTask) startMachine(
 	return nil
 }
 
+// provisionerMetadata returns the details of icfg that are worth
+// recording against the machine, to help explain how its instance was
+// configured. Credentials are removed from proxy URLs.
+func provisionerMetadata(icfg *instancecfg.InstanceConfig) map[string]string {
+	metadata := make(map[string]string)
+	add := func(key, value string) {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	add("apt-mirror", icfg.AptMirror)
+	add("http-proxy", redactURL(icfg.ProxySettings.Http))
+	add("https-proxy", redactURL(icfg.ProxySettings.Https))
+	add("ftp-proxy", redactURL(icfg.ProxySettings.Ftp))
+	add("no-proxy", icfg.ProxySettings.NoProxy)
+	add("apt-http-proxy", redactURL(icfg.AptProxySettings.Http))
+	add("apt-https-proxy", redactURL(icfg.AptProxySettings.Https))
+	add("apt-ftp-proxy", redactURL(icfg.AptProxySettings.Ftp))
+	if toolsList := icfg.ToolsList(); len(toolsList) > 0 {
+		add("tools-version", toolsList[0].Version.String())
+		add("tools-url", redactURL(toolsList[0].URL))
+	}
+	return metadata
+}
+
+// redactURL returns rawURL without any user credentials.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
 type provisioningInfo struct {
 	Constraints    constraints.Value
 	Series         string